@@ -0,0 +1,189 @@
+// Package breaker rate limits shortener resolution per domain and stops resolving on
+// domains that keep failing (a circuit breaker), so that a burst of clicks or a
+// misbehaving shortener does not stall every launch. The state is kept in a file so
+// that it is shared by the rurl processes started for each click.
+package breaker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Defaults used for zero Limits fields.
+const (
+	DefaultMaxPerMinute     = 30
+	DefaultFailureThreshold = 3
+	DefaultCooldown         = 5 * time.Minute
+)
+
+var (
+	// ErrRateLimited is returned by Acquire when a domain was resolved too often in the
+	// last minute.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrOpen is returned by Acquire while a domain's breaker is open.
+	ErrOpen = errors.New("circuit breaker open")
+)
+
+// Limits are the thresholds of the rate limiter and circuit breaker.
+type Limits struct {
+	MaxPerMinute     int           // Resolutions per domain per minute
+	FailureThreshold int           // Consecutive failures opening the breaker
+	Cooldown         time.Duration // How long the breaker stays open
+}
+
+// withDefaults returns l with zero fields set to their defaults.
+func (l Limits) withDefaults() Limits {
+	if l.MaxPerMinute <= 0 {
+		l.MaxPerMinute = DefaultMaxPerMinute
+	}
+	if l.FailureThreshold <= 0 {
+		l.FailureThreshold = DefaultFailureThreshold
+	}
+	if l.Cooldown <= 0 {
+		l.Cooldown = DefaultCooldown
+	}
+	return l
+}
+
+// Domain is the state of a single domain.
+type Domain struct {
+	Domain    string      `json:"domain"`
+	Requests  []time.Time `json:"requests,omitempty"`   // Resolutions started in the last minute
+	Failures  int         `json:"failures,omitempty"`   // Consecutive failed resolutions
+	OpenUntil *time.Time  `json:"open_until,omitempty"` // Set while the breaker is open
+}
+
+// DefaultPath returns the state file location in the user cache directory.
+func DefaultPath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "rurl", "resolution_breaker.json"), nil
+}
+
+// Load returns the domains stored at path. A missing file is not an error.
+func Load(path string) ([]Domain, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resolution state '%s': %w", path, err)
+	}
+	var list []Domain
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse resolution state '%s': %w", path, err)
+	}
+	return list, nil
+}
+
+// Save writes domains to path, replacing its contents.
+func Save(path string, list []Domain) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create resolution state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode resolution state: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write resolution state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write resolution state: %w", err)
+	}
+	return nil
+}
+
+// Acquire records a resolution on domain starting at now, unless the domain's breaker
+// is open or it reached limits.MaxPerMinute, in which case an error wrapping ErrOpen
+// or ErrRateLimited is returned. Other errors concern the state file.
+func Acquire(path, domain string, limits Limits, now time.Time) error {
+	limits = limits.withDefaults()
+	list, err := Load(path)
+	if err != nil {
+		return err
+	}
+	list = prune(list, now)
+	i := find(list, domain)
+	if i < 0 {
+		list = append(list, Domain{Domain: domain})
+		i = len(list) - 1
+	}
+	d := &list[i]
+	if d.OpenUntil != nil && now.Before(*d.OpenUntil) {
+		return fmt.Errorf("%s until %s after %d failures: %w", domain, d.OpenUntil.Local().Format("15:04:05"), d.Failures, ErrOpen)
+	}
+	if len(d.Requests) >= limits.MaxPerMinute {
+		return fmt.Errorf("%s was resolved %d times in the last minute: %w", domain, len(d.Requests), ErrRateLimited)
+	}
+	d.Requests = append(d.Requests, now)
+	return Save(path, list)
+}
+
+// Report records the outcome of a resolution on domain at now. Successes close the
+// breaker; after limits.FailureThreshold consecutive failures it is opened for
+// limits.Cooldown. Once that has passed, a single further failure opens it again.
+func Report(path, domain string, ok bool, limits Limits, now time.Time) error {
+	limits = limits.withDefaults()
+	list, err := Load(path)
+	if err != nil {
+		return err
+	}
+	list = prune(list, now)
+	i := find(list, domain)
+	if i < 0 {
+		if ok {
+			return nil
+		}
+		list = append(list, Domain{Domain: domain})
+		i = len(list) - 1
+	}
+	d := &list[i]
+	if ok {
+		d.Failures = 0
+		d.OpenUntil = nil
+	} else {
+		d.Failures++
+		if d.Failures >= limits.FailureThreshold {
+			until := now.Add(limits.Cooldown)
+			d.OpenUntil = &until
+		}
+	}
+	return Save(path, prune(list, now))
+}
+
+// find returns the index of the state of domain, or -1.
+func find(list []Domain, domain string) int {
+	for i := range list {
+		if list[i].Domain == domain {
+			return i
+		}
+	}
+	return -1
+}
+
+// prune drops requests older than a minute, and domains without any state left.
+func prune(list []Domain, now time.Time) []Domain {
+	kept := list[:0]
+	for _, d := range list {
+		requests := d.Requests[:0]
+		for _, r := range d.Requests {
+			if now.Sub(r) < time.Minute {
+				requests = append(requests, r)
+			}
+		}
+		d.Requests = requests
+		if len(d.Requests) > 0 || d.Failures > 0 {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,50 @@
+package breaker
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireRateLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "breaker.json")
+	limits := Limits{MaxPerMinute: 2}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, Acquire(path, "lnk.example", limits, now))
+	require.NoError(t, Acquire(path, "lnk.example", limits, now.Add(time.Second)))
+	assert.ErrorIs(t, Acquire(path, "lnk.example", limits, now.Add(2*time.Second)), ErrRateLimited)
+	// Other domains are limited separately
+	assert.NoError(t, Acquire(path, "go.example", limits, now.Add(2*time.Second)))
+
+	// A minute after the first request, there is room again
+	assert.NoError(t, Acquire(path, "lnk.example", limits, now.Add(time.Minute)))
+}
+
+func TestReportOpensBreaker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breaker.json")
+	limits := Limits{FailureThreshold: 2, Cooldown: time.Minute}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, Report(path, "lnk.example", false, limits, now))
+	require.NoError(t, Acquire(path, "lnk.example", limits, now))
+	require.NoError(t, Report(path, "lnk.example", false, limits, now))
+	assert.ErrorIs(t, Acquire(path, "lnk.example", limits, now.Add(30*time.Second)), ErrOpen)
+
+	// After the cooldown a single attempt is allowed; a failure opens the breaker again
+	later := now.Add(2 * time.Minute)
+	require.NoError(t, Acquire(path, "lnk.example", limits, later))
+	require.NoError(t, Report(path, "lnk.example", false, limits, later))
+	assert.ErrorIs(t, Acquire(path, "lnk.example", limits, later), ErrOpen)
+
+	// A success closes it, and domains without state are dropped
+	later = later.Add(2 * time.Minute)
+	require.NoError(t, Acquire(path, "lnk.example", limits, later))
+	require.NoError(t, Report(path, "lnk.example", true, limits, later.Add(time.Minute)))
+	list, err := Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
@@ -2,7 +2,6 @@ package browser
 
 import (
 	"fmt"
-	// "os" // No longer needed here
 	// "text/tabwriter" // No longer needed here
 
 	"github.com/jmylchreest/rurl/internal/config"
@@ -15,11 +14,31 @@ type Detector interface {
 	DiscoverProfiles(browser config.Browser) ([]config.Profile, error)
 }
 
+// registeredDetector overrides the OS-specific detector when non-nil.
+var registeredDetector Detector
+
+// RegisterDetector replaces the detector used by GetDetector and DetectAll.
+// Passing nil restores the OS-specific implementation.
+// It returns a function that restores the previously registered detector.
+func RegisterDetector(d Detector) (restore func()) {
+	previous := registeredDetector
+	registeredDetector = d
+	return func() { registeredDetector = previous }
+}
+
+// GetDetector returns the registered detector, or the OS-specific implementation.
+func GetDetector() (Detector, error) {
+	if registeredDetector != nil {
+		return registeredDetector, nil
+	}
+	return NewDetector() // Gets OS-specific implementation
+}
+
 // DetectAll orchestrates the detection across all browsers found.
 // It returns the combined list of discovered browsers and profiles.
 func DetectAll() ([]config.Browser, []config.Profile, error) {
 	log.Debug().Msg("Starting browser and profile detection...")
-	detector, err := NewDetector() // Gets OS-specific implementation
+	detector, err := GetDetector()
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create browser detector: %w", err)
 	}
@@ -30,20 +49,72 @@ func DetectAll() ([]config.Browser, []config.Profile, error) {
 		// Continue to profile discovery even if browser discovery fails partially
 	}
 
+	discoveredBrowsers = checkBrowsers(discoveredBrowsers)
 	var allDiscoveredProfiles []config.Profile
-	for _, b := range discoveredBrowsers {
+	for i, b := range discoveredBrowsers {
+		discoveredBrowsers[i].Origin = config.OriginDetected
 		discoveredProfiles, err := detector.DiscoverProfiles(b)
 		if err != nil {
 			log.Warn().Err(err).Str("browser_id", b.BrowserID).Msg("Failed to discover profiles for browser")
 			continue // Skip profiles for this browser on error
 		}
-		allDiscoveredProfiles = append(allDiscoveredProfiles, discoveredProfiles...)
+		allDiscoveredProfiles = append(allDiscoveredProfiles, checkProfiles(b, discoveredProfiles)...)
 	}
 
 	log.Debug().Int("browser_count", len(discoveredBrowsers)).Int("profile_count", len(allDiscoveredProfiles)).Msg("Detection finished")
 	return discoveredBrowsers, allDiscoveredProfiles, nil // Return nil error even if some discoveries failed partially
 }
 
+// checkBrowsers returns the browsers found by a detector that the rest of rurl can use:
+// browsers without an ID, and browsers with the ID of an earlier one, are dropped.
+func checkBrowsers(browsers []config.Browser) []config.Browser {
+	seen := make(map[string]bool)
+	checked := make([]config.Browser, 0, len(browsers))
+	for _, b := range browsers {
+		switch {
+		case b.BrowserID == "":
+			log.Warn().Str("name", b.Name).Str("executable", b.Executable).Msg("Detector returned a browser without an ID, skipping")
+			continue
+		case seen[b.BrowserID]:
+			log.Warn().Str("browser_id", b.BrowserID).Str("executable", b.Executable).Msg("Detector returned a browser ID twice, skipping")
+			continue
+		}
+		if b.Name == "" {
+			b.Name = b.BrowserID
+		}
+		seen[b.BrowserID] = true
+		checked = append(checked, b)
+	}
+	return checked
+}
+
+// checkProfiles returns the profiles found by a detector for browser, corrected so
+// that the rest of rurl can use them: they belong to browser, have a name and take
+// their stable IDs (see ProfileID), and profiles with the ID of an earlier one (the
+// same directory) are dropped.
+func checkProfiles(browser config.Browser, profiles []config.Profile) []config.Profile {
+	seen := make(map[string]bool)
+	checked := make([]config.Profile, 0, len(profiles))
+	for _, p := range profiles {
+		if p.BrowserID != browser.BrowserID {
+			log.Warn().Str("browser_id", browser.BrowserID).Str("profile_browser_id", p.BrowserID).Str("profile_dir", p.ProfileDir).Msg("Detector returned a profile of another browser, correcting")
+			p.BrowserID = browser.BrowserID
+		}
+		if p.Name == "" {
+			p.Name = p.ProfileDir
+		}
+		p.ID = ProfileID(browser, p)
+		p.Origin = config.OriginDetected
+		if seen[p.ID] {
+			log.Warn().Str("profile_id", p.ID).Msg("Detector returned a profile twice, skipping")
+			continue
+		}
+		seen[p.ID] = true
+		checked = append(checked, p)
+	}
+	return checked
+}
+
 /*
 // DetectAndSaveBrowsers orchestrates the detection and optional saving.
 // THIS FUNCTION IS DEPRECATED - Logic moved to cli.runDetectBrowsersCmd
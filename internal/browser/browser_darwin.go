@@ -3,12 +3,15 @@
 package browser
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"os/exec"
 	"os/user"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/jmylchreest/rurl/internal/config"
 	"github.com/rs/zerolog/log"
@@ -26,10 +29,13 @@ func NewDetector() (Detector, error) {
 type knownBrowserInfo struct {
 	name         string // User-friendly name (e.g., "Google Chrome")
 	browserID    string // Stable ID (chrome, firefox, edge)
-	executable   string // URI-style executable (e.g., "file://Google Chrome.app" or "bundle://com.google.Chrome")
+	executable   string // URI-style executable (e.g., "file://Google Chrome.app", "bundle://com.google.Chrome" or "path://lynx")
 	profileDir   string // Path relative to ~/Library/Application Support
+	sharedDir    string // Directory tried when profileDir has no profiles.ini (Mozilla builds share Firefox's)
 	profileArg   string // Command line arg for profile
 	incognitoArg string // Command line arg for incognito
+	launchByOpen bool   // Launch via "open -b <bundle>" as the binary does not accept URL arguments
+	terminal     bool   // Text-mode browser run in the current terminal
 }
 
 // knownBrowsers contains the list of supported browsers and their configurations
@@ -114,6 +120,7 @@ var knownBrowsers = []knownBrowserInfo{
 		browserID:    "firefox-dev",
 		executable:   "bundle://org.mozilla.firefoxdeveloperedition",
 		profileDir:   "Firefox Developer Edition",
+		sharedDir:    "Firefox",
 		profileArg:   "-P",
 		incognitoArg: "--private-window",
 	},
@@ -122,6 +129,40 @@ var knownBrowsers = []knownBrowserInfo{
 		browserID:    "firefox-nightly",
 		executable:   "bundle://org.mozilla.nightly",
 		profileDir:   "Firefox Nightly",
+		sharedDir:    "Firefox",
+		profileArg:   "-P",
+		incognitoArg: "--private-window",
+	},
+	// Firefox forks
+	{
+		name:         "LibreWolf",
+		browserID:    "librewolf",
+		executable:   "bundle://org.mozilla.librewolf",
+		profileDir:   "librewolf",
+		profileArg:   "-P",
+		incognitoArg: "--private-window",
+	},
+	{
+		name:         "Waterfox",
+		browserID:    "waterfox",
+		executable:   "bundle://net.waterfox.waterfox",
+		profileDir:   "Waterfox",
+		profileArg:   "-P",
+		incognitoArg: "--private-window",
+	},
+	{
+		name:         "Zen Browser",
+		browserID:    "zen",
+		executable:   "bundle://app.zen-browser.zen",
+		profileDir:   "zen",
+		profileArg:   "-P",
+		incognitoArg: "--private-window",
+	},
+	{
+		name:         "Floorp",
+		browserID:    "floorp",
+		executable:   "bundle://one.ablaze.floorp",
+		profileDir:   "Floorp",
 		profileArg:   "-P",
 		incognitoArg: "--private-window",
 	},
@@ -143,6 +184,15 @@ var knownBrowsers = []knownBrowserInfo{
 		profileArg:   "--profile-directory=",
 		incognitoArg: "--incognito",
 	},
+	// Thorium
+	{
+		name:         "Thorium",
+		browserID:    "thorium",
+		executable:   "bundle://org.chromium.Thorium",
+		profileDir:   "Thorium",
+		profileArg:   "--profile-directory=",
+		incognitoArg: "--incognito",
+	},
 	// Arc
 	{
 		name:         "Arc",
@@ -158,9 +208,151 @@ var knownBrowsers = []knownBrowserInfo{
 		browserID:    "safari",
 		executable:   "bundle://com.apple.Safari",
 		profileDir:   "Safari",
-		profileArg:   "",
+		profileArg:   config.ProfileAppleScript,
 		incognitoArg: "--private",
 	},
+	{
+		name:         "Safari Technology Preview",
+		browserID:    "safari-tp",
+		executable:   "bundle://com.apple.SafariTechnologyPreview",
+		profileDir:   "",
+		profileArg:   "",
+		incognitoArg: "",
+		launchByOpen: true,
+	},
+	// Orion (Kagi)
+	{
+		name:         "Orion",
+		browserID:    "orion",
+		executable:   "bundle://com.kagi.kagimacOS",
+		profileDir:   "",
+		profileArg:   "",
+		incognitoArg: "",
+		launchByOpen: true,
+	},
+	// Terminal browsers, typically installed with Homebrew
+	{
+		name:       "Lynx",
+		browserID:  "lynx",
+		executable: "path://lynx",
+		terminal:   true,
+	},
+	{
+		name:       "w3m",
+		browserID:  "w3m",
+		executable: "path://w3m",
+		terminal:   true,
+	},
+	{
+		name:       "Browsh",
+		browserID:  "browsh",
+		executable: "path://browsh",
+		terminal:   true,
+	},
+}
+
+// applicationDirs returns the directories apps are installed in, searched along with
+// their vendor subfolders (e.g. /Applications/Vendor/Browser.app).
+func applicationDirs() []string {
+	return []string{"/Applications", "/Applications/Utilities", filepath.Join(os.Getenv("HOME"), "Applications")}
+}
+
+// caskrooms are where Homebrew keeps casks, as <cask>/<version>/<app>.app, on Apple
+// silicon and Intel Macs. Casks are usually moved to /Applications, but some are only
+// linked there.
+var caskrooms = []string{"/opt/homebrew/Caskroom", "/usr/local/Caskroom"}
+
+// installedApps returns the .app bundles in dirs and their vendor subfolders, and in
+// the casks of caskrooms, with symbolic links (e.g. to a Homebrew cask) resolved.
+func installedApps(dirs, caskrooms []string) []string {
+	var patterns []string
+	for _, dir := range dirs {
+		patterns = append(patterns, filepath.Join(dir, "*.app"), filepath.Join(dir, "*", "*.app"))
+	}
+	for _, dir := range caskrooms {
+		patterns = append(patterns, filepath.Join(dir, "*", "*", "*.app"))
+	}
+	var apps []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, app := range matches {
+			if resolved, err := filepath.EvalSymlinks(app); err == nil {
+				app = resolved
+			}
+			if !seen[app] {
+				seen[app] = true
+				apps = append(apps, app)
+			}
+		}
+	}
+	return apps
+}
+
+// appsByBundleID maps the bundle identifiers of the installed apps to their bundles.
+// It is only built when Spotlight cannot find a browser, e.g. because indexing is
+// disabled, and then once.
+var appsByBundleID = sync.OnceValue(func() map[string]string {
+	return bundleIDs(installedApps(applicationDirs(), caskrooms))
+})
+
+// bundleIDs maps the bundle identifiers of apps to their bundles, the first one found
+// for an identifier.
+func bundleIDs(apps []string) map[string]string {
+	ids := make(map[string]string)
+	for _, app := range apps {
+		info, err := readBundleInfo(app)
+		if err != nil {
+			log.Debug().Err(err).Str("app", app).Msg("Cannot read app bundle info")
+			continue
+		}
+		if id, _ := info["CFBundleIdentifier"].(string); id != "" {
+			if _, exists := ids[id]; !exists {
+				ids[id] = app
+			}
+		}
+	}
+	return ids
+}
+
+// readBundleInfo returns the decoded Info.plist of the app bundle app.
+func readBundleInfo(app string) (map[string]any, error) {
+	path := filepath.Join(app, "Contents", "Info.plist")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// Some apps ship a binary plist, which plutil converts
+	if bytes.HasPrefix(data, []byte("bplist")) {
+		if data, err = exec.Command("plutil", "-convert", "xml1", "-o", "-", path).Output(); err != nil {
+			return nil, fmt.Errorf("failed to convert %s: %w", path, err)
+		}
+	}
+	plist, err := parsePlistXML(data)
+	if err != nil {
+		return nil, err
+	}
+	info, ok := plist.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a dictionary", path)
+	}
+	return info, nil
+}
+
+// bundleExecutable returns the executable of the app bundle app, named by its
+// CFBundleExecutable or else after the bundle, or "" if it does not exist.
+func bundleExecutable(app string) string {
+	name := strings.TrimSuffix(filepath.Base(app), ".app")
+	if info, err := readBundleInfo(app); err == nil {
+		if exe, _ := info["CFBundleExecutable"].(string); exe != "" {
+			name = exe
+		}
+	}
+	exePath := filepath.Join(app, "Contents", "MacOS", name)
+	if _, err := os.Stat(exePath); err != nil {
+		return ""
+	}
+	return exePath
 }
 
 // findExecutable tries to find the executable for a browser
@@ -174,18 +366,10 @@ func findExecutable(executable string) string {
 
 	switch scheme {
 	case "file":
-		// Search in common locations
-		searchPaths := []string{
-			"/Applications",
-			filepath.Join(os.Getenv("HOME"), "Applications"),
-		}
-
-		for _, base := range searchPaths {
-			appPath := filepath.Join(base, path)
-			if _, err := os.Stat(appPath); err == nil {
-				// Get the actual executable path within the .app bundle
-				exePath := filepath.Join(appPath, "Contents", "MacOS", strings.TrimSuffix(path, ".app"))
-				if _, err := os.Stat(exePath); err == nil {
+		// Search the application directories, their vendor subfolders and the casks
+		for _, appPath := range installedApps(applicationDirs(), caskrooms) {
+			if filepath.Base(appPath) == path {
+				if exePath := bundleExecutable(appPath); exePath != "" {
 					return exePath
 				}
 			}
@@ -195,15 +379,25 @@ func findExecutable(executable string) string {
 		// Check if the bundle is installed using mdfind
 		cmd := exec.Command("mdfind", "kMDItemCFBundleIdentifier =="+path)
 		if output, err := cmd.Output(); err == nil {
-			appPath := strings.TrimSpace(string(output))
-			if appPath != "" {
-				// Get the actual executable path within the .app bundle
-				exePath := filepath.Join(appPath, "Contents", "MacOS", filepath.Base(appPath))
-				if _, err := os.Stat(exePath); err == nil {
+			for _, appPath := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+				if appPath == "" {
+					continue
+				}
+				if exePath := bundleExecutable(appPath); exePath != "" {
 					return exePath
 				}
 			}
 		}
+		// Spotlight finds nothing if indexing is disabled, so look at the apps directly
+		if appPath, ok := appsByBundleID()[path]; ok {
+			return bundleExecutable(appPath)
+		}
+
+	case "path":
+		// Command-line programs are searched for in PATH
+		if exePath, err := exec.LookPath(path); err == nil {
+			return exePath
+		}
 
 	default:
 		log.Warn().Str("scheme", scheme).Msg("Unknown executable scheme")
@@ -225,14 +419,22 @@ func (d *darwinDetector) DiscoverBrowsers() ([]config.Browser, error) {
 
 		if _, exists := found[exePath]; !exists {
 			// Construct browser object
-			found[exePath] = config.Browser{
+			b := config.Browser{
 				Name:         browserInfo.name,
 				BrowserID:    browserInfo.browserID,
 				Executable:   exePath,
 				ProfileArg:   browserInfo.profileArg,
 				IncognitoArg: browserInfo.incognitoArg,
+				Terminal:     browserInfo.terminal,
 			}
-			log.Debug().Str("name", browserInfo.name).Str("path", exePath).Msg("Discovered browser")
+			if bundleID, ok := strings.CutPrefix(browserInfo.executable, "bundle://"); ok {
+				b.BundleID = bundleID
+				if browserInfo.launchByOpen {
+					b.Executable = "open -b " + bundleID
+				}
+			}
+			found[exePath] = b
+			log.Debug().Str("name", browserInfo.name).Str("path", b.Executable).Msg("Discovered browser")
 		}
 	}
 
@@ -272,7 +474,10 @@ func (d *darwinDetector) DiscoverProfiles(browser config.Browser) ([]config.Prof
 	}
 	profileBaseDir := filepath.Join(appSupportPath, info.profileDir)
 
-	if info.profileArg == "--profile-directory=" {
+	if info.profileArg == config.ProfileAppleScript {
+		// --- Safari Profile Discovery (container preferences) ---
+		profiles = discoverSafariProfiles(browser.BrowserID)
+	} else if info.profileArg == "--profile-directory=" {
 		log.Debug().Str("path", profileBaseDir).Msg("Discovering Chromium profiles")
 		// --- Chromium-based Profile Discovery (User Data directory) ---
 		foundProfiles, err := discoverChromiumProfiles(profileBaseDir, browser.BrowserID)
@@ -283,6 +488,13 @@ func (d *darwinDetector) DiscoverProfiles(browser config.Browser) ([]config.Prof
 		} else {
 			profiles = foundProfiles
 		}
+	} else if info.profileArg == "-P" {
+		// --- Firefox-based Profile Discovery (profiles.ini) ---
+		if _, err := os.Stat(filepath.Join(profileBaseDir, "profiles.ini")); err != nil && info.sharedDir != "" {
+			profileBaseDir = filepath.Join(appSupportPath, info.sharedDir)
+		}
+		log.Debug().Str("path", profileBaseDir).Msg("Discovering Firefox profiles")
+		profiles = discoverFirefoxProfiles(profileBaseDir, browser.BrowserID)
 	} else {
 		// Browsers with no known profile method (Safari, Arc)
 		log.Debug().Msg("Browser uses unknown or no profile discovery method, creating default profile")
@@ -309,7 +521,8 @@ func discoverChromiumProfiles(profileBaseDir, browserID string) ([]config.Profil
 			dirName := entry.Name()
 			if dirName == "Default" || strings.HasPrefix(dirName, "Profile ") {
 				// Basic check for a common file to ensure it's likely a valid profile
-				if _, err := os.Stat(filepath.Join(profileBaseDir, dirName, "Preferences")); err == nil {
+				prefsPath := filepath.Join(profileBaseDir, dirName, "Preferences")
+				if _, err := os.Stat(prefsPath); err == nil {
 					profileID := fmt.Sprintf("%s-%s", browserID, strings.ToLower(strings.ReplaceAll(dirName, " ", "")))
 					profileName := fmt.Sprintf("%s (%s)", browserID, dirName)
 					profiles = append(profiles, config.Profile{
@@ -317,6 +530,7 @@ func discoverChromiumProfiles(profileBaseDir, browserID string) ([]config.Profil
 						Name:       profileName,
 						BrowserID:  browserID,
 						ProfileDir: dirName, // Use the directory name for --profile-directory flag
+						Email:      ChromiumProfileEmail(prefsPath),
 					})
 				}
 			}
@@ -331,6 +545,81 @@ func discoverChromiumProfiles(profileBaseDir, browserID string) ([]config.Profil
 	return profiles, nil
 }
 
+// discoverFirefoxProfiles reads the profiles listed in profileBaseDir's profiles.ini,
+// falling back to a default profile if there are none.
+func discoverFirefoxProfiles(profileBaseDir, browserID string) []config.Profile {
+	iniPath := filepath.Join(profileBaseDir, "profiles.ini")
+	parsedProfiles, err := ParseProfilesIni(iniPath)
+	if err != nil {
+		log.Warn().Err(err).Str("ini_path", iniPath).Msg("Failed to parse Firefox profiles.ini")
+	}
+	sort.Slice(parsedProfiles, func(i, j int) bool { return parsedProfiles[i].Name < parsedProfiles[j].Name })
+
+	profiles := []config.Profile{}
+	for _, p := range parsedProfiles {
+		profileDirResolved := p.Path
+		if p.IsRelative == 1 {
+			profileDirResolved = filepath.Join(profileBaseDir, p.Path)
+		}
+		if _, err := os.Stat(profileDirResolved); os.IsNotExist(err) {
+			log.Warn().Str("profile_name", p.Name).Str("path", profileDirResolved).Msg("Firefox profile directory not found, skipping")
+			continue
+		}
+		profiles = append(profiles, config.Profile{
+			ID:         fmt.Sprintf("%s-%s", browserID, strings.ToLower(p.Name)),
+			Name:       fmt.Sprintf("%s (%s)", browserID, p.Name),
+			BrowserID:  browserID,
+			ProfileDir: p.Name, // Use the actual profile name for -P flag
+		})
+	}
+
+	if len(profiles) == 0 {
+		log.Warn().Str("path", profileBaseDir).Msg("No Firefox profiles found, creating default")
+		profiles = append(profiles, createSingleDefaultProfile(browserID, "default"))
+	}
+	return profiles
+}
+
+// safariContainerPrefs is Safari's preferences file, relative to the home directory.
+const safariContainerPrefs = "Library/Containers/com.apple.Safari/Data/Library/Preferences/com.apple.Safari.plist"
+
+// discoverSafariProfiles returns Safari's default profile, launched normally, and the
+// profiles recorded in its container preferences, opened by name through AppleScript
+// (see config.ProfileAppleScript).
+func discoverSafariProfiles(browserID string) []config.Profile {
+	profiles := []config.Profile{{
+		ID:        browserID,
+		Name:      fmt.Sprintf("%s (Personal)", browserID),
+		BrowserID: browserID,
+	}}
+	usr, err := user.Current()
+	if err != nil {
+		log.Warn().Err(err).Msg("Cannot find Safari's preferences")
+		return profiles
+	}
+	prefsPath := filepath.Join(usr.HomeDir, safariContainerPrefs)
+	// The file is usually a binary plist, which plutil converts
+	out, err := exec.Command("plutil", "-convert", "xml1", "-o", "-", prefsPath).Output()
+	if err != nil {
+		log.Debug().Err(err).Str("path", prefsPath).Msg("Could not read Safari preferences (Full Disk Access may be needed)")
+		return profiles
+	}
+	prefs, err := parsePlistXML(out)
+	if err != nil {
+		log.Warn().Err(err).Str("path", prefsPath).Msg("Could not parse Safari preferences")
+		return profiles
+	}
+	for _, name := range safariProfileNames(prefs) {
+		profiles = append(profiles, config.Profile{
+			ID:         fmt.Sprintf("%s-%s", browserID, profileIDPart(name)),
+			Name:       fmt.Sprintf("%s (%s)", browserID, name),
+			BrowserID:  browserID,
+			ProfileDir: name, // Selected by name from the New Window menu
+		})
+	}
+	return profiles
+}
+
 // createSingleDefaultProfile creates a default profile entry when detection fails or isn't applicable.
 func createSingleDefaultProfile(browserID, profileDirName string) config.Profile {
 	profileID := fmt.Sprintf("%s-%s", browserID, strings.ToLower(profileDirName))
@@ -343,6 +632,21 @@ func createSingleDefaultProfile(browserID, profileDirName string) config.Profile
 	}
 }
 
+// ProfileRoot returns the directory holding browser's profiles (the user data
+// directory of Chromium-based browsers).
+func (d *darwinDetector) ProfileRoot(browser config.Browser) (string, error) {
+	for i := range knownBrowsers {
+		if knownBrowsers[i].browserID == browser.BrowserID && knownBrowsers[i].profileDir != "" {
+			appSupportPath, err := getAppSupportPath()
+			if err != nil {
+				return "", fmt.Errorf("failed to get Application Support path: %w", err)
+			}
+			return filepath.Join(appSupportPath, knownBrowsers[i].profileDir), nil
+		}
+	}
+	return "", fmt.Errorf("profile directory of browser '%s' is not known", browser.BrowserID)
+}
+
 // getAppSupportPath returns the user's Application Support directory path.
 func getAppSupportPath() (string, error) {
 	usr, err := user.Current()
@@ -0,0 +1,110 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+)
+
+func TestDarwinDetectorOutput(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"Default", "Profile 1"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name, "Preferences"), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chrome := config.Browser{Name: "Google Chrome", BrowserID: "chrome"}
+	profiles, err := discoverChromiumProfiles(dir, chrome.BrowserID)
+	if err != nil {
+		t.Fatalf("discoverChromiumProfiles() error = %v", err)
+	}
+	checkDetectorOutput(t, chrome, profiles)
+
+	// Firefox profiles are read from profiles.ini
+	files := map[string]string{
+		"profiles.ini":                    "[Install4F96D1932A9F858E]\nDefault=Profiles/abcd.default-release\n\n[Profile1]\nName=work\nIsRelative=1\nPath=Profiles/efgh.work\n\n[Profile0]\nName=default-release\nIsRelative=1\nPath=Profiles/abcd.default-release\nDefault=1\n\n[Profile2]\nName=gone\nIsRelative=1\nPath=Profiles/ijkl.gone\n",
+		"Profiles/abcd.default-release/x": "",
+		"Profiles/efgh.work/x":            "",
+	}
+	firefoxDir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(firefoxDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	firefox := config.Browser{Name: "Firefox", BrowserID: "firefox"}
+	profiles = discoverFirefoxProfiles(firefoxDir, firefox.BrowserID)
+	checkDetectorOutput(t, firefox, profiles)
+	var dirs []string
+	for _, p := range profiles {
+		dirs = append(dirs, p.ProfileDir)
+	}
+	if strings.Join(dirs, ",") != "default-release,work" {
+		t.Errorf("Firefox profile directories = %v, want default-release,work", dirs)
+	}
+	if profiles := discoverFirefoxProfiles(t.TempDir(), firefox.BrowserID); len(profiles) != 1 || profiles[0].ProfileDir != "default" {
+		t.Errorf("discoverFirefoxProfiles() without profiles.ini = %+v, want a default profile", profiles)
+	}
+
+	// Browsers without profiles get a single default one
+	safari := config.Browser{Name: "Safari", BrowserID: "safari"}
+	checkDetectorOutput(t, safari, []config.Profile{createSingleDefaultProfile(safari.BrowserID, "default")})
+}
+
+func TestDarwinInstalledApps(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir()) // /var is a link to /private/var
+	if err != nil {
+		t.Fatal(err)
+	}
+	apps, caskroom := filepath.Join(root, "Applications"), filepath.Join(root, "Caskroom")
+	writeApp := func(app, id, exe string) {
+		t.Helper()
+		info := `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict>
+<key>CFBundleIdentifier</key><string>` + id + `</string>
+<key>CFBundleExecutable</key><string>` + exe + `</string>
+</dict></plist>`
+		if err := os.MkdirAll(filepath.Join(app, "Contents", "MacOS"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(app, "Contents", "Info.plist"), []byte(info), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(app, "Contents", "MacOS", exe), nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeApp(filepath.Join(apps, "Vendor", "Browser.app"), "com.example.browser", "browser")
+	cask := filepath.Join(caskroom, "firefox", "130.0", "Firefox.app")
+	writeApp(cask, "org.mozilla.firefox", "firefox")
+	if err := os.Symlink(cask, filepath.Join(apps, "Firefox.app")); err != nil {
+		t.Fatal(err)
+	}
+
+	found := installedApps([]string{apps}, []string{caskroom})
+	if len(found) != 2 {
+		t.Errorf("installedApps() = %v, want the nested app and the cask once", found)
+	}
+	ids := bundleIDs(found)
+	if ids["org.mozilla.firefox"] != cask {
+		t.Errorf("bundleIDs()[org.mozilla.firefox] = %q, want the cask %q", ids["org.mozilla.firefox"], cask)
+	}
+	browser := ids["com.example.browser"]
+	if got, want := bundleExecutable(browser), filepath.Join(browser, "Contents", "MacOS", "browser"); got != want {
+		t.Errorf("bundleExecutable() = %q, want %q", got, want)
+	}
+	if got := bundleExecutable(filepath.Join(apps, "Missing.app")); got != "" {
+		t.Errorf("bundleExecutable() of a missing app = %q, want none", got)
+	}
+}
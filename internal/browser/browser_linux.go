@@ -19,10 +19,11 @@ import (
 type knownBrowserInfo struct {
 	name         string // User-friendly name (e.g., "Google Chrome")
 	browserID    string // Stable ID (chrome, firefox, edge)
-	executable   string // URI-style executable (e.g., "file://google-chrome" or "flatpak://com.google.Chrome")
+	executable   string // URI-style executable (e.g., "file://google-chrome", "flatpak://com.google.Chrome" or "snap://chromium")
 	profileDir   string // Path relative to user home directory
 	profileArg   string // Command line arg for profile
 	incognitoArg string // Command line arg for incognito
+	terminal     bool   // Text-mode browser run in the current terminal
 	// iconPath     string   // Path to browser icon - REMOVED
 }
 
@@ -137,6 +138,14 @@ var knownBrowsers = []knownBrowserInfo{
 		incognitoArg: "--incognito",
 		// iconPath:     "/usr/share/icons/hicolor/256x256/apps/brave-browser-dev.png",
 	},
+	{
+		name:         "Brave (Snap)",
+		browserID:    "brave-snap",
+		executable:   "snap://brave",
+		profileDir:   "snap/brave/current/.config/BraveSoftware/Brave-Browser",
+		profileArg:   "--profile-directory=%s",
+		incognitoArg: "--incognito",
+	},
 	{
 		name:         "Brave (Flatpak)",
 		browserID:    "brave-flatpak",
@@ -173,6 +182,14 @@ var knownBrowsers = []knownBrowserInfo{
 		incognitoArg: "--private-window",
 		// iconPath:     "/usr/share/icons/hicolor/256x256/apps/firefox-beta.png",
 	},
+	{
+		name:         "Firefox (Snap)",
+		browserID:    "firefox-snap",
+		executable:   "snap://firefox",
+		profileDir:   "snap/firefox/common/.mozilla/firefox",
+		profileArg:   "-P %s",
+		incognitoArg: "--private-window",
+	},
 	{
 		name:         "Firefox (Flatpak)",
 		browserID:    "firefox-flatpak",
@@ -181,6 +198,71 @@ var knownBrowsers = []knownBrowserInfo{
 		profileArg:   "-P %s",
 		incognitoArg: "--private-window",
 	},
+	// Firefox forks
+	{
+		name:         "LibreWolf",
+		browserID:    "librewolf",
+		executable:   "file://librewolf",
+		profileDir:   ".librewolf",
+		profileArg:   "-P %s",
+		incognitoArg: "--private-window",
+	},
+	{
+		name:         "LibreWolf (Flatpak)",
+		browserID:    "librewolf-flatpak",
+		executable:   "flatpak://io.gitlab.librewolf-community",
+		profileDir:   ".var/app/io.gitlab.librewolf-community/.librewolf",
+		profileArg:   "-P %s",
+		incognitoArg: "--private-window",
+	},
+	{
+		name:         "Waterfox",
+		browserID:    "waterfox",
+		executable:   "file://waterfox",
+		profileDir:   ".waterfox",
+		profileArg:   "-P %s",
+		incognitoArg: "--private-window",
+	},
+	{
+		name:         "Waterfox (Flatpak)",
+		browserID:    "waterfox-flatpak",
+		executable:   "flatpak://net.waterfox.waterfox",
+		profileDir:   ".var/app/net.waterfox.waterfox/.waterfox",
+		profileArg:   "-P %s",
+		incognitoArg: "--private-window",
+	},
+	{
+		name:         "Zen Browser",
+		browserID:    "zen",
+		executable:   "file://zen-browser",
+		profileDir:   ".zen",
+		profileArg:   "-P %s",
+		incognitoArg: "--private-window",
+	},
+	{
+		name:         "Zen Browser (Flatpak)",
+		browserID:    "zen-flatpak",
+		executable:   "flatpak://app.zen_browser.zen",
+		profileDir:   ".var/app/app.zen_browser.zen/.zen",
+		profileArg:   "-P %s",
+		incognitoArg: "--private-window",
+	},
+	{
+		name:         "Floorp",
+		browserID:    "floorp",
+		executable:   "file://floorp",
+		profileDir:   ".floorp",
+		profileArg:   "-P %s",
+		incognitoArg: "--private-window",
+	},
+	{
+		name:         "Floorp (Flatpak)",
+		browserID:    "floorp-flatpak",
+		executable:   "flatpak://one.ablaze.floorp",
+		profileDir:   ".var/app/one.ablaze.floorp/.floorp",
+		profileArg:   "-P %s",
+		incognitoArg: "--private-window",
+	},
 	// Chromium
 	{
 		name:         "Chromium",
@@ -191,6 +273,39 @@ var knownBrowsers = []knownBrowserInfo{
 		incognitoArg: "--incognito",
 		// iconPath:     "/usr/share/icons/hicolor/256x256/apps/chromium.png",
 	},
+	{
+		name:         "Chromium (Snap)",
+		browserID:    "chromium-snap",
+		executable:   "snap://chromium",
+		profileDir:   "snap/chromium/common/chromium",
+		profileArg:   "--profile-directory=%s",
+		incognitoArg: "--incognito",
+	},
+	{
+		name:         "Ungoogled Chromium",
+		browserID:    "ungoogled-chromium",
+		executable:   "file://ungoogled-chromium",
+		profileDir:   ".config/chromium",
+		profileArg:   "--profile-directory=%s",
+		incognitoArg: "--incognito",
+	},
+	{
+		name:         "Ungoogled Chromium (Flatpak)",
+		browserID:    "ungoogled-chromium-flatpak",
+		executable:   "flatpak://io.github.ungoogled_software.ungoogled_chromium",
+		profileDir:   ".var/app/io.github.ungoogled_software.ungoogled_chromium/config/chromium",
+		profileArg:   "--profile-directory=%s",
+		incognitoArg: "--incognito",
+	},
+	// Thorium
+	{
+		name:         "Thorium",
+		browserID:    "thorium",
+		executable:   "file://thorium-browser",
+		profileDir:   ".config/thorium",
+		profileArg:   "--profile-directory=%s",
+		incognitoArg: "--incognito",
+	},
 	// Vivaldi
 	{
 		name:         "Vivaldi",
@@ -296,6 +411,27 @@ var knownBrowsers = []knownBrowserInfo{
 		profileArg:   "--profile %s",      // Common pattern, space separated
 		incognitoArg: "--private",         // Common private flag
 	},
+	// Terminal browsers (no profiles or private mode)
+	{
+		name:       "Lynx",
+		browserID:  "lynx",
+		executable: "file://lynx",
+		terminal:   true,
+	},
+	{
+		name:       "w3m",
+		browserID:  "w3m",
+		executable: "file://w3m",
+		profileDir: ".w3m",
+		terminal:   true,
+	},
+	{
+		name:       "Browsh",
+		browserID:  "browsh",
+		executable: "file://browsh",
+		profileDir: ".config/browsh",
+		terminal:   true,
+	},
 }
 
 // linuxDetector implements browser detection for Linux.
@@ -328,10 +464,19 @@ func findExecutable(executable string) string {
 		}
 		return ""
 
+	case "snap":
+		// Snap apps are run through their launcher in the snap bin directory
+		launcher := filepath.Join(snapBinDir, path)
+		if _, err := os.Stat(launcher); err == nil {
+			return launcher
+		}
+		return ""
+
 	case "file":
-		// Regular executable search
+		// Regular executable search. Snap launchers found on the PATH belong to the
+		// browser's snap entry, whose profiles live elsewhere.
 		path, err := exec.LookPath(path)
-		if err == nil {
+		if err == nil && !isSnapLauncher(path) {
 			return path
 		}
 		return ""
@@ -342,6 +487,26 @@ func findExecutable(executable string) string {
 	}
 }
 
+// snapBinDir holds the launchers of installed snap apps.
+const snapBinDir = "/snap/bin"
+
+// isSnapLauncher reports whether path runs a snap app: a launcher in snapBinDir, or a
+// link to the snap binary (as the launchers are).
+func isSnapLauncher(path string) bool {
+	if strings.HasPrefix(path, snapBinDir+"/") {
+		return true
+	}
+	target, err := filepath.EvalSymlinks(path)
+	return err == nil && filepath.Base(target) == "snap"
+}
+
+// installSources maps executable URI schemes to install sources.
+var installSources = map[string]string{
+	"file":    config.InstallNative,
+	"flatpak": config.InstallFlatpak,
+	"snap":    config.InstallSnap,
+}
+
 // DiscoverBrowsers finds installed browsers on Linux.
 func (d *linuxDetector) DiscoverBrowsers() ([]config.Browser, error) {
 	found := make(map[string]config.Browser) // Key: Executable Path
@@ -370,11 +535,13 @@ func (d *linuxDetector) DiscoverBrowsers() ([]config.Browser, error) {
 		if _, exists := found[fullExePath]; !exists {
 			// Construct browser object
 			found[fullExePath] = config.Browser{
-				Name:         browserInfo.name,
-				BrowserID:    browserInfo.browserID,
-				Executable:   fullExePath,
-				ProfileArg:   browserInfo.profileArg,
-				IncognitoArg: browserInfo.incognitoArg,
+				Name:          browserInfo.name,
+				BrowserID:     browserInfo.browserID,
+				Executable:    fullExePath,
+				ProfileArg:    browserInfo.profileArg,
+				IncognitoArg:  browserInfo.incognitoArg,
+				Terminal:      browserInfo.terminal,
+				InstallSource: installSources[scheme],
 			}
 			log.Debug().Str("name", browserInfo.name).Str("path", fullExePath).Msg("Discovered browser")
 		}
@@ -384,6 +551,8 @@ func (d *linuxDetector) DiscoverBrowsers() ([]config.Browser, error) {
 	for _, browser := range found {
 		result = append(result, browser)
 	}
+	// Inside WSL, the Windows host's browsers are available too
+	result = append(result, d.discoverWSLBrowsers()...)
 	return result, nil
 }
 
@@ -508,6 +677,8 @@ func (d *linuxDetector) discoverChromiumProfiles(profilesPath, browserID string)
 		return nil, fmt.Errorf("failed to read profiles directory: %w", err)
 	}
 
+	// Local State has the names shown in the browser (e.g. "Work" for "Profile 1")
+	infos := ChromiumProfileInfos(profilesPath)
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
@@ -523,16 +694,34 @@ func (d *linuxDetector) discoverChromiumProfiles(profilesPath, browserID string)
 		prefsPath := filepath.Join(profilesPath, name, "Preferences")
 		if _, err := os.Stat(prefsPath); err == nil {
 			profile := config.Profile{
-				ID:         fmt.Sprintf("%s-%s", browserID, strings.ToLower(strings.ReplaceAll(name, " ", "-"))),
-				Name:       name,
-				BrowserID:  browserID,
-				ProfileDir: name, // Chrome-based browsers use relative profile paths
+				ID:          fmt.Sprintf("%s-%s", browserID, strings.ToLower(strings.ReplaceAll(name, " ", "-"))),
+				Name:        name,
+				BrowserID:   browserID,
+				ProfileDir:  name, // Chrome-based browsers use relative profile paths
+				Email:       ChromiumProfileEmail(prefsPath),
+				AvatarIndex: infos[name].AvatarIndex(),
+			}
+			if infos[name].Name != "" {
+				profile.Name = infos[name].Name
 			}
 			profiles = append(profiles, profile)
 			log.Debug().Str("browser", browserID).Str("profile", name).Msg("Found profile")
 		}
 	}
 
+	// Opera keeps its single profile in the user data directory itself; it is launched
+	// without a profile directory
+	prefsPath := filepath.Join(profilesPath, "Preferences")
+	if _, err := os.Stat(prefsPath); err == nil && len(profiles) == 0 {
+		log.Debug().Str("browser", browserID).Str("path", profilesPath).Msg("Found single profile in user data directory")
+		return []config.Profile{{
+			ID:        browserID,
+			Name:      "Default",
+			BrowserID: browserID,
+			Email:     ChromiumProfileEmail(prefsPath),
+		}}, nil
+	}
+
 	// If no profiles were found, return a default profile
 	if len(profiles) == 0 {
 		return d.createSingleDefaultProfile(browserID, "Default"), nil
@@ -543,6 +732,10 @@ func (d *linuxDetector) discoverChromiumProfiles(profilesPath, browserID string)
 
 // DiscoverProfiles finds profiles for a given browser on Linux.
 func (d *linuxDetector) DiscoverProfiles(browser config.Browser) ([]config.Profile, error) {
+	if profiles, ok := d.discoverWSLProfiles(browser); ok {
+		return profiles, nil
+	}
+
 	// Find the browser configuration from knownBrowsers to get the base profile directory
 	var browserConfig *knownBrowserInfo
 	for i := range knownBrowsers {
@@ -559,17 +752,11 @@ func (d *linuxDetector) DiscoverProfiles(browser config.Browser) ([]config.Profi
 		return d.createSingleDefaultProfile(browser.BrowserID, "Default"), nil
 	}
 
-	// Get user's home directory
-	homeDir, err := os.UserHomeDir()
+	baseProfilesPath, err := profileBasePath(browserConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user home directory: %w", err)
+		return nil, err
 	}
 
-	// Construct the absolute path to the base profile directory for this browser
-	// Replace ~ with the actual home directory and expand the path
-	profileDir := strings.Replace(browserConfig.profileDir, "~", homeDir, 1)
-	baseProfilesPath := filepath.Clean(profileDir) // Clean the path to remove any duplicates
-
 	// Special handling for Epiphany
 	if browser.BrowserID == "epiphany" || browser.BrowserID == "epiphany-flatpak" {
 		// Ensure the profile directory exists
@@ -621,6 +808,33 @@ func (d *linuxDetector) DiscoverProfiles(browser config.Browser) ([]config.Profi
 	return d.createSingleDefaultProfile(browser.BrowserID, defaultProfileDir), nil
 }
 
+// profileBasePath returns the absolute path of the directory holding the profiles of
+// the known browser info.
+func profileBasePath(info *knownBrowserInfo) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+
+	// Replace ~ with the actual home directory and expand the path
+	profileDir := strings.Replace(info.profileDir, "~", homeDir, 1)
+	if !filepath.IsAbs(profileDir) {
+		profileDir = filepath.Join(homeDir, profileDir) // Entries are relative to the home directory
+	}
+	return filepath.Clean(profileDir), nil
+}
+
+// ProfileRoot returns the directory holding browser's profiles (the user data
+// directory of Chromium-based browsers).
+func (d *linuxDetector) ProfileRoot(browser config.Browser) (string, error) {
+	for i := range knownBrowsers {
+		if knownBrowsers[i].browserID == browser.BrowserID && knownBrowsers[i].profileDir != "" {
+			return profileBasePath(&knownBrowsers[i])
+		}
+	}
+	return "", fmt.Errorf("profile directory of browser '%s' is not known", browser.BrowserID)
+}
+
 // createSingleDefaultProfile is a helper to generate a default profile entry.
 func (d *linuxDetector) createSingleDefaultProfile(browserID, profileDirName string) []config.Profile {
 	return []config.Profile{{
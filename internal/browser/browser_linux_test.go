@@ -0,0 +1,111 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+)
+
+func TestLinuxDetectorOutput(t *testing.T) {
+	writeFakeSystem(t, []string{"google-chrome-stable", "firefox", "epiphany", "opera"}, map[string]string{
+		".config/google-chrome/Default/Preferences":   "{}",
+		".config/google-chrome/Profile 1/Preferences": "{}",
+		".config/google-chrome/Local State":           `{"profile":{"info_cache":{"Profile 1":{"name":"Work","avatar_icon":"chrome://theme/IDR_PROFILE_AVATAR_26"}}}}`,
+		".config/opera/Preferences":                   "{}",
+		".mozilla/firefox/profiles.ini":               "[Profile0]\nName=work\nIsRelative=1\nPath=abcd.work\n\n[Profile1]\nIsRelative=1\nPath=efgh.default\n",
+	})
+
+	detector, err := NewDetector()
+	if err != nil {
+		t.Fatalf("NewDetector() error = %v", err)
+	}
+	browsers, err := detector.DiscoverBrowsers()
+	if err != nil {
+		t.Fatalf("DiscoverBrowsers() error = %v", err)
+	}
+	if len(browsers) != 4 {
+		t.Errorf("DiscoverBrowsers() = %+v, want 4 browsers", browsers)
+	}
+	found := make(map[string][]config.Profile)
+	for _, b := range browsers {
+		profiles, err := detector.DiscoverProfiles(b)
+		if err != nil {
+			t.Errorf("DiscoverProfiles(%s) error = %v", b.BrowserID, err)
+			continue
+		}
+		checkDetectorOutput(t, b, profiles)
+		found[b.BrowserID] = profiles
+	}
+
+	// Chromium profiles are named as in the browser's profile picker
+	avatar := 26
+	for _, p := range found["chrome"] {
+		want := map[string]config.Profile{
+			"Default":   {Name: "Default"},
+			"Profile 1": {Name: "Work", AvatarIndex: &avatar},
+		}[p.ProfileDir]
+		if p.Name != want.Name || !reflect.DeepEqual(p.AvatarIndex, want.AvatarIndex) {
+			t.Errorf("profile %q is named %q with avatar %v, want %q with avatar %v", p.ProfileDir, p.Name, p.AvatarIndex, want.Name, want.AvatarIndex)
+		}
+	}
+	// Opera's single profile lives in the user data directory itself
+	if opera := found["opera"]; len(opera) != 1 || opera[0].ProfileDir != "" {
+		t.Errorf("Opera profiles = %+v, want one without a profile directory", opera)
+	}
+}
+
+func TestDetectAllFakeSystem(t *testing.T) {
+	writeFakeSystem(t, []string{"google-chrome-stable", "firefox"}, map[string]string{
+		".config/google-chrome/Default/Preferences":   "{}",
+		".config/google-chrome/Profile 1/Preferences": "{}",
+		".config/google-chrome/Crash Reports/x":       "",
+		".mozilla/firefox/profiles.ini":               "[Profile0]\nName=work\nIsRelative=1\nPath=abcd.work\n",
+	})
+
+	browsers, profiles, err := DetectAll()
+	if err != nil {
+		t.Fatalf("DetectAll() error = %v", err)
+	}
+	ids := make(map[string]string)
+	for _, b := range browsers {
+		ids[b.BrowserID] = b.Executable
+	}
+	if len(browsers) != 2 || !strings.HasSuffix(ids["chrome"], "google-chrome-stable") || !strings.HasSuffix(ids["firefox"], "firefox") {
+		t.Errorf("Unexpected browsers: %+v", browsers)
+	}
+
+	var profileIDs []string
+	for _, p := range profiles {
+		profileIDs = append(profileIDs, p.ID[:strings.LastIndex(p.ID, "-")]) // Without the executable hash
+	}
+	sort.Strings(profileIDs)
+	want := []string{"chrome-default", "chrome-profile-1", "firefox-abcd.work"}
+	if strings.Join(profileIDs, ",") != strings.Join(want, ",") {
+		t.Errorf("profile IDs = %v, want %v", profileIDs, want)
+	}
+}
+
+func TestLinuxDetectorIgnoresSnapLauncher(t *testing.T) {
+	writeFakeSystem(t, []string{"chromium", "snap"}, nil)
+	bin := os.Getenv("PATH")
+	// A snap launcher on the PATH is not taken for a native install
+	if err := os.Symlink(filepath.Join(bin, "snap"), filepath.Join(bin, "firefox")); err != nil {
+		t.Fatal(err)
+	}
+
+	browsers, _, err := DetectAll()
+	if err != nil {
+		t.Fatalf("DetectAll() error = %v", err)
+	}
+	if len(browsers) != 1 || browsers[0].Executable != filepath.Join(bin, "chromium") {
+		t.Fatalf("DetectAll() browsers = %+v, want only chromium", browsers)
+	}
+	if browsers[0].InstallSource != config.InstallNative {
+		t.Errorf("chromium install source = %q, want %q", browsers[0].InstallSource, config.InstallNative)
+	}
+}
@@ -1,7 +1,13 @@
 package browser
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/jmylchreest/rurl/internal/config"
@@ -257,3 +263,380 @@ Path=dev-edition-default
 		t.Error("Developer Edition Firefox profile not found")
 	}
 }
+
+func TestDetectAllWithRegisteredDetector(t *testing.T) {
+	fake := &StaticDetector{
+		Browsers: []config.Browser{
+			{Name: "Google Chrome", BrowserID: "chrome"},
+			{Name: "Firefox", BrowserID: "firefox"},
+		},
+		Profiles: map[string][]config.Profile{
+			"chrome": {{ID: "chrome-default", BrowserID: "chrome", ProfileDir: "Default"}},
+		},
+		ProfileErrors: map[string]error{"firefox": fmt.Errorf("profiles.ini unreadable")},
+	}
+	restore := RegisterDetector(fake)
+	defer restore()
+
+	browsers, profiles, err := DetectAll()
+	if err != nil {
+		t.Fatalf("DetectAll() error = %v", err)
+	}
+	if len(browsers) != 2 {
+		t.Errorf("Expected 2 browsers, got %d", len(browsers))
+	}
+	// Firefox profile discovery failed, so only the Chrome profile is returned
+	if len(profiles) != 1 || profiles[0].ID != "chrome-default-e3b0c4" { // Hash of no executable
+		t.Errorf("Unexpected profiles: %+v", profiles)
+	}
+}
+
+func TestDetectAllChecksDetectorOutput(t *testing.T) {
+	fake := &StaticDetector{
+		Browsers: []config.Browser{
+			{Name: "Google Chrome", BrowserID: "chrome"},
+			{Name: "Nameless"},
+			{Name: "Google Chrome (again)", BrowserID: "chrome"},
+		},
+		Profiles: map[string][]config.Profile{
+			"chrome": {
+				{BrowserID: "Google Chrome", ProfileDir: "Default"}, // The name instead of the ID
+				{BrowserID: "chrome", ProfileDir: "Default", Name: "Duplicate"},
+				{BrowserID: "chrome", ProfileDir: "Profile 1", Name: "Work"},
+			},
+		},
+	}
+	restore := RegisterDetector(fake)
+	defer restore()
+
+	browsers, profiles, err := DetectAll()
+	if err != nil {
+		t.Fatalf("DetectAll() error = %v", err)
+	}
+	if len(browsers) != 1 || browsers[0].Name != "Google Chrome" {
+		t.Errorf("Unexpected browsers: %+v", browsers)
+	}
+	want := []config.Profile{
+		{ID: "chrome-default-e3b0c4", Name: "Default", BrowserID: "chrome", ProfileDir: "Default", Origin: config.OriginDetected},
+		{ID: "chrome-profile-1-e3b0c4", Name: "Work", BrowserID: "chrome", ProfileDir: "Profile 1", Origin: config.OriginDetected},
+	}
+	if !reflect.DeepEqual(profiles, want) {
+		t.Errorf("DetectAll() profiles = %+v, want %+v", profiles, want)
+	}
+}
+
+// checkDetectorOutput fails t unless the profiles a detector found for browser need no
+// correction by DetectAll.
+func checkDetectorOutput(t *testing.T, browser config.Browser, profiles []config.Profile) {
+	t.Helper()
+	if len(profiles) == 0 {
+		t.Errorf("no profiles found for %s", browser.BrowserID)
+	}
+	dirs := make(map[string]bool)
+	for _, p := range profiles {
+		if p.BrowserID != browser.BrowserID {
+			t.Errorf("profile %q of %s has BrowserID %q", p.ProfileDir, browser.BrowserID, p.BrowserID)
+		}
+		if p.Name == "" {
+			t.Errorf("profile %+v of %s has no name", p, browser.BrowserID)
+		}
+		if dirs[p.ProfileDir] {
+			t.Errorf("profile directory %q of %s found twice", p.ProfileDir, browser.BrowserID)
+		}
+		dirs[p.ProfileDir] = true
+	}
+}
+
+func TestSafariProfileNames(t *testing.T) {
+	prefs := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>HomePage</key>
+	<string>https://example.com/</string>
+	<key>Profiles</key>
+	<array>
+		<dict>
+			<key>Title</key>
+			<string>Work &amp; Mail</string>
+			<key>Color</key>
+			<integer>3</integer>
+			<key>Created</key>
+			<date>2024-01-02T03:04:05Z</date>
+		</dict>
+		<dict>
+			<key>Symbol</key>
+			<data>AAEC</data>
+		</dict>
+		<dict>
+			<key>Title</key>
+			<string>School</string>
+			<key>Pinned</key>
+			<true/>
+		</dict>
+	</array>
+	<key>ZoomFactor</key>
+	<real>1.5</real>
+</dict>
+</plist>`
+	decoded, err := parsePlistXML([]byte(prefs))
+	if err != nil {
+		t.Fatalf("parsePlistXML() error = %v", err)
+	}
+	if zoom := decoded.(map[string]any)["ZoomFactor"]; zoom != 1.5 {
+		t.Errorf("ZoomFactor = %v, want 1.5", zoom)
+	}
+	if names := safariProfileNames(decoded); !reflect.DeepEqual(names, []string{"Work & Mail", "School"}) {
+		t.Errorf("safariProfileNames() = %v, want [Work & Mail School]", names)
+	}
+	if names := safariProfileNames("not a dict"); names != nil {
+		t.Errorf("safariProfileNames() of an unexpected plist = %v, want none", names)
+	}
+	if _, err := parsePlistXML([]byte("<plist><dict>")); err == nil {
+		t.Error("parsePlistXML() of a truncated plist succeeded")
+	}
+}
+
+// writeFakeSystem creates a home directory containing files and a PATH directory
+// containing executables, and points HOME and PATH at them so the real detector
+// runs against them.
+func writeFakeSystem(t *testing.T, executables []string, files map[string]string) {
+	t.Helper()
+	root := t.TempDir()
+	home := filepath.Join(root, "home")
+	bin := filepath.Join(root, "bin")
+	for _, dir := range []string{home, bin} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range executables {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range files {
+		path := filepath.Join(home, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("PATH", bin)
+}
+
+func TestProfileID(t *testing.T) {
+	chrome := config.Browser{BrowserID: "chrome", Executable: "/usr/bin/google-chrome-stable"}
+	profile := config.Profile{Name: "Person 1", ProfileDir: "Profile 1"}
+	id := ProfileID(chrome, profile)
+	if !strings.HasPrefix(id, "chrome-profile-1-") {
+		t.Errorf("ProfileID() = %s, want chrome-profile-1-<hash>", id)
+	}
+
+	// Renaming the profile keeps its ID
+	profile.Name = "Work"
+	if renamed := ProfileID(chrome, profile); renamed != id {
+		t.Errorf("ProfileID() of renamed profile = %s, want %s", renamed, id)
+	}
+	// Another install or user data directory gets another ID
+	flatpak := config.Browser{BrowserID: "chrome", Executable: "flatpak run com.google.Chrome"}
+	if other := ProfileID(flatpak, profile); other == id {
+		t.Errorf("ProfileID() is %s for both installs", id)
+	}
+	profile.UserDataDir = "/data/Chrome Work"
+	if other := ProfileID(chrome, profile); !strings.HasPrefix(other, "chrome-chrome-work-profile-1-") {
+		t.Errorf("ProfileID() = %s, want chrome-chrome-work-profile-1-<hash>", other)
+	}
+}
+
+func TestKnownBrowsersIncludeForks(t *testing.T) {
+	expected := map[string]string{
+		"librewolf":          "-P",
+		"waterfox":           "-P",
+		"zen":                "-P",
+		"floorp":             "-P",
+		"thorium":            "--profile-directory=",
+		"ungoogled-chromium": "--profile-directory=",
+	}
+	if runtime.GOOS == "linux" {
+		for _, id := range []string{"librewolf", "waterfox", "zen", "floorp", "ungoogled-chromium"} {
+			expected[id+"-flatpak"] = expected[id]
+		}
+	}
+	if runtime.GOOS == "darwin" {
+		// Ungoogled Chromium shares stock Chromium's bundle ID and cannot be told apart.
+		delete(expected, "ungoogled-chromium")
+	}
+
+	for id, argPrefix := range expected {
+		var found bool
+		for _, kb := range knownBrowsers {
+			if kb.browserID != id {
+				continue
+			}
+			found = true
+			if !strings.HasPrefix(kb.profileArg, argPrefix) {
+				t.Errorf("%s: profileArg = %q, want prefix %q", id, kb.profileArg, argPrefix)
+			}
+		}
+		if !found {
+			t.Errorf("known browser %q is missing", id)
+		}
+	}
+}
+
+func TestDiscoverFirefoxForkProfiles(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("profile paths are Linux-specific")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	ini := "[Profile0]\nName=default-release\nIsRelative=1\nPath=abcd.default-release\n"
+	if err := os.MkdirAll(filepath.Join(home, ".librewolf"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".librewolf", "profiles.ini"), []byte(ini), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	detector, err := NewDetector()
+	if err != nil {
+		t.Fatalf("NewDetector() error = %v", err)
+	}
+	profiles, err := detector.DiscoverProfiles(config.Browser{Name: "LibreWolf", BrowserID: "librewolf", ProfileArg: "-P %s"})
+	if err != nil {
+		t.Fatalf("DiscoverProfiles() error = %v", err)
+	}
+	if len(profiles) != 1 || profiles[0].ID != "librewolf-default-release" {
+		t.Errorf("Unexpected profiles: %+v", profiles)
+	}
+}
+
+func TestDiscoverUserDataDirProfiles(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "Chrome Portable")
+	prefs := map[string]string{
+		"Default":        "{}",
+		"Profile 1":      `{"account_info":[{"email":"me@example.com","full_name":"Me"}]}`,
+		"System Profile": "{}",
+	}
+	for name, content := range prefs {
+		if err := os.MkdirAll(filepath.Join(dataDir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dataDir, name, "Preferences"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(dataDir, "Crashpad"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	chrome := config.Browser{BrowserID: "chrome", ProfileArg: "--profile-directory=%s"}
+	profiles, err := DiscoverUserDataDirProfiles(chrome, dataDir)
+	if err != nil {
+		t.Fatalf("DiscoverUserDataDirProfiles() error = %v", err)
+	}
+	var ids []string
+	for _, p := range profiles {
+		ids = append(ids, p.ID)
+		if p.UserDataDir != dataDir {
+			t.Errorf("profile %s has UserDataDir %q, want %q", p.ID, p.UserDataDir, dataDir)
+		}
+	}
+	sort.Strings(ids)
+	if want := "chrome-chrome-portable-default-e3b0c4,chrome-chrome-portable-profile-1-e3b0c4"; strings.Join(ids, ",") != want {
+		t.Errorf("profile IDs = %v, want %s", ids, want)
+	}
+	for _, p := range profiles {
+		if want := map[string]string{"Profile 1": "me@example.com"}[p.ProfileDir]; p.Email != want {
+			t.Errorf("profile %s has Email %q, want %q", p.ID, p.Email, want)
+		}
+	}
+
+	if _, err := DiscoverUserDataDirProfiles(config.Browser{BrowserID: "firefox", ProfileArg: "-P %s"}, dataDir); err == nil {
+		t.Error("expected an error for a non-Chromium browser")
+	}
+	if _, err := DiscoverUserDataDirProfiles(chrome, filepath.Join(dataDir, "Crashpad")); err == nil {
+		t.Error("expected an error for a directory without profiles")
+	}
+}
+
+func TestCreateChromiumProfile(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "Chrome Work")
+	if err := os.MkdirAll(filepath.Join(dataDir, "Profile 1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	localState := `{"browser":{"enabled_labs_experiments":[]},"profile":{"info_cache":{"Default":{"name":"Person 1"},"Profile 2":{"name":"Old"}},"profiles_order":["Default","Profile 2"]}}`
+	if err := os.WriteFile(filepath.Join(dataDir, "Local State"), []byte(localState), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	chrome := config.Browser{BrowserID: "chrome", ProfileArg: "--profile-directory=%s"}
+	profile, err := CreateProfile(chrome, "Work", dataDir)
+	if err != nil {
+		t.Fatalf("CreateProfile() error = %v", err)
+	}
+	// Profile 1 exists on disk and Profile 2 in Local State, so Profile 3 is used
+	want := config.Profile{ID: "chrome-chrome-work-profile-3-e3b0c4", Name: "Work", BrowserID: "chrome", ProfileDir: "Profile 3", UserDataDir: dataDir}
+	if !reflect.DeepEqual(profile, want) {
+		t.Errorf("CreateProfile() = %+v, want %+v", profile, want)
+	}
+	prefs, err := os.ReadFile(filepath.Join(dataDir, "Profile 3", "Preferences"))
+	if err != nil || !strings.Contains(string(prefs), `"name":"Work"`) {
+		t.Errorf("Preferences = %q, %v; want the profile name", prefs, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dataDir, "Local State"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`"Profile 3":{"is_using_default_name":false,"name":"Work"}`, `"profiles_order":["Default","Profile 2","Profile 3"]`, `"enabled_labs_experiments":[]`} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("Local State = %s, missing %s", data, s)
+		}
+	}
+
+	// Names must be unique, and other browsers are not supported
+	if _, err := CreateProfile(chrome, "work", dataDir); err == nil {
+		t.Error("CreateProfile() with a duplicate name succeeded")
+	}
+	if _, err := CreateProfile(config.Browser{BrowserID: "safari"}, "Work", ""); err == nil {
+		t.Error("CreateProfile() for a browser without profiles succeeded")
+	}
+}
+
+func TestCreateFirefoxProfile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell script as the browser")
+	}
+	dir := t.TempDir()
+	firefox := filepath.Join(dir, "firefox")
+	script := fmt.Sprintf("#!/bin/sh\necho \"$@\" > %s/args\n", dir)
+	if err := os.WriteFile(firefox, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	browser := config.Browser{BrowserID: "firefox", Executable: firefox, ProfileArg: "-P %s"}
+	profile, err := CreateProfile(browser, "Banking", "")
+	if err != nil {
+		t.Fatalf("CreateProfile() error = %v", err)
+	}
+	want := config.Profile{Name: "Banking", BrowserID: "firefox", ProfileDir: "Banking"}
+	want.ID = ProfileID(browser, want)
+	if !reflect.DeepEqual(profile, want) {
+		t.Errorf("CreateProfile() = %+v, want %+v", profile, want)
+	}
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	if strings.TrimSpace(string(args)) != "-CreateProfile Banking" {
+		t.Errorf("browser run with %q, want -CreateProfile Banking", args)
+	}
+
+	if err := os.WriteFile(firefox, []byte("#!/bin/sh\necho 'profile exists' >&2\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateProfile(config.Browser{BrowserID: "firefox", Executable: firefox, ProfileArg: "-P %s"}, "Banking", ""); err == nil || !strings.Contains(err.Error(), "profile exists") {
+		t.Errorf("CreateProfile() error = %v, want the browser's output", err)
+	}
+}
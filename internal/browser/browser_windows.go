@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/jmylchreest/rurl/internal/config"
@@ -31,6 +32,7 @@ type knownBrowserInfo struct {
 	profileArg   string // Command line arg for profile
 	incognitoArg string // Command line arg for incognito
 	firefoxIni   bool   // true if it uses Firefox profiles.ini
+	scoopApp     string // Name of the Scoop app installing it (optional)
 }
 
 // knownBrowsers contains the list of supported browsers and their configurations
@@ -43,6 +45,7 @@ var knownBrowsers = []knownBrowserInfo{
 		appDataPath:  `Google\Chrome\User Data`,
 		profileArg:   "--profile-directory=",
 		incognitoArg: "--incognito",
+		scoopApp:     "googlechrome",
 	},
 	{
 		name:         "Google Chrome Beta",
@@ -51,6 +54,7 @@ var knownBrowsers = []knownBrowserInfo{
 		appDataPath:  `Google\Chrome Beta\User Data`,
 		profileArg:   "--profile-directory=",
 		incognitoArg: "--incognito",
+		scoopApp:     "googlechrome-beta",
 	},
 	{
 		name:         "Google Chrome Dev",
@@ -59,6 +63,7 @@ var knownBrowsers = []knownBrowserInfo{
 		appDataPath:  `Google\Chrome Dev\User Data`,
 		profileArg:   "--profile-directory=",
 		incognitoArg: "--incognito",
+		scoopApp:     "googlechrome-dev",
 	},
 	{
 		name:         "Google Chrome Canary",
@@ -67,6 +72,7 @@ var knownBrowsers = []knownBrowserInfo{
 		appDataPath:  `Google\Chrome SxS\User Data`,
 		profileArg:   "--profile-directory=",
 		incognitoArg: "--incognito",
+		scoopApp:     "googlechrome-canary",
 	},
 	// Microsoft Edge
 	{
@@ -110,6 +116,7 @@ var knownBrowsers = []knownBrowserInfo{
 		firefoxIni:   true,
 		profileArg:   "-P",
 		incognitoArg: "--private-window",
+		scoopApp:     "firefox",
 	},
 	{
 		name:         "Firefox Developer Edition",
@@ -119,6 +126,7 @@ var knownBrowsers = []knownBrowserInfo{
 		firefoxIni:   true,
 		profileArg:   "-P",
 		incognitoArg: "--private-window",
+		scoopApp:     "firefox-developer",
 	},
 	{
 		name:         "Firefox Nightly",
@@ -128,6 +136,48 @@ var knownBrowsers = []knownBrowserInfo{
 		firefoxIni:   true,
 		profileArg:   "-P",
 		incognitoArg: "--private-window",
+		scoopApp:     "firefox-nightly",
+	},
+	// Firefox forks
+	{
+		name:         "LibreWolf",
+		browserID:    "librewolf",
+		executable:   "file://librewolf.exe",
+		appDataPath:  `librewolf`,
+		firefoxIni:   true,
+		profileArg:   "-P",
+		incognitoArg: "--private-window",
+		scoopApp:     "librewolf",
+	},
+	{
+		name:         "Waterfox",
+		browserID:    "waterfox",
+		executable:   "file://waterfox.exe",
+		appDataPath:  `Waterfox`,
+		firefoxIni:   true,
+		profileArg:   "-P",
+		incognitoArg: "--private-window",
+		scoopApp:     "waterfox",
+	},
+	{
+		name:         "Zen Browser",
+		browserID:    "zen",
+		executable:   "file://zen.exe",
+		appDataPath:  `zen`,
+		firefoxIni:   true,
+		profileArg:   "-P",
+		incognitoArg: "--private-window",
+		scoopApp:     "zen-browser",
+	},
+	{
+		name:         "Floorp",
+		browserID:    "floorp",
+		executable:   "file://floorp.exe",
+		appDataPath:  `Floorp`,
+		firefoxIni:   true,
+		profileArg:   "-P",
+		incognitoArg: "--private-window",
+		scoopApp:     "floorp",
 	},
 	// Brave
 	{
@@ -137,6 +187,7 @@ var knownBrowsers = []knownBrowserInfo{
 		appDataPath:  `BraveSoftware\Brave-Browser\User Data`,
 		profileArg:   "--profile-directory=",
 		incognitoArg: "--incognito",
+		scoopApp:     "brave",
 	},
 	// Vivaldi
 	{
@@ -146,6 +197,27 @@ var knownBrowsers = []knownBrowserInfo{
 		appDataPath:  `Vivaldi\User Data`,
 		profileArg:   "--profile-directory=",
 		incognitoArg: "--incognito",
+		scoopApp:     "vivaldi",
+	},
+	// Thorium
+	{
+		name:         "Thorium",
+		browserID:    "thorium",
+		executable:   "file://thorium.exe",
+		appDataPath:  `Thorium\User Data`,
+		profileArg:   "--profile-directory=",
+		incognitoArg: "--incognito",
+		scoopApp:     "thorium",
+	},
+	// Ungoogled Chromium (installs as Chromium\Application\chrome.exe)
+	{
+		name:         "Ungoogled Chromium",
+		browserID:    "ungoogled-chromium",
+		executable:   `file://Chromium\Application\chrome.exe`,
+		appDataPath:  `Chromium\User Data`,
+		profileArg:   "--profile-directory=",
+		incognitoArg: "--incognito",
+		scoopApp:     "ungoogled-chromium",
 	},
 	// Arc
 	{
@@ -158,10 +230,83 @@ var knownBrowsers = []knownBrowserInfo{
 	},
 }
 
-// findExecutable tries to find the executable for a browser
-func findExecutable(executable string) string {
+// installRoots returns the directories browsers are installed in: the Program Files
+// directories (including the native ones of 64-bit and ARM64 Windows, which a process
+// running under emulation does not get as ProgramFiles) and the per-user ones in
+// LOCALAPPDATA, which installers without administrator rights use.
+func installRoots() []string {
+	var roots []string
+	for _, env := range []string{"ProgramFiles", "ProgramW6432", "ProgramFiles(Arm)", "ProgramFiles(x86)"} {
+		if dir := os.Getenv(env); dir != "" && !slices.Contains(roots, dir) {
+			roots = append(roots, dir)
+		}
+	}
+	if localAppData := os.Getenv("LOCALAPPDATA"); localAppData != "" {
+		roots = append(roots, localAppData, filepath.Join(localAppData, "Programs"))
+	}
+	return roots
+}
+
+// scoopRoots returns the Scoop installation directories: the user's (SCOOP, by default
+// %USERPROFILE%\scoop) and the global one (SCOOP_GLOBAL, by default %ProgramData%\scoop).
+func scoopRoots() []string {
+	var roots []string
+	if dir := os.Getenv("SCOOP"); dir != "" {
+		roots = append(roots, dir)
+	} else if home := os.Getenv("USERPROFILE"); home != "" {
+		roots = append(roots, filepath.Join(home, "scoop"))
+	}
+	if dir := os.Getenv("SCOOP_GLOBAL"); dir != "" {
+		roots = append(roots, dir)
+	} else if programData := os.Getenv("ProgramData"); programData != "" {
+		roots = append(roots, filepath.Join(programData, "scoop"))
+	}
+	return roots
+}
+
+// shimDirs returns the directories of the shims package managers put in front of the
+// programs they install: Scoop's shims and Chocolatey's bin directory
+// (ChocolateyInstall, by default %ProgramData%\chocolatey).
+func shimDirs() []string {
+	var dirs []string
+	for _, root := range scoopRoots() {
+		dirs = append(dirs, filepath.Join(root, "shims"))
+	}
+	if dir := os.Getenv("ChocolateyInstall"); dir != "" {
+		dirs = append(dirs, filepath.Join(dir, "bin"))
+	} else if programData := os.Getenv("ProgramData"); programData != "" {
+		dirs = append(dirs, filepath.Join(programData, "chocolatey", "bin"))
+	}
+	return dirs
+}
+
+// findScoopExecutable returns the executable called name of the Scoop app, in the
+// app's current version directory or a directory below it, or "" if it is not installed.
+func findScoopExecutable(app, name string) string {
+	for _, root := range scoopRoots() {
+		current := filepath.Join(root, "apps", app, "current")
+		if exePath := filepath.Join(current, name); fileExists(exePath) {
+			return exePath
+		}
+		if matches, _ := filepath.Glob(filepath.Join(current, "*", name)); len(matches) > 0 {
+			return matches[0]
+		}
+	}
+	return ""
+}
+
+// fileExists reports whether path exists.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// findExecutable tries to find the executable for a browser: in the install roots,
+// then as the browser's Scoop app, then as a package manager shim, on the PATH or in
+// the App Paths registry key.
+func findExecutable(info knownBrowserInfo) string {
 	// Split the URI into scheme and path
-	parts := strings.SplitN(executable, "://", 2)
+	parts := strings.SplitN(info.executable, "://", 2)
 	if len(parts) != 2 {
 		return ""
 	}
@@ -170,20 +315,7 @@ func findExecutable(executable string) string {
 	switch scheme {
 	case "file":
 		// Search in common locations
-		programFiles := os.Getenv("ProgramFiles")
-		programFilesX86 := os.Getenv("ProgramFiles(x86)")
-		localAppData := os.Getenv("LOCALAPPDATA")
-
-		searchPaths := []string{}
-		if programFiles != "" {
-			searchPaths = append(searchPaths, programFiles)
-		}
-		if programFilesX86 != "" && programFilesX86 != programFiles {
-			searchPaths = append(searchPaths, programFilesX86)
-		}
-		if localAppData != "" {
-			searchPaths = append(searchPaths, localAppData)
-		}
+		searchPaths := installRoots()
 
 		// Construct potential paths
 		potentialDirs := []string{
@@ -193,6 +325,27 @@ func findExecutable(executable string) string {
 			filepath.Join("BraveSoftware", "Brave-Browser", "Application"),
 			filepath.Join("Vivaldi", "Application"),
 			filepath.Join("Arc", "Application"),
+			filepath.Join("LibreWolf"),
+			filepath.Join("Waterfox"),
+			filepath.Join("Zen Browser"),
+			filepath.Join("Ablaze Floorp"),
+			filepath.Join("Thorium", "Application"),
+		}
+
+		// Paths with a directory component (e.g. Chromium\Application\chrome.exe) identify
+		// a specific install and are only resolved against the install roots, so that a
+		// bare executable name shared by several browsers cannot match the wrong one.
+		if strings.ContainsAny(path, `\/`) {
+			for _, base := range searchPaths {
+				exePath := filepath.Join(base, path)
+				if _, err := os.Stat(exePath); err == nil {
+					return exePath
+				}
+			}
+			if info.scoopApp != "" {
+				return findScoopExecutable(info.scoopApp, filepath.Base(path))
+			}
+			return ""
 		}
 
 		for _, base := range searchPaths {
@@ -204,6 +357,21 @@ func findExecutable(executable string) string {
 			}
 		}
 
+		// Check Scoop, whose apps are not in any of the install roots
+		if info.scoopApp != "" {
+			if exePath := findScoopExecutable(info.scoopApp, path); exePath != "" {
+				return exePath
+			}
+		}
+
+		// Check the shims of Scoop and Chocolatey, which need not be on the PATH rurl
+		// gets when started by the system
+		for _, dir := range shimDirs() {
+			if exePath := filepath.Join(dir, path); fileExists(exePath) {
+				return exePath
+			}
+		}
+
 		// Check PATH if not found in common locations
 		if exePath, err := exec.LookPath(path); err == nil {
 			return exePath
@@ -233,7 +401,7 @@ func (d *windowsDetector) DiscoverBrowsers() ([]config.Browser, error) {
 
 	for _, browserInfo := range knownBrowsers {
 		// Find executable path
-		exePath := findExecutable(browserInfo.executable)
+		exePath := findExecutable(browserInfo)
 		if exePath == "" {
 			continue // Skip if not found
 		}
@@ -260,6 +428,32 @@ func (d *windowsDetector) DiscoverBrowsers() ([]config.Browser, error) {
 	return result, nil
 }
 
+// appDataProfileDir returns the directory holding the profiles of the known browser
+// info, looked for in both LOCALAPPDATA and APPDATA.
+func appDataProfileDir(info *knownBrowserInfo) (string, error) {
+	for _, baseDir := range []string{os.Getenv("LOCALAPPDATA"), os.Getenv("APPDATA")} {
+		if baseDir == "" {
+			continue
+		}
+		potentialPath := filepath.Join(baseDir, info.appDataPath)
+		if _, err := os.Stat(potentialPath); err == nil {
+			return potentialPath, nil
+		}
+	}
+	return "", fmt.Errorf("could not find profile directory in either APPDATA or LOCALAPPDATA")
+}
+
+// ProfileRoot returns the directory holding browser's profiles (the user data
+// directory of Chromium-based browsers).
+func (d *windowsDetector) ProfileRoot(browser config.Browser) (string, error) {
+	for i := range knownBrowsers {
+		if knownBrowsers[i].browserID == browser.BrowserID && knownBrowsers[i].appDataPath != "" {
+			return appDataProfileDir(&knownBrowsers[i])
+		}
+	}
+	return "", fmt.Errorf("profile directory of browser '%s' is not known", browser.BrowserID)
+}
+
 // DiscoverProfiles finds profiles for a given browser on Windows.
 func (d *windowsDetector) DiscoverProfiles(browser config.Browser) ([]config.Profile, error) {
 	profiles := []config.Profile{}
@@ -278,26 +472,9 @@ func (d *windowsDetector) DiscoverProfiles(browser config.Browser) ([]config.Pro
 		return profiles, fmt.Errorf("profile discovery not supported for browser ID %s, Name %s", browser.BrowserID, browser.Name)
 	}
 
-	// Try both APPDATA and LOCALAPPDATA locations
-	baseDirs := []string{
-		os.Getenv("LOCALAPPDATA"),
-		os.Getenv("APPDATA"),
-	}
-
-	var profileBaseDir string
-	for _, baseDir := range baseDirs {
-		if baseDir == "" {
-			continue
-		}
-		potentialPath := filepath.Join(baseDir, info.appDataPath)
-		if _, err := os.Stat(potentialPath); err == nil {
-			profileBaseDir = potentialPath
-			break
-		}
-	}
-
-	if profileBaseDir == "" {
-		return profiles, fmt.Errorf("could not find profile directory in either APPDATA or LOCALAPPDATA")
+	profileBaseDir, err := appDataProfileDir(info)
+	if err != nil {
+		return profiles, err
 	}
 
 	if info.firefoxIni {
@@ -360,14 +537,16 @@ func (d *windowsDetector) DiscoverProfiles(browser config.Browser) ([]config.Pro
 				// Check if it looks like a profile directory ("Default" or "Profile <N>")
 				if dirName == "Default" || strings.HasPrefix(dirName, "Profile ") {
 					// Basic check for a common file to ensure it's likely a valid profile
-					if _, err := os.Stat(filepath.Join(profileBaseDir, dirName, "Preferences")); err == nil {
+					prefsPath := filepath.Join(profileBaseDir, dirName, "Preferences")
+					if _, err := os.Stat(prefsPath); err == nil {
 						profileID := fmt.Sprintf("%s-%s", info.browserID, strings.ToLower(strings.ReplaceAll(dirName, " ", "")))
 						profileName := fmt.Sprintf("%s (%s)", browser.Name, dirName)
 						profiles = append(profiles, config.Profile{
 							ID:         profileID,
 							Name:       profileName,
-							BrowserID:  browser.Name,
+							BrowserID:  browser.BrowserID,
 							ProfileDir: dirName,
+							Email:      ChromiumProfileEmail(prefsPath),
 						})
 					}
 				}
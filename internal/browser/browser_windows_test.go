@@ -0,0 +1,87 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+)
+
+func TestWindowsDetectorOutput(t *testing.T) {
+	localAppData, appData := t.TempDir(), t.TempDir()
+	files := map[string]string{
+		filepath.Join(localAppData, "Google", "Chrome", "User Data", "Default", "Preferences"):   "{}",
+		filepath.Join(localAppData, "Google", "Chrome", "User Data", "Profile 1", "Preferences"): "{}",
+		filepath.Join(appData, "Mozilla", "Firefox", "profiles.ini"):                             "[Profile0]\r\nName=work\r\nIsRelative=1\r\nPath=Profiles/abcd.work\r\n",
+		filepath.Join(appData, "Mozilla", "Firefox", "Profiles", "abcd.work", "prefs.js"):        "",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("LOCALAPPDATA", localAppData)
+	t.Setenv("APPDATA", appData)
+
+	detector := &windowsDetector{}
+	for _, b := range []config.Browser{
+		{Name: "Google Chrome", BrowserID: "chrome"},
+		{Name: "Mozilla Firefox", BrowserID: "firefox"},
+	} {
+		profiles, err := detector.DiscoverProfiles(b)
+		if err != nil {
+			t.Errorf("DiscoverProfiles(%s) error = %v", b.BrowserID, err)
+			continue
+		}
+		checkDetectorOutput(t, b, profiles)
+	}
+}
+
+func TestWindowsFindExecutable(t *testing.T) {
+	programFiles, arm, localAppData, home, programData := t.TempDir(), t.TempDir(), t.TempDir(), t.TempDir(), t.TempDir()
+	for _, env := range []string{"ProgramFiles", "ProgramW6432", "ProgramFiles(x86)", "SCOOP", "SCOOP_GLOBAL", "ChocolateyInstall", "PATH"} {
+		t.Setenv(env, "")
+	}
+	t.Setenv("ProgramFiles", programFiles)
+	t.Setenv("ProgramFiles(Arm)", arm)
+	t.Setenv("LOCALAPPDATA", localAppData)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("ProgramData", programData)
+
+	files := []string{
+		filepath.Join(arm, "Microsoft", "Edge", "Application", "msedge.exe"),
+		filepath.Join(localAppData, "Programs", "Zen Browser", "zen.exe"),
+		filepath.Join(home, "scoop", "apps", "vivaldi", "current", "Application", "vivaldi.exe"),
+		filepath.Join(home, "scoop", "apps", "ungoogled-chromium", "current", "chrome.exe"),
+		filepath.Join(programData, "chocolatey", "bin", "librewolf.exe"),
+	}
+	for _, path := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{
+		"edge":               files[0],
+		"zen":                files[1],
+		"vivaldi":            files[2],
+		"ungoogled-chromium": files[3],
+		"librewolf":          files[4],
+	}
+	for _, info := range knownBrowsers {
+		expected, ok := want[info.browserID]
+		if !ok {
+			continue
+		}
+		if got := findExecutable(info); got != expected {
+			t.Errorf("findExecutable(%s) = %q, want %q", info.browserID, got, expected)
+		}
+	}
+}
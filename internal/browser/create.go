@@ -0,0 +1,184 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// createProfileTimeout limits how long a browser may take to create a profile.
+const createProfileTimeout = 30 * time.Second
+
+// ProfileRootFinder is implemented by detectors that know where a browser keeps its
+// profiles, which CreateProfile needs for Chromium-based browsers.
+type ProfileRootFinder interface {
+	ProfileRoot(browser config.Browser) (string, error)
+}
+
+// CreateProfile creates a new profile called name in browser and returns it, ready to
+// be added to the configuration. Chromium-based browsers get a new "Profile N"
+// directory in dataDir, or in their default user data directory if dataDir is empty;
+// they should not be running, as they rewrite their Local State file on exit. Firefox
+// creates the profile itself (firefox -CreateProfile).
+func CreateProfile(browser config.Browser, name, dataDir string) (config.Profile, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return config.Profile{}, fmt.Errorf("profile name cannot be empty")
+	}
+	if browser.Remote != nil {
+		return config.Profile{}, fmt.Errorf("profiles cannot be created in remote browser '%s'", browser.BrowserID)
+	}
+
+	switch {
+	case strings.Contains(browser.ProfileArg, "--profile-directory"):
+		defaultDir := dataDir == ""
+		if defaultDir {
+			detector, err := GetDetector()
+			if err != nil {
+				return config.Profile{}, err
+			}
+			finder, ok := detector.(ProfileRootFinder)
+			if !ok {
+				return config.Profile{}, fmt.Errorf("profile directory of browser '%s' is not known", browser.BrowserID)
+			}
+			if dataDir, err = finder.ProfileRoot(browser); err != nil {
+				return config.Profile{}, err
+			}
+		}
+		dir, err := createChromiumProfile(dataDir, name)
+		if err != nil {
+			return config.Profile{}, err
+		}
+		profile := config.Profile{
+			Name:       name,
+			BrowserID:  browser.BrowserID,
+			ProfileDir: dir,
+		}
+		if !defaultDir {
+			profile.UserDataDir = dataDir
+		}
+		profile.ID = ProfileID(browser, profile)
+		return profile, nil
+	case strings.HasPrefix(browser.ProfileArg, "-P"):
+		if dataDir != "" {
+			return config.Profile{}, fmt.Errorf("browser '%s' does not support user data directories (Chromium-based browsers only)", browser.BrowserID)
+		}
+		if err := createFirefoxProfile(browser, name); err != nil {
+			return config.Profile{}, err
+		}
+		profile := config.Profile{
+			Name:       name,
+			BrowserID:  browser.BrowserID,
+			ProfileDir: name, // Selected by name with -P
+		}
+		profile.ID = ProfileID(browser, profile)
+		return profile, nil
+	default:
+		return config.Profile{}, fmt.Errorf("creating profiles is not supported for browser '%s' (Chromium- and Firefox-based browsers only)", browser.BrowserID)
+	}
+}
+
+// createChromiumProfile creates the next free "Profile N" directory in the Chromium
+// user data directory dataDir with a Preferences file naming it, registers it in the
+// Local State file, and returns the directory name.
+func createChromiumProfile(dataDir, name string) (string, error) {
+	localStatePath := filepath.Join(dataDir, "Local State")
+	localState := make(map[string]any)
+	data, err := os.ReadFile(localStatePath)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &localState); err != nil {
+			return "", fmt.Errorf("failed to parse %s: %w", localStatePath, err)
+		}
+	case !os.IsNotExist(err):
+		return "", fmt.Errorf("failed to read %s: %w", localStatePath, err)
+	}
+
+	profileState, _ := localState["profile"].(map[string]any)
+	if profileState == nil {
+		profileState = make(map[string]any)
+		localState["profile"] = profileState
+	}
+	infoCache, _ := profileState["info_cache"].(map[string]any)
+	if infoCache == nil {
+		infoCache = make(map[string]any)
+		profileState["info_cache"] = infoCache
+	}
+	for dir, info := range infoCache {
+		if entry, ok := info.(map[string]any); ok && strings.EqualFold(fmt.Sprint(entry["name"]), name) {
+			return "", fmt.Errorf("a profile named '%s' already exists (%s)", name, dir)
+		}
+	}
+
+	var dir string
+	for n := 1; ; n++ {
+		dir = fmt.Sprintf("Profile %d", n)
+		if _, taken := infoCache[dir]; taken {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dataDir, dir)); os.IsNotExist(err) {
+			break
+		}
+	}
+
+	prefs, err := json.Marshal(map[string]any{"profile": map[string]any{"name": name}})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode profile preferences: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dataDir, dir), 0700); err != nil {
+		return "", fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, dir, "Preferences"), prefs, 0600); err != nil {
+		return "", fmt.Errorf("failed to write profile preferences: %w", err)
+	}
+
+	infoCache[dir] = map[string]any{"name": name, "is_using_default_name": false}
+	if order, ok := profileState["profiles_order"].([]any); ok {
+		profileState["profiles_order"] = append(order, dir)
+	}
+	data, err = json.Marshal(localState)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s: %w", localStatePath, err)
+	}
+	tmp := localStatePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", localStatePath, err)
+	}
+	if err := os.Rename(tmp, localStatePath); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to write %s: %w", localStatePath, err)
+	}
+	log.Debug().Str("user_data_dir", dataDir).Str("profile_dir", dir).Str("name", name).Msg("Created Chromium profile")
+	return dir, nil
+}
+
+// createFirefoxProfile runs the browser with -CreateProfile to create a profile called
+// name in its default profile directory.
+func createFirefoxProfile(browser config.Browser, name string) error {
+	if browser.Executable == "" {
+		return fmt.Errorf("browser '%s' has no executable configured", browser.BrowserID)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), createProfileTimeout)
+	defer cancel()
+
+	// Flatpak apps are configured as "flatpak run <app>"
+	args := strings.Fields(browser.Executable)
+	if !strings.HasPrefix(browser.Executable, "flatpak run ") {
+		args = []string{browser.Executable}
+	}
+	args = append(args, "-CreateProfile", name)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	log.Debug().Strs("args", cmd.Args).Msg("Creating Firefox profile")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create profile with %s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
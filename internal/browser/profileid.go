@@ -0,0 +1,25 @@
+package browser
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+
+	"github.com/jmylchreest/rurl/internal/config"
+)
+
+// ProfileID returns the ID detection gives profile of browser. It is derived from data
+// that does not change when the profile is renamed in the browser: the profile
+// directory (and user data directory, if any), and a hash of the browser's executable
+// path telling installs apart. E.g. "chrome-profile-1-3fa9c2".
+func ProfileID(browser config.Browser, profile config.Profile) string {
+	dir := "default"
+	if profile.ProfileDir != "" {
+		dir = profileIDPart(filepath.Base(filepath.Clean(profile.ProfileDir)))
+	}
+	if profile.UserDataDir != "" {
+		dir = profileIDPart(filepath.Base(filepath.Clean(profile.UserDataDir))) + "-" + dir
+	}
+	sum := sha256.Sum256([]byte(browser.Executable))
+	return fmt.Sprintf("%s-%s-%x", browser.BrowserID, dir, sum[:3])
+}
@@ -2,9 +2,14 @@ package browser
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+
+	"github.com/rs/zerolog/log"
 )
 
 // FirefoxProfileInfo holds temporary parsed data from profiles.ini
@@ -95,3 +100,77 @@ func ParseProfilesIni(iniPath string) ([]FirefoxProfileInfo, error) {
 
 	return result, nil
 }
+
+// chromiumLocalState holds the parts of a Chromium user data directory's Local State
+// file that detection reads.
+type chromiumLocalState struct {
+	Profile struct {
+		InfoCache map[string]ChromiumProfileInfo `json:"info_cache"`
+	} `json:"profile"`
+}
+
+// ChromiumProfileInfo is what Chromium's Local State records about a profile.
+type ChromiumProfileInfo struct {
+	Name       string `json:"name"`        // Name shown in the browser's profile picker
+	AvatarIcon string `json:"avatar_icon"` // e.g. "chrome://theme/IDR_PROFILE_AVATAR_26"
+}
+
+// AvatarIndex returns the index of the profile's built-in avatar icon, or nil if it
+// uses none (e.g. a Google account picture).
+func (i ChromiumProfileInfo) AvatarIndex() *int {
+	_, digits, ok := strings.Cut(i.AvatarIcon, "IDR_PROFILE_AVATAR_")
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+// ChromiumProfileInfos returns what the Local State file of the Chromium user data
+// directory dataDir records about its profiles, by profile directory. It returns nil if
+// the file cannot be read.
+func ChromiumProfileInfos(dataDir string) map[string]ChromiumProfileInfo {
+	path := filepath.Join(dataDir, "Local State")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var state chromiumLocalState
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Debug().Err(err).Str("path", path).Msg("Could not parse Local State")
+		return nil
+	}
+	return state.Profile.InfoCache
+}
+
+// chromiumPreferences holds the parts of a Chromium profile's Preferences file that
+// detection reads.
+type chromiumPreferences struct {
+	AccountInfo []struct {
+		Email string `json:"email"`
+	} `json:"account_info"`
+}
+
+// ChromiumProfileEmail returns the email of the account signed in to the Chromium
+// profile whose Preferences file is at prefsPath, or "" if none is signed in or the
+// file cannot be read.
+func ChromiumProfileEmail(prefsPath string) string {
+	data, err := os.ReadFile(prefsPath)
+	if err != nil {
+		return ""
+	}
+	var prefs chromiumPreferences
+	if err := json.Unmarshal(data, &prefs); err != nil {
+		log.Debug().Err(err).Str("path", prefsPath).Msg("Could not parse profile preferences")
+		return ""
+	}
+	for _, account := range prefs.AccountInfo {
+		if account.Email != "" {
+			return account.Email // The primary account comes first
+		}
+	}
+	return ""
+}
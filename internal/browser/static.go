@@ -0,0 +1,24 @@
+package browser
+
+import "github.com/jmylchreest/rurl/internal/config"
+
+// StaticDetector is a Detector returning preconfigured results, e.g. to run detection
+// against a known set of browsers (see RegisterDetector) whatever the system has.
+type StaticDetector struct {
+	Browsers      []config.Browser
+	Profiles      map[string][]config.Profile // Keyed by BrowserID
+	ProfileErrors map[string]error            // Returned by DiscoverProfiles for the given BrowserID
+}
+
+// DiscoverBrowsers returns the configured browsers.
+func (d *StaticDetector) DiscoverBrowsers() ([]config.Browser, error) {
+	return d.Browsers, nil
+}
+
+// DiscoverProfiles returns the configured profiles of browser, or its configured error.
+func (d *StaticDetector) DiscoverProfiles(browser config.Browser) ([]config.Profile, error) {
+	if err := d.ProfileErrors[browser.BrowserID]; err != nil {
+		return nil, err
+	}
+	return d.Profiles[browser.BrowserID], nil
+}
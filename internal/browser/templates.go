@@ -0,0 +1,79 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmylchreest/rurl/internal/config"
+)
+
+// Browser families with curated argument templates, see ArgTemplates.
+const (
+	FamilyChromium = "chromium" // Chrome, Chromium, Brave, Vivaldi, Edge, Opera, ...
+	FamilyFirefox  = "firefox"  // Firefox, LibreWolf, Waterfox, Floorp, Zen, ...
+	FamilyCustom   = "custom"   // No template; arguments are given explicitly
+)
+
+// ArgTemplate is the launch argument syntax shared by a family of browsers.
+type ArgTemplate struct {
+	Family       string
+	Description  string
+	ProfileArg   string
+	IncognitoArg string
+	NewWindowArg string
+	NewTabArg    string
+}
+
+// argTemplates are the curated templates, in the order they are listed.
+var argTemplates = []ArgTemplate{
+	{
+		Family:       FamilyChromium,
+		Description:  "Chromium-based browsers (Chrome, Brave, Vivaldi, Edge, Opera)",
+		ProfileArg:   "--profile-directory=%s",
+		IncognitoArg: "--incognito",
+		NewWindowArg: "--new-window",
+	},
+	{
+		Family:       FamilyFirefox,
+		Description:  "Firefox and its forks (LibreWolf, Waterfox, Floorp, Zen)",
+		ProfileArg:   "-P %s",
+		IncognitoArg: "--private-window",
+		NewWindowArg: "--new-window",
+		NewTabArg:    "--new-tab",
+	},
+	{
+		Family:      FamilyCustom,
+		Description: "No template, only the arguments given explicitly are changed",
+	},
+}
+
+// ArgTemplates returns the curated argument templates.
+func ArgTemplates() []ArgTemplate {
+	return append([]ArgTemplate(nil), argTemplates...)
+}
+
+// FindArgTemplate returns the template for family (case-insensitive).
+func FindArgTemplate(family string) (ArgTemplate, error) {
+	for _, t := range argTemplates {
+		if strings.EqualFold(t.Family, family) {
+			return t, nil
+		}
+	}
+	families := make([]string, 0, len(argTemplates))
+	for _, t := range argTemplates {
+		families = append(families, t.Family)
+	}
+	return ArgTemplate{}, fmt.Errorf("unknown browser family '%s' (expected one of: %s)", family, strings.Join(families, ", "))
+}
+
+// Apply sets b's launch arguments from the template. The custom template leaves b
+// unchanged.
+func (t ArgTemplate) Apply(b *config.Browser) {
+	if t.Family == FamilyCustom {
+		return
+	}
+	b.ProfileArg = t.ProfileArg
+	b.IncognitoArg = t.IncognitoArg
+	b.NewWindowArg = t.NewWindowArg
+	b.NewTabArg = t.NewTabArg
+}
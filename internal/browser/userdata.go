@@ -0,0 +1,59 @@
+package browser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// DiscoverUserDataDirProfiles finds the profiles in a Chromium user data directory other
+// than the browser's default one (e.g. a portable install or a mounted work VM). The
+// profiles are launched with --user-data-dir=dataDir, and their IDs include the
+// directory's name so they don't clash with the default directory's profiles (see
+// ProfileID).
+func DiscoverUserDataDirProfiles(browser config.Browser, dataDir string) ([]config.Profile, error) {
+	if !strings.Contains(browser.ProfileArg, "--profile-directory") {
+		return nil, fmt.Errorf("browser '%s' does not support user data directories (Chromium-based browsers only)", browser.BrowserID)
+	}
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user data directory: %w", err)
+	}
+
+	var profiles []config.Profile
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || name == "System Profile" || name == "Guest Profile" {
+			continue
+		}
+		prefsPath := filepath.Join(dataDir, name, "Preferences")
+		if _, err := os.Stat(prefsPath); err != nil {
+			continue
+		}
+		profile := config.Profile{
+			Name:        fmt.Sprintf("%s (%s)", name, filepath.Base(filepath.Clean(dataDir))),
+			BrowserID:   browser.BrowserID,
+			ProfileDir:  name,
+			UserDataDir: dataDir,
+			Email:       ChromiumProfileEmail(prefsPath),
+			Origin:      config.OriginDetected,
+		}
+		profile.ID = ProfileID(browser, profile)
+		profiles = append(profiles, profile)
+		log.Debug().Str("browser", browser.BrowserID).Str("user_data_dir", dataDir).Str("profile", name).Msg("Found profile")
+	}
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("no Chromium profiles found in %s", dataDir)
+	}
+	return profiles, nil
+}
+
+// profileIDPart turns a directory name into a profile ID component, as detection does
+// for profile directories (e.g. "Profile 1" becomes "profile-1").
+func profileIDPart(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, " ", "-"))
+}
@@ -0,0 +1,158 @@
+//go:build linux
+
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/wsl"
+	"github.com/rs/zerolog/log"
+)
+
+// wslBrowserIDPrefix marks browsers installed on the Windows host of a WSL
+// distribution, so they don't clash with the distribution's own browsers.
+const wslBrowserIDPrefix = "win-"
+
+// wslBrowserInfo holds information about Windows browsers detected from inside WSL.
+type wslBrowserInfo struct {
+	name         string   // User-friendly name (e.g., "Google Chrome")
+	browserID    string   // Stable ID, without wslBrowserIDPrefix
+	executables  []string // Paths relative to Program Files, Program Files (x86) or the user's AppData/Local
+	profileDir   string   // Path relative to the Windows user profile directory
+	profileArg   string   // Command line arg for profile
+	incognitoArg string   // Command line arg for incognito
+}
+
+// wslBrowsers contains the Windows browsers detected from inside WSL.
+var wslBrowsers = []wslBrowserInfo{
+	{
+		name:         "Google Chrome",
+		browserID:    "chrome",
+		executables:  []string{"Google/Chrome/Application/chrome.exe"},
+		profileDir:   "AppData/Local/Google/Chrome/User Data",
+		profileArg:   "--profile-directory=%s",
+		incognitoArg: "--incognito",
+	},
+	{
+		name:         "Microsoft Edge",
+		browserID:    "edge",
+		executables:  []string{"Microsoft/Edge/Application/msedge.exe"},
+		profileDir:   "AppData/Local/Microsoft/Edge/User Data",
+		profileArg:   "--profile-directory=%s",
+		incognitoArg: "--inprivate",
+	},
+	{
+		name:         "Brave",
+		browserID:    "brave",
+		executables:  []string{"BraveSoftware/Brave-Browser/Application/brave.exe"},
+		profileDir:   "AppData/Local/BraveSoftware/Brave-Browser/User Data",
+		profileArg:   "--profile-directory=%s",
+		incognitoArg: "--incognito",
+	},
+	{
+		name:         "Vivaldi",
+		browserID:    "vivaldi",
+		executables:  []string{"Vivaldi/Application/vivaldi.exe"},
+		profileDir:   "AppData/Local/Vivaldi/User Data",
+		profileArg:   "--profile-directory=%s",
+		incognitoArg: "--incognito",
+	},
+	{
+		name:         "Mozilla Firefox",
+		browserID:    "firefox",
+		executables:  []string{"Mozilla Firefox/firefox.exe"},
+		profileDir:   "AppData/Roaming/Mozilla/Firefox",
+		profileArg:   "-P %s",
+		incognitoArg: "--private-window",
+	},
+}
+
+// wslWindowsHome returns the Windows user profile directory, replaced in tests.
+var wslWindowsHome = wsl.WindowsHome
+
+// discoverWSLBrowsers finds browsers installed on the Windows host when running inside
+// WSL. They are launched through WSL interop by running their .exe directly.
+func (d *linuxDetector) discoverWSLBrowsers() []config.Browser {
+	if !wsl.IsWSL() {
+		return nil
+	}
+	home, err := wslWindowsHome()
+	if err != nil {
+		log.Debug().Err(err).Msg("Skipping Windows browser detection")
+		return nil
+	}
+	roots := []string{
+		filepath.Join(wsl.DriveRoot, "Program Files"),
+		filepath.Join(wsl.DriveRoot, "Program Files (x86)"),
+		filepath.Join(home, "AppData", "Local"),
+	}
+
+	var result []config.Browser
+	for _, info := range wslBrowsers {
+		exePath := findWSLExecutable(roots, info.executables)
+		if exePath == "" {
+			continue
+		}
+		result = append(result, config.Browser{
+			Name:         info.name + " (Windows)",
+			BrowserID:    wslBrowserIDPrefix + info.browserID,
+			Executable:   exePath,
+			ProfileArg:   info.profileArg,
+			IncognitoArg: info.incognitoArg,
+		})
+		log.Debug().Str("name", info.name).Str("path", exePath).Msg("Discovered Windows browser")
+	}
+	return result
+}
+
+// findWSLExecutable returns the first of executables found under one of roots.
+func findWSLExecutable(roots, executables []string) string {
+	for _, root := range roots {
+		for _, exe := range executables {
+			path := filepath.Join(root, filepath.FromSlash(exe))
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// discoverWSLProfiles finds the profiles of a Windows browser detected by
+// discoverWSLBrowsers. The second result is false if browser is not one of them.
+func (d *linuxDetector) discoverWSLProfiles(browser config.Browser) ([]config.Profile, bool) {
+	id, ok := strings.CutPrefix(browser.BrowserID, wslBrowserIDPrefix)
+	if !ok {
+		return nil, false
+	}
+	var info *wslBrowserInfo
+	for i := range wslBrowsers {
+		if wslBrowsers[i].browserID == id {
+			info = &wslBrowsers[i]
+			break
+		}
+	}
+	if info == nil {
+		return nil, false
+	}
+
+	home, err := wslWindowsHome()
+	if err != nil {
+		return d.createSingleDefaultProfile(browser.BrowserID, "Default"), true
+	}
+	profilesPath := filepath.Join(home, filepath.FromSlash(info.profileDir))
+
+	var profiles []config.Profile
+	if strings.Contains(info.profileArg, "-P") {
+		profiles, err = d.discoverFirefoxProfiles(profilesPath, browser.BrowserID)
+	} else {
+		profiles, err = d.discoverChromiumProfiles(profilesPath, browser.BrowserID)
+	}
+	if err != nil || len(profiles) == 0 {
+		return d.createSingleDefaultProfile(browser.BrowserID, "Default"), true
+	}
+	return profiles, true
+}
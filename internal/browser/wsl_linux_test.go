@@ -0,0 +1,73 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/wsl"
+)
+
+func TestDiscoverWSLBrowsers(t *testing.T) {
+	drive := t.TempDir()
+	home := filepath.Join(drive, "Users", "me")
+	files := map[string]string{
+		"Program Files/Mozilla Firefox/firefox.exe":                            "",
+		"Program Files/Google/Chrome/Application/chrome.exe":                   "",
+		"Users/me/AppData/Local/Microsoft/Edge/Application/msedge.exe":         "",
+		"Users/me/AppData/Local/Google/Chrome/User Data/Default/Preferences":   "{}",
+		"Users/me/AppData/Local/Google/Chrome/User Data/Profile 2/Preferences": "{}",
+		"Users/me/AppData/Roaming/Mozilla/Firefox/profiles.ini":                "[Profile0]\nName=work\nIsRelative=1\nPath=Profiles/abcd.work\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(drive, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+	wsl.DriveRoot = drive
+	wslWindowsHome = func() (string, error) { return home, nil }
+	t.Cleanup(func() {
+		wsl.DriveRoot = "/mnt/c"
+		wslWindowsHome = wsl.WindowsHome
+	})
+
+	d := &linuxDetector{}
+	browsers := d.discoverWSLBrowsers()
+	exes := make(map[string]string)
+	for _, b := range browsers {
+		exes[b.BrowserID] = b.Executable
+	}
+	if len(browsers) != 3 || exes["win-chrome"] != filepath.Join(drive, "Program Files", "Google", "Chrome", "Application", "chrome.exe") ||
+		!strings.HasSuffix(exes["win-edge"], "msedge.exe") || !strings.HasSuffix(exes["win-firefox"], "firefox.exe") {
+		t.Errorf("Unexpected browsers: %+v", browsers)
+	}
+
+	var profileIDs []string
+	for _, b := range browsers {
+		profiles, err := d.DiscoverProfiles(b)
+		if err != nil {
+			t.Fatalf("DiscoverProfiles(%s) error = %v", b.BrowserID, err)
+		}
+		for _, p := range profiles {
+			profileIDs = append(profileIDs, p.ID)
+		}
+	}
+	sort.Strings(profileIDs)
+	want := []string{"win-chrome-default", "win-chrome-profile-2", "win-edge", "win-firefox-work"}
+	if strings.Join(profileIDs, ",") != strings.Join(want, ",") {
+		t.Errorf("profile IDs = %v, want %v", profileIDs, want)
+	}
+
+	t.Setenv("WSL_DISTRO_NAME", "")
+	t.Setenv("WSL_INTEROP", "")
+	if !wsl.IsWSL() && len(d.discoverWSLBrowsers()) != 0 {
+		t.Error("Windows browsers detected outside WSL")
+	}
+}
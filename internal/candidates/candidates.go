@@ -0,0 +1,154 @@
+// Package candidates keeps track of domains that looked like URL shorteners
+// (they redirected to another site) so they can be reviewed and promoted to
+// manual shorteners.
+package candidates
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// NoRedirectTTL is how long a domain found not to redirect is remembered, so that it
+// is not probed again on every launch.
+const NoRedirectTTL = 30 * 24 * time.Hour
+
+// Candidate is a domain seen redirecting to another site. Domains that were checked
+// and did not redirect are kept as NoRedirect entries, which are not candidates.
+type Candidate struct {
+	Domain     string    `json:"domain"`
+	Count      int       `json:"count"` // Number of times the domain was seen
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+	ExampleURL string    `json:"example_url"`           // First URL seen redirecting
+	RedirectTo string    `json:"redirect_to"`           // Where ExampleURL redirected to
+	Dismissed  bool      `json:"dismissed,omitempty"`   // Reviewed and rejected; never suggested again
+	NoRedirect bool      `json:"no_redirect,omitempty"` // Checked at LastSeen without a redirect; not a candidate
+}
+
+// Pending reports whether c is a candidate awaiting review.
+func (c Candidate) Pending() bool {
+	return !c.Dismissed && !c.NoRedirect
+}
+
+// CheckedRecently reports whether c is a NoRedirect entry younger than NoRedirectTTL.
+func (c Candidate) CheckedRecently(now time.Time) bool {
+	return c.NoRedirect && now.Sub(c.LastSeen) < NoRedirectTTL
+}
+
+// DefaultPath returns the candidates file location in the user cache directory.
+func DefaultPath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "rurl", "shortener_candidates.json"), nil
+}
+
+// Load returns the candidates stored at path, most frequently seen first.
+// A missing file is not an error.
+func Load(path string) ([]Candidate, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read candidates '%s': %w", path, err)
+	}
+	var list []Candidate
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse candidates '%s': %w", path, err)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Count > list[j].Count })
+	return list, nil
+}
+
+// Save writes candidates to path, replacing its contents.
+func Save(path string, list []Candidate) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create candidates directory: %w", err)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode candidates: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write candidates: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write candidates: %w", err)
+	}
+	return nil
+}
+
+// Find returns the index of the candidate for domain, or -1.
+func Find(list []Candidate, domain string) int {
+	for i := range list {
+		if list[i].Domain == domain {
+			return i
+		}
+	}
+	return -1
+}
+
+// Record notes that exampleURL on domain redirected to redirectTo, adding the
+// domain as a candidate or updating its count.
+func Record(path, domain, exampleURL, redirectTo string, at time.Time) error {
+	list, err := Load(path)
+	if err != nil {
+		return err
+	}
+	list = pruneChecked(list, at)
+	if i := Find(list, domain); i >= 0 && !list[i].NoRedirect {
+		list[i].Count++
+		list[i].LastSeen = at
+	} else {
+		if i >= 0 {
+			list = append(list[:i], list[i+1:]...) // Now redirects; replaces the NoRedirect entry
+		}
+		list = append(list, Candidate{
+			Domain:     domain,
+			Count:      1,
+			FirstSeen:  at,
+			LastSeen:   at,
+			ExampleURL: exampleURL,
+			RedirectTo: redirectTo,
+		})
+	}
+	return Save(path, list)
+}
+
+// RecordNoRedirect notes that domain was checked at at and did not redirect, so it
+// is not probed again until NoRedirectTTL has passed.
+func RecordNoRedirect(path, domain string, at time.Time) error {
+	list, err := Load(path)
+	if err != nil {
+		return err
+	}
+	list = pruneChecked(list, at)
+	if i := Find(list, domain); i >= 0 {
+		if !list[i].NoRedirect {
+			return nil // Keep candidates (and dismissals) already recorded
+		}
+		list[i].LastSeen = at
+	} else {
+		list = append(list, Candidate{Domain: domain, FirstSeen: at, LastSeen: at, NoRedirect: true})
+	}
+	return Save(path, list)
+}
+
+// pruneChecked drops NoRedirect entries older than NoRedirectTTL.
+func pruneChecked(list []Candidate, now time.Time) []Candidate {
+	kept := list[:0]
+	for _, c := range list {
+		if !c.NoRedirect || c.CheckedRecently(now) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
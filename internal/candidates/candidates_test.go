@@ -0,0 +1,75 @@
+package candidates
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "candidates.json")
+
+	list, err := Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	first := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, Record(path, "lnk.example", "https://lnk.example/a", "https://target.example/", first))
+	require.NoError(t, Record(path, "go.example", "https://go.example/x", "https://other.example/", first))
+	require.NoError(t, Record(path, "go.example", "https://go.example/y", "", first.Add(time.Hour)))
+
+	list, err = Load(path)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	// Most frequently seen first; the first example is kept
+	assert.Equal(t, "go.example", list[0].Domain)
+	assert.Equal(t, 2, list[0].Count)
+	assert.Equal(t, "https://go.example/x", list[0].ExampleURL)
+	assert.Equal(t, "https://other.example/", list[0].RedirectTo)
+	assert.True(t, first.Equal(list[0].FirstSeen))
+	assert.True(t, first.Add(time.Hour).Equal(list[0].LastSeen))
+
+	list[1].Dismissed = true
+	require.NoError(t, Save(path, list))
+	list, err = Load(path)
+	require.NoError(t, err)
+	i := Find(list, "lnk.example")
+	require.GreaterOrEqual(t, i, 0)
+	assert.True(t, list[i].Dismissed)
+	assert.Equal(t, -1, Find(list, "missing.example"))
+}
+
+func TestRecordNoRedirect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "candidates.json")
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, RecordNoRedirect(path, "plain.example", at))
+	list, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.False(t, list[0].Pending())
+	assert.True(t, list[0].CheckedRecently(at.Add(time.Hour)))
+	assert.False(t, list[0].CheckedRecently(at.Add(NoRedirectTTL)))
+
+	// A later redirect turns the entry into a candidate
+	require.NoError(t, Record(path, "plain.example", "https://plain.example/a", "https://target.example/", at.Add(time.Hour)))
+	list, err = Load(path)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.True(t, list[0].Pending())
+	assert.Equal(t, 1, list[0].Count)
+
+	// Existing candidates are not downgraded, and expired checks are pruned
+	require.NoError(t, RecordNoRedirect(path, "plain.example", at.Add(2*time.Hour)))
+	require.NoError(t, RecordNoRedirect(path, "old.example", at))
+	require.NoError(t, RecordNoRedirect(path, "new.example", at.Add(NoRedirectTTL+time.Hour)))
+	list, err = Load(path)
+	require.NoError(t, err)
+	assert.True(t, list[Find(list, "plain.example")].Pending())
+	assert.Equal(t, -1, Find(list, "old.example"))
+	assert.GreaterOrEqual(t, Find(list, "new.example"), 0)
+}
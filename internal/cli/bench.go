@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/router"
+	"github.com/spf13/cobra"
+)
+
+// benchURL is routed by the bench command when no URLs are given.
+const benchURL = "https://example.com/"
+
+// benchStages are the measured stages of routing a URL, in order.
+var benchStages = []string{"load config", "route", "launch command", "total"}
+
+// addBenchCommand adds the hidden bench command to the root command
+func addBenchCommand() {
+	benchCmd := &cobra.Command{
+		Use:   "bench [URL...]",
+		Short: "Measure the latency of routing URLs",
+		Long: `Routes each URL repeatedly without launching anything, and reports how long loading
+the configuration, routing (shortener resolution, plugins and rule matching) and
+building the launch command take. Like a real launch, shortened URLs and rules on
+content or the network make requests.
+
+Use --budget to fail if the 95th percentile of the total exceeds a duration, to catch
+regressions before and after changes.`,
+		Hidden:      true,
+		Annotations: map[string]string{readOnlyConfigAnnotation: ""},
+		RunE:        runBenchCmd,
+	}
+	benchCmd.Flags().IntP("iterations", "n", 100, "Number of times to route each URL")
+	benchCmd.Flags().Duration("budget", 0, "Fail if the 95th percentile of the total exceeds this (e.g. 20ms)")
+	rootCmd.AddCommand(benchCmd)
+}
+
+// runBenchCmd routes the URLs and prints the latency of each stage
+func runBenchCmd(cmd *cobra.Command, args []string) error {
+	iterations, _ := cmd.Flags().GetInt("iterations")
+	budget, _ := cmd.Flags().GetDuration("budget")
+	if iterations < 1 {
+		return fmt.Errorf("--iterations must be at least 1")
+	}
+	if len(args) == 0 {
+		args = []string{benchURL}
+	}
+
+	overBudget := false
+	for _, u := range args {
+		timings, err := benchRoute(u, iterations)
+		if err != nil {
+			return err
+		}
+		for _, d := range timings {
+			slices.Sort(d)
+		}
+		fmt.Printf("%s (%d iterations)\n", u, iterations)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "STAGE\tMIN\tMEDIAN\tP95\tMAX")
+		for i, stage := range benchStages {
+			d := timings[i]
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", stage, d[0], percentile(d, 50), percentile(d, 95), d[len(d)-1])
+		}
+		w.Flush()
+		fmt.Println()
+
+		if p95 := percentile(timings[len(benchStages)-1], 95); budget > 0 && p95 > budget {
+			errorf("%s: 95th percentile %s exceeds the budget of %s", u, p95, budget)
+			overBudget = true
+		}
+	}
+	if overBudget {
+		return fmt.Errorf("routing latency exceeds the budget of %s", budget)
+	}
+	return nil
+}
+
+// benchRoute routes rawURL iterations times, and returns the durations of each of
+// benchStages.
+func benchRoute(rawURL string, iterations int) ([][]time.Duration, error) {
+	timings := make([][]time.Duration, len(benchStages))
+	for range iterations {
+		start := time.Now()
+		if _, err := config.LoadConfigOrDefault(cfgFile); err != nil {
+			return nil, withExitCode(ExitConfig, fmt.Errorf("failed to load configuration: %w", err))
+		}
+		loaded := time.Now()
+
+		ctx, cancel := routingContext()
+		result, err := router.Route(ctx, cfg, rawURL)
+		cancel()
+		if err != nil {
+			return nil, withExitCode(routeExitCode(err), err)
+		}
+		routed := time.Now()
+
+		plan, err := planLaunch(result.Match)
+		if err != nil {
+			return nil, withExitCode(ExitConfig, err)
+		}
+		// Builds the command line of browser launches
+		buildHookInfo(result.LaunchURL, rawURL, result.Match, plan)
+		done := time.Now()
+
+		for i, d := range []time.Duration{loaded.Sub(start), routed.Sub(loaded), done.Sub(routed), done.Sub(start)} {
+			timings[i] = append(timings[i], d)
+		}
+	}
+	return timings, nil
+}
+
+// percentile returns the p-th percentile of sorted durations (nearest rank).
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p+99)/100 - 1
+	return sorted[max(i, 0)]
+}
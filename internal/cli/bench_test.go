@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPercentile(t *testing.T) {
+	d := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	assert.Equal(t, time.Duration(5), percentile(d, 50))
+	assert.Equal(t, time.Duration(10), percentile(d, 95))
+	assert.Equal(t, time.Duration(1), percentile(d[:1], 50))
+}
+
+func TestBenchRoute(t *testing.T) {
+	originalCfg, originalFile, originalDisplay := cfg, cfgFile, hasDisplay
+	defer func() { cfg, cfgFile, hasDisplay = originalCfg, originalFile, originalDisplay }()
+	hasDisplay = func() bool { return true }
+	cfgFile = filepath.Join(t.TempDir(), "config.toml")
+	cfg = config.DefaultConfig()
+	cfg.DefaultProfileID = "home"
+	cfg.Browsers = []config.Browser{{BrowserID: "chrome", Executable: "/usr/bin/google-chrome-stable"}}
+	cfg.Profiles = []config.Profile{{ID: "home", BrowserID: "chrome"}}
+	require.NoError(t, config.SaveConfig(cfg, cfgFile))
+
+	timings, err := benchRoute("https://example.com/", 3)
+	require.NoError(t, err)
+	require.Len(t, timings, len(benchStages))
+	for _, d := range timings {
+		assert.Len(t, d, 3)
+	}
+	assert.GreaterOrEqual(t, timings[3][0], timings[1][0])
+
+	// Routing errors carry the exit code of a real launch
+	cfg.DefaultProfileID = ""
+	_, err = benchRoute("https://example.com/", 1)
+	assert.Equal(t, ExitNoProfile, ExitCode(err))
+}
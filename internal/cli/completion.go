@@ -1,9 +1,13 @@
 package cli
 
 import (
+	"net/url"
+	"slices"
 	"strings"
 
+	"github.com/jmylchreest/rurl/internal/browser"
 	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/history"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
@@ -11,21 +15,9 @@ import (
 // Function type for loading config that can be mocked in tests
 type loadConfigFunc func() *config.Config
 
-// Default implementation of loadConfigForCompletion
-var loadConfigForCompletion loadConfigFunc = func() *config.Config {
-	// Ensure logging is initialized minimally if not already done
-	// logging.InitLogging(false) // Or determine debug status if possible
-
-	// Use the cfgFile variable from root.go if set, otherwise defaults
-	loadedCfg, err := config.LoadConfig(cfgFile)
-	if err != nil {
-		// Log the error but don't exit, completion might still work partially or
-		// the user might be completing a command before config exists.
-		log.Debug().Err(err).Msg("Failed to load config during completion")
-		return nil // Return nil, completers should handle this
-	}
-	return loadedCfg
-}
+// Default implementation of loadConfigForCompletion. Completion does not create the
+// configuration: the user might be completing a command before it exists.
+var loadConfigForCompletion loadConfigFunc = loadConfigOrDefault
 
 // completeRuleNames provides completion for rule names.
 func completeRuleNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -43,6 +35,24 @@ func completeRuleNames(cmd *cobra.Command, args []string, toComplete string) ([]
 	return names, cobra.ShellCompDirectiveNoFileComp
 }
 
+// completeRuleTags provides completion for the tags used by rules.
+func completeRuleTags(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg := loadConfigForCompletion()
+	if cfg == nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var tags []string
+	for _, rule := range cfg.Rules {
+		for _, tag := range rule.Tags {
+			if strings.HasPrefix(tag, toComplete) && !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags, cobra.ShellCompDirectiveNoFileComp
+}
+
 // completeProfileIDs provides completion for profile IDs.
 func completeProfileIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	cfg := loadConfigForCompletion()
@@ -84,3 +94,83 @@ func completeBrowserIDs(cmd *cobra.Command, args []string, toComplete string) ([
 	}
 	return ids, cobra.ShellCompDirectiveNoFileComp
 }
+
+// completeBrowserFamilies provides completion for the argument template families.
+func completeBrowserFamilies(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var families []string
+	for _, t := range browser.ArgTemplates() {
+		if strings.HasPrefix(t.Family, toComplete) {
+			families = append(families, t.Family+"\t"+t.Description)
+		}
+	}
+	return families, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProfileFlag provides completion for --profile flags. Profiles are offered by
+// ID and can also be found by typing the start of their name.
+func completeProfileFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg := loadConfigForCompletion()
+	if cfg == nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var ids []string
+	for _, profile := range cfg.Profiles {
+		if strings.HasPrefix(profile.ID, toComplete) ||
+			strings.HasPrefix(strings.ToLower(profile.Name), strings.ToLower(toComplete)) {
+			ids = append(ids, profile.ID+"\t"+profile.Name)
+		}
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeRuleScopes provides completion for rule scope values.
+func completeRuleScopes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var scopes []string
+	for _, choice := range ruleScopeChoices {
+		if strings.HasPrefix(choice.Text, toComplete) {
+			scopes = append(scopes, choice.Text+"\t"+choice.Note)
+		}
+	}
+	return scopes, cobra.ShellCompDirectiveNoFileComp
+}
+
+// maxHistoryCompletions limits how many recently routed domains are offered.
+const maxHistoryCompletions = 50
+
+// completeHistoryDomains provides completion for recently routed domains, most recent
+// first. Nothing is offered unless launch history is enabled.
+func completeHistoryDomains(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg := loadConfigForCompletion()
+	if cfg == nil || !cfg.History.Enabled {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	path, err := history.DefaultPath()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	entries, err := history.Load(path)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to load history during completion")
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	seen := make(map[string]bool)
+	var domains []string
+	for i := len(entries) - 1; i >= 0 && len(domains) < maxHistoryCompletions; i-- {
+		u, err := url.Parse(entries[i].URL)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		if host == "" || seen[host] || !strings.HasPrefix(host, strings.ToLower(toComplete)) {
+			continue
+		}
+		seen[host] = true
+		domains = append(domains, host)
+	}
+	return domains, cobra.ShellCompDirectiveNoFileComp
+}
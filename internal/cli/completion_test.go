@@ -1,11 +1,14 @@
 package cli
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/history"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // mockLoadConfigFunc is a variable to hold the mock function implementation
@@ -217,3 +220,61 @@ func TestCompleteBrowserIDs(t *testing.T) {
 	assert.Equal(t, cobra.ShellCompDirectiveError, dir)
 	loadConfigForCompletion = nullConfig
 }
+
+func TestCompleteProfileFlag(t *testing.T) {
+	originalLoadConfig := loadConfigForCompletion
+	defer func() { loadConfigForCompletion = originalLoadConfig }()
+	loadConfigForCompletion = func() *config.Config {
+		return &config.Config{Profiles: []config.Profile{
+			{ID: "chrome-work", Name: "Work"},
+			{ID: "firefox-personal", Name: "Personal"},
+		}}
+	}
+
+	// Profiles are found by ID or name and completed to their ID
+	got, dir := completeProfileFlag(nil, nil, "chrome")
+	assert.Equal(t, []string{"chrome-work\tWork"}, got)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, dir)
+	got, _ = completeProfileFlag(nil, nil, "pers")
+	assert.Equal(t, []string{"firefox-personal\tPersonal"}, got)
+	got, _ = completeProfileFlag(nil, nil, "")
+	assert.Len(t, got, 2)
+}
+
+func TestCompleteRuleScopes(t *testing.T) {
+	got, dir := completeRuleScopes(nil, nil, "")
+	assert.Len(t, got, len(ruleScopeChoices))
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, dir)
+	got, _ = completeRuleScopes(nil, nil, "d")
+	if assert.Len(t, got, 1) {
+		assert.True(t, strings.HasPrefix(got[0], "domain\t"))
+	}
+}
+
+func TestCompleteHistoryDomains(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	path, err := history.DefaultPath()
+	require.NoError(t, err)
+	for _, u := range []string{"https://docs.example.com/a", "https://Mail.example.org/", "https://docs.example.com/b", "mailto:x@example.com"} {
+		require.NoError(t, history.Append(path, history.Entry{URL: u, ProfileID: "personal"}, 0))
+	}
+
+	testCfg := &config.Config{}
+	originalLoadConfig := loadConfigForCompletion
+	defer func() { loadConfigForCompletion = originalLoadConfig }()
+	loadConfigForCompletion = func() *config.Config { return testCfg }
+
+	// Nothing is offered while history is disabled
+	got, _ := completeHistoryDomains(nil, nil, "")
+	assert.Empty(t, got)
+
+	// Unique domains, most recently routed first
+	testCfg.History.Enabled = true
+	got, dir := completeHistoryDomains(nil, nil, "")
+	assert.Equal(t, []string{"docs.example.com", "mail.example.org"}, got)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, dir)
+	got, _ = completeHistoryDomains(nil, nil, "mail")
+	assert.Equal(t, []string{"mail.example.org"}, got)
+	got, _ = completeHistoryDomains(nil, []string{"docs.example.com"}, "")
+	assert.Empty(t, got)
+}
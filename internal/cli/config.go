@@ -1,9 +1,14 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/jmylchreest/rurl/internal/browser"
@@ -22,11 +27,13 @@ func addConfigCommands() {
 
 	// --- Combined List Command ---
 	configListCmd := &cobra.Command{
-		Use:   "list",
-		Short: "List all configured browsers, profiles, and rules",
-		Long:  `Displays all configured browsers, profiles, and rules.`,
-		Run:   runConfigListCmd,
+		Use:         "list",
+		Short:       "List all configured browsers, profiles, and rules",
+		Long:        `Displays all configured browsers, profiles, and rules. With --filter, only the rows with a column matching the filter are listed. Output is paged when stdout is a terminal.`,
+		Annotations: map[string]string{readOnlyConfigAnnotation: ""},
+		Run:         runConfigListCmd,
 	}
+	addListFlags(configListCmd, nil)
 	configCmd.AddCommand(configListCmd)
 
 	// --- Detect Browsers Command ---
@@ -35,10 +42,21 @@ func addConfigCommands() {
 		Short: "Detect installed browsers/profiles and optionally update config",
 		Long: `Scans the system for known browser installations and their profiles.
 Prints the detected browsers and profiles.
-Use the --save flag to compare with current config, handle removals interactively, and save changes.`,
+Use the --save flag to compare with current config, handle removals interactively, and save changes.
+With --merge-policy, configured browsers and profiles that were not detected are handled without
+prompting: "keep" keeps them, "replace" removes them and points the default profile and the rules
+using them at another profile (of the same browser if possible), and "prune" removes them and the
+rules using them. Add --yes to save without confirmation, e.g. from provisioning scripts.
+Profiles in Chromium user data directories other than the default one (e.g. portable
+installs) are detected with --user-data-dir; once saved, their directories are scanned again
+on every detection.`,
 		Run: runDetectBrowsersCmd,
 	}
 	detectBrowsersCmd.Flags().BoolVar(&detectSave, "save", false, "Save detected browsers/profiles to config file (interactive update)")
+	detectBrowsersCmd.Flags().StringVar(&detectMergePolicy, "merge-policy", "", "handle configured browsers/profiles that were not detected without prompting: keep, replace or prune")
+	detectBrowsersCmd.Flags().BoolVarP(&detectYes, "yes", "y", false, "save without asking for confirmation")
+	_ = detectBrowsersCmd.RegisterFlagCompletionFunc("merge-policy", cobra.FixedCompletions(mergePolicies, cobra.ShellCompDirectiveNoFileComp))
+	detectBrowsersCmd.Flags().StringArrayVar(&detectUserDataDirs, "user-data-dir", nil, "also detect the profiles in a Chromium user data directory, given as browser-id=path (repeatable)")
 	configCmd.AddCommand(detectBrowsersCmd)
 
 	// --- Browser Commands (Moved to config_browsers.go) ---
@@ -64,6 +82,75 @@ Use the --save flag to compare with current config, handle removals interactivel
 	// --- Shortener Commands (Moved to config_shorteners.go) ---
 	registerShortURLCommands(configCmd)
 
+	// --- URL List Commands (config_url_lists.go) ---
+	addURLListCommands(configCmd)
+
+	// --- Override Commands (config_overrides.go) ---
+	addOverrideCommands(configCmd)
+
+	// --- Edit Command (config_edit.go) ---
+	addConfigEditCommand(configCmd)
+
+	// --- Init Command ---
+	configInitCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Create the default configuration file",
+		Long: fmt.Sprintf(`Writes the default configuration to the config file, which must not exist unless
+--force is given. Other commands create it when it is missing, unless %s is set;
+commands that only read the configuration (e.g. list and inspect) use the defaults
+without creating it.`, config.NoCreateConfigEnv),
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{noConfigAnnotation: ""},
+		Run:         runConfigInitCmd,
+	}
+	configInitCmd.Flags().BoolVar(&initForce, "force", false, "Replace an existing config file with the defaults")
+	configCmd.AddCommand(configInitCmd)
+
+	// --- Migrate Command ---
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "migrate",
+		Short: "Save the configuration upgraded to the current format",
+		Long: `Older configurations are upgraded in memory when loaded (e.g. rules are given
+IDs and unique names). This command writes the upgraded configuration to the config file.
+Any other command that saves the configuration does the same.`,
+		Args: cobra.NoArgs,
+		Run:  runConfigMigrateCmd,
+	})
+
+	// --- Split Command ---
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "split",
+		Short: "Keep machine-specific settings and rules in separate files",
+		Long: `Moves the browsers, profiles and default profile to machine.toml, and the rules,
+manual and disabled shorteners and overrides to rules.toml, next to the config file
+(with its extension, e.g. rules.yaml for config.yaml). Both are read and merged when the configuration is loaded,
+and changes are saved to the file holding them, so that rules.toml can be shared between
+machines (e.g. from a dotfiles repository) while browser detection stays per machine.
+Delete a file after moving its sections back to the config file to undo the split.`,
+		Args: cobra.NoArgs,
+		Run:  runConfigSplitCmd,
+	})
+
+	// --- Redacted Export Command ---
+	redactExportCmd := &cobra.Command{
+		Use:   "redact-export",
+		Short: "Export the configuration with personal details removed, for bug reports",
+		Long: `Writes a copy of the configuration that can be attached to a bug report. The home
+directory and user name are removed from paths; environment values, hook commands, plugin
+arguments and remote hosts are replaced by placeholders; words in rule patterns and
+domains are replaced by hashes, and rule and profile names and IDs are renamed.
+
+The structure is kept so that detection and rule-matching problems can be reproduced: the
+same word always becomes the same hash (with a random key for each export), and regular
+expression syntax, CIDR ranges, scopes and browser arguments are unchanged.
+Check the export before sharing it.`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{readOnlyConfigAnnotation: ""},
+		Run:         runRedactExportCmd,
+	}
+	redactExportCmd.Flags().StringVarP(&redactOutput, "output", "o", "", "write the export to this file instead of standard output")
+	configCmd.AddCommand(redactExportCmd)
+
 	// Add the main config command to the root command
 	rootCmd.AddCommand(configCmd)
 }
@@ -82,7 +169,7 @@ Use the --save flag to compare with current config, handle removals interactivel
 
 // performDetection calls the detector and gathers browser/profile info
 func performDetection() ([]config.Browser, []config.Profile, map[string]config.Browser, map[string]config.Profile, error) {
-	detector, err := browser.NewDetector()
+	detector, err := browser.GetDetector()
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create browser detector for this OS")
 		return nil, nil, nil, nil, fmt.Errorf("creating browser detector: %w", err)
@@ -92,7 +179,7 @@ func performDetection() ([]config.Browser, []config.Profile, map[string]config.B
 	if err != nil {
 		// Log but don't necessarily fail the whole process
 		log.Error().Err(err).Msg("Failed during browser discovery")
-		fmt.Fprintf(os.Stderr, "Warning: Error during browser discovery: %v\n", err)
+		errorf("Warning: Error during browser discovery: %v", err)
 		// Continue with potentially empty list
 	}
 
@@ -105,7 +192,7 @@ func performDetection() ([]config.Browser, []config.Profile, map[string]config.B
 		profiles, err := detector.DiscoverProfiles(b)
 		if err != nil {
 			log.Warn().Err(err).Str("browser_id", b.BrowserID).Msg("Failed to discover profiles for browser")
-			fmt.Fprintf(os.Stderr, "Warning: Failed to discover profiles for %s (ID: %s): %v\n", b.Name, b.BrowserID, err)
+			errorf("Warning: Failed to discover profiles for %s (ID: %s): %v", b.Name, b.BrowserID, err)
 		} else {
 			detectedProfiles = append(detectedProfiles, profiles...)
 			for _, p := range profiles {
@@ -117,6 +204,168 @@ func performDetection() ([]config.Browser, []config.Profile, map[string]config.B
 	return detectedBrowsers, detectedProfiles, detectedBrowserMap, detectedProfileMap, nil
 }
 
+// Merge policies of detect-browsers --save for configured browsers and profiles that
+// were not detected.
+const (
+	mergeKeep    = "keep"    // Keep them
+	mergeReplace = "replace" // Remove them, moving references to other profiles
+	mergePrune   = "prune"   // Remove them and the rules using them
+)
+
+// mergePolicies lists the valid --merge-policy values.
+var mergePolicies = []string{mergeKeep, mergeReplace, mergePrune}
+
+var (
+	// detectMergePolicy is the --merge-policy of detect-browsers; empty prompts.
+	detectMergePolicy string
+	// detectYes saves detect-browsers changes without confirmation.
+	detectYes bool
+)
+
+// detectUserDataDirs are the extra Chromium user data directories given to
+// detect-browsers, as "browser-id=path".
+var detectUserDataDirs []string
+
+// discoverUserDataDirs finds the profiles in Chromium user data directories other than
+// the browsers' default ones: those given in extra (as "browser-id=path") and those of
+// the configured profiles. Configured profiles whose directory cannot be read (e.g. an
+// unmounted drive) are kept as they are, so that saving does not remove them.
+func discoverUserDataDirs(browsers []config.Browser, configured []config.Profile, extra []string) ([]config.Profile, error) {
+	type dataDir struct{ browserID, path string }
+	var dirs []dataDir
+	for _, spec := range extra {
+		browserID, path, ok := strings.Cut(spec, "=")
+		if !ok || browserID == "" || path == "" {
+			return nil, fmt.Errorf("invalid --user-data-dir '%s' (expected browser-id=path)", spec)
+		}
+		dirs = append(dirs, dataDir{browserID, path})
+	}
+	for _, p := range configured {
+		if p.UserDataDir != "" {
+			dirs = append(dirs, dataDir{p.BrowserID, p.UserDataDir})
+		}
+	}
+
+	var profiles []config.Profile
+	seenDirs := make(map[dataDir]bool)
+	seenIDs := make(map[string]bool)
+	for i, dir := range dirs {
+		if seenDirs[dir] {
+			continue
+		}
+		seenDirs[dir] = true
+
+		var found []config.Profile
+		var err error
+		b := findBrowser(browsers, dir.browserID)
+		if b == nil {
+			err = fmt.Errorf("browser '%s' was not detected", dir.browserID)
+		} else {
+			found, err = browser.DiscoverUserDataDirProfiles(*b, dir.path)
+		}
+		if err != nil {
+			if i < len(extra) {
+				return nil, fmt.Errorf("user data directory %s: %w", dir.path, err)
+			}
+			log.Warn().Err(err).Str("user_data_dir", dir.path).Msg("Keeping configured profiles of unreadable user data directory")
+			errorf("Warning: cannot read user data directory %s, keeping its configured profiles: %v", dir.path, err)
+			for _, p := range configured {
+				if p.BrowserID == dir.browserID && p.UserDataDir == dir.path {
+					found = append(found, p)
+				}
+			}
+		}
+		for _, p := range found {
+			if !seenIDs[p.ID] {
+				seenIDs[p.ID] = true
+				profiles = append(profiles, p)
+			}
+		}
+	}
+	return profiles, nil
+}
+
+// findBrowser returns the browser with browserID in browsers, or nil.
+func findBrowser(browsers []config.Browser, browserID string) *config.Browser {
+	for i := range browsers {
+		if browsers[i].BrowserID == browserID {
+			return &browsers[i]
+		}
+	}
+	return nil
+}
+
+// reconcileBrowserIDs returns a copy of cfg in which configured browsers that detection
+// now knows under another ID take that ID, e.g. a Flatpak or snap install configured
+// before each install source had its own ID. Browsers are matched by executable, so a
+// native and a Flatpak install are never taken for each other. Configured profiles take
+// the IDs of the detected profiles in the same directories, which migrates the IDs of
+// older versions (derived from profile names) to the stable ones (see
+// browser.ProfileID), and the default profile and rules follow them, so that saving
+// does not remove the browsers and profiles and orphan their rules.
+func reconcileBrowserIDs(cfg *config.Config, detectedBrowsers []config.Browser, detectedProfiles []config.Profile) *config.Config {
+	detectedByExe := make(map[string]string) // Executable to detected browser ID
+	for _, b := range detectedBrowsers {
+		if b.Executable != "" && b.Remote == nil {
+			detectedByExe[b.Executable] = b.BrowserID
+		}
+	}
+	browserIDs := make(map[string]string) // Configured to detected browser ID
+	for _, b := range cfg.Browsers {
+		if id, ok := detectedByExe[b.Executable]; ok && b.Remote == nil && id != b.BrowserID {
+			browserIDs[b.BrowserID] = id
+		}
+	}
+
+	out := *cfg
+	out.Browsers = slices.Clone(cfg.Browsers)
+	for i, b := range out.Browsers {
+		if id, ok := browserIDs[b.BrowserID]; ok {
+			log.Info().Str("from", b.BrowserID).Str("to", id).Str("executable", b.Executable).Msg("Configured browser detected under another ID")
+			out.Browsers[i].BrowserID = id
+		}
+	}
+	profileIDs := make(map[string]string) // Configured to detected profile ID
+	out.Profiles = slices.Clone(cfg.Profiles)
+	for i, p := range out.Profiles {
+		if id, ok := browserIDs[p.BrowserID]; ok {
+			out.Profiles[i].BrowserID = id
+		}
+		for _, d := range detectedProfiles {
+			if d.BrowserID == out.Profiles[i].BrowserID && d.ProfileDir == p.ProfileDir && d.UserDataDir == p.UserDataDir {
+				if d.ID != p.ID {
+					log.Info().Str("from", p.ID).Str("to", d.ID).Msg("Configured profile detected under another ID")
+					profileIDs[p.ID] = d.ID
+					out.Profiles[i].ID = d.ID
+				}
+				break
+			}
+		}
+	}
+	if len(browserIDs) == 0 && len(profileIDs) == 0 {
+		return cfg
+	}
+	rename := func(id string) string {
+		if renamed, ok := profileIDs[id]; ok {
+			return renamed
+		}
+		return id
+	}
+	out.DefaultProfileID = rename(cfg.DefaultProfileID)
+	out.Headless.ProfileID = rename(cfg.Headless.ProfileID)
+	out.Behavior.FileProfileID = rename(cfg.Behavior.FileProfileID)
+	out.Behavior.IncognitoFallbackProfileID = rename(cfg.Behavior.IncognitoFallbackProfileID)
+	out.Hotkeys.Bindings = slices.Clone(cfg.Hotkeys.Bindings)
+	for i := range out.Hotkeys.Bindings {
+		out.Hotkeys.Bindings[i].ProfileID = rename(out.Hotkeys.Bindings[i].ProfileID)
+	}
+	out.Rules = slices.Clone(cfg.Rules)
+	for i := range out.Rules {
+		out.Rules[i].ProfileID = rename(out.Rules[i].ProfileID)
+	}
+	return &out
+}
+
 // compareDetectedWithConfig identifies items in config not found by detection
 func compareDetectedWithConfig(cfg *config.Config, detectedBrowserMap map[string]config.Browser, detectedProfileMap map[string]config.Profile) (map[string]config.Browser, map[string]config.Profile, map[string]struct{}) {
 	cfgBrowserMap := make(map[string]config.Browser)
@@ -132,9 +381,9 @@ func compareDetectedWithConfig(cfg *config.Config, detectedBrowserMap map[string
 	profilesToRemove := make(map[string]config.Profile) // Profiles explicitly not found for existing browsers
 	profileIDsToRemove := make(map[string]struct{})     // All profile IDs associated with removed items
 
-	// Find browsers in config but not detected
+	// Find browsers in config but not detected (browsers added by hand are kept)
 	for browserID, browser := range cfgBrowserMap {
-		if _, found := detectedBrowserMap[browserID]; !found {
+		if _, found := detectedBrowserMap[browserID]; !found && browser.Origin != config.OriginManual {
 			log.Warn().Str("browser_id", browserID).Str("name", browser.Name).Msg("Configured browser not detected.")
 			browsersToRemove[browserID] = browser
 			// Mark all its current profiles for removal
@@ -154,7 +403,7 @@ func compareDetectedWithConfig(cfg *config.Config, detectedBrowserMap map[string
 	for profileID, profile := range cfgProfileMap {
 		// Only check profiles whose browser WAS detected AND wasn't already marked for removal
 		if _, browserDetected := detectedBrowserMap[profile.BrowserID]; browserDetected {
-			if _, profileDetected := detectedProfileMap[profileID]; !profileDetected {
+			if _, profileDetected := detectedProfileMap[profileID]; !profileDetected && profile.Origin != config.OriginManual {
 				// Check if it wasn't already marked due to parent browser removal
 				if _, alreadyMarked := profileIDsToRemove[profileID]; !alreadyMarked {
 					log.Warn().Str("profile_id", profileID).Str("name", profile.Name).Str("browser_id", profile.BrowserID).Msg("Configured profile not detected.")
@@ -168,6 +417,76 @@ func compareDetectedWithConfig(cfg *config.Config, detectedBrowserMap map[string
 	return browsersToRemove, profilesToRemove, profileIDsToRemove
 }
 
+// Fields of detected browsers and profiles that detection keeps up to date, replacing
+// the configured values. Other configured fields are kept, see mergeDetected.
+var (
+	detectedBrowserFields = []string{"Executable", "BundleID", "InstallSource"}
+	detectedProfileFields = []string{"ProfileDir", "UserDataDir", "Email", "AvatarIndex"}
+)
+
+// mergeDetected returns the entry to save for detected, which is configured in the
+// configuration: configured as is if it was added by hand (manual), or else detected
+// with the configured values of its fields kept, except for the fields detection owns
+// and the fields configured empty.
+func mergeDetected[T any](configured, detected T, manual bool, owned []string) T {
+	if manual {
+		return configured
+	}
+	out := reflect.ValueOf(&configured).Elem()
+	det := reflect.ValueOf(detected)
+	for i := 0; i < out.NumField(); i++ {
+		field := out.Field(i)
+		if field.CanSet() && (field.IsZero() || slices.Contains(owned, out.Type().Field(i).Name)) {
+			field.Set(det.Field(i))
+		}
+	}
+	return configured
+}
+
+// mergeDetectedBrowsers returns the browsers to save: the detected browsers, merged
+// with their configured entries, followed by the browsers added by hand that were not
+// detected.
+func mergeDetectedBrowsers(configured, detected []config.Browser) []config.Browser {
+	merged := make([]config.Browser, 0, len(detected))
+	for _, d := range detected {
+		if c := findBrowser(configured, d.BrowserID); c != nil {
+			d = mergeDetected(*c, d, c.Origin == config.OriginManual, detectedBrowserFields)
+		}
+		merged = append(merged, d)
+	}
+	for _, c := range configured {
+		if c.Origin == config.OriginManual && findBrowser(detected, c.BrowserID) == nil {
+			merged = append(merged, c)
+		}
+	}
+	return merged
+}
+
+// mergeDetectedProfiles is mergeDetectedBrowsers for profiles. The configured profiles
+// that were not detected are kept unless they are in removed, which covers the profiles
+// added by hand and those of browsers added by hand.
+func mergeDetectedProfiles(configured, detected []config.Profile, removed map[string]struct{}) []config.Profile {
+	byID := make(map[string]config.Profile, len(configured))
+	for _, c := range configured {
+		byID[c.ID] = c
+	}
+	merged := make([]config.Profile, 0, len(detected))
+	seen := make(map[string]bool)
+	for _, d := range detected {
+		if c, ok := byID[d.ID]; ok {
+			d = mergeDetected(c, d, c.Origin == config.OriginManual, detectedProfileFields)
+		}
+		seen[d.ID] = true
+		merged = append(merged, d)
+	}
+	for _, c := range configured {
+		if _, gone := removed[c.ID]; !seen[c.ID] && !gone {
+			merged = append(merged, c)
+		}
+	}
+	return merged
+}
+
 // handleOrphanedDefaultProfile manages selection of a new default if needed
 func handleOrphanedDefaultProfile(originalDefaultID, currentDefaultID string, profileIDsToRemove map[string]struct{}, profilesToKeep []config.Profile) string {
 	newDefaultProfileID := currentDefaultID
@@ -177,12 +496,12 @@ func handleOrphanedDefaultProfile(originalDefaultID, currentDefaultID string, pr
 		if len(profilesToKeep) == 1 {
 			newDefaultProfileID = profilesToKeep[0].ID
 			log.Info().Str("profile_id", newDefaultProfileID).Msg("Automatically setting the only remaining profile as default.")
-			fmt.Printf("Info: Default profile '%s' removed. Automatically setting '%s' as new default.\n", originalDefaultID, newDefaultProfileID)
+			statusf("Info: Default profile '%s' removed. Automatically setting '%s' as new default.", originalDefaultID, newDefaultProfileID)
 		} else if len(profilesToKeep) > 1 {
 			selectedID, err := promptSelectProfile(fmt.Sprintf("Default profile '%s' is being removed. Select a new default profile:", originalDefaultID), profilesToKeep, originalDefaultID, "")
 			if err != nil || selectedID == "" {
 				log.Error().Err(err).Msg("Failed to select a new default profile. Clearing default.")
-				fmt.Fprintln(os.Stderr, "Error selecting default profile or selection cancelled. Default profile will be unset.")
+				errorf("Error selecting default profile or selection cancelled. Default profile will be unset.")
 				newDefaultProfileID = ""
 			} else {
 				newDefaultProfileID = selectedID
@@ -190,13 +509,29 @@ func handleOrphanedDefaultProfile(originalDefaultID, currentDefaultID string, pr
 			}
 		} else { // len == 0
 			log.Warn().Msg("No profiles remaining. Clearing default profile setting.")
-			fmt.Printf("Info: Default profile '%s' removed. No other profiles remain. Default profile unset.\n", originalDefaultID)
+			statusf("Info: Default profile '%s' removed. No other profiles remain. Default profile unset.", originalDefaultID)
 			newDefaultProfileID = ""
 		}
 	}
 	return newDefaultProfileID
 }
 
+// applyRuleUpdates returns rules without those in rulesToDelete and with the profile
+// of those in rulesToUpdate replaced, both by rule name.
+func applyRuleUpdates(rules []config.Rule, rulesToUpdate map[string]string, rulesToDelete map[string]struct{}) []config.Rule {
+	out := []config.Rule{}
+	for _, rule := range rules {
+		if _, markedForDeletion := rulesToDelete[rule.Name]; markedForDeletion {
+			continue // Skip deleted rules
+		}
+		if updatedProfileID, needsUpdate := rulesToUpdate[rule.Name]; needsUpdate {
+			rule.ProfileID = updatedProfileID // Update profile ID
+		}
+		out = append(out, rule) // Add rule (updated or unchanged)
+	}
+	return out
+}
+
 // handleOrphanedRules manages selection/deletion for rules with removed profiles
 func handleOrphanedRules(originalRules []config.Rule, profileIDsToRemove map[string]struct{}, profilesToKeep []config.Profile) (map[string]string, map[string]struct{}) {
 	rulesToUpdate := make(map[string]string)
@@ -210,7 +545,7 @@ func handleOrphanedRules(originalRules []config.Rule, profileIDsToRemove map[str
 			if len(profilesToKeep) == 1 {
 				newProfileID := profilesToKeep[0].ID
 				log.Info().Str("rule_name", rule.Name).Str("new_profile_id", newProfileID).Msg("Automatically updating rule to use the only remaining profile.")
-				fmt.Printf("Info: Rule '%s' automatically updated to use profile '%s'.\n", rule.Name, newProfileID)
+				statusf("Info: Rule '%s' automatically updated to use profile '%s'.", rule.Name, newProfileID)
 				rulesToUpdate[rule.Name] = newProfileID
 			} else if len(profilesToKeep) > 1 {
 				prompt := fmt.Sprintf("Rule '%s' uses profile '%s' which is being removed.", rule.Name, rule.ProfileID)
@@ -218,7 +553,7 @@ func handleOrphanedRules(originalRules []config.Rule, profileIDsToRemove map[str
 
 				if err != nil {
 					log.Error().Err(err).Str("rule_name", rule.Name).Msg("Error during rule update prompt. Rule will be deleted.")
-					fmt.Fprintf(os.Stderr, "Error processing rule '%s': %v. Rule will be deleted.\n", rule.Name, err)
+					errorf("Error processing rule '%s': %v. Rule will be deleted.", rule.Name, err)
 					rulesToDelete[rule.Name] = struct{}{}
 				} else if deleteRule {
 					log.Info().Str("rule_name", rule.Name).Msg("User chose to delete rule.")
@@ -228,12 +563,12 @@ func handleOrphanedRules(originalRules []config.Rule, profileIDsToRemove map[str
 					rulesToUpdate[rule.Name] = selectedID
 				} else { // Cancelled prompt
 					log.Warn().Str("rule_name", rule.Name).Msg("Rule update cancelled by user. Rule will be deleted.")
-					fmt.Fprintf(os.Stderr, "Rule '%s' update cancelled. Rule will be deleted.\n", rule.Name)
+					errorf("Rule '%s' update cancelled. Rule will be deleted.", rule.Name)
 					rulesToDelete[rule.Name] = struct{}{}
 				}
 			} else { // len == 0
 				log.Warn().Str("rule_name", rule.Name).Msg("No remaining profiles. Deleting rule.")
-				fmt.Printf("Info: Rule '%s' deleted because its profile '%s' was removed and no other profiles exist.\n", rule.Name, rule.ProfileID)
+				statusf("Info: Rule '%s' deleted because its profile '%s' was removed and no other profiles exist.", rule.Name, rule.ProfileID)
 				rulesToDelete[rule.Name] = struct{}{}
 			}
 		}
@@ -241,41 +576,129 @@ func handleOrphanedRules(originalRules []config.Rule, profileIDsToRemove map[str
 	return rulesToUpdate, rulesToDelete
 }
 
-// displayProposedChanges prints a summary of potential destructive changes
-// (No longer used - using simplified summary now)
+// resolveOrphansByPolicy decides, without prompting, what becomes of the default
+// profile and the rules using profiles that are removed, following policy (replace or
+// prune). References move to a replacement profile, see orphanReplacement; with prune,
+// or if no profiles remain, the rules are deleted instead.
+func resolveOrphansByPolicy(policy string, current *config.Config, profileIDsToRemove map[string]struct{}, profilesToKeep []config.Profile) (string, map[string]string, map[string]struct{}) {
+	removed := make(map[string]config.Profile)
+	for _, p := range current.Profiles {
+		if _, ok := profileIDsToRemove[p.ID]; ok {
+			removed[p.ID] = p
+		}
+	}
+
+	newDefaultProfileID := current.DefaultProfileID
+	if p, ok := removed[current.DefaultProfileID]; ok {
+		newDefaultProfileID = orphanReplacement(p, "", profilesToKeep)
+		statusf("Info: Default profile '%s' removed. New default: '%s'.", p.ID, newDefaultProfileID)
+	}
+
+	rulesToUpdate := make(map[string]string)
+	rulesToDelete := make(map[string]struct{})
+	for _, rule := range current.Rules {
+		p, ok := removed[rule.ProfileID]
+		if !ok {
+			continue
+		}
+		if policy == mergePrune || len(profilesToKeep) == 0 {
+			statusf("Info: Rule '%s' deleted because its profile '%s' was removed.", rule.Name, p.ID)
+			rulesToDelete[rule.Name] = struct{}{}
+			continue
+		}
+		rulesToUpdate[rule.Name] = orphanReplacement(p, newDefaultProfileID, profilesToKeep)
+		statusf("Info: Rule '%s' updated to use profile '%s'.", rule.Name, rulesToUpdate[rule.Name])
+	}
+	return newDefaultProfileID, rulesToUpdate, rulesToDelete
+}
+
+// orphanReplacement returns the profile taking over the references to the removed
+// profile: the first remaining profile of the same browser, or else fallback, or else
+// the first remaining profile ("" if none remain).
+func orphanReplacement(removed config.Profile, fallback string, profilesToKeep []config.Profile) string {
+	for _, p := range profilesToKeep {
+		if p.BrowserID == removed.BrowserID {
+			return p.ID
+		}
+	}
+	if fallback != "" {
+		return fallback
+	}
+	if len(profilesToKeep) > 0 {
+		return profilesToKeep[0].ID
+	}
+	return ""
+}
 
-// confirmAndSaveChanges prompts the user and saves the final configuration
-func confirmAndSaveChanges(finalCfg *config.Config, cfgFile string) bool {
-	confirm := promptString("\nApply these changes and save the configuration? (yes/no)", "no")
-	if !strings.EqualFold(confirm, "yes") {
-		fmt.Println("Changes discarded.")
-		log.Info().Msg("User cancelled configuration save.")
-		return false
+// confirmAndSaveChanges lets the user review changes, unless assumeYes is set, and
+// saves proposed with the changes they rejected reverted to original. Selections that
+// leave references to missing profiles or browsers are not saved.
+func confirmAndSaveChanges(original, proposed *config.Config, changes []configChange, assumeYes bool) bool {
+	finalCfg := proposed
+	if !assumeYes {
+		accepted := reviewChanges(changes)
+		if accepted == nil {
+			fmt.Println("Changes discarded.")
+			log.Info().Msg("User cancelled configuration save.")
+			return false
+		}
+		finalCfg = applyChanges(original, proposed, changes, accepted)
+		if problems := newProblems(original, finalCfg); len(problems) > 0 {
+			errorf("The selected changes leave the configuration inconsistent:")
+			for _, p := range problems {
+				errorf("  - %v", p)
+			}
+			fmt.Println("Changes discarded.")
+			return false
+		}
 	}
 
-	if err := config.SaveConfig(finalCfg, cfgFile); err != nil {
+	if err := saveConfig(finalCfg); err != nil {
 		log.Error().Err(err).Msg("Failed to save updated configuration")
-		fmt.Fprintf(os.Stderr, "Error saving configuration: %v\n", err)
-		os.Exit(1) // Exit on save error
+		errorf("Error saving configuration: %v", err)
+		os.Exit(ExitConfig) // Exit on save error
 	}
 
 	log.Info().Msg("Configuration updated successfully based on detection.")
-	fmt.Println("\nConfiguration saved successfully.")
+	statusf("\nConfiguration saved successfully.")
 	return true
 }
 
+// newProblems returns the problems validateConfig finds in updated that it does not
+// find in original.
+func newProblems(original, updated *config.Config) []error {
+	known := make(map[string]bool)
+	for _, p := range validateConfig(original) {
+		known[p.Error()] = true
+	}
+	var problems []error
+	for _, p := range validateConfig(updated) {
+		if !known[p.Error()] {
+			problems = append(problems, p)
+		}
+	}
+	return problems
+}
+
 // --- Main Command Run Functions ---
 
 // runConfigListCmd displays all configured browsers, profiles, and rules
 func runConfigListCmd(cmd *cobra.Command, args []string) {
 	if cfg == nil {
 		log.Error().Msg("Configuration not loaded.")
+		os.Exit(ExitConfig)
+	}
+	opts, err := listOptionsFromFlags(cmd, nil)
+	if err != nil {
+		errorf("Error: %v", err)
 		os.Exit(1)
 	}
+	defer startPager(cmd)()
+
 	fmt.Println("--- Configuration Summary ---")
-	printBrowserList(cfg)
-	printProfileList(cfg)
-	printRuleList(cfg)
+	printBrowserList(cfg, opts)
+	printProfileList(cfg, opts)
+	printRuleList(cfg, nil, opts)
 }
 
 // runDetectBrowsersCmd is the CLI command to detect browsers and handle config updates
@@ -283,6 +706,10 @@ func runDetectBrowsersCmd(cmd *cobra.Command, args []string) {
 	log.Info().Msg("Running browser detection...")
 	if cfg == nil {
 		log.Error().Msg("Configuration not loaded.")
+		os.Exit(ExitConfig)
+	}
+	if detectMergePolicy != "" && !slices.Contains(mergePolicies, detectMergePolicy) {
+		errorf("Error: invalid merge policy '%s' (expected one of: %s)", detectMergePolicy, strings.Join(mergePolicies, ", "))
 		os.Exit(1)
 	}
 
@@ -300,9 +727,17 @@ func runDetectBrowsersCmd(cmd *cobra.Command, args []string) {
 	if err != nil {
 		// Log the error from the detector creation
 		log.Error().Err(err).Msg("Failed to initialize browser detection")
-		fmt.Fprintf(os.Stderr, "Error initializing browser detection: %v\n", err)
+		errorf("Error initializing browser detection: %v", err)
 		os.Exit(1)
 	}
+	// Configured browsers and profiles, under the IDs detection now gives them
+	current := reconcileBrowserIDs(cfg, discoveredBrowsers, discoveredProfiles)
+	extraProfiles, err := discoverUserDataDirs(discoveredBrowsers, current.Profiles, detectUserDataDirs)
+	if err != nil {
+		errorf("Error: %v", err)
+		os.Exit(1)
+	}
+	discoveredProfiles = append(discoveredProfiles, extraProfiles...)
 	log.Info().Int("browser_count", len(discoveredBrowsers)).Int("profile_count", len(discoveredProfiles)).Msg("Detection complete")
 
 	// --- Report Detected Items or Save ---
@@ -310,11 +745,11 @@ func runDetectBrowsersCmd(cmd *cobra.Command, args []string) {
 		log.Info().Msg("Displaying detected browsers/profiles (use --save to update config).")
 		// Print detected browsers using utils helper
 		tempCfgBrowsers := &config.Config{Browsers: discoveredBrowsers}
-		printBrowserList(tempCfgBrowsers) // Will print header and "(None detected)" if empty
+		printBrowserList(tempCfgBrowsers, listOptions{}) // Will print header and "(None detected)" if empty
 
 		// Print detected profiles using utils helper
 		tempCfgProfiles := &config.Config{Profiles: discoveredProfiles}
-		printProfileList(tempCfgProfiles) // Will print header and "(None detected)" if empty
+		printProfileList(tempCfgProfiles, listOptions{}) // Will print header and "(None detected)" if empty
 
 		fmt.Println("\nRun with --save to update the configuration file.")
 		return
@@ -334,87 +769,80 @@ func runDetectBrowsersCmd(cmd *cobra.Command, args []string) {
 	}
 
 	// Identify items in config not found by detection
-	_, _, profileIDsToRemove := compareDetectedWithConfig(cfg, detectedBrowserMap, detectedProfileMap)
+	_, _, profileIDsToRemove := compareDetectedWithConfig(current, detectedBrowserMap, detectedProfileMap)
 
-	// Prepare intermediate state (start with detected items)
-	browsersToKeep := discoveredBrowsers
-	profilesToKeep := discoveredProfiles
-	newDefaultProfileID := cfg.DefaultProfileID // Start with current, may change
+	// Prepare intermediate state: detected items, keeping manual entries and edits
+	browsersToKeep := mergeDetectedBrowsers(current.Browsers, discoveredBrowsers)
+	profilesToKeep := mergeDetectedProfiles(current.Profiles, discoveredProfiles, profileIDsToRemove)
+	newDefaultProfileID := current.DefaultProfileID // Start with current, may change
 	rulesToUpdate := make(map[string]string)
 	rulesToDelete := make(map[string]struct{})
 
-	// Handle Default Profile Interactively if it's being removed
-	newDefaultProfileID = handleOrphanedDefaultProfile(cfg.DefaultProfileID, newDefaultProfileID, profileIDsToRemove, profilesToKeep)
-
-	// Handle Orphaned Rules Interactively
-	rulesToUpdate, rulesToDelete = handleOrphanedRules(cfg.Rules, profileIDsToRemove, profilesToKeep)
-
-	// --- Construct Final Proposed Config State ---
-	finalRules := []config.Rule{}
-	for _, rule := range cfg.Rules { // Iterate original rules
-		if _, markedForDeletion := rulesToDelete[rule.Name]; markedForDeletion {
-			continue // Skip deleted rules
+	// The keep policy keeps what was not detected, so nothing is orphaned
+	if detectMergePolicy == mergeKeep {
+		for _, b := range current.Browsers {
+			if _, detected := detectedBrowserMap[b.BrowserID]; !detected && b.Origin != config.OriginManual {
+				browsersToKeep = append(browsersToKeep, b)
+			}
 		}
-		if updatedProfileID, needsUpdate := rulesToUpdate[rule.Name]; needsUpdate {
-			rule.ProfileID = updatedProfileID // Update profile ID
+		for _, p := range current.Profiles {
+			if _, removed := profileIDsToRemove[p.ID]; removed {
+				profilesToKeep = append(profilesToKeep, p)
+			}
 		}
-		finalRules = append(finalRules, rule) // Add rule (updated or unchanged)
-	}
-
-	finalCfg := config.Config{
-		Browsers:         browsersToKeep,
-		Profiles:         profilesToKeep,
-		Rules:            finalRules,
-		DefaultProfileID: newDefaultProfileID,
-		Shorteners:       cfg.Shorteners, // Preserve other sections like Shorteners
+		profileIDsToRemove = map[string]struct{}{}
 	}
 
-	// --- Final Comparison and Confirmation ---
-	browsersActuallyChanged := !reflect.DeepEqual(originalBrowsers, finalCfg.Browsers)
-	profilesActuallyChanged := !reflect.DeepEqual(originalProfiles, finalCfg.Profiles)
-	defaultActuallyChanged := originalDefaultProfileID != finalCfg.DefaultProfileID
-	rulesActuallyChanged := !reflect.DeepEqual(originalRules, finalCfg.Rules)
+	if detectMergePolicy != "" {
+		newDefaultProfileID, rulesToUpdate, rulesToDelete = resolveOrphansByPolicy(detectMergePolicy, current, profileIDsToRemove, profilesToKeep)
+	} else {
+		// Handle Default Profile Interactively if it's being removed
+		newDefaultProfileID = handleOrphanedDefaultProfile(current.DefaultProfileID, newDefaultProfileID, profileIDsToRemove, profilesToKeep)
 
-	configActuallyChanged := browsersActuallyChanged || profilesActuallyChanged || defaultActuallyChanged || rulesActuallyChanged
+		// Handle Orphaned Rules Interactively
+		rulesToUpdate, rulesToDelete = handleOrphanedRules(current.Rules, profileIDsToRemove, profilesToKeep)
+	}
 
-	if !configActuallyChanged {
+	// --- Construct Final Proposed Config State ---
+	finalRules := applyRuleUpdates(current.Rules, rulesToUpdate, rulesToDelete)
+
+	// Other sections (shorteners, hooks, history, ...) are kept as configured
+	finalCfg := *current
+	finalCfg.Browsers = browsersToKeep
+	finalCfg.Profiles = profilesToKeep
+	finalCfg.Rules = finalRules
+	finalCfg.DefaultProfileID = newDefaultProfileID
+
+	// --- Review and Save Changes ---
+	original := &config.Config{
+		Browsers:         originalBrowsers,
+		Profiles:         originalProfiles,
+		Rules:            originalRules,
+		DefaultProfileID: originalDefaultProfileID,
+	}
+	changes := diffConfig(original, &finalCfg)
+	if len(changes) == 0 {
 		log.Info().Msg("No effective changes detected between configuration and detected state.")
-		fmt.Println("\nConfiguration matches detected state. No changes needed.")
+		statusf("\nConfiguration matches detected state. No changes needed.")
 		return
 	}
 
-	// --- Display Summary of Changes ---
 	fmt.Println("\nConfiguration changes detected:")
-	fmt.Printf("- Browsers Changed: %s\n", mapChangeToString(browsersActuallyChanged))
-	fmt.Printf("- Profiles Changed: %s\n", mapChangeToString(profilesActuallyChanged))
-	fmt.Printf("- Default Profile Changed: %s\n", mapChangeToString(defaultActuallyChanged))
-	fmt.Printf("- Rules Updated/Deleted: %s\n", mapChangeToString(rulesActuallyChanged))
-
-	// Optionally show detailed diff here later if needed
+	printChanges(os.Stdout, changes)
 
-	// --- Confirm and Save Changes ---
-	if confirmAndSaveChanges(&finalCfg, cfgFile) {
+	if confirmAndSaveChanges(original, &finalCfg, changes, detectYes) {
 		log.Info().Msg("Configuration updated and saved successfully.")
-		fmt.Println("Configuration updated and saved successfully.")
+		statusf("Configuration updated and saved successfully.")
 	} else {
 		log.Info().Msg("Configuration changes discarded by user.")
-		fmt.Println("Configuration changes discarded.")
-	}
-}
-
-// mapChangeToString helper for summary
-func mapChangeToString(changed bool) string {
-	if changed {
-		return "Yes"
 	}
-	return "No"
 }
 
 // runSetDefaultProfileCmd sets the default profile ID, prompting if none is provided
 func runSetDefaultProfileCmd(cmd *cobra.Command, args []string) {
 	if cfg == nil {
 		log.Error().Msg("Configuration not loaded.")
-		os.Exit(1)
+		os.Exit(ExitConfig)
 	}
 
 	var profileID string
@@ -424,7 +852,7 @@ func runSetDefaultProfileCmd(cmd *cobra.Command, args []string) {
 		// No profile ID provided, check number of profiles
 		numProfiles := len(cfg.Profiles)
 		if numProfiles == 0 {
-			fmt.Fprintln(os.Stderr, "Error: No profiles configured. Cannot set a default.")
+			errorf("Error: No profiles configured. Cannot set a default.")
 			os.Exit(1)
 		} else if numProfiles == 1 {
 			profileID = cfg.Profiles[0].ID
@@ -435,7 +863,7 @@ func runSetDefaultProfileCmd(cmd *cobra.Command, args []string) {
 			// Pass current default as hint, empty string for currentRuleProfileID
 			profileID, err = promptSelectProfile("Select the profile to set as default:", cfg.Profiles, cfg.DefaultProfileID, "")
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error selecting profile: %v\n", err)
+				errorf("Error selecting profile: %v", err)
 				os.Exit(1)
 			}
 			if profileID == "" { // User cancelled
@@ -458,8 +886,8 @@ func runSetDefaultProfileCmd(cmd *cobra.Command, args []string) {
 	}
 
 	if !profileExists {
-		fmt.Fprintf(os.Stderr, "Error: Profile with ID '%s' not found.\n", profileID)
-		fmt.Fprintln(os.Stderr, "Use 'rurl config list' or 'rurl config profile list' to see available profiles.")
+		errorf("Error: Profile with ID '%s' not found.", profileID)
+		errorf("Use 'rurl config list' or 'rurl config profile list' to see available profiles.")
 		os.Exit(1)
 	}
 
@@ -467,11 +895,120 @@ func runSetDefaultProfileCmd(cmd *cobra.Command, args []string) {
 	cfg.DefaultProfileID = profileID
 
 	// Save the config
-	if err := config.SaveConfig(cfg, cfgFile); err != nil {
+	if err := saveConfig(cfg); err != nil {
 		log.Error().Err(err).Str("profile_id", profileID).Msg("Failed to save config after setting default profile")
-		fmt.Fprintf(os.Stderr, "Error saving configuration after setting default profile to '%s': %v\n", profileID, err)
+		errorf("Error saving configuration after setting default profile to '%s': %v", profileID, err)
+		os.Exit(ExitConfig)
+	}
+
+	statusf("Default profile successfully set to '%s'.", profileID)
+}
+
+// runConfigMigrateCmd persists the in-memory upgrade done by config.LoadConfig.
+func runConfigMigrateCmd(cmd *cobra.Command, args []string) {
+	if cfg == nil {
+		log.Error().Msg("Configuration not loaded.")
+		os.Exit(ExitConfig)
+	}
+
+	if !cfg.NeedsMigration() {
+		statusf("Configuration is already up to date.")
+		return
+	}
+	if err := saveConfig(cfg); err != nil {
+		errorf("Error saving migrated configuration: %v", err)
+		os.Exit(ExitConfig)
+	}
+	statusf("Configuration migrated and saved.")
+}
+
+// initForce makes 'config init' replace an existing config file.
+var initForce bool
+
+// runConfigInitCmd writes the default configuration file.
+func runConfigInitCmd(cmd *cobra.Command, args []string) {
+	path, err := config.InitConfig(cfgFile, initForce)
+	if errors.Is(err, fs.ErrExist) {
+		errorf("Error: %v (use --force to replace it)", err)
+		os.Exit(ExitConfig)
+	}
+	if err != nil {
+		errorf("Error creating configuration: %v", err)
+		os.Exit(ExitConfig)
+	}
+	statusf("Created default config at: %s", path)
+	fmt.Println("Run 'rurl config detect-browsers --save' to add your browsers and profiles.")
+}
+
+// runConfigSplitCmd saves the configuration split into its part files.
+func runConfigSplitCmd(cmd *cobra.Command, args []string) {
+	if cfg == nil {
+		log.Error().Msg("Configuration not loaded.")
+		os.Exit(ExitConfig)
+	}
+
+	created, err := config.SplitConfig(cfg, cfgFile)
+	if err != nil {
+		errorf("Error splitting configuration: %v", err)
+		os.Exit(ExitConfig)
+	}
+	if len(created) == 0 {
+		statusf("Configuration is already split.")
+		return
+	}
+	for _, path := range created {
+		statusf("Created %s", path)
+	}
+}
+
+// redactOutput is the file written by 'config redact-export' (empty for standard output).
+var redactOutput string
+
+func runRedactExportCmd(cmd *cobra.Command, args []string) {
+	if cfg == nil {
+		log.Error().Msg("Configuration not loaded.")
+		os.Exit(ExitConfig)
+	}
+	redactor, err := config.NewRedactor()
+	if err != nil {
+		errorf("Error: %v", err)
+		os.Exit(1)
+	}
+
+	out := os.Stdout
+	if redactOutput != "" {
+		f, err := os.Create(redactOutput)
+		if err != nil {
+			errorf("Error creating %s: %v", redactOutput, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := writeRedactedConfig(out, redactor.Redact(cfg)); err != nil {
+		errorf("Error exporting configuration: %v", err)
 		os.Exit(1)
 	}
+	if redactOutput != "" {
+		errorf("Redacted configuration written to %s. Check it before sharing.", redactOutput)
+	}
+}
 
-	fmt.Printf("Default profile successfully set to '%s'.\n", profileID)
+// writeRedactedConfig writes redacted to w in the config file format.
+func writeRedactedConfig(w io.Writer, redacted *config.Config) error {
+	dir, err := os.MkdirTemp("", "rurl-redact-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.toml")
+	if err := config.SaveConfig(redacted, path); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
 }
@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/jmylchreest/rurl/internal/browser"
 	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/launcher"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
@@ -19,11 +22,13 @@ func addBrowserCommands(parentCmd *cobra.Command) {
 	}
 
 	browserListCmd := &cobra.Command{
-		Use:   "list",
-		Short: "List configured browsers",
-		Long:  `Display all configured browsers.`,
-		Run:   runBrowserListCmd,
+		Use:         "list",
+		Short:       "List configured browsers",
+		Long:        `Display all configured browsers, optionally filtered and sorted. Output is paged when stdout is a terminal.`,
+		Annotations: map[string]string{readOnlyConfigAnnotation: ""},
+		Run:         runBrowserListCmd,
 	}
+	addListFlags(browserListCmd, browserColumns.keys())
 	browserAddCmd := &cobra.Command{
 		Use:   "add",
 		Short: "Add a new browser configuration",
@@ -47,32 +52,79 @@ func addBrowserCommands(parentCmd *cobra.Command) {
 		ValidArgsFunction: completeBrowserIDs,
 	}
 
+	browserProbeCmd := &cobra.Command{
+		Use:   "probe [browser-id]",
+		Short: "Test-launch a browser to verify its launch arguments",
+		Long: `Launches the browser with a test URL using the configured profile (and optionally
+incognito) arguments, then watches it briefly. Reports whether the browser exited
+immediately with an error, along with any output it wrote to stderr.`,
+		Args:              cobra.ExactArgs(1),
+		Run:               runBrowserProbeCmd,
+		ValidArgsFunction: completeBrowserIDs,
+	}
+	browserProbeCmd.Flags().String("profile", "", "Profile ID to probe (default: first profile of the browser)")
+	browserProbeCmd.Flags().Bool("incognito", false, "Also pass the incognito argument")
+	browserProbeCmd.Flags().String("url", "https://example.com/", "URL to open")
+	browserProbeCmd.Flags().Duration("wait", 3*time.Second, "How long to watch the process for an immediate exit")
+	browserProbeCmd.RegisterFlagCompletionFunc("profile", completeProfileFlag)
+
+	browserSetTemplateCmd := &cobra.Command{
+		Use:   "set-template [browser-id]",
+		Short: "Set a browser's launch arguments from a browser family template",
+		Long: `Sets the profile, incognito, new-window and new-tab arguments of a browser from a
+curated template for its family, so manually added browsers get the right argument syntax:
+
+  chromium  --profile-directory=%s, --incognito, --new-window
+  firefox   -P %s, --private-window, --new-window, --new-tab
+  custom    no template, only the arguments given as flags are changed
+
+Arguments given as flags override the template (e.g. --incognito-arg=--inprivate for Edge).`,
+		Args:              cobra.ExactArgs(1),
+		Run:               runBrowserSetTemplateCmd,
+		ValidArgsFunction: completeBrowserIDs,
+	}
+	browserSetTemplateCmd.Flags().String("family", "", "Browser family: chromium, firefox or custom")
+	browserSetTemplateCmd.Flags().String("profile-arg", "", "Profile argument template (use %s for the profile directory)")
+	browserSetTemplateCmd.Flags().String("incognito-arg", "", "Incognito/private window argument")
+	browserSetTemplateCmd.Flags().String("new-window-arg", "", "Argument opening a new window")
+	browserSetTemplateCmd.Flags().String("new-tab-arg", "", "Argument opening a new tab")
+	browserSetTemplateCmd.MarkFlagRequired("family")
+	browserSetTemplateCmd.RegisterFlagCompletionFunc("family", completeBrowserFamilies)
+
 	browserCmd.AddCommand(browserListCmd)
 	browserCmd.AddCommand(browserAddCmd)
 	browserCmd.AddCommand(browserEditCmd)
 	browserCmd.AddCommand(browserDeleteCmd)
+	browserCmd.AddCommand(browserProbeCmd)
+	browserCmd.AddCommand(browserSetTemplateCmd)
 	parentCmd.AddCommand(browserCmd)
 }
 
 // runBrowserListCmd displays all configured browsers
 func runBrowserListCmd(cmd *cobra.Command, args []string) {
-	cfg, err := config.LoadConfig(cfgFile)
+	cfg, err := currentConfig()
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to load configuration")
-		return
+		os.Exit(ExitConfig)
+	}
+	opts, err := listOptionsFromFlags(cmd, browserColumns.keys())
+	if err != nil {
+		errorf("Error: %v", err)
+		os.Exit(1)
 	}
 
-	printBrowserList(cfg)
+	defer startPager(cmd)()
+	printBrowserList(cfg, opts)
 }
 
 // runBrowserAddCmd adds a new browser configuration
 func runBrowserAddCmd(cmd *cobra.Command, args []string) {
 	if cfg == nil {
 		log.Error().Msg("Configuration not loaded.")
-		os.Exit(1)
+		os.Exit(ExitConfig)
 	}
 
-	var browser config.Browser
+	browser := config.Browser{Origin: config.OriginManual}
 
 	// Prompt for browser details
 	fmt.Println("Enter details for the new browser:")
@@ -81,7 +133,7 @@ func runBrowserAddCmd(cmd *cobra.Command, args []string) {
 	for {
 		browser.Name = promptString("Browser Name", "")
 		if browser.Name == "" {
-			fmt.Fprintln(os.Stderr, "Error: Browser Name cannot be empty.")
+			errorf("Error: Browser Name cannot be empty.")
 		} else {
 			break
 		}
@@ -92,7 +144,7 @@ func runBrowserAddCmd(cmd *cobra.Command, args []string) {
 	for {
 		browser.BrowserID = strings.ToLower(strings.ReplaceAll(promptString("Browser ID", defaultID), " ", "-"))
 		if browser.BrowserID == "" {
-			fmt.Fprintln(os.Stderr, "Error: Browser ID cannot be empty.")
+			errorf("Error: Browser ID cannot be empty.")
 			continue
 		}
 
@@ -105,7 +157,7 @@ func runBrowserAddCmd(cmd *cobra.Command, args []string) {
 			}
 		}
 		if idExists {
-			fmt.Fprintf(os.Stderr, "Error: Browser with ID '%s' already exists.\n", browser.BrowserID)
+			errorf("Error: Browser with ID '%s' already exists.", browser.BrowserID)
 		} else {
 			break
 		}
@@ -115,11 +167,11 @@ func runBrowserAddCmd(cmd *cobra.Command, args []string) {
 	for {
 		browser.Executable = promptString("Executable Path or Command", "")
 		if browser.Executable == "" {
-			fmt.Fprintln(os.Stderr, "Error: Executable path cannot be empty.")
+			errorf("Error: Executable path cannot be empty.")
 			continue
 		}
 		if err := validateExecutable(browser.Executable); err != nil {
-			fmt.Fprintf(os.Stderr, "Validation Error: %v\n", err)
+			errorf("Validation Error: %v", err)
 		} else {
 			break
 		}
@@ -127,25 +179,26 @@ func runBrowserAddCmd(cmd *cobra.Command, args []string) {
 
 	browser.ProfileArg = promptString("Profile Argument Template (use %s for profile dir)", "--profile-directory=%s")
 	browser.IncognitoArg = promptString("Incognito Argument", "--incognito")
+	browser.Terminal = promptYesNo("Terminal browser (e.g. lynx, w3m) run in the current terminal?", false)
 
 	// Add the browser to config
 	cfg.Browsers = append(cfg.Browsers, browser)
 
 	// Save the config
-	if err := config.SaveConfig(cfg, cfgFile); err != nil {
-		fmt.Fprintf(os.Stderr, "Error saving configuration: %v\n", err)
-		os.Exit(1)
+	if err := saveConfig(cfg); err != nil {
+		errorf("Error saving configuration: %v", err)
+		os.Exit(ExitConfig)
 	}
 
-	fmt.Printf("\nBrowser '%s' (ID: %s) added successfully.\n", browser.Name, browser.BrowserID)
+	statusf("\nBrowser '%s' (ID: %s) added successfully.", browser.Name, browser.BrowserID)
 }
 
 // runBrowserEditCmd edits an existing browser configuration
 func runBrowserEditCmd(cmd *cobra.Command, args []string) {
-	cfg, err := config.LoadConfig(cfgFile)
+	cfg, err := currentConfig()
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to load configuration")
-		return
+		os.Exit(ExitConfig)
 	}
 
 	var browserID string
@@ -159,7 +212,7 @@ func runBrowserEditCmd(cmd *cobra.Command, args []string) {
 			return
 		}
 		if selectedID == "" {
-			fmt.Println("Operation cancelled.")
+			statusf("Operation cancelled.")
 			return
 		}
 		browserID = selectedID
@@ -177,7 +230,7 @@ func runBrowserEditCmd(cmd *cobra.Command, args []string) {
 	}
 
 	if !found {
-		fmt.Fprintf(os.Stderr, "Error: Browser with ID '%s' not found.\n", browserID)
+		errorf("Error: Browser with ID '%s' not found.", browserID)
 		return
 	}
 
@@ -188,30 +241,32 @@ func runBrowserEditCmd(cmd *cobra.Command, args []string) {
 	executable := promptString("Executable Path", browser.Executable)
 	profileArg := promptString("Profile Argument", browser.ProfileArg)
 	incognitoArg := promptString("Incognito Argument", browser.IncognitoArg)
+	terminal := promptYesNo("Terminal browser (e.g. lynx, w3m) run in the current terminal?", browser.Terminal)
 
 	// Update browser
 	browser.Name = name
 	browser.Executable = executable
 	browser.ProfileArg = profileArg
 	browser.IncognitoArg = incognitoArg
+	browser.Terminal = terminal
 
 	// Save configuration
-	if err := config.SaveConfig(cfg, cfgFile); err != nil {
+	if err := saveConfig(cfg); err != nil {
 		log.Error().Err(err).Msg("Failed to save configuration")
-		return
+		os.Exit(ExitConfig)
 	}
 
-	fmt.Printf("Browser '%s' updated successfully.\n", browser.Name)
+	statusf("Browser '%s' updated successfully.", browser.Name)
 }
 
 // runBrowserDeleteCmd deletes a browser configuration
 func runBrowserDeleteCmd(cmd *cobra.Command, args []string) {
 	browserID := args[0]
 
-	cfg, err := config.LoadConfig(cfgFile)
+	cfg, err := currentConfig()
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to load configuration")
-		return
+		os.Exit(ExitConfig)
 	}
 
 	// Find and remove the browser
@@ -221,7 +276,7 @@ func runBrowserDeleteCmd(cmd *cobra.Command, args []string) {
 			// Ask for confirmation
 			confirm := promptString(fmt.Sprintf("Are you sure you want to delete browser '%s'? (y/N)", browser.Name), "N")
 			if strings.ToLower(confirm) != "y" {
-				fmt.Println("Operation cancelled.")
+				statusf("Operation cancelled.")
 				return
 			}
 
@@ -232,15 +287,157 @@ func runBrowserDeleteCmd(cmd *cobra.Command, args []string) {
 	}
 
 	if !found {
-		fmt.Fprintf(os.Stderr, "Error: Browser with ID '%s' not found.\n", browserID)
+		errorf("Error: Browser with ID '%s' not found.", browserID)
 		return
 	}
 
 	// Save configuration
-	if err := config.SaveConfig(cfg, cfgFile); err != nil {
+	if err := saveConfig(cfg); err != nil {
 		log.Error().Err(err).Msg("Failed to save configuration")
-		return
+		os.Exit(ExitConfig)
+	}
+
+	statusf("Browser '%s' deleted successfully.", browserID)
+}
+
+// runBrowserSetTemplateCmd sets a browser's launch arguments from a family template
+func runBrowserSetTemplateCmd(cmd *cobra.Command, args []string) {
+	cfg, err := currentConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load configuration")
+		os.Exit(ExitConfig)
+	}
+
+	family, _ := cmd.Flags().GetString("family")
+	overrides := map[string]string{}
+	for _, name := range []string{"profile-arg", "incognito-arg", "new-window-arg", "new-tab-arg"} {
+		if cmd.Flags().Changed(name) {
+			overrides[name], _ = cmd.Flags().GetString(name)
+		}
+	}
+
+	b, err := setBrowserTemplate(cfg, args[0], family, overrides)
+	if err != nil {
+		errorf("Error: %v", err)
+		os.Exit(1)
+	}
+
+	if err := saveConfig(cfg); err != nil {
+		errorf("Error saving configuration: %v", err)
+		os.Exit(ExitConfig)
+	}
+
+	fmt.Printf("Browser '%s' updated:\n", b.BrowserID)
+	fmt.Printf("  Profile Arg:    %s\n", b.ProfileArg)
+	fmt.Printf("  Incognito Arg:  %s\n", b.IncognitoArg)
+	fmt.Printf("  New Window Arg: %s\n", b.NewWindowArg)
+	fmt.Printf("  New Tab Arg:    %s\n", b.NewTabArg)
+}
+
+// setBrowserTemplate applies the family's argument template to the browser, then the
+// explicitly given arguments (keyed by flag name), and returns the updated browser.
+func setBrowserTemplate(cfg *config.Config, browserID, family string, overrides map[string]string) (*config.Browser, error) {
+	tmpl, err := browser.FindArgTemplate(family)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl.Family == browser.FamilyCustom && len(overrides) == 0 {
+		return nil, fmt.Errorf("the custom family needs at least one of --profile-arg, --incognito-arg, --new-window-arg or --new-tab-arg")
+	}
+	b, err := cfg.FindBrowserByID(browserID)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl.Apply(b)
+	for name, value := range overrides {
+		switch name {
+		case "profile-arg":
+			b.ProfileArg = value
+		case "incognito-arg":
+			b.IncognitoArg = value
+		case "new-window-arg":
+			b.NewWindowArg = value
+		case "new-tab-arg":
+			b.NewTabArg = value
+		}
+	}
+	if b.ProfileArg != "" && !strings.Contains(b.ProfileArg, "%s") {
+		log.Warn().Str("profile_arg", b.ProfileArg).Msg("Profile argument has no %s placeholder for the profile directory")
+	}
+	return b, nil
+}
+
+// runBrowserProbeCmd test-launches a browser and reports whether its arguments were accepted
+func runBrowserProbeCmd(cmd *cobra.Command, args []string) {
+	if cfg == nil {
+		log.Error().Msg("Configuration not loaded.")
+		os.Exit(ExitConfig)
+	}
+
+	browser, err := cfg.FindBrowserByID(args[0])
+	if err != nil {
+		errorf("Error: %v", err)
+		os.Exit(1)
+	}
+
+	profileID, _ := cmd.Flags().GetString("profile")
+	incognito, _ := cmd.Flags().GetBool("incognito")
+	testURL, _ := cmd.Flags().GetString("url")
+	wait, _ := cmd.Flags().GetDuration("wait")
+
+	var profile config.Profile
+	if profileID != "" {
+		p, err := cfg.FindProfileByID(profileID)
+		if err != nil {
+			errorf("Error: %v", err)
+			os.Exit(1)
+		}
+		if p.BrowserID != browser.BrowserID {
+			errorf("Error: Profile '%s' belongs to browser '%s', not '%s'.", p.ID, p.BrowserID, browser.BrowserID)
+			os.Exit(1)
+		}
+		profile = *p
+	} else {
+		for _, p := range cfg.Profiles {
+			if p.BrowserID == browser.BrowserID {
+				profile = p
+				break
+			}
+		}
+		if profile.ID == "" {
+			fmt.Printf("No profiles configured for '%s'; probing without a profile argument.\n", browser.BrowserID)
+		}
 	}
 
-	fmt.Printf("Browser '%s' deleted successfully.\n", browserID)
+	if incognito && browser.IncognitoArg == "" {
+		fmt.Printf("Warning: Browser '%s' has no incognito argument configured.\n", browser.BrowserID)
+	}
+
+	fmt.Printf("Probing '%s' (profile: %s, incognito: %t)...\n", browser.Name, profile.ID, incognito)
+	result, err := launcher.NewExecLauncher().Probe(*browser, profile, testURL, incognito, wait)
+	if len(result.Args) > 0 {
+		fmt.Printf("Command: %s\n", strings.Join(result.Args, " "))
+	}
+	if err != nil {
+		errorf("Error: %v", err)
+		os.Exit(1)
+	}
+
+	switch {
+	case !result.Exited:
+		fmt.Printf("Result: OK - browser still running after %s.\n", wait)
+	case result.ExitCode == 0:
+		fmt.Println("Result: OK - browser exited successfully (likely handed off to a running instance).")
+	default:
+		fmt.Printf("Result: FAILED - browser exited with code %d.\n", result.ExitCode)
+	}
+	if stderr := strings.TrimSpace(result.Stderr); stderr != "" {
+		fmt.Println("\n--- stderr ---")
+		fmt.Println(stderr)
+	}
+
+	if !result.Accepted() {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/jmylchreest/rurl/internal/config"
+)
+
+// Kinds of configChange.
+const (
+	changeAdd    = "+"
+	changeRemove = "-"
+	changeModify = "~"
+)
+
+// Sections of the configuration a configChange is in.
+const (
+	sectionBrowser = "browser"
+	sectionProfile = "profile"
+	sectionRule    = "rule"
+	sectionDefault = "default profile"
+)
+
+// configChange is a difference between the configuration and the one 'config detect
+// --save' proposes: a browser, profile or rule added, removed or modified, or a new
+// default profile.
+type configChange struct {
+	Kind    string // One of the change* kinds
+	Section string // One of the section* names
+	ID      string // The BrowserID, profile ID or rule name (the new ID for sectionDefault)
+	Fields  []fieldChange
+}
+
+// fieldChange is a field modified by a configChange, named as in the configuration.
+type fieldChange struct {
+	Name     string
+	Old, New string
+}
+
+// diffConfig returns the changes turning the browsers, profiles, rules and default
+// profile of original into those of proposed, in that order.
+func diffConfig(original, proposed *config.Config) []configChange {
+	var changes []configChange
+	changes = append(changes, diffItems(sectionBrowser, original.Browsers, proposed.Browsers, browserKey)...)
+	changes = append(changes, diffItems(sectionProfile, original.Profiles, proposed.Profiles, profileKey)...)
+	changes = append(changes, diffItems(sectionRule, original.Rules, proposed.Rules, ruleKey)...)
+	if original.DefaultProfileID != proposed.DefaultProfileID {
+		changes = append(changes, configChange{
+			Kind:    changeModify,
+			Section: sectionDefault,
+			ID:      proposed.DefaultProfileID,
+			Fields:  []fieldChange{{Name: "DefaultProfileID", Old: original.DefaultProfileID, New: proposed.DefaultProfileID}},
+		})
+	}
+	return changes
+}
+
+func browserKey(b config.Browser) string { return b.BrowserID }
+func profileKey(p config.Profile) string { return p.ID }
+func ruleKey(r config.Rule) string       { return r.Name }
+
+// diffItems returns the changes turning the items of original into those of proposed,
+// matching them by key: modified and added items in the order of proposed, followed by
+// removed items.
+func diffItems[T any](section string, original, proposed []T, key func(T) string) []configChange {
+	byKey := make(map[string]T, len(original))
+	for _, o := range original {
+		byKey[key(o)] = o
+	}
+	var changes []configChange
+	kept := make(map[string]bool)
+	for _, p := range proposed {
+		k := key(p)
+		o, ok := byKey[k]
+		switch {
+		case !ok:
+			changes = append(changes, configChange{Kind: changeAdd, Section: section, ID: k})
+		case !reflect.DeepEqual(o, p):
+			changes = append(changes, configChange{Kind: changeModify, Section: section, ID: k, Fields: fieldChanges(o, p)})
+		}
+		kept[k] = true
+	}
+	for _, o := range original {
+		if k := key(o); !kept[k] {
+			changes = append(changes, configChange{Kind: changeRemove, Section: section, ID: k})
+		}
+	}
+	return changes
+}
+
+// fieldChanges returns the fields of the structs before and after that differ, named by
+// their mapstructure tags.
+func fieldChanges(before, after any) []fieldChange {
+	ov, nv := reflect.ValueOf(before), reflect.ValueOf(after)
+	var fields []fieldChange
+	for i := 0; i < ov.NumField(); i++ {
+		f := ov.Type().Field(i)
+		if !f.IsExported() || reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("mapstructure"), ",")
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, fieldChange{Name: name, Old: formatField(ov.Field(i)), New: formatField(nv.Field(i))})
+	}
+	return fields
+}
+
+// formatField returns the value of a configuration field as shown in a diff.
+func formatField(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "(none)"
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.String {
+		return fmt.Sprintf("%q", v.String())
+	}
+	return fmt.Sprintf("%+v", v.Interface())
+}
+
+// String returns the change as listed by printChanges, without its fields.
+func (c configChange) String() string {
+	if c.Section == sectionDefault {
+		return fmt.Sprintf("%s %s: %s -> %s", c.Kind, c.Section, c.Fields[0].Old, c.Fields[0].New)
+	}
+	return fmt.Sprintf("%s %s '%s'", c.Kind, c.Section, c.ID)
+}
+
+// printChanges writes changes to w, one per line, with the fields each modification
+// changes indented below it.
+func printChanges(w io.Writer, changes []configChange) {
+	for _, c := range changes {
+		fmt.Fprintln(w, c)
+		if c.Section == sectionDefault {
+			continue
+		}
+		for _, f := range c.Fields {
+			fmt.Fprintf(w, "    %s: %s -> %s\n", f.Name, f.Old, f.New)
+		}
+	}
+}
+
+// applyChanges returns original with the accepted changes of proposed applied:
+// proposed, with the items of the rejected changes as they are in original (kept if
+// removed, dropped if added). accepted[i] tells whether changes[i] is applied.
+func applyChanges(original, proposed *config.Config, changes []configChange, accepted []bool) *config.Config {
+	rejected := make(map[string]map[string]bool)
+	for i, c := range changes {
+		if accepted[i] {
+			continue
+		}
+		if rejected[c.Section] == nil {
+			rejected[c.Section] = make(map[string]bool)
+		}
+		rejected[c.Section][c.ID] = true
+	}
+
+	out := *proposed
+	out.Browsers = applyItems(original.Browsers, proposed.Browsers, browserKey, rejected[sectionBrowser])
+	out.Profiles = applyItems(original.Profiles, proposed.Profiles, profileKey, rejected[sectionProfile])
+	out.Rules = applyItems(original.Rules, proposed.Rules, ruleKey, rejected[sectionRule])
+	if rejected[sectionDefault][proposed.DefaultProfileID] {
+		out.DefaultProfileID = original.DefaultProfileID
+	}
+	return &out
+}
+
+// applyItems returns the items of proposed, except that the items whose key is in
+// rejected are left as in original: restored, reverted, or dropped if new.
+func applyItems[T any](original, proposed []T, key func(T) string, rejected map[string]bool) []T {
+	byKey := make(map[string]T, len(original))
+	for _, o := range original {
+		byKey[key(o)] = o
+	}
+	out := make([]T, 0, len(proposed))
+	inProposed := make(map[string]bool)
+	for _, p := range proposed {
+		k := key(p)
+		inProposed[k] = true
+		if !rejected[k] {
+			out = append(out, p)
+		} else if o, ok := byKey[k]; ok {
+			out = append(out, o)
+		}
+	}
+	for _, o := range original {
+		if k := key(o); !inProposed[k] && rejected[k] {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// reviewChanges asks whether to apply changes: all of them, none, or a choice made one
+// change at a time. It returns which changes to apply, or nil if none are.
+func reviewChanges(changes []configChange) []bool {
+	answer := strings.ToLower(promptString("\nApply these changes and save the configuration? (yes/no/select)", "no"))
+	accepted := make([]bool, len(changes))
+	switch answer {
+	case "yes", "y":
+		for i := range accepted {
+			accepted[i] = true
+		}
+		return accepted
+	case "select", "s":
+		applied := false
+		for i, c := range changes {
+			accepted[i] = promptYesNo(fmt.Sprintf("Apply %s?", c), true)
+			applied = applied || accepted[i]
+		}
+		if applied {
+			return accepted
+		}
+	}
+	return nil
+}
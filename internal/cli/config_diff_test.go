@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// detectDiffConfigs returns a configuration and what detection proposes to save: a new
+// Firefox, a Chromium moved to another path, and the profile "old" removed along with
+// the default and rule using it.
+func detectDiffConfigs() (*config.Config, *config.Config) {
+	original := &config.Config{
+		DefaultProfileID: "old",
+		Browsers:         []config.Browser{{Name: "Chromium", BrowserID: "chromium", Executable: "/usr/bin/chromium"}},
+		Profiles: []config.Profile{
+			{ID: "old", BrowserID: "chromium", ProfileDir: "Profile 9"},
+			{ID: "work", BrowserID: "chromium", ProfileDir: "Profile 1"},
+		},
+		Rules: []config.Rule{{Name: "Old", Pattern: "old", ProfileID: "old"}},
+	}
+	proposed := &config.Config{
+		DefaultProfileID: "work",
+		Browsers: []config.Browser{
+			{Name: "Chromium", BrowserID: "chromium", Executable: "/snap/bin/chromium"},
+			{Name: "Firefox", BrowserID: "firefox", Executable: "/usr/bin/firefox"},
+		},
+		Profiles: []config.Profile{{ID: "work", BrowserID: "chromium", ProfileDir: "Profile 1"}},
+		Rules:    []config.Rule{{Name: "Old", Pattern: "old", ProfileID: "work"}},
+	}
+	return original, proposed
+}
+
+func TestDiffConfig(t *testing.T) {
+	original, proposed := detectDiffConfigs()
+	changes := diffConfig(original, proposed)
+	require.Len(t, changes, 5)
+
+	var out bytes.Buffer
+	printChanges(&out, changes)
+	assert.Equal(t, `~ browser 'chromium'
+    executable: "/usr/bin/chromium" -> "/snap/bin/chromium"
++ browser 'firefox'
+- profile 'old'
+~ rule 'Old'
+    ProfileID: "old" -> "work"
+~ default profile: old -> work
+`, out.String())
+
+	assert.Empty(t, diffConfig(proposed, proposed))
+}
+
+func TestApplyChanges(t *testing.T) {
+	original, proposed := detectDiffConfigs()
+	changes := diffConfig(original, proposed)
+
+	all := applyChanges(original, proposed, changes, []bool{true, true, true, true, true})
+	assert.Equal(t, proposed, all)
+
+	none := applyChanges(original, proposed, changes, make([]bool, len(changes)))
+	assert.Equal(t, original.Browsers, none.Browsers)
+	assert.ElementsMatch(t, original.Profiles, none.Profiles) // Restored profiles come last
+	assert.Equal(t, original.Rules, none.Rules)
+	assert.Equal(t, original.DefaultProfileID, none.DefaultProfileID)
+
+	// Keep the profile "old" and the rule using it, but take the rest
+	some := applyChanges(original, proposed, changes, []bool{true, true, false, false, true})
+	assert.Equal(t, proposed.Browsers, some.Browsers)
+	assert.Equal(t, []config.Profile{proposed.Profiles[0], original.Profiles[0]}, some.Profiles)
+	assert.Equal(t, original.Rules, some.Rules)
+	assert.Equal(t, "work", some.DefaultProfileID)
+	assert.Empty(t, newProblems(original, some))
+
+	// Removing the profile but keeping the rule using it leaves a dangling reference
+	dangling := applyChanges(original, proposed, changes, []bool{true, true, true, false, true})
+	assert.Len(t, newProblems(original, dangling), 1)
+}
@@ -0,0 +1,284 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/hotkey"
+	"github.com/jmylchreest/rurl/internal/launcher"
+	"github.com/jmylchreest/rurl/internal/rules"
+	"github.com/spf13/cobra"
+)
+
+// addConfigEditCommand adds 'config edit' to the config command.
+func addConfigEditCommand(configCmd *cobra.Command) {
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "edit",
+		Short: "Edit the configuration file in your editor, checking it before saving",
+		Long: `Opens a copy of the configuration file in $VISUAL or $EDITOR (vi, or notepad on
+Windows, if neither is set). When the editor exits, the copy is loaded and checked: rule
+patterns must compile, and rules, profiles and the default profile must refer to profiles
+and browsers that exist. The configuration file is only replaced once the copy passes;
+otherwise the problems are listed and you can edit it again or discard the changes.
+The file is saved as written, comments and layout included.`,
+		Args: cobra.NoArgs,
+		Run:  runConfigEditCmd,
+	})
+}
+
+// runEditor opens path in the user's editor and waits for it to exit. Replaced in tests.
+var runEditor = func(path string) error {
+	args := append(editorCommand(), path)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor '%s' failed: %w", args[0], err)
+	}
+	return nil
+}
+
+// editAgain asks whether to fix an edited configuration that failed the checks.
+// Replaced in tests.
+var editAgain = func() bool {
+	return promptYesNo("Edit the configuration again? (No discards your changes)", true)
+}
+
+// editorCommand returns the user's editor and its arguments (e.g. "code --wait").
+func editorCommand() []string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(name)); len(fields) > 0 {
+			return fields
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+func runConfigEditCmd(cmd *cobra.Command, args []string) {
+	path := cfgFile
+	if path == "" {
+		path = DefaultConfigPath()
+	}
+	saved, err := editConfigFile(path)
+	if err != nil {
+		errorf("Error: %v", err)
+		os.Exit(1)
+	}
+	if saved {
+		statusf("Configuration saved to %s.", path)
+	}
+}
+
+// editConfigFile lets the user edit a copy of the configuration file at path, and
+// replaces the file with the copy once it passes validateConfigFile. It reports whether
+// the file was replaced: an unchanged copy, or one the user gives up on, is discarded.
+func editConfigFile(path string) (bool, error) {
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config file: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// The copy keeps the extension (.toml, .yaml, ...), which the loader and editors go
+	// by, and is validated with the part files next to the original (see
+	// config.ConfigParts), so it is kept in the same directory
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".rurl-config-*"+filepath.Ext(path))
+	if err != nil {
+		return false, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(original)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	for {
+		if err := runEditor(tmp.Name()); err != nil {
+			return false, err
+		}
+		edited, err := os.ReadFile(tmp.Name())
+		if err != nil {
+			return false, fmt.Errorf("failed to read edited configuration: %w", err)
+		}
+		if bytes.Equal(edited, original) {
+			statusf("No changes made.")
+			return false, nil
+		}
+
+		problems := validateConfigFile(tmp.Name())
+		if len(problems) == 0 {
+			if err := os.WriteFile(path, edited, info.Mode().Perm()); err != nil {
+				return false, fmt.Errorf("failed to write config file: %w", err)
+			}
+			return true, nil
+		}
+		errorf("The edited configuration has problems:")
+		for _, p := range problems {
+			errorf("  - %v", p)
+		}
+		if !editAgain() {
+			fmt.Println("Changes discarded.")
+			return false, nil
+		}
+	}
+}
+
+// validateConfigFile loads the configuration file at path and checks it with
+// validateConfig.
+func validateConfigFile(path string) []error {
+	loaded, err := config.LoadConfig(path)
+	if err != nil {
+		return []error{err}
+	}
+	return validateConfig(loaded)
+}
+
+// validateConfig checks what the interactive commands otherwise guarantee: unique
+// browser, profile and rule IDs and URL list names, rule patterns that compile, and
+// references to profiles, browsers, plugins and pattern lists that exist.
+func validateConfig(c *config.Config) []error {
+	var problems []error
+	browserIDs := make(map[string]bool)
+	for _, b := range c.Browsers {
+		switch {
+		case b.BrowserID == "":
+			problems = append(problems, fmt.Errorf("browser '%s' has no BrowserID", b.Name))
+		case browserIDs[b.BrowserID]:
+			problems = append(problems, fmt.Errorf("duplicate browser ID '%s'", b.BrowserID))
+		}
+		if !config.IsWindowMode(b.WindowMode) {
+			problems = append(problems, fmt.Errorf("browser '%s' has unknown window mode '%s'", b.BrowserID, b.WindowMode))
+		}
+		if !config.IsURLPassMode(b.URLPassMode) {
+			problems = append(problems, fmt.Errorf("browser '%s' has unknown URL pass mode '%s'", b.BrowserID, b.URLPassMode))
+		}
+		browserIDs[b.BrowserID] = true
+	}
+
+	profileIDs := make(map[string]bool)
+	for _, p := range c.Profiles {
+		switch {
+		case p.ID == "":
+			problems = append(problems, fmt.Errorf("profile '%s' has no id", p.Name))
+		case profileIDs[p.ID]:
+			problems = append(problems, fmt.Errorf("duplicate profile ID '%s'", p.ID))
+		}
+		if !browserIDs[p.BrowserID] {
+			problems = append(problems, fmt.Errorf("profile '%s' refers to unknown browser '%s'", p.ID, p.BrowserID))
+		}
+		if p.Ephemeral {
+			if b, err := c.FindBrowserByID(p.BrowserID); err == nil && !launcher.SupportsEphemeral(*b) {
+				problems = append(problems, fmt.Errorf("profile '%s' is ephemeral, which browser '%s' does not support (Chromium and Firefox only)", p.ID, p.BrowserID))
+			}
+			if p.UserDataDir != "" {
+				problems = append(problems, fmt.Errorf("profile '%s' is ephemeral and cannot also set UserDataDir", p.ID))
+			}
+		}
+		profileIDs[p.ID] = true
+	}
+	if c.DefaultProfileID != "" {
+		if _, err := c.FindProfileByID(c.DefaultProfileID); err != nil {
+			problems = append(problems, fmt.Errorf("default profile '%s' does not exist", c.DefaultProfileID))
+		}
+	}
+	if c.Headless.ProfileID != "" {
+		if _, err := c.FindProfileByID(c.Headless.ProfileID); err != nil {
+			problems = append(problems, fmt.Errorf("headless profile '%s' does not exist", c.Headless.ProfileID))
+		}
+	}
+	if c.Behavior.FileProfileID != "" {
+		if _, err := c.FindProfileByID(c.Behavior.FileProfileID); err != nil {
+			problems = append(problems, fmt.Errorf("file profile '%s' does not exist", c.Behavior.FileProfileID))
+		}
+	}
+	problems = append(problems, incognitoProblems(c)...)
+
+	for _, h := range c.Hotkeys.Bindings {
+		if _, err := hotkey.Parse(h.Keys); err != nil {
+			problems = append(problems, err)
+		}
+		if _, err := c.FindProfileByID(h.ProfileID); err != nil {
+			problems = append(problems, fmt.Errorf("hotkey '%s' refers to unknown profile '%s'", h.Keys, h.ProfileID))
+		}
+	}
+
+	listNames := make(map[string]bool)
+	for _, l := range c.URLLists {
+		switch {
+		case l.Name == "":
+			problems = append(problems, fmt.Errorf("a URL list has no name"))
+		case listNames[l.Name]:
+			problems = append(problems, fmt.Errorf("duplicate URL list name '%s'", l.Name))
+		}
+		listNames[l.Name] = true
+	}
+
+	if err := c.ValidateRules(); err != nil {
+		problems = append(problems, err)
+	}
+	for _, r := range c.Rules {
+		if err := rules.ValidateRule(&r); err != nil {
+			problems = append(problems, fmt.Errorf("rule '%s': %w", r.Name, err))
+		}
+		if _, err := c.FindProfileByID(r.ProfileID); err != nil {
+			problems = append(problems, fmt.Errorf("rule '%s' routes to profile '%s', which does not exist", r.Name, r.ProfileID))
+		}
+		// Downloaded lists may not have been refreshed yet; list files must exist
+		if r.PatternListRef != "" && !listNames[r.PatternListRef] {
+			if _, err := rules.LoadPatternList(c, r.PatternListRef, r.Scope); err != nil {
+				problems = append(problems, fmt.Errorf("rule '%s': %w", r.Name, err))
+			}
+		}
+	}
+	for _, u := range slices.Sorted(maps.Keys(c.Overrides)) {
+		if _, err := c.FindProfileByID(c.Overrides[u]); err != nil {
+			problems = append(problems, fmt.Errorf("override for '%s' routes to profile '%s', which does not exist", u, c.Overrides[u]))
+		}
+	}
+	return problems
+}
+
+// incognitoProblems checks that the incognito fallback profile, if set, can open
+// private windows, and, if it cannot or is not set, that profiles always opening
+// privately can.
+func incognitoProblems(c *config.Config) []error {
+	var problems []error
+	fallbackOK := false
+	if id := c.Behavior.IncognitoFallbackProfileID; id != "" {
+		if p, err := c.FindProfileByID(id); err != nil {
+			problems = append(problems, fmt.Errorf("incognito fallback profile '%s' does not exist", id))
+		} else if b, err := c.GetProfileBrowser(p); err == nil && !launcher.SupportsIncognito(*b) {
+			problems = append(problems, fmt.Errorf("incognito fallback profile '%s' uses browser '%s', which cannot open private windows either", id, b.BrowserID))
+		} else {
+			fallbackOK = err == nil
+		}
+	}
+	if fallbackOK {
+		return problems
+	}
+	for _, p := range c.Profiles {
+		if !p.AlwaysIncognito {
+			continue
+		}
+		if b, err := c.GetProfileBrowser(&p); err == nil && !launcher.SupportsIncognito(*b) {
+			problems = append(problems, fmt.Errorf("profile '%s' always opens privately, but browser '%s' cannot open private windows (set its IncognitoArg or behavior.incognito_fallback_profile_id)", p.ID, b.BrowserID))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const editTestConfig = `default_profile_id = "chrome-default"
+
+[[browsers]]
+name = "Chrome"
+BrowserID = "chrome"
+executable = "/usr/bin/chrome"
+
+[[profiles]]
+id = "chrome-default"
+name = "Default"
+BrowserID = "chrome"
+
+# Work mail
+[[rules]]
+id = "mail"
+name = "Mail"
+pattern = "%s"
+scope = "domain"
+ProfileID = "%s"
+`
+
+func TestEditConfigFile(t *testing.T) {
+	originalEditor, originalAgain := runEditor, editAgain
+	defer func() { runEditor, editAgain = originalEditor, originalAgain }()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	original := []byte(editedConfig(`^mail\\.example\\.com$`, "chrome-default"))
+	require.NoError(t, os.WriteFile(path, original, 0600))
+
+	// A broken edit is offered for editing again, then saved as written once fixed
+	edits := []string{
+		editedConfig(`^mail\\.(example\\.com$`, "chrome-work"),
+		editedConfig(`^mail\\.example\\.org$`, "chrome-default"),
+	}
+	var prompts int
+	runEditor = func(p string) error {
+		require.NotEmpty(t, edits)
+		err := os.WriteFile(p, []byte(edits[0]), 0600)
+		edits = edits[1:]
+		return err
+	}
+	editAgain = func() bool { prompts++; return true }
+
+	saved, err := editConfigFile(path)
+	require.NoError(t, err)
+	assert.True(t, saved)
+	assert.Equal(t, 1, prompts)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, editedConfig(`^mail\\.example\\.org$`, "chrome-default"), string(data))
+
+	// Giving up leaves the file alone
+	runEditor = func(p string) error {
+		return os.WriteFile(p, []byte(editedConfig(`(`, "chrome-default")), 0600)
+	}
+	editAgain = func() bool { return false }
+	saved, err = editConfigFile(path)
+	require.NoError(t, err)
+	assert.False(t, saved)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "example\\\\.org")
+
+	// So does an unchanged copy
+	runEditor = func(string) error { return nil }
+	saved, err = editConfigFile(path)
+	require.NoError(t, err)
+	assert.False(t, saved)
+}
+
+func TestValidateConfig(t *testing.T) {
+	c := &config.Config{
+		DefaultProfileID: "missing",
+		Browsers:         []config.Browser{{BrowserID: "chrome"}, {BrowserID: "chrome"}},
+		Profiles:         []config.Profile{{ID: "work", BrowserID: "firefox"}},
+		Rules: []config.Rule{
+			{ID: "a", Name: "A", Pattern: "(", Scope: config.ScopeURL, ProfileID: "work"},
+			{ID: "b", Name: "B", Pattern: "x", Scope: config.ScopeURL, ProfileID: "personal"},
+		},
+	}
+	var messages []string
+	for _, err := range validateConfig(c) {
+		messages = append(messages, err.Error())
+	}
+	assert.Len(t, messages, 5)
+	assert.Contains(t, messages, "duplicate browser ID 'chrome'")
+	assert.Contains(t, messages, "profile 'work' refers to unknown browser 'firefox'")
+	assert.Contains(t, messages, "default profile 'missing' does not exist")
+	assert.Contains(t, messages, "rule 'B' routes to profile 'personal', which does not exist")
+
+	c.DefaultProfileID = "work"
+	c.Browsers = []config.Browser{{BrowserID: "firefox"}}
+	c.Rules = c.Rules[1:]
+	c.Rules[0].ProfileID = "work"
+	assert.Empty(t, validateConfig(c))
+
+	// Ephemeral profiles need a Chromium or Firefox browser, and no user data directory
+	c.Profiles[0].Ephemeral = true
+	c.Profiles[0].UserDataDir = "/data"
+	messages = nil
+	for _, err := range validateConfig(c) {
+		messages = append(messages, err.Error())
+	}
+	assert.Equal(t, []string{
+		"profile 'work' is ephemeral, which browser 'firefox' does not support (Chromium and Firefox only)",
+		"profile 'work' is ephemeral and cannot also set UserDataDir",
+	}, messages)
+	c.Browsers[0].ProfileArg = "-P %s"
+	c.Profiles[0].UserDataDir = ""
+	assert.Empty(t, validateConfig(c))
+}
+
+// editedConfig returns editTestConfig with the rule's pattern and profile.
+func editedConfig(pattern, profileID string) string {
+	return fmt.Sprintf(editTestConfig, pattern, profileID)
+}
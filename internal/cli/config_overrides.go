@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// override is an entry of the overrides, for listing.
+type override struct {
+	url       string
+	profileID string
+}
+
+var overrideColumns = listColumns[override]{
+	"url":     func(o override) string { return o.url },
+	"profile": func(o override) string { return o.profileID },
+}
+
+// addOverrideCommands adds the commands for the overrides of exact URLs.
+func addOverrideCommands(configCmd *cobra.Command) {
+	overrideCmd := &cobra.Command{
+		Use:   "override",
+		Short: "Manage the profiles of exact URLs, checked before the rules",
+		Long: `Overrides route exact URLs to a profile, like a hosts file: a URL given an override
+opens in its profile without evaluating the rules. They suit many precise routings (e.g.
+pinned documents or dashboards) better than one rule each. Overrides are kept in the
+[overrides] section of the configuration file, "URL" = "profile-id", and a URL only
+matches if it is exactly the same (after shorteners are resolved and URLs cleaned).`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:         "list",
+		Short:       "List the overrides",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{readOnlyConfigAnnotation: ""},
+		RunE:        runOverrideListCmd,
+	}
+	addListFlags(listCmd, overrideColumns.keys())
+	overrideCmd.AddCommand(listCmd)
+
+	overrideCmd.AddCommand(&cobra.Command{
+		Use:   "add <url> <profile-id>",
+		Short: "Open an exact URL in a profile, replacing any override for it",
+		Args:  cobra.ExactArgs(2),
+		RunE:  runOverrideAddCmd,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 1 {
+				return completeProfileIDs(cmd, args, toComplete)
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	})
+
+	overrideCmd.AddCommand(&cobra.Command{
+		Use:               "remove <url>...",
+		Short:             "Remove the overrides of URLs",
+		Args:              cobra.MinimumNArgs(1),
+		RunE:              runOverrideRemoveCmd,
+		ValidArgsFunction: completeOverrideURLs,
+	})
+	configCmd.AddCommand(overrideCmd)
+}
+
+// completeOverrideURLs completes the URLs with an override.
+func completeOverrideURLs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg := loadConfigForCompletion()
+	if cfg == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var urls []string
+	for u := range cfg.Overrides {
+		if strings.HasPrefix(u, toComplete) && !slices.Contains(args, u) {
+			urls = append(urls, u)
+		}
+	}
+	slices.Sort(urls)
+	return urls, cobra.ShellCompDirectiveNoFileComp
+}
+
+func runOverrideListCmd(cmd *cobra.Command, args []string) error {
+	opts, err := listOptionsFromFlags(cmd, overrideColumns.keys())
+	if err != nil {
+		return err
+	}
+	if len(cfg.Overrides) == 0 {
+		fmt.Println("No overrides configured.")
+		return nil
+	}
+	var overrides []override
+	for _, u := range slices.Sorted(maps.Keys(cfg.Overrides)) {
+		overrides = append(overrides, override{url: u, profileID: cfg.Overrides[u]})
+	}
+	rows := overrideColumns.apply(overrides, opts)
+	if len(rows) == 0 {
+		fmt.Println("No overrides match the filter.")
+		return nil
+	}
+
+	defer startPager(cmd)()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "URL\tProfile")
+	fmt.Fprintln(w, "---\t-------")
+	for _, o := range rows {
+		fmt.Fprintf(w, "%s\t%s\n", o.url, o.profileID)
+	}
+	return w.Flush()
+}
+
+func runOverrideAddCmd(cmd *cobra.Command, args []string) error {
+	u, profileID := args[0], args[1]
+	if _, err := cfg.FindProfileByID(profileID); err != nil {
+		return fmt.Errorf("profile '%s' not found", profileID)
+	}
+	previous, replaced := cfg.Overrides[u]
+	if cfg.Overrides == nil {
+		cfg.Overrides = make(map[string]string)
+	}
+	cfg.Overrides[u] = profileID
+	if err := saveConfig(cfg); err != nil {
+		return withExitCode(ExitConfig, fmt.Errorf("failed to save configuration: %w", err))
+	}
+	if replaced {
+		statusf("Override for %s now opens in profile '%s' (was '%s').", u, profileID, previous)
+	} else {
+		statusf("Override added: %s opens in profile '%s'.", u, profileID)
+	}
+	return nil
+}
+
+func runOverrideRemoveCmd(cmd *cobra.Command, args []string) error {
+	for _, u := range args {
+		if _, ok := cfg.Overrides[u]; !ok {
+			return fmt.Errorf("no override for '%s'", u)
+		}
+	}
+	for _, u := range args {
+		delete(cfg.Overrides, u)
+	}
+	if err := saveConfig(cfg); err != nil {
+		return withExitCode(ExitConfig, fmt.Errorf("failed to save configuration: %w", err))
+	}
+	statusf("Removed %d override(s).", len(args))
+	return nil
+}
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverrideCommands(t *testing.T) {
+	originalCfg, originalFile := cfg, cfgFile
+	defer func() { cfg, cfgFile = originalCfg, originalFile }()
+	cfgFile = filepath.Join(t.TempDir(), "config.toml")
+	cfg = config.DefaultConfig()
+	cfg.Profiles = []config.Profile{{ID: "work", Name: "Work", BrowserID: "chrome"}, {ID: "home", Name: "Home", BrowserID: "chrome"}}
+
+	const pinned = "https://Docs.example.com/d/Q3.pdf?v=2"
+	out := captureStdout(t, func() { require.NoError(t, runOverrideAddCmd(nil, []string{pinned, "work"})) })
+	assert.Contains(t, out, "Override added")
+	out = captureStdout(t, func() { require.NoError(t, runOverrideAddCmd(nil, []string{pinned, "home"})) })
+	assert.Contains(t, out, "(was 'work')")
+	assert.ErrorContains(t, runOverrideAddCmd(nil, []string{pinned, "gone"}), "profile 'gone' not found")
+
+	loaded, err := config.LoadConfig(cfgFile)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{pinned: "home"}, loaded.Overrides)
+
+	assert.ErrorContains(t, runOverrideRemoveCmd(nil, []string{"https://example.com/"}), "no override")
+	captureStdout(t, func() { require.NoError(t, runOverrideRemoveCmd(nil, []string{pinned})) })
+	loaded, err = config.LoadConfig(cfgFile)
+	require.NoError(t, err)
+	assert.Empty(t, loaded.Overrides)
+}
@@ -3,10 +3,14 @@ package cli
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 
 	// Needed for printProfileList
+	"github.com/jmylchreest/rurl/internal/browser"
 	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/launcher"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	//"github.com/spf13/viper" // Not needed directly in profile funcs anymore
@@ -21,17 +25,38 @@ func addProfileCommands(parentCmd *cobra.Command) {
 	}
 
 	profileListCmd := &cobra.Command{
-		Use:   "list",
-		Short: "List configured profiles",
-		Long:  `Display all configured profiles.`,
-		Run:   runProfileListCmd,
+		Use:         "list",
+		Short:       "List configured profiles",
+		Long:        `Display all configured profiles, optionally filtered and sorted. Output is paged when stdout is a terminal.`,
+		Annotations: map[string]string{readOnlyConfigAnnotation: ""},
+		Run:         runProfileListCmd,
 	}
+	addListFlags(profileListCmd, profileColumns.keys())
 	profileAddCmd := &cobra.Command{
 		Use:   "add",
 		Short: "Add a new profile configuration",
 		Long:  `Interactively add a new profile configuration.`,
 		Run:   runProfileAddCmd,
 	}
+	profileCreateCmd := &cobra.Command{
+		Use:   "create-in-browser <browser-id> --name <name>",
+		Short: "Create a new profile in a browser and add it",
+		Long: `Create a new profile in the browser itself, then add it to the configuration.
+
+Chromium-based browsers get the next free "Profile N" directory in their user data directory
+(or the one given with --user-data-dir), registered in its Local State file; close the browser
+first, as it rewrites that file on exit. Firefox-based browsers create the profile themselves
+(firefox -CreateProfile).`,
+		Args:              cobra.ExactArgs(1),
+		RunE:              runProfileCreateInBrowserCmd,
+		ValidArgsFunction: completeBrowserIDs,
+	}
+	profileCreateCmd.Flags().String("name", "", "Name of the new profile (required)")
+	profileCreateCmd.Flags().String("id", "", "Profile ID in rurl (default: derived from the browser and profile directory)")
+	profileCreateCmd.Flags().String("user-data-dir", "", "Chromium user data directory to create the profile in (default: the browser's own)")
+	profileCreateCmd.Flags().Bool("default", false, "Make the new profile the default profile")
+	_ = profileCreateCmd.MarkFlagRequired("name")
+
 	profileEditCmd := &cobra.Command{
 		Use:               "edit [profile-id]",
 		Short:             "Edit a profile configuration",
@@ -41,18 +66,38 @@ func addProfileCommands(parentCmd *cobra.Command) {
 		ValidArgsFunction: completeProfileIDs, // Register completer
 	}
 	profileDeleteCmd := &cobra.Command{
-		Use:               "delete [profile-id]",
-		Short:             "Delete a profile configuration",
-		Long:              `Delete an existing profile configuration. If only one exists, it will be selected automatically if no ID is provided (confirmation still required).`,
+		Use:   "delete [profile-id]",
+		Short: "Delete a profile configuration",
+		Long: `Delete an existing profile configuration. If only one exists, it will be selected automatically if no ID is provided (confirmation still required).
+
+Rules using the profile are moved to another profile or deleted, one at a time as you
+choose, or all moved to the profile given with --reassign-to.`,
 		Args:              cobra.MaximumNArgs(1), // Allow 0 or 1 arg
 		Run:               runProfileDeleteCmd,
 		ValidArgsFunction: completeProfileIDs, // Register completer
 	}
+	profileDeleteCmd.Flags().String("reassign-to", "", "Move the rules using the profile to this profile without asking")
+	profileDeleteCmd.Flags().BoolP("yes", "y", false, "Delete without asking for confirmation")
+	_ = profileDeleteCmd.RegisterFlagCompletionFunc("reassign-to", completeProfileFlag)
+
+	profileGCCmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove the directories of ephemeral profiles",
+		Long: `Removes the temporary directories created for ephemeral profiles (Ephemeral = true)
+whose browser has exited. This is also done on each launch; directories still locked by a
+browser are kept, unless --all is given (e.g. after the browser crashed).`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{readOnlyConfigAnnotation: ""},
+		RunE:        runProfileGCCmd,
+	}
+	profileGCCmd.Flags().Bool("all", false, "Also remove directories that look in use (close those browsers first)")
 
 	profileCmd.AddCommand(profileListCmd)
 	profileCmd.AddCommand(profileAddCmd)
+	profileCmd.AddCommand(profileCreateCmd)
 	profileCmd.AddCommand(profileEditCmd)
 	profileCmd.AddCommand(profileDeleteCmd)
+	profileCmd.AddCommand(profileGCCmd)
 	parentCmd.AddCommand(profileCmd)
 }
 
@@ -60,9 +105,16 @@ func addProfileCommands(parentCmd *cobra.Command) {
 func runProfileListCmd(cmd *cobra.Command, args []string) {
 	if cfg == nil {
 		log.Error().Msg("Configuration not loaded.")
+		os.Exit(ExitConfig)
+	}
+	opts, err := listOptionsFromFlags(cmd, profileColumns.keys())
+	if err != nil {
+		errorf("Error: %v", err)
 		os.Exit(1)
 	}
-	printProfileList(cfg) // Pass cfg explicitly
+
+	defer startPager(cmd)()
+	printProfileList(cfg, opts) // Pass cfg explicitly
 }
 
 // printProfileList handles the actual printing of the profile list using tabwriter
@@ -72,7 +124,7 @@ func runProfileListCmd(cmd *cobra.Command, args []string) {
 func runProfileAddCmd(cmd *cobra.Command, args []string) {
 	if cfg == nil {
 		log.Error().Msg("Configuration not loaded.")
-		os.Exit(1)
+		os.Exit(ExitConfig)
 	}
 
 	if len(cfg.Browsers) == 0 {
@@ -80,13 +132,13 @@ func runProfileAddCmd(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	var profile config.Profile
+	profile := config.Profile{Origin: config.OriginManual}
 
 	fmt.Println("\nEnter details for the new profile:")
 
 	// List available browsers for user convenience
 	// fmt.Println("\nAvailable Browsers:") // Header printed by printBrowserList now
-	printBrowserList(cfg) // Call helper from utils.go
+	printBrowserList(cfg, listOptions{}) // Call helper from utils.go
 
 	// Prompt for browser ID and validate
 	for {
@@ -107,7 +159,7 @@ func runProfileAddCmd(cmd *cobra.Command, args []string) {
 	// Prompt for Profile Name first
 	profile.Name = promptString("Profile Name", "")
 	if profile.Name == "" {
-		fmt.Fprintln(os.Stderr, "Error: Profile Name cannot be empty.")
+		errorf("Error: Profile Name cannot be empty.")
 		// Exiting for simplicity as originally implemented
 		os.Exit(1)
 	}
@@ -118,7 +170,7 @@ func runProfileAddCmd(cmd *cobra.Command, args []string) {
 		profile.ID = strings.ToLower(strings.ReplaceAll(promptString("Profile ID", defaultID), " ", "-"))
 
 		if profile.ID == "" {
-			fmt.Fprintln(os.Stderr, "Error: Profile ID cannot be empty.")
+			errorf("Error: Profile ID cannot be empty.")
 			continue
 		}
 
@@ -126,7 +178,7 @@ func runProfileAddCmd(cmd *cobra.Command, args []string) {
 		idExists := false
 		for _, p := range cfg.Profiles {
 			if p.ID == profile.ID {
-				fmt.Fprintf(os.Stderr, "Error: Profile with ID '%s' already exists.\n", profile.ID)
+				errorf("Error: Profile with ID '%s' already exists.", profile.ID)
 				idExists = true
 				break
 			}
@@ -138,6 +190,10 @@ func runProfileAddCmd(cmd *cobra.Command, args []string) {
 	}
 
 	profile.ProfileDir = promptString("Profile Directory Name/Path (relative to browser's user data)", "Default") // Often "Default", "Profile 1", etc.
+	profile.AlwaysIncognito = promptYesNo("Always open URLs in this profile in a private window?", false)
+	if b, err := cfg.FindBrowserByID(profile.BrowserID); err == nil && launcher.SupportsEphemeral(*b) {
+		profile.Ephemeral = promptYesNo("Open each URL in a new, empty profile deleted afterwards (ephemeral)?", false)
+	}
 
 	// Add the profile to config
 	cfg.Profiles = append(cfg.Profiles, profile)
@@ -157,19 +213,70 @@ func runProfileAddCmd(cmd *cobra.Command, args []string) {
 	}
 
 	// Save the config
-	if err := config.SaveConfig(cfg, cfgFile); err != nil {
-		fmt.Fprintf(os.Stderr, "Error saving configuration: %v\n", err)
-		os.Exit(1)
+	if err := saveConfig(cfg); err != nil {
+		errorf("Error saving configuration: %v", err)
+		os.Exit(ExitConfig)
 	}
 
-	fmt.Printf("\nProfile '%s' (ID: %s) added successfully.\n", profile.Name, profile.ID)
+	statusf("\nProfile '%s' (ID: %s) added successfully.", profile.Name, profile.ID)
+}
+
+// runProfileCreateInBrowserCmd creates a profile in a browser and adds it to the
+// configuration.
+func runProfileCreateInBrowserCmd(cmd *cobra.Command, args []string) error {
+	if cfg == nil {
+		return withExitCode(ExitConfig, fmt.Errorf("configuration not loaded"))
+	}
+	b, err := cfg.FindBrowserByID(args[0])
+	if err != nil {
+		return err
+	}
+	name, _ := cmd.Flags().GetString("name")
+	id, _ := cmd.Flags().GetString("id")
+	dataDir, _ := cmd.Flags().GetString("user-data-dir")
+	makeDefault, _ := cmd.Flags().GetBool("default")
+	if id != "" && profileIDExists(id) {
+		return fmt.Errorf("a profile with ID '%s' already exists", id)
+	}
+
+	profile, err := browser.CreateProfile(*b, name, dataDir)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		profile.ID = id
+	}
+	for base, n := profile.ID, 2; profileIDExists(profile.ID); n++ {
+		profile.ID = fmt.Sprintf("%s-%d", base, n)
+	}
+
+	cfg.Profiles = append(cfg.Profiles, profile)
+	if makeDefault || cfg.DefaultProfileID == "" {
+		cfg.DefaultProfileID = profile.ID
+	}
+	if err := saveConfig(cfg); err != nil {
+		return withExitCode(ExitConfig, fmt.Errorf("failed to save config: %w", err))
+	}
+
+	statusf("Profile '%s' created in %s (%s) and added with ID '%s'.", profile.Name, b.Name, profile.ProfileDir, profile.ID)
+	return nil
+}
+
+// profileIDExists reports whether a configured profile has the ID id.
+func profileIDExists(id string) bool {
+	for _, p := range cfg.Profiles {
+		if p.ID == id {
+			return true
+		}
+	}
+	return false
 }
 
 // runProfileEditCmd edits an existing profile configuration
 func runProfileEditCmd(cmd *cobra.Command, args []string) {
 	if cfg == nil {
 		log.Error().Msg("Configuration not loaded.")
-		os.Exit(1)
+		os.Exit(ExitConfig)
 	}
 
 	var profileID string
@@ -181,15 +288,15 @@ func runProfileEditCmd(cmd *cobra.Command, args []string) {
 			profileID = cfg.Profiles[0].ID
 			log.Info().Str("profile_id", profileID).Msg("Only one profile found, selecting it automatically for editing.")
 		} else if len(cfg.Profiles) == 0 {
-			fmt.Fprintln(os.Stderr, "Error: No profiles configured to edit.")
-			fmt.Fprintln(os.Stderr, "Use 'rurl config profile add'.")
+			errorf("Error: No profiles configured to edit.")
+			errorf("Use 'rurl config profile add'.")
 			os.Exit(1)
 		} else {
 			// Multiple profiles exist, prompt user
 			fmt.Println("Multiple profiles configured.")
 			profileID, err = promptSelectProfile("Select the profile to edit:", cfg.Profiles, cfg.DefaultProfileID, "")
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error selecting profile: %v\n", err)
+				errorf("Error selecting profile: %v", err)
 				os.Exit(1)
 			}
 			if profileID == "" { // User cancelled selection
@@ -215,7 +322,7 @@ func runProfileEditCmd(cmd *cobra.Command, args []string) {
 	}
 
 	if profile == nil {
-		fmt.Fprintf(os.Stderr, "Error: Profile with ID '%s' not found.\n", profileID)
+		errorf("Error: Profile with ID '%s' not found.", profileID)
 		os.Exit(1)
 	}
 
@@ -225,7 +332,7 @@ func runProfileEditCmd(cmd *cobra.Command, args []string) {
 	originalName := profile.Name // Store original name
 	newName := promptString("Profile Name", originalName)
 	if newName == "" {
-		fmt.Fprintln(os.Stderr, "Error: Profile Name cannot be empty. Keeping original.")
+		errorf("Error: Profile Name cannot be empty. Keeping original.")
 		newName = originalName // Revert if empty
 	}
 	profile.Name = newName
@@ -235,7 +342,7 @@ func runProfileEditCmd(cmd *cobra.Command, args []string) {
 	for {
 		newProfileID = strings.ToLower(strings.ReplaceAll(promptString("Profile ID", originalID), " ", "-"))
 		if newProfileID == "" {
-			fmt.Fprintln(os.Stderr, "Error: Profile ID cannot be empty.")
+			errorf("Error: Profile ID cannot be empty.")
 			continue
 		}
 		// Check if new profile ID already exists (if changed)
@@ -249,7 +356,7 @@ func runProfileEditCmd(cmd *cobra.Command, args []string) {
 			}
 		}
 		if idExists {
-			fmt.Fprintf(os.Stderr, "Error: Profile with ID '%s' already exists.\n", newProfileID)
+			errorf("Error: Profile with ID '%s' already exists.", newProfileID)
 			// Loop continues, prompts again
 		} else {
 			break // Unique ID entered
@@ -280,6 +387,44 @@ func runProfileEditCmd(cmd *cobra.Command, args []string) {
 	}
 
 	profile.ProfileDir = promptString("Profile Directory Name/Path", profile.ProfileDir)
+	profile.UserDataDir = promptString("User Data Directory (empty uses the browser's default)", profile.UserDataDir)
+	profile.Email = promptString("Account Email (for rules targeting email:<address>, empty for none)", profile.Email)
+	for {
+		profile.ExecutableOverride = promptString("Executable Override (empty uses the browser's executable)", profile.ExecutableOverride)
+		if profile.ExecutableOverride == "" {
+			break
+		}
+		if err := validateExecutable(profile.ExecutableOverride); err != nil {
+			errorf("Validation Error: %v", err)
+		} else {
+			break
+		}
+	}
+	profile.AlwaysIncognito = promptYesNo("Always open URLs in this profile in a private window?", profile.AlwaysIncognito)
+	if b, err := cfg.FindBrowserByID(profile.BrowserID); err == nil && launcher.SupportsEphemeral(*b) {
+		profile.Ephemeral = promptYesNo("Open each URL in a new, empty profile deleted afterwards (ephemeral)?", profile.Ephemeral)
+	}
+	profile.WorkingDir = promptString("Working Directory (empty uses the current one)", profile.WorkingDir)
+	for {
+		nice, err := strconv.Atoi(promptString("Nice Level (positive lowers the priority, 0 for none)", strconv.Itoa(profile.Nice)))
+		if err != nil {
+			errorf("Validation Error: nice level must be a number")
+			continue
+		}
+		profile.Nice = nice
+		break
+	}
+	for {
+		profile.Sandbox = promptString("Sandbox Command (e.g. firejail --private, empty for none)", profile.Sandbox)
+		if profile.Sandbox == "" {
+			break
+		}
+		if err := launcher.ValidateSandbox(profile.Sandbox); err != nil {
+			errorf("Validation Error: %v", err)
+		} else {
+			break
+		}
+	}
 
 	// Offer to make this the default profile
 	if cfg.DefaultProfileID != profile.ID { // Use potentially updated profile.ID
@@ -288,23 +433,23 @@ func runProfileEditCmd(cmd *cobra.Command, args []string) {
 			fmt.Println("Profile set as default.")
 		}
 	} else {
-		fmt.Println("This is already the default profile.")
+		statusf("This is already the default profile.")
 	}
 
 	// Save the config
-	if err := config.SaveConfig(cfg, cfgFile); err != nil {
-		fmt.Fprintf(os.Stderr, "Error saving configuration: %v\n", err)
-		os.Exit(1)
+	if err := saveConfig(cfg); err != nil {
+		errorf("Error saving configuration: %v", err)
+		os.Exit(ExitConfig)
 	}
 
-	fmt.Printf("\nProfile '%s' (ID: %s) updated successfully.\n", profile.Name, profile.ID)
+	statusf("\nProfile '%s' (ID: %s) updated successfully.", profile.Name, profile.ID)
 }
 
 // runProfileDeleteCmd deletes a profile configuration
 func runProfileDeleteCmd(cmd *cobra.Command, args []string) {
 	if cfg == nil {
 		log.Error().Msg("Configuration not loaded.")
-		os.Exit(1)
+		os.Exit(ExitConfig)
 	}
 
 	var profileID string
@@ -315,7 +460,7 @@ func runProfileDeleteCmd(cmd *cobra.Command, args []string) {
 			profileID = cfg.Profiles[0].ID
 			log.Info().Str("profile_id", profileID).Msg("Only one profile found, selecting it automatically for deletion attempt.")
 		} else if len(cfg.Profiles) == 0 {
-			fmt.Fprintln(os.Stderr, "Error: No profiles configured to delete.")
+			errorf("Error: No profiles configured to delete.")
 			os.Exit(1)
 		} else {
 			// Multiple profiles exist, prompt user
@@ -323,11 +468,11 @@ func runProfileDeleteCmd(cmd *cobra.Command, args []string) {
 			var err error
 			profileID, err = promptSelectProfile("Select the profile to delete:", cfg.Profiles, cfg.DefaultProfileID, "") // Use the existing helper
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error selecting profile: %v\n", err)
+				errorf("Error selecting profile: %v", err)
 				os.Exit(1)
 			}
 			if profileID == "" { // User cancelled selection
-				fmt.Println("Deletion cancelled.")
+				statusf("Deletion cancelled.")
 				os.Exit(0) // Exit gracefully
 			}
 			log.Info().Str("profile_id", profileID).Msg("Profile selected by user for deletion.")
@@ -351,48 +496,87 @@ func runProfileDeleteCmd(cmd *cobra.Command, args []string) {
 	}
 
 	if index == -1 {
-		fmt.Fprintf(os.Stderr, "Error: Profile with ID '%s' not found.\n", profileID)
+		errorf("Error: Profile with ID '%s' not found.", profileID)
 		os.Exit(1)
 	}
 
 	// Prevent deleting the default profile
 	if cfg.DefaultProfileID == profileID {
-		fmt.Fprintf(os.Stderr, "Error: Cannot delete the default profile (ID: %s).\n", profileID)
-		fmt.Fprintln(os.Stderr, "Please set a different default profile first using 'rurl config set-default <other-profile-id>'.")
+		errorf("Error: Cannot delete the default profile (ID: %s).", profileID)
+		errorf("Please set a different default profile first using 'rurl config set-default <other-profile-id>'.")
 		os.Exit(1)
 	}
 
-	// Check if any rules reference this profile ID
-	var referencingRules []string
-	for _, r := range cfg.Rules {
-		if r.ProfileID == profileID {
-			referencingRules = append(referencingRules, r.Name)
-		}
-	}
-	if len(referencingRules) > 0 {
-		fmt.Fprintf(os.Stderr, "Error: Cannot delete profile '%s' (ID: %s) because it is referenced by the following rule(s):\n", profileName, profileID)
-		for _, ruleName := range referencingRules {
-			fmt.Fprintf(os.Stderr, "  - %s\n", ruleName)
-		}
-		fmt.Fprintln(os.Stderr, "Please edit or delete the rule(s) first.")
+	// Move or delete the rules using the profile, like detect-browsers does for the
+	// profiles it removes
+	reassignTo, _ := cmd.Flags().GetString("reassign-to")
+	remaining := slices.Delete(slices.Clone(cfg.Profiles), index, index+1)
+	rulesToUpdate, rulesToDelete, err := orphanedRulesOfDeletedProfile(cfg.Rules, profileID, reassignTo, remaining)
+	if err != nil {
+		errorf("Error: %v", err)
 		os.Exit(1)
 	}
 
 	// Confirm deletion
-	confirm := promptString(fmt.Sprintf("Are you sure you want to delete profile '%s' (ID: %s)? (yes/no)", profileName, profileID), "no")
-	if !strings.EqualFold(confirm, "yes") {
-		fmt.Println("Deletion cancelled.")
-		return
+	assumeYes, _ := cmd.Flags().GetBool("yes")
+	if !assumeYes {
+		confirm := promptString(fmt.Sprintf("Are you sure you want to delete profile '%s' (ID: %s)? (yes/no)", profileName, profileID), "no")
+		if !strings.EqualFold(confirm, "yes") {
+			statusf("Deletion cancelled.")
+			return
+		}
 	}
 
+	cfg.Rules = applyRuleUpdates(cfg.Rules, rulesToUpdate, rulesToDelete)
 	// Remove the profile
 	cfg.Profiles = append(cfg.Profiles[:index], cfg.Profiles[index+1:]...)
 
 	// Save the config
-	if err := config.SaveConfig(cfg, cfgFile); err != nil {
-		fmt.Fprintf(os.Stderr, "Error saving configuration: %v\n", err)
-		os.Exit(1)
+	if err := saveConfig(cfg); err != nil {
+		errorf("Error saving configuration: %v", err)
+		os.Exit(ExitConfig)
 	}
 
-	fmt.Printf("\nProfile '%s' (ID: %s) deleted successfully.\n", profileName, profileID)
+	statusf("\nProfile '%s' (ID: %s) deleted successfully.", profileName, profileID)
+}
+
+// orphanedRulesOfDeletedProfile decides what becomes of the rules using the profile
+// profileID, which is being deleted, leaving the profiles remaining: they move to
+// reassignTo if set, or else as the user chooses for each (see handleOrphanedRules).
+// It returns the new profile of the rules to update and the rules to delete, by name.
+func orphanedRulesOfDeletedProfile(rules []config.Rule, profileID, reassignTo string, remaining []config.Profile) (map[string]string, map[string]struct{}, error) {
+	removed := map[string]struct{}{profileID: {}}
+	if reassignTo == "" {
+		rulesToUpdate, rulesToDelete := handleOrphanedRules(rules, removed, remaining)
+		return rulesToUpdate, rulesToDelete, nil
+	}
+
+	if reassignTo == profileID {
+		return nil, nil, fmt.Errorf("cannot reassign the rules of profile '%s' to itself", profileID)
+	}
+	if !slices.ContainsFunc(remaining, func(p config.Profile) bool { return p.ID == reassignTo }) {
+		return nil, nil, fmt.Errorf("profile '%s' to reassign the rules to not found", reassignTo)
+	}
+	rulesToUpdate := make(map[string]string)
+	for _, r := range rules {
+		if r.ProfileID == profileID {
+			statusf("Info: Rule '%s' updated to use profile '%s'.", r.Name, reassignTo)
+			rulesToUpdate[r.Name] = reassignTo
+		}
+	}
+	return rulesToUpdate, map[string]struct{}{}, nil
+}
+
+// runProfileGCCmd removes the directories of ephemeral profiles no longer in use.
+func runProfileGCCmd(cmd *cobra.Command, args []string) error {
+	all, _ := cmd.Flags().GetBool("all")
+	removed, inUse, err := launcher.CleanEphemeral(all)
+	if err != nil {
+		return err
+	}
+	statusf("Removed %d ephemeral profile director(ies).", removed)
+	if inUse > 0 {
+		statusf("%d still in use; close their browsers, or use --all if they are gone.", inUse)
+	}
+	return nil
 }
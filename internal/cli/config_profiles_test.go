@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileDeleteReassignTo(t *testing.T) {
+	originalCfg, originalFile := cfg, cfgFile
+	defer func() { cfg, cfgFile = originalCfg, originalFile }()
+	cfgFile = filepath.Join(t.TempDir(), "config.toml")
+	cfg = config.DefaultConfig()
+	cfg.Browsers = []config.Browser{{Name: "Chrome", BrowserID: "chrome", Executable: "/usr/bin/google-chrome"}}
+	cfg.Profiles = []config.Profile{
+		{ID: "personal", Name: "Personal", BrowserID: "chrome"},
+		{ID: "work", Name: "Work", BrowserID: "chrome"},
+		{ID: "old-work", Name: "Old Work", BrowserID: "chrome"},
+	}
+	cfg.DefaultProfileID = "personal"
+	cfg.Rules = []config.Rule{
+		{ID: "docs", Name: "Docs", Pattern: "docs", Scope: config.ScopeDomain, ProfileID: "old-work"},
+		{ID: "mail", Name: "Mail", Pattern: "mail", Scope: config.ScopeDomain, ProfileID: "personal"},
+		{ID: "jira", Name: "Jira", Pattern: "jira", Scope: config.ScopeDomain, ProfileID: "old-work"},
+	}
+
+	// The target must be another existing profile
+	_, _, err := orphanedRulesOfDeletedProfile(cfg.Rules, "old-work", "old-work", cfg.Profiles[:2])
+	assert.ErrorContains(t, err, "to itself")
+	_, _, err = orphanedRulesOfDeletedProfile(cfg.Rules, "old-work", "gone", cfg.Profiles[:2])
+	assert.ErrorContains(t, err, "'gone'")
+
+	cmd, _, err := rootCmd.Find([]string{"config", "profile", "delete"})
+	require.NoError(t, err)
+	require.NoError(t, cmd.Flags().Set("reassign-to", "work"))
+	require.NoError(t, cmd.Flags().Set("yes", "true"))
+	defer func() {
+		_ = cmd.Flags().Set("reassign-to", "")
+		_ = cmd.Flags().Set("yes", "false")
+	}()
+	out := captureStdout(t, func() { runProfileDeleteCmd(cmd, []string{"old-work"}) })
+	assert.Contains(t, out, "Rule 'Docs' updated to use profile 'work'")
+
+	loaded, err := config.LoadConfig(cfgFile)
+	require.NoError(t, err)
+	require.Len(t, loaded.Profiles, 2)
+	ruleProfiles := make(map[string]string)
+	for _, r := range loaded.Rules {
+		ruleProfiles[r.Name] = r.ProfileID
+	}
+	assert.Equal(t, map[string]string{"Docs": "work", "Mail": "personal", "Jira": "work"}, ruleProfiles)
+}
+
+func TestApplyRuleUpdates(t *testing.T) {
+	rules := []config.Rule{{Name: "A", ProfileID: "x"}, {Name: "B", ProfileID: "x"}, {Name: "C", ProfileID: "y"}}
+	got := applyRuleUpdates(rules, map[string]string{"A": "z"}, map[string]struct{}{"B": {}})
+	assert.Equal(t, []config.Rule{{Name: "A", ProfileID: "z"}, {Name: "C", ProfileID: "y"}}, got)
+	assert.Equal(t, "x", rules[0].ProfileID) // The input is left alone
+}
@@ -2,10 +2,17 @@ package cli
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
 
 	"github.com/cqroot/prompt"
 	"github.com/cqroot/prompt/choose"
 	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/history"
+	"github.com/jmylchreest/rurl/internal/launcher"
+	"github.com/jmylchreest/rurl/internal/rules"
 	"github.com/spf13/cobra"
 )
 
@@ -24,39 +31,128 @@ func AddRuleCommands(configCmd *cobra.Command) {
 	ruleListCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List configured rules",
-		Long:  `Display all configured rules.`,
-		RunE:  runRuleListCmd,
+		Long: `Display all configured rules. With --tag, only the rules carrying all the given tags are
+listed, and with --filter only those with a column matching the filter. Rules are listed in
+the order they are evaluated unless --sort is given. Output is paged when stdout is a terminal.`,
+		Annotations: map[string]string{readOnlyConfigAnnotation: ""},
+		RunE:        runRuleListCmd,
 	}
+	addListFlags(ruleListCmd, ruleColumns.keys())
+	ruleListCmd.Flags().StringSlice("tag", nil, "Only list rules with this tag (repeatable)")
+	ruleListCmd.Flags().String("unused-since", "", "List the rules and profiles not used for this long instead (e.g. 90d; requires usage.enabled)")
+	_ = ruleListCmd.RegisterFlagCompletionFunc("tag", completeRuleTags)
 
 	ruleAddCmd := &cobra.Command{
-		Use:   "add",
+		Use:   "add [domain]",
 		Short: "Add a new rule",
-		Long:  `Interactively add a new URL routing rule.`,
-		RunE:  runRuleAddCmd,
+		Long: `Interactively add a new URL routing rule. If a domain (or URL) is given, the pattern
+starts as an exact match of that domain with the domain scope. When launch history is enabled,
+recently routed domains are offered as shell completions.
+
+With --handler, matching URLs are passed to a command instead of a browser profile, e.g.
+--handler 'mpv "%u"' (%u is replaced by the URL, or the URL is appended if it is missing).`,
+		Args:              cobra.MaximumNArgs(1),
+		RunE:              runRuleAddCmd,
+		ValidArgsFunction: completeHistoryDomains,
+	}
+	ruleAddCmd.Flags().String("handler", "", "Command to pass matching URLs to instead of a browser (e.g. 'mpv \"%u\"')")
+
+	ruleFromLastCmd := &cobra.Command{
+		Use:   "from-last",
+		Short: "Add a rule for the domain of the last routed URL",
+		Long: `Create a rule routing the domain of the most recently routed URL, asking only which profile
+it should open in from now on. The rule is named after the domain and matches it exactly with
+the domain scope; use 'rurl config rule edit' to refine it. Requires launch history.`,
+		Args: cobra.NoArgs,
+		RunE: runRuleFromLastCmd,
 	}
 
 	ruleEditCmd := &cobra.Command{
-		Use:               "edit [rule-name]",
+		Use:               "edit [rule-id|rule-name]",
 		Short:             "Edit an existing rule",
-		Long:              `Interactively edit an existing rule. If only one rule exists, it will be selected automatically if no name is provided.`,
+		Long:              `Interactively edit an existing rule, selected by ID or name. If no rule is given, you will be prompted to choose one.`,
 		Args:              cobra.MaximumNArgs(1),
 		RunE:              runRuleEditCmd,
 		ValidArgsFunction: completeRuleNames,
 	}
 
 	ruleDeleteCmd := &cobra.Command{
-		Use:               "delete [rule-name]",
+		Use:               "delete [rule-id|rule-name]",
 		Short:             "Delete an existing rule",
-		Long:              `Delete an existing rule. If only one exists, it will be selected automatically if no name is provided (confirmation still required).`,
+		Long:              `Delete an existing rule, selected by ID or name. If no rule is given, you will be prompted to choose one.`,
 		Args:              cobra.MaximumNArgs(1),
 		RunE:              runRuleDeleteCmd,
 		ValidArgsFunction: completeRuleNames,
 	}
 
+	ruleEnableCmd := &cobra.Command{
+		Use:               "enable <rule-id|rule-name>",
+		Short:             "Enable a disabled rule",
+		Long:              `Re-enable a rule so it takes part in URL matching again.`,
+		Args:              cobra.ExactArgs(1),
+		RunE:              func(cmd *cobra.Command, args []string) error { return setRuleEnabled(args[0], true) },
+		ValidArgsFunction: completeRuleNames,
+	}
+
+	ruleDisableCmd := &cobra.Command{
+		Use:               "disable <rule-id|rule-name>",
+		Short:             "Temporarily disable a rule",
+		Long:              `Disable a rule so it is skipped during URL matching, without deleting it.`,
+		Args:              cobra.ExactArgs(1),
+		RunE:              func(cmd *cobra.Command, args []string) error { return setRuleEnabled(args[0], false) },
+		ValidArgsFunction: completeRuleNames,
+	}
+
+	ruleLintCmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check rules for mistakes",
+		Long: `Check the enabled rules for duplicate patterns, rules that can never fire because a rule
+checked before them matches everything they match, invalid patterns, missing profiles, and
+incognito or app options the rule's browser cannot honour. Each warning comes with a suggested
+fix. Exits with status 1 if any issue is found.`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{readOnlyConfigAnnotation: ""},
+		RunE:        runRuleLintCmd,
+	}
+
+	ruleBulkAddCmd := &cobra.Command{
+		Use:   "bulk-add --profile <profile-id> --file <file>",
+		Short: "Add rules for every entry in a file",
+		Long: `Create rules from a file with one entry per line (use - to read stdin).
+Blank lines and # comments are ignored, and duplicate entries or entries already covered by an
+existing rule are skipped.
+
+For the domain scope each entry is a domain name (a pasted URL is reduced to its host) and is
+matched exactly, or including subdomains with --include-subdomains. For the cidr scope each entry
+is an IP address or CIDR range, and for the url and path scopes each entry is a regular expression.
+
+By default one rule is created per entry, named after the entry (prefixed with --name if given).
+With --group a single rule named --name matches all entries.`,
+		Args: cobra.NoArgs,
+		RunE: runRuleBulkAddCmd,
+	}
+	ruleBulkAddCmd.Flags().String("profile", "", "Profile ID the rules route to (required)")
+	ruleBulkAddCmd.Flags().String("file", "", "File with one entry per line, or - for stdin (required)")
+	ruleBulkAddCmd.Flags().String("scope", string(config.ScopeDomain), "Rule scope (url, domain, path, cidr)")
+	ruleBulkAddCmd.Flags().String("name", "", "Rule name (with --group) or prefix for the generated rule names")
+	ruleBulkAddCmd.Flags().Bool("group", false, "Create a single rule matching all entries instead of one rule per entry")
+	ruleBulkAddCmd.Flags().Bool("include-subdomains", false, "Domain scope: also match subdomains of each domain")
+	ruleBulkAddCmd.Flags().Bool("incognito", false, "Open matching URLs in incognito/private mode")
+	ruleBulkAddCmd.Flags().Bool("dry-run", false, "Show the rules that would be added without saving them")
+	_ = ruleBulkAddCmd.MarkFlagRequired("profile")
+	_ = ruleBulkAddCmd.MarkFlagRequired("file")
+	_ = ruleBulkAddCmd.RegisterFlagCompletionFunc("profile", completeProfileFlag)
+	_ = ruleBulkAddCmd.RegisterFlagCompletionFunc("scope", completeRuleScopes)
+
 	ruleCmd.AddCommand(ruleListCmd)
 	ruleCmd.AddCommand(ruleAddCmd)
+	ruleCmd.AddCommand(ruleFromLastCmd)
+	ruleCmd.AddCommand(ruleBulkAddCmd)
 	ruleCmd.AddCommand(ruleEditCmd)
 	ruleCmd.AddCommand(ruleDeleteCmd)
+	ruleCmd.AddCommand(ruleEnableCmd)
+	ruleCmd.AddCommand(ruleDisableCmd)
+	ruleCmd.AddCommand(ruleLintCmd)
 
 	// Add the main rule command to the config command
 	configCmd.AddCommand(ruleCmd)
@@ -65,9 +161,13 @@ func AddRuleCommands(configCmd *cobra.Command) {
 // --- Run Functions for Rules ---
 
 func runRuleListCmd(cmd *cobra.Command, args []string) error {
-	cfg, err := config.LoadConfig("")
+	cfg, err := currentConfig()
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return err
+	}
+
+	if age, _ := cmd.Flags().GetString("unused-since"); age != "" {
+		return listUnused(cmd, cfg, age)
 	}
 
 	if len(cfg.Rules) == 0 {
@@ -75,10 +175,43 @@ func runRuleListCmd(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	printRuleList(cfg)
+	opts, err := listOptionsFromFlags(cmd, ruleColumns.keys())
+	if err != nil {
+		return err
+	}
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+
+	defer startPager(cmd)()
+	printRuleList(cfg, tags, opts)
 	return nil
 }
 
+// runRuleLintCmd prints the issues found in the configured rules.
+func runRuleLintCmd(cmd *cobra.Command, args []string) error {
+	cfg, err := currentConfig()
+	if err != nil {
+		return err
+	}
+
+	issues := rules.Lint(cfg)
+	if len(issues) == 0 {
+		fmt.Printf("No issues found in %d rule(s).\n", len(cfg.Rules))
+		return nil
+	}
+	printLintIssues(os.Stdout, issues)
+	fmt.Printf("\n%d issue(s) found.\n", len(issues))
+	os.Exit(1)
+	return nil
+}
+
+// printLintIssues writes one warning per issue, with its suggested fix.
+func printLintIssues(w io.Writer, issues []rules.LintIssue) {
+	for _, issue := range issues {
+		fmt.Fprintf(w, "warning: rule '%s' (%s) [%s]: %s\n", issue.Rule.Name, issue.Rule.ID, issue.Kind, issue.Message)
+		fmt.Fprintf(w, "  suggestion: %s\n", issue.Suggestion)
+	}
+}
+
 func getProfileNote(profile config.Profile, cfg *config.Config, isDefault bool) string {
 	browser, err := cfg.FindBrowserByID(profile.BrowserID)
 	browserName := profile.BrowserID
@@ -106,9 +239,9 @@ func getRuleNote(rule config.Rule, cfg *config.Config) string {
 }
 
 func runRuleAddCmd(cmd *cobra.Command, args []string) error {
-	cfg, err := config.LoadConfig("")
+	cfg, err := currentConfig()
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return err
 	}
 
 	p := prompt.New()
@@ -116,119 +249,291 @@ func runRuleAddCmd(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to get rule name: %w", err)
 	}
+	if name == "" {
+		return fmt.Errorf("rule name cannot be empty")
+	}
+	if cfg.RuleNameExists(name, -1) {
+		return fmt.Errorf("a rule named '%s' already exists", name)
+	}
 
-	pattern, err := p.Ask("URL pattern:").Input("")
+	pattern, scope := "", config.ScopeURL
+	if len(args) > 0 {
+		domain, err := rules.NormalizeEntry(args[0], config.ScopeDomain)
+		if err != nil {
+			return err
+		}
+		pattern, scope = "^"+regexp.QuoteMeta(domain)+"$", config.ScopeDomain
+	}
+
+	pattern, scope, err = askRulePattern(p, pattern, scope)
 	if err != nil {
-		return fmt.Errorf("failed to get URL pattern: %w", err)
+		return err
 	}
 
-	// Create scope choices
-	scopeChoices := []choose.Choice{
-		{Text: string(config.ScopeURL), Note: "Match against the entire URL"},
-		{Text: string(config.ScopeDomain), Note: "Match against the domain part only"},
-		{Text: string(config.ScopePath), Note: "Match against the path part only"},
+	// Rules with a handler run it instead of opening a profile
+	handler, _ := cmd.Flags().GetString("handler")
+	profileID := ""
+	if handler != "" {
+		if _, err := launcher.HandlerCommand(handler, ""); err != nil {
+			return err
+		}
+	} else {
+		profileID, err = p.Ask("Select profile:").AdvancedChoose(profileChoices(cfg))
+		if err != nil {
+			return fmt.Errorf("failed to select profile: %w", err)
+		}
 	}
 
-	scope, err := p.Ask("Select scope:").AdvancedChoose(scopeChoices)
+	description, tags, err := askRuleNotes(p, "", nil)
 	if err != nil {
-		return fmt.Errorf("failed to select scope: %w", err)
+		return err
 	}
 
-	// Create choices for profiles
-	profileChoices := make([]choose.Choice, 0, len(cfg.Profiles))
+	rule := config.Rule{
+		ID:          cfg.GenerateRuleID(name),
+		Name:        name,
+		Pattern:     pattern,
+		ProfileID:   profileID,
+		Scope:       scope,
+		Handler:     handler,
+		Description: description,
+		Tags:        tags,
+	}
+
+	cfg.Rules = append(cfg.Rules, rule)
+	if err := saveConfig(cfg); err != nil {
+		return withExitCode(ExitConfig, fmt.Errorf("failed to save config: %w", err))
+	}
+
+	statusf("Rule '%s' added with ID '%s'.", rule.Name, rule.ID)
+	return nil
+}
+
+// profileChoices lists the configured profiles for selection prompts.
+func profileChoices(cfg *config.Config) []choose.Choice {
+	choices := make([]choose.Choice, 0, len(cfg.Profiles))
 	for _, profile := range cfg.Profiles {
-		isDefault := profile.ID == cfg.DefaultProfileID
 		browser, _ := cfg.FindBrowserByID(profile.BrowserID)
 		browserName := profile.BrowserID
 		if browser != nil {
 			browserName = browser.Name
 		}
 		note := fmt.Sprintf("Name: %s, Browser: %s", profile.Name, browserName)
-		if isDefault {
+		if profile.ID == cfg.DefaultProfileID {
 			note += " [DEFAULT]"
 		}
-		profileChoices = append(profileChoices, choose.Choice{
-			Text: profile.ID,
-			Note: note,
-		})
+		choices = append(choices, choose.Choice{Text: profile.ID, Note: note})
 	}
+	return choices
+}
 
-	profileID, err := p.Ask("Select profile:").AdvancedChoose(profileChoices)
+// runRuleFromLastCmd creates a domain rule for the most recently routed URL, asking
+// only which profile it should go to.
+func runRuleFromLastCmd(cmd *cobra.Command, args []string) error {
+	cfg, err := currentConfig()
 	if err != nil {
-		return fmt.Errorf("failed to select profile: %w", err)
+		return err
+	}
+	if !cfg.History.Enabled {
+		return fmt.Errorf("launch history is disabled; enable [history] to create rules from routed URLs")
+	}
+	path, err := history.DefaultPath()
+	if err != nil {
+		return err
+	}
+	entries, err := history.Load(path)
+	if err != nil {
+		return err
+	}
+	domain, last, ok := lastRoutedDomain(entries)
+	if !ok {
+		return fmt.Errorf("no routed URLs in the launch history")
 	}
 
-	rule := config.Rule{
-		Name:      name,
-		Pattern:   pattern,
-		ProfileID: profileID,
-		Scope:     config.RuleScope(scope),
+	rule := domainRule(cfg, domain)
+	if cfg.RuleNameExists(rule.Name, -1) {
+		return fmt.Errorf("a rule named '%s' already exists; use 'rurl config rule edit %s' to change it", rule.Name, rule.Name)
+	}
+	if last.RuleName != "" {
+		fmt.Printf("Last routed: %s (rule '%s' -> %s)\n", last.URL, last.RuleName, last.ProfileID)
+	} else {
+		fmt.Printf("Last routed: %s (default -> %s)\n", last.URL, last.ProfileID)
+	}
+
+	p := prompt.New()
+	rule.ProfileID, err = p.Ask(fmt.Sprintf("Route %s to which profile from now on?", domain)).AdvancedChoose(profileChoices(cfg))
+	if err != nil {
+		return fmt.Errorf("failed to select profile: %w", err)
 	}
 
 	cfg.Rules = append(cfg.Rules, rule)
-	if err := config.SaveConfig(cfg, ""); err != nil {
-		return fmt.Errorf("failed to save config: %w", err)
+	if err := saveConfig(cfg); err != nil {
+		return withExitCode(ExitConfig, fmt.Errorf("failed to save config: %w", err))
 	}
 
+	statusf("Rule '%s' added with ID '%s'.", rule.Name, rule.ID)
 	return nil
 }
 
-func runRuleEditCmd(cmd *cobra.Command, args []string) error {
-	cfg, err := config.LoadConfig("")
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+// lastRoutedDomain returns the domain of the newest history entry with one, and that
+// entry.
+func lastRoutedDomain(entries []history.Entry) (string, history.Entry, bool) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		domain, err := rules.NormalizeEntry(entries[i].URL, config.ScopeDomain)
+		if err == nil && domain != "" {
+			return domain, entries[i], true
+		}
 	}
+	return "", history.Entry{}, false
+}
 
-	p := prompt.New()
+// domainRule returns a rule named after domain matching exactly that domain, without
+// a profile.
+func domainRule(cfg *config.Config, domain string) config.Rule {
+	return config.Rule{
+		ID:      cfg.GenerateRuleID(domain),
+		Name:    domain,
+		Pattern: "^" + regexp.QuoteMeta(domain) + "$",
+		Scope:   config.ScopeDomain,
+	}
+}
 
-	// Create choices for rules
-	ruleChoices := make([]choose.Choice, 0, len(cfg.Rules))
-	for _, rule := range cfg.Rules {
-		ruleChoices = append(ruleChoices, choose.Choice{
-			Text: rule.Name,
-			Note: getRuleNote(rule, cfg),
-		})
+// ruleScopeChoices lists the rule scopes for selection prompts.
+var ruleScopeChoices = []choose.Choice{
+	{Text: string(config.ScopeURL), Note: "Match against the entire URL"},
+	{Text: string(config.ScopeDomain), Note: "Match against the domain part only"},
+	{Text: string(config.ScopePath), Note: "Match against the path part only"},
+	{Text: string(config.ScopeCIDR), Note: "Match IP literal hosts against CIDR ranges (e.g. 10.0.0.0/8)"},
+	{Text: string(config.ScopeScheme), Note: "Match against the scheme only (e.g. https)"},
+	{Text: string(config.ScopeExtension), Note: "Match against the file extension of the path (e.g. pdf)"},
+}
+
+// askRulePattern prompts for a rule pattern and scope, starting from the given values.
+// Invalid patterns are rejected immediately, and the user may test the pattern
+// against example URLs until they accept it.
+func askRulePattern(p *prompt.Prompt, pattern string, scope config.RuleScope) (string, config.RuleScope, error) {
+	const (
+		actionAccept = "Accept pattern"
+		actionTest   = "Test another URL"
+		actionChange = "Change pattern or scope"
+	)
+
+	for {
+		var err error
+		pattern, err = p.Ask("URL pattern:").Input(pattern)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get URL pattern: %w", err)
+		}
+
+		scopeIndex := 0
+		for i, choice := range ruleScopeChoices {
+			if choice.Text == string(scope) {
+				scopeIndex = i
+				break
+			}
+		}
+		selected, err := p.Ask("Select scope:").AdvancedChoose(ruleScopeChoices, choose.WithDefaultIndex(scopeIndex))
+		if err != nil {
+			return "", "", fmt.Errorf("failed to select scope: %w", err)
+		}
+		scope = config.RuleScope(selected)
+
+		if err := rules.ValidatePattern(scope, pattern); err != nil {
+			fmt.Printf("Invalid pattern for scope '%s': %v\n", scope, err)
+			continue
+		}
+
+		action := actionTest
+		for action == actionTest {
+			exampleURL, err := p.Ask("Example URL to test (leave empty to skip):").Input("")
+			if err != nil {
+				return "", "", fmt.Errorf("failed to get example URL: %w", err)
+			}
+			if exampleURL == "" {
+				return pattern, scope, nil
+			}
+
+			matched, matchString, err := rules.TestPattern(scope, pattern, exampleURL)
+			switch {
+			case err != nil:
+				fmt.Printf("Could not test URL: %v\n", err)
+			case matched:
+				fmt.Printf("MATCH: '%s' matches %s '%s'\n", pattern, scope, matchString)
+			default:
+				fmt.Printf("NO MATCH: '%s' does not match %s '%s'\n", pattern, scope, matchString)
+			}
+
+			action, err = p.Ask("What next?").Choose([]string{actionAccept, actionTest, actionChange})
+			if err != nil {
+				return "", "", fmt.Errorf("failed to select action: %w", err)
+			}
+		}
+		if action == actionAccept {
+			return pattern, scope, nil
+		}
 	}
+}
 
-	ruleName, err := p.Ask("Select rule to edit:").AdvancedChoose(ruleChoices)
+// askRuleNotes prompts for a rule's description and tags, starting from the given
+// values.
+func askRuleNotes(p *prompt.Prompt, description string, tags []string) (string, []string, error) {
+	description, err := p.Ask("Description (optional):").Input(description)
 	if err != nil {
-		return fmt.Errorf("failed to select rule: %w", err)
+		return "", nil, fmt.Errorf("failed to get description: %w", err)
 	}
+	tagList, err := p.Ask("Tags, comma-separated (optional):").Input(strings.Join(tags, ", "))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	return strings.TrimSpace(description), config.ParseTags(tagList), nil
+}
 
-	var ruleIndex int
-	var currentRule config.Rule
-	for i, rule := range cfg.Rules {
-		if rule.Name == ruleName {
-			ruleIndex = i
-			currentRule = rule
-			break
+// selectRuleIndex returns the index of the rule given by ID or name in args, or
+// prompts the user to choose one if args is empty.
+func selectRuleIndex(p *prompt.Prompt, cfg *config.Config, args []string, question string) (int, error) {
+	if len(args) > 0 {
+		ruleIndex := cfg.FindRuleIndex(args[0])
+		if ruleIndex == -1 {
+			return -1, fmt.Errorf("rule '%s' not found", args[0])
 		}
+		return ruleIndex, nil
+	}
+	if len(cfg.Rules) == 0 {
+		return -1, fmt.Errorf("no rules configured")
+	}
+
+	// Create choices for rules, keyed by ID so duplicate names can't be confused
+	ruleChoices := make([]choose.Choice, 0, len(cfg.Rules))
+	for _, rule := range cfg.Rules {
+		ruleChoices = append(ruleChoices, choose.Choice{
+			Text: rule.ID,
+			Note: fmt.Sprintf("Name: %s, %s", rule.Name, getRuleNote(rule, cfg)),
+		})
 	}
 
-	pattern, err := p.Ask("URL pattern:").Input(currentRule.Pattern)
+	ruleID, err := p.Ask(question).AdvancedChoose(ruleChoices)
 	if err != nil {
-		return fmt.Errorf("failed to get URL pattern: %w", err)
+		return -1, fmt.Errorf("failed to select rule: %w", err)
 	}
+	return cfg.FindRuleIndex(ruleID), nil
+}
 
-	// Create scope choices
-	scopeChoices := []choose.Choice{
-		{Text: string(config.ScopeURL), Note: "Match against the entire URL"},
-		{Text: string(config.ScopeDomain), Note: "Match against the domain part only"},
-		{Text: string(config.ScopePath), Note: "Match against the path part only"},
+func runRuleEditCmd(cmd *cobra.Command, args []string) error {
+	cfg, err := currentConfig()
+	if err != nil {
+		return err
 	}
 
-	// Find the current scope index for default selection
-	currentScopeIndex := 0
-	for i, choice := range scopeChoices {
-		if choice.Text == string(currentRule.Scope) {
-			currentScopeIndex = i
-			break
-		}
+	p := prompt.New()
+	ruleIndex, err := selectRuleIndex(p, cfg, args, "Select rule to edit:")
+	if err != nil {
+		return err
 	}
+	currentRule := cfg.Rules[ruleIndex]
 
-	scope, err := p.Ask("Select scope:").AdvancedChoose(scopeChoices, choose.WithDefaultIndex(currentScopeIndex))
+	pattern, scope, err := askRulePattern(p, currentRule.Pattern, currentRule.Scope)
 	if err != nil {
-		return fmt.Errorf("failed to select scope: %w", err)
+		return err
 	}
 
 	// Create choices for profiles
@@ -264,52 +569,73 @@ func runRuleEditCmd(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to select profile: %w", err)
 	}
 
+	description, tags, err := askRuleNotes(p, currentRule.Description, currentRule.Tags)
+	if err != nil {
+		return err
+	}
+
 	cfg.Rules[ruleIndex].Pattern = pattern
 	cfg.Rules[ruleIndex].ProfileID = profileID
-	cfg.Rules[ruleIndex].Scope = config.RuleScope(scope)
+	cfg.Rules[ruleIndex].Scope = scope
+	cfg.Rules[ruleIndex].Description = description
+	cfg.Rules[ruleIndex].Tags = tags
 
-	if err := config.SaveConfig(cfg, ""); err != nil {
-		return fmt.Errorf("failed to save config: %w", err)
+	if err := saveConfig(cfg); err != nil {
+		return withExitCode(ExitConfig, fmt.Errorf("failed to save config: %w", err))
 	}
 
 	return nil
 }
 
 func runRuleDeleteCmd(cmd *cobra.Command, args []string) error {
-	cfg, err := config.LoadConfig("")
+	cfg, err := currentConfig()
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return err
 	}
 
-	p := prompt.New()
+	ruleIndex, err := selectRuleIndex(prompt.New(), cfg, args, "Select rule to delete:")
+	if err != nil {
+		return err
+	}
+	ruleName := cfg.Rules[ruleIndex].Name
 
-	// Create choices for rules
-	ruleChoices := make([]choose.Choice, 0, len(cfg.Rules))
-	for _, rule := range cfg.Rules {
-		ruleChoices = append(ruleChoices, choose.Choice{
-			Text: rule.Name,
-			Note: getRuleNote(rule, cfg),
-		})
+	cfg.Rules = append(cfg.Rules[:ruleIndex], cfg.Rules[ruleIndex+1:]...)
+	if err := saveConfig(cfg); err != nil {
+		return withExitCode(ExitConfig, fmt.Errorf("failed to save config: %w", err))
 	}
 
-	ruleName, err := p.Ask("Select rule to delete:").AdvancedChoose(ruleChoices)
+	statusf("Rule '%s' deleted.", ruleName)
+	return nil
+}
+
+// setRuleEnabled enables or disables the rule with the given ID or name.
+func setRuleEnabled(key string, enabled bool) error {
+	cfg, err := currentConfig()
 	if err != nil {
-		return fmt.Errorf("failed to select rule: %w", err)
+		return err
 	}
 
-	var ruleIndex int
-	for i, rule := range cfg.Rules {
-		if rule.Name == ruleName {
-			ruleIndex = i
-			break
-		}
+	ruleIndex := cfg.FindRuleIndex(key)
+	if ruleIndex == -1 {
+		return fmt.Errorf("rule '%s' not found", key)
 	}
+	ruleName := cfg.Rules[ruleIndex].Name
 
-	cfg.Rules = append(cfg.Rules[:ruleIndex], cfg.Rules[ruleIndex+1:]...)
-	if err := config.SaveConfig(cfg, ""); err != nil {
-		return fmt.Errorf("failed to save config: %w", err)
+	state := "enabled"
+	if !enabled {
+		state = "disabled"
+	}
+	if cfg.Rules[ruleIndex].IsEnabled() == enabled {
+		statusf("Rule '%s' is already %s.", ruleName, state)
+		return nil
+	}
+
+	cfg.Rules[ruleIndex].Enabled = &enabled
+	if err := saveConfig(cfg); err != nil {
+		return withExitCode(ExitConfig, fmt.Errorf("failed to save config: %w", err))
 	}
 
+	statusf("Rule '%s' %s.", ruleName, state)
 	return nil
 }
 
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/rules"
+	"github.com/spf13/cobra"
+)
+
+// bulkRuleOptions controls how bulk-add entries are turned into rules.
+type bulkRuleOptions struct {
+	ProfileID         string
+	Scope             config.RuleScope
+	Name              string // Rule name when grouping, otherwise a prefix for each rule name
+	Group             bool
+	IncludeSubdomains bool
+	Incognito         bool
+}
+
+func runRuleBulkAddCmd(cmd *cobra.Command, args []string) error {
+	profileID, _ := cmd.Flags().GetString("profile")
+	file, _ := cmd.Flags().GetString("file")
+	scope, _ := cmd.Flags().GetString("scope")
+	name, _ := cmd.Flags().GetString("name")
+	group, _ := cmd.Flags().GetBool("group")
+	subdomains, _ := cmd.Flags().GetBool("include-subdomains")
+	incognito, _ := cmd.Flags().GetBool("incognito")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	opts := bulkRuleOptions{
+		ProfileID:         profileID,
+		Scope:             config.RuleScope(strings.ToLower(scope)),
+		Name:              strings.TrimSpace(name),
+		Group:             group,
+		IncludeSubdomains: subdomains,
+		Incognito:         incognito,
+	}
+	switch opts.Scope {
+	case config.ScopeURL, config.ScopeDomain, config.ScopePath, config.ScopeCIDR:
+	default:
+		return fmt.Errorf("invalid scope '%s': must be one of url, domain, path, cidr", scope)
+	}
+	if opts.Group && opts.Name == "" {
+		return fmt.Errorf("--name is required with --group")
+	}
+
+	cfg, err := currentConfig()
+	if err != nil {
+		return err
+	}
+	if _, err := cfg.FindProfileByID(profileID); err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return fmt.Errorf("failed to open '%s': %w", file, err)
+		}
+		defer f.Close()
+		r = f
+	}
+	entries, err := rules.ReadEntries(r, opts.Scope)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no entries found in '%s'", file)
+	}
+
+	added, skipped, err := addBulkRules(cfg, entries, opts)
+	if err != nil {
+		return err
+	}
+	for _, rule := range added {
+		fmt.Printf("  + %s (%s): %s\n", rule.Name, rule.ID, rule.Pattern)
+	}
+	for _, s := range skipped {
+		fmt.Printf("  - skipped %s\n", s)
+	}
+
+	if dryRun {
+		fmt.Printf("Dry run: %d rule(s) would be added, %d entries skipped.\n", len(added), len(skipped))
+		return nil
+	}
+	if len(added) == 0 {
+		statusf("No new rules to add.")
+		return nil
+	}
+	if err := saveConfig(cfg); err != nil {
+		return withExitCode(ExitConfig, fmt.Errorf("failed to save config: %w", err))
+	}
+	statusf("Added %d rule(s) for profile '%s', %d entries skipped.", len(added), profileID, len(skipped))
+	return nil
+}
+
+// addBulkRules appends rules for entries to cfg. Entries already routed to the same
+// profile by an identical rule, or whose generated name is taken, are skipped and
+// described in the second return value.
+func addBulkRules(cfg *config.Config, entries []string, opts bulkRuleOptions) ([]config.Rule, []string, error) {
+	existing := make(map[string]bool, len(cfg.Rules))
+	key := func(pattern string) string {
+		return string(opts.Scope) + "\x00" + pattern + "\x00" + opts.ProfileID
+	}
+	for _, r := range cfg.Rules {
+		existing[string(r.Scope)+"\x00"+r.Pattern+"\x00"+r.ProfileID] = true
+	}
+
+	newRule := func(name, pattern string) config.Rule {
+		rule := config.Rule{
+			ID:        cfg.GenerateRuleID(name),
+			Name:      name,
+			Pattern:   pattern,
+			Scope:     opts.Scope,
+			ProfileID: opts.ProfileID,
+			Incognito: opts.Incognito,
+		}
+		cfg.Rules = append(cfg.Rules, rule)
+		return rule
+	}
+
+	if opts.Group {
+		pattern := rules.EntriesPattern(entries, opts.Scope, opts.IncludeSubdomains)
+		if existing[key(pattern)] {
+			return nil, nil, fmt.Errorf("an identical rule already exists")
+		}
+		if cfg.RuleNameExists(opts.Name, -1) {
+			return nil, nil, fmt.Errorf("a rule named '%s' already exists", opts.Name)
+		}
+		return []config.Rule{newRule(opts.Name, pattern)}, nil, nil
+	}
+
+	var added []config.Rule
+	var skipped []string
+	for _, entry := range entries {
+		pattern := rules.EntriesPattern([]string{entry}, opts.Scope, opts.IncludeSubdomains)
+		name := entry
+		if opts.Name != "" {
+			name = opts.Name + ": " + entry
+		}
+		switch {
+		case existing[key(pattern)]:
+			skipped = append(skipped, fmt.Sprintf("%s: already covered by an existing rule", entry))
+		case cfg.RuleNameExists(name, -1):
+			skipped = append(skipped, fmt.Sprintf("%s: a rule named '%s' already exists", entry, name))
+		default:
+			added = append(added, newRule(name, pattern))
+		}
+	}
+	return added, skipped, nil
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddBulkRules(t *testing.T) {
+	newCfg := func() *config.Config {
+		return &config.Config{
+			Profiles: []config.Profile{{ID: "work"}},
+			Rules: []config.Rule{
+				{ID: "example-com", Name: "example.com", Pattern: `^example\.com$`, Scope: config.ScopeDomain, ProfileID: "work"},
+			},
+		}
+	}
+	entries := []string{"example.com", "example.org"}
+
+	cfg := newCfg()
+	added, skipped, err := addBulkRules(cfg, entries, bulkRuleOptions{ProfileID: "work", Scope: config.ScopeDomain})
+	require.NoError(t, err)
+	require.Len(t, added, 1)
+	assert.Equal(t, "example.org", added[0].Name)
+	assert.Equal(t, "example-org", added[0].ID)
+	assert.Equal(t, `^example\.org$`, added[0].Pattern)
+	assert.Len(t, skipped, 1)
+	assert.Len(t, cfg.Rules, 2)
+	assert.NoError(t, cfg.ValidateRules())
+
+	cfg = newCfg()
+	added, _, err = addBulkRules(cfg, entries, bulkRuleOptions{
+		ProfileID: "work", Scope: config.ScopeDomain, Name: "Allow-list", Group: true, IncludeSubdomains: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, added, 1)
+	assert.Equal(t, `^(?:.*\.)?(?:example\.com|example\.org)$`, added[0].Pattern)
+
+	_, _, err = addBulkRules(cfg, entries, bulkRuleOptions{
+		ProfileID: "work", Scope: config.ScopeDomain, Name: "Allow-list", Group: true,
+	})
+	assert.ErrorContains(t, err, "already exists")
+
+	cfg = newCfg()
+	added, _, err = addBulkRules(cfg, []string{"10.0.0.0/8", "192.168.1.1"}, bulkRuleOptions{
+		ProfileID: "work", Scope: config.ScopeCIDR, Name: "LAN", Group: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.0/8,192.168.1.1", added[0].Pattern)
+}
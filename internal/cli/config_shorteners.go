@@ -1,12 +1,22 @@
 package cli
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"reflect"
+	"slices"
 	"strings"
 	"text/tabwriter"
 
+	"github.com/jmylchreest/rurl/internal/candidates"
 	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/update"
+	"github.com/jmylchreest/rurl/internal/urlhandler"
 	// "github.com/jmylchreest/rurl/internal/logging"
 	"github.com/cqroot/prompt"
 	"github.com/cqroot/prompt/choose"
@@ -23,16 +33,19 @@ func addShortURLCommands(parentCmd *cobra.Command) {
 		Use:     "shorturl",
 		Aliases: []string{"short", "su"},
 		Short:   "Manage short URL domain configurations",
-		Long:    `Add, edit, delete, and list manually added or view built-in URL shortener domains recognized by rurl.`,
+		Long: `Add, edit, delete, and list manually added or view built-in URL shortener domains recognized by rurl.
+Built-in domains are not written to the config file: a manual entry for one overrides it, and
+'disable' turns it off.`,
 	}
 
 	// --- List Short URLs Command ---
 	listShortURLsCmd := &cobra.Command{
-		Use:     "list",
-		Aliases: []string{"ls"},
-		Short:   "List configured short URL domains",
-		Long:    `Displays manually added short URL domains. Use the --builtin flag to also show built-in domains.`,
-		Run:     runListShortURLsCmd,
+		Use:         "list",
+		Aliases:     []string{"ls"},
+		Short:       "List configured short URL domains",
+		Long:        `Displays manually added short URL domains. Use the --builtin flag to also show built-in domains.`,
+		Annotations: map[string]string{readOnlyConfigAnnotation: ""},
+		Run:         runListShortURLsCmd,
 	}
 	listShortURLsCmd.Flags().BoolP("builtin", "b", false, "Include built-in shortener domains in the list")
 	shorturlCmd.AddCommand(listShortURLsCmd)
@@ -43,23 +56,26 @@ func addShortURLCommands(parentCmd *cobra.Command) {
 		Short: "Add a new domain to the manual short URL list",
 		Long: `Adds a new domain to the list of known shortener services.
 URLs from this domain will be resolved before rule matching.
-You can optionally set the --safelink flag.`,
+You can optionally set the --safelink flag, and a User-Agent or extra headers for services
+that refuse requests from rurl.`,
 		Args: cobra.ExactArgs(1),
 		Run:  runAddManualShortURLCmd,
 	}
 	addShortURLCmd.Flags().BoolP("safelink", "s", false, "Mark this domain as a safelink (launch original URL after matching)")
+	addShortenerRequestFlags(addShortURLCmd)
 	shorturlCmd.AddCommand(addShortURLCmd)
 
 	// --- Edit Manual Short URL Command ---
 	editShortURLCmd := &cobra.Command{
 		Use:               "edit [domain]",
 		Short:             "Edit settings for a manually added short URL domain",
-		Long:              `Edits settings (currently only the IsSafelink flag) for a manually added short URL domain. Prompts for domain if not provided.`,
+		Long:              `Edits settings (the IsSafelink flag, User-Agent and headers) for a manually added short URL domain. Prompts for domain if not provided.`,
 		Args:              cobra.MaximumNArgs(1),
 		Run:               runEditManualShortURLCmd,
 		ValidArgsFunction: completeManualShortURLDomains,
 	}
 	editShortURLCmd.Flags().BoolP("safelink", "s", false, "Mark this domain as a safelink (launch original URL after matching)")
+	addShortenerRequestFlags(editShortURLCmd)
 	shorturlCmd.AddCommand(editShortURLCmd)
 
 	// --- Delete Manual Short URL Command ---
@@ -74,6 +90,57 @@ You can optionally set the --safelink flag.`,
 	}
 	shorturlCmd.AddCommand(deleteShortURLCmd)
 
+	// --- Disable/Enable Built-in Short URL Commands ---
+	shorturlCmd.AddCommand(&cobra.Command{
+		Use:   "disable <domain>",
+		Short: "Stop treating a built-in domain as a short URL domain",
+		Long: `Adds a built-in (or downloaded, see 'update') shortener domain to disabled_shorteners,
+so that its URLs are matched against the rules as they are instead of being resolved.
+A manual entry for the domain is still used.`,
+		Args:              cobra.ExactArgs(1),
+		RunE:              runDisableShortURLCmd,
+		ValidArgsFunction: completeBuiltinShortURLDomains(false),
+	})
+	shorturlCmd.AddCommand(&cobra.Command{
+		Use:               "enable <domain>",
+		Short:             "Re-enable a disabled built-in short URL domain",
+		Long:              `Removes a domain from disabled_shorteners, so that it is resolved as a built-in shortener again.`,
+		Args:              cobra.ExactArgs(1),
+		RunE:              runEnableShortURLCmd,
+		ValidArgsFunction: completeBuiltinShortURLDomains(true),
+	})
+
+	// --- Review Candidate Short URLs Command ---
+	reviewShortURLCmd := &cobra.Command{
+		Use:   "review",
+		Short: "Review domains detected as possible shorteners",
+		Long: `Lists domains that were seen redirecting to other sites (recorded when shortener_learning is
+enabled) and lets you add each one as a manual short URL domain, or dismiss it so it is not suggested again.`,
+		Args: cobra.NoArgs,
+		Run:  runReviewShortURLCmd,
+	}
+	reviewShortURLCmd.Flags().Bool("list", false, "Only list the candidates, do not prompt")
+	shorturlCmd.AddCommand(reviewShortURLCmd)
+
+	// --- Update Curated Short URLs Command ---
+	updateShortURLCmd := &cobra.Command{
+		Use:   "update",
+		Short: "Download the curated list of shortener domains",
+		Long: `Downloads the curated list of shortener domains (from the rurl repository, or from
+shortener_feed.url) and merges it into the built-in domains, so that new shorteners are
+recognized without a new release. The last verified list is kept in the cache directory
+and merged each time rurl starts.
+
+The list must be signed with minisign (a signature at the feed URL + ".minisig", checked
+against shortener_feed.public_key or, for the default feed, the key rurl releases are
+signed with) or match shortener_feed.sha256.`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{readOnlyConfigAnnotation: ""},
+		RunE:        runUpdateShortURLsCmd,
+	}
+	updateShortURLCmd.Flags().Bool("skip-signature", false, "Accept a list that cannot be verified, trusting the download as is")
+	shorturlCmd.AddCommand(updateShortURLCmd)
+
 	// Add the main 'shorturl' command to the parent ('config')
 	parentCmd.AddCommand(shorturlCmd)
 }
@@ -83,7 +150,7 @@ You can optionally set the --safelink flag.`,
 func runListShortURLsCmd(cmd *cobra.Command, args []string) {
 	if cfg == nil {
 		log.Logger.Error().Msg("Configuration not loaded.")
-		os.Exit(1)
+		os.Exit(ExitConfig)
 	}
 	showBuiltin, _ := cmd.Flags().GetBool("builtin")
 	printShortURLList(cfg, showBuiltin)
@@ -92,27 +159,21 @@ func runListShortURLsCmd(cmd *cobra.Command, args []string) {
 func runAddManualShortURLCmd(cmd *cobra.Command, args []string) {
 	if cfg == nil {
 		log.Logger.Error().Msg("Configuration not loaded.")
-		os.Exit(1)
+		os.Exit(ExitConfig)
 	}
 	domain := args[0]
 
-	// Validate domain uniqueness across *both* built-in and manual lists
-	for _, s := range cfg.Shorteners {
-		if s.Domain == domain {
-			fmt.Fprintf(os.Stderr, "Error: Domain '%s' is already present in the built-in shortener list.\n", domain)
-			os.Exit(1)
-		}
-	}
+	// A built-in domain may be added, the manual entry overriding its settings
 	for _, s := range cfg.ManualShorteners {
 		if s.Domain == domain {
-			fmt.Fprintf(os.Stderr, "Error: Domain '%s' has already been manually added.\n", domain)
+			errorf("Error: Domain '%s' has already been manually added.", domain)
 			os.Exit(1)
 		}
 	}
 
 	// Basic domain format check (could be more robust)
 	if !strings.Contains(domain, ".") || strings.ContainsAny(domain, "/:?#@") {
-		fmt.Fprintf(os.Stderr, "Error: Invalid domain format '%s'. Please provide just the domain name (e.g., my.shortener.com).\n", domain)
+		errorf("Error: Invalid domain format '%s'. Please provide just the domain name (e.g., my.shortener.com).", domain)
 		os.Exit(1)
 	}
 
@@ -129,22 +190,29 @@ func runAddManualShortURLCmd(cmd *cobra.Command, args []string) {
 		Domain:     domain,
 		IsSafelink: isSafelink,
 	}
+	if err := applyShortenerRequestFlags(cmd, &newShortener); err != nil {
+		errorf("Error: %v", err)
+		os.Exit(1)
+	}
 	cfg.ManualShorteners = append(cfg.ManualShorteners, newShortener)
 
-	if err := config.SaveConfig(cfg, cfgFile); err != nil {
+	if err := saveConfig(cfg); err != nil {
 		log.Logger.Error().Err(err).Str("domain", domain).Msg("Failed to save config after adding manual short URL domain")
-		fmt.Fprintf(os.Stderr, "Error saving configuration: %v\n", err)
-		os.Exit(1)
+		errorf("Error saving configuration: %v", err)
+		os.Exit(ExitConfig)
 	}
 
 	log.Logger.Info().Str("domain", domain).Bool("is_safelink", isSafelink).Msg("Manual short URL domain added successfully.")
-	fmt.Printf("Manual short URL domain '%s' added successfully (IsSafelink: %t).\n", domain, isSafelink)
+	statusf("Manual short URL domain '%s' added successfully (IsSafelink: %t).", domain, isSafelink)
+	if isBuiltinShortener(domain) {
+		statusf("It overrides the built-in entry for the domain.")
+	}
 }
 
 func runEditManualShortURLCmd(cmd *cobra.Command, args []string) {
 	if cfg == nil {
 		log.Logger.Error().Msg("Configuration not loaded.")
-		os.Exit(1)
+		os.Exit(ExitConfig)
 	}
 
 	var domainName string
@@ -152,16 +220,16 @@ func runEditManualShortURLCmd(cmd *cobra.Command, args []string) {
 
 	if len(args) == 0 {
 		if len(cfg.ManualShorteners) == 0 {
-			fmt.Fprintln(os.Stderr, "Error: No manual short URLs configured to edit.")
+			errorf("Error: No manual short URLs configured to edit.")
 			os.Exit(1)
 		}
 		domainName, err = promptSelectManualShortURL("Select the manual short URL domain to edit:", cfg.ManualShorteners)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error selecting short URL domain: %v\n", err)
+			errorf("Error selecting short URL domain: %v", err)
 			os.Exit(1)
 		}
 		if domainName == "" { // User cancelled
-			fmt.Println("Edit cancelled.")
+			statusf("Edit cancelled.")
 			os.Exit(0)
 		}
 	} else {
@@ -170,42 +238,48 @@ func runEditManualShortURLCmd(cmd *cobra.Command, args []string) {
 
 	shortenerToEdit, index, err := cfg.FindManualShortenerByDomain(domainName)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		errorf("Error: %v", err)
 		os.Exit(1)
 	}
 
 	// Check if safelink flag was provided
-	var newValue bool
+	newValue := shortenerToEdit.IsSafelink
 	if cmd.Flags().Changed("safelink") {
 		newValue, _ = cmd.Flags().GetBool("safelink")
-	} else {
-		// If flag not provided, prompt for it
+	} else if !cmd.Flags().Changed("user-agent") && !cmd.Flags().Changed("header") {
+		// If no flag was provided, prompt for it
 		newValue = promptSafelink(fmt.Sprintf("Should '%s' be treated as a safelink?", domainName), shortenerToEdit.IsSafelink)
 	}
+	updated := *shortenerToEdit
+	updated.IsSafelink = newValue
+	if err := applyShortenerRequestFlags(cmd, &updated); err != nil {
+		errorf("Error: %v", err)
+		os.Exit(1)
+	}
 
-	// Only update if the value is different
-	if newValue == shortenerToEdit.IsSafelink {
+	// Only update if a value is different
+	if reflect.DeepEqual(updated, *shortenerToEdit) {
 		fmt.Println("Setting unchanged. No edit necessary.")
 		os.Exit(0)
 	}
 
 	// Update the shortener in the slice
-	cfg.ManualShorteners[index].IsSafelink = newValue
+	cfg.ManualShorteners[index] = updated
 
-	if err := config.SaveConfig(cfg, cfgFile); err != nil {
+	if err := saveConfig(cfg); err != nil {
 		log.Logger.Error().Err(err).Str("domain", domainName).Msg("Failed to save config after editing manual short URL domain")
-		fmt.Fprintf(os.Stderr, "Error saving configuration: %v\n", err)
-		os.Exit(1)
+		errorf("Error saving configuration: %v", err)
+		os.Exit(ExitConfig)
 	}
 
 	log.Logger.Info().Str("domain", domainName).Bool("is_safelink", newValue).Msg("Manual short URL domain updated successfully.")
-	fmt.Printf("Manual short URL domain '%s' updated successfully (IsSafelink: %t).\n", domainName, newValue)
+	statusf("Manual short URL domain '%s' updated successfully (IsSafelink: %t).", domainName, newValue)
 }
 
 func runDeleteManualShortURLCmd(cmd *cobra.Command, args []string) {
 	if cfg == nil {
 		log.Logger.Error().Msg("Configuration not loaded.")
-		os.Exit(1)
+		os.Exit(ExitConfig)
 	}
 
 	var domainName string
@@ -213,66 +287,204 @@ func runDeleteManualShortURLCmd(cmd *cobra.Command, args []string) {
 
 	if len(args) == 0 {
 		if len(cfg.ManualShorteners) == 0 {
-			fmt.Fprintln(os.Stderr, "Error: No manual short URLs configured to delete.")
+			errorf("Error: No manual short URLs configured to delete.")
 			os.Exit(1)
 		}
 		domainName, err = promptSelectManualShortURL("Select the manual short URL domain to delete:", cfg.ManualShorteners)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error selecting short URL domain: %v\n", err)
+			errorf("Error selecting short URL domain: %v", err)
 			os.Exit(1)
 		}
 		if domainName == "" { // User cancelled
-			fmt.Println("Delete cancelled.")
+			statusf("Delete cancelled.")
 			os.Exit(0)
 		}
 	} else {
 		domainName = args[0]
 	}
 
-	// --- Check if it's a built-in shortener first ---
-	isBuiltIn := false
-	for _, s := range cfg.Shorteners {
-		if s.Domain == domainName {
-			isBuiltIn = true
-			break
-		}
-	}
-	if isBuiltIn {
-		fmt.Fprintf(os.Stderr, "Error: Domain '%s' is a built-in shortener and cannot be deleted.\n", domainName)
+	_, index, err := cfg.FindManualShortenerByDomain(domainName)
+	if err != nil && isBuiltinShortener(domainName) {
+		errorf("Error: Domain '%s' is a built-in shortener and cannot be deleted; use 'rurl config shorturl disable %s' to turn it off.", domainName, domainName)
 		os.Exit(1)
 	}
-	// --- End check ---
-
-	_, index, err := cfg.FindManualShortenerByDomain(domainName)
 	if err != nil {
-		// This error now implies it's not a manual domain *either*
-		fmt.Fprintf(os.Stderr, "Error: Manual short URL domain '%s' not found.\n", domainName)
+		errorf("Error: Manual short URL domain '%s' not found.", domainName)
 		os.Exit(1)
 	}
 
 	confirm := promptString(fmt.Sprintf("Are you sure you want to delete the manual short URL domain '%s'? (yes/no)", domainName), "no")
 	if !strings.EqualFold(confirm, "yes") {
-		fmt.Println("Deletion cancelled.")
+		statusf("Deletion cancelled.")
 		os.Exit(0)
 	}
 
 	// Remove the shortener from the slice
 	cfg.ManualShorteners = append(cfg.ManualShorteners[:index], cfg.ManualShorteners[index+1:]...)
 
-	if err := config.SaveConfig(cfg, cfgFile); err != nil {
+	if err := saveConfig(cfg); err != nil {
 		log.Logger.Error().Err(err).Str("domain", domainName).Msg("Failed to save config after deleting manual short URL domain")
-		fmt.Fprintf(os.Stderr, "Error saving configuration: %v\n", err)
-		os.Exit(1)
+		errorf("Error saving configuration: %v", err)
+		os.Exit(ExitConfig)
 	}
 
 	log.Logger.Info().Str("domain", domainName).Msg("Manual short URL domain deleted successfully.")
-	fmt.Printf("Manual short URL domain '%s' deleted successfully.\n", domainName)
+	statusf("Manual short URL domain '%s' deleted successfully.", domainName)
+}
+
+func runDisableShortURLCmd(cmd *cobra.Command, args []string) error {
+	c, err := currentConfig()
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+	domain := strings.ToLower(args[0])
+	if !isBuiltinShortener(domain) {
+		return fmt.Errorf("'%s' is not a built-in shortener domain (see 'rurl config shorturl list --builtin')", domain)
+	}
+	if slices.Contains(c.DisabledShorteners, domain) {
+		statusf("Built-in short URL domain '%s' is already disabled.", domain)
+		return nil
+	}
+	c.DisabledShorteners = append(c.DisabledShorteners, domain)
+	if err := saveConfig(c); err != nil {
+		return withExitCode(ExitConfig, fmt.Errorf("error saving configuration: %w", err))
+	}
+	statusf("Built-in short URL domain '%s' disabled.", domain)
+	if _, _, err := c.FindManualShortenerByDomain(domain); err == nil {
+		statusf("Note: '%s' is also a manual short URL domain, which is still used.", domain)
+	}
+	return nil
+}
+
+func runEnableShortURLCmd(cmd *cobra.Command, args []string) error {
+	c, err := currentConfig()
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+	domain := strings.ToLower(args[0])
+	i := slices.Index(c.DisabledShorteners, domain)
+	if i < 0 {
+		return fmt.Errorf("'%s' is not a disabled short URL domain", domain)
+	}
+	c.DisabledShorteners = slices.Delete(c.DisabledShorteners, i, i+1)
+	if err := saveConfig(c); err != nil {
+		return withExitCode(ExitConfig, fmt.Errorf("error saving configuration: %w", err))
+	}
+	statusf("Built-in short URL domain '%s' enabled.", domain)
+	return nil
+}
+
+func runReviewShortURLCmd(cmd *cobra.Command, args []string) {
+	if cfg == nil {
+		log.Logger.Error().Msg("Configuration not loaded.")
+		os.Exit(ExitConfig)
+	}
+	listOnly, _ := cmd.Flags().GetBool("list")
+
+	path, err := candidates.DefaultPath()
+	if err != nil {
+		errorf("Error: %v", err)
+		os.Exit(1)
+	}
+	list, err := candidates.Load(path)
+	if err != nil {
+		errorf("Error: %v", err)
+		os.Exit(1)
+	}
+
+	// Drop candidates that have since been added as shorteners by other means
+	var pending, kept []candidates.Candidate
+	for _, c := range list {
+		if urlhandler.FindShortener(cfg, c.Domain) != nil {
+			continue
+		}
+		kept = append(kept, c)
+		if c.Pending() {
+			pending = append(pending, c)
+		}
+	}
+
+	if len(pending) == 0 {
+		fmt.Println("No candidate short URL domains to review.")
+		if !cfg.ShortenerLearning.Enabled {
+			statusf("Set 'enabled = true' under [shortener_learning] in the config to detect them automatically.")
+		}
+		return
+	}
+
+	if listOnly {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Domain\tSeen\tLast Seen\tExample")
+		fmt.Fprintln(w, "------\t----\t---------\t-------")
+		for _, c := range pending {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s -> %s\n", c.Domain, c.Count, c.LastSeen.Local().Format("2006-01-02 15:04"), c.ExampleURL, c.RedirectTo)
+		}
+		w.Flush()
+		return
+	}
+
+	const (
+		actionAdd      = "Add as short URL domain"
+		actionSafelink = "Add as safelink domain"
+		actionDismiss  = "Dismiss (don't suggest again)"
+		actionSkip     = "Skip for now"
+		actionStop     = "Stop reviewing"
+	)
+
+	p := prompt.New()
+	added := 0
+	for _, c := range pending {
+		fmt.Printf("\n%s (seen %d times)\n  %s\n  -> %s\n", c.Domain, c.Count, c.ExampleURL, c.RedirectTo)
+		action, err := p.Ask(fmt.Sprintf("What should be done with '%s'?", c.Domain)).
+			Choose([]string{actionAdd, actionSafelink, actionDismiss, actionSkip, actionStop})
+		if err != nil {
+			errorf("Error: %v", err)
+			os.Exit(1)
+		}
+		if action == actionStop {
+			break
+		}
+
+		i := candidates.Find(kept, c.Domain)
+		switch action {
+		case actionAdd, actionSafelink:
+			cfg.ManualShorteners = append(cfg.ManualShorteners, config.ShortenerService{
+				Domain:     c.Domain,
+				IsSafelink: action == actionSafelink,
+			})
+			kept = append(kept[:i], kept[i+1:]...)
+			added++
+		case actionDismiss:
+			kept[i].Dismissed = true
+		}
+	}
+
+	if added > 0 {
+		if err := saveConfig(cfg); err != nil {
+			errorf("Error saving configuration: %v", err)
+			os.Exit(ExitConfig)
+		}
+	}
+	if err := candidates.Save(path, kept); err != nil {
+		errorf("Error saving candidates: %v", err)
+		os.Exit(ExitConfig)
+	}
+	statusf("\n%d short URL domain(s) added.", added)
 }
 
 // --- Helper Functions ---
 
+// isBuiltinShortener reports whether domain is a built-in (or downloaded) shortener
+// domain, whether or not it is disabled.
+func isBuiltinShortener(domain string) bool {
+	return slices.ContainsFunc(config.BuiltinShorteners(), func(s config.ShortenerService) bool {
+		return s.Domain == domain
+	})
+}
+
 // printShortURLList prints the list of configured shortener domains using tabwriter.
-// If showBuiltin is true, it includes both manual and built-in domains.
+// If showBuiltin is true, it includes both manual and built-in domains, with those
+// disabled or overridden by a manual entry marked as such.
 func printShortURLList(cfg *config.Config, showBuiltin bool) {
 	fmt.Println("--- Short URLs ---")
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) // minwidth, tabwidth, padding, padchar, flags
@@ -290,11 +502,18 @@ func printShortURLList(cfg *config.Config, showBuiltin bool) {
 	builtinCount := 0
 	if showBuiltin {
 		// Add a separator if both lists are shown and manual list wasn't empty
-		if manualCount > 0 && len(cfg.Shorteners) > 0 {
+		builtin := config.BuiltinShorteners()
+		if manualCount > 0 && len(builtin) > 0 {
 			fmt.Fprintln(w, "------\t----------\t----") // Separator line
 		}
-		for _, s := range cfg.Shorteners {
-			fmt.Fprintf(w, "%s\t%t\t%s\n", s.Domain, s.IsSafelink, "Built-in")
+		for _, s := range builtin {
+			kind := "Built-in"
+			if slices.Contains(cfg.DisabledShorteners, s.Domain) {
+				kind = "Built-in (disabled)"
+			} else if _, _, err := cfg.FindManualShortenerByDomain(s.Domain); err == nil {
+				kind = "Built-in (overridden)"
+			}
+			fmt.Fprintf(w, "%s\t%t\t%s\n", s.Domain, s.IsSafelink, kind)
 			builtinCount++
 		}
 		if builtinCount == 0 {
@@ -308,6 +527,7 @@ func printShortURLList(cfg *config.Config, showBuiltin bool) {
 
 // completeManualShortURLDomains provides completion for manually added short URL domains.
 func completeManualShortURLDomains(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg := loadConfigForCompletion()
 	if cfg == nil {
 		log.Logger.Warn().Msg("Completion: Configuration not loaded.")
 		return nil, cobra.ShellCompDirectiveError
@@ -325,6 +545,24 @@ func completeManualShortURLDomains(cmd *cobra.Command, args []string, toComplete
 	return domains, cobra.ShellCompDirectiveNoFileComp
 }
 
+// completeBuiltinShortURLDomains returns a completion function for built-in short URL
+// domains: the disabled ones if disabled is set, the others otherwise.
+func completeBuiltinShortURLDomains(disabled bool) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		cfg := loadConfigForCompletion()
+		if cfg == nil || len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var domains []string
+		for _, s := range config.BuiltinShorteners() {
+			if slices.Contains(cfg.DisabledShorteners, s.Domain) == disabled && strings.HasPrefix(s.Domain, toComplete) {
+				domains = append(domains, s.Domain)
+			}
+		}
+		return domains, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
 // promptSelectManualShortURL prompts the user to select a manually added short URL domain from a list.
 func promptSelectManualShortURL(promptText string, shorteners []config.ShortenerService) (string, error) {
 	if len(shorteners) == 0 {
@@ -379,7 +617,158 @@ func promptSafelink(promptText string, currentValue bool) bool {
 	return result == "Yes"
 }
 
+// addShortenerRequestFlags adds the flags setting the User-Agent and headers sent to a
+// shortener domain.
+func addShortenerRequestFlags(cmd *cobra.Command) {
+	cmd.Flags().String("user-agent", "", "User-Agent sent to the domain when resolving (empty for rurl's own)")
+	cmd.Flags().StringArray("header", nil, "Extra header sent to the domain when resolving, as 'Name: value' (repeatable; '' removes them)")
+}
+
+// applyShortenerRequestFlags sets the User-Agent and headers of s from the flags of cmd
+// that were given. The headers replace any existing ones.
+func applyShortenerRequestFlags(cmd *cobra.Command, s *config.ShortenerService) error {
+	if cmd.Flags().Changed("user-agent") {
+		s.UserAgent, _ = cmd.Flags().GetString("user-agent")
+	}
+	if !cmd.Flags().Changed("header") {
+		return nil
+	}
+	values, _ := cmd.Flags().GetStringArray("header")
+	headers, err := parseHeaders(values)
+	if err != nil {
+		return err
+	}
+	s.Headers = headers
+	return nil
+}
+
+// parseHeaders parses "Name: value" headers, skipping empty strings. It returns nil if
+// there are none.
+func parseHeaders(values []string) (map[string]string, error) {
+	var headers map[string]string
+	for _, v := range values {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(v, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid header '%s', expected 'Name: value'", v)
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
 // registerShortURLCommands adds the shorturl subcommands to the given parent command.
 func registerShortURLCommands(parentCmd *cobra.Command) {
 	addShortURLCommands(parentCmd)
 }
+
+// maxShortenerFeedSize bounds a downloaded shortener feed or its signature.
+const maxShortenerFeedSize = 1 << 20
+
+// runUpdateShortURLsCmd downloads, verifies and stores the shortener feed
+func runUpdateShortURLsCmd(cmd *cobra.Command, args []string) error {
+	c, err := currentConfig()
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+	skipSignature, _ := cmd.Flags().GetBool("skip-signature")
+	feedURL, publicKey := c.ShortenerFeed.URL, c.ShortenerFeed.PublicKey
+	if feedURL == "" {
+		feedURL = config.DefaultShortenerFeedURL
+		if publicKey == "" {
+			publicKey = update.PublicKey
+		}
+	}
+	path, err := config.ShortenerFeedPath()
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	client := &http.Client{Timeout: urlListTimeout}
+	feed, err := updateShortenerFeed(ctx, client, feedURL, publicKey, c.ShortenerFeed.SHA256, skipSignature, path)
+	if err != nil {
+		return err
+	}
+	statusf("Downloaded %d shortener domain(s) from %s, %d of them not built in.", len(feed), feedURL, len(feed)-countBuiltin(feed))
+	return nil
+}
+
+// updateShortenerFeed downloads the feed at feedURL, verifies it (see
+// verifyShortenerFeed) and writes it to path, returning the shorteners it lists. path
+// is left alone unless the feed is valid.
+func updateShortenerFeed(ctx context.Context, client *http.Client, feedURL, publicKey, sha256Hex string, skipSignature bool, path string) ([]config.ShortenerService, error) {
+	if u, err := url.Parse(feedURL); err != nil || u.Scheme != "https" && u.Scheme != "http" {
+		return nil, fmt.Errorf("invalid shortener feed URL '%s' (expected http or https)", feedURL)
+	}
+	data, err := fetch(ctx, client, feedURL, maxShortenerFeedSize)
+	if err != nil {
+		return nil, fmt.Errorf("cannot download the shortener feed: %w", err)
+	}
+	if err := verifyShortenerFeed(ctx, client, feedURL, data, publicKey, sha256Hex, skipSignature); err != nil {
+		return nil, err
+	}
+	feed, err := config.ParseShortenerFeed(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shortener feed: %w", err)
+	}
+	if err := replaceFile(path, data); err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// verifyShortenerFeed checks data, downloaded from feedURL, against sha256Hex if set
+// and against the minisign signature at feedURL + ".minisig" if publicKey is set. At
+// least one of them must be set, unless skipSignature is.
+func verifyShortenerFeed(ctx context.Context, client *http.Client, feedURL string, data []byte, publicKey, sha256Hex string, skipSignature bool) error {
+	verified := false
+	if sha256Hex != "" {
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, strings.TrimSpace(sha256Hex)) {
+			return fmt.Errorf("shortener feed checksum %s does not match shortener_feed.sha256", got)
+		}
+		verified = true
+	}
+	if publicKey != "" {
+		sig, err := fetch(ctx, client, feedURL+".minisig", maxShortenerFeedSize)
+		if err != nil {
+			return fmt.Errorf("cannot download the shortener feed signature: %w", err)
+		}
+		if err := update.VerifySignature(publicKey, data, sig); err != nil {
+			return fmt.Errorf("shortener feed: %w", err)
+		}
+		verified = true
+	}
+	if !verified && !skipSignature {
+		return fmt.Errorf("cannot verify the shortener feed: set shortener_feed.public_key or shortener_feed.sha256, or pass --skip-signature to trust the download as is")
+	}
+	if !verified {
+		log.Warn().Str("url", feedURL).Msg("Shortener feed not verified (--skip-signature)")
+	}
+	return nil
+}
+
+// countBuiltin returns how many shorteners of feed are built into rurl.
+func countBuiltin(feed []config.ShortenerService) int {
+	builtin := config.DefaultConfig().Shorteners
+	n := 0
+	for _, s := range feed {
+		for _, b := range builtin {
+			if b.Domain == s.Domain {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
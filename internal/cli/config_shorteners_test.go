@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateShortenerFeed(t *testing.T) {
+	const feedData = "# Curated shorteners\nbit.ly\nnew.example\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/shorteners.txt":
+			_, _ = w.Write([]byte(feedData))
+		case "/broken.txt":
+			_, _ = w.Write([]byte("https://not-a-domain/\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	feedURL := server.URL + "/shorteners.txt"
+	path := filepath.Join(t.TempDir(), "rurl", "shorteners.txt")
+	sum := sha256.Sum256([]byte(feedData))
+	checksum := hex.EncodeToString(sum[:])
+	ctx := context.Background()
+
+	// Without a key or checksum the feed is refused, unless told to trust it
+	_, err := updateShortenerFeed(ctx, server.Client(), feedURL, "", "", false, path)
+	assert.ErrorContains(t, err, "cannot verify")
+	assert.NoFileExists(t, path)
+
+	_, err = updateShortenerFeed(ctx, server.Client(), feedURL, "", "00ff", false, path)
+	assert.ErrorContains(t, err, "does not match")
+	assert.NoFileExists(t, path)
+
+	feed, err := updateShortenerFeed(ctx, server.Client(), feedURL, "", checksum, false, path)
+	require.NoError(t, err)
+	assert.Equal(t, []config.ShortenerService{{Domain: "bit.ly"}, {Domain: "new.example"}}, feed)
+	assert.Equal(t, 1, countBuiltin(feed))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, feedData, string(data))
+
+	// The signature is required when a key is set, and an invalid feed keeps the last one
+	_, err = updateShortenerFeed(ctx, server.Client(), feedURL, "RWQ=", checksum, false, path)
+	assert.ErrorContains(t, err, "signature")
+	_, err = updateShortenerFeed(ctx, server.Client(), server.URL+"/broken.txt", "", "", true, path)
+	assert.ErrorContains(t, err, "invalid shortener feed")
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, feedData, string(data))
+}
+
+func TestDisableBuiltinShortener(t *testing.T) {
+	originalCfg, originalFile := cfg, cfgFile
+	defer func() { cfg, cfgFile = originalCfg, originalFile }()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir()) // No downloaded feed
+	t.Setenv("HOME", t.TempDir())
+	cfgFile = filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, config.SaveConfig(config.DefaultConfig(), cfgFile))
+	var err error
+	cfg, err = config.LoadConfig(cfgFile)
+	require.NoError(t, err)
+
+	assert.ErrorContains(t, runDisableShortURLCmd(nil, []string{"links.example"}), "not a built-in")
+	require.NoError(t, runDisableShortURLCmd(nil, []string{"T.co"}))
+	loaded, err := config.LoadConfig(cfgFile)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"t.co"}, loaded.DisabledShorteners)
+	assert.NotContains(t, loaded.Shorteners, config.ShortenerService{Domain: "t.co"})
+
+	out := captureStdout(t, func() { printShortURLList(cfg, true) })
+	assert.Regexp(t, `t\.co\s+false\s+Built-in \(disabled\)`, out)
+
+	require.NoError(t, runEnableShortURLCmd(nil, []string{"t.co"}))
+	assert.ErrorContains(t, runEnableShortURLCmd(nil, []string{"t.co"}), "not a disabled")
+	loaded, err = config.LoadConfig(cfgFile)
+	require.NoError(t, err)
+	assert.Empty(t, loaded.DisabledShorteners)
+	assert.Contains(t, loaded.Shorteners, config.ShortenerService{Domain: "t.co"})
+}
@@ -0,0 +1,390 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/browser"
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/history"
+	"github.com/jmylchreest/rurl/internal/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureStdout returns everything fn writes to os.Stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	originalStdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = originalStdout }()
+
+	fn()
+	w.Close()
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestRunDetectBrowsersCmd(t *testing.T) {
+	originalCfg, originalSave := cfg, detectSave
+	defer func() { cfg, detectSave = originalCfg, originalSave }()
+	cfg, detectSave = &config.Config{}, false
+
+	// Detection finds a Chromium install, whatever this system has
+	root := t.TempDir()
+	chromium := filepath.Join(root, "bin", "chromium")
+	restore := browser.RegisterDetector(&browser.StaticDetector{
+		Browsers: []config.Browser{
+			{Name: "Chromium", BrowserID: "chromium", Executable: chromium, ProfileArg: "--profile-directory=%s", InstallSource: config.InstallNative},
+		},
+		Profiles: map[string][]config.Profile{"chromium": {{BrowserID: "chromium", ProfileDir: "Profile 2"}}},
+	})
+	defer restore()
+
+	out := captureStdout(t, func() { runDetectBrowsersCmd(nil, nil) })
+	assert.Contains(t, out, chromium)
+	assert.Contains(t, out, "chromium-profile-2")
+	assert.Contains(t, out, config.InstallNative)
+	assert.Contains(t, out, "Run with --save")
+
+	// Profiles in other user data directories are detected on request
+	portable := filepath.Join(root, "portable")
+	require.NoError(t, os.MkdirAll(filepath.Join(portable, "Default"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(portable, "Default", "Preferences"), []byte("{}"), 0644))
+	profiles, err := discoverUserDataDirs([]config.Browser{{BrowserID: "chromium", ProfileArg: "--profile-directory=%s"}}, nil, []string{"chromium=" + portable})
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	assert.Equal(t, "chromium-portable-default-e3b0c4", profiles[0].ID) // Hash of no executable
+	assert.Equal(t, portable, profiles[0].UserDataDir)
+
+	// Configured directories are scanned again, and kept when they cannot be read
+	missing := config.Profile{ID: "chromium-usb-default", BrowserID: "chromium", ProfileDir: "Default", UserDataDir: filepath.Join(root, "usb")}
+	profiles, err = discoverUserDataDirs([]config.Browser{{BrowserID: "chromium", ProfileArg: "--profile-directory=%s"}}, []config.Profile{profiles[0], missing}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"chromium-portable-default-e3b0c4", "chromium-usb-default"}, []string{profiles[0].ID, profiles[1].ID})
+
+	// Requested directories must be valid
+	_, err = discoverUserDataDirs(nil, nil, []string{"chromium=" + portable})
+	assert.Error(t, err)
+	_, err = discoverUserDataDirs(nil, nil, []string{portable})
+	assert.Error(t, err)
+}
+
+func TestDetectBrowsersMergePolicy(t *testing.T) {
+	originalCfg, originalFile, originalSave, originalPolicy, originalYes := cfg, cfgFile, detectSave, detectMergePolicy, detectYes
+	defer func() {
+		cfg, cfgFile, detectSave, detectMergePolicy, detectYes = originalCfg, originalFile, originalSave, originalPolicy, originalYes
+	}()
+
+	// Only Chromium and its "Profile 2" are installed
+	bin := filepath.Join(t.TempDir(), "bin")
+	detected := config.Browser{Name: "Chromium", BrowserID: "chromium", Executable: filepath.Join(bin, "chromium"), ProfileArg: "--profile-directory=%s"}
+	restore := browser.RegisterDetector(&browser.StaticDetector{
+		Browsers: []config.Browser{detected},
+		Profiles: map[string][]config.Profile{"chromium": {{BrowserID: "chromium", ProfileDir: "Profile 2"}}},
+	})
+	defer restore()
+	detectedID := browser.ProfileID(detected, config.Profile{ProfileDir: "Profile 2"})
+
+	configured := func() *config.Config {
+		return &config.Config{
+			DefaultProfileID: "chromium-old",
+			Browsers: []config.Browser{
+				{Name: "Chromium", BrowserID: "chromium", Executable: filepath.Join(bin, "chromium"), ProfileArg: "--profile-directory=%s"},
+				{Name: "Firefox", BrowserID: "firefox", Executable: "/opt/firefox/firefox", ProfileArg: "-P %s"},
+			},
+			Profiles: []config.Profile{
+				{ID: "chromium-old", BrowserID: "chromium", ProfileDir: "Profile 9"},
+				{ID: "firefox-work", BrowserID: "firefox", ProfileDir: "work"},
+			},
+			Rules: []config.Rule{
+				{Name: "Old", Pattern: "old", Scope: config.ScopeDomain, ProfileID: "chromium-old"},
+				{Name: "Work", Pattern: "work", Scope: config.ScopeDomain, ProfileID: "firefox-work"},
+			},
+			Hooks: config.Hooks{PostLaunch: "logger rurl"},
+		}
+	}
+	detect := func(policy string) *config.Config {
+		cfg, cfgFile = configured(), filepath.Join(t.TempDir(), "config.toml")
+		detectSave, detectMergePolicy, detectYes = true, policy, true
+		captureStdout(t, func() { runDetectBrowsersCmd(nil, nil) })
+		loaded, err := config.LoadConfig(cfgFile)
+		require.NoError(t, err)
+		return loaded
+	}
+	ruleProfiles := func(c *config.Config) map[string]string {
+		out := make(map[string]string)
+		for _, r := range c.Rules {
+			out[r.Name] = r.ProfileID
+		}
+		return out
+	}
+
+	// keep: nothing configured is removed
+	loaded := detect(mergeKeep)
+	assert.Len(t, loaded.Browsers, 2)
+	assert.Len(t, loaded.Profiles, 3)
+	assert.Equal(t, "chromium-old", loaded.DefaultProfileID)
+	assert.Equal(t, map[string]string{"Old": "chromium-old", "Work": "firefox-work"}, ruleProfiles(loaded))
+	assert.Equal(t, "logger rurl", loaded.Hooks.PostLaunch) // Other sections are kept
+
+	// replace: references move to a profile of the same browser, or the default
+	loaded = detect(mergeReplace)
+	assert.Len(t, loaded.Browsers, 1)
+	assert.Equal(t, detectedID, loaded.DefaultProfileID)
+	assert.Equal(t, map[string]string{"Old": detectedID, "Work": detectedID}, ruleProfiles(loaded))
+	assert.Equal(t, "logger rurl", loaded.Hooks.PostLaunch)
+
+	// prune: rules using removed profiles are deleted
+	loaded = detect(mergePrune)
+	assert.Equal(t, detectedID, loaded.DefaultProfileID)
+	assert.Empty(t, loaded.Rules)
+}
+
+func TestMergeDetected(t *testing.T) {
+	configuredBrowsers := []config.Browser{
+		{Name: "My Chromium", BrowserID: "chromium", Executable: "/old/chromium", ExtraArgs: []string{"--incognito"}},
+		{Name: "Custom", BrowserID: "custom", Executable: "/opt/custom", Origin: config.OriginManual},
+		{Name: "Edge", BrowserID: "edge", Executable: "/usr/bin/microsoft-edge"},
+	}
+	detectedBrowsers := []config.Browser{
+		{Name: "Chromium", BrowserID: "chromium", Executable: "/usr/bin/chromium", ProfileArg: "--profile-directory=%s", Origin: config.OriginDetected},
+	}
+	browsers := mergeDetectedBrowsers(configuredBrowsers, detectedBrowsers)
+	require.Len(t, browsers, 2)
+	// Edits are kept, but detection owns the executable
+	assert.Equal(t, config.Browser{
+		Name: "My Chromium", BrowserID: "chromium", Executable: "/usr/bin/chromium", ProfileArg: "--profile-directory=%s",
+		ExtraArgs: []string{"--incognito"}, Origin: config.OriginDetected,
+	}, browsers[0])
+	// Manual browsers are kept although not detected
+	assert.Equal(t, configuredBrowsers[1], browsers[1])
+
+	configuredProfiles := []config.Profile{
+		{ID: "chromium-default", Name: "Personal", BrowserID: "chromium", ProfileDir: "Default", Origin: config.OriginManual},
+		{ID: "chromium-work", Name: "Work", BrowserID: "chromium", ProfileDir: "Profile 1", Origin: config.OriginManual},
+		{ID: "custom-default", BrowserID: "custom"},
+		{ID: "edge-default", BrowserID: "edge", ProfileDir: "Default"},
+	}
+	detectedProfiles := []config.Profile{
+		{ID: "chromium-default", Name: "Default", BrowserID: "chromium", ProfileDir: "Default", Email: "me@example.com", Origin: config.OriginDetected},
+	}
+	profiles := mergeDetectedProfiles(configuredProfiles, detectedProfiles, map[string]struct{}{"edge-default": {}})
+	// Manual entries win over detection; removed profiles are dropped
+	assert.Equal(t, configuredProfiles[:3], profiles)
+}
+
+func TestReconcileBrowserIDs(t *testing.T) {
+	// A Flatpak Chrome configured as "chrome", before the native one was installed
+	configured := &config.Config{
+		Browsers:         []config.Browser{{BrowserID: "chrome", Executable: "flatpak run com.google.Chrome"}},
+		Profiles:         []config.Profile{{ID: "chrome-profile-1", BrowserID: "chrome", ProfileDir: "Profile 1"}},
+		DefaultProfileID: "chrome-profile-1",
+		Rules:            []config.Rule{{Name: "Work", ProfileID: "chrome-profile-1"}},
+	}
+	detectedBrowsers := []config.Browser{
+		{BrowserID: "chrome", Executable: "/usr/bin/google-chrome-stable", InstallSource: config.InstallNative},
+		{BrowserID: "chrome-flatpak", Executable: "flatpak run com.google.Chrome", InstallSource: config.InstallFlatpak},
+	}
+	detectedProfiles := []config.Profile{
+		{ID: "chrome-profile-1", BrowserID: "chrome", ProfileDir: "Profile 1"},
+		{ID: "chrome-flatpak-profile-1", BrowserID: "chrome-flatpak", ProfileDir: "Profile 1"},
+	}
+
+	got := reconcileBrowserIDs(configured, detectedBrowsers, detectedProfiles)
+	assert.Equal(t, "chrome-flatpak", got.Browsers[0].BrowserID)
+	assert.Equal(t, config.Profile{ID: "chrome-flatpak-profile-1", BrowserID: "chrome-flatpak", ProfileDir: "Profile 1"}, got.Profiles[0])
+	assert.Equal(t, "chrome-flatpak-profile-1", got.DefaultProfileID)
+	assert.Equal(t, "chrome-flatpak-profile-1", got.Rules[0].ProfileID)
+	assert.Equal(t, "chrome", configured.Browsers[0].BrowserID, "configuration modified")
+
+	// Nothing is removed when both installs are configured under their own IDs
+	_, _, removed := compareDetectedWithConfig(got,
+		map[string]config.Browser{"chrome": detectedBrowsers[0], "chrome-flatpak": detectedBrowsers[1]},
+		map[string]config.Profile{"chrome-profile-1": detectedProfiles[0], "chrome-flatpak-profile-1": detectedProfiles[1]})
+	assert.Empty(t, removed)
+
+	// Browsers already known under their detected IDs are left alone
+	assert.Same(t, got, reconcileBrowserIDs(got, detectedBrowsers, detectedProfiles))
+
+	// Profiles configured with the IDs of older versions take the stable detected IDs
+	legacy := &config.Config{
+		Browsers:         detectedBrowsers[:1],
+		Profiles:         []config.Profile{{ID: "chrome-person-1", Name: "Person 1", BrowserID: "chrome", ProfileDir: "Profile 1"}},
+		DefaultProfileID: "chrome-person-1",
+		Headless:         config.Headless{ProfileID: "chrome-person-1"},
+		Rules:            []config.Rule{{Name: "Work", ProfileID: "chrome-person-1"}},
+	}
+	stable := []config.Profile{{ID: "chrome-profile-1-3fa9c2", Name: "Work", BrowserID: "chrome", ProfileDir: "Profile 1"}}
+	got = reconcileBrowserIDs(legacy, detectedBrowsers[:1], stable)
+	assert.Equal(t, "chrome-profile-1-3fa9c2", got.Profiles[0].ID)
+	assert.Equal(t, "Person 1", got.Profiles[0].Name)
+	assert.Equal(t, "chrome-profile-1-3fa9c2", got.DefaultProfileID)
+	assert.Equal(t, "chrome-profile-1-3fa9c2", got.Headless.ProfileID)
+	assert.Equal(t, "chrome-profile-1-3fa9c2", got.Rules[0].ProfileID)
+	assert.Equal(t, "chrome-person-1", legacy.Profiles[0].ID, "configuration modified")
+}
+
+func TestSetBrowserTemplate(t *testing.T) {
+	testCfg := &config.Config{
+		Browsers: []config.Browser{{Name: "Edge", BrowserID: "edge", Executable: "/usr/bin/microsoft-edge"}},
+	}
+
+	// Templates fill the argument syntax of their family; flags override single arguments
+	b, err := setBrowserTemplate(testCfg, "edge", "Chromium", map[string]string{"incognito-arg": "--inprivate"})
+	require.NoError(t, err)
+	assert.Equal(t, "--profile-directory=%s", testCfg.Browsers[0].ProfileArg)
+	assert.Equal(t, "--inprivate", b.IncognitoArg)
+	assert.Equal(t, "--new-window", b.NewWindowArg)
+	assert.Empty(t, b.NewTabArg)
+
+	_, err = setBrowserTemplate(testCfg, "edge", "firefox", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "-P %s", testCfg.Browsers[0].ProfileArg)
+	assert.Equal(t, "--private-window", testCfg.Browsers[0].IncognitoArg)
+	assert.Equal(t, "--new-tab", testCfg.Browsers[0].NewTabArg)
+
+	// The custom family only changes what is given
+	_, err = setBrowserTemplate(testCfg, "edge", "custom", map[string]string{"profile-arg": "--profile=%s"})
+	require.NoError(t, err)
+	assert.Equal(t, "--profile=%s", testCfg.Browsers[0].ProfileArg)
+	assert.Equal(t, "--private-window", testCfg.Browsers[0].IncognitoArg)
+
+	_, err = setBrowserTemplate(testCfg, "edge", "custom", nil)
+	assert.Error(t, err)
+	_, err = setBrowserTemplate(testCfg, "edge", "webkit", nil)
+	assert.ErrorContains(t, err, "unknown browser family")
+	_, err = setBrowserTemplate(testCfg, "missing", "chromium", nil)
+	assert.Error(t, err)
+}
+
+func TestRulesWithTags(t *testing.T) {
+	all := []config.Rule{
+		{Name: "Mail", Tags: []string{"work", "mail"}},
+		{Name: "Docs", Tags: []string{"Work"}},
+		{Name: "News"},
+	}
+	names := func(rs []config.Rule) []string {
+		var out []string
+		for _, r := range rs {
+			out = append(out, r.Name)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"Mail", "Docs", "News"}, names(rulesWithTags(all, nil)))
+	assert.Equal(t, []string{"Mail", "Docs"}, names(rulesWithTags(all, []string{"work"})))
+	assert.Equal(t, []string{"Mail"}, names(rulesWithTags(all, []string{"work", "MAIL"})))
+	assert.Empty(t, rulesWithTags(all, []string{"personal"}))
+}
+
+func TestPrintLintIssues(t *testing.T) {
+	var buf bytes.Buffer
+	printLintIssues(&buf, []rules.LintIssue{{
+		Rule:       config.Rule{ID: "docs", Name: "Docs"},
+		Kind:       rules.LintShadowed,
+		Message:    "never fires",
+		Suggestion: "delete it",
+	}})
+	assert.Equal(t, "warning: rule 'Docs' (docs) [shadowed]: never fires\n  suggestion: delete it\n", buf.String())
+}
+
+func TestWriteRedactedConfig(t *testing.T) {
+	r := &config.Redactor{Key: []byte("test"), Home: "/home/jdoe", Username: "jdoe"}
+	source := &config.Config{
+		DefaultProfileID: "chrome-work",
+		Browsers:         []config.Browser{{Name: "Chrome", BrowserID: "chrome", Executable: "/home/jdoe/bin/chrome", ProfileArg: "--profile-directory=%s"}},
+		Profiles:         []config.Profile{{ID: "chrome-work", Name: "Work", BrowserID: "chrome", ProfileDir: "Profile 1"}},
+		Rules:            []config.Rule{{ID: "corp", Name: "Corp", Pattern: `^(?:.*\.)?acmecorp\.com$`, Scope: config.ScopeDomain, ProfileID: "chrome-work"}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeRedactedConfig(&buf, r.Redact(source)))
+	assert.NotContains(t, buf.String(), "jdoe")
+	assert.NotContains(t, buf.String(), "acmecorp")
+
+	// The export is a valid configuration with its references intact
+	path := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+	exported, err := config.LoadConfig(path)
+	require.NoError(t, err)
+	require.Len(t, exported.Rules, 1)
+	assert.Equal(t, "~/bin/chrome", exported.Browsers[0].Executable)
+	assert.Equal(t, exported.DefaultProfileID, exported.Rules[0].ProfileID)
+	assert.Equal(t, "Profile 1", exported.Profiles[0].ProfileDir)
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := parseHeaders([]string{"X-Api-Key: secret", "Accept:text/html ", ""})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"X-Api-Key": "secret", "Accept": "text/html"}, headers)
+
+	headers, err = parseHeaders([]string{""})
+	require.NoError(t, err)
+	assert.Nil(t, headers)
+
+	for _, invalid := range []string{"no colon", ": value", "Bad Name: value"} {
+		_, err = parseHeaders([]string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestLastRoutedDomain(t *testing.T) {
+	_, _, ok := lastRoutedDomain(nil)
+	assert.False(t, ok)
+
+	entries := []history.Entry{
+		{URL: "https://docs.example.com/a", ProfileID: "work"},
+		{URL: "https://Mail.Example.org:8443/inbox", RuleName: "Mail", ProfileID: "personal"},
+		{URL: "file:///tmp/page.html", ProfileID: "personal"}, // No domain, skipped
+	}
+	domain, last, ok := lastRoutedDomain(entries)
+	require.True(t, ok)
+	assert.Equal(t, "mail.example.org", domain)
+	assert.Equal(t, "Mail", last.RuleName)
+
+	rule := domainRule(&config.Config{}, domain)
+	assert.Equal(t, "mail.example.org", rule.Name)
+	assert.Equal(t, config.ScopeDomain, rule.Scope)
+	matched, _, err := rules.TestPattern(rule.Scope, rule.Pattern, "https://mail.example.org/inbox")
+	require.NoError(t, err)
+	assert.True(t, matched)
+	matched, _, err = rules.TestPattern(rule.Scope, rule.Pattern, "https://xmail.example.org/inbox")
+	require.NoError(t, err)
+	assert.False(t, matched)
+}
+
+func TestConfigCommandsShareConfig(t *testing.T) {
+	originalCfg, originalFile := cfg, cfgFile
+	defer func() { cfg, cfgFile = originalCfg, originalFile }()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir()) // The default location must not be used
+
+	// Commands work on the configuration loaded from --config and save it back there
+	cfgFile = filepath.Join(t.TempDir(), "custom.toml")
+	cfg = &config.Config{
+		DefaultProfileID: "personal",
+		Browsers:         []config.Browser{{Name: "Test", BrowserID: "test", Executable: "/bin/echo"}},
+		Profiles:         []config.Profile{{ID: "personal", BrowserID: "test"}},
+		Rules:            []config.Rule{{ID: "work", Name: "Work", Pattern: "work", Scope: config.ScopeDomain, ProfileID: "personal"}},
+	}
+	captureStdout(t, func() { require.NoError(t, setRuleEnabled("work", false)) })
+	assert.False(t, cfg.Rules[0].IsEnabled())
+
+	loaded, err := config.LoadConfig(cfgFile)
+	require.NoError(t, err)
+	require.Len(t, loaded.Rules, 1)
+	assert.False(t, loaded.Rules[0].IsEnabled())
+	defaultPath, err := config.DefaultConfigFile()
+	require.NoError(t, err)
+	assert.NoFileExists(t, defaultPath)
+
+	// Without a loaded configuration, commands fail with the configuration exit code
+	cfg = nil
+	err = setRuleEnabled("work", true)
+	assert.Equal(t, ExitConfig, ExitCode(err))
+}
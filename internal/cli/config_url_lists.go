@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// urlListTimeout bounds the download of one URL list.
+const urlListTimeout = 30 * time.Second
+
+// maxURLListSize bounds a downloaded URL list, which is read on every rule match.
+const maxURLListSize = 4 << 20
+
+// addURLListCommands adds the commands for URL lists referenced by rules.
+func addURLListCommands(configCmd *cobra.Command) {
+	listCmd := &cobra.Command{
+		Use:   "url-list",
+		Short: "Manage the URL lists referenced by rules",
+		Long: `URL lists hold domains or patterns that rules refer to with PatternListRef, kept
+in a file maintained separately from the rules (or downloaded from a URL). Lists are
+defined in the [[url_lists]] section of the configuration file.`,
+	}
+	listCmd.AddCommand(&cobra.Command{
+		Use:         "list",
+		Short:       "List the configured URL lists",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{readOnlyConfigAnnotation: ""},
+		RunE:        runURLListListCmd,
+	})
+	listCmd.AddCommand(&cobra.Command{
+		Use:   "refresh [name...]",
+		Short: "Download URL lists from their URL",
+		Long: `Downloads the lists with a URL (or only those named) and replaces their file.
+A list's file is only replaced by a complete download, so a failed refresh keeps the
+previous entries.`,
+		RunE:              runURLListRefreshCmd,
+		ValidArgsFunction: completeURLListNames,
+	})
+	configCmd.AddCommand(listCmd)
+}
+
+// completeURLListNames completes the names of URL lists with a URL.
+func completeURLListNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg := loadConfigForCompletion()
+	if cfg == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, l := range cfg.URLLists {
+		if l.URL != "" && strings.HasPrefix(l.Name, toComplete) {
+			names = append(names, l.Name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func runURLListListCmd(cmd *cobra.Command, args []string) error {
+	if len(cfg.URLLists) == 0 {
+		fmt.Println("No URL lists configured.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Name\tFile\tURL\tEntries\tRules")
+	fmt.Fprintln(w, "----\t----\t---\t-------\t-----")
+	for _, l := range cfg.URLLists {
+		path, err := cfg.URLListPath(l)
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			path = "-"
+		}
+		used := 0
+		for _, r := range cfg.Rules {
+			if r.PatternListRef == l.Name {
+				used++
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\n", l.Name, path, l.URL, len(l.Entries), used)
+	}
+	return w.Flush()
+}
+
+func runURLListRefreshCmd(cmd *cobra.Command, args []string) error {
+	var lists []config.URLList
+	for _, name := range args {
+		l := cfg.FindURLList(name)
+		if l == nil {
+			return fmt.Errorf("no URL list named '%s'", name)
+		}
+		if l.URL == "" {
+			return fmt.Errorf("URL list '%s' has no URL to refresh from", name)
+		}
+		lists = append(lists, *l)
+	}
+	if len(args) == 0 {
+		for _, l := range cfg.URLLists {
+			if l.URL != "" {
+				lists = append(lists, l)
+			}
+		}
+	}
+	if len(lists) == 0 {
+		statusf("No URL lists with a URL to refresh.")
+		return nil
+	}
+
+	client := &http.Client{Timeout: urlListTimeout}
+	failed := 0
+	for _, l := range lists {
+		n, err := refreshURLList(cmd.Context(), client, cfg, l)
+		if err != nil {
+			errorf("  ! %s: %v", l.Name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("  %s: %d lines\n", l.Name, n)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d URL list(s) could not be refreshed", failed, len(lists))
+	}
+	return nil
+}
+
+// refreshURLList downloads list.URL to the file of list in c and returns the number of
+// entry lines downloaded. The file is replaced only once the download is complete.
+func refreshURLList(ctx context.Context, client *http.Client, c *config.Config, list config.URLList) (int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if u, err := url.Parse(list.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return 0, fmt.Errorf("invalid URL '%s' (expected http or https)", list.URL)
+	}
+	path, err := c.URLListPath(list)
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := fetch(ctx, client, list.URL, maxURLListSize)
+	if err != nil {
+		return 0, err
+	}
+	if err := replaceFile(path, data); err != nil {
+		return 0, err
+	}
+
+	lines := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			lines++
+		}
+	}
+	return lines, nil
+}
+
+// fetch downloads rawURL, refusing responses larger than limit bytes.
+func fetch(ctx context.Context, client *http.Client, rawURL string, limit int) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "rurl/1.0")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(limit)+1))
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	if len(data) > limit {
+		return nil, fmt.Errorf("download is larger than %d bytes", limit)
+	}
+	return data, nil
+}
+
+// replaceFile writes data to path through a temporary file, so that path is only
+// replaced once data is completely written.
+func replaceFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".rurl-download-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshURLList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/domains.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("# Company domains\nexample.com\n\nexample-corp.net\n"))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "lists", "company.txt")
+	list := config.URLList{Name: "company", File: path, URL: server.URL + "/domains.txt"}
+	n, err := refreshURLList(context.Background(), server.Client(), config.DefaultConfig(), list)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "example-corp.net")
+
+	// A failed download keeps the previous file
+	list.URL = server.URL + "/missing.txt"
+	_, err = refreshURLList(context.Background(), server.Client(), config.DefaultConfig(), list)
+	assert.ErrorContains(t, err, "404")
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "example-corp.net")
+
+	list.URL = "file:///etc/hosts"
+	_, err = refreshURLList(context.Background(), server.Client(), config.DefaultConfig(), list)
+	assert.ErrorContains(t, err, "expected http or https")
+}
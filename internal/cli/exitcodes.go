@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"errors"
+
+	"github.com/jmylchreest/rurl/internal/router"
+	"github.com/jmylchreest/rurl/internal/rules"
+)
+
+// Exit codes of rurl, for scripts and desktop wrappers reacting to failures. Errors
+// without a more specific code exit with ExitError.
+const (
+	ExitOK         = 0
+	ExitError      = 1 // Any other error (invalid arguments, failed command, ...)
+	ExitConfig     = 2 // The configuration could not be loaded or saved, or is invalid
+	ExitNoProfile  = 3 // No rule matched and there is no default profile, or the profile to use does not exist
+	ExitResolution = 4 // The URL could not be resolved (shortener, plugin or timeout)
+	ExitLaunch     = 5 // The browser or system handler could not be started
+	ExitBlocked    = 6 // The launch was refused by the resolution policy, strict mode, the data: URL limit, URL safety checks or a pre_launch hook
+)
+
+// exitError is an error that makes rurl exit with code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode returns err annotated with the code rurl exits with for it, or nil if
+// err is nil.
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// ExitCode returns the code rurl exits with for err: ExitOK for nil, the code err
+// carries if any, and ExitError otherwise.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return ExitError
+}
+
+// routeExitCode returns the exit code for an error routing a URL.
+func routeExitCode(err error) int {
+	var blocked *router.BlockedError
+	var noProfile *rules.NoProfileError
+	switch {
+	case errors.As(err, &blocked):
+		return ExitBlocked
+	case errors.As(err, &noProfile):
+		return ExitNoProfile
+	default:
+		return ExitResolution
+	}
+}
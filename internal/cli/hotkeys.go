@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/hotkey"
+	"github.com/jmylchreest/rurl/internal/launcher"
+	"github.com/jmylchreest/rurl/internal/router"
+	"github.com/jmylchreest/rurl/internal/rules"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// readClipboard returns the text on the clipboard; replaced in tests.
+var readClipboard = launcher.ReadClipboard
+
+// addHotkeysCommand adds the hotkeys and clipboard commands to the root command
+func addHotkeysCommand() {
+	hotkeysCmd := &cobra.Command{
+		Use:   "hotkeys",
+		Short: "Open the clipboard URL in a profile with global hotkeys",
+		Long: `Registers the global hotkeys of hotkeys.bindings and runs until interrupted. Each
+hotkey opens the URL on the clipboard in its profile, whatever the rules say, e.g.
+Ctrl+Alt+1 for work and Ctrl+Alt+2 for personal:
+
+  [[hotkeys.bindings]]
+  Keys = "Ctrl+Alt+1"
+  ProfileID = "chrome-work"
+
+Global hotkeys are registered on Windows. On other platforms, the command prints the
+'rurl clipboard' commands to bind to the keys in your desktop's keyboard settings.`,
+		Args: cobra.NoArgs,
+		RunE: runHotkeysCmd,
+	}
+	rootCmd.AddCommand(hotkeysCmd)
+
+	clipboardCmd := &cobra.Command{
+		Use:   "clipboard",
+		Short: "Open the URL on the clipboard",
+		Long: `Opens the URL on the clipboard like 'rurl <url>', or in the profile given with
+--profile whatever the rules say. Bind it to a key in your desktop's keyboard settings
+where 'rurl hotkeys' cannot register global hotkeys.`,
+		Args: cobra.NoArgs,
+		RunE: runClipboardCmd,
+	}
+	clipboardCmd.Flags().String("profile", "", "Open the URL in this profile instead of the routed one")
+	clipboardCmd.Flags().Bool("incognito", false, "Open the URL in a private window, or not with --incognito=false")
+	_ = clipboardCmd.RegisterFlagCompletionFunc("profile", completeProfileFlag)
+	rootCmd.AddCommand(clipboardCmd)
+}
+
+// runClipboardCmd opens the URL on the clipboard
+func runClipboardCmd(cmd *cobra.Command, args []string) error {
+	if _, err := currentConfig(); err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+	profileID, _ := cmd.Flags().GetString("profile")
+	if cmd.Flags().Changed("incognito") {
+		incognito, _ := cmd.Flags().GetBool("incognito")
+		incognitoFlag = &incognito
+	}
+
+	urlInput, err := clipboardURL()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := routingContext()
+	defer cancel()
+	if profileID == "" {
+		return openURL(ctx, urlInput, confirmLaunch)
+	}
+	return openURLInProfile(ctx, urlInput, profileID, false, confirmLaunch)
+}
+
+// runHotkeysCmd registers the configured hotkeys and opens the clipboard URL in the
+// profile of each hotkey pressed, until interrupted
+func runHotkeysCmd(cmd *cobra.Command, args []string) error {
+	c, err := currentConfig()
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+	bindings := c.Hotkeys.Bindings
+	if len(bindings) == 0 {
+		return withExitCode(ExitConfig, errors.New("no hotkeys configured (add [[hotkeys.bindings]] to the configuration)"))
+	}
+	combos := make([]hotkey.Combo, len(bindings))
+	for i, b := range bindings {
+		if combos[i], err = hotkey.Parse(b.Keys); err != nil {
+			return withExitCode(ExitConfig, err)
+		}
+		if _, err := cfg.FindProfileByID(b.ProfileID); err != nil {
+			return withExitCode(ExitConfig, fmt.Errorf("hotkey '%s': %w", b.Keys, err))
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Launches run outside the hotkey loop, one at a time, so that a slow resolution
+	// does not hold up the desktop's key events
+	var mu sync.Mutex
+	pressed := func(i int) {
+		go func() {
+			mu.Lock()
+			defer mu.Unlock()
+			if err := openClipboardInProfile(ctx, bindings[i]); err != nil {
+				log.Error().Err(err).Str("hotkey", combos[i].String()).Msg("Failed to open clipboard URL")
+				errorf("%s: %v", combos[i], err)
+			}
+		}()
+	}
+
+	for i, b := range bindings {
+		statusf("%s opens the clipboard URL in profile '%s'", combos[i], b.ProfileID)
+	}
+	err = hotkey.Listen(ctx, combos, pressed)
+	if errors.Is(err, hotkey.ErrUnsupported) {
+		errorf("Global hotkeys cannot be registered on %s. Bind these commands in your desktop's keyboard settings instead:", runtime.GOOS)
+		for i, b := range bindings {
+			errorf("  %s\t%s", combos[i], hotkeyCommand(b))
+		}
+	}
+	return err
+}
+
+// hotkeyCommand returns the command that does what pressing the hotkey of b does.
+func hotkeyCommand(b config.Hotkey) string {
+	command := "rurl clipboard --profile " + b.ProfileID
+	if b.Incognito {
+		command += " --incognito"
+	}
+	return command
+}
+
+// openClipboardInProfile opens the URL on the clipboard in the profile of b. URLs the
+// resolution policy would ask about are not launched, as there is no one to ask.
+func openClipboardInProfile(ctx context.Context, b config.Hotkey) error {
+	urlInput, err := clipboardURL()
+	if err != nil {
+		return err
+	}
+	if routeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, routeTimeout, fmt.Errorf("timed out after %s", routeTimeout))
+		defer cancel()
+	}
+	return openURLInProfile(ctx, urlInput, b.ProfileID, b.Incognito, func(string) bool { return false })
+}
+
+// clipboardURL returns the URL on the clipboard, or an error if the clipboard does not
+// hold one.
+func clipboardURL() (string, error) {
+	text, err := readClipboard()
+	if err != nil {
+		return "", err
+	}
+	if u, err := url.Parse(text); err != nil || u.Scheme == "" {
+		return "", fmt.Errorf("the clipboard does not hold a URL")
+	}
+	return text, nil
+}
+
+// openURLInProfile routes urlInput like openURL (resolution, URL safety, launch hooks
+// and history) but opens it in profileID instead of the routed profile, privately if
+// incognito is set, without the rule's handler, app, native app link or browser
+// arguments. confirm asks whether to launch a URL the resolution policy does not allow.
+func openURLInProfile(ctx context.Context, urlInput, profileID string, incognito bool, confirm func(question string) bool) error {
+	if _, err := cfg.FindProfileByID(profileID); err != nil {
+		return withExitCode(ExitNoProfile, err)
+	}
+	log.Info().Str("url", urlInput).Str("profile_id", profileID).Msg("Processing URL for profile")
+
+	route, err := router.Route(ctx, cfg, urlInput)
+	if err != nil {
+		log.Error().Err(err).Str("input_url", urlInput).Msg("Failed to route URL")
+		reportBlocked(urlInput, err)
+		return withExitCode(routeExitCode(err), err)
+	}
+	if route.PolicyViolation != "" {
+		question := fmt.Sprintf("%s resolves to %s, which %s. Open it anyway?", urlInput, route.MatchURL, route.PolicyViolation)
+		if !confirm(question) {
+			log.Warn().Str("resolved_url", route.MatchURL).Str("reason", route.PolicyViolation).Msg("Launch declined by resolution policy")
+			return withExitCode(ExitBlocked, fmt.Errorf("not launched: %s %s", route.MatchURL, route.PolicyViolation))
+		}
+	}
+
+	target := rules.MatchResult{Rule: route.Match.Rule, ProfileID: profileID, Incognito: incognito}
+	return launchMatch(ctx, urlInput, route.LaunchURL, target)
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenClipboardInProfile(t *testing.T) {
+	originalCfg, originalLauncher, originalDisplay, originalClipboard := cfg, appLauncher, hasDisplay, readClipboard
+	defer func() {
+		cfg, appLauncher, hasDisplay, readClipboard = originalCfg, originalLauncher, originalDisplay, originalClipboard
+	}()
+	hasDisplay = func() bool { return true }
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	rec := &recordingLauncher{}
+	appLauncher = rec
+	cfg = &config.Config{
+		DefaultProfileID: "personal",
+		Browsers:         []config.Browser{{Name: "Test Browser", BrowserID: "test", Executable: "/bin/echo"}},
+		Profiles: []config.Profile{
+			{ID: "personal", BrowserID: "test"},
+			{ID: "work", BrowserID: "test"},
+		},
+		Rules: []config.Rule{
+			{Name: "Docs", Pattern: `^docs\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "personal", Handler: "mpv {url}"},
+		},
+	}
+
+	// The hotkey's profile wins over the rule, and its handler is not run
+	readClipboard = func() (string, error) { return "https://docs.example.com/a", nil }
+	require.NoError(t, openClipboardInProfile(context.Background(), config.Hotkey{Keys: "Ctrl+Alt+1", ProfileID: "work", Incognito: true}))
+	require.Len(t, rec.profiles, 1)
+	assert.Equal(t, "work", rec.profiles[0].ID)
+	assert.Equal(t, []bool{true}, rec.incognito)
+
+	readClipboard = func() (string, error) { return "not a url", nil }
+	assert.Error(t, openClipboardInProfile(context.Background(), config.Hotkey{ProfileID: "work"}))
+
+	readClipboard = func() (string, error) { return "", errors.New("no clipboard") }
+	assert.Error(t, openClipboardInProfile(context.Background(), config.Hotkey{ProfileID: "work"}))
+
+	readClipboard = func() (string, error) { return "https://example.com/", nil }
+	err := openClipboardInProfile(context.Background(), config.Hotkey{ProfileID: "missing"})
+	assert.Equal(t, ExitNoProfile, ExitCode(err))
+	assert.Len(t, rec.urls, 1)
+}
+
+func TestHotkeyCommand(t *testing.T) {
+	assert.Equal(t, "rurl clipboard --profile work", hotkeyCommand(config.Hotkey{Keys: "Ctrl+Alt+1", ProfileID: "work"}))
+	assert.Equal(t, "rurl clipboard --profile personal --incognito", hotkeyCommand(config.Hotkey{Keys: "Ctrl+Alt+2", ProfileID: "personal", Incognito: true}))
+}
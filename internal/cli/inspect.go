@@ -0,0 +1,261 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/launcher"
+	"github.com/jmylchreest/rurl/internal/router"
+	"github.com/jmylchreest/rurl/internal/rules"
+	"github.com/jmylchreest/rurl/internal/urlhandler"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var inspectResolve bool
+
+// addInspectCommand adds the inspect command to the root command
+func addInspectCommand() {
+	inspectCmd := &cobra.Command{
+		Use:   "inspect <url>",
+		Short: "Show how a URL would be handled, without opening it",
+		Long: `Shows the parsed components of a URL, whether it is on a known shortener domain
+and how each rule evaluates against it. Nothing is launched.
+
+No network requests are made unless --resolve is given, in which case redirects are
+followed (with HEAD requests) and the redirect chain is shown with status codes. This
+makes it safe to triage links from unknown senders.`,
+		Args:        cobra.ExactArgs(1),
+		Annotations: map[string]string{readOnlyConfigAnnotation: ""},
+		Run:         runInspectCmd,
+	}
+	inspectCmd.Flags().BoolVar(&inspectResolve, "resolve", false, "Follow redirects and show the redirect chain")
+	rootCmd.AddCommand(inspectCmd)
+}
+
+// runInspectCmd prints the inspection report for the URL argument
+func runInspectCmd(cmd *cobra.Command, args []string) {
+	if cfg == nil {
+		log.Error().Msg("Configuration not loaded.")
+		os.Exit(ExitConfig)
+	}
+
+	// Inspect the URL as routing sees it
+	rawURL := urlhandler.NormalizeURL(args[0])
+	var hops []urlhandler.RedirectHop
+	var traceErr error
+	if inspectResolve {
+		ctx, cancel := routingContext()
+		defer cancel()
+		hops, traceErr = urlhandler.TraceRedirects(ctx, rawURL, 10, 10*time.Second)
+	}
+	if err := printInspection(os.Stdout, cfg, rawURL, hops, traceErr); err != nil {
+		errorf("Error: %v", err)
+		os.Exit(1)
+	}
+}
+
+// printInspection writes the inspection report for rawURL to w. hops is the redirect
+// chain (nil if redirects were not followed) and traceErr any error following it.
+func printInspection(w io.Writer, cfg *config.Config, rawURL string, hops []urlhandler.RedirectHop, traceErr error) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse URL: %w", err)
+	}
+
+	fmt.Fprintln(w, "--- URL ---")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Scheme:\t%s\n", u.Scheme)
+	if u.User != nil {
+		_, hasPassword := u.User.Password()
+		fmt.Fprintf(tw, "User:\t%s (password: %t)\n", u.User.Username(), hasPassword)
+	}
+	fmt.Fprintf(tw, "Host:\t%s\n", u.Hostname())
+	if u.Port() != "" {
+		fmt.Fprintf(tw, "Port:\t%s\n", u.Port())
+	}
+	if u.Opaque != "" {
+		fmt.Fprintf(tw, "Opaque:\t%s\n", u.Opaque)
+	}
+	fmt.Fprintf(tw, "Path:\t%s\n", u.Path)
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range query[k] {
+			fmt.Fprintf(tw, "Query:\t%s = %s\n", k, v)
+		}
+	}
+	if u.Fragment != "" {
+		fmt.Fprintf(tw, "Fragment:\t%s\n", u.Fragment)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w, "\n--- Handling ---")
+	if u.Scheme != "" && cfg.IsPassthroughScheme(u.Scheme) {
+		fmt.Fprintf(w, "Passthrough scheme '%s': would be opened with the system handler, rules are not applied.\n", u.Scheme)
+		return nil
+	}
+	shortener := urlhandler.FindShortener(cfg, u.Hostname())
+	if shortener != nil {
+		kind := "built-in"
+		if _, _, err := cfg.FindManualShortenerByDomain(shortener.Domain); err == nil {
+			kind = "manual"
+		}
+		fmt.Fprintf(w, "Shortener: %s (%s, safelink: %t)\n", shortener.Domain, kind, shortener.IsSafelink)
+	} else {
+		fmt.Fprintln(w, "Shortener: none")
+	}
+
+	// Mirror runRootCmd: only shortener URLs are resolved before matching
+	matchURL := rawURL
+	isSafelink := false
+	if hops != nil {
+		fmt.Fprintln(w, "\n--- Redirect Chain ---")
+		for i, hop := range hops {
+			if hop.Location != "" {
+				fmt.Fprintf(w, "%d. [%d] %s\n   -> %s\n", i+1, hop.Status, hop.URL, hop.Location)
+			} else {
+				fmt.Fprintf(w, "%d. [%d] %s\n", i+1, hop.Status, hop.URL)
+			}
+		}
+		if traceErr != nil {
+			fmt.Fprintf(w, "Error: %v\n", traceErr)
+		} else if shortener == nil || (u.Scheme != "http" && u.Scheme != "https") {
+			fmt.Fprintln(w, "Not a shortener: rules are applied to the URL as given and redirects are left to the browser.")
+		} else if len(hops) > 0 {
+			matchURL = urlhandler.NormalizeURL(hops[len(hops)-1].URL)
+			isSafelink = shortener.IsSafelink
+		}
+	} else if shortener != nil {
+		fmt.Fprintln(w, "Rules would be applied to the resolved URL; use --resolve to follow the redirects.")
+	}
+	shortened := matchURL != rawURL
+	if shortened {
+		if reason := router.CheckResolutionPolicy(cfg.ResolutionPolicy, matchURL); reason != "" {
+			action := cfg.ResolutionPolicy.Action
+			if action == "" {
+				action = config.PolicyBlock
+			}
+			fmt.Fprintf(w, "Resolution policy: %s %s (action: %s)\n", redactURL(matchURL), reason, action)
+		}
+	}
+
+	evals, err := rules.EvaluateRules(cfg, matchURL)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "\n--- Rules (evaluation order) ---")
+	if len(evals) == 0 {
+		fmt.Fprintln(w, "(No user-defined rules)")
+	}
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	var winner *rules.RuleEvaluation
+	for i := range evals {
+		e := &evals[i]
+		result := "no match"
+		switch {
+		case e.Skipped:
+			result = "disabled"
+		case e.Err != nil:
+			result = "error: " + e.Err.Error()
+		case e.Matched:
+			result = "MATCH"
+			if e.ContentConditions {
+				result += " (content conditions not checked)"
+			} else if e.NetworkConditions {
+				result += " (network conditions not checked)"
+			} else if e.PluginCondition {
+				result += fmt.Sprintf(" (plugin '%s' not run)", e.Rule.Plugin)
+			} else if winner == nil {
+				winner = e
+			}
+		}
+		pattern := e.Rule.Pattern
+		if e.Rule.PatternListRef != "" {
+			pattern = strings.TrimSpace(fmt.Sprintf("%s (+list %s)", pattern, e.Rule.PatternListRef))
+		}
+		if n := len(e.Rule.Conditions); n > 0 {
+			match := e.Rule.Match
+			if match == "" {
+				match = config.MatchAll
+			}
+			pattern = strings.TrimSpace(fmt.Sprintf("%s (+%d conditions, match %s)", pattern, n, match))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%q\t%s\n", e.Rule.Name, e.Rule.Scope, pattern, e.MatchString, result)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	var matchResult rules.MatchResult
+	if profileID, ok := cfg.Overrides[matchURL]; ok {
+		fmt.Fprintf(w, "Result: override for the exact URL -> profile '%s' (rules are not used)\n", profileID)
+		winner = nil
+	} else if winner != nil && winner.Rule.Handler != "" {
+		fmt.Fprintf(w, "Result: rule '%s' -> handler %s\n", winner.Rule.Name, winner.Rule.Handler)
+	} else if winner != nil {
+		fmt.Fprintf(w, "Result: rule '%s' -> profile '%s' (incognito: %t)\n", winner.Rule.Name, winner.Rule.ProfileID,
+			launcher.ResolveIncognito(cfg, winner.Rule.ProfileID, winner.Rule.Incognito, nil))
+		if winner.Rule.Description != "" {
+			fmt.Fprintf(w, "Rule description: %s\n", winner.Rule.Description)
+		}
+		if len(winner.Rule.Tags) > 0 {
+			fmt.Fprintf(w, "Rule tags: %s\n", strings.Join(winner.Rule.Tags, ", "))
+		}
+		matchResult.LaunchOriginal = winner.Rule.LaunchOriginal
+		if matchResult.RewriteURL, err = rules.ExpandCaptures(&winner.Rule, matchURL, winner.Rule.RewriteURL); err != nil {
+			return err
+		}
+	} else if cfg.Strict.Enabled {
+		fmt.Fprintln(w, "Result: no rule matches -> blocked (strict mode)")
+		return nil
+	} else {
+		fmt.Fprintf(w, "Result: no rule matches -> default profile '%s'\n", cfg.DefaultProfileID)
+	}
+	launchURL := router.LaunchURL(matchURL, rawURL, shortened, isSafelink, matchResult)
+	safeURL, err := router.SanitizeURL(cfg, launchURL, winner != nil && winner.Rule.Handler != "")
+	var blocked *router.BlockedError
+	if errors.As(err, &blocked) {
+		fmt.Fprintf(w, "Launch URL: %s\nURL safety: blocked, the URL %s\n", redactURL(launchURL), blocked.Reason)
+		return nil
+	}
+	fmt.Fprintf(w, "Launch URL: %s\n", redactURL(safeURL))
+	if safeURL != launchURL {
+		fmt.Fprintln(w, "URL safety: embedded credentials removed")
+	}
+	if winner != nil && len(winner.Rule.ExtraArgs) > 0 {
+		args := make([]string, len(winner.Rule.ExtraArgs))
+		for i, arg := range winner.Rule.ExtraArgs {
+			args[i], _ = rules.ExpandCaptures(&winner.Rule, matchURL, arg)
+		}
+		fmt.Fprintf(w, "Extra arguments: %q\n", args)
+	}
+	if winner != nil && winner.Rule.DeepLink {
+		if link := urlhandler.DeepLinkURL(matchURL); link != "" {
+			fmt.Fprintf(w, "Deep link: %s (opened in the native app when there is a display)\n", link)
+		} else {
+			fmt.Fprintln(w, "Deep link: none (not a known meeting link, opened in the profile)")
+		}
+	}
+	return nil
+}
+
+// redactURL returns rawURL with any password replaced by "xxxxx".
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Redacted()
+}
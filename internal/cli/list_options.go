@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// defaultPager pages list output when neither RURL_PAGER nor PAGER is set. -F quits
+// at once if the output fits on one screen, and -R shows colours.
+const defaultPager = "less -FRX"
+
+// listOptions selects and orders the rows of a list command, see addListFlags.
+type listOptions struct {
+	filter func(value string) bool // Reports whether a column value matches; nil matches all rows
+	sortBy string                  // Column to sort by, empty for configuration order
+}
+
+// listColumns maps the --sort keys of a list to the column values of its items.
+type listColumns[T any] map[string]func(T) string
+
+// keys returns the --sort keys, sorted.
+func (c listColumns[T]) keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// apply returns the items with a column matching the filter, sorted by the --sort
+// column (ignoring case, keeping the configuration order of equal values).
+func (c listColumns[T]) apply(items []T, opts listOptions) []T {
+	keys := c.keys()
+	var rows []T
+	for _, item := range items {
+		if opts.filter == nil || slices.ContainsFunc(keys, func(k string) bool { return opts.filter(c[k](item)) }) {
+			rows = append(rows, item)
+		}
+	}
+	if column, ok := c[opts.sortBy]; ok {
+		slices.SortStableFunc(rows, func(a, b T) int {
+			return strings.Compare(strings.ToLower(column(a)), strings.ToLower(column(b)))
+		})
+	}
+	return rows
+}
+
+var browserColumns = listColumns[config.Browser]{
+	"id":         func(b config.Browser) string { return b.BrowserID },
+	"name":       func(b config.Browser) string { return b.Name },
+	"source":     func(b config.Browser) string { return b.InstallSource },
+	"executable": func(b config.Browser) string { return b.Executable },
+}
+
+var profileColumns = listColumns[config.Profile]{
+	"id":      func(p config.Profile) string { return p.ID },
+	"name":    func(p config.Profile) string { return p.Name },
+	"browser": func(p config.Profile) string { return p.BrowserID },
+	"dir":     func(p config.Profile) string { return p.ProfileDir },
+	"email":   func(p config.Profile) string { return p.Email },
+}
+
+var ruleColumns = listColumns[config.Rule]{
+	"id":          func(r config.Rule) string { return r.ID },
+	"name":        func(r config.Rule) string { return r.Name },
+	"pattern":     func(r config.Rule) string { return r.Pattern },
+	"scope":       func(r config.Rule) string { return string(r.Scope) },
+	"profile":     func(r config.Rule) string { return r.ProfileID },
+	"tags":        func(r config.Rule) string { return strings.Join(r.Tags, ", ") },
+	"description": func(r config.Rule) string { return r.Description },
+}
+
+// addListFlags adds the --filter and --no-pager flags of list commands, and --sort by
+// one of sortKeys if any are given.
+func addListFlags(cmd *cobra.Command, sortKeys []string) {
+	cmd.Flags().String("filter", "", "Only list rows with a column containing this text (ignoring case), or matching /regex/")
+	cmd.Flags().Bool("no-pager", false, "Do not page the output")
+	if len(sortKeys) == 0 {
+		return
+	}
+	cmd.Flags().String("sort", "", fmt.Sprintf("Sort by column (%s) instead of configuration order", strings.Join(sortKeys, ", ")))
+	_ = cmd.RegisterFlagCompletionFunc("sort", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return sortKeys, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// listOptionsFromFlags returns the options given by the flags of addListFlags.
+func listOptionsFromFlags(cmd *cobra.Command, sortKeys []string) (listOptions, error) {
+	var opts listOptions
+	filter, _ := cmd.Flags().GetString("filter")
+	var err error
+	if opts.filter, err = parseListFilter(filter); err != nil {
+		return opts, err
+	}
+	if cmd.Flags().Lookup("sort") != nil {
+		opts.sortBy, _ = cmd.Flags().GetString("sort")
+		opts.sortBy = strings.ToLower(opts.sortBy)
+	}
+	if opts.sortBy != "" && !slices.Contains(sortKeys, opts.sortBy) {
+		return opts, fmt.Errorf("cannot sort by '%s' (expected one of: %s)", opts.sortBy, strings.Join(sortKeys, ", "))
+	}
+	return opts, nil
+}
+
+// parseListFilter returns the matcher for a --filter value: a regular expression
+// between slashes, or text contained in the value, ignoring case. It returns nil for an
+// empty filter.
+func parseListFilter(filter string) (func(string) bool, error) {
+	if filter == "" {
+		return nil, nil
+	}
+	if len(filter) > 1 && strings.HasPrefix(filter, "/") && strings.HasSuffix(filter, "/") {
+		re, err := regexp.Compile(filter[1 : len(filter)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+		return re.MatchString, nil
+	}
+	filter = strings.ToLower(filter)
+	return func(value string) bool { return strings.Contains(strings.ToLower(value), filter) }, nil
+}
+
+// startPager sends stdout through the user's pager (RURL_PAGER, PAGER, or less) if it
+// is a terminal, unless --no-pager is given, and returns a function that waits for the
+// pager to exit and restores stdout. Output is left as is if no pager can be started.
+func startPager(cmd *cobra.Command) (stop func()) {
+	noPager, _ := cmd.Flags().GetBool("no-pager")
+	if noPager || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return func() {}
+	}
+	pager, set := os.LookupEnv("RURL_PAGER")
+	if !set {
+		pager, set = os.LookupEnv("PAGER")
+	}
+	if !set && runtime.GOOS != "windows" {
+		pager = defaultPager
+	}
+	args := strings.Fields(pager)
+	if len(args) == 0 || args[0] == "cat" {
+		return func() {}
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		log.Debug().Err(err).Str("pager", pager).Msg("Pager not found, not paging output")
+		return func() {}
+	}
+
+	// The print helpers write to os.Stdout, so swap it for a pipe into the pager.
+	// Writes fail once the user quits the pager, and the rest is dropped.
+	r, w, err := os.Pipe()
+	if err != nil {
+		return func() {}
+	}
+	pagerCmd := exec.Command(path, args[1:]...)
+	pagerCmd.Stdin, pagerCmd.Stdout, pagerCmd.Stderr = r, os.Stdout, os.Stderr
+	err = pagerCmd.Start()
+	r.Close()
+	if err != nil {
+		w.Close()
+		log.Debug().Err(err).Str("pager", pager).Msg("Failed to start pager, not paging output")
+		return func() {}
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	return func() {
+		os.Stdout = stdout
+		w.Close()
+		_ = pagerCmd.Wait()
+	}
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseListFilter(t *testing.T) {
+	match, err := parseListFilter("")
+	require.NoError(t, err)
+	assert.Nil(t, match)
+
+	match, err = parseListFilter("Work")
+	require.NoError(t, err)
+	assert.True(t, match("chrome-work-1"))
+	assert.False(t, match("personal"))
+
+	match, err = parseListFilter("/^chrome-.*-1$/")
+	require.NoError(t, err)
+	assert.True(t, match("chrome-work-1"))
+	assert.False(t, match("Chrome-work-1"))
+
+	_, err = parseListFilter("/(/")
+	assert.Error(t, err)
+}
+
+func TestListColumnsApply(t *testing.T) {
+	profiles := []config.Profile{
+		{ID: "firefox-work", Name: "work", BrowserID: "firefox"},
+		{ID: "chrome-default", Name: "Default", BrowserID: "chrome"},
+		{ID: "chrome-work", Name: "Work", BrowserID: "chrome", Email: "me@corp.example"},
+	}
+	ids := func(ps []config.Profile) []string {
+		var out []string
+		for _, p := range ps {
+			out = append(out, p.ID)
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"firefox-work", "chrome-default", "chrome-work"}, ids(profileColumns.apply(profiles, listOptions{})))
+
+	filter, err := parseListFilter("corp")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"chrome-work"}, ids(profileColumns.apply(profiles, listOptions{filter: filter})))
+
+	// Sorting ignores case and keeps the configuration order of equal values
+	assert.Equal(t, []string{"chrome-default", "firefox-work", "chrome-work"}, ids(profileColumns.apply(profiles, listOptions{sortBy: "name"})))
+	assert.Equal(t, []string{"chrome-default", "chrome-work", "firefox-work"}, ids(profileColumns.apply(profiles, listOptions{sortBy: "browser"})))
+}
+
+func TestListOptionsFromFlags(t *testing.T) {
+	cmd := &cobra.Command{Use: "list"}
+	addListFlags(cmd, ruleColumns.keys())
+	require.NoError(t, cmd.Flags().Parse([]string{"--filter", "/^docs/", "--sort", "Profile"}))
+	opts, err := listOptionsFromFlags(cmd, ruleColumns.keys())
+	require.NoError(t, err)
+	assert.Equal(t, "profile", opts.sortBy)
+	assert.True(t, opts.filter("docs.example.com"))
+
+	require.NoError(t, cmd.Flags().Set("sort", "color"))
+	_, err = listOptionsFromFlags(cmd, ruleColumns.keys())
+	assert.ErrorContains(t, err, "cannot sort by 'color'")
+
+	// Lists without columns to sort by only filter
+	cmd = &cobra.Command{Use: "list"}
+	addListFlags(cmd, nil)
+	assert.Nil(t, cmd.Flags().Lookup("sort"))
+	_, err = listOptionsFromFlags(cmd, nil)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,281 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/launcher"
+	"github.com/jmylchreest/rurl/internal/nativehost"
+	"github.com/jmylchreest/rurl/internal/router"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	nativeHostBrowsers          []string
+	nativeHostExtensionIDs      []string
+	nativeHostUninstallBrowsers []string
+)
+
+// Native messaging actions, see nativeRequest.Action.
+const (
+	nativeActionRoute = "route" // Decide where the URL would be opened, without opening it
+	nativeActionOpen  = "open"  // Open the URL as 'rurl <url>' would
+)
+
+// nativeRequest is a message from the companion extension.
+type nativeRequest struct {
+	ID     json.RawMessage `json:"id,omitempty"` // Echoed in the response, to match it to the request
+	Action string          `json:"action"`       // One of the nativeAction* actions
+	URL    string          `json:"url"`
+}
+
+// nativeResponse answers a nativeRequest.
+type nativeResponse struct {
+	ID       json.RawMessage `json:"id,omitempty"`
+	OK       bool            `json:"ok"`
+	Error    string          `json:"error,omitempty"`
+	Decision *nativeDecision `json:"decision,omitempty"` // Set for route requests
+}
+
+// nativeDecision describes where a URL is routed.
+type nativeDecision struct {
+	URL             string `json:"url"`
+	MatchedURL      string `json:"matched_url"` // URL the rules were matched against
+	LaunchURL       string `json:"launch_url"`
+	Passthrough     bool   `json:"passthrough,omitempty"` // Handed to the system's default handler
+	RuleID          string `json:"rule_id,omitempty"`     // Empty if the default profile or an override is used
+	RuleName        string `json:"rule_name,omitempty"`
+	Override        bool   `json:"override,omitempty"` // The profile is given by an override for the exact URL
+	ProfileID       string `json:"profile_id,omitempty"`
+	BrowserID       string `json:"browser_id,omitempty"`
+	Incognito       bool   `json:"incognito,omitempty"`
+	AppID           string `json:"app_id,omitempty"`
+	DeepLinkURL     string `json:"deep_link_url,omitempty"`
+	Handler         string `json:"handler,omitempty"`          // Command the URL is passed to instead of a browser
+	PolicyViolation string `json:"policy_violation,omitempty"` // Why opening the URL would be refused
+}
+
+// addNativeHostCommand adds the native-host command and its subcommands to the root
+// command
+func addNativeHostCommand() {
+	nativeHostCmd := &cobra.Command{
+		Use:   "native-host",
+		Short: "Exchange links with the companion browser extension (native messaging)",
+		Long: `Runs rurl as a native messaging host: the browser starts it when the companion
+extension connects, and they exchange length-prefixed JSON messages over stdin and
+stdout. Requests look like {"id": 1, "action": "route", "url": "https://..."}:
+
+  route  replies with where the URL would be opened, without opening it
+  open   opens the URL as 'rurl <url>' would, and replies whether it was launched
+
+Replies echo the request's id, with "ok" and either "error" or (for route) "decision".
+Use 'rurl native-host install' to register rurl with your browsers; the arguments the
+browser passes (the extension's origin) are ignored.`,
+		Args: cobra.ArbitraryArgs,
+		Run:  runNativeHostCmd,
+	}
+
+	installCmd := &cobra.Command{
+		Use:   "install",
+		Short: "Register rurl as a native messaging host with browsers",
+		Long: fmt.Sprintf(`Writes the native messaging host manifest allowing the given extensions to
+connect to rurl, for each browser given (%s), and the script the
+manifest starts, next to the configuration file. On Windows the manifest is also
+registered in the registry.
+
+Chromium-based browsers identify extensions by their 32-letter ID, Firefox by the ID
+in the extension's manifest (e.g. rurl@example.org).`, strings.Join(nativehost.Browsers(), ", ")),
+		Args: cobra.NoArgs,
+		RunE: runNativeHostInstallCmd,
+	}
+	installCmd.Flags().StringSliceVar(&nativeHostBrowsers, "browser", nil, "Browsers to register with (repeatable)")
+	installCmd.Flags().StringSliceVar(&nativeHostExtensionIDs, "extension-id", nil, "IDs of the extensions allowed to connect (repeatable)")
+	_ = installCmd.MarkFlagRequired("browser")
+	_ = installCmd.MarkFlagRequired("extension-id")
+	_ = installCmd.RegisterFlagCompletionFunc("browser", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return nativehost.Browsers(), cobra.ShellCompDirectiveNoFileComp
+	})
+
+	uninstallCmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove rurl's native messaging host registration from browsers",
+		Args:  cobra.NoArgs,
+		RunE:  runNativeHostUninstallCmd,
+	}
+	uninstallCmd.Flags().StringSliceVar(&nativeHostUninstallBrowsers, "browser", nativehost.Browsers(), "Browsers to unregister from (repeatable)")
+
+	nativeHostCmd.AddCommand(installCmd, uninstallCmd)
+	rootCmd.AddCommand(nativeHostCmd)
+}
+
+// runNativeHostCmd answers the extension's messages until the browser closes stdin
+func runNativeHostCmd(cmd *cobra.Command, args []string) {
+	if cfg == nil {
+		log.Error().Msg("Configuration not loaded.")
+		os.Exit(ExitConfig)
+	}
+
+	// Stdout carries the messages, so anything else printed (e.g. by a headless
+	// fallback) goes to stderr, which the browser logs
+	out := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = out }()
+
+	if err := serveNativeMessages(context.Background(), os.Stdin, out); err != nil {
+		log.Error().Err(err).Msg("Native messaging failed")
+		os.Exit(1)
+	}
+}
+
+// serveNativeMessages answers requests read from r on w, one at a time, until r ends.
+func serveNativeMessages(ctx context.Context, r io.Reader, w io.Writer) error {
+	for {
+		var req nativeRequest
+		if err := nativehost.ReadMessage(r, &req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if err := nativehost.WriteMessage(w, handleNativeRequest(ctx, req)); err != nil {
+			return err
+		}
+	}
+}
+
+// handleNativeRequest carries out req.
+func handleNativeRequest(ctx context.Context, req nativeRequest) nativeResponse {
+	resp := nativeResponse{ID: req.ID}
+	if req.URL == "" {
+		resp.Error = "missing url"
+		return resp
+	}
+
+	if routeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, routeTimeout, fmt.Errorf("timed out after %s", routeTimeout))
+		defer cancel()
+	}
+	switch req.Action {
+	case nativeActionRoute:
+		decision, err := nativeRoute(ctx, req.URL)
+		if err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		resp.Decision = &decision
+	case nativeActionOpen:
+		// There is no terminal to ask about URLs the resolution policy would prompt for
+		if err := openURL(ctx, req.URL, func(string) bool { return false }); err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+	default:
+		resp.Error = fmt.Sprintf("unknown action '%s' (expected %s or %s)", req.Action, nativeActionRoute, nativeActionOpen)
+		return resp
+	}
+	resp.OK = true
+	return resp
+}
+
+// nativeRoute decides where rawURL would be opened, without opening it.
+func nativeRoute(ctx context.Context, rawURL string) (nativeDecision, error) {
+	if u, err := url.Parse(rawURL); err == nil && u.Scheme != "" && cfg.IsPassthroughScheme(u.Scheme) {
+		return nativeDecision{URL: rawURL, MatchedURL: rawURL, LaunchURL: rawURL, Passthrough: true}, nil
+	}
+	route, err := router.Route(ctx, cfg, rawURL)
+	if err != nil {
+		return nativeDecision{}, err
+	}
+	d := nativeDecision{
+		URL:             rawURL,
+		MatchedURL:      route.MatchURL,
+		LaunchURL:       route.LaunchURL,
+		ProfileID:       route.Match.ProfileID,
+		Incognito:       launcher.ResolveIncognito(cfg, route.Match.ProfileID, route.Match.Incognito, nil),
+		AppID:           route.Match.PWAAppID,
+		DeepLinkURL:     route.Match.DeepLinkURL,
+		Handler:         route.Match.Handler,
+		Override:        route.Match.Override,
+		PolicyViolation: route.PolicyViolation,
+	}
+	if route.Match.Rule != nil {
+		d.RuleID = route.Match.Rule.ID
+		d.RuleName = route.Match.Rule.Name
+	}
+	if browser, err := profileBrowser(d.ProfileID); err == nil {
+		d.BrowserID = browser.BrowserID
+	}
+	return d, nil
+}
+
+// nativeHostWrapperPath returns the script browsers start as the native messaging host.
+func nativeHostWrapperPath() (string, error) {
+	dir := filepath.Dir(cfgFile)
+	if cfgFile == "" {
+		var err error
+		if dir, err = config.GetConfigDir(); err != nil {
+			return "", err
+		}
+	}
+	name := "native-host.sh"
+	if runtime.GOOS == "windows" {
+		name = "native-host.bat"
+	}
+	return filepath.Join(dir, name), nil
+}
+
+func runNativeHostInstallCmd(cmd *cobra.Command, args []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("cannot determine the rurl executable: %w", err)
+	}
+	command := []string{executable}
+	if cfgFile != "" {
+		path, err := filepath.Abs(cfgFile)
+		if err != nil {
+			return err
+		}
+		command = append(command, "--config", path)
+	}
+	command = append(command, "native-host")
+
+	wrapperPath, err := nativeHostWrapperPath()
+	if err != nil {
+		return err
+	}
+	for _, browser := range nativeHostBrowsers {
+		written, err := nativehost.Install(browser, nativeHostExtensionIDs, wrapperPath, command)
+		if err != nil {
+			return fmt.Errorf("%s: %w", browser, err)
+		}
+		statusf("Registered with %s:", browser)
+		for _, path := range written {
+			statusf("  %s", path)
+		}
+	}
+	return nil
+}
+
+func runNativeHostUninstallCmd(cmd *cobra.Command, args []string) error {
+	for _, browser := range nativeHostUninstallBrowsers {
+		removed, err := nativehost.Uninstall(browser)
+		if err != nil {
+			return fmt.Errorf("%s: %w", browser, err)
+		}
+		for _, path := range removed {
+			statusf("Removed %s", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/nativehost"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeNativeMessages(t *testing.T) {
+	originalCfg, originalLauncher, originalDisplay := cfg, appLauncher, hasDisplay
+	defer func() { cfg, appLauncher, hasDisplay = originalCfg, originalLauncher, originalDisplay }()
+	hasDisplay = func() bool { return true }
+
+	rec := &recordingLauncher{}
+	appLauncher = rec
+	cfg = &config.Config{
+		DefaultProfileID: "personal",
+		Browsers:         []config.Browser{{Name: "Test Browser", BrowserID: "test", Executable: "/bin/echo"}},
+		Profiles: []config.Profile{
+			{ID: "personal", Name: "Personal", BrowserID: "test"},
+			{ID: "work", Name: "Work", BrowserID: "test"},
+		},
+		Rules: []config.Rule{
+			{ID: "work", Name: "Work", Pattern: `^work\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "work", Incognito: true},
+		},
+		Behavior: config.Behavior{PassthroughSchemes: []string{"mailto"}},
+	}
+
+	var in bytes.Buffer
+	for _, req := range []nativeRequest{
+		{ID: json.RawMessage(`1`), Action: nativeActionRoute, URL: "https://work.example.com/a"},
+		{ID: json.RawMessage(`"two"`), Action: nativeActionOpen, URL: "https://other.example.com/"},
+		{Action: nativeActionRoute, URL: "mailto:me@example.com"},
+		{Action: "delete", URL: "https://example.com/"},
+		{Action: nativeActionOpen},
+	} {
+		require.NoError(t, nativehost.WriteMessage(&in, req))
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, serveNativeMessages(context.Background(), &in, &out))
+
+	var responses []nativeResponse
+	for {
+		var resp nativeResponse
+		if err := nativehost.ReadMessage(&out, &resp); err == io.EOF {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+		responses = append(responses, resp)
+	}
+	require.Len(t, responses, 5)
+
+	// Route decides without launching
+	assert.JSONEq(t, `1`, string(responses[0].ID))
+	assert.True(t, responses[0].OK)
+	if assert.NotNil(t, responses[0].Decision) {
+		assert.Equal(t, "work", responses[0].Decision.RuleID)
+		assert.Equal(t, "work", responses[0].Decision.ProfileID)
+		assert.Equal(t, "test", responses[0].Decision.BrowserID)
+		assert.True(t, responses[0].Decision.Incognito)
+	}
+
+	// Open launches
+	assert.JSONEq(t, `"two"`, string(responses[1].ID))
+	assert.True(t, responses[1].OK)
+	assert.Nil(t, responses[1].Decision)
+	assert.Equal(t, []string{"https://other.example.com/"}, rec.urls)
+
+	assert.True(t, responses[2].Decision.Passthrough)
+	assert.Contains(t, responses[3].Error, "unknown action")
+	assert.Equal(t, "missing url", responses[4].Error)
+	assert.False(t, responses[4].OK)
+
+	// A malformed message ends the session with an error
+	assert.Error(t, serveNativeMessages(context.Background(), bytes.NewReader([]byte{2, 0, 0, 0, '{', 'x'}), io.Discard))
+}
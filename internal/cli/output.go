@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+)
+
+// User-facing messages of the commands go through the functions below rather than fmt,
+// so that they are routed consistently: results a command was asked for (lists,
+// reports, printed URLs) go to stdout with fmt as before, status messages go to stdout
+// unless --quiet is given, and errors and warnings always go to stderr. Message formats
+// are passed through localize first.
+
+// quietEnv turns on --quiet when set (to anything but "" or "0"), e.g. for the
+// invocations made by the operating system.
+const quietEnv = "RURL_QUIET"
+
+var (
+	// quiet suppresses status messages (--quiet, or the RURL_QUIET environment variable).
+	quiet bool
+
+	// localize returns the message format to print for format, the English original by
+	// default. Front-ends shipping translations may replace it.
+	localize = func(format string) string { return format }
+)
+
+// statusf prints a status message, such as what a command did, on its own line.
+func statusf(format string, args ...any) {
+	if quiet {
+		return
+	}
+	fmt.Fprintf(os.Stdout, localize(format)+"\n", args...)
+}
+
+// errorf prints an error or warning on its own line to stderr, even when quiet.
+func errorf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, localize(format)+"\n", args...)
+}
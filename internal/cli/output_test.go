@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusfQuiet(t *testing.T) {
+	originalQuiet, originalLocalize := quiet, localize
+	defer func() { quiet, localize = originalQuiet, originalLocalize }()
+
+	quiet = false
+	assert.Equal(t, "Rule 'docs' deleted.\n", captureStdout(t, func() { statusf("Rule '%s' deleted.", "docs") }))
+
+	localize = func(format string) string { return strings.Replace(format, "deleted", "gelöscht", 1) }
+	assert.Equal(t, "Rule 'docs' gelöscht.\n", captureStdout(t, func() { statusf("Rule '%s' deleted.", "docs") }))
+
+	quiet = true
+	assert.Empty(t, captureStdout(t, func() { statusf("Rule '%s' deleted.", "docs") }))
+}
+
+func TestErrorfIgnoresQuiet(t *testing.T) {
+	originalQuiet := quiet
+	defer func() { quiet = originalQuiet }()
+	quiet = true
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	originalStderr := os.Stderr
+	os.Stderr = w
+	stdout := captureStdout(t, func() { errorf("Error: %v", "no such rule") })
+	os.Stderr = originalStderr
+	w.Close()
+	stderr, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	assert.Empty(t, stdout)
+	assert.Equal(t, "Error: no such rule\n", string(stderr))
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/registration"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	purgeData bool
+	purgeYes  bool
+)
+
+// addPurgeCommand adds the purge command to the root command
+func addPurgeCommand() {
+	purgeCmd := &cobra.Command{
+		Use:   "purge",
+		Short: "Unregister rurl and remove its system integration",
+		Long: `Unregisters rurl as the default browser. On Linux this removes the rurl.desktop
+entry and its mime associations; on macOS and Windows you are told how to choose
+another default browser. On every platform the native messaging host manifests
+written by 'rurl native-host install' are removed, along with their registry keys
+on Windows.
+
+Use --data to also delete the configuration and cache (history, shortener
+candidates, downloaded URL lists) directories. With --config, the configuration
+file, its part files (machine, rules) and the native messaging host script next to
+it are deleted instead of the default configuration directory. Everything is listed
+before anything is deleted.`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{noConfigAnnotation: ""},
+		Run:         runPurgeCmd,
+	}
+	purgeCmd.Flags().BoolVar(&purgeData, "data", false, "Also delete the configuration and cache directories")
+	purgeCmd.Flags().BoolVarP(&purgeYes, "yes", "y", false, "Do not prompt for confirmation")
+	rootCmd.AddCommand(purgeCmd)
+}
+
+// runPurgeCmd unregisters rurl and optionally removes its data directories
+func runPurgeCmd(cmd *cobra.Command, args []string) {
+	var dataPaths []string
+	if purgeData {
+		dataPaths = purgeDataPaths()
+	}
+
+	pending, err := registration.Pending()
+	if err != nil {
+		errorf("Error: %v", err)
+		os.Exit(1)
+	}
+	pending = append(pending, dataPaths...)
+
+	if len(pending) == 0 {
+		statusf("Nothing to remove on %s.", runtime.GOOS)
+	} else {
+		fmt.Println("The following will be removed:")
+		for _, p := range pending {
+			fmt.Printf("  - %s\n", p)
+		}
+	}
+
+	if len(pending) > 0 && !purgeYes && !promptYesNo("Continue?", false) {
+		statusf("Purge cancelled.")
+		return
+	}
+
+	res, err := registration.Unregister()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to unregister rurl")
+		errorf("Error unregistering rurl: %v", err)
+		os.Exit(1)
+	}
+
+	if len(dataPaths) > 0 {
+		dataRes, err := registration.RemoveDataPaths(dataPaths)
+		res.Removed = append(res.Removed, dataRes.Removed...)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to remove data directories")
+			errorf("Error removing data: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	for _, r := range res.Removed {
+		statusf("Removed: %s", r)
+	}
+	for _, m := range res.Messages {
+		fmt.Printf("Note: %s\n", m)
+	}
+	fmt.Println("Purge complete.")
+}
+
+// purgeDataPaths returns the data purge --data deletes: the configuration directory,
+// or for a configuration file given with --config, the files rurl keeps in its
+// directory, which may hold unrelated files. The cache directory is included either
+// way.
+func purgeDataPaths() []string {
+	if cfgFile == "" {
+		configDir, err := config.GetConfigDir()
+		if err != nil {
+			log.Warn().Err(err).Msg("Could not determine config directory")
+		}
+		return registration.DataPaths(configDir)
+	}
+	paths := []string{cfgFile}
+	for _, part := range config.ConfigParts {
+		paths = append(paths, config.PartFile(cfgFile, part.Name))
+	}
+	if wrapperPath, err := nativeHostWrapperPath(); err == nil {
+		paths = append(paths, wrapperPath)
+	}
+	return registration.DataPaths(paths...)
+}
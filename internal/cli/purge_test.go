@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeDataPathsWithConfigFile(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("cache directory is only configurable through XDG_CACHE_HOME on Unix")
+	}
+	originalCfgFile := cfgFile
+	defer func() { cfgFile = originalCfgFile }()
+
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(tmpDir, "cache"))
+	cacheDir := filepath.Join(tmpDir, "cache", "rurl")
+	require.NoError(t, os.MkdirAll(filepath.Join(cacheDir, "lists"), 0750))
+
+	dir := filepath.Join(tmpDir, "dotfiles")
+	require.NoError(t, os.MkdirAll(dir, 0750))
+	for _, name := range []string{"rurl.toml", "machine.toml", "rules.toml", "native-host.sh", "unrelated.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0600))
+	}
+	cfgFile = filepath.Join(dir, "rurl.toml")
+
+	// Only rurl's files are removed from the directory of the configuration file
+	assert.Equal(t, []string{
+		cfgFile,
+		filepath.Join(dir, "machine.toml"),
+		filepath.Join(dir, "rules.toml"),
+		filepath.Join(dir, "native-host.sh"),
+		cacheDir,
+	}, purgeDataPaths())
+}
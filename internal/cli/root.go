@@ -1,15 +1,29 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/jmylchreest/rurl/internal/candidates"
 	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/history"
 	"github.com/jmylchreest/rurl/internal/launcher"
 	"github.com/jmylchreest/rurl/internal/logging"
+	"github.com/jmylchreest/rurl/internal/metrics"
+	"github.com/jmylchreest/rurl/internal/router"
 	"github.com/jmylchreest/rurl/internal/rules"
 	"github.com/jmylchreest/rurl/internal/urlhandler"
+	"github.com/jmylchreest/rurl/internal/usage"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
@@ -17,18 +31,69 @@ import (
 var (
 	cfgFile     string
 	logLevelStr string
-	cfg         *config.Config
-	detectSave  bool
-	rootCmd     *cobra.Command
+	// verbose logs at info level unless --log-level is given (--verbose).
+	verbose      bool
+	routeTimeout time.Duration
+	cfg          *config.Config
+	detectSave   bool
+	// incognitoFlag is the value of the --incognito flag of the root command, nil if
+	// it is not given.
+	incognitoFlag *bool
+	// broadcastFlag lists the profiles given with --broadcast, which the URL is opened in
+	// instead of the routed profile.
+	broadcastFlag []string
+	// dryRun prints what would be launched instead of launching it (--dry-run, or the
+	// RURL_DRY_RUN environment variable).
+	dryRun  bool
+	rootCmd *cobra.Command
+
+	// appLauncher opens the routed URL. Tests and alternative front-ends may replace it.
+	appLauncher launcher.Launcher = launcher.NewExecLauncher()
+
+	// systemOpen hands passthrough-scheme URLs and deep links to the OS default handler.
+	// Tests may replace it.
+	systemOpen = launcher.OpenWithSystem
+
+	// portalOpen opens URLs whose browser could not be started with the desktop's
+	// default handler. Tests may replace it.
+	portalOpen = launcher.OpenWithPortal
+
+	// hasDisplay reports whether GUI browsers can be launched. Tests may replace it.
+	hasDisplay = launcher.HasDisplay
+
+	// runHandler runs the handler command of a rule. Tests may replace it.
+	runHandler = launcher.RunHandler
+
+	// notify shows a desktop notification. Tests may replace it.
+	notify = launcher.Notify
+
+	// confirmLaunch asks whether to launch a URL the resolution policy does not allow.
+	// Tests may replace it.
+	confirmLaunch = confirmInTerminal
+)
+
+const (
+	// noConfigAnnotation marks commands that run without loading the configuration, so
+	// they neither read nor create it (see needsConfig).
+	noConfigAnnotation = "rurl:no-config"
+
+	// readOnlyConfigAnnotation marks commands that only read the configuration. They
+	// use the default configuration in memory rather than creating its file.
+	readOnlyConfigAnnotation = "rurl:read-only-config"
+
+	// dryRunEnv turns on --dry-run when set (to anything but "" or "0"), including for
+	// the invocations made by the operating system.
+	dryRunEnv = "RURL_DRY_RUN"
 )
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// main exits with ExitCode of the error it returns.
 func Execute() error {
 	return rootCmd.Execute()
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
+	cobra.OnInitialize(initLogging)
 
 	rootCmd = &cobra.Command{
 		Use:   "rurl [URL]",
@@ -38,15 +103,57 @@ func init() {
 When a URL is provided as an argument or passed via OS default browser mechanism,
 it routes the URL to the correct browser profile based on rules.`,
 		Args: cobra.MaximumNArgs(1), // Accepts zero or one argument (the URL)
-		Run:  runRootCmd,
+		// The configuration is only loaded for commands that use it
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if v := os.Getenv(dryRunEnv); v != "" && v != "0" {
+				dryRun = true
+			}
+			if v := os.Getenv(quietEnv); v != "" && v != "0" {
+				quiet = true
+			}
+			if needsConfig(cmd, args) {
+				initConfig(cmd)
+			}
+		},
+		Run: runRootCmd,
 	}
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", fmt.Sprintf("config file (default is %s)", DefaultConfigPath()))
 	rootCmd.PersistentFlags().StringVarP(&logLevelStr, "log-level", "l", "error", "set log level (trace, debug, info, warn, error, fatal, panic)")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "log at info level (same as --log-level info)")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "do not print status messages, only results and errors (also set by "+quietEnv+"=1)")
+	rootCmd.PersistentFlags().DurationVar(&routeTimeout, "timeout", 0, "bound URL resolution, plugins and hooks (e.g. 5s; 0 for no limit)")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "route URLs as usual (resolving them over the network) but print the command that would open them instead of running it, hooks or history (also set by "+dryRunEnv+"=1)")
+	rootCmd.Flags().Bool("incognito", false, "open the URL in a private window, or not with --incognito=false, whatever the rule says (profiles with AlwaysIncognito always open privately)")
+	rootCmd.Flags().StringSlice("broadcast", nil, "open the URL in each of these profiles (comma-separated IDs) instead of the routed one, e.g. to compare accounts")
 
 	// Add config command and its subcommands
 	addConfigCommands()
 
+	// Add purge command
+	addPurgeCommand()
+
+	// Add self-update command
+	addSelfUpdateCommand()
+
+	// Add tui command
+	addTUICommand()
+
+	// Add inspect command
+	addInspectCommand()
+
+	// Add serve command
+	addServeCommand()
+
+	// Add native messaging host command
+	addNativeHostCommand()
+
+	// Add hotkeys and clipboard commands
+	addHotkeysCommand()
+
+	// Add hidden bench command
+	addBenchCommand()
+
 	// Add completion command
 	rootCmd.AddCommand(&cobra.Command{
 		Use:   "completion [bash|zsh|fish|powershell]",
@@ -90,6 +197,7 @@ PowerShell:
   # and source this file from your PowerShell profile.
 `,
 		DisableFlagsInUseLine: true,
+		Annotations:           map[string]string{noConfigAnnotation: ""},
 		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
 		Args:                  cobra.ExactValidArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
@@ -107,87 +215,661 @@ PowerShell:
 	})
 }
 
-// initConfig reads in config file and ENV variables if set.
-func initConfig() {
-	var err error
+// initLogging initializes logging at the level given by the flags, for all commands.
+func initLogging() {
+	level := logLevelStr
+	if verbose && !rootCmd.PersistentFlags().Changed("log-level") {
+		level = "info"
+	}
+	logging.InitLogging(level)
+}
 
-	// Initialize logging first, using the flag value
-	// Note: Log level might be limited until config is fully loaded if config loading itself logs
-	logging.InitLogging(logLevelStr)
+// needsConfig reports whether cmd uses the configuration: all commands except help,
+// shell completion (completers load it themselves), those annotated with
+// noConfigAnnotation, and rurl without a URL.
+func needsConfig(cmd *cobra.Command, args []string) bool {
+	switch cmd.Name() {
+	case "help", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return false
+	}
+	if _, ok := cmd.Annotations[noConfigAnnotation]; ok {
+		return false
+	}
+	return cmd.HasParent() || len(args) > 0
+}
 
-	cfg, err = config.LoadConfig(cfgFile)
+// initConfig reads in config file and ENV variables if set, for cmd.
+func initConfig(cmd *cobra.Command) {
+	load := config.LoadConfig
+	if _, ok := cmd.Annotations[readOnlyConfigAnnotation]; ok {
+		load = config.LoadConfigOrDefault
+	}
+	loaded, err := load(cfgFile)
 	if err != nil {
 		// Use Printf directly as logger might not be fully ready or might filter this out
-		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
-		os.Exit(1)
+		errorf("Error loading configuration: %v", err)
+		os.Exit(ExitConfig)
 	}
+	cfgMu.Lock()
+	cfg = loaded
+	cfgMu.Unlock()
 	log.Debug().Msg("Configuration loaded successfully")
 
+	// Watch launches for browsers exiting at once, if enabled
+	if el, ok := appLauncher.(*launcher.ExecLauncher); ok && cfg.LaunchMonitoring.Enabled {
+		el.MonitorWait = defaultLaunchMonitorWait
+		if cfg.LaunchMonitoring.WaitMillis > 0 {
+			el.MonitorWait = time.Duration(cfg.LaunchMonitoring.WaitMillis) * time.Millisecond
+		}
+	}
+
 	// Re-initialize logging in case config file specifies a different level?
 	// For now, command-line flag takes precedence.
 	// If config file should override, logic needs adjustment here.
 	// logging.InitLogging(cfg.LogLevel, debug) // Example if config has level
 }
 
-// runRootCmd handles the main URL routing functionality
-func runRootCmd(cmd *cobra.Command, args []string) {
+// cfgMu guards cfg for the commands reading and saving it through currentConfig and
+// saveConfig while others run (e.g. the requests handled by 'rurl serve').
+var cfgMu sync.RWMutex
+
+// currentConfig returns the configuration loaded for the running command from --config
+// (or the default location), which every command shares instead of loading its own.
+func currentConfig() (*config.Config, error) {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
 	if cfg == nil {
-		log.Fatal().Msg("Configuration not loaded (should not happen)")
+		return nil, withExitCode(ExitConfig, errors.New("configuration not loaded"))
 	}
+	return cfg, nil
+}
 
+// saveConfig saves c to the file given with --config (or the default location) and
+// makes it the current configuration.
+func saveConfig(c *config.Config) error {
+	cfgMu.Lock()
+	defer cfgMu.Unlock()
+	if err := config.SaveConfig(c, cfgFile); err != nil {
+		return err
+	}
+	cfg = c
+	return nil
+}
+
+// runRootCmd handles the main URL routing functionality
+func runRootCmd(cmd *cobra.Command, args []string) {
 	if len(args) == 0 {
 		cmd.Help()
 		os.Exit(0)
 	}
 
-	urlInput := args[0]
-	log.Info().Str("url", urlInput).Msg("Processing URL")
+	if cfg == nil {
+		log.Fatal().Msg("Configuration not loaded (should not happen)")
+	}
+	if cmd.Flags().Changed("incognito") {
+		incognito, _ := cmd.Flags().GetBool("incognito")
+		incognitoFlag = &incognito
+	}
+	broadcastFlag, _ = cmd.Flags().GetStringSlice("broadcast")
 
-	// 1. Process URL (Resolve shorteners, check for safelinks)
-	resolvedURL, originalURL, isSafelink, err := urlhandler.ProcessURL(cfg, urlInput)
-	if err != nil {
-		log.Error().Err(err).Str("input_url", urlInput).Msg("Failed to process URL")
-		fmt.Fprintf(os.Stderr, "Error processing URL: %v\n", err)
-		os.Exit(1)
+	ctx, cancel := routingContext()
+	defer cancel()
+
+	if err := openURL(ctx, args[0], confirmLaunch); err != nil {
+		errorf("Error: %v", err)
+		os.Exit(ExitCode(err))
 	}
+}
 
-	// Determine which URL to actually launch
-	urlToLaunch := resolvedURL
-	if isSafelink {
-		urlToLaunch = originalURL
-		log.Info().Str("original_url", originalURL).Msg("Safelink detected, launching original URL after rule matching")
+// openURL routes urlInput through the normal pipeline (passthrough schemes, resolution,
+// rules, launch hooks and history) and launches it. confirm asks whether to launch a
+// URL the resolution policy does not allow.
+func openURL(ctx context.Context, urlInput string, confirm func(question string) bool) error {
+	log.Info().Str("url", urlInput).Msg("Processing URL")
+
+	// Hand configured schemes (mailto:, tel:, ...) straight to the OS default handler
+	if u, err := url.Parse(urlInput); err == nil && u.Scheme != "" && cfg.IsPassthroughScheme(u.Scheme) {
+		log.Info().Str("scheme", u.Scheme).Msg("Passthrough scheme, opening with system handler")
+		if dryRun {
+			fmt.Printf("Would open %s with the system handler\n", urlInput)
+			return nil
+		}
+		if err := systemOpen(urlInput); err != nil {
+			log.Error().Err(err).Str("url", urlInput).Msg("Failed to open URL with system handler")
+			return withExitCode(ExitLaunch, fmt.Errorf("failed to open URL with system handler: %w", err))
+		}
+		return nil
 	}
 
-	// Apply Rules based on the RESOLVED URL
-	matchResult, err := rules.ApplyRules(cfg, resolvedURL)
+	// Resolve the URL, apply the rules and decide what to launch
+	route, err := router.Route(ctx, cfg, urlInput)
 	if err != nil {
-		log.Error().Err(err).Str("url", resolvedURL).Msg("Failed to apply rules")
-		fmt.Fprintf(os.Stderr, "Error applying rules: %v\n", err)
-		os.Exit(1)
+		log.Error().Err(err).Str("input_url", urlInput).Msg("Failed to route URL")
+		reportBlocked(urlInput, err)
+		metrics.ObserveRouteFailure()
+		return withExitCode(routeExitCode(err), err)
+	}
+	if route.PolicyViolation != "" {
+		question := fmt.Sprintf("%s resolves to %s, which %s. Open it anyway?", urlInput, route.MatchURL, route.PolicyViolation)
+		if !confirm(question) {
+			log.Warn().Str("resolved_url", route.MatchURL).Str("reason", route.PolicyViolation).Msg("Launch declined by resolution policy")
+			metrics.ObserveRouteFailure()
+			return withExitCode(ExitBlocked, fmt.Errorf("not launched: %s %s", route.MatchURL, route.PolicyViolation))
+		}
 	}
+	matchResult, urlToLaunch := route.Match, route.LaunchURL
 
 	if matchResult.Rule != nil {
 		log.Info().Str("rule_name", matchResult.Rule.Name).Str("profile_id", matchResult.ProfileID).Msg("Rule matched")
+	} else if matchResult.Override {
+		log.Info().Str("profile_id", matchResult.ProfileID).Msg("URL overridden, using its profile")
 	} else {
 		log.Info().Str("profile_id", matchResult.ProfileID).Msg("No specific rule matched, using default profile")
 	}
 
-	err = launcher.Launch(cfg, matchResult.ProfileID, urlToLaunch, matchResult.Incognito)
+	if len(broadcastFlag) > 0 {
+		err = broadcastURL(ctx, urlInput, urlToLaunch, matchResult, broadcastFlag)
+	} else {
+		err = launchMatch(ctx, urlInput, urlToLaunch, matchResult)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Done after launching so the browser doesn't wait on the extra request
+	if cfg.ShortenerLearning.Enabled && !dryRun {
+		learnShortener(ctx, urlInput)
+	}
+	return nil
+}
+
+// broadcastURL opens urlToLaunch in each of profileIDs instead of the profile of
+// matchResult, as if each had been routed to (without the rule's handler, app, native
+// app link or browser arguments, which are for the routed profile's browser). All
+// profiles are tried; the error reports those that failed.
+func broadcastURL(ctx context.Context, urlInput, urlToLaunch string, matchResult rules.MatchResult, profileIDs []string) error {
+	for _, id := range profileIDs {
+		if _, err := cfg.FindProfileByID(id); err != nil {
+			return withExitCode(ExitConfig, fmt.Errorf("cannot broadcast to profile '%s': %w", id, err))
+		}
+	}
+	var errs []error
+	for _, id := range profileIDs {
+		log.Info().Str("profile_id", id).Msg("Broadcasting URL to profile")
+		target := rules.MatchResult{Rule: matchResult.Rule, ProfileID: id, Incognito: matchResult.Incognito}
+		if err := launchMatch(ctx, urlInput, urlToLaunch, target); err != nil {
+			errs = append(errs, fmt.Errorf("profile '%s': %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// launchMatch launches urlToLaunch as decided for matchResult, running the launch hooks
+// and recording the launch in the history.
+func launchMatch(ctx context.Context, urlInput, urlToLaunch string, matchResult rules.MatchResult) error {
+	plan, err := planLaunch(matchResult)
 	if err != nil {
-		log.Error().Err(err).Str("profile_id", matchResult.ProfileID).Str("url_launched", urlToLaunch).Msg("Failed to launch browser")
-		fmt.Fprintf(os.Stderr, "Error launching browser: %v\n", err)
-		os.Exit(1)
+		log.Error().Err(err).Msg("Cannot decide how to launch URL")
+		return withExitCode(ExitConfig, err)
+	}
+
+	hookInfo := buildHookInfo(urlToLaunch, urlInput, matchResult, plan)
+	if dryRun {
+		printDryRun(os.Stdout, plan, hookInfo)
+		return nil
+	}
+	hookTimeout := time.Duration(cfg.Hooks.TimeoutSeconds) * time.Second
+	if err := launcher.RunHook(ctx, launcher.HookPreLaunch, cfg.Hooks.PreLaunch, hookTimeout, hookInfo); err != nil {
+		log.Warn().Err(err).Str("url", urlToLaunch).Msg("Launch aborted by pre_launch hook")
+		return withExitCode(ExitBlocked, fmt.Errorf("launch aborted: %w", err))
+	}
+
+	if plan.Mode == launcher.LaunchModeHandler {
+		err = runHandler(plan.Handler, hookInfo)
+	} else {
+		err = executeLaunch(plan, urlToLaunch)
+	}
+	metrics.ObserveLaunch(plan.ProfileID, hookInfo.RuleID, err)
+
+	if cfg.History.Enabled {
+		recordLaunch(urlToLaunch, matchResult, plan, err)
+	}
+	if cfg.Usage.Enabled && err == nil {
+		recordUsage(matchResult, plan)
+	}
+	// Ephemeral profiles of earlier launches are removed once their browser has exited
+	if removed, _, cleanErr := launcher.CleanEphemeral(false); cleanErr != nil {
+		log.Debug().Err(cleanErr).Msg("Failed to clean up ephemeral profiles")
+	} else if removed > 0 {
+		log.Debug().Int("removed", removed).Msg("Removed ephemeral profiles")
+	}
+
+	hookInfo.LaunchError = err
+	if hookErr := launcher.RunHook(ctx, launcher.HookPostLaunch, cfg.Hooks.PostLaunch, hookTimeout, hookInfo); hookErr != nil {
+		log.Warn().Err(hookErr).Msg("post_launch hook failed")
+	}
+	if cfg.Hooks.Webhook != "" {
+		payload := launcher.NewWebhookPayload(hookInfo, time.Now())
+		if hookErr := launcher.SendWebhook(ctx, cfg.Hooks.Webhook, cfg.Hooks.WebhookSecret, hookTimeout, payload); hookErr != nil {
+			log.Warn().Err(hookErr).Msg("Decision webhook failed")
+		}
+	}
+
+	if err != nil {
+		log.Error().Err(err).Str("profile_id", plan.ProfileID).Str("url_launched", urlToLaunch).Msg("Failed to launch browser")
+		return withExitCode(ExitLaunch, fmt.Errorf("failed to launch browser: %w", err))
 	}
 
 	log.Info().Msg("Browser launched successfully")
+	return nil
+}
+
+// printDryRun writes to w what the launch described by plan and info would do.
+func printDryRun(w io.Writer, plan launchPlan, info launcher.HookInfo) {
+	switch {
+	case plan.Mode == launcher.LaunchModeDeepLink:
+		fmt.Fprintf(w, "Would open %s with the system handler\n", plan.DeepLinkURL)
+	case plan.Mode == launcher.LaunchModePrint || plan.Mode == launcher.LaunchModeOSC52:
+		fmt.Fprintf(w, "Would print %s (no display, headless fallback %s)\n", info.URL, plan.Mode)
+	case len(info.Command) > 0:
+		fmt.Fprintln(w, launcher.QuoteCommandLine(info.Command))
+	default:
+		fmt.Fprintf(w, "Would open %s in profile '%s' (incognito: %t)\n", info.URL, plan.ProfileID, plan.Incognito)
+	}
+}
+
+// confirmInTerminal asks question in the terminal. Without a terminal to ask in (e.g.
+// when rurl is started by clicking a link) the answer is no.
+func confirmInTerminal(question string) bool {
+	if !launcher.HasTerminal() {
+		return false
+	}
+	return promptYesNo(question, false)
+}
+
+// routingContext returns the context bounding the network requests, plugins and hooks
+// of a launch: it is cancelled by Ctrl-C and, with --timeout, once the timeout expires.
+func routingContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if routeTimeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, routeTimeout, fmt.Errorf("timed out after %s", routeTimeout))
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// learnShortener records the domain of rawURL as a candidate shortener if it is not a
+// known shortener and redirects to another site. Failures are only logged.
+func learnShortener(ctx context.Context, rawURL string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return
+	}
+	domain := strings.ToLower(u.Hostname())
+	if urlhandler.FindShortener(cfg, domain) != nil {
+		return
+	}
+
+	path, err := candidates.DefaultPath()
+	if err != nil {
+		log.Debug().Err(err).Msg("Cannot determine shortener candidates path")
+		return
+	}
+	list, err := candidates.Load(path)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load shortener candidates")
+		return
+	}
+
+	redirectTo := ""
+	now := time.Now()
+	if i := candidates.Find(list, domain); i >= 0 && (list[i].Dismissed || list[i].CheckedRecently(now)) {
+		return
+	} else if i < 0 || list[i].NoRedirect {
+		// Probe each domain once (per NoRedirectTTL); a failed request counts as a check
+		timeout := time.Duration(cfg.ShortenerLearning.TimeoutSeconds) * time.Second
+		redirectTo, err = urlhandler.DetectOffsiteRedirect(ctx, rawURL, timeout)
+		if ctx.Err() != nil {
+			return // Interrupted; the domain was not checked
+		}
+		if err != nil || redirectTo == "" {
+			if err != nil {
+				log.Debug().Err(err).Str("url", rawURL).Msg("Shortener detection request failed")
+			}
+			if err := candidates.RecordNoRedirect(path, domain, now); err != nil {
+				log.Warn().Err(err).Msg("Failed to record checked shortener domain")
+			}
+			return
+		}
+		log.Info().Str("domain", domain).Str("redirect_to", redirectTo).Msg("Recording candidate shortener domain")
+	}
+	// Otherwise already a candidate; just count the sighting without another request
+
+	if err := candidates.Record(path, domain, rawURL, redirectTo, now); err != nil {
+		log.Warn().Err(err).Msg("Failed to record shortener candidate")
+	}
+}
+
+// needsNoDisplay reports whether profileID belongs to a terminal or remote browser,
+// which does not need a local graphical display.
+func needsNoDisplay(profileID string) bool {
+	browser, err := profileBrowser(profileID)
+	return err == nil && (browser.Terminal || browser.Remote != nil)
+}
+
+// launchPlan describes how a URL will be opened. It is decided before the pre_launch
+// hook runs, so hooks are told exactly what will be launched.
+type launchPlan struct {
+	Mode        string // launcher.LaunchModeBrowser, LaunchModeApp, LaunchModeDeepLink, LaunchModeHandler, or a print/osc52 headless fallback
+	ProfileID   string // Profile to launch (browser and app modes)
+	AppID       string // Installed app to open the URL in (app mode)
+	Incognito   bool
+	WindowMode  string   // Overrides the browser's window mode (browser mode)
+	DeepLinkURL string   // Native app link opened instead of the URL (deep link mode)
+	ExtraArgs   []string // Extra browser arguments of the matched rule (browser mode)
+	Handler     string   // Command template the URL is passed to (handler mode)
+}
+
+// planLaunch decides how to open the URL for matchResult: with the rule's handler, in
+// the matched profile (or its installed app, or a native meeting app), or according to
+// the headless fallback if there is no display.
+func planLaunch(matchResult rules.MatchResult) (launchPlan, error) {
+	// Handlers may not need a display (e.g. downloaders), so they always run
+	if matchResult.Handler != "" {
+		return launchPlan{Mode: launcher.LaunchModeHandler, Handler: matchResult.Handler}, nil
+	}
+
+	// Native apps need a display too; without one the web URL takes the headless path
+	if matchResult.DeepLinkURL != "" && hasDisplay() {
+		return launchPlan{Mode: launcher.LaunchModeDeepLink, DeepLinkURL: matchResult.DeepLinkURL}, nil
+	}
+
+	plan := launchPlan{
+		Mode:       launcher.LaunchModeBrowser,
+		ProfileID:  matchResult.ProfileID,
+		AppID:      matchResult.PWAAppID,
+		Incognito:  matchResult.Incognito,
+		WindowMode: matchResult.WindowMode,
+		ExtraArgs:  matchResult.ExtraArgs,
+	}
+
+	if !hasDisplay() && !needsNoDisplay(matchResult.ProfileID) {
+		fallback := cfg.Headless.Fallback
+		log.Info().Str("fallback", fallback).Msg("No graphical display detected, using headless fallback")
+		switch fallback {
+		case config.HeadlessNone, "":
+		case config.HeadlessProfile:
+			if cfg.Headless.ProfileID == "" {
+				return plan, fmt.Errorf("headless fallback '%s' requires headless.profile_id to be set", fallback)
+			}
+			plan = launchPlan{Mode: launcher.LaunchModeBrowser, ProfileID: cfg.Headless.ProfileID}
+		case config.HeadlessOSC52:
+			return launchPlan{Mode: launcher.LaunchModeOSC52}, nil
+		case config.HeadlessPrint:
+			return launchPlan{Mode: launcher.LaunchModePrint}, nil
+		default:
+			return plan, fmt.Errorf("unknown headless fallback '%s' (expected print, osc52, profile or none)", fallback)
+		}
+	}
+
+	// The --incognito flag overrides the rule, and some profiles always open privately
+	plan.Incognito = launcher.ResolveIncognito(cfg, plan.ProfileID, plan.Incognito, incognitoFlag)
+
+	// Apps only open in Chromium-based browsers through a launcher that supports them,
+	// and cannot be opened privately
+	if plan.AppID != "" {
+		_, isAppLauncher := appLauncher.(launcher.AppLauncher)
+		if profile, err := cfg.FindProfileByID(plan.ProfileID); err == nil && profile.AlwaysIncognito {
+			log.Warn().Str("app_id", plan.AppID).Str("profile_id", plan.ProfileID).Msg("Profile always opens privately, opening URL in a private window instead of the app")
+			plan.AppID = ""
+		} else if browser, err := profileBrowser(plan.ProfileID); err == nil && isAppLauncher && launcher.IsChromium(*browser) {
+			plan.Mode = launcher.LaunchModeApp
+			plan.Incognito = false
+		} else {
+			log.Warn().Str("app_id", plan.AppID).Str("profile_id", plan.ProfileID).Msg("Browser cannot open app windows, opening URL normally")
+			plan.AppID = ""
+		}
+	}
+
+	// Browsers without private windows (e.g. Arc) would open the URL normally
+	if plan.Incognito && plan.Mode == launcher.LaunchModeBrowser {
+		plan.ProfileID = privateProfile(plan.ProfileID)
+	}
+	return plan, nil
+}
+
+// privateProfile returns the profile to open a private launch routed to profileID in:
+// profileID itself if its browser can open private windows, or else
+// behavior.incognito_fallback_profile_id if that one can. A warning is shown whenever
+// profileID cannot.
+func privateProfile(profileID string) string {
+	browser, err := profileBrowser(profileID)
+	if err != nil || launcher.SupportsIncognito(*browser) {
+		return profileID // Missing profiles are reported by the launch
+	}
+	fallbackID := cfg.Behavior.IncognitoFallbackProfileID
+	if fallback, err := profileBrowser(fallbackID); fallbackID != "" && err == nil && launcher.SupportsIncognito(*fallback) {
+		log.Warn().Str("profile_id", profileID).Str("browser_id", browser.BrowserID).Str("fallback_profile_id", fallbackID).Msg("Browser cannot open private windows, using the incognito fallback profile")
+		errorf("Warning: browser '%s' of profile '%s' cannot open private windows; opening the URL privately in profile '%s' instead.", browser.BrowserID, profileID, fallbackID)
+		return fallbackID
+	}
+	log.Warn().Str("profile_id", profileID).Str("browser_id", browser.BrowserID).Msg("Browser cannot open private windows, opening URL in a normal window")
+	errorf("Warning: browser '%s' of profile '%s' cannot open private windows; the URL opens in a NORMAL window. Set behavior.incognito_fallback_profile_id to a profile that can.", browser.BrowserID, profileID)
+	return profileID
+}
+
+// executeLaunch opens urlToLaunch as described by plan.
+func executeLaunch(plan launchPlan, urlToLaunch string) error {
+	switch plan.Mode {
+	case launcher.LaunchModeApp:
+		err := launcher.LaunchProfileApp(appLauncher, cfg, plan.ProfileID, plan.AppID, urlToLaunch, false)
+		return systemFallback(err, plan, urlToLaunch)
+	case launcher.LaunchModeDeepLink:
+		log.Info().Str("deep_link", plan.DeepLinkURL).Msg("Opening meeting link in native app")
+		return systemOpen(plan.DeepLinkURL)
+	case launcher.LaunchModeOSC52:
+		if err := launcher.CopyOSC52(urlToLaunch, os.Stderr); err != nil {
+			log.Warn().Err(err).Msg("Failed to copy URL to the clipboard")
+		} else {
+			errorf("No display available; URL copied to the clipboard:")
+		}
+		fmt.Println(urlToLaunch)
+		return nil
+	case launcher.LaunchModePrint:
+		errorf("No display available; open this URL manually:")
+		fmt.Println(urlToLaunch)
+		return nil
+	default:
+		err := launcher.LaunchProfileWindow(appLauncher, cfg, plan.ProfileID, urlToLaunch, plan.Incognito, plan.WindowMode, plan.ExtraArgs...)
+		return systemFallback(retryFailedLaunch(err, plan, urlToLaunch), plan, urlToLaunch)
+	}
+}
+
+// systemFallback opens urlToLaunch with the desktop's default handler if launching its
+// browser failed with err and the fallback is enabled. Remote and terminal browsers do
+// not fall back, nor do launches by the handler of an earlier fallback, which may be
+// rurl itself.
+func systemFallback(err error, plan launchPlan, urlToLaunch string) error {
+	if err == nil || !cfg.SystemFallback.Enabled || os.Getenv(launcher.SystemFallbackEnv) != "" {
+		return err
+	}
+	if browser, findErr := profileBrowser(plan.ProfileID); findErr == nil && (browser.Remote != nil || browser.Terminal) {
+		return err
+	}
+	log.Warn().Err(err).Str("profile_id", plan.ProfileID).Msg("Browser could not be launched, opening URL with the desktop's default handler")
+	if fallbackErr := portalOpen(urlToLaunch); fallbackErr != nil {
+		return fmt.Errorf("%w (system fallback also failed: %v)", err, fallbackErr)
+	}
+	return nil
+}
+
+// defaultLaunchMonitorWait is how long launches are watched when launch monitoring is
+// enabled without a wait_ms.
+const defaultLaunchMonitorWait = 1500 * time.Millisecond
+
+// retryFailedLaunch retries a launch that failed because the browser exited at once, if
+// enabled: first without profile and window arguments, then in the default profile.
+func retryFailedLaunch(err error, plan launchPlan, urlToLaunch string) error {
+	var failed *launcher.LaunchFailedError
+	if !cfg.LaunchMonitoring.Retry || !errors.As(err, &failed) {
+		return err
+	}
+	log.Warn().Err(err).Str("profile_id", plan.ProfileID).Msg("Browser exited immediately, retrying with simplified arguments")
+	if err = launcher.LaunchSimplified(appLauncher, cfg, plan.ProfileID, urlToLaunch, plan.Incognito); !errors.As(err, &failed) {
+		return err
+	}
+	defaultProfile, findErr := cfg.FindProfileByID(cfg.DefaultProfileID)
+	if cfg.DefaultProfileID == "" || findErr != nil || defaultProfile.ID == plan.ProfileID {
+		return err
+	}
+	log.Warn().Err(err).Str("profile_id", cfg.DefaultProfileID).Msg("Browser exited immediately again, retrying with the default profile")
+	return launcher.LaunchProfile(appLauncher, cfg, cfg.DefaultProfileID, urlToLaunch, plan.Incognito)
+}
+
+// profileBrowser returns the browser of profileID.
+func profileBrowser(profileID string) (*config.Browser, error) {
+	profile, err := cfg.FindProfileByID(profileID)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.GetProfileBrowser(profile)
+}
+
+// reportBlocked records a URL blocked by strict mode or the resolution policy in the
+// history, if enabled, and shows a notification if strict.notify is set. err is the
+// routing error; other errors are ignored.
+func reportBlocked(urlInput string, err error) {
+	var blocked *router.BlockedError
+	if !errors.As(err, &blocked) {
+		return
+	}
+	if cfg.History.Enabled {
+		recorded := urlInput
+		if strings.HasPrefix(strings.ToLower(urlInput), "data:") {
+			recorded = blocked.URL // Shortened, as oversized data: URLs are blocked
+		}
+		recordLaunch(recorded, rules.MatchResult{}, launchPlan{}, err)
+	}
+	if cfg.Strict.Notify {
+		if err := notify("rurl blocked a link", fmt.Sprintf("%s %s", blocked.URL, blocked.Reason)); err != nil {
+			log.Warn().Err(err).Msg("Failed to show notification")
+		}
+	}
+}
+
+// recordLaunch appends the launch to the history file. Failures are only logged.
+func recordLaunch(urlLaunched string, matchResult rules.MatchResult, plan launchPlan, launchErr error) {
+	path, err := history.DefaultPath()
+	if err != nil {
+		log.Debug().Err(err).Msg("Cannot determine history path, not recording launch")
+		return
+	}
+	entry := history.Entry{
+		Time:      time.Now(),
+		URL:       urlLaunched,
+		ProfileID: plan.ProfileID,
+		Incognito: plan.Incognito,
+	}
+	if matchResult.Rule != nil {
+		entry.RuleName = matchResult.Rule.Name
+		entry.RuleTags = matchResult.Rule.Tags
+	}
+	if launchErr != nil {
+		entry.Error = launchErr.Error()
+	}
+	if err := history.Append(path, entry, cfg.History.MaxEntries); err != nil {
+		log.Warn().Err(err).Msg("Failed to record launch history")
+	}
+}
+
+// recordUsage counts the launch for the matched rule and the launched profile in the
+// usage file. Failures are only logged.
+func recordUsage(matchResult rules.MatchResult, plan launchPlan) {
+	path, err := usage.DefaultPath()
+	if err != nil {
+		log.Debug().Err(err).Msg("Cannot determine usage path, not counting launch")
+		return
+	}
+	ruleID := ""
+	if matchResult.Rule != nil {
+		ruleID = matchResult.Rule.ID
+	}
+	if err := usage.Record(path, ruleID, plan.ProfileID, time.Now()); err != nil {
+		log.Warn().Err(err).Msg("Failed to record rule and profile usage")
+	}
+}
+
+// commandLiner is implemented by launchers that can report the command they would run.
+type commandLiner interface {
+	CommandLine(browser config.Browser, profile config.Profile, url string, incognito bool, appID string) ([]string, error)
+}
+
+// buildHookInfo describes the planned launch for pre/post launch hooks.
+func buildHookInfo(urlToLaunch, originalURL string, matchResult rules.MatchResult, plan launchPlan) launcher.HookInfo {
+	info := launcher.HookInfo{
+		URL:         urlToLaunch,
+		OriginalURL: originalURL,
+		Mode:        plan.Mode,
+		ProfileID:   plan.ProfileID,
+		AppID:       plan.AppID,
+		Incognito:   plan.Incognito,
+	}
+	if plan.Mode == launcher.LaunchModeDeepLink {
+		info.URL = plan.DeepLinkURL
+	}
+	if matchResult.Rule != nil {
+		info.RuleID = matchResult.Rule.ID
+		info.RuleName = matchResult.Rule.Name
+	}
+	if plan.Mode == launcher.LaunchModeHandler {
+		info.Command, _ = launcher.HandlerCommand(plan.Handler, urlToLaunch)
+		return info
+	}
+	if plan.ProfileID == "" {
+		return info // Nothing is launched
+	}
+
+	profile, err := cfg.FindProfileByID(plan.ProfileID)
+	if err != nil {
+		return info
+	}
+	browser, err := cfg.GetProfileBrowser(profile)
+	if err != nil {
+		return info
+	}
+	info.BrowserID = browser.BrowserID
+	b := *browser
+	b.WindowMode = launcher.WindowMode(cfg, b, plan.WindowMode)
+	b.ExtraArgs = append(slices.Clip(b.ExtraArgs), plan.ExtraArgs...)
+	if cl, ok := appLauncher.(commandLiner); ok {
+		if args, err := cl.CommandLine(b, *profile, urlToLaunch, plan.Incognito, plan.AppID); err == nil {
+			info.Command = args
+		}
+	}
+	return info
+}
+
+// loadConfigOrDefault returns the configuration, or the default one if its file does
+// not exist, for commands that only use it if available. Unlike initConfig, it never
+// creates the file and returns nil on errors.
+func loadConfigOrDefault() *config.Config {
+	loaded, err := config.LoadConfigOrDefault(cfgFile)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to load configuration")
+		return nil
+	}
+	return loaded
 }
 
-// DefaultConfigPath helper for CLI flags.
+// DefaultConfigPath helper for CLI flags: the configuration file used without --config.
 func DefaultConfigPath() string {
-	dir, err := os.UserConfigDir()
+	path, err := config.DefaultConfigFile()
 	if err != nil {
-		dir = "$HOME/.config" // Fallback for display
 		log.Warn().Err(err).Msg("Could not determine user config dir for help text")
+		return filepath.Join("$HOME/.config", "rurl", "config.toml") // Fallback for display
 	}
-	return filepath.Join(dir, "rurl", "config.toml")
+	return path
 }
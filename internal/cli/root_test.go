@@ -0,0 +1,876 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/candidates"
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/history"
+	"github.com/jmylchreest/rurl/internal/launcher"
+	"github.com/jmylchreest/rurl/internal/rules"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingLauncher captures launch requests instead of spawning processes
+type recordingLauncher struct {
+	browsers  []config.Browser
+	profiles  []config.Profile
+	urls      []string
+	incognito []bool
+	errs      []error // Returned by successive launches (nil once exhausted)
+}
+
+func (r *recordingLauncher) LaunchBrowser(browser config.Browser, profile config.Profile, url string, incognito bool) error {
+	r.browsers = append(r.browsers, browser)
+	r.profiles = append(r.profiles, profile)
+	r.urls = append(r.urls, url)
+	r.incognito = append(r.incognito, incognito)
+	if len(r.errs) > 0 {
+		err := r.errs[0]
+		r.errs = r.errs[1:]
+		return err
+	}
+	return nil
+}
+
+func TestRunRootCmdRoutesURL(t *testing.T) {
+	originalCfg, originalLauncher, originalDisplay := cfg, appLauncher, hasDisplay
+	defer func() { cfg, appLauncher, hasDisplay = originalCfg, originalLauncher, originalDisplay }()
+	hasDisplay = func() bool { return true }
+
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	rec := &recordingLauncher{}
+	appLauncher = rec
+	cfg = &config.Config{
+		DefaultProfileID: "personal",
+		Browsers: []config.Browser{
+			{Name: "Test Browser", BrowserID: "test", Executable: "/bin/echo"},
+		},
+		Profiles: []config.Profile{
+			{ID: "personal", Name: "Personal", BrowserID: "test", ProfileDir: "Default"},
+			{ID: "work", Name: "Work", BrowserID: "test", ProfileDir: "Profile 1"},
+		},
+		Rules: []config.Rule{
+			{Name: "Work", Pattern: `^work\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "work", Incognito: true, WindowMode: config.WindowNewTab, Tags: []string{"work"}},
+		},
+		History: config.History{Enabled: true},
+	}
+
+	runRootCmd(rootCmd, []string{"https://work.example.com/dashboard"})
+	runRootCmd(rootCmd, []string{"https://other.example.com/"})
+
+	assert.Equal(t, []string{"https://work.example.com/dashboard", "https://other.example.com/"}, rec.urls)
+	assert.Equal(t, "work", rec.profiles[0].ID)
+	assert.True(t, rec.incognito[0])
+	assert.Equal(t, config.WindowNewTab, rec.browsers[0].WindowMode)
+	assert.Equal(t, "personal", rec.profiles[1].ID)
+	assert.Empty(t, rec.browsers[1].WindowMode)
+	assert.False(t, rec.incognito[1])
+
+	// Launches are recorded in the history
+	path, err := history.DefaultPath()
+	assert.NoError(t, err)
+	entries, err := history.Load(path)
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "Work", entries[0].RuleName)
+		assert.Equal(t, []string{"work"}, entries[0].RuleTags)
+		assert.Equal(t, "personal", entries[1].ProfileID)
+	}
+
+	// Nothing is recorded once history is disabled
+	cfg.History.Enabled = false
+	runRootCmd(rootCmd, []string{"https://other.example.com/"})
+	entries, err = history.Load(path)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestRunRootCmdPassthroughScheme(t *testing.T) {
+	originalCfg, originalLauncher, originalOpen, originalDisplay := cfg, appLauncher, systemOpen, hasDisplay
+	defer func() {
+		cfg, appLauncher, systemOpen, hasDisplay = originalCfg, originalLauncher, originalOpen, originalDisplay
+	}()
+	hasDisplay = func() bool { return true }
+
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	rec := &recordingLauncher{}
+	appLauncher = rec
+	var opened []string
+	systemOpen = func(url string) error {
+		opened = append(opened, url)
+		return nil
+	}
+	cfg = &config.Config{
+		DefaultProfileID: "personal",
+		Browsers:         []config.Browser{{Name: "Test Browser", BrowserID: "test", Executable: "/bin/echo"}},
+		Profiles:         []config.Profile{{ID: "personal", Name: "Personal", BrowserID: "test"}},
+		Behavior:         config.Behavior{PassthroughSchemes: []string{"mailto", "TEL:"}},
+	}
+
+	runRootCmd(rootCmd, []string{"mailto:someone@example.com"})
+	runRootCmd(rootCmd, []string{"tel:+441234567890"})
+	runRootCmd(rootCmd, []string{"https://example.com/"})
+
+	assert.Equal(t, []string{"mailto:someone@example.com", "tel:+441234567890"}, opened)
+	assert.Equal(t, []string{"https://example.com/"}, rec.urls)
+}
+
+func TestRunRootCmdDeepLink(t *testing.T) {
+	originalCfg, originalLauncher, originalOpen, originalDisplay := cfg, appLauncher, systemOpen, hasDisplay
+	defer func() {
+		cfg, appLauncher, systemOpen, hasDisplay = originalCfg, originalLauncher, originalOpen, originalDisplay
+	}()
+	hasDisplay = func() bool { return true }
+
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	rec := &recordingLauncher{}
+	appLauncher = rec
+	var opened []string
+	systemOpen = func(url string) error {
+		opened = append(opened, url)
+		return nil
+	}
+	cfg = &config.Config{
+		DefaultProfileID: "personal",
+		Browsers:         []config.Browser{{Name: "Test Browser", BrowserID: "test", Executable: "/bin/echo"}},
+		Profiles:         []config.Profile{{ID: "personal", Name: "Personal", BrowserID: "test"}},
+		Rules:            []config.Rule{{ID: "zoom", Name: "Zoom", Pattern: `(^|\.)zoom\.us$`, Scope: config.ScopeDomain, ProfileID: "personal", DeepLink: true}},
+	}
+
+	// Meeting links open in the app; other URLs of the rule open in its profile
+	runRootCmd(rootCmd, []string{"https://example.zoom.us/j/123456789?pwd=abc"})
+	runRootCmd(rootCmd, []string{"https://zoom.us/pricing"})
+	assert.Equal(t, []string{"zoommtg://example.zoom.us/join?action=join&confno=123456789&pwd=abc"}, opened)
+	assert.Equal(t, []string{"https://zoom.us/pricing"}, rec.urls)
+
+	// Without a display the meeting link takes the headless path like any other URL
+	hasDisplay = func() bool { return false }
+	runRootCmd(rootCmd, []string{"https://zoom.us/j/987654321"})
+	assert.Len(t, opened, 1)
+	assert.Equal(t, []string{"https://zoom.us/pricing", "https://zoom.us/j/987654321"}, rec.urls)
+}
+
+func TestRunRootCmdRuleOverridesSafelink(t *testing.T) {
+	originalCfg, originalLauncher, originalDisplay := cfg, appLauncher, hasDisplay
+	defer func() { cfg, appLauncher, hasDisplay = originalCfg, originalLauncher, originalDisplay }()
+	hasDisplay = func() bool { return true }
+
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	// A local "shortener" redirecting /s/* to /target/*
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := strings.CutPrefix(r.URL.Path, "/s/"); ok {
+			http.Redirect(w, r, "/target/"+id, http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	launchResolved, launchOriginal := false, true
+	rec := &recordingLauncher{}
+	appLauncher = rec
+	cfg = &config.Config{
+		DefaultProfileID: "personal",
+		Browsers:         []config.Browser{{Name: "Test Browser", BrowserID: "test", Executable: "/bin/echo"}},
+		Profiles:         []config.Profile{{ID: "personal", Name: "Personal", BrowserID: "test"}},
+		ManualShorteners: []config.ShortenerService{{Domain: "127.0.0.1", IsSafelink: true}},
+		Rules: []config.Rule{
+			{Name: "Resolved", Pattern: `^/target/resolved$`, Scope: config.ScopePath, ProfileID: "personal", LaunchOriginal: &launchResolved},
+			{Name: "Original", Pattern: `^/target/original$`, Scope: config.ScopePath, ProfileID: "personal", LaunchOriginal: &launchOriginal},
+			{Name: "Inherit", Pattern: `^/target/inherit$`, Scope: config.ScopePath, ProfileID: "personal"},
+		},
+	}
+
+	runRootCmd(rootCmd, []string{server.URL + "/s/resolved"})
+	runRootCmd(rootCmd, []string{server.URL + "/s/inherit"})
+	cfg.ManualShorteners[0].IsSafelink = false
+	runRootCmd(rootCmd, []string{server.URL + "/s/original"})
+	runRootCmd(rootCmd, []string{server.URL + "/s/inherit"})
+
+	assert.Equal(t, []string{
+		server.URL + "/target/resolved", // Rule overrides the safelink
+		server.URL + "/s/inherit",       // Safelink: original URL
+		server.URL + "/s/original",      // Rule forces the original URL
+		server.URL + "/target/inherit",  // Not a safelink: resolved URL
+	}, rec.urls)
+}
+
+func TestRunRootCmdResolutionPolicyPrompt(t *testing.T) {
+	originalCfg, originalLauncher, originalDisplay, originalConfirm := cfg, appLauncher, hasDisplay, confirmLaunch
+	defer func() {
+		cfg, appLauncher, hasDisplay, confirmLaunch = originalCfg, originalLauncher, originalDisplay, originalConfirm
+	}()
+	hasDisplay = func() bool { return true }
+
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	// A local "shortener" on 127.0.0.1 redirecting to localhost
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/s" {
+			http.Redirect(w, r, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)+"/target", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	var questions []string
+	confirmLaunch = func(question string) bool {
+		questions = append(questions, question)
+		return true
+	}
+	rec := &recordingLauncher{}
+	appLauncher = rec
+	cfg = &config.Config{
+		DefaultProfileID: "personal",
+		Browsers:         []config.Browser{{Name: "Test Browser", BrowserID: "test", Executable: "/bin/echo"}},
+		Profiles:         []config.Profile{{ID: "personal", Name: "Personal", BrowserID: "test"}},
+		ManualShorteners: []config.ShortenerService{{Domain: "127.0.0.1"}},
+		ResolutionPolicy: config.ResolutionPolicy{Allow: []string{"localhost"}, Action: config.PolicyPrompt},
+	}
+
+	// Allowed targets are launched without asking
+	runRootCmd(rootCmd, []string{server.URL + "/s"})
+	assert.Empty(t, questions)
+
+	// Others are launched once confirmed
+	cfg.ResolutionPolicy.Allow = []string{"example.com"}
+	runRootCmd(rootCmd, []string{server.URL + "/s"})
+	require.Len(t, questions, 1)
+	assert.Contains(t, questions[0], "is not on the resolution policy's allow list")
+
+	target := strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "/target"
+	assert.Equal(t, []string{target, target}, rec.urls)
+}
+
+func TestRunRootCmdHeadlessFallback(t *testing.T) {
+	originalCfg, originalLauncher, originalDisplay := cfg, appLauncher, hasDisplay
+	defer func() { cfg, appLauncher, hasDisplay = originalCfg, originalLauncher, originalDisplay }()
+	hasDisplay = func() bool { return false }
+
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	rec := &recordingLauncher{}
+	appLauncher = rec
+	cfg = &config.Config{
+		DefaultProfileID: "personal",
+		Browsers: []config.Browser{
+			{Name: "Test Browser", BrowserID: "test", Executable: "/bin/echo"},
+			{Name: "Terminal Browser", BrowserID: "term", Executable: "/bin/echo"},
+		},
+		Profiles: []config.Profile{
+			{ID: "personal", Name: "Personal", BrowserID: "test"},
+			{ID: "terminal", Name: "Terminal", BrowserID: "term"},
+		},
+	}
+
+	// Default: launch the matched profile anyway, as before headless support
+	runRootCmd(rootCmd, []string{"https://example.com/default"})
+
+	cfg.Headless = config.Headless{Fallback: config.HeadlessPrint}
+	runRootCmd(rootCmd, []string{"https://example.com/print"})
+
+	cfg.Headless = config.Headless{Fallback: config.HeadlessProfile, ProfileID: "terminal"}
+	runRootCmd(rootCmd, []string{"https://example.com/terminal"})
+
+	cfg.Headless = config.Headless{Fallback: config.HeadlessNone}
+	runRootCmd(rootCmd, []string{"https://example.com/none"})
+
+	assert.Equal(t, []string{"https://example.com/default", "https://example.com/terminal", "https://example.com/none"}, rec.urls)
+	assert.Equal(t, "personal", rec.profiles[0].ID)
+	assert.Equal(t, "terminal", rec.profiles[1].ID)
+	assert.Equal(t, "personal", rec.profiles[2].ID)
+}
+
+func TestRunRootCmdTerminalProfileWithoutDisplay(t *testing.T) {
+	originalCfg, originalLauncher, originalDisplay := cfg, appLauncher, hasDisplay
+	defer func() { cfg, appLauncher, hasDisplay = originalCfg, originalLauncher, originalDisplay }()
+	hasDisplay = func() bool { return false }
+
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	rec := &recordingLauncher{}
+	appLauncher = rec
+	cfg = &config.Config{
+		DefaultProfileID: "lynx",
+		Browsers: []config.Browser{
+			{Name: "Test Browser", BrowserID: "test", Executable: "/bin/echo"},
+			{Name: "Lynx", BrowserID: "lynx", Executable: "/usr/bin/lynx", Terminal: true},
+			{Name: "Desktop", BrowserID: "desktop", Remote: &config.RemoteTarget{Host: "desktop"}},
+		},
+		Profiles: []config.Profile{
+			{ID: "personal", Name: "Personal", BrowserID: "test"},
+			{ID: "lynx", Name: "Lynx", BrowserID: "lynx"},
+			{ID: "desktop", Name: "Desktop", BrowserID: "desktop"},
+		},
+		Rules: []config.Rule{
+			{ID: "gui", Name: "GUI", Pattern: `^gui\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "personal"},
+			{ID: "remote", Name: "Remote", Pattern: `^remote\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "desktop"},
+		},
+		Headless: config.Headless{Fallback: config.HeadlessPrint},
+	}
+
+	// A terminal browser profile is launched even though there is no display
+	runRootCmd(rootCmd, []string{"https://example.com/"})
+	// So is a remote browser, which opens the URL on another machine
+	runRootCmd(rootCmd, []string{"https://remote.example.com/"})
+	// A GUI profile still uses the headless fallback
+	runRootCmd(rootCmd, []string{"https://gui.example.com/"})
+
+	assert.Equal(t, []string{"https://example.com/", "https://remote.example.com/"}, rec.urls)
+	assert.Equal(t, "lynx", rec.profiles[0].ID)
+	assert.Equal(t, "desktop", rec.profiles[1].ID)
+}
+
+func TestPlanLaunchAndHookInfo(t *testing.T) {
+	originalCfg, originalLauncher, originalDisplay := cfg, appLauncher, hasDisplay
+	defer func() { cfg, appLauncher, hasDisplay = originalCfg, originalLauncher, originalDisplay }()
+
+	appLauncher = launcher.NewExecLauncher()
+	cfg = &config.Config{
+		DefaultProfileID: "chrome",
+		Browsers: []config.Browser{
+			{Name: "Chrome", BrowserID: "chrome", Executable: "/usr/bin/chrome", ProfileArg: "--profile-directory=%s"},
+			{Name: "Firefox", BrowserID: "firefox", Executable: "/usr/bin/firefox", ProfileArg: "-P %s"},
+		},
+		Profiles: []config.Profile{
+			{ID: "chrome", Name: "Chrome", BrowserID: "chrome", ProfileDir: "Default"},
+			{ID: "firefox", Name: "Firefox", BrowserID: "firefox", ProfileDir: "work"},
+		},
+		Headless: config.Headless{Fallback: config.HeadlessProfile, ProfileID: "firefox"},
+	}
+	rule := &config.Rule{ID: "docs", Name: "Docs"}
+	appMatch := rules.MatchResult{Rule: rule, ProfileID: "chrome", PWAAppID: "abcdef", Incognito: true}
+
+	// With a display, the app is opened and hooks see the app command
+	hasDisplay = func() bool { return true }
+	plan, err := planLaunch(appMatch)
+	require.NoError(t, err)
+	assert.Equal(t, launchPlan{Mode: launcher.LaunchModeApp, ProfileID: "chrome", AppID: "abcdef"}, plan)
+	info := buildHookInfo("https://docs.example.com/", "https://docs.example.com/", appMatch, plan)
+	assert.Equal(t, "chrome", info.BrowserID)
+	assert.Contains(t, info.Command, "--app-id=abcdef")
+
+	// Browsers that cannot open apps get a normal launch
+	plan, err = planLaunch(rules.MatchResult{ProfileID: "firefox", PWAAppID: "abcdef"})
+	require.NoError(t, err)
+	assert.Equal(t, launchPlan{Mode: launcher.LaunchModeBrowser, ProfileID: "firefox"}, plan)
+
+	// Without a display, hooks see the headless fallback profile
+	hasDisplay = func() bool { return false }
+	plan, err = planLaunch(appMatch)
+	require.NoError(t, err)
+	assert.Equal(t, launchPlan{Mode: launcher.LaunchModeBrowser, ProfileID: "firefox"}, plan)
+	info = buildHookInfo("https://docs.example.com/", "https://docs.example.com/", appMatch, plan)
+	assert.Equal(t, "firefox", info.BrowserID)
+	assert.Equal(t, []string{"/usr/bin/firefox", "-P", "work", "https://docs.example.com/"}, info.Command)
+	assert.Equal(t, "Docs", info.RuleName)
+
+	// Printing launches nothing
+	cfg.Headless = config.Headless{Fallback: config.HeadlessPrint}
+	plan, err = planLaunch(appMatch)
+	require.NoError(t, err)
+	info = buildHookInfo("https://docs.example.com/", "https://docs.example.com/", appMatch, plan)
+	assert.Equal(t, launcher.LaunchModePrint, info.Mode)
+	assert.Empty(t, info.ProfileID)
+	assert.Empty(t, info.Command)
+}
+
+func TestPlanLaunchIncognito(t *testing.T) {
+	originalCfg, originalLauncher, originalDisplay, originalFlag := cfg, appLauncher, hasDisplay, incognitoFlag
+	defer func() {
+		cfg, appLauncher, hasDisplay, incognitoFlag = originalCfg, originalLauncher, originalDisplay, originalFlag
+	}()
+
+	appLauncher = launcher.NewExecLauncher()
+	hasDisplay = func() bool { return true }
+	cfg = &config.Config{
+		Browsers: []config.Browser{{Name: "Chrome", BrowserID: "chrome", Executable: "/usr/bin/chrome"}},
+		Profiles: []config.Profile{
+			{ID: "work", BrowserID: "chrome"},
+			{ID: "throwaway", BrowserID: "chrome", AlwaysIncognito: true},
+		},
+	}
+	on, off := true, false
+
+	// The flag overrides the rule, unless the profile always opens privately
+	for _, tt := range []struct {
+		profileID string
+		rule      bool
+		flag      *bool
+		want      bool
+	}{
+		{"work", true, nil, true},
+		{"work", false, &on, true},
+		{"work", true, &off, false},
+		{"throwaway", false, nil, true},
+		{"throwaway", false, &off, true},
+	} {
+		incognitoFlag = tt.flag
+		plan, err := planLaunch(rules.MatchResult{ProfileID: tt.profileID, Incognito: tt.rule})
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, plan.Incognito, "%+v", tt)
+	}
+
+	// Apps cannot be opened privately, so the URL opens in a private window instead
+	incognitoFlag = nil
+	plan, err := planLaunch(rules.MatchResult{ProfileID: "throwaway", PWAAppID: "abcdef"})
+	require.NoError(t, err)
+	assert.Equal(t, launchPlan{Mode: launcher.LaunchModeBrowser, ProfileID: "throwaway", Incognito: true}, plan)
+}
+
+func TestPlanLaunchIncognitoFallback(t *testing.T) {
+	originalCfg, originalLauncher, originalDisplay, originalFlag := cfg, appLauncher, hasDisplay, incognitoFlag
+	defer func() {
+		cfg, appLauncher, hasDisplay, incognitoFlag = originalCfg, originalLauncher, originalDisplay, originalFlag
+	}()
+
+	appLauncher = launcher.NewExecLauncher()
+	hasDisplay = func() bool { return true }
+	incognitoFlag = nil
+	cfg = &config.Config{
+		Browsers: []config.Browser{
+			{Name: "Arc", BrowserID: "arc", Executable: "/usr/bin/arc"},
+			{Name: "Chrome", BrowserID: "chrome", Executable: "/usr/bin/chrome", IncognitoArg: "--incognito"},
+		},
+		Profiles: []config.Profile{
+			{ID: "arc", BrowserID: "arc"},
+			{ID: "chrome", BrowserID: "chrome"},
+		},
+	}
+
+	// Without a fallback the URL opens in the profile, normally
+	plan, err := planLaunch(rules.MatchResult{ProfileID: "arc", Incognito: true})
+	require.NoError(t, err)
+	assert.Equal(t, "arc", plan.ProfileID)
+
+	// With one, private launches move to it and others stay
+	cfg.Behavior.IncognitoFallbackProfileID = "chrome"
+	plan, err = planLaunch(rules.MatchResult{ProfileID: "arc", Incognito: true})
+	require.NoError(t, err)
+	assert.Equal(t, launchPlan{Mode: launcher.LaunchModeBrowser, ProfileID: "chrome", Incognito: true}, plan)
+	plan, err = planLaunch(rules.MatchResult{ProfileID: "arc"})
+	require.NoError(t, err)
+	assert.Equal(t, "arc", plan.ProfileID)
+
+	// A fallback that cannot open private windows either is not used, and is invalid
+	cfg.Behavior.IncognitoFallbackProfileID = "arc"
+	plan, err = planLaunch(rules.MatchResult{ProfileID: "arc", Incognito: true})
+	require.NoError(t, err)
+	assert.Equal(t, "arc", plan.ProfileID)
+	assert.Equal(t, []error{errors.New("incognito fallback profile 'arc' uses browser 'arc', which cannot open private windows either")}, incognitoProblems(cfg))
+
+	cfg.Behavior.IncognitoFallbackProfileID = ""
+	cfg.Profiles[0].AlwaysIncognito = true
+	assert.Len(t, incognitoProblems(cfg), 1)
+	cfg.Behavior.IncognitoFallbackProfileID = "chrome"
+	assert.Empty(t, incognitoProblems(cfg))
+}
+
+func TestLearnShortenerProbesOnce(t *testing.T) {
+	originalCfg := cfg
+	defer func() { cfg = originalCfg }()
+	cfg = &config.Config{ShortenerLearning: config.ShortenerLearning{Enabled: true}}
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	// A domain that does not redirect is only checked once
+	learnShortener(context.Background(), server.URL+"/a")
+	learnShortener(context.Background(), server.URL+"/b")
+	assert.Equal(t, 1, requests)
+
+	path, err := candidates.DefaultPath()
+	require.NoError(t, err)
+	list, err := candidates.Load(path)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.False(t, list[0].Pending())
+}
+
+func TestExecuteLaunchRetriesFailedLaunch(t *testing.T) {
+	originalCfg, originalLauncher := cfg, appLauncher
+	defer func() { cfg, appLauncher = originalCfg, originalLauncher }()
+
+	failed := &launcher.LaunchFailedError{Args: []string{"browser"}, ExitCode: 1, Stderr: "bad flag"}
+	cfg = &config.Config{
+		DefaultProfileID: "personal",
+		Browsers: []config.Browser{
+			{Name: "Test Browser", BrowserID: "test", Executable: "/bin/echo", WindowMode: config.WindowNew},
+		},
+		Profiles: []config.Profile{
+			{ID: "personal", Name: "Personal", BrowserID: "test", ProfileDir: "Default"},
+			{ID: "work", Name: "Work", BrowserID: "test", ProfileDir: "Profile 1"},
+		},
+	}
+	plan := launchPlan{Mode: launcher.LaunchModeBrowser, ProfileID: "work", Incognito: true}
+
+	// Without retry the failure is returned as is
+	rec := &recordingLauncher{errs: []error{failed}}
+	appLauncher = rec
+	assert.ErrorIs(t, executeLaunch(plan, "https://example.com/"), failed)
+	assert.Len(t, rec.urls, 1)
+
+	// With retry, the simplified launch is tried first, then the default profile
+	cfg.LaunchMonitoring.Retry = true
+	rec = &recordingLauncher{errs: []error{failed, failed}}
+	appLauncher = rec
+	assert.NoError(t, executeLaunch(plan, "https://example.com/"))
+	require.Len(t, rec.profiles, 3)
+	assert.Equal(t, "Profile 1", rec.profiles[0].ProfileDir)
+	assert.Empty(t, rec.profiles[1].ProfileDir)
+	assert.Empty(t, rec.browsers[1].WindowMode)
+	assert.True(t, rec.incognito[1])
+	assert.Equal(t, "personal", rec.profiles[2].ID)
+	assert.True(t, rec.incognito[2])
+
+	// Other errors are not retried
+	rec = &recordingLauncher{errs: []error{errors.New("not found")}}
+	appLauncher = rec
+	assert.Error(t, executeLaunch(plan, "https://example.com/"))
+	assert.Len(t, rec.urls, 1)
+}
+
+func TestOpenURLExitCodes(t *testing.T) {
+	originalCfg, originalLauncher, originalOpen, originalDisplay := cfg, appLauncher, systemOpen, hasDisplay
+	defer func() {
+		cfg, appLauncher, systemOpen, hasDisplay = originalCfg, originalLauncher, originalOpen, originalDisplay
+	}()
+	hasDisplay = func() bool { return true }
+
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	// A local "shortener" on 127.0.0.1 redirecting to localhost
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/s" {
+			http.Redirect(w, r, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)+"/target", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	newConfig := func() *config.Config {
+		return &config.Config{
+			DefaultProfileID: "personal",
+			Browsers:         []config.Browser{{Name: "Test Browser", BrowserID: "test", Executable: "/bin/echo"}},
+			Profiles:         []config.Profile{{ID: "personal", Name: "Personal", BrowserID: "test"}},
+			ManualShorteners: []config.ShortenerService{{Domain: "127.0.0.1"}},
+			Behavior:         config.Behavior{PassthroughSchemes: []string{"mailto"}},
+		}
+	}
+	decline := func(string) bool { return false }
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name  string
+		setup func(rec *recordingLauncher)
+		ctx   context.Context
+		url   string
+		want  int
+	}{
+		{"launched", func(*recordingLauncher) {}, context.Background(), "https://example.com/", ExitOK},
+		{"no default profile", func(*recordingLauncher) { cfg.DefaultProfileID = "" }, context.Background(), "https://example.com/", ExitNoProfile},
+		{"missing rule profile", func(*recordingLauncher) {
+			cfg.Rules = []config.Rule{{Name: "Gone", Pattern: "example", Scope: config.ScopeDomain, ProfileID: "gone"}}
+		}, context.Background(), "https://example.com/", ExitNoProfile},
+		{"routing stopped", func(*recordingLauncher) {}, cancelled, "https://example.com/", ExitResolution},
+		{"blocked by policy", func(*recordingLauncher) {
+			cfg.ResolutionPolicy = config.ResolutionPolicy{Allow: []string{"example.com"}}
+		}, context.Background(), server.URL + "/s", ExitBlocked},
+		{"declined by policy prompt", func(*recordingLauncher) {
+			cfg.ResolutionPolicy = config.ResolutionPolicy{Allow: []string{"example.com"}, Action: config.PolicyPrompt}
+		}, context.Background(), server.URL + "/s", ExitBlocked},
+		{"invalid headless fallback", func(*recordingLauncher) {
+			hasDisplay = func() bool { return false }
+			cfg.Headless = config.Headless{Fallback: config.HeadlessProfile}
+		}, context.Background(), "https://example.com/", ExitConfig},
+		{"browser failed", func(rec *recordingLauncher) {
+			rec.errs = []error{errors.New("browser not found")}
+		}, context.Background(), "https://example.com/", ExitLaunch},
+		{"system handler failed", func(*recordingLauncher) {
+			systemOpen = func(string) error { return errors.New("no handler") }
+		}, context.Background(), "mailto:someone@example.com", ExitLaunch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasDisplay = func() bool { return true }
+			systemOpen = func(string) error { return nil }
+			cfg = newConfig()
+			rec := &recordingLauncher{}
+			appLauncher = rec
+			tt.setup(rec)
+
+			err := openURL(tt.ctx, tt.url, decline)
+			assert.Equal(t, tt.want, ExitCode(err), "err = %v", err)
+		})
+	}
+
+	// Codes survive further wrapping
+	err := fmt.Errorf("rule 'x': %w", withExitCode(ExitConfig, errors.New("failed to save config")))
+	assert.Equal(t, ExitConfig, ExitCode(err))
+	assert.Equal(t, ExitError, ExitCode(errors.New("other")))
+	assert.NoError(t, withExitCode(ExitLaunch, nil))
+}
+
+func TestOpenURLStrictMode(t *testing.T) {
+	originalCfg, originalLauncher, originalDisplay, originalNotify := cfg, appLauncher, hasDisplay, notify
+	defer func() {
+		cfg, appLauncher, hasDisplay, notify = originalCfg, originalLauncher, originalDisplay, originalNotify
+	}()
+	hasDisplay = func() bool { return true }
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	var notifications []string
+	notify = func(title, message string) error {
+		notifications = append(notifications, message)
+		return nil
+	}
+	rec := &recordingLauncher{}
+	appLauncher = rec
+	cfg = &config.Config{
+		DefaultProfileID: "kids",
+		Browsers:         []config.Browser{{Name: "Test Browser", BrowserID: "test", Executable: "/bin/echo"}},
+		Profiles:         []config.Profile{{ID: "kids", BrowserID: "test"}},
+		Rules:            []config.Rule{{Name: "School", Pattern: `^school\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "kids"}},
+		Strict:           config.Strict{Enabled: true, Notify: true},
+		History:          config.History{Enabled: true},
+	}
+
+	require.NoError(t, openURL(context.Background(), "https://school.example.com/", nil))
+	err := openURL(context.Background(), "https://games.example.net/", nil)
+	assert.Equal(t, ExitBlocked, ExitCode(err))
+	assert.Equal(t, []string{"https://school.example.com/"}, rec.urls)
+	assert.Equal(t, []string{"https://games.example.net/ matches no rule (strict mode)"}, notifications)
+
+	// Blocked URLs are recorded in the history
+	path, err := history.DefaultPath()
+	require.NoError(t, err)
+	entries, err := history.Load(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "https://games.example.net/", entries[1].URL)
+	assert.Contains(t, entries[1].Error, "strict mode")
+}
+
+func TestNeedsConfig(t *testing.T) {
+	find := func(args ...string) *cobra.Command {
+		cmd, _, err := rootCmd.Find(args)
+		require.NoError(t, err)
+		return cmd
+	}
+	assert.True(t, needsConfig(rootCmd, []string{"https://example.com"}))
+	assert.False(t, needsConfig(rootCmd, nil))
+	assert.True(t, needsConfig(find("config", "rule", "list"), nil))
+	assert.True(t, needsConfig(find("inspect"), nil))
+	for _, name := range []string{"version", "completion", "purge", "self-update"} {
+		assert.False(t, needsConfig(find(name), nil), name)
+	}
+	assert.False(t, needsConfig(find("config", "init"), nil))
+	assert.False(t, needsConfig(&cobra.Command{Use: cobra.ShellCompRequestCmd}, nil))
+}
+
+func TestLoadConfigOrDefault(t *testing.T) {
+	originalCfg, originalFile := cfg, cfgFile
+	defer func() { cfg, cfgFile = originalCfg, originalFile }()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// The default configuration is used, but not created
+	cfgFile = ""
+	loaded := loadConfigOrDefault()
+	require.NotNil(t, loaded)
+	assert.NotEmpty(t, loaded.Shorteners)
+	path, err := config.DefaultConfigFile()
+	require.NoError(t, err)
+	assert.NoFileExists(t, path)
+	cmd, _, err := rootCmd.Find([]string{"config", "rule", "list"})
+	require.NoError(t, err)
+	initConfig(cmd)
+	assert.NotNil(t, cfg)
+	assert.NoFileExists(t, path)
+
+	cfgFile = filepath.Join(t.TempDir(), "config.toml")
+	existing := config.DefaultConfig()
+	existing.CheckForUpdates = true
+	require.NoError(t, config.SaveConfig(existing, cfgFile))
+	loaded = loadConfigOrDefault()
+	require.NotNil(t, loaded)
+	assert.True(t, loaded.CheckForUpdates)
+}
+
+func TestOpenURLHandler(t *testing.T) {
+	originalCfg, originalLauncher, originalDisplay, originalHandler := cfg, appLauncher, hasDisplay, runHandler
+	defer func() {
+		cfg, appLauncher, hasDisplay, runHandler = originalCfg, originalLauncher, originalDisplay, originalHandler
+	}()
+	// Handlers run without a display too
+	hasDisplay = func() bool { return false }
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	var handled []launcher.HookInfo
+	runHandler = func(template string, info launcher.HookInfo) error {
+		assert.Equal(t, `mpv "%u"`, template)
+		handled = append(handled, info)
+		return nil
+	}
+	rec := &recordingLauncher{}
+	appLauncher = rec
+	cfg = &config.Config{
+		DefaultProfileID: "personal",
+		Browsers:         []config.Browser{{Name: "Test Browser", BrowserID: "test", Executable: "/bin/echo"}},
+		Profiles:         []config.Profile{{ID: "personal", BrowserID: "test"}},
+		Rules:            []config.Rule{{ID: "videos", Name: "Videos", Pattern: `^www\.youtube\.com$`, Scope: config.ScopeDomain, Handler: `mpv "%u"`}},
+	}
+
+	require.NoError(t, openURL(context.Background(), "https://www.youtube.com/watch?v=abc", nil))
+	assert.Empty(t, rec.urls)
+	require.Len(t, handled, 1)
+	assert.Equal(t, "https://www.youtube.com/watch?v=abc", handled[0].URL)
+	assert.Equal(t, launcher.LaunchModeHandler, handled[0].Mode)
+	assert.Equal(t, "Videos", handled[0].RuleName)
+	assert.Equal(t, []string{"mpv", "https://www.youtube.com/watch?v=abc"}, handled[0].Command)
+}
+
+func TestOpenURLSystemFallback(t *testing.T) {
+	originalCfg, originalLauncher, originalDisplay, originalPortal := cfg, appLauncher, hasDisplay, portalOpen
+	defer func() {
+		cfg, appLauncher, hasDisplay, portalOpen = originalCfg, originalLauncher, originalDisplay, originalPortal
+	}()
+	hasDisplay = func() bool { return true }
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv(launcher.SystemFallbackEnv, "")
+
+	var opened []string
+	portalOpen = func(url string) error {
+		opened = append(opened, url)
+		return nil
+	}
+	rec := &recordingLauncher{errs: []error{errors.New("exec: \"chrome\": executable file not found in $PATH")}}
+	appLauncher = rec
+	cfg = &config.Config{
+		DefaultProfileID: "personal",
+		Browsers:         []config.Browser{{Name: "Test Browser", BrowserID: "test", Executable: "chrome"}},
+		Profiles:         []config.Profile{{ID: "personal", BrowserID: "test"}},
+	}
+
+	// Failures are reported while the fallback is disabled
+	assert.Error(t, openURL(context.Background(), "https://example.com/", nil))
+	assert.Empty(t, opened)
+
+	// Once enabled, the desktop's handler opens the URL; direct launches are still tried first
+	cfg.SystemFallback.Enabled = true
+	rec.errs = []error{errors.New("exec: \"chrome\": executable file not found in $PATH")}
+	require.NoError(t, openURL(context.Background(), "https://example.com/", nil))
+	assert.Equal(t, []string{"https://example.com/"}, opened)
+	require.NoError(t, openURL(context.Background(), "https://example.com/direct", nil))
+	assert.Len(t, opened, 1)
+	assert.Len(t, rec.urls, 3)
+
+	// A handler started by an earlier fallback (possibly rurl itself) does not loop
+	t.Setenv(launcher.SystemFallbackEnv, "1")
+	rec.errs = []error{errors.New("exec: \"chrome\": executable file not found in $PATH")}
+	assert.Error(t, openURL(context.Background(), "https://example.com/", nil))
+	assert.Len(t, opened, 1)
+}
+
+func TestOpenURLBroadcast(t *testing.T) {
+	originalCfg, originalLauncher, originalDisplay, originalBroadcast := cfg, appLauncher, hasDisplay, broadcastFlag
+	defer func() {
+		cfg, appLauncher, hasDisplay, broadcastFlag = originalCfg, originalLauncher, originalDisplay, originalBroadcast
+	}()
+	hasDisplay = func() bool { return true }
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	rec := &recordingLauncher{}
+	appLauncher = rec
+	cfg = &config.Config{
+		DefaultProfileID: "personal",
+		Browsers:         []config.Browser{{Name: "Test Browser", BrowserID: "test", Executable: "chrome"}},
+		Profiles: []config.Profile{
+			{ID: "personal", BrowserID: "test"},
+			{ID: "work", BrowserID: "test"},
+			{ID: "testing", BrowserID: "test", AlwaysIncognito: true},
+		},
+		Rules: []config.Rule{{Name: "Docs", Pattern: "docs.example.com", Scope: config.ScopeDomain, ProfileID: "personal", Handler: "mpv %u"}},
+	}
+
+	// The URL opens in every listed profile instead of the routed one (or its handler)
+	broadcastFlag = []string{"work", "testing"}
+	require.NoError(t, openURL(context.Background(), "https://docs.example.com/", nil))
+	require.Len(t, rec.profiles, 2)
+	assert.Equal(t, "work", rec.profiles[0].ID)
+	assert.Equal(t, "testing", rec.profiles[1].ID)
+	assert.Equal(t, []bool{false, true}, rec.incognito)
+
+	// A failed launch does not stop the others, and is reported
+	rec.errs = []error{errors.New("cannot start browser")}
+	err := openURL(context.Background(), "https://example.com/", nil)
+	assert.ErrorContains(t, err, "profile 'work'")
+	assert.Equal(t, ExitLaunch, ExitCode(err))
+	assert.Len(t, rec.urls, 4)
+
+	// Unknown profiles are rejected before anything is launched
+	broadcastFlag = []string{"work", "missing"}
+	err = openURL(context.Background(), "https://example.com/", nil)
+	assert.Equal(t, ExitConfig, ExitCode(err))
+	assert.Len(t, rec.urls, 4)
+}
+
+func TestOpenURLDryRun(t *testing.T) {
+	originalCfg, originalLauncher, originalDisplay, originalDryRun := cfg, appLauncher, hasDisplay, dryRun
+	defer func() {
+		cfg, appLauncher, hasDisplay, dryRun = originalCfg, originalLauncher, originalDisplay, originalDryRun
+	}()
+	hasDisplay = func() bool { return true }
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	marker := filepath.Join(t.TempDir(), "hook-ran")
+	appLauncher = launcher.NewExecLauncher()
+	dryRun = true
+	cfg = &config.Config{
+		DefaultProfileID: "personal",
+		Browsers:         []config.Browser{{Name: "Test Browser", BrowserID: "test", Executable: "/opt/test browser/chrome", ProfileArg: "--profile-directory=%s"}},
+		Profiles:         []config.Profile{{ID: "personal", BrowserID: "test", ProfileDir: "Profile 1"}},
+		Rules:            []config.Rule{{Name: "Video", Pattern: "video.example.com", Scope: config.ScopeDomain, ProfileID: "personal", Handler: "mpv %u"}},
+		Hooks:            config.Hooks{PreLaunch: "touch " + marker},
+		History:          config.History{Enabled: true},
+	}
+
+	// The command is printed, quoted for a shell, instead of being run
+	out := captureStdout(t, func() {
+		require.NoError(t, openURL(context.Background(), "https://example.com/a b", nil))
+	})
+	assert.Contains(t, out, "'/opt/test browser/chrome'")
+	assert.Contains(t, out, "'--profile-directory=Profile 1'")
+	assert.Contains(t, out, "https://example.com/a")
+
+	out = captureStdout(t, func() {
+		require.NoError(t, openURL(context.Background(), "https://video.example.com/watch", nil))
+	})
+	assert.Equal(t, "mpv https://video.example.com/watch\n", out)
+
+	// Neither hooks nor the history see dry runs
+	assert.NoFileExists(t, marker)
+	path, err := history.DefaultPath()
+	require.NoError(t, err)
+	entries, err := history.Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
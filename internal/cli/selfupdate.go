@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/update"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	selfUpdateCheckOnly bool
+	selfUpdateForce     bool
+	selfUpdateYes       bool
+	selfUpdateSkipSig   bool
+
+	// updateClient is used by self-update and the version notice. Tests may replace it.
+	updateClient = update.NewClient(30 * time.Second)
+)
+
+// addSelfUpdateCommand adds the self-update command to the root command
+func addSelfUpdateCommand() {
+	selfUpdateCmd := &cobra.Command{
+		Use:   "self-update",
+		Short: "Update rurl to the latest release",
+		Long: `Checks GitHub for the latest rurl release and, if it is newer than the running
+version, downloads the archive for this platform, verifies it against the release's
+checksums.txt and replaces the current binary in place.
+
+checksums.txt must carry a valid minisign signature from the key built into release
+binaries. Builds without the key (e.g. built from source) refuse to update unless
+--skip-signature is given, in which case only the checksums are checked; that does
+not protect against a compromised release.
+
+Installations managed by a package manager should be updated with that package manager instead.`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{noConfigAnnotation: ""},
+		Run:         runSelfUpdateCmd,
+	}
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateCheckOnly, "check", false, "Only check for a newer version, do not install it")
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateForce, "force", false, "Install the latest release even if it is not newer (e.g. on dev builds)")
+	selfUpdateCmd.Flags().BoolVarP(&selfUpdateYes, "yes", "y", false, "Do not prompt for confirmation")
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateSkipSig, "skip-signature", false, "Install without verifying the signature of checksums.txt (insecure)")
+	rootCmd.AddCommand(selfUpdateCmd)
+}
+
+// runSelfUpdateCmd checks for and installs the latest release
+func runSelfUpdateCmd(cmd *cobra.Command, args []string) {
+	release, err := updateClient.LatestRelease()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to check for updates")
+		errorf("Error checking for updates: %v", err)
+		os.Exit(1)
+	}
+
+	newer := update.IsNewer(release.Version(), config.Version)
+	fmt.Printf("Current version: %s\n", config.Version)
+	fmt.Printf("Latest release:  %s\n", release.Version())
+
+	if !newer && !selfUpdateForce {
+		fmt.Println("rurl is up to date.")
+		return
+	}
+	if selfUpdateCheckOnly {
+		if newer {
+			fmt.Printf("A new version is available: %s\n", release.HTMLURL)
+		}
+		return
+	}
+
+	exePath, err := os.Executable()
+	if err == nil {
+		exePath, err = filepath.EvalSymlinks(exePath)
+	}
+	if err != nil {
+		errorf("Error locating current executable: %v", err)
+		os.Exit(1)
+	}
+
+	if !selfUpdateYes && !promptYesNo(fmt.Sprintf("Replace %s with version %s?", exePath, release.Version()), true) {
+		statusf("Update cancelled.")
+		return
+	}
+
+	updateClient.SkipSignature = selfUpdateSkipSig
+	binary, err := updateClient.DownloadBinary(release)
+	if err != nil {
+		log.Error().Err(err).Str("release", release.TagName).Msg("Failed to download update")
+		errorf("Error downloading update: %v", err)
+		os.Exit(1)
+	}
+
+	if err := update.ReplaceExecutable(exePath, binary); err != nil {
+		log.Error().Err(err).Str("path", exePath).Msg("Failed to install update")
+		errorf("Error installing update: %v", err)
+		os.Exit(1)
+	}
+
+	log.Info().Str("version", release.Version()).Str("path", exePath).Msg("rurl updated")
+	statusf("Updated rurl to %s.", release.Version())
+}
+
+// printUpdateNotice prints a notice if a newer release is available.
+// It is best-effort: failures are logged at debug level and otherwise ignored.
+func printUpdateNotice() {
+	httpClient := *updateClient.HTTPClient
+	httpClient.Timeout = 3 * time.Second
+	client := update.Client{APIURL: updateClient.APIURL, HTTPClient: &httpClient}
+
+	release, err := client.LatestRelease()
+	if err != nil {
+		log.Debug().Err(err).Msg("Update check failed")
+		return
+	}
+	if update.IsNewer(release.Version(), config.Version) {
+		fmt.Printf("\nA new version of rurl is available: %s (run 'rurl self-update')\n", release.Version())
+	}
+}
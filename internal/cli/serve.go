@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmylchreest/rurl/internal/metrics"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// defaultServePort is the port 'rurl serve' listens on if neither --port nor
+// serve.port is set.
+const defaultServePort = 7777
+
+var (
+	servePort        int
+	serveToken       string
+	serveMetricsAddr string
+)
+
+// addServeCommand adds the serve command to the root command
+func addServeCommand() {
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Open URLs handed to a local HTTP endpoint",
+		Long: `Listens on 127.0.0.1 for URLs to open, so that browser extensions, launcher
+scripts (Raycast, Alfred, ...) and other programs can hand links to rurl without
+starting a process:
+
+  curl -H "Authorization: Bearer $TOKEN" "http://127.0.0.1:7777/open?url=https%3A%2F%2Fexample.com"
+
+GET and POST requests to /open take the URL as the url parameter and route it exactly
+like 'rurl <url>'. Requests must present the token, as a bearer token or as the token
+parameter. It is taken from --token or serve.token in the configuration; if neither is
+set, a random token is generated and printed at startup. URLs the resolution policy
+would ask about are not launched, as there is no one to ask.
+
+With --metrics-addr (or serve.metrics_addr), Prometheus metrics are served at /metrics
+on that loopback address, without a token: launches and launch failures per profile
+and rule, URLs that could not be routed, and shortener resolutions and their latency.`,
+		Args: cobra.NoArgs,
+		Run:  runServeCmd,
+	}
+	serveCmd.Flags().IntVar(&servePort, "port", 0, fmt.Sprintf("Port to listen on (default serve.port, or %d)", defaultServePort))
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "Token requests must present (default serve.token, or a random one)")
+	serveCmd.Flags().StringVar(&serveMetricsAddr, "metrics-addr", "", "Loopback address to serve Prometheus metrics on, e.g. 127.0.0.1:9777 (default serve.metrics_addr)")
+	rootCmd.AddCommand(serveCmd)
+}
+
+// runServeCmd serves the /open endpoint until interrupted
+func runServeCmd(cmd *cobra.Command, args []string) {
+	if cfg == nil {
+		log.Error().Msg("Configuration not loaded.")
+		os.Exit(ExitConfig)
+	}
+
+	port := servePort
+	if port == 0 {
+		port = cfg.Serve.Port
+	}
+	if port == 0 {
+		port = defaultServePort
+	}
+	token := serveToken
+	if token == "" {
+		token = cfg.Serve.Token
+	}
+	if token == "" {
+		var err error
+		if token, err = randomToken(); err != nil {
+			errorf("Error: %v", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Token: %s\n", token)
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		errorf("Error: %v", err)
+		os.Exit(1)
+	}
+	server := &http.Server{Handler: newServeHandler(token), ReadHeaderTimeout: 10 * time.Second}
+	servers := []*http.Server{server}
+
+	metricsAddr := serveMetricsAddr
+	if metricsAddr == "" {
+		metricsAddr = cfg.Serve.MetricsAddr
+	}
+	if metricsAddr != "" {
+		metricsListener, err := listenMetrics(metricsAddr)
+		if err != nil {
+			errorf("Error: %v", err)
+			os.Exit(1)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Enable().Handler())
+		metricsServer := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		servers = append(servers, metricsServer)
+		go func() {
+			if err := metricsServer.Serve(metricsListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error().Err(err).Msg("Metrics server failed")
+			}
+		}()
+		statusf("Serving metrics on http://%s/metrics", metricsListener.Addr())
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for _, s := range servers {
+			_ = s.Shutdown(shutdownCtx)
+		}
+	}()
+
+	statusf("Listening on http://%s/open", listener.Addr())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errorf("Error: %v", err)
+		os.Exit(1)
+	}
+}
+
+// listenMetrics listens on addr for the metrics endpoint, which has no token and is
+// therefore refused on anything but a loopback address.
+func listenMetrics(addr string) (net.Listener, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metrics address '%s': %w", addr, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("metrics address '%s' is not a loopback address", addr)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// randomToken returns a random token for a run of 'rurl serve'.
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newServeHandler returns the handler of 'rurl serve': /open opens the url parameter
+// of requests presenting token through the normal pipeline, one URL at a time.
+func newServeHandler(token string) http.Handler {
+	var mu sync.Mutex
+	mux := http.NewServeMux()
+	mux.HandleFunc("/open", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !hasServeToken(r, token) {
+			http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+			return
+		}
+		target := r.FormValue("url")
+		if target == "" {
+			http.Error(w, "missing url parameter", http.StatusBadRequest)
+			return
+		}
+
+		ctx := r.Context()
+		if routeTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeoutCause(ctx, routeTimeout, fmt.Errorf("timed out after %s", routeTimeout))
+			defer cancel()
+		}
+		mu.Lock()
+		err := openURL(ctx, target, func(string) bool { return false })
+		mu.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// hasServeToken reports whether r presents token, as a bearer token or the token
+// parameter.
+func hasServeToken(r *http.Request, token string) bool {
+	presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		presented = r.FormValue("token")
+	}
+	return subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
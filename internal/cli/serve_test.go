@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeHandler(t *testing.T) {
+	originalCfg, originalLauncher, originalDisplay := cfg, appLauncher, hasDisplay
+	defer func() { cfg, appLauncher, hasDisplay = originalCfg, originalLauncher, originalDisplay }()
+	hasDisplay = func() bool { return true }
+
+	rec := &recordingLauncher{}
+	appLauncher = rec
+	cfg = &config.Config{
+		DefaultProfileID: "personal",
+		Browsers:         []config.Browser{{Name: "Test Browser", BrowserID: "test", Executable: "/bin/echo"}},
+		Profiles: []config.Profile{
+			{ID: "personal", Name: "Personal", BrowserID: "test"},
+			{ID: "work", Name: "Work", BrowserID: "test"},
+		},
+		Rules: []config.Rule{
+			{Name: "Work", Pattern: `^work\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "work"},
+		},
+	}
+	handler := newServeHandler("s3cret")
+	openQuery := "/open?url=" + url.QueryEscape("https://work.example.com/")
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// Requests without the right token are refused
+	assert.Equal(t, http.StatusUnauthorized, serve(httptest.NewRequest(http.MethodGet, openQuery, nil)).Code)
+	req := httptest.NewRequest(http.MethodGet, openQuery, nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	assert.Equal(t, http.StatusUnauthorized, serve(req).Code)
+	assert.Empty(t, rec.urls)
+
+	// A bearer token or token parameter opens the URL through the rules
+	req = httptest.NewRequest(http.MethodGet, openQuery, nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	assert.Equal(t, http.StatusNoContent, serve(req).Code)
+	req = httptest.NewRequest(http.MethodPost, "/open", strings.NewReader("token=s3cret&url="+url.QueryEscape("https://other.example.com/")))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	assert.Equal(t, http.StatusNoContent, serve(req).Code)
+	assert.Equal(t, []string{"https://work.example.com/", "https://other.example.com/"}, rec.urls)
+	if assert.Len(t, rec.profiles, 2) {
+		assert.Equal(t, "work", rec.profiles[0].ID)
+		assert.Equal(t, "personal", rec.profiles[1].ID)
+	}
+
+	// Missing URLs, other methods and failed launches are reported
+	assert.Equal(t, http.StatusBadRequest, serve(httptest.NewRequest(http.MethodGet, "/open?token=s3cret", nil)).Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(httptest.NewRequest(http.MethodDelete, "/open?token=s3cret", nil)).Code)
+	rec.errs = []error{errors.New("browser not found")}
+	w := serve(httptest.NewRequest(http.MethodGet, openQuery+"&token=s3cret", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "browser not found")
+}
+
+func TestListenMetrics(t *testing.T) {
+	listener, err := listenMetrics("127.0.0.1:0")
+	if assert.NoError(t, err) {
+		listener.Close()
+	}
+
+	// Metrics have no token, so only loopback addresses are accepted
+	_, err = listenMetrics("0.0.0.0:9777")
+	assert.ErrorContains(t, err, "not a loopback address")
+	_, err = listenMetrics(":9777")
+	assert.ErrorContains(t, err, "not a loopback address")
+	_, err = listenMetrics("127.0.0.1")
+	assert.ErrorContains(t, err, "invalid metrics address")
+}
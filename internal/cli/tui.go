@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/jmylchreest/rurl/internal/history"
+	"github.com/jmylchreest/rurl/internal/tui"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// addTUICommand adds the tui command to the root command
+func addTUICommand() {
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "tui",
+		Short: "Manage browsers, profiles, rules and shorteners in a full-screen interface",
+		Long: `Opens a full-screen terminal interface to browse and edit browsers, profiles,
+rules and manual shorteners, test URLs against the rules and view recent launches.
+
+Changes are kept in memory until saved with 's'.`,
+		Args: cobra.NoArgs,
+		Run:  runTUICmd,
+	})
+}
+
+// runTUICmd starts the terminal interface
+func runTUICmd(cmd *cobra.Command, args []string) {
+	if cfg == nil {
+		log.Error().Msg("Configuration not loaded.")
+		os.Exit(ExitConfig)
+	}
+
+	// The history tab stays empty unless launch history is enabled
+	var historyPath string
+	if cfg.History.Enabled {
+		path, err := history.DefaultPath()
+		if err != nil {
+			log.Warn().Err(err).Msg("Could not determine history path")
+		}
+		historyPath = path
+	}
+
+	save := saveConfig
+	if err := tui.Run(cfg, save, historyPath); err != nil {
+		errorf("Error running TUI: %v", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+// parseAge parses an age given to --unused-since: a Go duration such as "36h", or a
+// number of days such as "90d".
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age '%s' (expected e.g. 90d or 36h)", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age '%s' (expected e.g. 90d or 36h)", s)
+	}
+	return d, nil
+}
+
+// listUnused prints the rules and profiles not used for age, as counted in the usage
+// file.
+func listUnused(cmd *cobra.Command, cfg *config.Config, age string) error {
+	d, err := parseAge(age)
+	if err != nil {
+		return err
+	}
+	path, err := usage.DefaultPath()
+	if err != nil {
+		return err
+	}
+	stats, err := usage.Load(path)
+	if err != nil {
+		return err
+	}
+	if stats.Since.IsZero() {
+		if !cfg.Usage.Enabled {
+			return fmt.Errorf("usage is not counted; set enabled = true under [usage] and check again later")
+		}
+		return fmt.Errorf("no usage has been counted yet")
+	}
+	if !cfg.Usage.Enabled {
+		statusf("Warning: usage counting is disabled; the counts stop at the last time it was enabled.")
+	}
+
+	defer startPager(cmd)()
+	printUnused(os.Stdout, cfg, stats, time.Now().Add(-d))
+	return nil
+}
+
+// printUnused writes the rules and profiles of c that stats records no use of at or
+// after cutoff. Profiles still used as the default or by rules are listed with a note,
+// as deleting them would leave those references dangling.
+func printUnused(w io.Writer, c *config.Config, stats *usage.Stats, cutoff time.Time) {
+	day := cutoff.Format(time.DateOnly)
+	if stats.Since.After(cutoff) {
+		fmt.Fprintf(w, "Note: usage is only counted since %s, so entries may be listed that were used before.\n", stats.Since.Format(time.DateOnly))
+	}
+
+	fmt.Fprintf(w, "\n--- Rules unused since %s ---\n", day)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tName\tProfile ID\tHits\tLast Used")
+	fmt.Fprintln(tw, "--\t----\t----------\t----\t---------")
+	unused := 0
+	for _, r := range c.Rules {
+		if !usage.UnusedSince(stats.Rules, r.ID, cutoff) {
+			continue
+		}
+		counter := stats.Rules[r.ID]
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", r.ID, r.Name, r.ProfileID, counter.Hits, lastUsed(counter))
+		unused++
+	}
+	if unused == 0 {
+		fmt.Fprintln(tw, "(None)")
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n--- Profiles unused since %s ---\n", day)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tBrowser ID\tHits\tLast Used\tNote")
+	fmt.Fprintln(tw, "--\t----------\t----\t---------\t----")
+	unused = 0
+	for _, p := range c.Profiles {
+		if !usage.UnusedSince(stats.Profiles, p.ID, cutoff) {
+			continue
+		}
+		counter := stats.Profiles[p.ID]
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", p.ID, p.BrowserID, counter.Hits, lastUsed(counter), profileReferences(c, p.ID))
+		unused++
+	}
+	if unused == 0 {
+		fmt.Fprintln(tw, "(None)")
+	}
+	tw.Flush()
+}
+
+// lastUsed returns the date counter was last used, or "never".
+func lastUsed(counter usage.Counter) string {
+	if counter.LastUsed.IsZero() {
+		return "never"
+	}
+	return counter.LastUsed.Local().Format(time.DateOnly)
+}
+
+// profileReferences describes what in c refers to the profile id, or "-" if nothing.
+func profileReferences(c *config.Config, id string) string {
+	var refs []string
+	if c.DefaultProfileID == id {
+		refs = append(refs, "default profile")
+	}
+	n := 0
+	for _, r := range c.Rules {
+		if r.ProfileID == id {
+			n++
+		}
+	}
+	if n > 0 {
+		refs = append(refs, fmt.Sprintf("used by %d rule(s)", n))
+	}
+	if len(refs) == 0 {
+		return "-"
+	}
+	return strings.Join(refs, ", ")
+}
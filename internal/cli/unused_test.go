@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/usage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAge(t *testing.T) {
+	d, err := parseAge("90d")
+	require.NoError(t, err)
+	assert.Equal(t, 90*24*time.Hour, d)
+	d, err = parseAge("36h")
+	require.NoError(t, err)
+	assert.Equal(t, 36*time.Hour, d)
+
+	for _, in := range []string{"", "d", "-3d", "3w", "-1h"} {
+		_, err := parseAge(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestLaunchCountsUsage(t *testing.T) {
+	originalCfg, originalLauncher, originalDisplay := cfg, appLauncher, hasDisplay
+	defer func() { cfg, appLauncher, hasDisplay = originalCfg, originalLauncher, originalDisplay }()
+	hasDisplay = func() bool { return true }
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	appLauncher = &recordingLauncher{}
+	cfg = &config.Config{
+		DefaultProfileID: "personal",
+		Browsers:         []config.Browser{{Name: "Test Browser", BrowserID: "test", Executable: "/bin/echo"}},
+		Profiles: []config.Profile{
+			{ID: "personal", BrowserID: "test"},
+			{ID: "work", BrowserID: "test"},
+			{ID: "old", BrowserID: "test"},
+		},
+		Rules: []config.Rule{
+			{ID: "rule-work", Name: "Work", Pattern: `^work\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "work"},
+			{ID: "rule-old", Name: "Old", Pattern: `^old\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "old"},
+		},
+		Usage: config.Usage{Enabled: true},
+	}
+
+	require.NoError(t, openURL(context.Background(), "https://work.example.com/", nil))
+	require.NoError(t, openURL(context.Background(), "https://other.example.com/", nil))
+
+	path, err := usage.DefaultPath()
+	require.NoError(t, err)
+	stats, err := usage.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"rule-work": 1}, hits(stats.Rules))
+	assert.Equal(t, map[string]int{"work": 1, "personal": 1}, hits(stats.Profiles))
+
+	// Since counting started after the cutoff, a note warns the list may be incomplete
+	var out bytes.Buffer
+	printUnused(&out, cfg, stats, time.Now().Add(-90*24*time.Hour))
+	assert.Contains(t, out.String(), "Note: usage is only counted since")
+	assert.Regexp(t, `rule-old\s+Old\s+old\s+0\s+never`, out.String())
+	assert.NotContains(t, out.String(), "rule-work")
+	assert.Regexp(t, `old\s+test\s+0\s+never\s+used by 1 rule\(s\)`, out.String())
+	assert.NotRegexp(t, `(?m)^personal`, out.String())
+}
+
+// hits returns the hit counts of counters.
+func hits(counters map[string]usage.Counter) map[string]int {
+	out := make(map[string]int, len(counters))
+	for id, c := range counters {
+		out[id] = c.Hits
+	}
+	return out
+}
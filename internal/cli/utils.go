@@ -226,12 +226,18 @@ func promptYesNo(promptText string, defaultYes bool) bool {
 
 // --- Printing Helpers ---
 
-// printBrowserList handles the actual printing of the browser list using tabwriter
-func printBrowserList(cfg *config.Config) {
+// printBrowserList handles the actual printing of the browser list using tabwriter,
+// listing the browsers selected by opts
+func printBrowserList(cfg *config.Config, opts listOptions) {
 	if cfg == nil || len(cfg.Browsers) == 0 {
 		fmt.Println("No browsers configured. Run 'rurl config detect-browsers --save' or 'rurl config browser add'.")
 		return
 	}
+	browsers := browserColumns.apply(cfg.Browsers, opts)
+	if len(browsers) == 0 {
+		fmt.Println("No browsers match the filter.")
+		return
+	}
 
 	fmt.Println("\n--- Browsers ---")
 
@@ -239,15 +245,20 @@ func printBrowserList(cfg *config.Config) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) // minwidth, tabwidth, padding, padchar, flags
 
 	// Print header
-	fmt.Fprintln(w, "ID\tName\tExecutable\tProfile Arg\tIncognito Arg")
-	fmt.Fprintln(w, "--\t----\t----------\t------------\t--------------")
+	fmt.Fprintln(w, "ID\tName\tSource\tExecutable\tProfile Arg\tIncognito Arg")
+	fmt.Fprintln(w, "--\t----\t------\t----------\t------------\t--------------")
 
 	// Print rows
-	for _, b := range cfg.Browsers {
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+	for _, b := range browsers {
+		executable := b.Executable
+		if b.Remote != nil {
+			executable = "ssh " + b.Remote.Host
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
 			b.BrowserID,
 			b.Name,
-			b.Executable,
+			b.InstallSource,
+			executable,
 			b.ProfileArg,
 			b.IncognitoArg,
 		)
@@ -257,12 +268,18 @@ func printBrowserList(cfg *config.Config) {
 	w.Flush()
 }
 
-// printProfileList handles the actual printing of the profile list using tabwriter
-func printProfileList(cfg *config.Config) {
+// printProfileList handles the actual printing of the profile list using tabwriter,
+// listing the profiles selected by opts
+func printProfileList(cfg *config.Config, opts listOptions) {
 	if cfg == nil || len(cfg.Profiles) == 0 {
 		fmt.Println("No profiles configured. Run 'rurl config profile add'.")
 		return
 	}
+	profiles := profileColumns.apply(cfg.Profiles, opts)
+	if len(profiles) == 0 {
+		fmt.Println("No profiles match the filter.")
+		return
+	}
 
 	fmt.Println("\n--- Profiles ---")
 
@@ -273,20 +290,21 @@ func printProfileList(cfg *config.Config) {
 	cyan := color.New(color.FgCyan).SprintFunc()
 
 	// Print header
-	fmt.Fprintln(w, "ID\tName\tBrowser ID\tDirectory\tDefault")
-	fmt.Fprintln(w, "--\t----\t----------\t----------\t-------")
+	fmt.Fprintln(w, "ID\tName\tBrowser ID\tDirectory\tEmail\tDefault")
+	fmt.Fprintln(w, "--\t----\t----------\t----------\t-----\t-------")
 
 	// Print rows
-	for _, p := range cfg.Profiles {
+	for _, p := range profiles {
 		defaultMarker := ""
 		if cfg.DefaultProfileID == p.ID {
 			defaultMarker = cyan("[DEFAULT]")
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
 			p.ID,
 			p.Name,
 			p.BrowserID,
 			p.ProfileDir,
+			p.Email,
 			defaultMarker,
 		)
 	}
@@ -295,50 +313,87 @@ func printProfileList(cfg *config.Config) {
 	w.Flush()
 }
 
-// printRuleList displays the configured rules using a tabwriter
-func printRuleList(cfg *config.Config) {
+// printRuleList displays the configured rules using a tabwriter. With tags, only the
+// rules carrying all of them are listed, of those selected by opts.
+func printRuleList(cfg *config.Config, tags []string, opts listOptions) {
 	fmt.Println("\n--- Rules ---")
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "Name\tPattern\tScope\tProfile ID\tIncognito\tType")
-	fmt.Fprintln(w, "----\t-------\t-----\t----------\t----------\t----")
-
-	// Display the Default Rule first
-	defaultProfileDisplay := "<none set>"
-	if cfg.DefaultProfileID != "" {
-		// Check if default profile actually exists, otherwise show it as invalid
-		if _, err := cfg.FindProfileByID(cfg.DefaultProfileID); err == nil {
-			defaultProfileDisplay = cfg.DefaultProfileID
-		} else {
-			defaultProfileDisplay = fmt.Sprintf("%s (invalid!)", cfg.DefaultProfileID)
+	fmt.Fprintln(w, "ID\tName\tPattern\tScope\tProfile ID\tIncognito\tEnabled\tType\tTags\tDescription")
+	fmt.Fprintln(w, "--\t----\t-------\t-----\t----------\t----------\t-------\t----\t----\t-----------")
+
+	// Display the Default Rule first, unless rules are selected
+	if len(tags) == 0 && opts.filter == nil {
+		defaultProfileDisplay := "<none set>"
+		if cfg.DefaultProfileID != "" {
+			// Check if default profile actually exists, otherwise show it as invalid
+			if _, err := cfg.FindProfileByID(cfg.DefaultProfileID); err == nil {
+				defaultProfileDisplay = cfg.DefaultProfileID
+			} else {
+				defaultProfileDisplay = fmt.Sprintf("%s (invalid!)", cfg.DefaultProfileID)
+			}
 		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\t%t\t%s\t%s\t%s\n",
+			"-",             // The default rule has no ID
+			defaultRuleName, // Assumes defaultRuleName is accessible (it's in config_rules.go)
+			".*",            // Matches everything
+			"url",           // Default rule always matches full URL
+			defaultProfileDisplay,
+			false, // Default rule is never incognito
+			true,  // Default rule cannot be disabled
+			"Built-in",
+			"-",
+			"Used when no rule matches",
+		)
 	}
-	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\n",
-		defaultRuleName, // Assumes defaultRuleName is accessible (it's in config_rules.go)
-		".*",            // Matches everything
-		"url",           // Default rule always matches full URL
-		defaultProfileDisplay,
-		false, // Default rule is never incognito
-		"Built-in",
-	)
 
 	// Display user-defined rules
-	if len(cfg.Rules) == 0 {
+	listed := ruleColumns.apply(rulesWithTags(cfg.Rules, tags), opts)
+	switch {
+	case len(cfg.Rules) == 0:
 		fmt.Fprintln(w, "(No user-defined rules)")
-	} else {
-		for _, r := range cfg.Rules {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\n",
-				r.Name,
-				r.Pattern,
-				r.Scope,
-				r.ProfileID,
-				r.Incognito,
-				"User",
-			)
+	case len(listed) == 0:
+		fmt.Fprintln(w, "(No rules match the filter)")
+	}
+	for _, r := range listed {
+		ruleTags := "-"
+		if len(r.Tags) > 0 {
+			ruleTags = strings.Join(r.Tags, ", ")
 		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\t%t\t%s\t%s\t%s\n",
+			r.ID,
+			r.Name,
+			r.Pattern,
+			r.Scope,
+			r.ProfileID,
+			r.Incognito,
+			r.IsEnabled(),
+			"User",
+			ruleTags,
+			r.Description,
+		)
 	}
 	w.Flush()
 }
 
+// rulesWithTags returns the rules carrying all of tags (ignoring case), or all rules if
+// tags is empty.
+func rulesWithTags(rules []config.Rule, tags []string) []config.Rule {
+	var matching []config.Rule
+	for _, r := range rules {
+		hasAll := true
+		for _, tag := range tags {
+			if !r.HasTag(tag) {
+				hasAll = false
+				break
+			}
+		}
+		if hasAll {
+			matching = append(matching, r)
+		}
+	}
+	return matching
+}
+
 // --- Validation Helpers ---
 
 // validateExecutable checks if a file exists and is executable
@@ -12,10 +12,14 @@ func init() {
 }
 
 var versionCmd = &cobra.Command{
-	Use:   "version",
-	Short: "Print the version information",
-	Long:  `Display version, build date, and git commit information for rurl.`,
+	Use:         "version",
+	Short:       "Print the version information",
+	Long:        `Display version, build date, and git commit information for rurl.`,
+	Annotations: map[string]string{noConfigAnnotation: ""},
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Printf("rurl %s\n", config.GetVersionInfo())
+		if c := loadConfigOrDefault(); c != nil && c.CheckForUpdates {
+			printUpdateNotice()
+		}
 	},
 }
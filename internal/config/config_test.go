@@ -1,10 +1,14 @@
 package config
 
 import (
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -92,6 +96,7 @@ func TestFindProfileByID(t *testing.T) {
 				Name:       "Profile 2",
 				BrowserID:  "browser2",
 				ProfileDir: "dir2",
+				Email:      "me@example.com",
 			},
 		},
 	}
@@ -102,10 +107,17 @@ func TestFindProfileByID(t *testing.T) {
 	assert.NotNil(t, profile)
 	assert.Equal(t, "Profile 1", profile.Name)
 
+	// Test finding a profile by the account signed in to it
+	profile, err = cfg.FindProfileByID("email:Me@Example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "profile2", profile.ID)
+
 	// Test finding non-existent profile
 	profile, err = cfg.FindProfileByID("nonexistent")
 	assert.Error(t, err)
 	assert.Nil(t, profile)
+	_, err = cfg.FindProfileByID("email:other@example.com")
+	assert.Error(t, err)
 }
 
 func TestFindBrowserByID(t *testing.T) {
@@ -139,3 +151,581 @@ func TestFindBrowserByID(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, browser)
 }
+
+func TestRuleEnabledRoundTrip(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	disabled := false
+	cfg := DefaultConfig()
+	cfg.Rules = []Rule{
+		{Name: "On", Pattern: "a", Scope: ScopeDomain, ProfileID: "p"},
+		{Name: "Off", Pattern: "b", Scope: ScopeDomain, ProfileID: "p", Enabled: &disabled},
+	}
+	require.NoError(t, SaveConfig(cfg, configPath))
+
+	loaded, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	require.Len(t, loaded.Rules, 2)
+	assert.True(t, loaded.Rules[0].IsEnabled())
+	assert.False(t, loaded.Rules[1].IsEnabled())
+}
+
+func TestProfileAvatarIndexRoundTrip(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	avatar := 0 // The first avatar, not "unknown"
+	cfg := DefaultConfig()
+	cfg.Profiles = []Profile{
+		{ID: "known", BrowserID: "chrome", AvatarIndex: &avatar},
+		{ID: "unknown", BrowserID: "chrome"},
+	}
+	require.NoError(t, SaveConfig(cfg, configPath))
+
+	loaded, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	require.Len(t, loaded.Profiles, 2)
+	require.NotNil(t, loaded.Profiles[0].AvatarIndex)
+	assert.Equal(t, 0, *loaded.Profiles[0].AvatarIndex)
+	assert.Nil(t, loaded.Profiles[1].AvatarIndex)
+}
+
+func TestRuleEffectiveWindowMode(t *testing.T) {
+	assert.Empty(t, Rule{}.EffectiveWindowMode())
+	assert.Equal(t, WindowNew, Rule{NewWindow: true}.EffectiveWindowMode())
+	assert.Equal(t, WindowNewTab, Rule{WindowMode: WindowNewTab}.EffectiveWindowMode())
+}
+
+func TestRuleTags(t *testing.T) {
+	assert.Equal(t, []string{"work", "sso"}, ParseTags(" work, ,sso,Work "))
+	assert.Nil(t, ParseTags(""))
+
+	rule := Rule{Tags: []string{"Work", "sso"}}
+	assert.True(t, rule.HasTag("work"))
+	assert.False(t, rule.HasTag("personal"))
+
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	cfg := DefaultConfig()
+	cfg.Rules = []Rule{{Name: "Work", Pattern: "a", Scope: ScopeDomain, ProfileID: "p", Description: "Why", Tags: []string{"work"}}}
+	require.NoError(t, SaveConfig(cfg, configPath))
+	loaded, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	require.Len(t, loaded.Rules, 1)
+	assert.Equal(t, "Why", loaded.Rules[0].Description)
+	assert.Equal(t, []string{"work"}, loaded.Rules[0].Tags)
+}
+
+func TestEnvKeyCaseRoundTrip(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	cfg := DefaultConfig()
+	cfg.Browsers = []Browser{{Name: "Chrome", BrowserID: "chrome", Executable: "/usr/bin/chrome", Env: map[string]string{"LANG": "en_GB.UTF-8"}}}
+	cfg.Profiles = []Profile{{ID: "work", Name: "Work", BrowserID: "chrome", Env: map[string]string{"HTTPS_PROXY": "a", "no_proxy": "b"}}}
+	require.NoError(t, SaveConfig(cfg, configPath))
+
+	loaded, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"LANG": "en_GB.UTF-8"}, loaded.Browsers[0].Env)
+	assert.Equal(t, map[string]string{"HTTPS_PROXY": "a", "no_proxy": "b"}, loaded.Profiles[0].Env)
+}
+
+func TestConfigFormatsRoundTrip(t *testing.T) {
+	disabled := false
+	for _, name := range []string{"config.toml", "config.yaml", "config.yml", "config.json"} {
+		t.Run(name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), name)
+			cfg := DefaultConfig()
+			cfg.DefaultProfileID = "work"
+			cfg.Overrides = map[string]string{"https://Docs.example.com/d/Q3.pdf": "work", "http://[::1]:8080/": "work"}
+			cfg.Browsers = []Browser{{Name: "Chrome", BrowserID: "chrome", Executable: "/usr/bin/chrome", Env: map[string]string{"LANG": "en_GB.UTF-8"}}}
+			cfg.Profiles = []Profile{{ID: "work", Name: "Work", BrowserID: "chrome", ProfileDir: "Profile 1", Env: map[string]string{"HTTPS_PROXY": "a"}}}
+			cfg.Rules = []Rule{
+				{Name: "Docs", Pattern: `^docs\.example\.com$`, Scope: ScopeDomain, ProfileID: "work", Incognito: true, Tags: []string{"work"}},
+				{Name: "Off", Scope: ScopePath, ProfileID: "work", Enabled: &disabled, Match: MatchAny,
+					Conditions: []Condition{{Pattern: "^/a", Scope: ScopePath}, {Pattern: "10.0.0.0/8", Scope: ScopeCIDR}}},
+			}
+			require.NoError(t, SaveConfig(cfg, configPath))
+
+			loaded, err := LoadConfig(configPath)
+			require.NoError(t, err)
+			assert.Equal(t, "work", loaded.DefaultProfileID)
+			assert.Equal(t, cfg.Overrides, loaded.Overrides)
+			assert.Equal(t, cfg.Browsers[0].Env, loaded.Browsers[0].Env)
+			assert.Equal(t, cfg.Profiles, loaded.Profiles)
+			require.Len(t, loaded.Rules, 2)
+			assert.Equal(t, "docs", loaded.Rules[0].ID)
+			assert.Equal(t, cfg.Rules[0].Pattern, loaded.Rules[0].Pattern)
+			assert.Equal(t, []string{"work"}, loaded.Rules[0].Tags)
+			assert.True(t, loaded.Rules[0].Incognito)
+			assert.False(t, loaded.Rules[1].IsEnabled())
+			assert.Equal(t, cfg.Rules[1].Conditions, loaded.Rules[1].Conditions)
+		})
+	}
+}
+
+func TestLoadConfigYAMLAnchors(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`# Hand-written, with comments and anchors
+default_profile_id: work
+browsers:
+  - name: Chrome
+    BrowserID: chrome
+    executable: /usr/bin/chrome
+profiles:
+  - id: work
+    name: Work
+    BrowserID: chrome
+    Env: &proxy
+      HTTPS_PROXY: http://proxy.example:3128
+  - id: other
+    name: Other
+    BrowserID: chrome
+    Env: *proxy
+`), 0600))
+
+	loaded, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	require.Len(t, loaded.Profiles, 2)
+	assert.Equal(t, map[string]string{"HTTPS_PROXY": "http://proxy.example:3128"}, loaded.Profiles[1].Env)
+}
+
+func TestDefaultConfigFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir, err := GetConfigDir()
+	require.NoError(t, err)
+
+	path, err := DefaultConfigFile()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "config.toml"), path)
+
+	// An existing YAML file is used, and saved back as YAML
+	yamlPath := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.MkdirAll(dir, 0750))
+	require.NoError(t, os.WriteFile(yamlPath, []byte("default_profile_id: work\n"), 0600))
+	path, err = DefaultConfigFile()
+	require.NoError(t, err)
+	assert.Equal(t, yamlPath, path)
+
+	cfg, err := LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, "work", cfg.DefaultProfileID)
+	cfg.DefaultProfileID = "personal"
+	require.NoError(t, SaveConfig(cfg, ""))
+	data, err := os.ReadFile(yamlPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "default_profile_id: personal")
+	assert.NoFileExists(t, filepath.Join(dir, "config.toml"))
+
+	_, err = LoadConfig(filepath.Join(dir, "config.ini"))
+	assert.ErrorContains(t, err, "unsupported config file format '.ini'")
+}
+
+func TestInitConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path, err := DefaultConfigFile()
+	require.NoError(t, err)
+
+	// Without a config file, the defaults are used in memory
+	inMemory, err := LoadConfigOrDefault("")
+	require.NoError(t, err)
+	assert.NotEmpty(t, inMemory.Shorteners)
+	assert.NoFileExists(t, path)
+	t.Setenv(NoCreateConfigEnv, "1")
+	_, err = LoadConfig("")
+	require.NoError(t, err)
+	assert.NoFileExists(t, path)
+
+	created, err := InitConfig("", false)
+	require.NoError(t, err)
+	assert.Equal(t, path, created)
+	loaded, err := LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, inMemory.Shorteners, loaded.Shorteners)
+	assert.Equal(t, inMemory.History, loaded.History)
+	assert.Equal(t, inMemory.Headless, loaded.Headless)
+
+	// An existing file is only replaced with --force
+	loaded.DefaultProfileID = "work"
+	require.NoError(t, SaveConfig(loaded, ""))
+	_, err = InitConfig("", false)
+	assert.ErrorIs(t, err, fs.ErrExist)
+	_, err = InitConfig("", true)
+	require.NoError(t, err)
+	loaded, err = LoadConfig("")
+	require.NoError(t, err)
+	assert.Empty(t, loaded.DefaultProfileID)
+}
+
+func TestRemoteTargetRoundTrip(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	cfg := DefaultConfig()
+	remote := &RemoteTarget{Host: "me@desktop", Port: 2222, IdentityFile: "~/.ssh/rurl", Command: "DISPLAY=:0 rurl {url}"}
+	cfg.Browsers = []Browser{
+		{Name: "Desktop", BrowserID: "desktop", Remote: remote},
+		{Name: "Chrome", BrowserID: "chrome", Executable: "/usr/bin/chrome"},
+	}
+	require.NoError(t, SaveConfig(cfg, configPath))
+
+	loaded, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	require.Len(t, loaded.Browsers, 2)
+	assert.Equal(t, remote, loaded.Browsers[0].Remote)
+	assert.Nil(t, loaded.Browsers[1].Remote)
+}
+
+func TestResolveConfigPathNonDefault(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := filepath.Join(t.TempDir(), "srv", "rurl")
+	configPath := filepath.Join(dir, "config.toml")
+	cfg := DefaultConfig()
+	cfg.URLLists = []URLList{{Name: "company", File: "company-domains.txt"}}
+	require.NoError(t, SaveConfig(cfg, configPath))
+
+	// Relative paths are relative to the file loaded, not the default directory
+	loaded, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	path, err := loaded.URLListPath(loaded.URLLists[0])
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "company-domains.txt"), path)
+	assert.Equal(t, filepath.Join(dir, "lists", "lab.txt"), loaded.ResolveConfigPath(filepath.Join("lists", "lab.txt")))
+
+	// Configurations not loaded from a file use the default directory
+	defaultDir, err := GetConfigDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(defaultDir, "lab.txt"), DefaultConfig().ResolveConfigPath("lab.txt"))
+}
+
+func TestHotkeyBindingsRoundTrip(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	cfg := DefaultConfig()
+	cfg.Hotkeys.Bindings = []Hotkey{
+		{Keys: "Ctrl+Alt+1", ProfileID: "chrome-work"},
+		{Keys: "Ctrl+Alt+2", ProfileID: "firefox-personal", Incognito: true},
+	}
+	require.NoError(t, SaveConfig(cfg, configPath))
+
+	loaded, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, cfg.Hotkeys.Bindings, loaded.Hotkeys.Bindings)
+}
+
+func TestRuleConditionsRoundTrip(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	cfg := DefaultConfig()
+	conditions := []Condition{
+		{Pattern: "^/admin", Scope: ScopePath},
+		{Pattern: "^https$", Scope: ScopeScheme, Negate: true},
+	}
+	cfg.Rules = []Rule{{ID: "admin", Name: "Admin", Pattern: `^example\.com$`, Scope: ScopeDomain, ProfileID: "p", Conditions: conditions, Match: MatchAny}}
+	require.NoError(t, SaveConfig(cfg, configPath))
+
+	loaded, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	require.Len(t, loaded.Rules, 1)
+	assert.Equal(t, conditions, loaded.Rules[0].Conditions)
+	assert.Equal(t, MatchAny, loaded.Rules[0].Match)
+
+	cfg.Rules[0].Match = "either"
+	assert.Error(t, SaveConfig(cfg, configPath))
+}
+
+func TestRuleIDMigration(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	configContent := `
+[[rules]]
+name = "Work Sites"
+pattern = "work"
+scope = "domain"
+ProfileID = "p"
+
+[[rules]]
+name = "Work Sites"
+pattern = "intranet"
+scope = "domain"
+ProfileID = "p"
+
+[[rules]]
+id = "custom"
+name = "Other"
+pattern = "other"
+scope = "domain"
+ProfileID = "p"
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	cfg, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	require.Len(t, cfg.Rules, 3)
+	assert.Equal(t, "work-sites", cfg.Rules[0].ID)
+	assert.Equal(t, "work-sites-2", cfg.Rules[1].ID)
+	assert.Equal(t, "custom", cfg.Rules[2].ID)
+	assert.Equal(t, "Work Sites", cfg.Rules[0].Name)
+	assert.Equal(t, "Work Sites (2)", cfg.Rules[1].Name)
+	assert.True(t, cfg.NeedsMigration())
+
+	// Loading does not rewrite the file, but the generated IDs are stable
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, configContent, string(data))
+	reloaded, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, cfg.Rules, reloaded.Rules)
+
+	// Saving persists the migration
+	require.NoError(t, SaveConfig(reloaded, configPath))
+	assert.False(t, reloaded.NeedsMigration())
+	reloaded, err = LoadConfig(configPath)
+	require.NoError(t, err)
+	assert.False(t, reloaded.NeedsMigration())
+	assert.Equal(t, cfg.Rules, reloaded.Rules)
+
+	assert.Equal(t, 1, reloaded.FindRuleIndex("work-sites-2"))
+	assert.Equal(t, 2, reloaded.FindRuleIndex("Other"))
+	assert.Equal(t, -1, reloaded.FindRuleIndex("missing"))
+}
+
+func TestSaveConfigRuleUniqueness(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	cfg := DefaultConfig()
+
+	cfg.Rules = []Rule{{Name: "A"}, {Name: "A"}}
+	assert.ErrorContains(t, SaveConfig(cfg, configPath), "duplicate rule name")
+
+	cfg.Rules = []Rule{{ID: "x", Name: "A"}, {ID: "x", Name: "B"}}
+	assert.ErrorContains(t, SaveConfig(cfg, configPath), "duplicate rule ID")
+
+	cfg.Rules = []Rule{{ID: "x", Name: "A", Plugin: "check"}}
+	assert.ErrorContains(t, SaveConfig(cfg, configPath), "unknown matcher plugin")
+	cfg.Plugins = []Plugin{{Name: "check", Kind: PluginMatcher, Command: "/bin/true"}}
+	require.NoError(t, SaveConfig(cfg, configPath))
+
+	cfg.Rules = []Rule{{ID: "x", Name: "A", NewWindow: true, WindowMode: WindowNewTab}}
+	assert.ErrorContains(t, SaveConfig(cfg, configPath), "conflicts with window mode")
+
+	// Missing IDs are generated on save
+	cfg.Rules = []Rule{{Name: "Dev Server!"}, {Name: "Dev Server?"}}
+	require.NoError(t, SaveConfig(cfg, configPath))
+	assert.Equal(t, "dev-server", cfg.Rules[0].ID)
+	assert.Equal(t, "dev-server-2", cfg.Rules[1].ID)
+}
+
+func TestSaveConfigKeepsComments(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`# My browser setup
+default_profile_id = "work" # Used when no rule matches
+
+[[profiles]]
+ID = "work"
+Name = "Work"
+BrowserID = "chrome"
+
+[[profiles]]
+ID = "home"
+Name = "Home"
+BrowserID = "chrome"
+
+# --- Rules, most specific first ---
+
+# Internal docs, see the #docs channel
+[[rules]]
+ID = "docs"
+Name = "Docs"
+Pattern = '^docs\.example\.com$' # Not the public site
+ProfileID = "work"
+Tags = [
+  "work", # Team docs
+  "docs",
+]
+
+# Remove me
+[[rules]]
+ID = "old"
+Name = "Old"
+Pattern = "old.example.com"
+ProfileID = "home"
+
+[[browsers]]
+Name = "Chrome"
+BrowserID = "chrome"
+Executable = "/usr/bin/chrome"
+
+# Proxy for the corporate network
+[browsers.Env]
+HTTPS_PROXY = "http://proxy:3128"
+
+# The end
+`), 0600))
+
+	cfg, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	cfg.DefaultProfileID = "home"
+	cfg.Rules[1].Pattern = "new.example.com"
+	cfg.Rules = []Rule{cfg.Rules[0], {Name: "Mail", Pattern: "mail.example.com", ProfileID: "home"}}
+	require.NoError(t, SaveConfig(cfg, configPath))
+
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	saved := string(data)
+	for _, kept := range []string{
+		"# My browser setup\n",
+		`default_profile_id = 'home' # Used when no rule matches`,
+		"# --- Rules, most specific first ---\n",
+		"# Internal docs, see the #docs channel\n[[rules]]\nID = \"docs\"",
+		`Pattern = '^docs\.example\.com$' # Not the public site`,
+		`  "work", # Team docs`,
+		"# Proxy for the corporate network\n[browsers.Env]\nHTTPS_PROXY = \"http://proxy:3128\"",
+		"# The end\n",
+	} {
+		assert.Contains(t, saved, kept)
+	}
+	assert.NotContains(t, saved, "Remove me")
+	assert.NotContains(t, saved, "old.example.com")
+	// Tables keep their order, and new rules take the place of removed ones
+	assert.Less(t, strings.Index(saved, "[[profiles]]"), strings.Index(saved, "[[rules]]"))
+	assert.Less(t, strings.Index(saved, "docs"), strings.Index(saved, "mail.example.com"))
+	assert.Less(t, strings.Index(saved, "mail.example.com"), strings.Index(saved, "[[browsers]]"))
+
+	loaded, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "home", loaded.DefaultProfileID)
+	require.Len(t, loaded.Rules, 2)
+	assert.Equal(t, "mail", loaded.Rules[1].ID)
+	assert.Equal(t, []string{"work", "docs"}, loaded.Rules[0].Tags)
+	assert.Equal(t, map[string]string{"HTTPS_PROXY": "http://proxy:3128"}, loaded.Browsers[0].Env)
+	assert.Len(t, loaded.Profiles, 2)
+}
+
+func TestSplitConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.toml")
+	cfg := DefaultConfig()
+	cfg.DefaultProfileID = "work"
+	cfg.Browsers = []Browser{{Name: "Chrome", BrowserID: "chrome", Executable: "/usr/bin/chrome", Env: map[string]string{"LANG": "C"}}}
+	cfg.Profiles = []Profile{{ID: "work", Name: "Work", BrowserID: "chrome"}}
+	cfg.Rules = []Rule{{Name: "Docs", Pattern: "docs.example.com", ProfileID: "work"}}
+	cfg.History.Enabled = true
+	cfg.Overrides = map[string]string{"https://docs.example.com/pinned": "work"}
+	require.NoError(t, SaveConfig(cfg, configPath))
+
+	created, err := SplitConfig(cfg, configPath)
+	require.NoError(t, err)
+	machinePath, rulesPath := filepath.Join(dir, "machine.toml"), filepath.Join(dir, "rules.toml")
+	assert.Equal(t, []string{machinePath, rulesPath}, created)
+
+	read := func(path string) string {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		return string(data)
+	}
+	main, machine, rules := read(configPath), read(machinePath), read(rulesPath)
+	assert.NotContains(t, main, "[[browsers]]")
+	assert.NotContains(t, main, "[[rules]]")
+	assert.NotContains(t, main, "default_profile_id")
+	assert.Contains(t, main, "[history]")
+	assert.Contains(t, machine, "[[browsers]]")
+	assert.Contains(t, machine, "[[profiles]]")
+	assert.Contains(t, machine, "default_profile_id = 'work'")
+	assert.NotContains(t, machine, "[[rules]]")
+	assert.Contains(t, rules, "docs.example.com")
+	assert.Contains(t, rules, "[overrides]")
+	assert.NotContains(t, main, "[overrides]")
+
+	// A shared rule set replaces the rules, keeping what was detected on this machine
+	require.NoError(t, os.WriteFile(rulesPath, []byte(`[[rules]]
+ID = "mail"
+Name = "Mail"
+Pattern = "mail.example.com"
+ProfileID = "work"
+`), 0600))
+	loaded, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	require.Len(t, loaded.Rules, 1)
+	assert.Equal(t, "mail", loaded.Rules[0].ID)
+	assert.Equal(t, "work", loaded.DefaultProfileID)
+	assert.Equal(t, map[string]string{"LANG": "C"}, loaded.Browsers[0].Env)
+	assert.True(t, loaded.History.Enabled)
+
+	loaded.Profiles = append(loaded.Profiles, Profile{ID: "home", Name: "Home", BrowserID: "chrome"})
+	require.NoError(t, SaveConfig(loaded, configPath))
+	assert.Contains(t, read(machinePath), "home")
+	assert.NotContains(t, read(configPath), "home")
+	assert.Contains(t, read(rulesPath), `Pattern = "mail.example.com"`)
+}
+
+func BenchmarkLoadConfig(b *testing.B) {
+	// Log at the CLI's default level, as debug logging would dominate the timings
+	defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+	zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+	configPath := filepath.Join(b.TempDir(), "config.toml")
+	cfg := DefaultConfig()
+	cfg.DefaultProfileID = "chrome-default"
+	cfg.Browsers = []Browser{{Name: "Google Chrome", BrowserID: "chrome", Executable: "/usr/bin/google-chrome-stable"}}
+	cfg.Profiles = []Profile{{ID: "chrome-default", Name: "Default", BrowserID: "chrome", ProfileDir: "Default"}}
+	for i := range 100 {
+		cfg.Rules = append(cfg.Rules, Rule{Name: fmt.Sprintf("Rule %d", i), Pattern: fmt.Sprintf(`^site%d\.example\.com$`, i), Scope: ScopeDomain, ProfileID: "chrome-default"})
+	}
+	require.NoError(b, SaveConfig(cfg, configPath))
+	b.ResetTimer()
+	for range b.N {
+		if _, err := LoadConfig(configPath); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestShortenerFeed(t *testing.T) {
+	feed, err := ParseShortenerFeed([]byte("# Curated shorteners\nBIT.ly\n\nnew.example\nlinks.example safelink\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []ShortenerService{
+		{Domain: "bit.ly"},
+		{Domain: "new.example"},
+		{Domain: "links.example", IsSafelink: true},
+	}, feed)
+	for _, bad := range []string{"https://bit.ly/", "bit.ly trusted", "localhost", "bit.ly safelink extra"} {
+		_, err := ParseShortenerFeed([]byte(bad))
+		assert.Error(t, err, bad)
+	}
+
+	// The downloaded feed adds the domains that are not built in
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir()) // The cache directory on macOS
+	path, err := ShortenerFeedPath()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	require.NoError(t, os.WriteFile(path, []byte("bit.ly\nnew.example\n"), 0600))
+	cfgFile := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, SaveConfig(DefaultConfig(), cfgFile))
+	loaded, err := LoadConfig(cfgFile)
+	require.NoError(t, err)
+	builtin := DefaultConfig().Shorteners
+	require.Len(t, loaded.Shorteners, len(builtin)+1)
+	assert.Equal(t, ShortenerService{Domain: "new.example"}, loaded.Shorteners[len(builtin)])
+}
+
+func TestBuiltinShortenersNotSaved(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir()) // No downloaded feed
+	t.Setenv("HOME", t.TempDir())
+	cfgFile := filepath.Join(t.TempDir(), "config.toml")
+	cfg := DefaultConfig()
+	cfg.DisabledShorteners = []string{"t.co"}
+	require.NoError(t, SaveConfig(cfg, cfgFile))
+	data, err := os.ReadFile(cfgFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "bit.ly")
+	assert.Contains(t, string(data), "disabled_shorteners")
+
+	// Built-ins come from rurl itself, without the disabled ones
+	loaded, err := LoadConfig(cfgFile)
+	require.NoError(t, err)
+	assert.Len(t, loaded.Shorteners, len(DefaultConfig().Shorteners)-1)
+	assert.NotContains(t, loaded.Shorteners, ShortenerService{Domain: "t.co"})
+	assert.False(t, loaded.NeedsMigration())
+
+	// Shorteners written by older versions are ignored, and dropped by the next save
+	legacy := string(data) + "\n[[shorteners]]\ndomain = \"old.example\"\n"
+	require.NoError(t, os.WriteFile(cfgFile, []byte(legacy), 0600))
+	loaded, err = LoadConfig(cfgFile)
+	require.NoError(t, err)
+	assert.NotContains(t, loaded.Shorteners, ShortenerService{Domain: "old.example"})
+	assert.True(t, loaded.NeedsMigration())
+	require.NoError(t, SaveConfig(loaded, cfgFile))
+	data, err = os.ReadFile(cfgFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "old.example")
+}
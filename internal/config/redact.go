@@ -0,0 +1,361 @@
+package config
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/user"
+	"regexp"
+	"strings"
+)
+
+// Placeholders written by Redact in place of removed values.
+const (
+	RedactedValue = "<redacted>" // Environment values, shortener headers, hook commands, webhooks, plugin arguments and the serve token
+	RedactedUser  = "<user>"     // The user name in paths
+	RedactedHost  = "<host>"     // Remote browser hosts
+)
+
+// genericProfileDir matches profile directories that reveal nothing about the user, and
+// genericProfileID the profile ID suffixes detection derives from them.
+var (
+	genericProfileDir = regexp.MustCompile(`^(?:Default|Profile \d+|Guest Profile|System Profile)$`)
+	genericProfileID  = regexp.MustCompile(`^(?:default|profile-\d+|guest-profile|system-profile)$`)
+)
+
+// Redactor removes personal details from a configuration while keeping its structure,
+// so that detection and rule-matching problems can be reproduced from it. Words in
+// domains and patterns are replaced by keyed hashes: the same word always becomes the
+// same placeholder within one export, so rules still overlap as they did, but the
+// words cannot be recovered by hashing guesses without the key.
+type Redactor struct {
+	Key      []byte // Hash key; NewRedactor generates a random one per export
+	Home     string // Home directory, written as ~ in paths
+	Username string // User name, written as RedactedUser in paths
+}
+
+// NewRedactor returns a Redactor for the current user with a random key.
+func NewRedactor() (*Redactor, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate redaction key: %w", err)
+	}
+	r := &Redactor{Key: key}
+	r.Home, _ = os.UserHomeDir()
+	if u, err := user.Current(); err == nil {
+		r.Username = u.Username
+		if i := strings.LastIndex(r.Username, `\`); i >= 0 {
+			r.Username = r.Username[i+1:] // Windows DOMAIN\user
+		}
+	}
+	return r, nil
+}
+
+// Redact returns a copy of cfg with personal details removed:
+//   - the home directory and user name in paths and browser arguments, environment
+//     values, hook commands, plugin and rule arguments and remote hosts
+//   - the serve token and the webhook URL and secret
+//   - words in rule and condition patterns, rewrite URLs, URL list entries and URLs,
+//     override URLs, manual shortener domains and plugin domains
+//     (CIDR and scheme patterns, built-in shorteners and words of up to three letters
+//     such as TLDs are kept)
+//   - rule IDs and names, URL list names, and profile IDs, names and directories other
+//     than generic ones such as "Default" (references to them are updated)
+//   - words in profile emails and email references to profiles
+func (r *Redactor) Redact(cfg *Config) *Config {
+	out := *cfg
+	out.Browsers = make([]Browser, len(cfg.Browsers))
+	for i, b := range cfg.Browsers {
+		b.Executable = r.redactPath(b.Executable)
+		b.Env = redactEnv(b.Env)
+		if b.ExtraArgs != nil {
+			args := make([]string, len(b.ExtraArgs))
+			for j, arg := range b.ExtraArgs {
+				args[j] = r.redactPath(arg)
+			}
+			b.ExtraArgs = args
+		}
+		if b.Remote != nil {
+			remote := *b.Remote
+			remote.Host = RedactedHost
+			remote.IdentityFile = r.redactPath(remote.IdentityFile)
+			remote.Command = r.redactPath(remote.Command)
+			b.Remote = &remote
+		}
+		out.Browsers[i] = b
+	}
+
+	profileIDs := make(map[string]string) // Original ID to redacted ID
+	out.Profiles = make([]Profile, len(cfg.Profiles))
+	for i, p := range cfg.Profiles {
+		id := r.redactProfileID(p)
+		profileIDs[p.ID] = id
+		p.ID = id
+		if !genericProfileDir.MatchString(p.Name) {
+			p.Name = "Profile " + r.hash(p.Name)
+		}
+		switch {
+		case p.ProfileDir == "" || genericProfileDir.MatchString(p.ProfileDir):
+		case strings.ContainsAny(p.ProfileDir, `/\`):
+			p.ProfileDir = r.redactPath(p.ProfileDir)
+		default:
+			p.ProfileDir = r.hash(p.ProfileDir)
+		}
+		p.Email = r.redactWords(p.Email)
+		p.UserDataDir = r.redactPath(p.UserDataDir)
+		p.ExecutableOverride = r.redactPath(p.ExecutableOverride)
+		p.WorkingDir = r.redactPath(p.WorkingDir)
+		if p.Sandbox != "" {
+			p.Sandbox = RedactedValue // e.g. firejail --private=/home/<user>/...
+		}
+		p.Env = redactEnv(p.Env)
+		out.Profiles[i] = p
+	}
+	redactRef := func(id string) string {
+		if redacted, ok := profileIDs[id]; ok {
+			return redacted
+		}
+		if id == "" {
+			return ""
+		}
+		if email, ok := strings.CutPrefix(id, ProfileEmailPrefix); ok {
+			return ProfileEmailPrefix + r.redactWords(email) // Redacted like the profiles' emails
+		}
+		return r.hash(id) // Dangling reference, kept dangling
+	}
+	out.DefaultProfileID = redactRef(cfg.DefaultProfileID)
+	out.Headless.ProfileID = redactRef(cfg.Headless.ProfileID)
+	out.Behavior.FileProfileID = redactRef(cfg.Behavior.FileProfileID)
+	out.Behavior.IncognitoFallbackProfileID = redactRef(cfg.Behavior.IncognitoFallbackProfileID)
+	if cfg.Hotkeys.Bindings != nil {
+		out.Hotkeys.Bindings = make([]Hotkey, len(cfg.Hotkeys.Bindings))
+		for i, h := range cfg.Hotkeys.Bindings {
+			h.ProfileID = redactRef(h.ProfileID)
+			out.Hotkeys.Bindings[i] = h
+		}
+	}
+	if cfg.Overrides != nil {
+		out.Overrides = make(map[string]string, len(cfg.Overrides))
+		for u, id := range cfg.Overrides {
+			out.Overrides[r.redactWords(u)] = redactRef(id)
+		}
+	}
+
+	listNames := make(map[string]string) // Original name to redacted name
+	out.URLLists = make([]URLList, len(cfg.URLLists))
+	for i, l := range cfg.URLLists {
+		name := fmt.Sprintf("list-%d", i+1)
+		listNames[l.Name] = name
+		l.Name = name
+		l.File = r.redactPath(l.File)
+		l.URL = r.redactWords(l.URL)
+		if l.Entries != nil {
+			entries := make([]string, len(l.Entries))
+			for j, e := range l.Entries {
+				entries[j] = r.redactPattern(e)
+			}
+			l.Entries = entries
+		}
+		out.URLLists[i] = l
+	}
+
+	out.Rules = make([]Rule, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		rule.ID = fmt.Sprintf("rule-%d", i+1)
+		rule.Name = fmt.Sprintf("Rule %d", i+1)
+		if rule.Scope != ScopeCIDR && rule.Scope != ScopeScheme && rule.Scope != ScopeExtension {
+			rule.Pattern = r.redactPattern(rule.Pattern)
+		}
+		if rule.Conditions != nil {
+			conditions := make([]Condition, len(rule.Conditions))
+			for j, c := range rule.Conditions {
+				if c.Scope != ScopeCIDR && c.Scope != ScopeScheme && c.Scope != ScopeExtension {
+					c.Pattern = r.redactPattern(c.Pattern)
+				}
+				conditions[j] = c
+			}
+			rule.Conditions = conditions
+		}
+		if name, ok := listNames[rule.PatternListRef]; ok {
+			rule.PatternListRef = name
+		} else {
+			rule.PatternListRef = r.redactPath(rule.PatternListRef) // A list file
+		}
+		rule.WiFiSSID = r.redactPattern(rule.WiFiSSID) // Network names can identify a workplace or home
+		if rule.Description != "" {
+			rule.Description = RedactedValue
+		}
+		if rule.Tags != nil {
+			// Hashed alike, so rules sharing a tag still do
+			tags := make([]string, len(rule.Tags))
+			for j, tag := range rule.Tags {
+				tags[j] = r.hash(tag)
+			}
+			rule.Tags = tags
+		}
+		rule.RewriteURL = r.redactWords(rule.RewriteURL)
+		rule.ExtraArgs = redactAll(rule.ExtraArgs)
+		if rule.Handler != "" {
+			rule.Handler = RedactedValue // Script paths and credentials, like hook commands
+		}
+		rule.ProfileID = redactRef(rule.ProfileID)
+		out.Rules[i] = rule
+	}
+
+	out.ManualShorteners = make([]ShortenerService, len(cfg.ManualShorteners))
+	for i, s := range cfg.ManualShorteners {
+		s.Domain = r.redactWords(s.Domain)
+		s.Headers = redactEnv(s.Headers)
+		out.ManualShorteners[i] = s
+	}
+
+	out.Plugins = make([]Plugin, len(cfg.Plugins))
+	for i, p := range cfg.Plugins {
+		p.Command = r.redactPath(p.Command)
+		p.Args = redactAll(p.Args)
+		domains := make([]string, len(p.Domains))
+		for j, d := range p.Domains {
+			domains[j] = r.redactWords(d)
+		}
+		p.Domains = domains
+		out.Plugins[i] = p
+	}
+
+	if out.Hooks.PreLaunch != "" {
+		out.Hooks.PreLaunch = RedactedValue
+	}
+	if out.Hooks.PostLaunch != "" {
+		out.Hooks.PostLaunch = RedactedValue
+	}
+	if out.Hooks.Webhook != "" {
+		out.Hooks.Webhook = RedactedValue
+	}
+	if out.Hooks.WebhookSecret != "" {
+		out.Hooks.WebhookSecret = RedactedValue
+	}
+	if out.Serve.Token != "" {
+		out.Serve.Token = RedactedValue
+	}
+	return &out
+}
+
+// hash returns the placeholder for word: an "x" and 8 hex digits, valid in domains,
+// paths and regular expressions. Words are compared case-insensitively, like hosts.
+func (r *Redactor) hash(word string) string {
+	mac := hmac.New(sha256.New, r.Key)
+	mac.Write([]byte(strings.ToLower(word)))
+	return "x" + hex.EncodeToString(mac.Sum(nil))[:8]
+}
+
+// redactProfileID keeps IDs made of the browser ID and a generic profile directory
+// (as detection creates them) and replaces the rest of other IDs.
+func (r *Redactor) redactProfileID(p Profile) string {
+	if p.ID == p.BrowserID {
+		return p.ID
+	}
+	if rest, ok := strings.CutPrefix(p.ID, p.BrowserID+"-"); ok {
+		if genericProfileID.MatchString(rest) {
+			return p.ID
+		}
+		return p.BrowserID + "-" + r.hash(rest)
+	}
+	return r.hash(p.ID)
+}
+
+// redactPath replaces the home directory in s with ~ and other occurrences of the user
+// name with RedactedUser.
+func (r *Redactor) redactPath(s string) string {
+	if r.Home != "" && len(r.Home) > 1 {
+		s = strings.ReplaceAll(s, r.Home, "~")
+	}
+	if len(r.Username) >= 3 {
+		s = strings.ReplaceAll(s, r.Username, RedactedUser)
+	}
+	return s
+}
+
+// redactPattern replaces the words of a rule pattern, keeping its regular expression
+// syntax. If that breaks the pattern (e.g. a word was a character class name), the
+// whole pattern becomes a placeholder literal.
+func (r *Redactor) redactPattern(pattern string) string {
+	redacted := r.redactWords(pattern)
+	if _, err := regexp.Compile(pattern); err == nil {
+		if _, err := regexp.Compile(redacted); err != nil {
+			return regexp.QuoteMeta(r.hash(pattern))
+		}
+	}
+	return redacted
+}
+
+// redactWords replaces each run of letters, digits and hyphens longer than three
+// characters and containing a letter by its hash. Escape sequences (e.g. \b) and
+// character classes (e.g. [a-zA-Z0-9-]) are kept.
+func (r *Redactor) redactWords(s string) string {
+	var b strings.Builder
+	inClass := false
+	for i := 0; i < len(s); {
+		if s[i] == '\\' && i+1 < len(s) {
+			b.WriteString(s[i : i+2])
+			i += 2
+			continue
+		}
+		if inClass || !isWordChar(s[i]) {
+			switch s[i] {
+			case '[':
+				inClass = true
+			case ']':
+				inClass = false
+			}
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(s) && isWordChar(s[j]) {
+			j++
+		}
+		word := s[i:j]
+		if len(word) > 3 && strings.IndexFunc(word, isLetter) >= 0 && word != "http" && word != "https" {
+			b.WriteString(r.hash(word))
+		} else {
+			b.WriteString(word)
+		}
+		i = j
+	}
+	return b.String()
+}
+
+func isWordChar(c byte) bool {
+	return c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isLetter(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// redactEnv keeps the names of environment variables and replaces their values.
+func redactEnv(env map[string]string) map[string]string {
+	if env == nil {
+		return nil
+	}
+	out := make(map[string]string, len(env))
+	for k := range env {
+		out[k] = RedactedValue
+	}
+	return out
+}
+
+// redactAll replaces every value of values.
+func redactAll(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	out := make([]string, len(values))
+	for i := range out {
+		out[i] = RedactedValue
+	}
+	return out
+}
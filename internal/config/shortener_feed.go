@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DefaultShortenerFeedURL is the curated list of shortener domains maintained in the
+// rurl repository, downloaded by 'rurl config shorturl update' unless
+// shortener_feed.url says otherwise.
+const DefaultShortenerFeedURL = "https://raw.githubusercontent.com/jmylchreest/rurl/main/shorteners.txt"
+
+// feedSafelink marks a feed entry as a safelink, e.g. "safelinks.example.com safelink".
+const feedSafelink = "safelink"
+
+// ShortenerFeedPath returns the file in the user cache directory holding the last
+// verified download of the shortener feed.
+func ShortenerFeedPath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "rurl", "shorteners.txt"), nil
+}
+
+// ParseShortenerFeed parses a shortener feed: one domain per line, followed by
+// "safelink" for safelink services. Blank lines and lines starting with '#' are
+// ignored.
+func ParseShortenerFeed(data []byte) ([]ShortenerService, error) {
+	var feed []ShortenerService
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		domain := strings.ToLower(fields[0])
+		if len(fields) > 2 || (len(fields) == 2 && fields[1] != feedSafelink) || !isFeedDomain(domain) {
+			return nil, fmt.Errorf("line %d: invalid shortener entry '%s' (expected a domain, optionally followed by '%s')", i+1, line, feedSafelink)
+		}
+		feed = append(feed, ShortenerService{Domain: domain, IsSafelink: len(fields) == 2})
+	}
+	return feed, nil
+}
+
+// isFeedDomain reports whether s looks like a domain name.
+func isFeedDomain(s string) bool {
+	if !strings.Contains(s, ".") || strings.HasPrefix(s, ".") || strings.HasSuffix(s, ".") {
+		return false
+	}
+	for _, r := range s {
+		if !('a' <= r && r <= 'z' || '0' <= r && r <= '9' || r == '-' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeShortenerFeed returns the built-in shorteners followed by the shorteners of
+// feed that are not built in.
+func mergeShortenerFeed(builtin, feed []ShortenerService) []ShortenerService {
+	merged := slices.Clone(builtin)
+	for _, s := range feed {
+		if !slices.ContainsFunc(merged, func(b ShortenerService) bool { return b.Domain == s.Domain }) {
+			merged = append(merged, s)
+		}
+	}
+	return merged
+}
+
+// BuiltinShorteners returns the built-in shorteners followed by those of the
+// downloaded feed that are not built in, before any of them are disabled.
+func BuiltinShorteners() []ShortenerService {
+	return mergeShortenerFeed(DefaultConfig().Shorteners, loadShortenerFeed())
+}
+
+// enabledShorteners returns the shorteners of builtin whose domain is not in disabled.
+func enabledShorteners(builtin []ShortenerService, disabled []string) []ShortenerService {
+	return slices.DeleteFunc(builtin, func(s ShortenerService) bool {
+		return slices.Contains(disabled, s.Domain)
+	})
+}
+
+// loadShortenerFeed returns the shorteners of the downloaded feed, or nil if it was
+// never downloaded or cannot be read.
+func loadShortenerFeed() []ShortenerService {
+	path, err := ShortenerFeedPath()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	feed, err := ParseShortenerFeed(data)
+	if err != nil {
+		return nil
+	}
+	return feed
+}
@@ -0,0 +1,426 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// tomlIdentityKeys are the keys identifying an entry of an array of tables, so that it
+// is matched to the same entry of the previous file even if entries were added,
+// removed or moved. Entries without any of them are matched by position.
+var tomlIdentityKeys = []string{"id", "browserid", "domain", "name"}
+
+// tomlDoc is a TOML document split into tables and keys, keeping the raw lines of each
+// so it can be written back as it was.
+type tomlDoc struct {
+	root    *tomlTable // Keys before the first table header
+	tables  []*tomlTable
+	trailer []string // Comments and blank lines after the last key
+}
+
+// tomlTable is a table of a tomlDoc.
+type tomlTable struct {
+	header   string   // Raw header line, empty for the root table
+	path     string   // Dotted name of the table, unquoted and in lower case
+	array    bool     // Whether the header is [[path]]
+	id       string   // Identity matching the table between documents, see identifyTOMLTables
+	parent   bool     // Whether the table is a sub-table of an array entry
+	comments []string // Comments and blank lines before the header
+	keys     []*tomlKey
+}
+
+// tomlKey is a key-value pair of a tomlTable.
+type tomlKey struct {
+	name     string   // Dotted name of the key, unquoted
+	lines    []string // Raw lines of the pair, more than one for multi-line values
+	eq       int      // Index of the '=' in the first line
+	comment  string   // Comment after the value on its last line, from the '#'
+	comments []string // Comments and blank lines before the pair
+}
+
+// value returns the raw text of the value, without the trailing comment.
+func (k *tomlKey) value() string {
+	text := strings.Join(k.lines, "\n")[k.eq+1:]
+	if k.comment != "" {
+		text = strings.TrimSuffix(strings.TrimRight(text, "\r"), k.comment)
+	}
+	return strings.TrimSpace(text)
+}
+
+// parsed returns the value decoded, or nil if it cannot be decoded.
+func (k *tomlKey) parsed() any {
+	var m map[string]any
+	if err := toml.Unmarshal([]byte("v = "+k.value()), &m); err != nil {
+		return nil
+	}
+	return m["v"]
+}
+
+// key returns the key of t named name, ignoring case unless a key has the exact name,
+// and skipping the keys in used.
+func (t *tomlTable) key(name string, used map[*tomlKey]bool) *tomlKey {
+	for _, k := range t.keys {
+		if k.name == name && !used[k] {
+			return k
+		}
+	}
+	for _, k := range t.keys {
+		if strings.EqualFold(k.name, name) && !used[k] {
+			return k
+		}
+	}
+	return nil
+}
+
+// mergeTOMLComments returns updated, a TOML document generated from the configuration,
+// laid out like previous, the file it replaces: tables, keys, comments and formatting
+// of previous are kept wherever the value is unchanged, changed values are replaced in
+// place, and new tables and keys are added after the existing ones. The order of the
+// entries of arrays of tables is the one of updated. updated is returned as is if
+// either document cannot be parsed.
+func mergeTOMLComments(previous, updated []byte) []byte {
+	prev, err := parseTOMLDoc(string(previous))
+	if err != nil {
+		return updated
+	}
+	next, err := parseTOMLDoc(string(updated))
+	if err != nil {
+		return updated
+	}
+
+	prevTables := map[string]*tomlTable{}
+	for _, t := range prev.tables {
+		prevTables[t.id] = t
+	}
+
+	// A table the file started with needs a blank line if it no longer comes first
+	var first *tomlTable
+	if len(prev.root.keys) == 0 && len(prev.tables) > 0 {
+		first = prev.tables[0]
+	}
+
+	var out []string
+	out = appendMergedKeys(out, prev.root, next.root)
+	for _, block := range orderTOMLBlocks(tomlBlocks(prev.tables), tomlBlocks(next.tables)) {
+		for _, t := range block {
+			old := prevTables[t.id]
+			if old == nil {
+				out = append(out, t.comments...)
+				out = append(out, t.header)
+				out = appendMergedKeys(out, nil, t)
+				continue
+			}
+			if old == first && len(out) > 0 && (len(old.comments) == 0 || strings.TrimSpace(old.comments[0]) != "") {
+				out = append(out, "")
+			}
+			out = append(out, old.comments...)
+			out = append(out, old.header)
+			out = appendMergedKeys(out, old, t)
+		}
+	}
+	if prev.trailer != nil {
+		out = append(out, prev.trailer...)
+	} else {
+		out = append(out, next.trailer...)
+	}
+
+	for len(out) > 0 && strings.TrimSpace(out[0]) == "" {
+		out = out[1:]
+	}
+	for len(out) > 0 && strings.TrimSpace(out[len(out)-1]) == "" {
+		out = out[:len(out)-1]
+	}
+	return []byte(strings.Join(out, "\n") + "\n")
+}
+
+// appendMergedKeys appends the keys of next to out: those also in prev (which may be
+// nil) first, in the order and with the comments and formatting of prev.
+func appendMergedKeys(out []string, prev, next *tomlTable) []string {
+	merged := map[*tomlKey]bool{}
+	if prev != nil {
+		for _, old := range prev.keys {
+			k := next.key(old.name, merged)
+			if k == nil {
+				continue
+			}
+			merged[k] = true
+			out = append(out, old.comments...)
+			if reflect.DeepEqual(old.parsed(), k.parsed()) {
+				out = append(out, old.lines...)
+				continue
+			}
+			// Keep the spelling of the key and the comment after the value
+			lines := append([]string{}, k.lines...)
+			lines[0] = old.lines[0][:old.eq] + "= " + strings.TrimLeft(lines[0][k.eq+1:], " \t")
+			if old.comment != "" && len(lines) == 1 {
+				lines[0] += " " + old.comment
+			}
+			out = append(out, lines...)
+		}
+	}
+	for _, k := range next.keys {
+		if merged[k] {
+			continue
+		}
+		out = append(out, k.comments...)
+		out = append(out, k.lines...)
+	}
+	return out
+}
+
+// tomlBlocks groups tables into top-level tables followed by the sub-tables of their
+// entries.
+func tomlBlocks(tables []*tomlTable) [][]*tomlTable {
+	var blocks [][]*tomlTable
+	for _, t := range tables {
+		if t.parent && len(blocks) > 0 {
+			blocks[len(blocks)-1] = append(blocks[len(blocks)-1], t)
+			continue
+		}
+		blocks = append(blocks, []*tomlTable{t})
+	}
+	return blocks
+}
+
+// orderTOMLBlocks returns the blocks of next in the order of the blocks of prev. Entries
+// of an array of tables take the places of the entries of prev in turn, and any extra
+// ones follow the last of them. Tables not in prev come last, in the order of next.
+func orderTOMLBlocks(prev, next [][]*tomlTable) [][]*tomlTable {
+	last := map[string]int{}
+	for i, b := range prev {
+		last[b[0].path] = i
+	}
+	used := make([]bool, len(next))
+	var order [][]*tomlTable
+	take := func(match func(t *tomlTable) bool, all bool) {
+		for i, b := range next {
+			if !used[i] && match(b[0]) {
+				used[i] = true
+				order = append(order, b)
+				if !all {
+					return
+				}
+			}
+		}
+	}
+	for i, b := range prev {
+		head := b[0]
+		if head.array {
+			take(func(t *tomlTable) bool { return t.array && t.path == head.path }, false)
+		} else {
+			take(func(t *tomlTable) bool { return t.id == head.id }, false)
+		}
+		if last[head.path] == i {
+			take(func(t *tomlTable) bool { return t.path == head.path }, true)
+		}
+	}
+	take(func(*tomlTable) bool { return true }, true)
+	return order
+}
+
+// parseTOMLDoc splits a TOML document into tables and keys. It only checks the syntax
+// as far as needed to find where each key and table starts and ends.
+func parseTOMLDoc(data string) (*tomlDoc, error) {
+	lines := strings.Split(data, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	doc := &tomlDoc{root: &tomlTable{}}
+	table := doc.root
+	var pending []string
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case line == "" || line[0] == '#':
+			pending = append(pending, lines[i])
+		case line[0] == '[':
+			array := strings.HasPrefix(line, "[[")
+			name, err := tomlHeaderName(line, array)
+			if err != nil {
+				return nil, err
+			}
+			table = &tomlTable{header: lines[i], path: strings.ToLower(name), array: array, comments: pending}
+			doc.tables = append(doc.tables, table)
+			pending = nil
+		default:
+			k, end, err := scanTOMLKey(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			k.comments = pending
+			table.keys = append(table.keys, k)
+			pending = nil
+			i = end
+		}
+	}
+	doc.trailer = pending
+	identifyTOMLTables(doc.tables)
+	return doc, nil
+}
+
+// identifyTOMLTables sets the id of tables: the path of top-level tables, followed by
+// the value of an identity key or the position for entries of arrays, and for
+// sub-tables of array entries the id of the entry followed by the rest of the path.
+func identifyTOMLTables(tables []*tomlTable) {
+	seen := map[string]bool{}
+	counts := map[string]int{}
+	var entries []*tomlTable
+	for _, t := range tables {
+		id := t.path
+		for j := len(entries) - 1; j >= 0; j-- {
+			if strings.HasPrefix(t.path, entries[j].path+".") {
+				id = entries[j].id + "/" + strings.TrimPrefix(t.path, entries[j].path+".")
+				t.parent = true
+				break
+			}
+		}
+		if t.array {
+			identified := false
+			for _, name := range tomlIdentityKeys {
+				if k := t.key(name, nil); k != nil {
+					if v := k.parsed(); v != nil && !seen[id+"="+strconv.Quote(fmt.Sprint(v))] {
+						id += "=" + strconv.Quote(fmt.Sprint(v))
+						identified = true
+					}
+					break
+				}
+			}
+			if !identified {
+				n := counts[id]
+				counts[id]++
+				id += "#" + strconv.Itoa(n)
+			}
+			entries = append(entries, t)
+		}
+		seen[id] = true
+		t.id = id
+	}
+}
+
+// scanTOMLKey scans the key-value pair starting on lines[start], and returns it with
+// the index of its last line.
+func scanTOMLKey(lines []string, start int) (*tomlKey, int, error) {
+	first := lines[start]
+	eq := -1
+	var s tomlScanner
+	for i := 0; i < len(first) && eq < 0; i++ {
+		if s.quote == "" && first[i] == '=' {
+			eq = i
+			continue
+		}
+		i = s.step(first, i)
+	}
+	if eq < 0 {
+		return nil, 0, fmt.Errorf("line %d: expected a key and value", start+1)
+	}
+	k := &tomlKey{name: normalizeTOMLKey(first[:eq]), eq: eq}
+
+	s = tomlScanner{}
+	for i := start; i < len(lines); i++ {
+		from := 0
+		if i == start {
+			from = eq + 1
+		}
+		comment := s.scanLine(lines[i], from)
+		k.lines = append(k.lines, lines[i])
+		if s.quote == "" && s.depth <= 0 {
+			if comment >= 0 {
+				k.comment = strings.TrimRight(lines[i][comment:], "\r")
+			}
+			return k, i, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("line %d: unterminated value", start+1)
+}
+
+// tomlHeaderName returns the normalized name of the table of a header line.
+func tomlHeaderName(line string, array bool) (string, error) {
+	open := 1
+	if array {
+		open = 2
+	}
+	var s tomlScanner
+	for i := open; i < len(line); i++ {
+		if s.quote == "" && line[i] == ']' {
+			return normalizeTOMLKey(line[open:i]), nil
+		}
+		i = s.step(line, i)
+	}
+	return "", fmt.Errorf("invalid table header '%s'", line)
+}
+
+// normalizeTOMLKey returns a dotted key without quotes or spaces around its parts.
+func normalizeTOMLKey(key string) string {
+	var parts []string
+	var s tomlScanner
+	start := 0
+	for i := 0; i <= len(key); i++ {
+		if i < len(key) && (s.quote != "" || key[i] != '.') {
+			i = s.step(key, i)
+			continue
+		}
+		part := strings.TrimSpace(key[start:i])
+		if unquoted, err := strconv.Unquote(part); err == nil && strings.HasPrefix(part, `"`) {
+			part = unquoted
+		} else if len(part) >= 2 && part[0] == '\'' && part[len(part)-1] == '\'' {
+			part = part[1 : len(part)-1]
+		}
+		parts = append(parts, part)
+		start = i + 1
+	}
+	return strings.Join(parts, ".")
+}
+
+// tomlScanner tracks the strings and brackets of TOML text, to find where values and
+// comments end.
+type tomlScanner struct {
+	quote string // Delimiter of the string being scanned, if any
+	depth int    // Number of open arrays and inline tables
+}
+
+// scanLine scans line from index from, and returns the index of the comment ending it,
+// or -1 if there is none.
+func (s *tomlScanner) scanLine(line string, from int) int {
+	for i := from; i < len(line); i++ {
+		if s.quote == "" && line[i] == '#' {
+			return i
+		}
+		i = s.step(line, i)
+	}
+	// Only multi-line strings continue on the next line
+	if len(s.quote) == 1 {
+		s.quote = ""
+	}
+	return -1
+}
+
+// step scans the character at line[i], and returns the index of the last character
+// it consumed.
+func (s *tomlScanner) step(line string, i int) int {
+	c := line[i]
+	switch {
+	case s.quote == "":
+		switch {
+		case strings.HasPrefix(line[i:], `"""`) || strings.HasPrefix(line[i:], `'''`):
+			s.quote = line[i : i+3]
+			return i + 2
+		case c == '"' || c == '\'':
+			s.quote = string(c)
+		case c == '[' || c == '{':
+			s.depth++
+		case c == ']' || c == '}':
+			s.depth--
+		}
+	case c == '\\' && s.quote[0] == '"':
+		return i + 1
+	case strings.HasPrefix(line[i:], s.quote):
+		n := len(s.quote)
+		s.quote = ""
+		return i + n - 1
+	}
+	return i
+}
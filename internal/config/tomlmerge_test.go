@@ -0,0 +1,52 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeTOMLComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous string
+		updated  string
+		want     string
+	}{
+		{
+			name:     "unchanged values keep their formatting",
+			previous: "# Top\na = \"x\"   # note\n\n[t]\nb = [ 1,\n  2 ] # two\n",
+			updated:  "a = 'x'\n\n[t]\nb = [1, 2]\n",
+			want:     "# Top\na = \"x\"   # note\n\n[t]\nb = [ 1,\n  2 ] # two\n",
+		},
+		{
+			name:     "strings containing comment and table syntax",
+			previous: "s = \"\"\"\n# not a comment\n[not.a.table]\n\"\"\"\nu = 'a # b' # real\n",
+			updated:  "s = '''\n# not a comment\n[not.a.table]\n'''\nu = 'c'\n",
+			want:     "s = \"\"\"\n# not a comment\n[not.a.table]\n\"\"\"\nu = 'c' # real\n",
+		},
+		{
+			name:     "new keys and tables are added, removed ones dropped",
+			previous: "a = 1\n# Gone\nb = 2\n\n[t]\nc = 3\n",
+			updated:  "a = 1\nd = 4\n\n[t]\nc = 3\n\n[u]\ne = 5\n",
+			want:     "a = 1\nd = 4\n\n[t]\nc = 3\n\n[u]\ne = 5\n",
+		},
+		{
+			name:     "array entries keep their comments when moved",
+			previous: "# First\n[[r]]\nID = 'a'\n\n# Second\n[[r]]\nID = 'b'\n[r.x]\nk = 1\n",
+			updated:  "[[r]]\nID = 'b'\n\n[r.x]\nk = 1\n\n[[r]]\nID = 'a'\n",
+			want:     "# Second\n[[r]]\nID = 'b'\n[r.x]\nk = 1\n\n# First\n[[r]]\nID = 'a'\n",
+		},
+		{
+			name:     "invalid previous file",
+			previous: "a = [1,\n",
+			updated:  "a = 1\n",
+			want:     "a = 1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(mergeTOMLComments([]byte(tt.previous), []byte(tt.updated))))
+		})
+	}
+}
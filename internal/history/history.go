@@ -0,0 +1,125 @@
+// Package history records recent URL launches so they can be reviewed later.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultMaxEntries is the number of launches kept when no limit is configured.
+const DefaultMaxEntries = 200
+
+// Entry describes a single routed URL.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	URL       string    `json:"url"`
+	RuleName  string    `json:"rule,omitempty"` // Empty if the default profile was used
+	RuleTags  []string  `json:"rule_tags,omitempty"`
+	ProfileID string    `json:"profile"`
+	Incognito bool      `json:"incognito,omitempty"`
+	Error     string    `json:"error,omitempty"` // Launch failure, if any
+}
+
+// DefaultPath returns the history file location in the user cache directory.
+func DefaultPath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "rurl", "history.jsonl"), nil
+}
+
+// Load returns the entries in the history file at path, oldest first.
+// A missing file is not an error. Malformed lines are skipped.
+func Load(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history '%s': %w", path, err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err == nil {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return entries, fmt.Errorf("failed to read history '%s': %w", path, err)
+	}
+	return entries, nil
+}
+
+// Append adds e to the history file at path. The entry is written with a single
+// append so concurrent launches cannot clobber each other. Once the file holds more
+// than twice maxEntries entries it is compacted to the newest maxEntries (0 uses
+// DefaultMaxEntries); Load may therefore return up to twice maxEntries entries.
+func Append(path string, e Entry, maxEntries int) error {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode history entry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+
+	entries, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if len(entries) > 2*maxEntries {
+		return compact(path, entries[len(entries)-maxEntries:])
+	}
+	return nil
+}
+
+// compact atomically replaces the history file at path with entries.
+func compact(path string, entries []Entry) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to compact history: %w", err)
+	}
+	tmp := f.Name()
+	enc := json.NewEncoder(f)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return fmt.Errorf("failed to compact history: %w", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compact history: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compact history: %w", err)
+	}
+	return nil
+}
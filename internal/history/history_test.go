@@ -0,0 +1,54 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "history.jsonl")
+
+	entries, err := Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, Append(path, Entry{Time: now, URL: "https://a.example.com", ProfileID: "work", RuleName: "Work"}, 0))
+	require.NoError(t, Append(path, Entry{Time: now, URL: "https://b.example.com", ProfileID: "personal", Error: "boom"}, 0))
+
+	entries, err = Load(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "https://a.example.com", entries[0].URL)
+	assert.Equal(t, "Work", entries[0].RuleName)
+	assert.True(t, now.Equal(entries[0].Time))
+	assert.Equal(t, "boom", entries[1].Error)
+}
+
+func TestAppendTrimsAndSkipsMalformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("not json\n"), 0600))
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, Append(path, Entry{URL: "https://example.com", ProfileID: "p"}, 10))
+	}
+	entries, err := Load(path)
+	require.NoError(t, err)
+	assert.Len(t, entries, 20)
+
+	// Exceeding twice the limit compacts to the newest entries
+	require.NoError(t, Append(path, Entry{URL: "https://last.example.com", ProfileID: "p"}, 10))
+	entries, err = Load(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 10)
+	assert.Equal(t, "https://last.example.com", entries[9].URL)
+
+	matches, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
@@ -0,0 +1,91 @@
+// Package hotkey registers global hotkeys, key combinations the desktop reports to rurl
+// whichever window has the focus. Only Windows has an API for this that needs no cgo;
+// on other platforms Listen returns ErrUnsupported, and hotkeys are bound to commands
+// in the desktop's keyboard settings instead.
+package hotkey
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupported is returned by Listen on platforms without global hotkeys.
+var ErrUnsupported = errors.New("global hotkeys are not supported on this platform")
+
+// Modifiers is a set of modifier keys.
+type Modifiers uint8
+
+// Modifier keys of a Combo.
+const (
+	ModCtrl  Modifiers = 1 << iota // Ctrl (Control)
+	ModAlt                         // Alt (Option on macOS)
+	ModShift                       // Shift
+	ModSuper                       // The Windows, Super or Command key
+)
+
+// modifierNames maps the lowercased names of modifiers accepted by Parse to them.
+var modifierNames = map[string]Modifiers{
+	"ctrl":    ModCtrl,
+	"control": ModCtrl,
+	"alt":     ModAlt,
+	"option":  ModAlt,
+	"shift":   ModShift,
+	"super":   ModSuper,
+	"win":     ModSuper,
+	"cmd":     ModSuper,
+	"meta":    ModSuper,
+}
+
+// Combo is a key pressed together with modifiers, e.g. Ctrl+Alt+1.
+type Combo struct {
+	Modifiers Modifiers
+	Key       string // An uppercase letter, a digit, or F1 to F24
+}
+
+// String returns the combo as Parse accepts it, e.g. "Ctrl+Alt+1".
+func (c Combo) String() string {
+	var parts []string
+	for _, m := range []struct {
+		mod  Modifiers
+		name string
+	}{{ModCtrl, "Ctrl"}, {ModAlt, "Alt"}, {ModShift, "Shift"}, {ModSuper, "Super"}} {
+		if c.Modifiers&m.mod != 0 {
+			parts = append(parts, m.name)
+		}
+	}
+	return strings.Join(append(parts, c.Key), "+")
+}
+
+// Parse parses a combo of modifiers and a key joined by '+', e.g. "Ctrl+Alt+1" or
+// "super+shift+f5". Names are case-insensitive. At least one modifier is required, so
+// that a hotkey does not take a key away from every application.
+func Parse(s string) (Combo, error) {
+	parts := strings.Split(s, "+")
+	var c Combo
+	for _, part := range parts[:len(parts)-1] {
+		mod, ok := modifierNames[strings.ToLower(strings.TrimSpace(part))]
+		if !ok {
+			return Combo{}, fmt.Errorf("invalid hotkey '%s': unknown modifier '%s' (expected Ctrl, Alt, Shift or Super)", s, part)
+		}
+		c.Modifiers |= mod
+	}
+	if c.Modifiers == 0 {
+		return Combo{}, fmt.Errorf("invalid hotkey '%s': a modifier (Ctrl, Alt, Shift or Super) is required", s)
+	}
+	c.Key = strings.ToUpper(strings.TrimSpace(parts[len(parts)-1]))
+	if !validKey(c.Key) {
+		return Combo{}, fmt.Errorf("invalid hotkey '%s': unknown key '%s' (expected a letter, a digit or F1 to F24)", s, c.Key)
+	}
+	return c, nil
+}
+
+// validKey reports whether key is an uppercase letter, a digit or F1 to F24.
+func validKey(key string) bool {
+	if len(key) == 1 {
+		return 'A' <= key[0] && key[0] <= 'Z' || '0' <= key[0] && key[0] <= '9'
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(key, "F"))
+	return strings.HasPrefix(key, "F") && err == nil && 1 <= n && n <= 24
+}
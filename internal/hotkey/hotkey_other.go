@@ -0,0 +1,11 @@
+//go:build !windows
+
+package hotkey
+
+import "context"
+
+// Listen returns ErrUnsupported: registering global hotkeys needs the X11, Wayland
+// compositor or Carbon APIs, which rurl does not link against.
+func Listen(ctx context.Context, combos []Combo, pressed func(i int)) error {
+	return ErrUnsupported
+}
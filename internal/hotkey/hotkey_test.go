@@ -0,0 +1,44 @@
+package hotkey
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Combo
+	}{
+		{"Ctrl+Alt+1", Combo{ModCtrl | ModAlt, "1"}},
+		{"super + shift + f5", Combo{ModSuper | ModShift, "F5"}},
+		{"Cmd+Option+w", Combo{ModSuper | ModAlt, "W"}},
+		{"control+F24", Combo{ModCtrl, "F24"}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"1", "Ctrl+", "Hyper+1", "Ctrl+Alt+F25", "Ctrl+F0", "Ctrl+Space", ""} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestComboString(t *testing.T) {
+	c, err := Parse("shift+super+ctrl+alt+k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.String(); got != "Ctrl+Alt+Shift+Super+K" {
+		t.Errorf("String() = %q, want Ctrl+Alt+Shift+Super+K", got)
+	}
+	if again, err := Parse(c.String()); err != nil || again != c {
+		t.Errorf("Parse(String()) = %+v, %v, want %+v", again, err, c)
+	}
+}
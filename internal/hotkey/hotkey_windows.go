@@ -0,0 +1,120 @@
+//go:build windows
+
+package hotkey
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	user32                 = windows.NewLazySystemDLL("user32.dll")
+	procRegisterHotKey     = user32.NewProc("RegisterHotKey")
+	procUnregisterHotKey   = user32.NewProc("UnregisterHotKey")
+	procGetMessageW        = user32.NewProc("GetMessageW")
+	procPostThreadMessageW = user32.NewProc("PostThreadMessageW")
+)
+
+// Window messages and RegisterHotKey modifiers (winuser.h).
+const (
+	wmQuit   = 0x0012
+	wmHotkey = 0x0312
+
+	modAlt      = 0x0001
+	modControl  = 0x0002
+	modShift    = 0x0004
+	modWin      = 0x0008
+	modNoRepeat = 0x4000 // Holding the keys down reports them once
+)
+
+// msg is the MSG structure filled in by GetMessageW.
+type msg struct {
+	hwnd    uintptr
+	message uint32
+	wParam  uintptr
+	lParam  uintptr
+	time    uint32
+	pt      struct{ x, y int32 }
+}
+
+// Listen registers combos as global hotkeys and calls pressed with the index of the
+// combo each time one is pressed, until ctx is done. It fails if a combo is already
+// registered by another application.
+func Listen(ctx context.Context, combos []Combo, pressed func(i int)) error {
+	// Hotkeys are reported to the message queue of the thread registering them
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	for i, c := range combos {
+		if r, _, err := procRegisterHotKey.Call(0, uintptr(i+1), uintptr(winModifiers(c.Modifiers)|modNoRepeat), uintptr(virtualKey(c.Key))); r == 0 {
+			unregister(i)
+			return fmt.Errorf("cannot register hotkey %s (is it used by another application?): %w", c, err)
+		}
+	}
+	defer unregister(len(combos))
+
+	threadID := windows.GetCurrentThreadId()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			procPostThreadMessageW.Call(uintptr(threadID), wmQuit, 0, 0)
+		case <-done:
+		}
+	}()
+
+	for {
+		var m msg
+		r, _, err := procGetMessageW.Call(uintptr(unsafe.Pointer(&m)), 0, 0, 0)
+		switch int32(r) {
+		case -1:
+			return fmt.Errorf("failed to read hotkey messages: %w", err)
+		case 0: // WM_QUIT
+			return nil
+		}
+		if m.message == wmHotkey && m.wParam >= 1 && int(m.wParam) <= len(combos) {
+			pressed(int(m.wParam) - 1)
+		}
+	}
+}
+
+// unregister unregisters the first n hotkeys registered by Listen.
+func unregister(n int) {
+	for i := 0; i < n; i++ {
+		procUnregisterHotKey.Call(0, uintptr(i+1))
+	}
+}
+
+// winModifiers returns the RegisterHotKey modifiers of mods.
+func winModifiers(mods Modifiers) uint32 {
+	var m uint32
+	if mods&ModCtrl != 0 {
+		m |= modControl
+	}
+	if mods&ModAlt != 0 {
+		m |= modAlt
+	}
+	if mods&ModShift != 0 {
+		m |= modShift
+	}
+	if mods&ModSuper != 0 {
+		m |= modWin
+	}
+	return m
+}
+
+// virtualKey returns the virtual-key code of key as validated by Parse: letters and
+// digits are their ASCII codes, F1 to F24 are VK_F1 (0x70) onwards.
+func virtualKey(key string) uint32 {
+	if n, err := strconv.Atoi(strings.TrimPrefix(key, "F")); err == nil && len(key) > 1 {
+		return 0x70 + uint32(n-1)
+	}
+	return uint32(key[0])
+}
@@ -0,0 +1,56 @@
+package launcher
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/jmylchreest/rurl/internal/wsl"
+)
+
+// clipboardCommands returns the commands that print the clipboard, in the order they
+// are tried: pbpaste on macOS, PowerShell on Windows (and inside WSL), and wl-paste,
+// xclip or xsel on Linux and other Unix-like systems.
+func clipboardCommands() [][]string {
+	powershell := []string{"powershell.exe", "-NoProfile", "-NonInteractive", "-Command", "Get-Clipboard"}
+	switch {
+	case runtime.GOOS == "darwin":
+		return [][]string{{"pbpaste"}}
+	case runtime.GOOS == "windows":
+		return [][]string{powershell}
+	case wsl.IsWSL():
+		return [][]string{powershell, {"wl-paste", "--no-newline"}}
+	}
+	commands := [][]string{
+		{"xclip", "-selection", "clipboard", "-out"},
+		{"xsel", "--clipboard", "--output"},
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		commands = append([][]string{{"wl-paste", "--no-newline"}}, commands...)
+	}
+	return commands
+}
+
+// ReadClipboard returns the text on the desktop's clipboard, without surrounding
+// whitespace, using the first of clipboardCommands that is installed.
+func ReadClipboard() (string, error) {
+	commands := clipboardCommands()
+	for _, args := range commands {
+		path, err := exec.LookPath(args[0])
+		if err != nil {
+			continue
+		}
+		out, err := exec.Command(path, args[1:]...).Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to read the clipboard with %s: %w", args[0], err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+	names := make([]string, len(commands))
+	for i, args := range commands {
+		names[i] = args[0]
+	}
+	return "", fmt.Errorf("cannot read the clipboard: none of %s is installed", strings.Join(names, ", "))
+}
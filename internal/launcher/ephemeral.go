@@ -0,0 +1,128 @@
+package launcher
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// ephemeralGrace is how long a new ephemeral profile directory is kept before the
+// browser has had time to lock it.
+const ephemeralGrace = time.Minute
+
+// ephemeralPlaceholder stands for the directory of an ephemeral profile in command
+// lines that are only shown (see CommandLine), as it is created at launch.
+const ephemeralPlaceholder = "<new ephemeral directory>"
+
+// Browsers keep a lock in their profile directory while they run: Chromium's
+// SingletonLock and Firefox's lock are symlinks on Linux and macOS, which exist until
+// the browser exits, and Chromium's lockfile and Firefox's parent.lock are held open
+// on Windows, where they cannot be removed until it exits.
+var (
+	ephemeralLinkLocks = []string{"SingletonLock", "lock"}
+	ephemeralOpenLocks = []string{"lockfile", "parent.lock"}
+)
+
+// EphemeralRoot returns the directory in the user cache directory holding the
+// directories of ephemeral profiles.
+func EphemeralRoot() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "rurl", "ephemeral"), nil
+}
+
+// SupportsEphemeral reports whether browser can run ephemeral profiles, which are
+// passed as a user data directory (Chromium) or profile path (Firefox).
+func SupportsEphemeral(browser config.Browser) bool {
+	return browser.Remote == nil && (IsChromium(browser) || isFirefox(browser))
+}
+
+// newEphemeralDir creates an empty directory for an ephemeral profile.
+func newEphemeralDir() (string, error) {
+	root, err := EphemeralRoot()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return "", fmt.Errorf("failed to create ephemeral profile directory: %w", err)
+	}
+	dir, err := os.MkdirTemp(root, "profile-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create ephemeral profile directory: %w", err)
+	}
+	return dir, nil
+}
+
+// ephemeralArgs returns the arguments running browser with the empty profile in dir,
+// skipping the first-run screens that would otherwise greet every launch.
+func ephemeralArgs(browser config.Browser, dir string) []string {
+	if isFirefox(browser) {
+		return []string{"-profile", dir, "-no-remote"}
+	}
+	return []string{"--user-data-dir=" + dir, "--no-first-run", "--no-default-browser-check"}
+}
+
+// CleanEphemeral removes the directories of ephemeral profiles whose browser has
+// exited, or all of them if all is set (e.g. after a browser crashed and left its lock
+// behind). It returns how many were removed and how many are still in use.
+func CleanEphemeral(all bool) (removed, inUse int, err error) {
+	root, err := EphemeralRoot()
+	if err != nil {
+		return 0, 0, err
+	}
+	entries, err := os.ReadDir(root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read ephemeral profiles: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		if !all && ephemeralInUse(dir) {
+			inUse++
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			log.Debug().Err(err).Str("dir", dir).Msg("Failed to remove ephemeral profile")
+			inUse++
+			continue
+		}
+		removed++
+	}
+	return removed, inUse, nil
+}
+
+// ephemeralInUse reports whether a browser may still be using the ephemeral profile
+// directory dir: it was created moments ago, or a browser holds its lock.
+func ephemeralInUse(dir string) bool {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return false
+	}
+	if time.Since(info.ModTime()) < ephemeralGrace {
+		return true
+	}
+	for _, name := range ephemeralLinkLocks {
+		if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	for _, name := range ephemeralOpenLocks {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return true
+		}
+	}
+	return false
+}
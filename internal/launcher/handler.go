@@ -0,0 +1,123 @@
+package launcher
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// HandlerCommand returns the command line of a rule's handler template for url. The
+// template is split into arguments as a shell would (quotes group words, a backslash
+// escapes the next character outside single quotes), but is not run by a shell. %u is
+// replaced by url within its argument, so the URL is never split or interpreted, and
+// %% is a literal %. If the template has no %u, url is appended as the last argument.
+func HandlerCommand(template, url string) ([]string, error) {
+	words, err := splitCommand(template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid handler '%s': %w", template, err)
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("handler is empty")
+	}
+
+	args := make([]string, len(words))
+	hasURL := false
+	for i, w := range words {
+		var b strings.Builder
+		for j := 0; j < len(w); j++ {
+			if w[j] != '%' || j == len(w)-1 {
+				b.WriteByte(w[j])
+				continue
+			}
+			switch w[j+1] {
+			case 'u':
+				b.WriteString(url)
+				hasURL = true
+				j++
+			case '%':
+				b.WriteByte('%')
+				j++
+			default:
+				b.WriteByte('%')
+			}
+		}
+		args[i] = b.String()
+	}
+	if !hasURL {
+		args = append(args, url)
+	}
+	return args, nil
+}
+
+// splitCommand splits command into words, honouring single and double quotes and
+// backslash escapes.
+func splitCommand(command string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote byte
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				word.WriteByte(c)
+			}
+		case c == '\\' && i+1 < len(command) && (quote == 0 || strings.IndexByte(`"\$`+"`", command[i+1]) >= 0):
+			i++
+			word.WriteByte(command[i])
+			inWord = true
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				word.WriteByte(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// RunHandler starts the command of a rule's handler template for info.URL, with the
+// launch described in RURL_* environment variables as for hooks, and returns once it
+// has started.
+func RunHandler(template string, info HookInfo) error {
+	args, err := HandlerCommand(template, info.URL)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), info.launchEnv()...)
+	log.Debug().Strs("args", cmd.Args).Msg("Starting handler")
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start handler %s: %w", args[0], err)
+	}
+	if err := cmd.Process.Release(); err != nil {
+		log.Warn().Err(err).Msg("Failed to release handler process")
+	}
+	return nil
+}
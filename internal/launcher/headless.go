@@ -0,0 +1,60 @@
+package launcher
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/jmylchreest/rurl/internal/wsl"
+	"golang.org/x/term"
+)
+
+// HasDisplay reports whether a graphical session appears to be available for GUI
+// browsers. On Linux and other Unix-like systems this requires DISPLAY or
+// WAYLAND_DISPLAY to be set; macOS and Windows have one unless rurl runs in an SSH
+// session, where a launched browser would open on the remote desktop (if at all). The
+// same applies inside WSL, whose Windows host has the desktop.
+func HasDisplay() bool {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" || wsl.IsWSL() {
+		return os.Getenv("SSH_CONNECTION") == "" && os.Getenv("SSH_TTY") == ""
+	}
+	return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+}
+
+// HasTerminal reports whether rurl's standard input and output are an interactive
+// terminal, which terminal browsers need to run in.
+func HasTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// OSC52Sequence returns the terminal escape sequence asking the terminal emulator to
+// copy text to the system clipboard. Inside tmux or GNU screen the sequence is wrapped
+// so that it is passed through to the outer terminal.
+func OSC52Sequence(text string) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	switch {
+	case os.Getenv("TMUX") != "":
+		return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		return "\x1bP" + seq + "\x1b\\"
+	}
+	return seq
+}
+
+// CopyOSC52 copies text to the clipboard of the terminal rurl is running in (which may
+// be on another machine, e.g. over SSH). The sequence is written to the controlling
+// terminal, or to fallback if there is none.
+func CopyOSC52(text string, fallback io.Writer) error {
+	var w io.Writer = fallback
+	if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+		defer tty.Close()
+		w = tty
+	}
+	if _, err := io.WriteString(w, OSC52Sequence(text)); err != nil {
+		return fmt.Errorf("failed to write clipboard sequence: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package launcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// Hook names, exposed to hook commands as RURL_HOOK.
+const (
+	HookPreLaunch  = "pre_launch"
+	HookPostLaunch = "post_launch"
+)
+
+// DefaultHookTimeout is used when no hook timeout is configured.
+const DefaultHookTimeout = 10 * time.Second
+
+// ErrLaunchVetoed is returned by RunHook when a pre-launch hook exits non-zero.
+var ErrLaunchVetoed = errors.New("launch vetoed by pre_launch hook")
+
+// Launch modes, exposed to hook commands as RURL_LAUNCH_MODE.
+const (
+	LaunchModeBrowser  = "browser"  // Opened in a browser profile
+	LaunchModeApp      = "app"      // Opened in an installed PWA/Chrome app window
+	LaunchModeDeepLink = "deeplink" // Opened in a native meeting app through the system handler
+	LaunchModeHandler  = "handler"  // Passed to the command of the rule's handler
+	LaunchModePrint    = "print"    // Headless: URL printed
+	LaunchModeOSC52    = "osc52"    // Headless: URL printed and copied to the clipboard via OSC 52
+)
+
+// HookInfo describes a launch for hook commands.
+type HookInfo struct {
+	URL         string   // URL being launched
+	OriginalURL string   // URL as originally received (before shortener resolution)
+	Mode        string   // How the URL is opened (one of the LaunchMode constants)
+	RuleID      string   // Matched rule ID (empty if the default profile was used)
+	RuleName    string   // Matched rule name (empty if the default profile was used)
+	ProfileID   string   // Profile that is launched (empty if no browser is launched)
+	BrowserID   string   // Browser the profile belongs to
+	AppID       string   // Installed app the URL is opened in (app mode only)
+	Incognito   bool     // Whether the launch is incognito/private
+	Command     []string // Browser (or handler) command line (if known)
+	LaunchError error    // Launch failure (post_launch only)
+}
+
+// env returns the RURL_* environment variables for the hook.
+func (h HookInfo) env(hook string) []string {
+	env := append([]string{"RURL_HOOK=" + hook}, h.launchEnv()...)
+	if hook == HookPostLaunch {
+		status, launchErr := "ok", ""
+		if h.LaunchError != nil {
+			status, launchErr = "error", h.LaunchError.Error()
+		}
+		env = append(env, "RURL_LAUNCH_STATUS="+status, "RURL_LAUNCH_ERROR="+launchErr)
+	}
+	return env
+}
+
+// launchEnv returns the RURL_* environment variables describing the launch.
+func (h HookInfo) launchEnv() []string {
+	return []string{
+		"RURL_URL=" + h.URL,
+		"RURL_ORIGINAL_URL=" + h.OriginalURL,
+		"RURL_LAUNCH_MODE=" + h.Mode,
+		"RURL_RULE_ID=" + h.RuleID,
+		"RURL_RULE_NAME=" + h.RuleName,
+		"RURL_PROFILE_ID=" + h.ProfileID,
+		"RURL_BROWSER_ID=" + h.BrowserID,
+		"RURL_APP_ID=" + h.AppID,
+		"RURL_INCOGNITO=" + strconv.FormatBool(h.Incognito),
+		"RURL_COMMAND=" + strings.Join(h.Command, " "),
+	}
+}
+
+// shellCommand wraps command in the platform shell so hooks may use arguments and pipes.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// RunHook runs a hook command with the launch described in environment variables
+// and waits for it to finish. The hook is killed if it outlives timeout or ctx.
+// An empty command is a no-op.
+// A pre_launch hook that fails or exits non-zero returns an error wrapping ErrLaunchVetoed.
+func RunHook(ctx context.Context, hook, command string, timeout time.Duration, info HookInfo) error {
+	if strings.TrimSpace(command) == "" {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := shellCommand(ctx, command)
+	cmd.Env = append(os.Environ(), info.env(hook)...)
+	cmd.Stdout = os.Stderr // Keep stdout clean; hooks are for side effects and logging
+	cmd.Stderr = os.Stderr
+
+	log.Debug().Str("hook", hook).Str("command", command).Msg("Running launch hook")
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	if err == nil {
+		return nil
+	}
+
+	if hook == HookPreLaunch {
+		return fmt.Errorf("%w: %v", ErrLaunchVetoed, err)
+	}
+	return fmt.Errorf("%s hook failed: %w", hook, err)
+}
+
+// CommandLine returns the command line that LaunchBrowser, or LaunchApp if appID is
+// set, would execute.
+func (l *ExecLauncher) CommandLine(browser config.Browser, profile config.Profile, url string, incognito bool, appID string) ([]string, error) {
+	cmd, err := l.buildCommand(browser, profile, url, incognito, appID)
+	if err != nil {
+		return nil, err
+	}
+	return cmd.Args, nil
+}
+
+// QuoteCommandLine joins args into a command line for display, quoting the arguments
+// a POSIX shell would split or expand.
+func QuoteCommandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = arg
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$`&|;<>()*?[]{}~#!") {
+			quoted[i] = shellQuote(arg)
+		}
+	}
+	return strings.Join(quoted, " ")
+}
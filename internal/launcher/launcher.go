@@ -5,33 +5,128 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jmylchreest/rurl/internal/config"
 	"github.com/rs/zerolog/log" // Added for structured logging
 )
 
-// LaunchFunc defines the signature for the Launch function to allow mocking in tests
-type LaunchFunc func(cfg *config.Config, profileID string, targetURL string, incognito bool) error
+// Launcher opens a URL in a specific browser profile.
+// Implementations may spawn a process, print the command, forward it elsewhere, etc.
+type Launcher interface {
+	LaunchBrowser(browser config.Browser, profile config.Profile, url string, incognito bool) error
+}
 
-// defaultLaunch is the implementation of Launch that actually launches browsers
-func defaultLaunch(cfg *config.Config, profileID string, targetURL string, incognito bool) error {
-	profile, err := cfg.FindProfileByID(profileID)
-	if err != nil {
-		return fmt.Errorf("cannot launch profile: %w", err)
+// ExecLauncher is the production Launcher which starts the browser as a detached process.
+type ExecLauncher struct {
+	// MonitorWait, if set, is how long a started browser is watched for an immediate
+	// failure, which is reported as a *LaunchFailedError. Zero returns at once.
+	MonitorWait time.Duration
+}
+
+// NewExecLauncher creates a Launcher that executes browser processes.
+func NewExecLauncher() *ExecLauncher {
+	return &ExecLauncher{}
+}
+
+// AppLauncher is implemented by launchers that can open a URL in an installed
+// PWA/Chrome app window rather than a normal browser tab.
+type AppLauncher interface {
+	LaunchApp(browser config.Browser, profile config.Profile, appID string, url string) error
+}
+
+// IsChromium reports whether browser looks Chromium-based, judged by its profile argument.
+func IsChromium(browser config.Browser) bool {
+	return strings.Contains(browser.ProfileArg, "--profile-directory")
+}
+
+// SupportsIncognito reports whether browser can open private windows: it has an
+// IncognitoArg and is not a terminal browser, and remote browsers cannot use AppleScript
+// and need a command passing {incognito} on. Others (e.g. Arc, or Safari without
+// IncognitoArg = "@applescript") open "private" launches in a normal window.
+func SupportsIncognito(browser config.Browser) bool {
+	if browser.IncognitoArg == "" || browser.Terminal {
+		return false
+	}
+	if browser.Remote == nil {
+		return true
+	}
+	command := browser.Remote.Command
+	if command == "" {
+		command = DefaultRemoteCommand
 	}
+	return browser.IncognitoArg != config.IncognitoAppleScript && strings.Contains(command, "{incognito}")
+}
 
-	browser, err := cfg.GetProfileBrowser(profile)
-	if err != nil {
-		return fmt.Errorf("cannot find browser '%s' for profile '%s': %w", profile.BrowserID, profile.Name, err)
+// constructCommand builds the command used to open url in the given browser profile.
+// Ephemeral profiles get a new, empty directory each time.
+func (l *ExecLauncher) constructCommand(browser config.Browser, profile config.Profile, url string, incognito bool) (*exec.Cmd, error) {
+	if profile.Ephemeral && SupportsEphemeral(browser) {
+		dir, err := newEphemeralDir()
+		if err != nil {
+			return nil, err
+		}
+		profile.UserDataDir = dir
+	}
+	return l.buildCommand(browser, profile, url, incognito, "")
+}
+
+// buildCommand builds the browser command. If appID is set, that installed app's
+// window is opened with --app-id (Chromium only) and incognito is ignored.
+func (l *ExecLauncher) buildCommand(browser config.Browser, profile config.Profile, url string, incognito bool, appID string) (*exec.Cmd, error) {
+	if browser.Remote != nil {
+		if browser.URLPassMode != "" && browser.URLPassMode != config.URLPassArg {
+			return nil, fmt.Errorf("remote browser '%s' only supports passing the URL as an argument", browser.BrowserID)
+		}
+		if profile.WorkingDir != "" || profile.Nice != 0 || profile.Sandbox != "" || profile.Ephemeral {
+			return nil, fmt.Errorf("profile '%s' sets a working directory, nice level, sandbox or ephemeral, which remote browser '%s' does not support", profile.ID, browser.BrowserID)
+		}
+		return remoteCommand(browser, profile, url, incognito)
+	}
+	if profile.ExecutableOverride != "" {
+		browser.Executable = profile.ExecutableOverride
+	}
+	if browser.Executable == "" {
+		return nil, fmt.Errorf("browser '%s' has no executable configured", browser.BrowserID)
 	}
 
 	// Start with empty args
 	args := []string{}
 
-	// For Flatpak apps, we need to split the command into executable and arguments
+	// Private windows through AppleScript are opt-in (IncognitoArg = "@applescript"), and
+	// so are profile windows (ProfileArg = "@applescript", e.g. Safari)
+	useAppleScript := incognito && appID == "" && browser.IncognitoArg == config.IncognitoAppleScript
+	useProfileScript := !incognito && appID == "" && browser.ProfileArg == config.ProfileAppleScript && profile.ProfileDir != ""
+
+	// Environment variables cannot be passed through LaunchServices or AppleScript
+	env := buildEnv(browser.Env, profile.Env)
+	if len(env) > 0 && (useAppleScript || useProfileScript || strings.HasPrefix(browser.Executable, "open -b ")) {
+		return nil, fmt.Errorf("browser '%s' is launched via macOS LaunchServices, which does not support env", browser.BrowserID)
+	}
+	if browser.URLPassMode != "" && browser.URLPassMode != config.URLPassArg &&
+		(useAppleScript || useProfileScript || strings.HasPrefix(browser.Executable, "open -b ")) {
+		return nil, fmt.Errorf("browser '%s' is launched via macOS LaunchServices, which only supports passing the URL as an argument", browser.BrowserID)
+	}
+	if browser.URLPassMode == config.URLPassEnv {
+		env = append(env, URLEnv+"="+url)
+	}
+
+	if useAppleScript {
+		return l.appleScriptWindowCommand(browser, url, true)
+	}
+	if useProfileScript {
+		return l.appleScriptProfileCommand(browser, profile.ProfileDir, url)
+	}
+
+	// For Flatpak apps (and macOS "open -b <bundle>" launches), we need to split the
+	// command into executable and arguments
 	var cmd *exec.Cmd
-	if strings.HasPrefix(browser.Executable, "flatpak run ") {
+	openBundle := strings.HasPrefix(browser.Executable, "open -b ")
+	if strings.HasPrefix(browser.Executable, "flatpak run ") || openBundle {
 		// Split the command into parts
 		parts := strings.Split(browser.Executable, " ")
 		cmd = exec.Command(parts[0], parts[1:]...)
@@ -39,27 +134,50 @@ func defaultLaunch(cfg *config.Config, profileID string, targetURL string, incog
 		cmd = exec.Command(browser.Executable)
 	}
 
-	// 1. Add profile argument first (as a single combined argument if possible)
-	if browser.ProfileArg != "" && profile.ProfileDir != "" {
-		// Check if the ProfileArg contains "%s" to replace
-		if strings.Contains(browser.ProfileArg, "%s") {
-			// Replace %s with the actual profile directory identifier
-			args = append(args, strings.Replace(browser.ProfileArg, "%s", profile.ProfileDir, 1))
-		} else {
-			// If ProfileArg doesn't contain %s, assume it's a simple flag like --use-this-profile
-			args = append(args, browser.ProfileArg)
+	// Browsers without arguments of their own get new windows through AppleScript
+	if openBundle && browser.WindowMode == config.WindowNew && browser.NewWindowArg == "" && appID == "" &&
+		!IsChromium(browser) && !isFirefox(browser) {
+		return l.appleScriptWindowCommand(browser, url, false)
+	}
+
+	// 1. Add the user data directory and profile arguments first. Ephemeral profiles
+	// get the directory created for the launch (see constructCommand) instead.
+	if profile.Ephemeral {
+		if !SupportsEphemeral(browser) {
+			return nil, fmt.Errorf("browser '%s' does not support ephemeral profiles (Chromium- and Firefox-based browsers only)", browser.BrowserID)
+		}
+		if appID != "" {
+			return nil, fmt.Errorf("profile '%s' is ephemeral, so it has no apps installed", profile.ID)
 		}
+		dir := profile.UserDataDir
+		if dir == "" {
+			dir = ephemeralPlaceholder
+		}
+		args = append(args, ephemeralArgs(browser, dir)...)
+	} else if profile.UserDataDir != "" {
+		if !IsChromium(browser) {
+			return nil, fmt.Errorf("browser '%s' does not support user data directories (Chromium-based browsers only)", browser.BrowserID)
+		}
+		args = append(args, "--user-data-dir="+profile.UserDataDir)
+	}
+	if !profile.Ephemeral {
+		args = append(args, profileArgs(browser.ProfileArg, profile)...)
 	}
 
-	// 2. Add incognito argument
-	if incognito && browser.IncognitoArg != "" {
+	// 2. Add incognito argument (apps cannot be opened incognito)
+	if incognito && browser.IncognitoArg != "" && browser.IncognitoArg != config.IncognitoAppleScript && appID == "" {
 		args = append(args, browser.IncognitoArg)
 	}
 
-	// 3. Add Wayland specific flags for Chromium-based browsers only
+	// 3. Add the window handling argument (apps always open in their own window)
+	if arg := windowArg(browser); arg != "" && appID == "" {
+		args = append(args, arg)
+	}
+
+	// 4. Add Wayland specific flags for Chromium-based browsers only
 	if runtime.GOOS == "linux" && os.Getenv("XDG_SESSION_TYPE") == "wayland" {
 		// Check if this is a Chromium-based browser by looking at the profile argument format
-		if strings.Contains(browser.ProfileArg, "--profile-directory") {
+		if IsChromium(browser) {
 			log.Debug().Str("XDG_SESSION_TYPE", os.Getenv("XDG_SESSION_TYPE")).Msg("Wayland session detected, adding Wayland flags for Chromium-based browser")
 			args = append(args, "--enable-features=UseOzonePlatform")
 			args = append(args, "--ozone-platform=wayland")
@@ -70,12 +188,267 @@ func defaultLaunch(cfg *config.Config, profileID string, targetURL string, incog
 		log.Debug().Str("XDG_SESSION_TYPE", os.Getenv("XDG_SESSION_TYPE")).Msg("Linux detected, but not Wayland session, skipping Wayland flags")
 	}
 
-	// 4. Add the target URL LAST
-	args = append(args, targetURL)
+	// 5. Add the app to open (Chromium only honours the URL if the app's scope covers it)
+	if appID != "" {
+		args = append(args, "--app-id="+appID)
+	}
+
+	// 6. Add the extra arguments
+	args = append(args, browser.ExtraArgs...)
+
+	// 7. Add the target URL LAST, or the file holding it (see URLPassMode)
+	args = append(args, urlArgs(browser.URLPassMode, url)...)
 
-	// Set the command arguments
+	// Set the command arguments. LaunchServices only passes arguments to an app it
+	// starts, so Chromium and Firefox are started again (open -n) to hand them over
+	// to the running instance.
+	if openBundle && (IsChromium(browser) || isFirefox(browser)) {
+		cmd.Args = slices.Insert(cmd.Args, 1, "-n")
+		cmd.Args = append(cmd.Args, "--args")
+	} else if openBundle && len(args) > 1 {
+		args = append([]string{url, "--args"}, args[:len(args)-1]...)
+	}
 	cmd.Args = append(cmd.Args, args...)
 
+	// 7. Add per-browser and per-profile environment variables (profile wins)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	// 8. Run it in the profile's working directory, priority and sandbox
+	return wrapCommand(cmd, profile)
+}
+
+// ValidateSandbox reports whether sandbox can be split into a wrapper command.
+func ValidateSandbox(sandbox string) error {
+	words, err := splitCommand(sandbox)
+	if err != nil {
+		return fmt.Errorf("invalid sandbox '%s': %w", sandbox, err)
+	}
+	if len(words) == 0 {
+		return fmt.Errorf("sandbox is empty")
+	}
+	return nil
+}
+
+// wrapCommand applies the working directory, nice level and sandbox of profile to the
+// browser command cmd, which is run as: nice -n <Nice> <Sandbox...> <browser...>.
+func wrapCommand(cmd *exec.Cmd, profile config.Profile) (*exec.Cmd, error) {
+	var prefix []string
+	if profile.Nice != 0 {
+		if runtime.GOOS == "windows" {
+			return nil, fmt.Errorf("profile '%s' sets a nice level, which is not supported on Windows", profile.ID)
+		}
+		prefix = append(prefix, "nice", "-n", strconv.Itoa(profile.Nice))
+	}
+	if profile.Sandbox != "" {
+		words, err := splitCommand(profile.Sandbox)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sandbox '%s' for profile '%s': %w", profile.Sandbox, profile.ID, err)
+		}
+		prefix = append(prefix, words...)
+	}
+	if len(prefix) > 0 {
+		wrapped := exec.Command(prefix[0], append(prefix[1:], cmd.Args...)...)
+		wrapped.Env = cmd.Env
+		cmd = wrapped
+	}
+	cmd.Dir = profile.WorkingDir
+	return cmd, nil
+}
+
+// windowArg returns the argument implementing browser.WindowMode, using the browser's
+// configured arguments or the Chromium/Firefox defaults. Chromium opens new tabs in a
+// running instance by default, so it needs no new-tab argument.
+func windowArg(browser config.Browser) string {
+	switch browser.WindowMode {
+	case config.WindowNew:
+		if browser.NewWindowArg != "" {
+			return browser.NewWindowArg
+		}
+		if IsChromium(browser) || isFirefox(browser) {
+			return "--new-window"
+		}
+	case config.WindowNewTab:
+		if browser.NewTabArg != "" {
+			return browser.NewTabArg
+		}
+		if isFirefox(browser) {
+			return "--new-tab"
+		}
+	}
+	return ""
+}
+
+// isFirefox reports whether browser looks Firefox-based, judged by its profile argument.
+func isFirefox(browser config.Browser) bool {
+	return strings.HasPrefix(browser.ProfileArg, "-P")
+}
+
+// profileArgs returns the arguments selecting profile with profileArg. The profile is
+// substituted for %s, or appended to an argument ending in "=" (e.g.
+// "--profile-directory="). A flag and %s separated by a space (e.g. Firefox's
+// "-P %s"), or Firefox's bare "-P", give the flag and the profile as two arguments.
+// Chromium's --profile-email selects the profile by its Email rather than ProfileDir.
+// Any other profileArg is passed as a simple flag.
+func profileArgs(profileArg string, profile config.Profile) []string {
+	value := profile.ProfileDir
+	if strings.HasPrefix(profileArg, "--profile-email") && profile.Email != "" {
+		value = profile.Email
+	}
+	if profileArg == "" || profileArg == config.ProfileAppleScript || value == "" {
+		return nil
+	}
+	if flag, ok := strings.CutSuffix(profileArg, " %s"); ok && !strings.Contains(flag, "%s") {
+		return []string{flag, value}
+	}
+	switch {
+	case strings.Contains(profileArg, "%s"):
+		return []string{strings.Replace(profileArg, "%s", value, 1)}
+	case strings.HasSuffix(profileArg, "="):
+		return []string{profileArg + value}
+	case profileArg == "-P":
+		return []string{profileArg, value}
+	}
+	return []string{profileArg}
+}
+
+// windowScript activates the browser (argv 1: bundle ID), opens a window with the
+// given shortcut and navigates it to argv 2. It requires rurl (or the terminal) to be
+// granted Accessibility access for System Events keystrokes.
+func windowScript(modifiers string) []string {
+	return []string{
+		"on run argv",
+		"tell application id (item 1 of argv) to activate",
+		"delay 0.3",
+		"tell application \"System Events\"",
+		"keystroke \"n\" using " + modifiers,
+		"delay 0.5",
+		"keystroke \"l\" using {command down}",
+		"keystroke (item 2 of argv)",
+		"key code 36",
+		"end tell",
+		"end run",
+	}
+}
+
+// profileWindowScript activates the browser (argv 1: bundle ID), opens a window of the
+// profile named by argv 3 from its File > New Window menu ("New <profile> Window", as
+// Safari names them) and navigates it to argv 2. Like windowScript, it requires
+// Accessibility access.
+func profileWindowScript() []string {
+	return []string{
+		"on run argv",
+		"tell application id (item 1 of argv) to activate",
+		"delay 0.3",
+		"tell application \"System Events\"",
+		"tell (first application process whose bundle identifier is (item 1 of argv))",
+		"click menu item (\"New \" & (item 3 of argv) & \" Window\") of menu 1 of menu item \"New Window\" of menu 1 of menu bar item \"File\" of menu bar 1",
+		"end tell",
+		"delay 0.5",
+		"keystroke \"l\" using {command down}",
+		"keystroke (item 2 of argv)",
+		"key code 36",
+		"end tell",
+		"end run",
+	}
+}
+
+// appleScriptProfileCommand builds an osascript command opening url in a new window of
+// the browser profile called name.
+func (l *ExecLauncher) appleScriptProfileCommand(browser config.Browser, name, url string) (*exec.Cmd, error) {
+	if browser.BundleID == "" {
+		return nil, fmt.Errorf("browser '%s' needs a bundle_id to open profile windows via AppleScript", browser.BrowserID)
+	}
+	script := profileWindowScript()
+	args := make([]string, 0, 2*len(script)+3)
+	for _, line := range script {
+		args = append(args, "-e", line)
+	}
+	// URL and profile name are passed as arguments, so they cannot alter the script
+	args = append(args, browser.BundleID, url, name)
+
+	return exec.Command("osascript", args...), nil
+}
+
+// appleScriptWindowCommand builds an osascript command opening url in a new window,
+// private (Shift-Cmd-N) if private is set.
+func (l *ExecLauncher) appleScriptWindowCommand(browser config.Browser, url string, private bool) (*exec.Cmd, error) {
+	if browser.BundleID == "" {
+		return nil, fmt.Errorf("browser '%s' needs a bundle_id to open windows via AppleScript", browser.BrowserID)
+	}
+	script := windowScript("{command down}")
+	if private {
+		script = windowScript("{command down, shift down}")
+	}
+	args := make([]string, 0, 2*len(script)+2)
+	for _, line := range script {
+		args = append(args, "-e", line)
+	}
+	// URL is passed as an argument rather than interpolated, so it cannot alter the script
+	args = append(args, browser.BundleID, url)
+
+	return exec.Command("osascript", args...), nil
+}
+
+// buildEnv merges environment maps into KEY=VALUE pairs, later maps taking precedence.
+// Names are kept as written since they are case-sensitive on Unix; on Windows, where
+// they are not, names differing only in case are treated as the same variable.
+func buildEnv(envs ...map[string]string) []string {
+	merged := make(map[string]string)
+	names := make(map[string]string) // Merge key -> name as last written
+	for _, env := range envs {
+		for k, v := range env {
+			key := k
+			if runtime.GOOS == "windows" {
+				key = strings.ToUpper(k)
+			}
+			merged[key] = v
+			names[key] = k
+		}
+	}
+	keys := make([]string, 0, len(merged))
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]string, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, names[k]+"="+merged[k])
+	}
+	return result
+}
+
+// LaunchBrowser starts the browser asynchronously and releases the process.
+func (l *ExecLauncher) LaunchBrowser(browser config.Browser, profile config.Profile, url string, incognito bool) error {
+	cmd, err := l.constructCommand(browser, profile, url, incognito)
+	if err != nil {
+		return err
+	}
+	return l.start(cmd, browser, profile, url)
+}
+
+// LaunchApp opens the installed PWA/Chrome app appID using the given profile, passing
+// url for the app to open if it is within the app's scope. Only Chromium-based
+// browsers support apps.
+func (l *ExecLauncher) LaunchApp(browser config.Browser, profile config.Profile, appID string, url string) error {
+	if !IsChromium(browser) {
+		return fmt.Errorf("browser '%s' does not support app windows (Chromium-based browsers only)", browser.BrowserID)
+	}
+	cmd, err := l.buildCommand(browser, profile, url, false, appID)
+	if err != nil {
+		return err
+	}
+	return l.start(cmd, browser, profile, url)
+}
+
+// start runs a prepared browser command asynchronously, handing it url as
+// browser.URLPassMode requires, and releases the process. Terminal browsers are instead
+// run in the foreground, see runInTerminal, and remote launches are waited for, see
+// runRemote.
+func (l *ExecLauncher) start(cmd *exec.Cmd, browser config.Browser, profile config.Profile, url string) error {
+
 	// Debug logging for the exact command and arguments
 	log.Debug().
 		Str("browser", browser.Name).
@@ -84,8 +457,33 @@ func defaultLaunch(cfg *config.Config, profileID string, targetURL string, incog
 		Interface("args", cmd.Args).
 		Str("profile_dir", profile.ProfileDir).
 		Str("profile_arg", browser.ProfileArg).
+		Strs("extra_env", buildEnv(browser.Env, profile.Env)).
 		Msg("Preparing to launch browser")
 
+	release, err := passURL(cmd, browser, url)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if browser.Terminal {
+		return runInTerminal(cmd, browser)
+	}
+	if browser.Remote != nil {
+		return runRemote(cmd, browser)
+	}
+
+	if l.MonitorWait > 0 {
+		result, err := watchProcess(cmd, l.MonitorWait)
+		if err != nil {
+			return err
+		}
+		if !result.Accepted() {
+			return &LaunchFailedError{Args: result.Args, ExitCode: result.ExitCode, Stderr: result.Stderr}
+		}
+		return nil
+	}
+
 	// Run the command asynchronously
 	if err := cmd.Start(); err != nil {
 		log.Error().Err(err).Str("command", cmd.Path).Interface("args", cmd.Args).Msg("Failed to start browser process")
@@ -100,6 +498,147 @@ func defaultLaunch(cfg *config.Config, profileID string, targetURL string, incog
 	return nil
 }
 
+// hasTerminal reports whether terminal browsers can be run. Tests may replace it.
+var hasTerminal = HasTerminal
+
+// runInTerminal runs a terminal browser attached to the current terminal and waits
+// for the user to quit it.
+func runInTerminal(cmd *exec.Cmd, browser config.Browser) error {
+	if !hasTerminal() {
+		return fmt.Errorf("terminal browser '%s' needs an interactive terminal to run in", browser.BrowserID)
+	}
+	if cmd.Stdin == nil { // Unless it reads the URL from stdin
+		cmd.Stdin = os.Stdin
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("terminal browser %s with args %v failed: %w", cmd.Path, cmd.Args, err)
+	}
+	return nil
+}
+
+// ResolveIncognito returns whether a launch in profileID of cfg opens a private window.
+// In order of precedence: always if the profile has AlwaysIncognito set, then as flag
+// says if it is not nil (the --incognito command line flag), then as the matched rule
+// says (rule).
+func ResolveIncognito(cfg *config.Config, profileID string, rule bool, flag *bool) bool {
+	if profile, err := cfg.FindProfileByID(profileID); err == nil && profile.AlwaysIncognito {
+		return true
+	}
+	if flag != nil {
+		return *flag
+	}
+	return rule
+}
+
+// LaunchProfile resolves the profile and its browser from cfg and opens the URL using l.
+func LaunchProfile(l Launcher, cfg *config.Config, profileID string, targetURL string, incognito bool) error {
+	return LaunchProfileWindow(l, cfg, profileID, targetURL, incognito, "")
+}
+
+// LaunchProfileWindow is like LaunchProfile, opening the URL according to windowMode
+// (one of the config.Window* modes) instead of the browser's WindowMode if it is set,
+// and passing extraArgs after the browser's ExtraArgs.
+func LaunchProfileWindow(l Launcher, cfg *config.Config, profileID string, targetURL string, incognito bool, windowMode string, extraArgs ...string) error {
+	profile, err := cfg.FindProfileByID(profileID)
+	if err != nil {
+		return fmt.Errorf("cannot launch profile: %w", err)
+	}
+
+	browser, err := cfg.GetProfileBrowser(profile)
+	if err != nil {
+		return fmt.Errorf("cannot find browser '%s' for profile '%s': %w", profile.BrowserID, profile.Name, err)
+	}
+
+	b := *browser
+	b.WindowMode = WindowMode(cfg, b, windowMode)
+	if len(extraArgs) > 0 {
+		b.ExtraArgs = append(slices.Clip(b.ExtraArgs), extraArgs...)
+	}
+	return l.LaunchBrowser(b, *profile, targetURL, incognito || profile.AlwaysIncognito)
+}
+
+// WindowMode returns the window mode of a launch in browser: windowMode (a rule's) if
+// set, then the browser's WindowMode, then a new window if the configuration's
+// Behavior.NewWindow is set.
+func WindowMode(cfg *config.Config, browser config.Browser, windowMode string) string {
+	switch {
+	case windowMode != "":
+		return windowMode
+	case browser.WindowMode != "":
+		return browser.WindowMode
+	case cfg.Behavior.NewWindow:
+		return config.WindowNew
+	}
+	return ""
+}
+
+// LaunchSimplified opens the URL in profileID's browser without the profile, window
+// and extra arguments, keeping only the incognito argument if requested. It is a
+// fallback after a launch failed because the browser rejected its arguments.
+func LaunchSimplified(l Launcher, cfg *config.Config, profileID string, targetURL string, incognito bool) error {
+	profile, err := cfg.FindProfileByID(profileID)
+	if err != nil {
+		return fmt.Errorf("cannot launch profile: %w", err)
+	}
+
+	browser, err := cfg.GetProfileBrowser(profile)
+	if err != nil {
+		return fmt.Errorf("cannot find browser '%s' for profile '%s': %w", profile.BrowserID, profile.Name, err)
+	}
+
+	b := *browser
+	b.WindowMode = ""
+	b.ExtraArgs = nil
+	p := *profile
+	p.ProfileDir = ""
+	p.UserDataDir = ""
+	return l.LaunchBrowser(b, p, targetURL, incognito || profile.AlwaysIncognito)
+}
+
+// LaunchProfileApp is like LaunchProfile but opens the URL in the installed PWA/Chrome
+// app appID. If appID is empty, or l or the browser cannot open apps, the URL is
+// opened normally (a warning is logged in the latter case). Apps cannot be opened
+// privately, so profiles with AlwaysIncognito open the URL in a private window instead.
+func LaunchProfileApp(l Launcher, cfg *config.Config, profileID string, appID string, targetURL string, incognito bool) error {
+	if appID == "" {
+		return LaunchProfile(l, cfg, profileID, targetURL, incognito)
+	}
+
+	profile, err := cfg.FindProfileByID(profileID)
+	if err != nil {
+		return fmt.Errorf("cannot launch profile: %w", err)
+	}
+
+	browser, err := cfg.GetProfileBrowser(profile)
+	if err != nil {
+		return fmt.Errorf("cannot find browser '%s' for profile '%s': %w", profile.BrowserID, profile.Name, err)
+	}
+
+	if profile.AlwaysIncognito {
+		log.Warn().Str("app_id", appID).Str("profile_id", profileID).Msg("Profile always opens privately, opening URL in a private window instead of the app")
+		return l.LaunchBrowser(*browser, *profile, targetURL, true)
+	}
+	al, ok := l.(AppLauncher)
+	if !ok || !IsChromium(*browser) {
+		log.Warn().Str("app_id", appID).Str("browser_id", browser.BrowserID).Msg("Browser cannot open app windows, opening URL normally")
+		return l.LaunchBrowser(*browser, *profile, targetURL, incognito)
+	}
+	if incognito {
+		log.Warn().Str("app_id", appID).Msg("Incognito is not supported for app windows, ignoring")
+	}
+	return al.LaunchApp(*browser, *profile, appID, targetURL)
+}
+
+// LaunchFunc defines the signature for the Launch function to allow mocking in tests
+type LaunchFunc func(cfg *config.Config, profileID string, targetURL string, incognito bool) error
+
+// defaultLaunch is the implementation of Launch that actually launches browsers
+func defaultLaunch(cfg *config.Config, profileID string, targetURL string, incognito bool) error {
+	return LaunchProfile(NewExecLauncher(), cfg, profileID, targetURL, incognito)
+}
+
 // The current implementation of Launch, which can be replaced in tests
 var actualLaunchFunc LaunchFunc = defaultLaunch
 
@@ -1,10 +1,23 @@
 package launcher
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/wsl"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -166,3 +179,882 @@ func TestLaunch(t *testing.T) {
 	err = Launch(cfg, "nonexistent-profile", "https://example.com", false)
 	assert.Error(t, err)
 }
+
+// Ensure the mock satisfies the production interface
+var _ Launcher = (*mockLauncher)(nil)
+
+func TestExecLauncherConstructCommand(t *testing.T) {
+	t.Setenv("XDG_SESSION_TYPE", "x11")
+	l := NewExecLauncher()
+	browser := config.Browser{
+		Name:         "Test Browser",
+		BrowserID:    "test",
+		Executable:   "/bin/echo",
+		ProfileArg:   "--profile-directory=%s",
+		IncognitoArg: "--incognito",
+	}
+	profile := config.Profile{
+		Name:       "Work",
+		ProfileDir: "Profile 1",
+	}
+
+	cmd, err := l.constructCommand(browser, profile, "https://example.com", true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/bin/echo", "--profile-directory=Profile 1", "--incognito", "https://example.com"}, cmd.Args)
+
+	// Flatpak executables are split into command and arguments
+	browser.Executable = "flatpak run com.google.Chrome"
+	cmd, err = l.constructCommand(browser, profile, "https://example.com", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"flatpak", "run", "com.google.Chrome", "--profile-directory=Profile 1", "https://example.com"}, cmd.Args)
+
+	// A profile's executable override replaces the browser's executable, keeping its arguments
+	devProfile := profile
+	devProfile.ExecutableOverride = "/bin/true"
+	cmd, err = l.constructCommand(browser, devProfile, "https://example.com", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/bin/true", "--profile-directory=Profile 1", "https://example.com"}, cmd.Args)
+
+	// A profile in another user data directory passes it before the profile directory
+	portableProfile := profile
+	portableProfile.UserDataDir = "/data/chrome"
+	cmd, err = l.constructCommand(browser, portableProfile, "https://example.com", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"flatpak", "run", "com.google.Chrome", "--user-data-dir=/data/chrome", "--profile-directory=Profile 1", "https://example.com"}, cmd.Args)
+
+	// Only Chromium-based browsers have user data directories
+	firefox := browser
+	firefox.ProfileArg = "-P %s"
+	_, err = l.constructCommand(firefox, portableProfile, "https://example.com", false)
+	assert.Error(t, err)
+
+	// Missing executable is an error
+	browser.Executable = ""
+	_, err = l.constructCommand(browser, profile, "https://example.com", false)
+	assert.Error(t, err)
+}
+
+func TestLaunchProfile(t *testing.T) {
+	mock := newMockLauncher()
+	cfg := &config.Config{
+		Profiles: []config.Profile{
+			{ID: "test-profile", Name: "Test Profile", BrowserID: "test", ProfileDir: "Default"},
+			{ID: "orphan-profile", Name: "Orphan", BrowserID: "missing"},
+		},
+		Browsers: []config.Browser{
+			{Name: "Test Browser", BrowserID: "test", Executable: "/bin/echo"},
+		},
+	}
+
+	err := LaunchProfile(mock, cfg, "test-profile", "https://example.com", true)
+	assert.NoError(t, err)
+	assert.Len(t, mock.launchAttempts, 1)
+	assert.Equal(t, "test", mock.launchAttempts[0].browser.BrowserID)
+	assert.Equal(t, "Default", mock.launchAttempts[0].profile.ProfileDir)
+	assert.True(t, mock.launchAttempts[0].incognito)
+
+	// Unknown profile and missing browser are errors and do not reach the launcher
+	assert.Error(t, LaunchProfile(mock, cfg, "nonexistent", "https://example.com", false))
+	assert.Error(t, LaunchProfile(mock, cfg, "orphan-profile", "https://example.com", false))
+	assert.Len(t, mock.launchAttempts, 1)
+}
+
+func TestLaunchProfileAlwaysIncognito(t *testing.T) {
+	mock := &appMockLauncher{}
+	cfg := &config.Config{
+		Profiles: []config.Profile{{ID: "throwaway", BrowserID: "chrome", AlwaysIncognito: true}},
+		Browsers: []config.Browser{{Name: "Chrome", BrowserID: "chrome", Executable: "/usr/bin/google-chrome"}},
+	}
+	assert.True(t, ResolveIncognito(cfg, "throwaway", false, nil))
+	off := false
+	assert.True(t, ResolveIncognito(cfg, "throwaway", false, &off))
+	assert.False(t, ResolveIncognito(cfg, "missing", true, &off))
+
+	assert.NoError(t, LaunchProfile(mock, cfg, "throwaway", "https://example.com", false))
+	assert.NoError(t, LaunchSimplified(mock, cfg, "throwaway", "https://example.com", false))
+	assert.NoError(t, LaunchProfileApp(mock, cfg, "throwaway", "abcdef", "https://example.com", false))
+	assert.Empty(t, mock.appIDs)
+	assert.Len(t, mock.launchAttempts, 3)
+	for _, a := range mock.launchAttempts {
+		assert.True(t, a.incognito)
+	}
+}
+
+// appMockLauncher also records app launches
+type appMockLauncher struct {
+	mockLauncher
+	appIDs []string
+}
+
+func (m *appMockLauncher) LaunchApp(browser config.Browser, profile config.Profile, appID string, url string) error {
+	m.appIDs = append(m.appIDs, appID)
+	return nil
+}
+
+func TestExecLauncherAppCommand(t *testing.T) {
+	l := NewExecLauncher()
+	browser := config.Browser{
+		Executable:   "/usr/bin/google-chrome",
+		ProfileArg:   "--profile-directory=%s",
+		IncognitoArg: "--incognito",
+	}
+	profile := config.Profile{ProfileDir: "Profile 1"}
+
+	cmd, err := l.buildCommand(browser, profile, "https://teams.microsoft.com/l/chat", true, "cifhbcnohmdccbgoicgdjpfamggdegmo")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"/usr/bin/google-chrome",
+		"--profile-directory=Profile 1",
+		"--app-id=cifhbcnohmdccbgoicgdjpfamggdegmo",
+		"https://teams.microsoft.com/l/chat",
+	}, cmd.Args)
+
+	// Non-Chromium browsers cannot open apps
+	err = l.LaunchApp(config.Browser{Executable: "firefox", ProfileArg: "-P %s"}, profile, "someapp", "https://example.com")
+	assert.Error(t, err)
+}
+
+func TestLaunchProfileApp(t *testing.T) {
+	cfg := &config.Config{
+		Profiles: []config.Profile{
+			{ID: "chrome-work", Name: "Work", BrowserID: "chrome", ProfileDir: "Profile 1"},
+			{ID: "firefox", Name: "Firefox", BrowserID: "firefox", ProfileDir: "default"},
+		},
+		Browsers: []config.Browser{
+			{Name: "Chrome", BrowserID: "chrome", Executable: "chrome", ProfileArg: "--profile-directory=%s"},
+			{Name: "Firefox", BrowserID: "firefox", Executable: "firefox", ProfileArg: "-P %s"},
+		},
+	}
+
+	mock := &appMockLauncher{}
+	assert.NoError(t, LaunchProfileApp(mock, cfg, "chrome-work", "teams", "https://teams.microsoft.com", false))
+	assert.Equal(t, []string{"teams"}, mock.appIDs)
+	assert.Empty(t, mock.launchAttempts)
+
+	// Non-Chromium browsers and an empty app ID fall back to a normal launch
+	assert.NoError(t, LaunchProfileApp(mock, cfg, "firefox", "teams", "https://teams.microsoft.com", false))
+	assert.NoError(t, LaunchProfileApp(mock, cfg, "chrome-work", "", "https://example.com", false))
+	assert.Len(t, mock.appIDs, 1)
+	assert.Len(t, mock.launchAttempts, 2)
+
+	// Launchers without app support fall back as well
+	plain := newMockLauncher()
+	assert.NoError(t, LaunchProfileApp(plain, cfg, "chrome-work", "teams", "https://teams.microsoft.com", false))
+	assert.Len(t, plain.launchAttempts, 1)
+}
+
+func TestExecLauncherAppleScriptPrivateWindow(t *testing.T) {
+	l := NewExecLauncher()
+	browser := config.Browser{
+		BrowserID:    "orion",
+		Executable:   "open -b com.kagi.kagimacOS",
+		BundleID:     "com.kagi.kagimacOS",
+		IncognitoArg: config.IncognitoAppleScript,
+	}
+
+	// Normal launches go through "open -b <bundle>"
+	cmd, err := l.constructCommand(browser, config.Profile{}, "https://example.com", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"open", "-b", "com.kagi.kagimacOS", "https://example.com"}, cmd.Args)
+
+	// Private launches use osascript, passing the bundle ID and URL as arguments
+	cmd, err = l.constructCommand(browser, config.Profile{}, `https://example.com/?q="x"`, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "osascript", cmd.Args[0])
+	assert.Equal(t, []string{"com.kagi.kagimacOS", `https://example.com/?q="x"`}, cmd.Args[len(cmd.Args)-2:])
+	assert.NotContains(t, cmd.Args, config.IncognitoAppleScript)
+
+	// Env cannot be passed through LaunchServices or AppleScript
+	_, err = l.constructCommand(browser, config.Profile{Env: map[string]string{"MOZ_X": "1"}}, "https://example.com", false)
+	assert.Error(t, err)
+	_, err = l.constructCommand(browser, config.Profile{Env: map[string]string{"MOZ_X": "1"}}, "https://example.com", true)
+	assert.Error(t, err)
+
+	// A bundle ID is required
+	browser.BundleID = ""
+	_, err = l.constructCommand(browser, config.Profile{}, "https://example.com", true)
+	assert.Error(t, err)
+}
+
+func TestExecLauncherAppleScriptProfileWindow(t *testing.T) {
+	l := NewExecLauncher()
+	safari := config.Browser{
+		BrowserID:  "safari",
+		Executable: "/Applications/Safari.app/Contents/MacOS/Safari",
+		BundleID:   "com.apple.Safari",
+		ProfileArg: config.ProfileAppleScript,
+	}
+
+	// Named profiles are opened through osascript, passing the bundle ID, URL and name
+	cmd, err := l.constructCommand(safari, config.Profile{ProfileDir: "Work"}, "https://example.com", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "osascript", cmd.Args[0])
+	assert.Equal(t, []string{"com.apple.Safari", "https://example.com", "Work"}, cmd.Args[len(cmd.Args)-3:])
+
+	// The default profile is launched normally, without the profile argument
+	cmd, err = l.constructCommand(safari, config.Profile{}, "https://example.com", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{safari.Executable, "https://example.com"}, cmd.Args)
+
+	// Env cannot be passed through AppleScript, and a bundle ID is required
+	_, err = l.constructCommand(safari, config.Profile{ProfileDir: "Work", Env: map[string]string{"X": "1"}}, "https://example.com", false)
+	assert.Error(t, err)
+	safari.BundleID = ""
+	_, err = l.constructCommand(safari, config.Profile{ProfileDir: "Work"}, "https://example.com", false)
+	assert.Error(t, err)
+}
+
+func TestExecLauncherWindowMode(t *testing.T) {
+	l := NewExecLauncher()
+	firefox := config.Browser{BrowserID: "firefox", Executable: "/usr/bin/firefox", ProfileArg: "-P %s"}
+	chrome := config.Browser{BrowserID: "chrome", Executable: "/usr/bin/chrome", ProfileArg: "--profile-directory=%s"}
+	custom := config.Browser{BrowserID: "custom", Executable: "/usr/bin/custom", NewTabArg: "-tab"}
+
+	tests := []struct {
+		name    string
+		browser config.Browser
+		mode    string
+		want    []string
+	}{
+		{"firefox new window", firefox, config.WindowNew, []string{"/usr/bin/firefox", "--new-window", "https://example.com"}},
+		{"firefox new tab", firefox, config.WindowNewTab, []string{"/usr/bin/firefox", "--new-tab", "https://example.com"}},
+		{"chrome new window", chrome, config.WindowNew, []string{"/usr/bin/chrome", "--new-window", "https://example.com"}},
+		{"chrome new tab", chrome, config.WindowNewTab, []string{"/usr/bin/chrome", "https://example.com"}},
+		{"reuse", firefox, config.WindowReuse, []string{"/usr/bin/firefox", "https://example.com"}},
+		{"custom new tab", custom, config.WindowNewTab, []string{"/usr/bin/custom", "-tab", "https://example.com"}},
+		{"custom new window", custom, config.WindowNew, []string{"/usr/bin/custom", "https://example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_SESSION_TYPE", "x11")
+			tt.browser.WindowMode = tt.mode
+			args, err := l.CommandLine(tt.browser, config.Profile{}, "https://example.com", false, "")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, args)
+		})
+	}
+
+	// Apps ignore the window mode
+	chrome.WindowMode = config.WindowNew
+	args, err := l.CommandLine(chrome, config.Profile{}, "https://example.com", false, "abc")
+	assert.NoError(t, err)
+	assert.NotContains(t, args, "--new-window")
+
+	// LaunchProfileWindow overrides the browser's mode
+	mock := newMockLauncher()
+	cfg := &config.Config{
+		Browsers: []config.Browser{firefox},
+		Profiles: []config.Profile{{ID: "ff", BrowserID: "firefox"}},
+	}
+	assert.NoError(t, LaunchProfileWindow(mock, cfg, "ff", "https://example.com", false, config.WindowNewTab))
+	if assert.Len(t, mock.launchAttempts, 1) {
+		assert.Equal(t, config.WindowNewTab, mock.launchAttempts[0].browser.WindowMode)
+	}
+
+	// The configuration's default only applies when neither the rule nor the browser
+	// sets a mode
+	cfg.Behavior.NewWindow = true
+	assert.Equal(t, config.WindowNew, WindowMode(cfg, firefox, ""))
+	assert.Equal(t, config.WindowReuse, WindowMode(cfg, firefox, config.WindowReuse))
+	firefox.WindowMode = config.WindowNewTab
+	assert.Equal(t, config.WindowNewTab, WindowMode(cfg, firefox, ""))
+}
+
+func TestExecLauncherMacOSOpen(t *testing.T) {
+	l := NewExecLauncher()
+	chrome := config.Browser{BrowserID: "chrome", Executable: "open -b com.google.Chrome", BundleID: "com.google.Chrome", ProfileArg: "--profile-directory=", IncognitoArg: "--incognito"}
+	firefox := config.Browser{BrowserID: "firefox", Executable: "open -b org.mozilla.firefox", BundleID: "org.mozilla.firefox", ProfileArg: "-P"}
+	safari := config.Browser{BrowserID: "safari", Executable: "open -b com.apple.Safari", BundleID: "com.apple.Safari"}
+	profile := config.Profile{ProfileDir: "Profile 1"}
+
+	tests := []struct {
+		name      string
+		browser   config.Browser
+		profile   config.Profile
+		incognito bool
+		mode      string
+		extraArgs []string
+		want      []string
+	}{
+		{"chromium", chrome, profile, true, "", nil, []string{"open", "-n", "-b", "com.google.Chrome", "--args", "--profile-directory=Profile 1", "--incognito", "https://example.com"}},
+		{"chromium new window", chrome, config.Profile{}, false, config.WindowNew, nil, []string{"open", "-n", "-b", "com.google.Chrome", "--args", "--new-window", "https://example.com"}},
+		{"firefox", firefox, config.Profile{ProfileDir: "work"}, false, config.WindowNew, nil, []string{"open", "-n", "-b", "org.mozilla.firefox", "--args", "-P", "work", "--new-window", "https://example.com"}},
+		{"safari", safari, profile, false, "", nil, []string{"open", "-b", "com.apple.Safari", "https://example.com"}},
+		{"safari extra args", safari, config.Profile{}, false, "", []string{"-debug"}, []string{"open", "-b", "com.apple.Safari", "https://example.com", "--args", "-debug"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.browser.WindowMode = tt.mode
+			tt.browser.ExtraArgs = tt.extraArgs
+			cmd, err := l.constructCommand(tt.browser, tt.profile, "https://example.com", tt.incognito)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, cmd.Args)
+		})
+	}
+
+	// Browsers without window arguments open new windows through AppleScript
+	safari.WindowMode = config.WindowNew
+	cmd, err := l.constructCommand(safari, config.Profile{}, "https://example.com", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "osascript", cmd.Args[0])
+	assert.Contains(t, cmd.Args, `keystroke "n" using {command down}`)
+	assert.Equal(t, []string{"com.apple.Safari", "https://example.com"}, cmd.Args[len(cmd.Args)-2:])
+}
+
+func TestProfileArgs(t *testing.T) {
+	profile := config.Profile{ProfileDir: "Profile 1", Email: "me@example.com"}
+	tests := []struct {
+		profileArg string
+		want       []string
+	}{
+		{"--profile-directory=%s", []string{"--profile-directory=Profile 1"}},
+		{"--profile-directory=", []string{"--profile-directory=Profile 1"}},
+		{"-P %s", []string{"-P", "Profile 1"}},
+		{"-P", []string{"-P", "Profile 1"}},
+		{"--profile-email=%s", []string{"--profile-email=me@example.com"}},
+		{"--profile-email=", []string{"--profile-email=me@example.com"}},
+		{"--use-this-profile", []string{"--use-this-profile"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, profileArgs(tt.profileArg, profile), tt.profileArg)
+	}
+
+	// Profiles without an email are selected by directory
+	assert.Equal(t, []string{"--profile-email=Default"}, profileArgs("--profile-email=%s", config.Profile{ProfileDir: "Default"}))
+	assert.Nil(t, profileArgs("-P %s", config.Profile{}))
+}
+
+func TestExecLauncherExtraArgs(t *testing.T) {
+	t.Setenv("XDG_SESSION_TYPE", "x11")
+	l := NewExecLauncher()
+	chrome := config.Browser{BrowserID: "chrome", Executable: "/usr/bin/chrome", ProfileArg: "--profile-directory=%s",
+		ExtraArgs: []string{"--disable-gpu"}}
+
+	args, err := l.CommandLine(chrome, config.Profile{ProfileDir: "Default"}, "https://example.com", false, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/usr/bin/chrome", "--profile-directory=Default", "--disable-gpu", "https://example.com"}, args)
+
+	// A rule's extra arguments follow the browser's, without changing the configuration
+	mock := newMockLauncher()
+	cfg := &config.Config{
+		Browsers: []config.Browser{chrome},
+		Profiles: []config.Profile{{ID: "work", BrowserID: "chrome", ProfileDir: "Default"}},
+	}
+	assert.NoError(t, LaunchProfileWindow(mock, cfg, "work", "https://example.com", false, "", "--app=https://example.com"))
+	if assert.Len(t, mock.launchAttempts, 1) {
+		assert.Equal(t, []string{"--disable-gpu", "--app=https://example.com"}, mock.launchAttempts[0].browser.ExtraArgs)
+	}
+	assert.Equal(t, []string{"--disable-gpu"}, cfg.Browsers[0].ExtraArgs)
+}
+
+func TestExecLauncherEnv(t *testing.T) {
+	l := NewExecLauncher()
+	browser := config.Browser{
+		BrowserID:  "test",
+		Executable: "/bin/echo",
+		Env:        map[string]string{"https_proxy": "http://browser-proxy:3128", "LANG": "en_GB.UTF-8"},
+	}
+	profile := config.Profile{
+		ProfileDir: "Default",
+		Env:        map[string]string{"https_proxy": "http://work-proxy:3128", "no_proxy": "localhost"},
+	}
+
+	cmd, err := l.constructCommand(browser, profile, "https://example.com", false)
+	assert.NoError(t, err)
+	assert.Contains(t, cmd.Env, "https_proxy=http://work-proxy:3128")
+	assert.Contains(t, cmd.Env, "no_proxy=localhost")
+	assert.Contains(t, cmd.Env, "LANG=en_GB.UTF-8")
+	assert.NotContains(t, cmd.Env, "https_proxy=http://browser-proxy:3128")
+
+	// Names differing in case are separate variables, except on Windows
+	env := buildEnv(map[string]string{"HTTPS_PROXY": "upper"}, map[string]string{"https_proxy": "lower"})
+	if runtime.GOOS == "windows" {
+		assert.Equal(t, []string{"https_proxy=lower"}, env)
+	} else {
+		assert.Equal(t, []string{"HTTPS_PROXY=upper", "https_proxy=lower"}, env)
+	}
+
+	// Without extra env the process inherits the environment unchanged
+	cmd, err = l.constructCommand(config.Browser{Executable: "/bin/echo"}, config.Profile{}, "https://example.com", false)
+	assert.NoError(t, err)
+	assert.Nil(t, cmd.Env)
+}
+
+func TestExecLauncherWrapCommand(t *testing.T) {
+	l := NewExecLauncher()
+	browser := config.Browser{BrowserID: "firefox", Executable: "firefox", ProfileArg: "-P %s"}
+	profile := config.Profile{ID: "untrusted", ProfileDir: "untrusted", WorkingDir: "/tmp", Sandbox: `firejail --private "--name=rurl untrusted"`}
+
+	cmd, err := l.constructCommand(browser, profile, "https://example.com", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"firejail", "--private", "--name=rurl untrusted", "firefox", "-P", "untrusted", "https://example.com"}, cmd.Args)
+	assert.Equal(t, "/tmp", cmd.Dir)
+
+	// The nice level wraps the sandbox
+	profile.Nice = 10
+	cmd, err = l.constructCommand(browser, profile, "https://example.com", false)
+	if runtime.GOOS == "windows" {
+		assert.Error(t, err)
+	} else {
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"nice", "-n", "10", "firejail", "--private", "--name=rurl untrusted", "firefox", "-P", "untrusted", "https://example.com"}, cmd.Args)
+	}
+
+	// Broken sandboxes and remote browsers are errors
+	profile.Nice = 0
+	profile.Sandbox = `firejail "--private`
+	_, err = l.constructCommand(browser, profile, "https://example.com", false)
+	assert.Error(t, err)
+	assert.Error(t, ValidateSandbox(profile.Sandbox))
+	profile.Sandbox = "firejail"
+	assert.NoError(t, ValidateSandbox(profile.Sandbox))
+	browser.Remote = &config.RemoteTarget{Host: "desktop"}
+	_, err = l.constructCommand(browser, profile, "https://example.com", false)
+	assert.Error(t, err)
+}
+
+func TestSupportsIncognito(t *testing.T) {
+	tests := []struct {
+		browser config.Browser
+		want    bool
+	}{
+		{config.Browser{IncognitoArg: "--incognito"}, true},
+		{config.Browser{IncognitoArg: config.IncognitoAppleScript}, true},
+		{config.Browser{}, false}, // e.g. Arc
+		{config.Browser{IncognitoArg: "-private", Terminal: true}, false},
+		{config.Browser{IncognitoArg: config.IncognitoAppleScript, Remote: &config.RemoteTarget{Host: "mac"}}, false},
+		// Remote commands must pass the argument on, which the default one does not
+		{config.Browser{IncognitoArg: "--incognito", Remote: &config.RemoteTarget{Host: "desk"}}, false},
+		{config.Browser{IncognitoArg: "--incognito", Remote: &config.RemoteTarget{Host: "desk", Command: "chromium {url}"}}, false},
+		{config.Browser{IncognitoArg: "--incognito", Remote: &config.RemoteTarget{Host: "desk", Command: "chromium {incognito} {url}"}}, true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, SupportsIncognito(tt.browser), "%+v", tt.browser)
+	}
+}
+
+func TestExecLauncherEphemeral(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir()) // The cache directory on macOS
+	l := NewExecLauncher()
+	chromium := config.Browser{BrowserID: "chromium", Executable: "chromium", ProfileArg: "--profile-directory=%s", IncognitoArg: "--incognito"}
+	firefox := config.Browser{BrowserID: "firefox", Executable: "firefox", ProfileArg: "-P %s"}
+	profile := config.Profile{ID: "sketchy", ProfileDir: "Default", Ephemeral: true}
+
+	// Each launch gets a new directory instead of the profile's
+	cmd, err := l.constructCommand(chromium, profile, "https://example.com", true)
+	assert.NoError(t, err)
+	root, err := EphemeralRoot()
+	assert.NoError(t, err)
+	dir := strings.TrimPrefix(cmd.Args[1], "--user-data-dir=")
+	assert.Equal(t, root, filepath.Dir(dir))
+	assert.DirExists(t, dir)
+	assert.Equal(t, []string{"chromium", "--user-data-dir=" + dir, "--no-first-run", "--no-default-browser-check", "--incognito", "https://example.com"}, cmd.Args)
+	cmd, err = l.constructCommand(firefox, profile, "https://example.com", false)
+	assert.NoError(t, err)
+	assert.NotEqual(t, dir, cmd.Args[2])
+	assert.Equal(t, []string{"firefox", "-profile", cmd.Args[2], "-no-remote", "https://example.com"}, cmd.Args)
+
+	// Shown command lines do not create a directory
+	args, err := l.CommandLine(chromium, profile, "https://example.com", false, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "--user-data-dir="+ephemeralPlaceholder, args[1])
+
+	_, err = l.constructCommand(config.Browser{BrowserID: "epiphany", Executable: "epiphany"}, profile, "https://example.com", false)
+	assert.ErrorContains(t, err, "does not support ephemeral profiles")
+	_, err = l.buildCommand(chromium, profile, "https://example.com", false, "app")
+	assert.Error(t, err)
+}
+
+func TestCleanEphemeral(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	removed, inUse, err := CleanEphemeral(false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, removed+inUse)
+
+	newDir := func(age time.Duration, lock string) string {
+		dir, err := newEphemeralDir()
+		assert.NoError(t, err)
+		if lock != "" {
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, lock), nil, 0600))
+		}
+		at := time.Now().Add(-age)
+		assert.NoError(t, os.Chtimes(dir, at, at))
+		return dir
+	}
+	exited := newDir(time.Hour, "")
+	fresh := newDir(0, "")
+	running := newDir(time.Hour, "SingletonLock")
+
+	removed, inUse, err = CleanEphemeral(false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, 2, inUse)
+	assert.NoDirExists(t, exited)
+	assert.DirExists(t, fresh)
+	assert.DirExists(t, running)
+
+	removed, inUse, err = CleanEphemeral(true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	assert.Equal(t, 0, inUse)
+	assert.NoDirExists(t, running)
+}
+
+func TestExecLauncherProbe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("probe test uses POSIX shell scripts")
+	}
+	l := NewExecLauncher()
+	profile := config.Profile{ProfileDir: "Default"}
+
+	// A command that exits successfully is accepted
+	res, err := l.Probe(config.Browser{Executable: "true"}, profile, "https://example.com", false, 2*time.Second)
+	assert.NoError(t, err)
+	assert.True(t, res.Exited)
+	assert.True(t, res.Accepted())
+
+	// A command that fails immediately is reported with its exit code and stderr
+	script := filepath.Join(t.TempDir(), "browser.sh")
+	assert.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho bad flag >&2\nexit 3\n"), 0755))
+	res, err = l.Probe(config.Browser{Executable: script}, profile, "https://example.com", false, 2*time.Second)
+	assert.NoError(t, err)
+	assert.True(t, res.Exited)
+	assert.Equal(t, 3, res.ExitCode)
+	assert.Contains(t, res.Stderr, "bad flag")
+	assert.False(t, res.Accepted())
+}
+
+func TestExecLauncherMonitorWait(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("monitor test uses POSIX shell scripts")
+	}
+	l := NewExecLauncher()
+	l.MonitorWait = 2 * time.Second
+	profile := config.Profile{ProfileDir: "Default"}
+
+	script := filepath.Join(t.TempDir(), "browser.sh")
+	assert.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho unknown profile >&2\nexit 2\n"), 0755))
+	err := l.LaunchBrowser(config.Browser{Executable: script}, profile, "https://example.com", false)
+	var failed *LaunchFailedError
+	if assert.ErrorAs(t, err, &failed) {
+		assert.Equal(t, 2, failed.ExitCode)
+		assert.Contains(t, failed.Stderr, "unknown profile")
+	}
+
+	// A clean exit (e.g. handing the URL to a running instance) is not a failure
+	assert.NoError(t, l.LaunchBrowser(config.Browser{Executable: "true"}, profile, "https://example.com", false))
+}
+
+func TestRemoteLaunch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("remote test uses a POSIX shell script as ssh")
+	}
+	l := NewExecLauncher()
+	browser := config.Browser{
+		BrowserID:    "desktop",
+		IncognitoArg: "--incognito",
+		Remote:       &config.RemoteTarget{Host: "me@desktop", Port: 2222, IdentityFile: "~/.ssh/rurl"},
+	}
+	profile := config.Profile{ProfileDir: "Profile 1"}
+	url := "https://example.com/?q=it's&x=$(id)"
+
+	cmd, err := l.constructCommand(browser, profile, url, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ssh", "-o", "BatchMode=yes", "-o", "ConnectTimeout=10", "-p", "2222", "-i", "~/.ssh/rurl",
+		"me@desktop", `rurl 'https://example.com/?q=it'\''s&x=$(id)'`}, cmd.Args)
+
+	browser.Remote = &config.RemoteTarget{Host: "desktop", Command: "google-chrome --profile-directory={profile} {incognito} {url}"}
+	cmd, err = l.constructCommand(browser, profile, "https://example.com/", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "google-chrome --profile-directory='Profile 1' '--incognito' 'https://example.com/'", cmd.Args[len(cmd.Args)-1])
+	cmd, err = l.constructCommand(browser, profile, "https://example.com/", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "google-chrome --profile-directory='Profile 1'  'https://example.com/'", cmd.Args[len(cmd.Args)-1])
+
+	browser.Remote = &config.RemoteTarget{Host: "-oProxyCommand=evil"}
+	_, err = l.constructCommand(browser, profile, "https://example.com/", false)
+	assert.Error(t, err)
+
+	// Launches wait for ssh and report its failures
+	bin := t.TempDir()
+	t.Setenv("PATH", bin)
+	ssh := filepath.Join(bin, "ssh")
+	assert.NoError(t, os.WriteFile(ssh, []byte("#!/bin/sh\necho \"Permission denied (publickey).\" >&2\nexit 255\n"), 0755))
+	browser.Remote = &config.RemoteTarget{Host: "desktop"}
+	err = l.LaunchBrowser(browser, profile, "https://example.com/", false)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "remote launch on desktop failed")
+		assert.Contains(t, err.Error(), "Permission denied (publickey).")
+	}
+	assert.NoError(t, os.WriteFile(ssh, []byte("#!/bin/sh\nexit 0\n"), 0755))
+	assert.NoError(t, l.LaunchBrowser(browser, profile, "https://example.com/", false))
+}
+
+func TestRunHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook test uses POSIX shell commands")
+	}
+	info := HookInfo{
+		URL:       "https://example.com/",
+		RuleName:  "Example",
+		ProfileID: "chrome-default",
+		Command:   []string{"chrome", "https://example.com/"},
+	}
+
+	// Empty command is a no-op
+	assert.NoError(t, RunHook(context.Background(), HookPreLaunch, "", 0, info))
+
+	// Hook receives launch details via the environment
+	out := filepath.Join(t.TempDir(), "hook.log")
+	cmd := `echo "$RURL_HOOK|$RURL_URL|$RURL_RULE_NAME|$RURL_PROFILE_ID|$RURL_COMMAND|$RURL_LAUNCH_STATUS" > ` + out
+	assert.NoError(t, RunHook(context.Background(), HookPostLaunch, cmd, 0, info))
+	data, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Equal(t, "post_launch|https://example.com/|Example|chrome-default|chrome https://example.com/|ok\n", string(data))
+
+	// Non-zero pre_launch exit vetoes the launch
+	err = RunHook(context.Background(), HookPreLaunch, "exit 1", 0, info)
+	assert.ErrorIs(t, err, ErrLaunchVetoed)
+
+	// Non-zero post_launch exit is an error but not a veto
+	err = RunHook(context.Background(), HookPostLaunch, "exit 1", 0, info)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrLaunchVetoed)
+
+	// Hooks that overrun the timeout are killed
+	err = RunHook(context.Background(), HookPreLaunch, "exec sleep 5", 100*time.Millisecond, info)
+	assert.ErrorIs(t, err, ErrLaunchVetoed)
+	assert.Contains(t, err.Error(), "timed out")
+}
+
+func TestSendWebhook(t *testing.T) {
+	defer func(b time.Duration) { webhookBackoff = b }(webhookBackoff)
+	webhookBackoff = time.Millisecond
+
+	var bodies [][]byte
+	var signatures []string
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		signatures = append(signatures, r.Header.Get(WebhookSignatureHeader))
+		w.WriteHeader(status)
+		status = http.StatusNoContent // Succeed on the retry
+	}))
+	defer srv.Close()
+
+	now := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	info := HookInfo{URL: "https://GitHub.com/org/private-repo?token=x", Mode: LaunchModeBrowser, RuleID: "work", RuleName: "Work", ProfileID: "chrome-work"}
+	payload := NewWebhookPayload(info, now)
+	assert.NoError(t, SendWebhook(context.Background(), srv.URL, "s3cret", 0, payload))
+	if assert.Len(t, bodies, 2) {
+		var got WebhookPayload
+		assert.NoError(t, json.Unmarshal(bodies[1], &got))
+		assert.Equal(t, WebhookPayload{Time: now, Domain: "github.com", Mode: "browser", RuleID: "work", RuleName: "Work", ProfileID: "chrome-work", Status: "ok"}, got)
+		assert.NotContains(t, string(bodies[1]), "private-repo") // Only the domain is sent
+		assert.Equal(t, SignWebhook("s3cret", bodies[1]), signatures[1])
+		assert.Regexp(t, `^sha256=[0-9a-f]{64}$`, signatures[1])
+	}
+
+	// Failed launches are reported, unsigned without a secret
+	info.LaunchError = errors.New("boom")
+	bodies, signatures = nil, nil
+	status = http.StatusOK
+	assert.NoError(t, SendWebhook(context.Background(), srv.URL, "", 0, NewWebhookPayload(info, now)))
+	assert.Contains(t, string(bodies[0]), `"status":"error"`)
+	assert.Empty(t, signatures[0])
+
+	// Client errors are not retried; server errors are, until the attempts run out
+	bodies = nil
+	status = http.StatusBadRequest
+	assert.Error(t, SendWebhook(context.Background(), srv.URL, "", 0, payload))
+	assert.Len(t, bodies, 1)
+	always503 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bodies = append(bodies, nil)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer always503.Close()
+	bodies = nil
+	assert.Error(t, SendWebhook(context.Background(), always503.URL, "", 0, payload))
+	assert.Len(t, bodies, webhookAttempts)
+}
+
+func TestOSC52Sequence(t *testing.T) {
+	t.Setenv("TMUX", "")
+	t.Setenv("TERM", "xterm-256color")
+	assert.Equal(t, "\x1b]52;c;aHR0cHM6Ly9leGFtcGxlLmNvbS8=\a", OSC52Sequence("https://example.com/"))
+
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1234,0")
+	assert.Equal(t, "\x1bPtmux;\x1b\x1b]52;c;aHR0cHM6Ly9leGFtcGxlLmNvbS8=\a\x1b\\", OSC52Sequence("https://example.com/"))
+}
+
+func TestHasDisplay(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" || wsl.IsWSL() {
+		assert.True(t, HasDisplay())
+		return
+	}
+	t.Setenv("DISPLAY", "")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	assert.False(t, HasDisplay())
+	t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+	assert.True(t, HasDisplay())
+}
+
+func TestHasTerminal(t *testing.T) {
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	originalStdin := os.Stdin
+	defer func() { os.Stdin = originalStdin }()
+	os.Stdin = r
+	assert.False(t, HasTerminal())
+}
+
+func TestExecLauncherURLPassMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("URL pass mode test uses POSIX shell scripts")
+	}
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	l := NewExecLauncher()
+	out := filepath.Join(tmp, "out.txt")
+	script := filepath.Join(tmp, "handler.sh")
+	assert.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+{
+  echo "args: $#"
+  echo "env: $RURL_URL"
+  if [ -f "$1" ]; then echo "file: $(cat "$1")"; fi
+  read -r url; echo "stdin: $url"
+} > "$OUT"
+`), 0755))
+	browser := config.Browser{BrowserID: "script", Executable: script, Env: map[string]string{"OUT": out}}
+	url := "https://example.com/?a=1&b=$(id)"
+
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{"", "args: 1\nenv: \nstdin: \n"},
+		{config.URLPassArg, "args: 1\nenv: \nstdin: \n"},
+		{config.URLPassStdin, "args: 0\nenv: \nstdin: " + url + "\n"},
+		{config.URLPassTempFile, "args: 1\nenv: \nfile: " + url + "\nstdin: \n"},
+		{config.URLPassEnv, "args: 0\nenv: " + url + "\nstdin: \n"},
+	}
+	for _, tt := range tests {
+		browser.URLPassMode = tt.mode
+		res, err := l.Probe(browser, config.Profile{}, url, false, 2*time.Second)
+		assert.NoError(t, err, tt.mode)
+		assert.True(t, res.Exited, tt.mode)
+		data, err := os.ReadFile(out)
+		assert.NoError(t, err, tt.mode)
+		assert.Equal(t, tt.want, string(data), tt.mode)
+	}
+
+	// The stdin file is removed after the launch; temporary files are kept for the
+	// handler, until they are a day old
+	files, err := filepath.Glob(filepath.Join(tmp, urlFilePrefix+"*"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	old := time.Now().Add(-2 * staleURLFileAge)
+	assert.NoError(t, os.Chtimes(files[0], old, old))
+	removeStaleURLFiles()
+	assert.NoFileExists(t, files[0])
+
+	// Remote browsers only take the URL as an argument
+	browser.Remote = &config.RemoteTarget{Host: "desktop"}
+	_, err = l.constructCommand(browser, config.Profile{}, url, false)
+	assert.ErrorContains(t, err, "only supports passing the URL as an argument")
+}
+
+func TestRunInTerminal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("terminal browser test uses POSIX commands")
+	}
+	originalHasTerminal := hasTerminal
+	defer func() { hasTerminal = originalHasTerminal }()
+	browser := config.Browser{BrowserID: "lynx", Executable: "true", Terminal: true}
+
+	// Terminal browsers get no Wayland flags and just the URL
+	t.Setenv("XDG_SESSION_TYPE", "wayland")
+	cmd, err := NewExecLauncher().constructCommand(browser, config.Profile{}, "https://example.com", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"true", "https://example.com"}, cmd.Args)
+
+	// Without a terminal the browser is not started
+	hasTerminal = func() bool { return false }
+	err = NewExecLauncher().LaunchBrowser(browser, config.Profile{}, "https://example.com", false)
+	assert.ErrorContains(t, err, "needs an interactive terminal")
+
+	// With a terminal the browser runs in the foreground and its exit status is reported
+	hasTerminal = func() bool { return true }
+	assert.NoError(t, runInTerminal(exec.Command("true"), browser))
+	assert.Error(t, runInTerminal(exec.Command("false"), browser))
+}
+
+func BenchmarkCommandLine(b *testing.B) {
+	// Log at the CLI's default level, as debug logging would dominate the timings
+	defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+	zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+	l := NewExecLauncher()
+	browser := config.Browser{
+		Name:         "Google Chrome",
+		BrowserID:    "chrome",
+		Executable:   "/usr/bin/google-chrome-stable",
+		ProfileArg:   "--profile-directory=%s",
+		IncognitoArg: "--incognito",
+		ExtraArgs:    []string{"--new-window"},
+	}
+	profile := config.Profile{ID: "chrome-work", BrowserID: "chrome", ProfileDir: "Profile 1"}
+	b.ResetTimer()
+	for range b.N {
+		if _, err := l.CommandLine(browser, profile, "https://example.com/some/path", false, ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestNotifyCommand(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("notify-send is only used on Linux")
+	}
+	cmd := notifyCommand("rurl blocked a link", "https://example.com/ matches no rule")
+	assert.Equal(t, []string{"notify-send", "--app-name=rurl", "rurl blocked a link", "https://example.com/ matches no rule"}, cmd.Args)
+}
+
+func TestHandlerCommand(t *testing.T) {
+	const u = "https://www.youtube.com/watch?v=abc&t=1 2"
+	tests := []struct {
+		template string
+		want     []string
+	}{
+		{`mpv "%u"`, []string{"mpv", u}},
+		{`mpv %u`, []string{"mpv", u}},
+		{`yt-dlp -o '%(title)s.%%(ext)s' --`, []string{"yt-dlp", "-o", "%(title)s.%(ext)s", "--", u}},
+		{`notify "Opening: %u" 100%%`, []string{"notify", "Opening: " + u, "100%"}},
+		{`"/opt/My Player/player" --url=%u`, []string{"/opt/My Player/player", "--url=" + u}},
+		{`echo a\ b "c\"d" ''`, []string{"echo", "a b", `c"d`, "", u}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			got, err := HandlerCommand(tt.template, u)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	for _, invalid := range []string{"", "  ", `mpv "%u`, `mpv '%u`} {
+		_, err := HandlerCommand(invalid, u)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestQuoteCommandLine(t *testing.T) {
+	args := []string{"chrome", "--profile-directory=Profile 1", "https://example.com/path", "https://example.com/?a=1&b=it's"}
+	assert.Equal(t, `chrome '--profile-directory=Profile 1' https://example.com/path 'https://example.com/?a=1&b=it'\''s'`, QuoteCommandLine(args))
+	assert.Equal(t, "''", QuoteCommandLine([]string{""}))
+}
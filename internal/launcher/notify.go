@@ -0,0 +1,50 @@
+package launcher
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/rs/zerolog/log"
+)
+
+// notifyCommand returns the command that shows a desktop notification with title and
+// message. They are passed as arguments or environment variables, never parsed as code.
+func notifyCommand(title, message string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, message)
+	case "windows":
+		// A balloon tip, which Windows 10 and later show as a toast
+		cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command",
+			"Add-Type -AssemblyName System.Windows.Forms; "+
+				"$n = New-Object System.Windows.Forms.NotifyIcon; "+
+				"$n.Icon = [System.Drawing.SystemIcons]::Warning; $n.Visible = $true; "+
+				"$n.ShowBalloonTip(5000, $env:RURL_NOTIFY_TITLE, $env:RURL_NOTIFY_MESSAGE, 'Warning'); "+
+				"Start-Sleep -Seconds 6; $n.Dispose()")
+		cmd.Env = append(os.Environ(), "RURL_NOTIFY_TITLE="+title, "RURL_NOTIFY_MESSAGE="+message)
+		return cmd
+	default:
+		return exec.Command("notify-send", "--app-name=rurl", title, message)
+	}
+}
+
+// Notify shows a desktop notification (notify-send, osascript or a Windows balloon tip)
+// without waiting for it to close.
+func Notify(title, message string) error {
+	cmd := notifyCommand(title, message)
+	log.Debug().Interface("args", cmd.Args).Msg("Showing notification")
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", cmd.Path, err)
+	}
+	if err := cmd.Process.Release(); err != nil {
+		log.Warn().Err(err).Msg("Failed to release notification process")
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package launcher
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// ProbeResult describes how a browser reacted to a test launch.
+type ProbeResult struct {
+	Args     []string // Full command line that was executed
+	Exited   bool     // True if the process exited within the wait window
+	ExitCode int      // Exit code (only meaningful if Exited)
+	Stderr   string   // Stderr captured during the wait window
+}
+
+// Accepted reports whether the browser appears to have accepted the arguments:
+// it either kept running or exited successfully (e.g. handed off to a running instance).
+func (r ProbeResult) Accepted() bool {
+	return !r.Exited || r.ExitCode == 0
+}
+
+// Probe launches the browser with the given profile and URL exactly as a real launch
+// would, then watches it for up to wait to detect an immediate failure.
+// Stderr is captured to a temporary file so the browser is unaffected once rurl exits.
+func (l *ExecLauncher) Probe(browser config.Browser, profile config.Profile, url string, incognito bool, wait time.Duration) (ProbeResult, error) {
+	cmd, err := l.constructCommand(browser, profile, url, incognito)
+	if err != nil {
+		return ProbeResult{}, err
+	}
+	release, err := passURL(cmd, browser, url)
+	if err != nil {
+		return ProbeResult{}, err
+	}
+	defer release()
+	return watchProcess(cmd, wait)
+}
+
+// watchProcess starts cmd and watches it for up to wait to detect an immediate exit,
+// capturing its stderr to a temporary file so the browser is unaffected once rurl
+// exits. A process still running after wait is released.
+func watchProcess(cmd *exec.Cmd, wait time.Duration) (ProbeResult, error) {
+	result := ProbeResult{Args: cmd.Args}
+
+	stderrFile, err := os.CreateTemp("", "rurl-probe-*.log")
+	if err != nil {
+		return result, fmt.Errorf("failed to create stderr capture file: %w", err)
+	}
+	defer os.Remove(stderrFile.Name())
+	defer stderrFile.Close()
+	cmd.Stderr = stderrFile
+
+	log.Debug().Interface("args", cmd.Args).Dur("wait", wait).Msg("Watching browser launch")
+	if err := cmd.Start(); err != nil {
+		return result, fmt.Errorf("failed to start browser process %s: %w", cmd.Path, err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case waitErr := <-done:
+		result.Exited = true
+		var exitErr *exec.ExitError
+		if errors.As(waitErr, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+		} else if waitErr != nil {
+			return result, fmt.Errorf("failed waiting for browser process: %w", waitErr)
+		}
+	case <-time.After(wait):
+		// Still running: leave the browser open. The process is not released, as the
+		// pending Wait reaps it once it exits (done is buffered, so it never blocks).
+	}
+
+	if data, err := os.ReadFile(stderrFile.Name()); err == nil {
+		result.Stderr = string(data)
+	}
+	return result, nil
+}
+
+// LaunchFailedError reports a browser that exited with an error right after starting,
+// e.g. because of an unsupported argument or a missing profile.
+type LaunchFailedError struct {
+	Args     []string // Full command line that was executed
+	ExitCode int
+	Stderr   string // Stderr captured before the browser exited
+}
+
+func (e *LaunchFailedError) Error() string {
+	msg := fmt.Sprintf("browser %v exited immediately with code %d", e.Args, e.ExitCode)
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		if len(stderr) > maxErrorStderr {
+			stderr = "..." + stderr[len(stderr)-maxErrorStderr:]
+		}
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+// maxErrorStderr limits how much captured stderr is included in a LaunchFailedError message.
+const maxErrorStderr = 500
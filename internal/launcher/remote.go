@@ -0,0 +1,96 @@
+package launcher
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmylchreest/rurl/internal/config"
+)
+
+// DefaultRemoteCommand is run on a remote browser's host if it has no Command.
+const DefaultRemoteCommand = "rurl {url}"
+
+// remoteTimeout bounds a remote launch, which waits for the remote command to finish.
+const remoteTimeout = 30 * time.Second
+
+// remoteCommand builds the ssh command forwarding url to browser's remote host. The
+// remote command's placeholders are quoted for the remote shell, which ssh hands the
+// command to.
+func remoteCommand(browser config.Browser, profile config.Profile, url string, incognito bool) (*exec.Cmd, error) {
+	remote := browser.Remote
+	if remote.Host == "" {
+		return nil, fmt.Errorf("remote browser '%s' has no host configured", browser.BrowserID)
+	}
+	if strings.HasPrefix(remote.Host, "-") {
+		return nil, fmt.Errorf("remote browser '%s' has an invalid host '%s'", browser.BrowserID, remote.Host)
+	}
+
+	command := remote.Command
+	if command == "" {
+		command = DefaultRemoteCommand
+	}
+	incognitoArg := "" // Omitted rather than passed as an empty argument
+	if incognito && browser.IncognitoArg != "" && browser.IncognitoArg != config.IncognitoAppleScript {
+		incognitoArg = shellQuote(browser.IncognitoArg)
+	}
+	command = strings.NewReplacer(
+		"{url}", shellQuote(url),
+		"{profile}", shellQuote(profile.ProfileDir),
+		"{incognito}", incognitoArg,
+	).Replace(command)
+
+	// Never prompt for a password or host key: there may be no one to answer
+	args := []string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=10"}
+	if remote.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(remote.Port))
+	}
+	if remote.IdentityFile != "" {
+		args = append(args, "-i", remote.IdentityFile)
+	}
+	args = append(args, remote.Host, command)
+
+	cmd := exec.Command("ssh", args...)
+	if env := buildEnv(browser.Env, profile.Env); len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	return cmd, nil
+}
+
+// shellQuote quotes s as a single word for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// runRemote runs a remote launch command and waits for it, so that connection and
+// authentication failures are reported rather than lost with a detached process.
+func runRemote(cmd *exec.Cmd, browser config.Browser) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", cmd.Path, err)
+	}
+	var timedOut atomic.Bool
+	timer := time.AfterFunc(remoteTimeout, func() {
+		timedOut.Store(true)
+		_ = cmd.Process.Kill()
+	})
+	err := cmd.Wait()
+	timer.Stop()
+	if err == nil {
+		return nil
+	}
+
+	if timedOut.Load() {
+		err = fmt.Errorf("timed out after %s", remoteTimeout)
+	}
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return fmt.Errorf("remote launch on %s failed: %w: %s", browser.Remote.Host, err, msg)
+	}
+	return fmt.Errorf("remote launch on %s failed: %w", browser.Remote.Host, err)
+}
@@ -0,0 +1,82 @@
+package launcher
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/jmylchreest/rurl/internal/wsl"
+	"github.com/rs/zerolog/log"
+)
+
+// systemOpenCommand returns the command that hands url to the OS default handler.
+func systemOpenCommand(url string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url)
+	case "windows":
+		// Avoid "cmd /c start", which re-parses the URL and mangles '&'.
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		if wsl.IsWSL() {
+			return wsl.OpenCommand(url)
+		}
+		return exec.Command("xdg-open", url)
+	}
+}
+
+// OpenWithSystem hands url to the operating system's default handler for its scheme
+// (xdg-open, open or the Windows URL protocol handler, also from inside WSL) instead of a specific browser.
+func OpenWithSystem(url string) error {
+	cmd := systemOpenCommand(url)
+	log.Debug().Interface("args", cmd.Args).Msg("Opening URL with system handler")
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start system handler %s: %w", cmd.Path, err)
+	}
+	if err := cmd.Process.Release(); err != nil {
+		log.Warn().Err(err).Msg("Failed to release system handler process")
+	}
+	return nil
+}
+
+// SystemFallbackEnv is set for the handler started by OpenWithPortal. If rurl is itself
+// the default browser it is started again with it set, and must not fall back again.
+const SystemFallbackEnv = "RURL_SYSTEM_FALLBACK"
+
+// portalOpenCommand returns the command that asks the desktop to open url: xdg-open or,
+// if it is not installed, gio open on Linux (both use the OpenURI portal inside Flatpak
+// and snap sandboxes), and the system handler elsewhere.
+func portalOpenCommand(url string) (*exec.Cmd, error) {
+	if runtime.GOOS != "linux" || wsl.IsWSL() {
+		return systemOpenCommand(url), nil
+	}
+	if _, err := exec.LookPath("xdg-open"); err == nil {
+		return exec.Command("xdg-open", url), nil
+	}
+	if _, err := exec.LookPath("gio"); err == nil {
+		return exec.Command("gio", "open", url), nil
+	}
+	return nil, fmt.Errorf("neither xdg-open nor gio is installed")
+}
+
+// OpenWithPortal opens url with the desktop's default handler, as a fallback for
+// browsers that cannot be started directly (e.g. from a sandbox or an immutable system).
+// No profile can be selected this way.
+func OpenWithPortal(url string) error {
+	cmd, err := portalOpenCommand(url)
+	if err != nil {
+		return err
+	}
+	cmd.Env = append(os.Environ(), SystemFallbackEnv+"=1")
+	log.Debug().Interface("args", cmd.Args).Msg("Opening URL through the desktop portal")
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", cmd.Path, err)
+	}
+	if err := cmd.Process.Release(); err != nil {
+		log.Warn().Err(err).Msg("Failed to release system handler process")
+	}
+	return nil
+}
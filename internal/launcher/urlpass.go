@@ -0,0 +1,111 @@
+package launcher
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// URLEnv is the environment variable holding the URL for browsers with URLPassMode
+// config.URLPassEnv, named like the variable of the launch hooks.
+const URLEnv = "RURL_URL"
+
+// urlFilePrefix starts the names of the temporary files URLs are passed in.
+const urlFilePrefix = "rurl-url-"
+
+// staleURLFileAge is how old a URL file must be for rurl to remove it. Handlers given
+// a file with URLPassTempFile may read it at any time, so it is not removed after the
+// launch.
+const staleURLFileAge = 24 * time.Hour
+
+// urlFilePath returns the path of a new URL file in the temporary directory.
+func urlFilePath() string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("%s%016x.txt", urlFilePrefix, rand.Uint64()))
+}
+
+// urlArgs returns the arguments passing url to a browser with URLPassMode mode: the URL,
+// the path of the file start writes it to, or none.
+func urlArgs(mode, url string) []string {
+	switch mode {
+	case config.URLPassStdin, config.URLPassEnv:
+		return nil
+	case config.URLPassTempFile:
+		return []string{urlFilePath()}
+	}
+	return []string{url}
+}
+
+// passURL hands url to cmd as browser.URLPassMode requires beyond its arguments: it
+// writes the URL file of URLPassTempFile or feeds it to standard input for
+// URLPassStdin. The returned function releases what cmd no longer needs once started.
+func passURL(cmd *exec.Cmd, browser config.Browser, url string) (func(), error) {
+	switch browser.URLPassMode {
+	case config.URLPassTempFile:
+		removeStaleURLFiles()
+		path := cmd.Args[len(cmd.Args)-1]
+		if err := writeURLFile(path, url); err != nil {
+			return nil, err
+		}
+		return func() {}, nil
+	case config.URLPassStdin:
+		removeStaleURLFiles()
+		path := urlFilePath()
+		if err := writeURLFile(path, url); err != nil {
+			return nil, err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			os.Remove(path)
+			return nil, fmt.Errorf("failed to open URL file: %w", err)
+		}
+		cmd.Stdin = f
+		return func() {
+			f.Close()
+			// The browser keeps reading its copy; Windows refuses to remove a file in
+			// use, leaving it to removeStaleURLFiles
+			os.Remove(path)
+		}, nil
+	}
+	return func() {}, nil
+}
+
+// writeURLFile creates path, readable only by the user, holding url and a newline.
+func writeURLFile(path, url string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create URL file: %w", err)
+	}
+	if _, err := f.WriteString(url + "\n"); err != nil {
+		f.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to write URL file: %w", err)
+	}
+	return f.Close()
+}
+
+// removeStaleURLFiles removes the URL files left in the temporary directory by
+// launches more than staleURLFileAge ago.
+func removeStaleURLFiles() {
+	entries, err := os.ReadDir(os.TempDir())
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), urlFilePrefix) {
+			continue
+		}
+		if info, err := entry.Info(); err == nil && time.Since(info.ModTime()) > staleURLFileAge {
+			path := filepath.Join(os.TempDir(), entry.Name())
+			if err := os.Remove(path); err != nil {
+				log.Debug().Err(err).Str("path", path).Msg("Failed to remove stale URL file")
+			}
+		}
+	}
+}
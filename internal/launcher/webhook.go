@@ -0,0 +1,130 @@
+package launcher
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// WebhookSignatureHeader carries the HMAC-SHA256 of a webhook body, as
+// "sha256=<hex>", when a webhook secret is configured.
+const WebhookSignatureHeader = "X-Rurl-Signature"
+
+// webhookAttempts is the number of times a webhook is sent before giving up.
+const webhookAttempts = 3
+
+// webhookBackoff is the wait before the second attempt, doubled for each further
+// attempt. Tests may shorten it.
+var webhookBackoff = 500 * time.Millisecond
+
+// WebhookPayload is the JSON body POSTed to the decision webhook. Only the domain of
+// the URL is sent.
+type WebhookPayload struct {
+	Time      time.Time `json:"time"`
+	Domain    string    `json:"domain"`
+	Mode      string    `json:"mode"`
+	RuleID    string    `json:"rule_id,omitempty"`
+	RuleName  string    `json:"rule,omitempty"`
+	ProfileID string    `json:"profile,omitempty"`
+	Incognito bool      `json:"incognito"`
+	Status    string    `json:"status"` // "ok" or "error"
+}
+
+// NewWebhookPayload returns the webhook payload describing the launch in info at now.
+func NewWebhookPayload(info HookInfo, now time.Time) WebhookPayload {
+	domain := ""
+	if u, err := url.Parse(info.URL); err == nil {
+		domain = strings.ToLower(u.Hostname())
+	}
+	status := "ok"
+	if info.LaunchError != nil {
+		status = "error"
+	}
+	return WebhookPayload{
+		Time:      now.UTC(),
+		Domain:    domain,
+		Mode:      info.Mode,
+		RuleID:    info.RuleID,
+		RuleName:  info.RuleName,
+		ProfileID: info.ProfileID,
+		Incognito: info.Incognito,
+		Status:    status,
+	}
+}
+
+// SignWebhook returns the WebhookSignatureHeader value for body signed with secret.
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SendWebhook POSTs payload as JSON to endpoint, signed with secret if it is set. Each
+// attempt may take up to timeout (0 uses DefaultHookTimeout). Network errors, 429 and
+// 5xx responses are retried with exponential backoff; other responses are final.
+func SendWebhook(ctx context.Context, endpoint, secret string, timeout time.Duration, payload WebhookPayload) error {
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	client := &http.Client{Timeout: timeout}
+	backoff := webhookBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := postWebhook(ctx, client, endpoint, secret, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == webhookAttempts {
+			return fmt.Errorf("webhook failed after %d attempt(s): %w", attempt, err)
+		}
+		log.Debug().Err(err).Int("attempt", attempt).Dur("backoff", backoff).Msg("Webhook failed, retrying")
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("webhook cancelled: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// postWebhook makes a single webhook request, and reports whether a failure is worth
+// retrying.
+func postWebhook(ctx context.Context, client *http.Client, endpoint, secret string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("invalid webhook URL '%s': %w", endpoint, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "rurl")
+	if secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(secret, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("status %d", resp.StatusCode)
+	}
+}
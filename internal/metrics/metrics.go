@@ -0,0 +1,213 @@
+// Package metrics counts, once enabled by 'rurl serve', the launches, shortener
+// resolutions and failures of the URLs rurl opens, and serves them in the Prometheus
+// text exposition format so they can be charted.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// resolutionBuckets are the upper bounds, in seconds, of the shortener resolution
+// latency histogram.
+var resolutionBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// launchKey identifies the launches of a profile through a rule ("" for the default
+// profile and overrides).
+type launchKey struct {
+	profileID string
+	ruleID    string
+}
+
+// resolutionKey identifies the resolutions of a shortener domain by outcome.
+type resolutionKey struct {
+	domain string
+	ok     bool
+}
+
+// Registry holds the metrics. Its methods are safe for concurrent use.
+type Registry struct {
+	mu             sync.Mutex
+	launches       map[launchKey]uint64
+	launchFailures map[launchKey]uint64
+	routeFailures  uint64
+	resolutions    map[resolutionKey]uint64
+	buckets        []uint64 // Resolutions per bucket of resolutionBuckets, not cumulative
+	durationSum    float64
+	durationCount  uint64
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		launches:       make(map[launchKey]uint64),
+		launchFailures: make(map[launchKey]uint64),
+		resolutions:    make(map[resolutionKey]uint64),
+		buckets:        make([]uint64, len(resolutionBuckets)),
+	}
+}
+
+// ObserveLaunch counts a launch of profileID for ruleID, failed if err is not nil.
+func (r *Registry) ObserveLaunch(profileID, ruleID string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := launchKey{profileID: profileID, ruleID: ruleID}
+	if err != nil {
+		r.launchFailures[key]++
+	} else {
+		r.launches[key]++
+	}
+}
+
+// ObserveRouteFailure counts a URL that could not be routed, e.g. because it was
+// blocked.
+func (r *Registry) ObserveRouteFailure() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routeFailures++
+}
+
+// ObserveResolution counts a resolution of a URL on the shortener domain that took d
+// and succeeded if ok is set.
+func (r *Registry) ObserveResolution(domain string, d time.Duration, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resolutions[resolutionKey{domain: domain, ok: ok}]++
+	seconds := d.Seconds()
+	if i, _ := slices.BinarySearch(resolutionBuckets, seconds); i < len(r.buckets) {
+		r.buckets[i]++
+	}
+	r.durationSum += seconds
+	r.durationCount++
+}
+
+// Write writes the metrics to w in the Prometheus text exposition format.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var b strings.Builder
+
+	writeLaunches := func(name, help string, counts map[launchKey]uint64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		keys := make([]launchKey, 0, len(counts))
+		for key := range counts {
+			keys = append(keys, key)
+		}
+		slices.SortFunc(keys, func(a, b launchKey) int {
+			return strings.Compare(a.profileID+"\x00"+a.ruleID, b.profileID+"\x00"+b.ruleID)
+		})
+		for _, key := range keys {
+			fmt.Fprintf(&b, "%s{profile=%s,rule=%s} %d\n", name, quote(key.profileID), quote(key.ruleID), counts[key])
+		}
+	}
+	writeLaunches("rurl_launches_total", "URLs launched, by profile and rule.", r.launches)
+	writeLaunches("rurl_launch_failures_total", "URLs that failed to launch, by profile and rule.", r.launchFailures)
+
+	b.WriteString("# HELP rurl_route_failures_total URLs that could not be routed, e.g. because they were blocked.\n")
+	b.WriteString("# TYPE rurl_route_failures_total counter\n")
+	fmt.Fprintf(&b, "rurl_route_failures_total %d\n", r.routeFailures)
+
+	b.WriteString("# HELP rurl_shortener_resolutions_total Shortened URLs resolved, by domain and result.\n")
+	b.WriteString("# TYPE rurl_shortener_resolutions_total counter\n")
+	keys := make([]resolutionKey, 0, len(r.resolutions))
+	for key := range r.resolutions {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b resolutionKey) int {
+		if c := strings.Compare(a.domain, b.domain); c != 0 {
+			return c
+		}
+		return strings.Compare(resultLabel(a.ok), resultLabel(b.ok))
+	})
+	for _, key := range keys {
+		fmt.Fprintf(&b, "rurl_shortener_resolutions_total{domain=%s,result=%s} %d\n", quote(key.domain), quote(resultLabel(key.ok)), r.resolutions[key])
+	}
+
+	b.WriteString("# HELP rurl_shortener_resolution_duration_seconds Time taken to resolve shortened URLs.\n")
+	b.WriteString("# TYPE rurl_shortener_resolution_duration_seconds histogram\n")
+	var cumulative uint64
+	for i, bound := range resolutionBuckets {
+		cumulative += r.buckets[i]
+		fmt.Fprintf(&b, "rurl_shortener_resolution_duration_seconds_bucket{le=%s} %d\n", quote(strconv.FormatFloat(bound, 'g', -1, 64)), cumulative)
+	}
+	fmt.Fprintf(&b, "rurl_shortener_resolution_duration_seconds_bucket{le=\"+Inf\"} %d\n", r.durationCount)
+	fmt.Fprintf(&b, "rurl_shortener_resolution_duration_seconds_sum %s\n", strconv.FormatFloat(r.durationSum, 'g', -1, 64))
+	fmt.Fprintf(&b, "rurl_shortener_resolution_duration_seconds_count %d\n", r.durationCount)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Handler returns the handler serving the metrics to Prometheus.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.Write(w)
+	})
+}
+
+// resultLabel returns the result label of a resolution.
+func resultLabel(ok bool) string {
+	if ok {
+		return "ok"
+	}
+	return "error"
+}
+
+// quote quotes a label value, escaping backslashes, double quotes and line feeds.
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+// std is the registry observations are recorded in, nil until Enable is called.
+var (
+	stdMu sync.RWMutex
+	std   *Registry
+)
+
+// Enable starts recording observations made through the package functions and
+// returns the registry they are recorded in. Until then they are discarded, as a
+// single launch has no one to serve them to.
+func Enable() *Registry {
+	stdMu.Lock()
+	defer stdMu.Unlock()
+	if std == nil {
+		std = NewRegistry()
+	}
+	return std
+}
+
+// enabled returns the registry of Enable, or nil.
+func enabled() *Registry {
+	stdMu.RLock()
+	defer stdMu.RUnlock()
+	return std
+}
+
+// ObserveLaunch counts a launch in the registry of Enable, see Registry.ObserveLaunch.
+func ObserveLaunch(profileID, ruleID string, err error) {
+	if r := enabled(); r != nil {
+		r.ObserveLaunch(profileID, ruleID, err)
+	}
+}
+
+// ObserveRouteFailure counts a URL that could not be routed in the registry of Enable.
+func ObserveRouteFailure() {
+	if r := enabled(); r != nil {
+		r.ObserveRouteFailure()
+	}
+}
+
+// ObserveResolution counts a shortener resolution in the registry of Enable, see
+// Registry.ObserveResolution.
+func ObserveResolution(domain string, d time.Duration, ok bool) {
+	if r := enabled(); r != nil {
+		r.ObserveResolution(domain, d, ok)
+	}
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+	r.ObserveLaunch("work", "jira", nil)
+	r.ObserveLaunch("work", "jira", nil)
+	r.ObserveLaunch("personal", "", nil)
+	r.ObserveLaunch("work", "jira", errors.New("browser not found"))
+	r.ObserveRouteFailure()
+	r.ObserveResolution("bit.ly", 80*time.Millisecond, true)
+	r.ObserveResolution("bit.ly", 3*time.Second, false)
+	r.ObserveResolution(`t."co`, time.Minute, true)
+
+	var b strings.Builder
+	require.NoError(t, r.Write(&b))
+	out := b.String()
+
+	for _, line := range []string{
+		"# TYPE rurl_launches_total counter",
+		`rurl_launches_total{profile="personal",rule=""} 1`,
+		`rurl_launches_total{profile="work",rule="jira"} 2`,
+		`rurl_launch_failures_total{profile="work",rule="jira"} 1`,
+		"rurl_route_failures_total 1",
+		`rurl_shortener_resolutions_total{domain="bit.ly",result="error"} 1`,
+		`rurl_shortener_resolutions_total{domain="bit.ly",result="ok"} 1`,
+		`rurl_shortener_resolutions_total{domain="t.\"co",result="ok"} 1`,
+		"# TYPE rurl_shortener_resolution_duration_seconds histogram",
+		`rurl_shortener_resolution_duration_seconds_bucket{le="0.05"} 0`,
+		`rurl_shortener_resolution_duration_seconds_bucket{le="0.1"} 1`,
+		`rurl_shortener_resolution_duration_seconds_bucket{le="5"} 2`,
+		`rurl_shortener_resolution_duration_seconds_bucket{le="30"} 2`,
+		`rurl_shortener_resolution_duration_seconds_bucket{le="+Inf"} 3`,
+		"rurl_shortener_resolution_duration_seconds_sum 63.08",
+		"rurl_shortener_resolution_duration_seconds_count 3",
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+	// Label sets are sorted so that scrapes are stable
+	assert.Less(t, strings.Index(out, `profile="personal"`), strings.Index(out, `profile="work"`))
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.ObserveLaunch("work", "", nil)
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "version=0.0.4")
+	assert.Contains(t, w.Body.String(), `rurl_launches_total{profile="work",rule=""} 1`)
+}
+
+func TestObserveBeforeEnable(t *testing.T) {
+	// Observations are discarded until metrics are enabled
+	ObserveLaunch("work", "", nil)
+	r := Enable()
+	var b strings.Builder
+	require.NoError(t, r.Write(&b))
+	assert.NotContains(t, b.String(), `profile="work"`)
+
+	ObserveLaunch("work", "", nil)
+	assert.Same(t, r, Enable())
+	b.Reset()
+	require.NoError(t, r.Write(&b))
+	assert.Contains(t, b.String(), `rurl_launches_total{profile="work",rule=""} 1`)
+}
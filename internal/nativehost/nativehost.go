@@ -0,0 +1,272 @@
+// Package nativehost implements the native messaging protocol Chromium and Firefox use
+// to talk to a program installed alongside an extension, and installs the manifests
+// registering rurl as such a host for its companion extension.
+package nativehost
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// HostName is the name rurl is registered under, which extensions pass to
+// runtime.connectNative.
+const HostName = "io.github.jmylchreest.rurl"
+
+// MaxMessageSize bounds messages in either direction. Browsers refuse messages larger
+// than 1 MiB from a host, and the extension's requests are far smaller.
+const MaxMessageSize = 1 << 20
+
+// Browsers rurl can be registered with, see Install.
+const (
+	BrowserChrome   = "chrome"
+	BrowserChromium = "chromium"
+	BrowserEdge     = "edge"
+	BrowserBrave    = "brave"
+	BrowserFirefox  = "firefox"
+)
+
+// browserLocation is where a browser looks for native messaging host manifests: a
+// directory under the user configuration directory on Linux and macOS (Firefox uses
+// ~/.mozilla on Linux), and a registry key under HKEY_CURRENT_USER on Windows.
+type browserLocation struct {
+	linux    string
+	darwin   string
+	registry string
+}
+
+var browserLocations = map[string]browserLocation{
+	BrowserChrome:   {"google-chrome/NativeMessagingHosts", "Google/Chrome/NativeMessagingHosts", `Software\Google\Chrome\NativeMessagingHosts`},
+	BrowserChromium: {"chromium/NativeMessagingHosts", "Chromium/NativeMessagingHosts", `Software\Chromium\NativeMessagingHosts`},
+	BrowserEdge:     {"microsoft-edge/NativeMessagingHosts", "Microsoft Edge/NativeMessagingHosts", `Software\Microsoft\Edge\NativeMessagingHosts`},
+	BrowserBrave:    {"BraveSoftware/Brave-Browser/NativeMessagingHosts", "BraveSoftware/Brave-Browser/NativeMessagingHosts", `Software\BraveSoftware\Brave-Browser\NativeMessagingHosts`},
+	BrowserFirefox:  {"", "Mozilla/NativeMessagingHosts", `Software\Mozilla\NativeMessagingHosts`},
+}
+
+// Browsers returns the browsers rurl can be registered with, sorted.
+func Browsers() []string {
+	names := make([]string, 0, len(browserLocations))
+	for name := range browserLocations {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// chromiumExtensionID matches a Chromium extension ID: 32 letters from a to p.
+var chromiumExtensionID = regexp.MustCompile(`^[a-p]{32}$`)
+
+// ReadMessage reads one message into v: a 32-bit length in native byte order followed
+// by that many bytes of JSON. It returns io.EOF if r ends before a message starts.
+func ReadMessage(r io.Reader, v any) error {
+	var length uint32
+	if err := binary.Read(r, binary.NativeEndian, &length); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("truncated message length: %w", err)
+		}
+		return err
+	}
+	if length > MaxMessageSize {
+		return fmt.Errorf("message of %d bytes exceeds the limit of %d", length, MaxMessageSize)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return fmt.Errorf("truncated message: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid message: %w", err)
+	}
+	return nil
+}
+
+// WriteMessage writes v as one message, framed as ReadMessage expects.
+func WriteMessage(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(data) > MaxMessageSize {
+		return fmt.Errorf("message of %d bytes exceeds the limit of %d", len(data), MaxMessageSize)
+	}
+	if err := binary.Write(w, binary.NativeEndian, uint32(len(data))); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Manifest is a native messaging host manifest. Chromium browsers name the extensions
+// allowed to connect by origin, Firefox by extension ID.
+type Manifest struct {
+	Name              string   `json:"name"`
+	Description       string   `json:"description"`
+	Path              string   `json:"path"`
+	Type              string   `json:"type"`
+	AllowedOrigins    []string `json:"allowed_origins,omitempty"`
+	AllowedExtensions []string `json:"allowed_extensions,omitempty"`
+}
+
+// NewManifest returns the manifest registering the host program at path with browser,
+// for the given extension IDs.
+func NewManifest(browser, path string, extensionIDs []string) (Manifest, error) {
+	if _, ok := browserLocations[browser]; !ok {
+		return Manifest{}, fmt.Errorf("unknown browser '%s' (expected one of: %s)", browser, strings.Join(Browsers(), ", "))
+	}
+	if len(extensionIDs) == 0 {
+		return Manifest{}, fmt.Errorf("at least one extension ID is required")
+	}
+	m := Manifest{
+		Name:        HostName,
+		Description: "rurl URL router",
+		Path:        path,
+		Type:        "stdio",
+	}
+	for _, id := range extensionIDs {
+		if browser == BrowserFirefox {
+			if id == "" || strings.ContainsAny(id, " \t\n") {
+				return Manifest{}, fmt.Errorf("invalid Firefox extension ID '%s'", id)
+			}
+			m.AllowedExtensions = append(m.AllowedExtensions, id)
+			continue
+		}
+		if !chromiumExtensionID.MatchString(id) {
+			return Manifest{}, fmt.Errorf("invalid extension ID '%s' (expected 32 letters from a to p)", id)
+		}
+		m.AllowedOrigins = append(m.AllowedOrigins, "chrome-extension://"+id+"/")
+	}
+	return m, nil
+}
+
+// ManifestPath returns where the manifest for browser is installed.
+func ManifestPath(browser string) (string, error) {
+	loc, ok := browserLocations[browser]
+	if !ok {
+		return "", fmt.Errorf("unknown browser '%s' (expected one of: %s)", browser, strings.Join(Browsers(), ", "))
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user config directory: %w", err)
+	}
+	var dir string
+	switch {
+	case runtime.GOOS == "windows":
+		// Any location works, as the registry points to the manifest
+		dir = filepath.Join(configDir, "rurl", "NativeMessagingHosts", browser)
+	case runtime.GOOS == "darwin":
+		dir = filepath.Join(configDir, filepath.FromSlash(loc.darwin))
+	case browser == BrowserFirefox:
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not get home directory: %w", err)
+		}
+		dir = filepath.Join(home, ".mozilla", "native-messaging-hosts")
+	default:
+		dir = filepath.Join(configDir, filepath.FromSlash(loc.linux))
+	}
+	return filepath.Join(dir, HostName+".json"), nil
+}
+
+// WrapperScript returns the script browsers start as the host, running command (the
+// rurl executable and its arguments) with the arguments the browser passes: a batch
+// file on Windows and a POSIX shell script elsewhere. Browsers cannot pass arguments
+// of their own choosing, such as rurl's native-host subcommand.
+func WrapperScript(command []string) string {
+	quoted := make([]string, len(command))
+	if runtime.GOOS == "windows" {
+		for i, arg := range command {
+			quoted[i] = `"` + strings.ReplaceAll(arg, "%", "%%") + `"`
+		}
+		return "@echo off\r\n" + strings.Join(quoted, " ") + " %*\r\n"
+	}
+	for i, arg := range command {
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return "#!/bin/sh\nexec " + strings.Join(quoted, " ") + ` "$@"` + "\n"
+}
+
+// Install registers the host with browser for extensionIDs: it writes wrapperPath (see
+// WrapperScript) to run command, and the manifest pointing to it. It returns the files
+// and registry keys written.
+func Install(browser string, extensionIDs []string, wrapperPath string, command []string) ([]string, error) {
+	manifest, err := NewManifest(browser, wrapperPath, extensionIDs)
+	if err != nil {
+		return nil, err
+	}
+	manifestPath, err := ManifestPath(browser)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(wrapperPath), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", wrapperPath, err)
+	}
+	if err := os.WriteFile(wrapperPath, []byte(WrapperScript(command)), 0700); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", wrapperPath, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(manifestPath), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", manifestPath, err)
+	}
+	if err := os.WriteFile(manifestPath, append(data, '\n'), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", manifestPath, err)
+	}
+	written := []string{wrapperPath, manifestPath}
+
+	key, err := register(browser, manifestPath)
+	if err != nil {
+		return written, err
+	}
+	if key != "" {
+		written = append(written, key)
+	}
+	return written, nil
+}
+
+// Installed returns the files and registry keys registering the host with browser
+// that exist, which Uninstall would remove.
+func Installed(browser string) ([]string, error) {
+	manifestPath, err := ManifestPath(browser)
+	if err != nil {
+		return nil, err
+	}
+	var installed []string
+	if _, err := os.Stat(manifestPath); err == nil {
+		installed = append(installed, manifestPath)
+	}
+	key, err := registered(browser)
+	if key != "" {
+		installed = append(installed, key)
+	}
+	return installed, err
+}
+
+// Uninstall removes the registration of the host with browser and returns the files
+// and registry keys removed. The wrapper script is left for other browsers.
+func Uninstall(browser string) ([]string, error) {
+	manifestPath, err := ManifestPath(browser)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	if err := os.Remove(manifestPath); err == nil {
+		removed = append(removed, manifestPath)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove %s: %w", manifestPath, err)
+	}
+	key, err := unregister(browser)
+	if key != "" {
+		removed = append(removed, key)
+	}
+	return removed, err
+}
@@ -0,0 +1,104 @@
+package nativehost
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageFraming(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteMessage(&buf, map[string]string{"action": "route", "url": "https://example.com/"}))
+	require.NoError(t, WriteMessage(&buf, map[string]string{"action": "open"}))
+
+	// The length prefix is in native byte order
+	assert.Equal(t, uint32(buf.Len()-4-len(`{"action":"open"}`)-4), binary.NativeEndian.Uint32(buf.Bytes()))
+
+	var msg map[string]string
+	require.NoError(t, ReadMessage(&buf, &msg))
+	assert.Equal(t, "https://example.com/", msg["url"])
+	require.NoError(t, ReadMessage(&buf, &msg))
+	assert.Equal(t, "open", msg["action"])
+	assert.ErrorIs(t, ReadMessage(&buf, &msg), io.EOF)
+
+	// Truncated and oversized messages are errors, not the end of input
+	buf.Reset()
+	require.NoError(t, WriteMessage(&buf, map[string]string{"url": "https://example.com/"}))
+	assert.ErrorIs(t, ReadMessage(bytes.NewReader(buf.Bytes()[:buf.Len()-2]), &msg), io.ErrUnexpectedEOF)
+	assert.ErrorIs(t, ReadMessage(bytes.NewReader([]byte{1, 0}), &msg), io.ErrUnexpectedEOF)
+	huge := binary.NativeEndian.AppendUint32(nil, MaxMessageSize+1)
+	assert.ErrorContains(t, ReadMessage(bytes.NewReader(huge), &msg), "exceeds the limit")
+	assert.ErrorContains(t, WriteMessage(io.Discard, string(make([]byte, MaxMessageSize))), "exceeds the limit")
+}
+
+func TestNewManifest(t *testing.T) {
+	id := "abcdefghijklmnopabcdefghijklmnop"
+	m, err := NewManifest(BrowserChrome, "/home/me/.config/rurl/native-host.sh", []string{id})
+	require.NoError(t, err)
+	assert.Equal(t, HostName, m.Name)
+	assert.Equal(t, "stdio", m.Type)
+	assert.Equal(t, []string{"chrome-extension://" + id + "/"}, m.AllowedOrigins)
+	assert.Empty(t, m.AllowedExtensions)
+
+	m, err = NewManifest(BrowserFirefox, "/home/me/.config/rurl/native-host.sh", []string{"rurl@example.org"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"rurl@example.org"}, m.AllowedExtensions)
+	assert.Empty(t, m.AllowedOrigins)
+
+	_, err = NewManifest(BrowserChrome, "/x", []string{"rurl@example.org"})
+	assert.ErrorContains(t, err, "invalid extension ID")
+	_, err = NewManifest(BrowserChrome, "/x", nil)
+	assert.Error(t, err)
+	_, err = NewManifest("netscape", "/x", []string{id})
+	assert.ErrorContains(t, err, "unknown browser")
+}
+
+func TestInstall(t *testing.T) {
+	if _, err := os.UserConfigDir(); err != nil || filepath.Separator != '/' {
+		t.Skip("manifest locations are tested on Unix")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+
+	wrapper := filepath.Join(home, "rurl", "native-host.sh")
+	written, err := Install(BrowserFirefox, []string{"rurl@example.org"}, wrapper, []string{"/opt/it's/rurl", "native-host"})
+	require.NoError(t, err)
+	manifestPath, err := ManifestPath(BrowserFirefox)
+	require.NoError(t, err)
+	assert.Equal(t, []string{wrapper, manifestPath}, written)
+
+	script, err := os.ReadFile(wrapper)
+	require.NoError(t, err)
+	assert.Equal(t, "#!/bin/sh\nexec '/opt/it'\\''s/rurl' 'native-host' \"$@\"\n", string(script))
+	data, err := os.ReadFile(manifestPath)
+	require.NoError(t, err)
+	var m Manifest
+	require.NoError(t, json.Unmarshal(data, &m))
+	assert.Equal(t, wrapper, m.Path)
+
+	installed, err := Installed(BrowserFirefox)
+	require.NoError(t, err)
+	assert.Equal(t, []string{manifestPath}, installed)
+
+	removed, err := Uninstall(BrowserFirefox)
+	require.NoError(t, err)
+	assert.Equal(t, []string{manifestPath}, removed)
+	assert.NoFileExists(t, manifestPath)
+	assert.FileExists(t, wrapper)
+	installed, err = Installed(BrowserFirefox)
+	require.NoError(t, err)
+	assert.Empty(t, installed)
+
+	// Removing what is not installed is not an error
+	removed, err = Uninstall(BrowserChrome)
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+}
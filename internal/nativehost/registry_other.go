@@ -0,0 +1,18 @@
+//go:build !windows
+
+package nativehost
+
+// register does nothing outside Windows, where browsers find manifests by location.
+func register(browser, manifestPath string) (string, error) {
+	return "", nil
+}
+
+// registered returns nothing outside Windows.
+func registered(browser string) (string, error) {
+	return "", nil
+}
+
+// unregister does nothing outside Windows.
+func unregister(browser string) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,52 @@
+//go:build windows
+
+package nativehost
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// register points browser's registry key for the host to manifestPath and returns
+// the key written.
+func register(browser, manifestPath string) (string, error) {
+	path := browserLocations[browser].registry + `\` + HostName
+	key, _, err := registry.CreateKey(registry.CURRENT_USER, path, registry.SET_VALUE)
+	if err != nil {
+		return "", fmt.Errorf("failed to create registry key %s: %w", path, err)
+	}
+	defer key.Close()
+	if err := key.SetStringValue("", manifestPath); err != nil {
+		return "", fmt.Errorf("failed to write registry key %s: %w", path, err)
+	}
+	return `HKEY_CURRENT_USER\` + path, nil
+}
+
+// registered returns browser's registry key for the host, if it exists.
+func registered(browser string) (string, error) {
+	path := browserLocations[browser].registry + `\` + HostName
+	key, err := registry.OpenKey(registry.CURRENT_USER, path, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to open registry key %s: %w", path, err)
+	}
+	key.Close()
+	return `HKEY_CURRENT_USER\` + path, nil
+}
+
+// unregister deletes browser's registry key for the host and returns the key
+// deleted, if it existed.
+func unregister(browser string) (string, error) {
+	path := browserLocations[browser].registry + `\` + HostName
+	if err := registry.DeleteKey(registry.CURRENT_USER, path); err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to delete registry key %s: %w", path, err)
+	}
+	return `HKEY_CURRENT_USER\` + path, nil
+}
@@ -0,0 +1,80 @@
+// Package network reads the network context rules can be conditioned on: the Wi-Fi
+// network the machine is connected to and the network interfaces that are up.
+package network
+
+import (
+	"context"
+	"net"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultVPNInterfaces matches the names of the interfaces of common VPN clients
+// (OpenVPN, WireGuard, IPsec, PPP and macOS utun interfaces), used when a rule does not
+// give its own.
+const DefaultVPNInterfaces = `^(tun|tap|utun|wg|ppp|ipsec|gpd|cscotun)\d*$`
+
+// Info is the network context of the machine.
+type Info struct {
+	SSID       string   // Wi-Fi network connected to, empty if none (or unknown)
+	Interfaces []string // Names of the network interfaces that are up, loopback excepted
+}
+
+// Detect returns the current network context. The SSID is left empty if it cannot be
+// read (e.g. no Wi-Fi, or the system tool is missing).
+func Detect(ctx context.Context) (*Info, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	info := &Info{}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp != 0 && iface.Flags&net.FlagLoopback == 0 {
+			info.Interfaces = append(info.Interfaces, iface.Name)
+		}
+	}
+	if info.SSID, err = currentSSID(ctx); err != nil {
+		log.Debug().Err(err).Msg("Could not read the Wi-Fi network")
+	}
+	log.Debug().Str("ssid", info.SSID).Strs("interfaces", info.Interfaces).Msg("Detected network context")
+	return info, nil
+}
+
+// VPNUp reports whether an interface matching pattern (DefaultVPNInterfaces if empty)
+// is up.
+func (i *Info) VPNUp(pattern string) (bool, error) {
+	if pattern == "" {
+		pattern = DefaultVPNInterfaces
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	for _, name := range i.Interfaces {
+		if re.MatchString(name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// output runs a command and returns its trimmed output.
+func output(ctx context.Context, name string, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// fieldValue returns the value of the first "key : value" line of out whose key is
+// key, ignoring surrounding spaces.
+func fieldValue(out, key string) string {
+	for _, line := range strings.Split(out, "\n") {
+		k, v, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(k) == key {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
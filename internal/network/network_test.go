@@ -0,0 +1,52 @@
+package network
+
+import "testing"
+
+func TestVPNUp(t *testing.T) {
+	tests := []struct {
+		name       string
+		interfaces []string
+		pattern    string
+		want       bool
+	}{
+		{"no vpn", []string{"eth0", "wlan0", "docker0"}, "", false},
+		{"openvpn", []string{"wlan0", "tun0"}, "", true},
+		{"wireguard", []string{"wg0"}, "", true},
+		{"macos", []string{"en0", "utun4"}, "", true},
+		{"prefix only", []string{"tunnel-bridge"}, "", false},
+		{"custom", []string{"eth0", "corpvpn"}, "^corpvpn$", true},
+		{"custom ignores defaults", []string{"tun0"}, "^corpvpn$", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &Info{Interfaces: tt.interfaces}
+			got, err := info.VPNUp(tt.pattern)
+			if err != nil {
+				t.Fatalf("VPNUp() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("VPNUp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := (&Info{}).VPNUp("("); err == nil {
+		t.Error("VPNUp() accepted an invalid pattern")
+	}
+}
+
+func TestFieldValue(t *testing.T) {
+	// netsh wlan show interfaces (abridged)
+	out := `
+    Name                   : Wi-Fi
+    State                  : connected
+    SSID                   : Corp: Guest
+    BSSID                  : aa:bb:cc:dd:ee:ff
+`
+	if got := fieldValue(out, "SSID"); got != "Corp: Guest" {
+		t.Errorf("fieldValue(SSID) = %q, want %q", got, "Corp: Guest")
+	}
+	if got := fieldValue(out, "Profile"); got != "" {
+		t.Errorf("fieldValue(Profile) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,32 @@
+//go:build darwin
+
+package network
+
+import (
+	"context"
+	"strings"
+)
+
+// currentSSID reads the Wi-Fi network of the Wi-Fi hardware port from ipconfig.
+func currentSSID(ctx context.Context) (string, error) {
+	ports, err := output(ctx, "networksetup", "-listallhardwareports")
+	if err != nil {
+		return "", err
+	}
+	device := ""
+	lines := strings.Split(ports, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "Hardware Port: Wi-Fi" && i+1 < len(lines) {
+			device = fieldValue(lines[i+1], "Device")
+			break
+		}
+	}
+	if device == "" {
+		return "", nil
+	}
+	summary, err := output(ctx, "ipconfig", "getsummary", device)
+	if err != nil {
+		return "", err
+	}
+	return fieldValue(summary, "SSID"), nil
+}
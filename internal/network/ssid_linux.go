@@ -0,0 +1,23 @@
+//go:build linux
+
+package network
+
+import (
+	"context"
+	"strings"
+)
+
+// currentSSID asks NetworkManager for the active Wi-Fi network, falling back to
+// iwgetid (wireless-tools).
+func currentSSID(ctx context.Context) (string, error) {
+	out, err := output(ctx, "nmcli", "-t", "-f", "ACTIVE,SSID", "device", "wifi")
+	if err == nil {
+		for _, line := range strings.Split(out, "\n") {
+			if ssid, ok := strings.CutPrefix(line, "yes:"); ok {
+				return strings.ReplaceAll(ssid, `\:`, ":"), nil // Terse output escapes colons
+			}
+		}
+		return "", nil
+	}
+	return output(ctx, "iwgetid", "-r")
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !windows
+
+package network
+
+import "context"
+
+// currentSSID is not supported on this system.
+func currentSSID(ctx context.Context) (string, error) {
+	return "", nil
+}
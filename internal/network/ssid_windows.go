@@ -0,0 +1,14 @@
+//go:build windows
+
+package network
+
+import "context"
+
+// currentSSID reads the Wi-Fi network of the first wireless interface from netsh.
+func currentSSID(ctx context.Context) (string, error) {
+	out, err := output(ctx, "netsh", "wlan", "show", "interfaces")
+	if err != nil {
+		return "", err
+	}
+	return fieldValue(out, "SSID"), nil
+}
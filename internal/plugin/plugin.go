@@ -0,0 +1,160 @@
+// Package plugin runs external executables that extend URL handling with custom
+// resolvers (URL rewriting) and matchers (rule predicates).
+//
+// A plugin is started once per request. rurl writes a single JSON Request to its
+// standard input and reads a single JSON Response from its standard output:
+//
+//	{"version": 1, "action": "resolve", "url": "https://wrap.example/?u=..."}
+//	{"url": "https://target.example/"}
+//
+//	{"version": 1, "action": "match", "url": "https://example.com/", "rule": "work"}
+//	{"match": true}
+//
+// A resolver returning an empty URL leaves the URL unchanged. A non-zero exit
+// status or a non-empty "error" field is treated as a failure.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// ProtocolVersion is the version of the request format sent to plugins.
+const ProtocolVersion = 1
+
+// DefaultTimeout is used for plugins without a configured timeout.
+const DefaultTimeout = 5 * time.Second
+
+// Plugin actions.
+const (
+	ActionResolve = "resolve"
+	ActionMatch   = "match"
+)
+
+// Request is written to the plugin's standard input.
+type Request struct {
+	Version int    `json:"version"`
+	Action  string `json:"action"`
+	URL     string `json:"url"`
+	Rule    string `json:"rule,omitempty"` // ID of the rule being matched (match only)
+}
+
+// Response is read from the plugin's standard output.
+type Response struct {
+	URL   string `json:"url,omitempty"`   // Rewritten URL (resolve only; empty means unchanged)
+	Match bool   `json:"match,omitempty"` // Whether the URL matches (match only)
+	Error string `json:"error,omitempty"` // Reported failure
+}
+
+// Run sends req to the plugin p and returns its response. The plugin is killed if it
+// outlives its timeout or ctx.
+func Run(ctx context.Context, p config.Plugin, req Request) (Response, error) {
+	if p.Command == "" {
+		return Response{}, fmt.Errorf("plugin '%s' has no command configured", p.Name)
+	}
+	req.Version = ProtocolVersion
+	input, err := json.Marshal(req)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to encode plugin request: %w", err)
+	}
+
+	timeout := time.Duration(p.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second // Don't wait on children still holding the output open after a timeout
+
+	log.Debug().Str("plugin", p.Name).Str("action", req.Action).Str("url", req.URL).Msg("Running plugin")
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return Response{}, fmt.Errorf("plugin '%s' timed out after %s", p.Name, timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return Response{}, fmt.Errorf("plugin '%s' failed: %w: %s", p.Name, err, msg)
+		}
+		return Response{}, fmt.Errorf("plugin '%s' failed: %w", p.Name, err)
+	}
+
+	var resp Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return Response{}, fmt.Errorf("plugin '%s' returned an invalid response: %w", p.Name, err)
+	}
+	if resp.Error != "" {
+		return Response{}, fmt.Errorf("plugin '%s' reported an error: %s", p.Name, resp.Error)
+	}
+	return resp, nil
+}
+
+// Resolve passes rawURL through each resolver plugin that applies to its domain, in
+// configuration order, and returns the rewritten URL. Failing plugins are logged and
+// skipped, and no further plugins are run once ctx is cancelled.
+func Resolve(ctx context.Context, cfg *config.Config, rawURL string) string {
+	for _, p := range cfg.Plugins {
+		if ctx.Err() != nil {
+			break
+		}
+		if p.Kind != config.PluginResolver || !appliesTo(p, rawURL) {
+			continue
+		}
+		resp, err := Run(ctx, p, Request{Action: ActionResolve, URL: rawURL})
+		if err != nil {
+			log.Warn().Err(err).Str("url", rawURL).Msg("Resolver plugin failed, keeping URL")
+			continue
+		}
+		if resp.URL != "" && resp.URL != rawURL {
+			log.Info().Str("plugin", p.Name).Str("url", rawURL).Str("resolved_url", resp.URL).Msg("Plugin rewrote URL")
+			rawURL = resp.URL
+		}
+	}
+	return rawURL
+}
+
+// Match asks the matcher plugin named by rule.Plugin whether rawURL matches rule.
+func Match(ctx context.Context, cfg *config.Config, rule *config.Rule, rawURL string) (bool, error) {
+	p := cfg.FindPlugin(rule.Plugin)
+	if p == nil || p.Kind != config.PluginMatcher {
+		return false, fmt.Errorf("unknown matcher plugin '%s'", rule.Plugin)
+	}
+	resp, err := Run(ctx, *p, Request{Action: ActionMatch, URL: rawURL, Rule: rule.ID})
+	if err != nil {
+		return false, err
+	}
+	return resp.Match, nil
+}
+
+// appliesTo reports whether p should run for rawURL, based on its Domains.
+func appliesTo(p config.Plugin, rawURL string) bool {
+	if len(p.Domains) == 0 {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, d := range p.Domains {
+		d = strings.ToLower(strings.TrimPrefix(d, "."))
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeScript creates an executable shell script plugin in a temporary directory.
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script plugins are not supported on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "plugin.sh")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755))
+	return path
+}
+
+func TestResolve(t *testing.T) {
+	unwrap := writeScript(t, `grep -q '"action":"resolve"' && echo '{"url": "https://target.example/"}'`)
+	failing := writeScript(t, "echo nope >&2; exit 3")
+	cfg := &config.Config{Plugins: []config.Plugin{
+		{Name: "broken", Kind: config.PluginResolver, Command: failing},
+		{Name: "unwrap", Kind: config.PluginResolver, Command: unwrap, Domains: []string{"wrap.example"}},
+		{Name: "matcher", Kind: config.PluginMatcher, Command: failing},
+	}}
+
+	assert.Equal(t, "https://target.example/", Resolve(context.Background(), cfg, "https://links.wrap.example/?u=abc"))
+	assert.Equal(t, "https://other.example/", Resolve(context.Background(), cfg, "https://other.example/"))
+}
+
+func TestRun(t *testing.T) {
+	echo := writeScript(t, `cat >/dev/null; echo '{"match": true}'`)
+	resp, err := Run(context.Background(), config.Plugin{Name: "echo", Command: echo}, Request{Action: ActionMatch, URL: "https://example.com/"})
+	require.NoError(t, err)
+	assert.True(t, resp.Match)
+
+	reported := writeScript(t, `echo '{"error": "unsupported"}'`)
+	_, err = Run(context.Background(), config.Plugin{Name: "reported", Command: reported}, Request{Action: ActionMatch})
+	assert.ErrorContains(t, err, "unsupported")
+
+	failing := writeScript(t, "echo bad input >&2; exit 1")
+	_, err = Run(context.Background(), config.Plugin{Name: "failing", Command: failing}, Request{Action: ActionMatch})
+	assert.ErrorContains(t, err, "bad input")
+
+	slow := writeScript(t, "sleep 5")
+	_, err = Run(context.Background(), config.Plugin{Name: "slow", Command: slow, TimeoutSeconds: 1}, Request{Action: ActionMatch})
+	assert.ErrorContains(t, err, "timed out")
+}
+
+func TestMatch(t *testing.T) {
+	script := writeScript(t, `if grep -q '"rule":"tickets"'; then echo '{"match": true}'; else echo '{}'; fi`)
+	cfg := &config.Config{Plugins: []config.Plugin{{Name: "tickets", Kind: config.PluginMatcher, Command: script}}}
+
+	ok, err := Match(context.Background(), cfg, &config.Rule{ID: "tickets", Plugin: "tickets"}, "https://example.com/")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = Match(context.Background(), cfg, &config.Rule{ID: "other", Plugin: "tickets"}, "https://example.com/")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = Match(context.Background(), cfg, &config.Rule{ID: "x", Plugin: "missing"}, "https://example.com/")
+	assert.Error(t, err)
+}
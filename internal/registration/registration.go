@@ -0,0 +1,136 @@
+package registration
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jmylchreest/rurl/internal/nativehost"
+	"github.com/rs/zerolog/log"
+)
+
+// AppName is the identifier used for the desktop entry and data directories.
+const AppName = "rurl"
+
+// Result describes what an unregister operation changed.
+type Result struct {
+	Removed  []string // Files or entries that were removed
+	Messages []string // Additional notes for the user (e.g. manual steps required)
+}
+
+// nativeHostPending lists the native messaging host manifests and registry keys the
+// native-host install command registered rurl with, see unregisterNativeHosts.
+func nativeHostPending() ([]string, error) {
+	var pending []string
+	for _, browser := range nativehost.Browsers() {
+		installed, err := nativehost.Installed(browser)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, installed...)
+	}
+	return pending, nil
+}
+
+// unregisterNativeHosts removes the native messaging host registrations of every
+// browser and records them in res.
+func unregisterNativeHosts(res *Result) error {
+	for _, browser := range nativehost.Browsers() {
+		removed, err := nativehost.Uninstall(browser)
+		res.Removed = append(res.Removed, removed...)
+		if err != nil {
+			return err
+		}
+		for _, path := range removed {
+			log.Debug().Str("path", path).Msg("Removed native messaging host registration")
+		}
+	}
+	return nil
+}
+
+// DataPaths returns the existing paths rurl writes user data to: configPaths (the
+// configuration directory, or the files of a configuration kept elsewhere) and the
+// cache directory (launch history, shortener candidates, downloaded URL lists and
+// ephemeral profiles). Paths that cannot be determined or do not exist are omitted.
+func DataPaths(configPaths ...string) []string {
+	candidates := slices.Clone(configPaths)
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		candidates = append(candidates, filepath.Join(cacheDir, AppName))
+	}
+
+	var paths []string
+	for _, p := range candidates {
+		if p == "" {
+			continue
+		}
+		if _, err := os.Stat(p); err == nil {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// RemoveDataPaths deletes the given files and directories if they exist.
+// Missing paths are skipped without error.
+func RemoveDataPaths(paths []string) (Result, error) {
+	var res Result
+	for _, p := range paths {
+		if _, err := os.Stat(p); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.RemoveAll(p); err != nil {
+			return res, fmt.Errorf("failed to remove '%s': %w", p, err)
+		}
+		log.Debug().Str("path", p).Msg("Removed data directory")
+		res.Removed = append(res.Removed, p)
+	}
+	return res, nil
+}
+
+// stripMimeAppsEntries removes every reference to desktopFile from the contents
+// of a mimeapps.list file. Keys whose value list becomes empty are dropped.
+// It returns the new contents and whether anything changed.
+func stripMimeAppsEntries(contents string, desktopFile string) (string, bool) {
+	var out strings.Builder
+	changed := false
+
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "[") {
+			out.WriteString(line + "\n")
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			out.WriteString(line + "\n")
+			continue
+		}
+
+		var kept []string
+		for _, v := range strings.Split(parts[1], ";") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			if v == desktopFile {
+				changed = true
+				continue
+			}
+			kept = append(kept, v)
+		}
+
+		if len(kept) == 0 {
+			changed = true
+			continue // Drop the key entirely
+		}
+		out.WriteString(parts[0] + "=" + strings.Join(kept, ";") + ";\n")
+	}
+
+	return out.String(), changed
+}
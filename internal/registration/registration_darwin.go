@@ -0,0 +1,23 @@
+//go:build darwin
+
+package registration
+
+// Pending lists what Unregister would remove: the native messaging host manifests
+// installed by the native-host command.
+func Pending() ([]string, error) {
+	return nativeHostPending()
+}
+
+// Unregister removes the native messaging host manifests and explains how to stop
+// using rurl as the default browser. rurl does not create any handler application on
+// macOS, and the default browser cannot be unset programmatically, so the user is told
+// to pick a replacement in System Settings.
+func Unregister() (Result, error) {
+	var res Result
+	if err := unregisterNativeHosts(&res); err != nil {
+		return res, err
+	}
+	res.Messages = append(res.Messages,
+		"Select a new default web browser in System Settings > Desktop & Dock > Default web browser.")
+	return res, nil
+}
@@ -0,0 +1,138 @@
+//go:build linux
+
+package registration
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+)
+
+// desktopFileName is the name of the desktop entry documented for registering rurl.
+const desktopFileName = AppName + ".desktop"
+
+// linuxPaths holds the locations rurl's desktop entry and mime associations live in.
+type linuxPaths struct {
+	mimeLists []string
+	appsDir   string
+}
+
+// desktopPath returns the path of rurl's desktop entry.
+func (p linuxPaths) desktopPath() string {
+	return filepath.Join(p.appsDir, desktopFileName)
+}
+
+// getLinuxPaths resolves the XDG locations used for desktop entries and mime associations.
+func getLinuxPaths() (linuxPaths, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return linuxPaths{}, fmt.Errorf("failed to get user home directory: %w", err)
+	}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(homeDir, ".config")
+	}
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(homeDir, ".local", "share")
+	}
+	return linuxPaths{
+		mimeLists: []string{
+			filepath.Join(configHome, "mimeapps.list"),
+			filepath.Join(dataHome, "applications", "mimeapps.list"),
+		},
+		appsDir: filepath.Join(dataHome, "applications"),
+	}, nil
+}
+
+// Pending lists the desktop entry and mime associations Unregister would remove,
+// without changing anything.
+func Pending() ([]string, error) {
+	paths, err := getLinuxPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []string
+	for _, listPath := range paths.mimeLists {
+		data, err := os.ReadFile(listPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read '%s': %w", listPath, err)
+		}
+		if _, changed := stripMimeAppsEntries(string(data), desktopFileName); changed {
+			pending = append(pending, fmt.Sprintf("%s entries in %s", desktopFileName, listPath))
+		}
+	}
+	if _, err := os.Stat(paths.desktopPath()); err == nil {
+		pending = append(pending, paths.desktopPath())
+	}
+	hosts, err := nativeHostPending()
+	if err != nil {
+		return nil, err
+	}
+	return append(pending, hosts...), nil
+}
+
+// Unregister removes rurl as the default handler for web URLs and deletes the
+// rurl.desktop entry described in the README.
+func Unregister() (Result, error) {
+	var res Result
+
+	paths, err := getLinuxPaths()
+	if err != nil {
+		return res, err
+	}
+
+	// 1. Remove rurl from the mime associations
+	for _, listPath := range paths.mimeLists {
+		data, err := os.ReadFile(listPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return res, fmt.Errorf("failed to read '%s': %w", listPath, err)
+		}
+		updated, changed := stripMimeAppsEntries(string(data), desktopFileName)
+		if !changed {
+			continue
+		}
+		if err := os.WriteFile(listPath, []byte(updated), 0644); err != nil {
+			return res, fmt.Errorf("failed to write '%s': %w", listPath, err)
+		}
+		log.Debug().Str("path", listPath).Msg("Removed rurl mime associations")
+		res.Removed = append(res.Removed, fmt.Sprintf("%s entries in %s", desktopFileName, listPath))
+	}
+
+	// 2. Remove the desktop entry itself
+	desktopPath := paths.desktopPath()
+	if _, err := os.Stat(desktopPath); err == nil {
+		if err := os.Remove(desktopPath); err != nil {
+			return res, fmt.Errorf("failed to remove '%s': %w", desktopPath, err)
+		}
+		res.Removed = append(res.Removed, desktopPath)
+
+		// Refresh the desktop database if the tool is available
+		if _, err := exec.LookPath("update-desktop-database"); err == nil {
+			if err := exec.Command("update-desktop-database", paths.appsDir).Run(); err != nil {
+				log.Warn().Err(err).Str("path", paths.appsDir).Msg("Failed to update desktop database")
+			}
+		}
+	}
+
+	// 3. Remove the native messaging host manifests
+	if err := unregisterNativeHosts(&res); err != nil {
+		return res, err
+	}
+
+	if len(res.Removed) == 0 {
+		res.Messages = append(res.Messages, "No rurl desktop entries, mime associations or native messaging hosts were found.")
+	}
+	return res, nil
+}
@@ -0,0 +1,73 @@
+package registration
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripMimeAppsEntries(t *testing.T) {
+	input := `[Default Applications]
+x-scheme-handler/http=rurl.desktop;
+x-scheme-handler/https=rurl.desktop;firefox.desktop;
+text/html=firefox.desktop;
+
+[Added Associations]
+x-scheme-handler/http=rurl.desktop;chromium.desktop;
+`
+	want := `[Default Applications]
+x-scheme-handler/https=firefox.desktop;
+text/html=firefox.desktop;
+
+[Added Associations]
+x-scheme-handler/http=chromium.desktop;
+`
+	got, changed := stripMimeAppsEntries(input, "rurl.desktop")
+	assert.True(t, changed)
+	assert.Equal(t, want, got)
+
+	// No references means no change
+	_, changed = stripMimeAppsEntries("[Default Applications]\ntext/html=firefox.desktop;\n", "rurl.desktop")
+	assert.False(t, changed)
+}
+
+func TestRemoveDataPaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	existing := filepath.Join(tmpDir, "config")
+	require.NoError(t, os.MkdirAll(existing, 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(existing, "config.toml"), []byte(""), 0644))
+	missing := filepath.Join(tmpDir, "missing")
+
+	res, err := RemoveDataPaths([]string{existing, missing})
+	require.NoError(t, err)
+	assert.Equal(t, []string{existing}, res.Removed)
+	assert.NoDirExists(t, existing)
+}
+
+func TestDataPaths(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("cache directory is only configurable through XDG_CACHE_HOME on Unix")
+	}
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(tmpDir, "cache"))
+	configDir := filepath.Join(tmpDir, "config")
+	require.NoError(t, os.MkdirAll(configDir, 0750))
+
+	// Only existing directories are listed
+	assert.Equal(t, []string{configDir}, DataPaths(configDir))
+
+	cacheDir := filepath.Join(tmpDir, "cache", AppName)
+	require.NoError(t, os.MkdirAll(cacheDir, 0750))
+	assert.Equal(t, []string{configDir, cacheDir}, DataPaths(configDir))
+
+	// Files of a configuration kept elsewhere are listed individually
+	cfgFile := filepath.Join(tmpDir, "dotfiles", "rurl.toml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(cfgFile), 0750))
+	require.NoError(t, os.WriteFile(cfgFile, nil, 0600))
+	missing := filepath.Join(tmpDir, "dotfiles", "machine.toml")
+	assert.Equal(t, []string{cfgFile, cacheDir}, DataPaths(cfgFile, missing))
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package registration
+
+// Pending lists what Unregister would remove: the native messaging host manifests
+// and their NativeMessagingHosts registry keys under HKEY_CURRENT_USER, written by the
+// native-host command.
+func Pending() ([]string, error) {
+	return nativeHostPending()
+}
+
+// Unregister removes the native messaging host manifests and registry keys, and
+// explains how to stop using rurl as the default browser. rurl does not register
+// itself as a browser, so any default browser association was made by the user and is
+// left alone.
+func Unregister() (Result, error) {
+	var res Result
+	if err := unregisterNativeHosts(&res); err != nil {
+		return res, err
+	}
+	res.Messages = append(res.Messages,
+		"Select a new default web browser in Settings > Apps > Default apps.")
+	return res, nil
+}
@@ -0,0 +1,52 @@
+package router
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/jmylchreest/rurl/internal/config"
+)
+
+// BlockedError is returned by Route when a resolved URL violates the resolution policy
+// and the policy blocks it, or when a URL is refused for other reasons (strict mode,
+// the data: URL limit or SanitizeURL).
+type BlockedError struct {
+	URL    string // Resolved URL
+	Reason string // Why the policy rejects it, see CheckResolutionPolicy
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("blocked: %s %s", e.URL, e.Reason)
+}
+
+// CheckResolutionPolicy returns why policy does not allow launching resolvedURL, or ""
+// if it does.
+func CheckResolutionPolicy(policy config.ResolutionPolicy, resolvedURL string) string {
+	if len(policy.Allow) == 0 && len(policy.Deny) == 0 {
+		return ""
+	}
+	u, err := url.Parse(resolvedURL)
+	if err != nil || u.Hostname() == "" {
+		return "has no domain the resolution policy can check"
+	}
+	host := strings.ToLower(u.Hostname())
+	if matchesDomain(host, policy.Deny) {
+		return "is on the resolution policy's deny list"
+	}
+	if len(policy.Allow) > 0 && !matchesDomain(host, policy.Allow) {
+		return "is not on the resolution policy's allow list"
+	}
+	return ""
+}
+
+// matchesDomain reports whether host is one of domains or a subdomain of one.
+func matchesDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		d = strings.ToLower(strings.TrimPrefix(d, "."))
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
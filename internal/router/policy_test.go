@@ -0,0 +1,109 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckResolutionPolicy(t *testing.T) {
+	policy := config.ResolutionPolicy{
+		Allow: []string{"example.com", ".example.org"},
+		Deny:  []string{"ads.example.com"},
+	}
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/a", ""},
+		{"https://docs.EXAMPLE.com/a", ""},
+		{"https://example.org/", ""},
+		{"https://ads.example.com/x", "is on the resolution policy's deny list"},
+		{"https://tracker.ads.example.com/x", "is on the resolution policy's deny list"},
+		{"https://notexample.com/", "is not on the resolution policy's allow list"},
+		{"mailto:someone@example.com", "has no domain the resolution policy can check"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, CheckResolutionPolicy(policy, tt.url), tt.url)
+	}
+
+	// Without an allow list only denied domains are rejected
+	assert.Empty(t, CheckResolutionPolicy(config.ResolutionPolicy{Deny: []string{"ads.example.com"}}, "https://other.example/"))
+	assert.Empty(t, CheckResolutionPolicy(config.ResolutionPolicy{}, "mailto:someone@example.com"))
+}
+
+func TestRouteResolutionPolicy(t *testing.T) {
+	// A local "shortener" on 127.0.0.1 redirecting to localhost
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/s" {
+			http.Redirect(w, r, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)+"/target", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		DefaultProfileID: "personal",
+		Browsers:         []config.Browser{{Name: "Test Browser", BrowserID: "test", Executable: "/bin/echo"}},
+		Profiles:         []config.Profile{{ID: "personal", Name: "Personal", BrowserID: "test"}},
+		ManualShorteners: []config.ShortenerService{{Domain: "127.0.0.1"}},
+		ResolutionPolicy: config.ResolutionPolicy{Allow: []string{"example.com"}},
+	}
+
+	_, err := Route(context.Background(), cfg, server.URL+"/s")
+	var blocked *BlockedError
+	require.True(t, errors.As(err, &blocked), "err = %v", err)
+	assert.True(t, strings.HasPrefix(blocked.URL, "http://localhost:"))
+	assert.Equal(t, "is not on the resolution policy's allow list", blocked.Reason)
+
+	// Prompting leaves the decision to the caller
+	cfg.ResolutionPolicy.Action = config.PolicyPrompt
+	result, err := Route(context.Background(), cfg, server.URL+"/s")
+	require.NoError(t, err)
+	assert.Equal(t, "is not on the resolution policy's allow list", result.PolicyViolation)
+
+	// URLs that were not resolved are not checked
+	cfg.ResolutionPolicy.Action = config.PolicyBlock
+	result, err = Route(context.Background(), cfg, "https://other.example/")
+	require.NoError(t, err)
+	assert.Empty(t, result.PolicyViolation)
+	assert.Equal(t, "personal", result.Match.ProfileID)
+}
+
+func TestRouteStrictMode(t *testing.T) {
+	cfg := &config.Config{
+		DefaultProfileID: "personal",
+		Profiles:         []config.Profile{{ID: "personal"}, {ID: "school"}},
+		Rules:            []config.Rule{{Name: "School", Pattern: `^school\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "school"}},
+		Overrides:        map[string]string{"https://example.org/homework": "school"},
+		Strict:           config.Strict{Enabled: true},
+	}
+
+	result, err := Route(context.Background(), cfg, "https://school.example.com/")
+	require.NoError(t, err)
+	assert.Equal(t, "school", result.Match.ProfileID)
+	result, err = Route(context.Background(), cfg, "https://example.org/homework")
+	require.NoError(t, err)
+	assert.True(t, result.Match.Override)
+
+	// Anything else is blocked rather than opened in the default profile, if any
+	var blocked *BlockedError
+	_, err = Route(context.Background(), cfg, "https://games.example.net/")
+	require.True(t, errors.As(err, &blocked), "err = %v", err)
+	assert.Equal(t, StrictModeReason, blocked.Reason)
+	cfg.DefaultProfileID = ""
+	_, err = Route(context.Background(), cfg, "https://games.example.net/")
+	assert.True(t, errors.As(err, &blocked), "err = %v", err)
+
+	// Missing profiles are still configuration errors
+	cfg.Rules[0].ProfileID = "gone"
+	_, err = Route(context.Background(), cfg, "https://school.example.com/")
+	assert.False(t, errors.As(err, &blocked), "err = %v", err)
+}
@@ -0,0 +1,185 @@
+// Package router implements the routing core shared by the rurl command and the
+// public pkg/rurl API: resolving a URL, matching it against the rules and deciding
+// which URL to launch.
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/network"
+	"github.com/jmylchreest/rurl/internal/plugin"
+	"github.com/jmylchreest/rurl/internal/rules"
+	"github.com/jmylchreest/rurl/internal/urlhandler"
+	"github.com/rs/zerolog/log"
+)
+
+// Result is the outcome of routing a URL.
+type Result struct {
+	InputURL  string            // URL as given
+	MatchURL  string            // URL the rules were matched against (shortener resolved, rewritten and cleaned)
+	LaunchURL string            // URL to open; the original URL for safelink shorteners
+	Shortened bool              // A shortener was resolved
+	Match     rules.MatchResult // Matched rule and profile
+
+	// PolicyViolation is why the resolved URL violates the resolution policy, if it does
+	// and the policy asks to prompt (blocked URLs are returned as a *BlockedError).
+	PolicyViolation string
+}
+
+// Route normalizes inputURL (see urlhandler.NormalizeURL), resolves it (shorteners,
+// resolver plugins, URL cleaning), checks the resolution policy, optionally inspects
+// its content, applies the rules and decides which URL to launch, which is checked with
+// SanitizeURL. Network requests are only made for known shorteners and opt-in content
+// inspection. Routing stops with an error once ctx is cancelled, and oversized data:
+// URLs and URLs failing SanitizeURL are refused with a *BlockedError.
+func Route(ctx context.Context, cfg *config.Config, inputURL string) (Result, error) {
+	result := Result{InputURL: inputURL}
+
+	if err := checkDataURL(cfg, inputURL); err != nil {
+		return Result{}, err
+	}
+
+	// Normalize once, so that shortener matching and the rules see the same URL
+	inputURL = urlhandler.NormalizeURL(inputURL)
+
+	// Resolve shorteners and check for safelinks
+	resolvedURL, originalURL, isSafelink, err := urlhandler.ProcessURL(ctx, cfg, inputURL)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to process URL: %w", err)
+	}
+	result.Shortened = resolvedURL != inputURL
+
+	// Let resolver plugins unwrap proprietary link wrappers
+	if len(cfg.Plugins) > 0 {
+		resolvedURL = plugin.Resolve(ctx, cfg, resolvedURL)
+	}
+	if resolvedURL != inputURL {
+		resolvedURL = urlhandler.NormalizeURL(resolvedURL)
+	}
+
+	// Check where resolution led before the rules are applied
+	if resolvedURL != inputURL {
+		if reason := CheckResolutionPolicy(cfg.ResolutionPolicy, resolvedURL); reason != "" {
+			if cfg.ResolutionPolicy.Action != config.PolicyPrompt {
+				return Result{}, &BlockedError{URL: resolvedURL, Reason: reason}
+			}
+			result.PolicyViolation = reason
+		}
+	}
+
+	// Optionally rewrite AMP/mobile variants to the canonical page
+	if cfg.URLCleaning.UnAMP {
+		resolvedURL = urlhandler.CanonicalizeURL(resolvedURL)
+	}
+
+	// Optionally inspect the target's content type (opt-in, only when rules need it)
+	matchCtx := rules.MatchContext{
+		PluginMatch: func(rule *config.Rule, u string) (bool, error) { return plugin.Match(ctx, cfg, rule, u) },
+	}
+	if cfg.ContentInspection.Enabled && rules.NeedsContentInspection(cfg) &&
+		(strings.HasPrefix(resolvedURL, "http://") || strings.HasPrefix(resolvedURL, "https://")) {
+		timeout := time.Duration(cfg.ContentInspection.TimeoutSeconds) * time.Second
+		content, err := urlhandler.InspectURL(ctx, resolvedURL, timeout)
+		if err != nil {
+			log.Warn().Err(err).Str("url", resolvedURL).Msg("Content inspection failed, content conditions will not match")
+		} else {
+			matchCtx.Content = content
+		}
+	}
+
+	// Detect the network context if rules depend on it (Wi-Fi network, VPN)
+	if rules.NeedsNetworkInfo(cfg) {
+		info, err := network.Detect(ctx)
+		if err != nil {
+			log.Warn().Err(err).Msg("Network detection failed, network conditions will not match")
+		} else {
+			matchCtx.Network = info
+		}
+	}
+
+	// Apply rules based on the resolved URL
+	result.Match, err = rules.ApplyRulesWithContext(cfg, resolvedURL, matchCtx)
+	if cfg.Strict.Enabled && usesDefaultProfile(result.Match, err) {
+		return Result{}, &BlockedError{URL: resolvedURL, Reason: StrictModeReason}
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to apply rules: %w", err)
+	}
+	// Plugins and inspection only log failures; don't launch a half-routed URL
+	if ctx.Err() != nil {
+		return Result{}, fmt.Errorf("routing stopped: %w", context.Cause(ctx))
+	}
+	result.MatchURL = resolvedURL
+	launchURL := LaunchURL(resolvedURL, originalURL, result.Shortened, isSafelink, result.Match)
+	if result.LaunchURL, err = SanitizeURL(cfg, launchURL, result.Match.Handler != ""); err != nil {
+		return Result{}, err
+	}
+	return result, nil
+}
+
+// defaultMaxDataURLBytes is the longest data: URL launched when
+// Behavior.MaxDataURLBytes is not set, well within the 32767 characters of a Windows
+// command line.
+const defaultMaxDataURLBytes = 16 * 1024
+
+// checkDataURL returns a *BlockedError if inputURL is a data: URL longer than the
+// configured limit, which browsers (and command lines) handle unpredictably.
+func checkDataURL(cfg *config.Config, inputURL string) error {
+	if len(inputURL) < 5 || !strings.EqualFold(inputURL[:5], "data:") {
+		return nil
+	}
+	limit := cfg.Behavior.MaxDataURLBytes
+	if limit <= 0 {
+		limit = defaultMaxDataURLBytes
+	}
+	if len(inputURL) <= limit {
+		return nil
+	}
+	shown, _, _ := strings.Cut(inputURL, ",")
+	if len(shown) > 64 {
+		shown = shown[:64]
+	}
+	return &BlockedError{
+		URL:    shown + ",...",
+		Reason: fmt.Sprintf("is a data: URL of %d bytes, over the limit of %d (behavior.max_data_url_bytes)", len(inputURL), limit),
+	}
+}
+
+// StrictModeReason is the reason of the BlockedError for URLs that match no rule in
+// strict mode.
+const StrictModeReason = "matches no rule (strict mode)"
+
+// usesDefaultProfile reports whether the rules matched nothing, falling back to the
+// default profile, given the result and error of rules.ApplyRules.
+func usesDefaultProfile(match rules.MatchResult, err error) bool {
+	var noProfile *rules.NoProfileError
+	if errors.As(err, &noProfile) {
+		return noProfile.RuleName == "" && noProfile.URL == ""
+	}
+	return err == nil && match.Rule == nil && !match.Override
+}
+
+// LaunchURL returns the URL to launch after rule matching: the resolved URL, or the
+// original one for safelink shorteners. The matched rule may override the
+// shortener's safelink setting, or rewrite the URL altogether.
+func LaunchURL(resolvedURL, originalURL string, shortened, isSafelink bool, matchResult rules.MatchResult) string {
+	if matchResult.RewriteURL != "" {
+		log.Info().Str("rewritten_url", matchResult.RewriteURL).Msg("Matched rule rewrites the launched URL")
+		return matchResult.RewriteURL
+	}
+	launchOriginal := isSafelink
+	if shortened && matchResult.LaunchOriginal != nil {
+		launchOriginal = *matchResult.LaunchOriginal
+		log.Debug().Bool("launch_original", launchOriginal).Msg("Matched rule overrides shortener safelink setting")
+	}
+	if launchOriginal {
+		log.Info().Str("original_url", originalURL).Msg("Safelink detected, launching original URL after rule matching")
+		return originalURL
+	}
+	return resolvedURL
+}
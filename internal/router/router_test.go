@@ -0,0 +1,75 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteNormalizesURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/s" {
+			http.Redirect(w, r, "/%7etarget", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		DefaultProfileID: "personal",
+		Profiles:         []config.Profile{{ID: "personal"}, {ID: "work"}},
+		Rules: []config.Rule{
+			{Name: "Docs", Pattern: `^docs\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "work"},
+			{Name: "Target", Pattern: `^/~target$`, Scope: config.ScopePath, ProfileID: "work"},
+		},
+		ManualShorteners: []config.ShortenerService{{Domain: "localhost"}},
+	}
+
+	// Rules see the normalized URL, whatever its spelling
+	result, err := Route(context.Background(), cfg, "HTTPS://DOCS.example.com.:443/%7Eguide")
+	require.NoError(t, err)
+	assert.Equal(t, "HTTPS://DOCS.example.com.:443/%7Eguide", result.InputURL)
+	assert.Equal(t, "https://docs.example.com/~guide", result.MatchURL)
+	assert.Equal(t, "work", result.Match.ProfileID)
+	assert.False(t, result.Shortened)
+
+	// Shorteners are found by their normalized host, and resolved URLs are normalized too
+	host := strings.Replace(server.URL, "127.0.0.1", "LOCALHOST", 1)
+	result, err = Route(context.Background(), cfg, host+"/s")
+	require.NoError(t, err)
+	assert.True(t, result.Shortened)
+	assert.Equal(t, strings.ToLower(host)+"/~target", result.MatchURL)
+	assert.Equal(t, "work", result.Match.ProfileID)
+}
+
+func TestRouteDataURLs(t *testing.T) {
+	cfg := &config.Config{
+		DefaultProfileID: "personal",
+		Profiles:         []config.Profile{{ID: "personal"}},
+		Behavior:         config.Behavior{MaxDataURLBytes: 64},
+	}
+
+	small := "data:text/plain;base64,aGVsbG8="
+	result, err := Route(context.Background(), cfg, small)
+	require.NoError(t, err)
+	assert.Equal(t, small, result.LaunchURL)
+
+	// Oversized ones are refused, naming the media type but not the whole payload
+	large := "data:text/html;base64," + strings.Repeat("A", 100)
+	_, err = Route(context.Background(), cfg, large)
+	var blocked *BlockedError
+	require.True(t, errors.As(err, &blocked), "err = %v", err)
+	assert.Equal(t, "data:text/html;base64,...", blocked.URL)
+	assert.Contains(t, blocked.Reason, "over the limit of 64")
+
+	// The default limit allows more
+	cfg.Behavior.MaxDataURLBytes = 0
+	_, err = Route(context.Background(), cfg, large)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,60 @@
+package router
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultAllowedSchemes are the schemes launched when URLSafety.AllowedSchemes is empty.
+var DefaultAllowedSchemes = []string{"http", "https", "file", "data"}
+
+// handlerUnsafeChars are the characters refused in URLs passed to handlers: the shell
+// metacharacters that a well-formed URL has escaped (or, for $ and ', rarely uses),
+// which would let a crafted link break out of the quotes a script puts it in.
+const handlerUnsafeChars = "`$\"'\\|<>{}^"
+
+// SanitizeURL checks launchURL against cfg.URLSafety before it is opened, handler
+// being set if it is passed to a rule's handler rather than a browser. It returns the
+// URL with embedded credentials removed unless they are allowed, or a *BlockedError if
+// its scheme is not allowed or, for handlers, it contains whitespace, control
+// characters or shell metacharacters.
+func SanitizeURL(cfg *config.Config, launchURL string, handler bool) (string, error) {
+	u, err := url.Parse(launchURL)
+	if err != nil {
+		return "", &BlockedError{URL: launchURL, Reason: "cannot be parsed"}
+	}
+	allowed := cfg.URLSafety.AllowedSchemes
+	if len(allowed) == 0 {
+		allowed = DefaultAllowedSchemes
+	}
+	if !slices.ContainsFunc(allowed, func(s string) bool { return strings.EqualFold(s, u.Scheme) }) {
+		return "", &BlockedError{
+			URL:    u.Redacted(),
+			Reason: fmt.Sprintf("has scheme '%s', which is not allowed (url_safety.allowed_schemes)", u.Scheme),
+		}
+	}
+	if handler && strings.ContainsFunc(launchURL, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune(handlerUnsafeChars, r)
+	}) {
+		return "", &BlockedError{URL: u.Redacted(), Reason: "contains whitespace or shell metacharacters, which are not passed to handlers"}
+	}
+
+	if u.User != nil && !cfg.URLSafety.AllowCredentials {
+		// Cut the user info out of the URL as written rather than re-encoding it
+		prefix := len(u.Scheme) + len("://")
+		authority := launchURL[prefix:]
+		if end := strings.IndexAny(authority, "/?#"); end >= 0 {
+			authority = authority[:end]
+		}
+		at := strings.LastIndex(authority, "@")
+		log.Warn().Str("url", u.Redacted()).Msg("Removed credentials embedded in URL (url_safety.allow_credentials)")
+		launchURL = launchURL[:prefix] + launchURL[prefix+at+1:]
+	}
+	return launchURL, nil
+}
@@ -0,0 +1,337 @@
+package rules
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/launcher"
+)
+
+// Lint issue kinds.
+const (
+	LintInvalidPattern = "invalid-pattern" // The pattern does not compile
+	LintDuplicate      = "duplicate"       // Same scope and pattern as an earlier rule
+	LintShadowed       = "shadowed"        // Every URL the rule matches is taken by an earlier rule
+	LintUnknownProfile = "unknown-profile" // The rule routes to a profile or browser that does not exist
+	LintNoIncognito    = "incognito"       // Incognito is requested but the browser cannot honour it
+	LintAppIncognito   = "app-incognito"   // Incognito is ignored for installed app windows
+	LintAppUnsupported = "app-unsupported" // An installed app is requested on a non-Chromium browser
+	LintInvalidHandler = "invalid-handler" // The handler command cannot be parsed
+)
+
+// LintIssue is a problem found in the configured rules.
+type LintIssue struct {
+	Rule       config.Rule
+	Kind       string // One of the Lint* kinds
+	Message    string
+	Suggestion string // How the issue could be fixed
+}
+
+// maxFiniteMatches bounds how many strings a pattern may match for shadowing analysis.
+const maxFiniteMatches = 256
+
+// Lint checks the enabled rules for mistakes that make them behave differently than
+// intended: invalid patterns, rules that can never fire because an earlier rule (in
+// evaluation order) matches everything they match, and incognito or app options the
+// rule's browser cannot honour.
+//
+// Shadowing is only detected for patterns matching a finite set of strings (e.g.
+// `^docs\.example\.com$` or `^(?:a|b)\.example\.com$`) and for CIDR ranges; other
+// patterns are only compared for exact duplicates.
+func Lint(cfg *config.Config) []LintIssue {
+	var issues []LintIssue
+	var earlier []config.Rule // Enabled rules evaluated before the current one
+	for _, rule := range sortedRules(cfg.Rules) {
+		if !rule.IsEnabled() {
+			continue
+		}
+		// Rules are analysed with the entries of their pattern list
+		rule, err := withPatternList(cfg, rule)
+		if err != nil {
+			issues = append(issues, LintIssue{
+				Rule:       rule,
+				Kind:       LintInvalidPattern,
+				Message:    fmt.Sprintf("pattern list is unusable: %v", err),
+				Suggestion: "fix the list, or refresh downloaded lists with 'rurl config url-list refresh'",
+			})
+			continue
+		}
+		if err := ValidateRule(&rule); err != nil {
+			issues = append(issues, LintIssue{
+				Rule:       rule,
+				Kind:       LintInvalidPattern,
+				Message:    fmt.Sprintf("pattern is invalid: %v", err),
+				Suggestion: "fix the pattern with 'rurl config rule edit'",
+			})
+			continue
+		}
+		if issue, ok := lintShadowing(rule, earlier); ok {
+			issues = append(issues, issue)
+		}
+		issues = append(issues, lintOptions(cfg, rule)...)
+		earlier = append(earlier, rule)
+	}
+	return issues
+}
+
+// lintShadowing reports whether one of the earlier rules makes rule a duplicate or
+// matches every URL it could match.
+func lintShadowing(rule config.Rule, earlier []config.Rule) (LintIssue, bool) {
+	// Only the rules' own patterns are compared, so conditions widening a rule's
+	// match beyond its pattern rule out the analysis
+	if !hasOwnPattern(&rule) || (len(rule.Conditions) > 0 && rule.Match == config.MatchAny) {
+		return LintIssue{}, false
+	}
+
+	for _, prev := range earlier {
+		if !hasOwnPattern(&prev) || prev.Scope != rule.Scope || prev.Pattern != rule.Pattern {
+			continue
+		}
+		issue := LintIssue{Rule: rule, Kind: LintDuplicate}
+		switch {
+		case isConditional(&prev):
+			issue.Message = fmt.Sprintf("has the same pattern as rule '%s' and only fires when that rule's conditions fail", prev.Name)
+			issue.Suggestion = "merge the rules, or check that this is intended"
+		case prev.ProfileID == rule.ProfileID && prev.Handler == rule.Handler:
+			issue.Message = fmt.Sprintf("duplicates rule '%s' and never fires", prev.Name)
+			issue.Suggestion = fmt.Sprintf("delete it with 'rurl config rule delete %s'", rule.ID)
+		default:
+			issue.Message = fmt.Sprintf("has the same pattern as rule '%s' and never fires (URLs go to profile '%s' instead of '%s')", prev.Name, prev.ProfileID, rule.ProfileID)
+			issue.Suggestion = "delete one of the rules, or change its pattern"
+		}
+		return issue, true
+	}
+
+	for _, prev := range earlier {
+		if !hasOwnPattern(&prev) || prev.Scope != rule.Scope || isConditional(&prev) || !covers(prev, rule) {
+			continue
+		}
+		return LintIssue{
+			Rule:       rule,
+			Kind:       LintShadowed,
+			Message:    fmt.Sprintf("never fires: everything it matches is matched first by rule '%s' (%s)", prev.Name, prev.Pattern),
+			Suggestion: "delete it, or make its pattern longer than the other rule's so it is checked first",
+		}, true
+	}
+	return LintIssue{}, false
+}
+
+// hasOwnPattern reports whether rule matches with its own pattern and scope, rather
+// than only with its conditions.
+func hasOwnPattern(rule *config.Rule) bool {
+	return rule.Pattern != "" || len(rule.Conditions) == 0
+}
+
+// isConditional reports whether rule may decline a URL its pattern matches.
+func isConditional(rule *config.Rule) bool {
+	return hasContentConditions(rule) || hasNetworkConditions(rule) || rule.Plugin != "" || (len(rule.Conditions) > 0 && rule.Match != config.MatchAny)
+}
+
+// covers reports whether prev matches everything rule can match. Both rules have the
+// same scope and valid patterns.
+func covers(prev, rule config.Rule) bool {
+	if rule.Scope == config.ScopeCIDR {
+		ranges, _ := ParseCIDRList(rule.Pattern)
+		prevRanges, _ := ParseCIDRList(prev.Pattern)
+		for _, r := range ranges {
+			if !prefixCovered(r, prevRanges) {
+				return false
+			}
+		}
+		return len(ranges) > 0
+	}
+
+	matches, ok := finiteMatches(rule.Pattern)
+	if !ok {
+		return false
+	}
+	re, err := regexp.Compile(prev.Pattern)
+	if err != nil {
+		return false
+	}
+	for _, s := range matches {
+		if !re.MatchString(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// prefixCovered reports whether p lies entirely within one of ranges.
+func prefixCovered(p netip.Prefix, ranges []netip.Prefix) bool {
+	for _, r := range ranges {
+		if r.Bits() <= p.Bits() && r.Contains(p.Addr()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Markers for text anchors in expanded patterns; they cannot occur in URLs.
+const (
+	beginMarker = "\x02"
+	endMarker   = "\x03"
+)
+
+// finiteMatches returns every string pattern matches, if it only matches a small,
+// finite set of strings: it is anchored at both ends and built from literals,
+// alternations, optional parts and small character classes.
+func finiteMatches(pattern string) ([]string, bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, false
+	}
+	expanded, ok := expand(re.Simplify())
+	if !ok {
+		return nil, false
+	}
+	matches := make([]string, 0, len(expanded))
+	for _, s := range expanded {
+		inner, ok := strings.CutPrefix(s, beginMarker)
+		if !ok {
+			return nil, false
+		}
+		if inner, ok = strings.CutSuffix(inner, endMarker); !ok {
+			return nil, false
+		}
+		if strings.Contains(inner, beginMarker) || strings.Contains(inner, endMarker) {
+			return nil, false
+		}
+		matches = append(matches, inner)
+	}
+	return matches, true
+}
+
+// expand enumerates the strings re matches, with anchors kept as markers.
+func expand(re *syntax.Regexp) ([]string, bool) {
+	switch re.Op {
+	case syntax.OpEmptyMatch:
+		return []string{""}, true
+	case syntax.OpBeginText:
+		return []string{beginMarker}, true
+	case syntax.OpEndText:
+		return []string{endMarker}, true
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return nil, false
+		}
+		return []string{string(re.Rune)}, true
+	case syntax.OpCharClass:
+		var out []string
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			for r := re.Rune[i]; r <= re.Rune[i+1]; r++ {
+				if len(out) >= maxFiniteMatches {
+					return nil, false
+				}
+				out = append(out, string(r))
+			}
+		}
+		return out, true
+	case syntax.OpCapture:
+		return expand(re.Sub[0])
+	case syntax.OpQuest:
+		sub, ok := expand(re.Sub[0])
+		if !ok {
+			return nil, false
+		}
+		return append([]string{""}, sub...), true
+	case syntax.OpAlternate:
+		var out []string
+		for _, sub := range re.Sub {
+			s, ok := expand(sub)
+			if !ok || len(out)+len(s) > maxFiniteMatches {
+				return nil, false
+			}
+			out = append(out, s...)
+		}
+		return out, true
+	case syntax.OpConcat:
+		out := []string{""}
+		for _, sub := range re.Sub {
+			s, ok := expand(sub)
+			if !ok || len(out)*len(s) > maxFiniteMatches {
+				return nil, false
+			}
+			next := make([]string, 0, len(out)*len(s))
+			for _, prefix := range out {
+				for _, suffix := range s {
+					next = append(next, prefix+suffix)
+				}
+			}
+			out = next
+		}
+		return out, true
+	default:
+		return nil, false
+	}
+}
+
+// lintOptions checks the rule's handler, profile, incognito and app options.
+func lintOptions(cfg *config.Config, rule config.Rule) []LintIssue {
+	if rule.Handler != "" {
+		if _, err := launcher.HandlerCommand(rule.Handler, ""); err != nil {
+			return []LintIssue{{
+				Rule:       rule,
+				Kind:       LintInvalidHandler,
+				Message:    err.Error(),
+				Suggestion: "fix the quoting of the handler command",
+			}}
+		}
+		return nil // No browser is launched
+	}
+	profile, err := cfg.FindProfileByID(rule.ProfileID)
+	if err != nil {
+		return []LintIssue{{
+			Rule:       rule,
+			Kind:       LintUnknownProfile,
+			Message:    fmt.Sprintf("routes to profile '%s', which does not exist", rule.ProfileID),
+			Suggestion: "choose an existing profile with 'rurl config rule edit', see 'rurl config profile list'",
+		}}
+	}
+	browser, err := cfg.FindBrowserByID(profile.BrowserID)
+	if err != nil {
+		return []LintIssue{{
+			Rule:       rule,
+			Kind:       LintUnknownProfile,
+			Message:    fmt.Sprintf("routes to profile '%s', whose browser '%s' does not exist", profile.ID, profile.BrowserID),
+			Suggestion: "run 'rurl config detect-browsers --save' or fix the profile's browser",
+		}}
+	}
+
+	var issues []LintIssue
+	if rule.PWAAppID != "" && !strings.Contains(browser.ProfileArg, "--profile-directory") {
+		issues = append(issues, LintIssue{
+			Rule:       rule,
+			Kind:       LintAppUnsupported,
+			Message:    fmt.Sprintf("opens app '%s', but browser '%s' does not support app windows (Chromium-based browsers only)", rule.PWAAppID, browser.BrowserID),
+			Suggestion: "route the rule to a Chromium profile, or clear its app",
+		})
+	}
+	switch {
+	case !rule.Incognito:
+	case rule.PWAAppID != "":
+		issues = append(issues, LintIssue{
+			Rule:       rule,
+			Kind:       LintAppIncognito,
+			Message:    "requests incognito, which is ignored for app windows",
+			Suggestion: "clear the app to open a private window, or turn incognito off",
+		})
+	case !launcher.SupportsIncognito(*browser):
+		issue := LintIssue{
+			Rule:       rule,
+			Kind:       LintNoIncognito,
+			Message:    fmt.Sprintf("requests incognito, but browser '%s' cannot open private windows, so URLs open in a normal window", browser.BrowserID),
+			Suggestion: fmt.Sprintf("set an incognito argument with 'rurl config browser set-template %s --family ...', route the rule to another browser, or set behavior.incognito_fallback_profile_id", browser.BrowserID),
+		}
+		if fallback := cfg.Behavior.IncognitoFallbackProfileID; fallback != "" {
+			issue.Message = fmt.Sprintf("requests incognito, but browser '%s' cannot open private windows, so URLs open in the fallback profile '%s'", browser.BrowserID, fallback)
+			issue.Suggestion = fmt.Sprintf("route the rule to profile '%s' directly", fallback)
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
@@ -0,0 +1,119 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+)
+
+func TestLint(t *testing.T) {
+	disabled := false
+	isDownload := true
+	cfg := &config.Config{
+		Browsers: []config.Browser{
+			{BrowserID: "chrome", ProfileArg: "--profile-directory=%s", IncognitoArg: "--incognito"},
+			{BrowserID: "lynx", Terminal: true},
+			{BrowserID: "custom"},
+		},
+		Profiles: []config.Profile{
+			{ID: "work", BrowserID: "chrome"},
+			{ID: "personal", BrowserID: "chrome"},
+			{ID: "text", BrowserID: "lynx"},
+			{ID: "other", BrowserID: "custom"},
+		},
+		Rules: []config.Rule{
+			{ID: "all-example", Name: "All Example", Pattern: `^(?:.*\.)?example\.com$`, Scope: config.ScopeDomain, ProfileID: "work"},
+			{ID: "docs", Name: "Docs", Pattern: `^docs\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "personal"},
+			{ID: "mail", Name: "Mail", Pattern: `^(?:mail|calendar)\.example\.org$`, Scope: config.ScopeDomain, ProfileID: "work"},
+			{ID: "mail-again", Name: "Mail Again", Pattern: `^(?:mail|calendar)\.example\.org$`, Scope: config.ScopeDomain, ProfileID: "work"},
+			{ID: "downloads", Name: "Downloads", Pattern: `^files\.example\.net$`, Scope: config.ScopeDomain, ProfileID: "work", IsDownload: &isDownload},
+			{ID: "files", Name: "Files", Pattern: `^files\.example\.net$`, Scope: config.ScopeDomain, ProfileID: "personal"},
+			{ID: "lan", Name: "LAN", Pattern: "10.0.0.0/8", Scope: config.ScopeCIDR, ProfileID: "work"},
+			{ID: "lab", Name: "Lab", Pattern: "10.1.2.3", Scope: config.ScopeCIDR, ProfileID: "personal"},
+			{ID: "private", Name: "Private", Pattern: `^news\.`, Scope: config.ScopeDomain, ProfileID: "text", Incognito: true},
+			{ID: "custom-private", Name: "Custom Private", Pattern: `^shop\.`, Scope: config.ScopeDomain, ProfileID: "other", Incognito: true},
+			{ID: "app", Name: "App", Pattern: `^app\.example\.io$`, Scope: config.ScopeDomain, ProfileID: "work", PWAAppID: "abc", Incognito: true},
+			{ID: "missing", Name: "Missing", Pattern: `^x\.example\.io$`, Scope: config.ScopeDomain, ProfileID: "gone"},
+			{ID: "broken", Name: "Broken", Pattern: `^(unclosed`, Scope: config.ScopeURL, ProfileID: "work"},
+			{ID: "off", Name: "Off", Pattern: `^(?:mail|calendar)\.example\.org$`, Scope: config.ScopeDomain, ProfileID: "work", Enabled: &disabled},
+			{ID: "path", Name: "Path", Pattern: `^/docs`, Scope: config.ScopePath, ProfileID: "work"},
+			{ID: "path-more", Name: "Path More", Pattern: `^/docs/`, Scope: config.ScopePath, ProfileID: "personal"},
+			{ID: "blog", Name: "Blog", Pattern: `^b\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "personal",
+				Conditions: []config.Condition{{Pattern: `^/`, Scope: config.ScopePath}}, Match: config.MatchAny},
+			{ID: "conditions-only", Name: "Conditions Only", Scope: config.ScopeURL, ProfileID: "personal",
+				Conditions: []config.Condition{{Pattern: `^x`, Scope: config.ScopeDomain}}},
+			{ID: "broken-condition", Name: "Broken Condition", Pattern: `^y\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "work",
+				Conditions: []config.Condition{{Pattern: `^(unclosed`, Scope: config.ScopePath}}},
+		},
+	}
+
+	got := make(map[string]string)
+	for _, issue := range Lint(cfg) {
+		if prev, ok := got[issue.Rule.ID]; ok {
+			t.Errorf("rule %s: multiple issues (%s, %s)", issue.Rule.ID, prev, issue.Kind)
+		}
+		if issue.Message == "" || issue.Suggestion == "" {
+			t.Errorf("rule %s: issue %s has no message or suggestion", issue.Rule.ID, issue.Kind)
+		}
+		got[issue.Rule.ID] = issue.Kind
+	}
+
+	want := map[string]string{
+		"docs":           LintShadowed,
+		"mail-again":     LintDuplicate,
+		"files":          LintDuplicate,
+		"lab":            LintShadowed,
+		"private":        LintNoIncognito,
+		"custom-private": LintNoIncognito,
+		"app":            LintAppIncognito,
+		"missing":        LintUnknownProfile,
+		"broken":         LintInvalidPattern,
+		// Conditions are validated too
+		"broken-condition": LintInvalidPattern,
+	}
+	for id, kind := range want {
+		if got[id] != kind {
+			t.Errorf("rule %s: got issue %q, want %q", id, got[id], kind)
+		}
+	}
+	for id, kind := range got {
+		if _, ok := want[id]; !ok {
+			t.Errorf("rule %s: unexpected issue %q", id, kind)
+		}
+	}
+}
+
+func TestFiniteMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string // nil if the pattern is not finite
+	}{
+		{`^docs\.example\.com$`, []string{"docs.example.com"}},
+		{`^(?:a|b)\.example\.com$`, []string{"a.example.com", "b.example.com"}},
+		{`^(www\.)?example\.com$`, []string{"example.com", "www.example.com"}},
+		{`^host[12]$`, []string{"host1", "host2"}},
+		{`example\.com$`, nil},
+		{`^example\.com`, nil},
+		{`^.*\.example\.com$`, nil},
+		{`(?i)^example\.com$`, nil},
+	}
+	for _, tt := range tests {
+		got, ok := finiteMatches(tt.pattern)
+		if tt.want == nil {
+			if ok {
+				t.Errorf("finiteMatches(%q) = %v, want not finite", tt.pattern, got)
+			}
+			continue
+		}
+		if !ok || len(got) != len(tt.want) {
+			t.Errorf("finiteMatches(%q) = %v, %v, want %v", tt.pattern, got, ok, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("finiteMatches(%q) = %v, want %v", tt.pattern, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package rules
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/jmylchreest/rurl/internal/config"
+)
+
+// domainNameRegex matches a lowercase DNS name such as "mail.example.com".
+var domainNameRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+
+// ReadEntries reads a list of domains or patterns, one per line, ignoring blank lines
+// and # comments. Entries are normalized for the scope (see NormalizeEntry) and
+// duplicates are dropped. All invalid lines are reported together so the list can be
+// fixed in one pass.
+func ReadEntries(r io.Reader, scope config.RuleScope) ([]string, error) {
+	var entries, problems []string
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		entry, err := NormalizeEntry(line, scope)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", lineNum, err))
+			continue
+		}
+		if !seen[entry] {
+			seen[entry] = true
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read entries: %w", err)
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid entries:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return entries, nil
+}
+
+// stripComment removes a trailing comment from line. A '#' only starts a comment at
+// the beginning of the line or after whitespace, so URL fragments are kept.
+func stripComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// NormalizeEntry validates a single list entry for the scope and returns its canonical
+// form: a lowercase domain for the domain scope (taken from a URL if need be), CIDR
+// ranges for the cidr scope, and a regular expression otherwise.
+func NormalizeEntry(entry string, scope config.RuleScope) (string, error) {
+	switch scope {
+	case config.ScopeDomain:
+		domain := strings.ToLower(entry)
+		if strings.Contains(domain, "://") {
+			u, err := url.Parse(domain)
+			if err != nil {
+				return "", fmt.Errorf("invalid URL '%s': %w", entry, err)
+			}
+			domain = u.Hostname()
+		} else if i := strings.IndexAny(domain, "/:"); i >= 0 {
+			domain = domain[:i] // Drop any path or port
+		}
+		domain = strings.TrimSuffix(strings.TrimPrefix(domain, "*."), ".")
+		if !domainNameRegex.MatchString(domain) {
+			return "", fmt.Errorf("invalid domain '%s'", entry)
+		}
+		return domain, nil
+	case config.ScopeCIDR:
+		if _, err := ParseCIDRList(entry); err != nil {
+			return "", err
+		}
+		return entry, nil
+	default:
+		if _, err := regexp.Compile(entry); err != nil {
+			return "", fmt.Errorf("invalid pattern '%s': %w", entry, err)
+		}
+		return entry, nil
+	}
+}
+
+// EntriesPattern builds the pattern matching any of entries, as read by ReadEntries:
+// the domains (and, with includeSubdomains, their subdomains) for the domain scope,
+// the ranges for the cidr scope, or any of the regular expressions.
+func EntriesPattern(entries []string, scope config.RuleScope, includeSubdomains bool) string {
+	switch scope {
+	case config.ScopeDomain:
+		quoted := make([]string, len(entries))
+		for i, e := range entries {
+			quoted[i] = regexp.QuoteMeta(e)
+		}
+		alt := strings.Join(quoted, "|")
+		if len(entries) > 1 {
+			alt = "(?:" + alt + ")"
+		}
+		if includeSubdomains {
+			return `^(?:.*\.)?` + alt + "$"
+		}
+		return "^" + alt + "$"
+	case config.ScopeCIDR:
+		return strings.Join(entries, ",")
+	default:
+		if len(entries) == 1 {
+			return entries[0]
+		}
+		return "(?:" + strings.Join(entries, ")|(?:") + ")"
+	}
+}
+
+// LoadPatternList returns the entries of the pattern list ref for scope: those of the
+// configured URL list named ref, or of the file ref when no list has that name.
+func LoadPatternList(cfg *config.Config, ref string, scope config.RuleScope) ([]string, error) {
+	var lines []string
+	path := cfg.ResolveConfigPath(ref)
+	if list := cfg.FindURLList(ref); list != nil {
+		lines = list.Entries
+		var err error
+		if path, err = cfg.URLListPath(*list); err != nil {
+			return nil, err
+		}
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pattern list '%s': %w", ref, err)
+		}
+		lines = append(lines, strings.Split(string(data), "\n")...)
+	}
+
+	entries, err := ReadEntries(strings.NewReader(strings.Join(lines, "\n")), scope)
+	if err != nil {
+		return nil, fmt.Errorf("pattern list '%s': %w", ref, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("pattern list '%s' has no entries", ref)
+	}
+	return entries, nil
+}
+
+// withPatternList returns rule with the entries of its pattern list (if any) added to
+// its pattern as alternatives, for matching. Domain entries also match subdomains.
+func withPatternList(cfg *config.Config, rule config.Rule) (config.Rule, error) {
+	if rule.PatternListRef == "" {
+		return rule, nil
+	}
+	entries, err := LoadPatternList(cfg, rule.PatternListRef, rule.Scope)
+	if err != nil {
+		return rule, err
+	}
+	listPattern := EntriesPattern(entries, rule.Scope, true)
+	switch {
+	case rule.Pattern == "":
+		rule.Pattern = listPattern
+	case rule.Scope == config.ScopeCIDR:
+		rule.Pattern += "," + listPattern
+	default:
+		rule.Pattern = "(?:" + rule.Pattern + ")|(?:" + listPattern + ")"
+	}
+	return rule, nil
+}
@@ -0,0 +1,113 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+)
+
+func TestReadEntries(t *testing.T) {
+	input := `
+# Corporate allow-list
+example.com
+https://Intranet.Example.com/login   # pasted URL
+*.corp.example.net.
+example.com
+`
+	entries, err := ReadEntries(strings.NewReader(input), config.ScopeDomain)
+	if err != nil {
+		t.Fatalf("ReadEntries() error = %v", err)
+	}
+	if want := []string{"example.com", "intranet.example.com", "corp.example.net"}; !slices.Equal(entries, want) {
+		t.Errorf("ReadEntries() = %q, want %q", entries, want)
+	}
+
+	// '#' only starts a comment at the line start or after whitespace
+	entries, err = ReadEntries(strings.NewReader("https://docs.example.org/page#section\t# docs\n"), config.ScopeDomain)
+	if err != nil || !slices.Equal(entries, []string{"docs.example.org"}) {
+		t.Errorf("ReadEntries() = %q, %v, want [docs.example.org]", entries, err)
+	}
+
+	_, err = ReadEntries(strings.NewReader("good.com\nbad domain\nalso_bad\n"), config.ScopeDomain)
+	if err == nil || !strings.Contains(err.Error(), "line 2") || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("ReadEntries() error = %v, want lines 2 and 3 reported", err)
+	}
+
+	_, err = ReadEntries(strings.NewReader("10.0.0.0/8\n300.1.1.1\n"), config.ScopeCIDR)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("ReadEntries() error = %v, want line 2 reported", err)
+	}
+}
+
+func TestApplyRulesWithPatternList(t *testing.T) {
+	dir := t.TempDir()
+	domains := filepath.Join(dir, "company-domains.txt")
+	if err := os.WriteFile(domains, []byte("# Company domains\nexample.com\nexample-corp.net\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		DefaultProfileID: "default-profile",
+		Profiles: []config.Profile{
+			{ID: "default-profile", Name: "Default"},
+			{ID: "work", Name: "Work"},
+			{ID: "lab", Name: "Lab"},
+			{ID: "docs", Name: "Docs"},
+		},
+		URLLists: []config.URLList{
+			{Name: "company", File: domains},
+			{Name: "lab-networks", Entries: []string{"10.1.0.0/16", "192.168.50.7"}},
+			{Name: "missing", File: filepath.Join(dir, "missing.txt")},
+		},
+		Rules: []config.Rule{
+			{Name: "Company", Scope: config.ScopeDomain, ProfileID: "work", PatternListRef: "company"},
+			{Name: "Lab", Scope: config.ScopeCIDR, ProfileID: "lab", PatternListRef: "lab-networks"},
+			{Name: "Docs", Pattern: `^/docs/`, Scope: config.ScopePath, ProfileID: "docs", PatternListRef: domains + ".paths"},
+			{Name: "Broken", Scope: config.ScopeDomain, ProfileID: "docs", PatternListRef: "missing"},
+		},
+	}
+	if err := os.WriteFile(domains+".paths", []byte("^/wiki/\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.example.com/", "work"},
+		{"https://example-corp.net/", "work"},
+		{"https://example.org/", "default-profile"},
+		{"http://10.1.2.3/", "lab"},
+		{"http://192.168.50.7/", "lab"},
+		{"https://other.org/docs/a", "docs"}, // The rule's own pattern
+		{"https://other.org/wiki/a", "docs"}, // A list file given by path
+	}
+	for _, tt := range tests {
+		got, err := ApplyRules(cfg, tt.url)
+		if err != nil {
+			t.Fatalf("ApplyRules(%s) error = %v", tt.url, err)
+		}
+		if got.ProfileID != tt.want {
+			t.Errorf("ApplyRules(%s) ProfileID = %v, want %v", tt.url, got.ProfileID, tt.want)
+		}
+	}
+
+	// Unreadable lists are reported rather than matching everything
+	evals, err := EvaluateRules(cfg, "https://example.org/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range evals {
+		if (e.Rule.Name == "Broken") != (e.Err != nil) {
+			t.Errorf("rule %s: Err = %v", e.Rule.Name, e.Err)
+		}
+	}
+	for _, issue := range Lint(cfg) {
+		if (issue.Kind == LintInvalidPattern) != (issue.Rule.Name == "Broken") {
+			t.Errorf("Lint() issue for %s: %s %s", issue.Rule.Name, issue.Kind, issue.Message)
+		}
+	}
+}
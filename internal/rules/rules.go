@@ -2,11 +2,17 @@ package rules
 
 import (
 	"fmt"
+	"iter"
+	"net/netip"
 	"net/url"
+	"path"
 	"regexp"
 	"sort"
+	"strings"
 
 	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/network"
+	"github.com/jmylchreest/rurl/internal/urlhandler"
 	"github.com/rs/zerolog/log"
 )
 
@@ -14,9 +20,154 @@ import (
 // If a rule matched, Rule will be non-nil.
 // If no rule matched, ProfileID will be the DefaultProfileID.
 type MatchResult struct {
-	Rule      *config.Rule // Pointer to the matched rule (nil if no match)
-	ProfileID string       // The ID of the profile to use
-	Incognito bool         // Whether to launch in incognito mode
+	Rule           *config.Rule // Pointer to the matched rule (nil if no match)
+	ProfileID      string       // The ID of the profile to use
+	Incognito      bool         // Whether to launch in incognito mode
+	PWAAppID       string       // Installed PWA/Chrome app to open the URL in (empty for a normal tab)
+	LaunchOriginal *bool        // Overrides the shortener's safelink setting (nil if not set by the rule)
+	WindowMode     string       // Overrides the browser's window mode (empty if not set by the rule)
+	DeepLinkURL    string       // Native app link to open instead (meeting links of DeepLink rules only)
+	Handler        string       // Command template run with the URL instead of a browser (empty if not set by the rule)
+	RewriteURL     string       // URL to launch instead, capture groups expanded (empty if not set by the rule)
+	ExtraArgs      []string     // Extra browser arguments, capture groups expanded (nil if not set by the rule)
+	Override       bool         // ProfileID was given by the overrides for the exact URL (Rule is nil)
+}
+
+// NoProfileError is returned when there is no profile to open a URL in: no rule matched
+// and there is no default profile, or the profile to use does not exist.
+type NoProfileError struct {
+	ProfileID string // Missing profile, empty if no rule matched and there is no default profile
+	RuleName  string // Matched rule routing to ProfileID, empty for the default profile
+	URL       string // Overridden URL routing to ProfileID, if any
+}
+
+func (e *NoProfileError) Error() string {
+	switch {
+	case e.ProfileID == "":
+		return "no matching rule found and no default profile is configured"
+	case e.URL != "":
+		return fmt.Sprintf("profile '%s' specified in the override for '%s' not found", e.ProfileID, e.URL)
+	case e.RuleName == "":
+		return fmt.Sprintf("default profile '%s' not found", e.ProfileID)
+	default:
+		return fmt.Sprintf("profile '%s' specified in rule '%s' not found", e.ProfileID, e.RuleName)
+	}
+}
+
+// MatchContext carries optional information about the target URL gathered
+// before rule matching (e.g. by content inspection).
+type MatchContext struct {
+	Content *urlhandler.ContentInfo // Result of content inspection (nil if not performed)
+	Network *network.Info           // Network context (nil if not detected)
+	// PluginMatch runs the matcher plugin of rules with a Plugin set. Such rules never
+	// match when it is nil.
+	PluginMatch func(rule *config.Rule, url string) (bool, error)
+}
+
+// NeedsContentInspection reports whether any rule has content conditions,
+// i.e. whether a content inspection request would affect rule matching.
+func NeedsContentInspection(cfg *config.Config) bool {
+	if cfg == nil {
+		return false
+	}
+	for _, r := range cfg.Rules {
+		if r.IsEnabled() && hasContentConditions(&r) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasContentConditions reports whether a rule is conditioned on the target's content.
+func hasContentConditions(rule *config.Rule) bool {
+	return rule.IsDownload != nil || rule.ContentType != ""
+}
+
+// matchContentConditions checks a rule's content conditions against the inspected content.
+// Rules with content conditions never match when no inspection was performed.
+func matchContentConditions(rule *config.Rule, content *urlhandler.ContentInfo) (bool, error) {
+	if !hasContentConditions(rule) {
+		return true, nil
+	}
+	if content == nil {
+		log.Debug().Str("rule_name", rule.Name).Msg("Rule has content conditions but content was not inspected")
+		return false, nil
+	}
+	if rule.IsDownload != nil && *rule.IsDownload != content.IsDownload {
+		return false, nil
+	}
+	if rule.ContentType != "" {
+		re, err := regexp.Compile(rule.ContentType)
+		if err != nil {
+			return false, fmt.Errorf("invalid content type pattern: %w", err)
+		}
+		if !re.MatchString(content.ContentType) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// NeedsNetworkInfo reports whether any rule has network conditions, i.e. whether the
+// network context needs to be detected for rule matching.
+func NeedsNetworkInfo(cfg *config.Config) bool {
+	if cfg == nil {
+		return false
+	}
+	for _, r := range cfg.Rules {
+		if r.IsEnabled() && hasNetworkConditions(&r) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasNetworkConditions reports whether a rule is conditioned on the network context.
+func hasNetworkConditions(rule *config.Rule) bool {
+	return rule.WiFiSSID != "" || rule.VPN != nil
+}
+
+// matchNetworkConditions checks a rule's network conditions against the network
+// context. Rules with network conditions never match when it was not detected.
+func matchNetworkConditions(rule *config.Rule, info *network.Info) (bool, error) {
+	if !hasNetworkConditions(rule) {
+		return true, nil
+	}
+	if info == nil {
+		log.Debug().Str("rule_name", rule.Name).Msg("Rule has network conditions but the network was not detected")
+		return false, nil
+	}
+	if rule.WiFiSSID != "" {
+		re, err := regexp.Compile(rule.WiFiSSID)
+		if err != nil {
+			return false, fmt.Errorf("invalid Wi-Fi SSID pattern: %w", err)
+		}
+		if info.SSID == "" || !re.MatchString(info.SSID) {
+			return false, nil
+		}
+	}
+	if rule.VPN != nil {
+		up, err := info.VPNUp(rule.VPNInterface)
+		if err != nil {
+			return false, fmt.Errorf("invalid VPN interface pattern: %w", err)
+		}
+		if up != *rule.VPN {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matchPluginCondition asks the rule's matcher plugin (if any) whether inputURL matches.
+func matchPluginCondition(rule *config.Rule, inputURL string, mctx MatchContext) (bool, error) {
+	if rule.Plugin == "" {
+		return true, nil
+	}
+	if mctx.PluginMatch == nil {
+		log.Debug().Str("rule_name", rule.Name).Msg("Rule has a matcher plugin but plugins are not run")
+		return false, nil
+	}
+	return mctx.PluginMatch(rule, inputURL)
 }
 
 // getMatchString returns the appropriate part of the URL to match against based on the rule's scope
@@ -27,6 +178,11 @@ func getMatchString(parsedURL *url.URL, scope config.RuleScope) string {
 		matchStr = parsedURL.Hostname() // Just the hostname part (e.g., "images.google.com")
 	case config.ScopePath:
 		matchStr = parsedURL.Path // Just the path part (e.g., "/search/images")
+	case config.ScopeScheme:
+		matchStr = parsedURL.Scheme // Just the scheme (e.g., "https"), empty for scheme-less input
+	case config.ScopeExtension:
+		// The file extension of the path, without the dot (e.g., "pdf"), empty if none
+		matchStr = strings.ToLower(strings.TrimPrefix(path.Ext(parsedURL.Path), "."))
 	default: // config.ScopeURL
 		// For URL scope, include host, path, and query, but only include scheme if it exists
 		if parsedURL.Scheme != "" {
@@ -49,18 +205,124 @@ func getMatchString(parsedURL *url.URL, scope config.RuleScope) string {
 	return matchStr
 }
 
-// ApplyRules iterates through the configured rules and returns the first match.
-// Rules are checked in order of pattern length (descending) to prioritize specificity.
-// If no rules match, it returns the default profile.
-func ApplyRules(cfg *config.Config, inputURL string) (MatchResult, error) {
-	if cfg == nil {
-		return MatchResult{}, fmt.Errorf("configuration is nil")
+// matchRule checks a single rule against the parsed URL. It returns whether the
+// rule matched, the parts of the URL that were evaluated, and an error if one of the
+// rule's patterns is invalid.
+func matchRule(rule *config.Rule, parsedURL *url.URL) (bool, string, error) {
+	if len(rule.Conditions) == 0 {
+		return matchPattern(rule.Scope, rule.Pattern, parsedURL)
 	}
 
-	// Parse the URL once for all rules
+	// Stop at the first condition deciding the outcome: a match for "any", a miss for "all"
+	matchAny := rule.Match == config.MatchAny
+	var matchStrings []string
+	for _, c := range ruleConditions(rule) {
+		matches, matchString, err := matchPattern(c.Scope, c.Pattern, parsedURL)
+		if err != nil {
+			return false, "", err
+		}
+		matchStrings = append(matchStrings, matchString)
+		if (matches != c.Negate) == matchAny {
+			return matchAny, strings.Join(matchStrings, ", "), nil
+		}
+	}
+	return !matchAny, strings.Join(matchStrings, ", "), nil
+}
+
+// ruleConditions returns the URL conditions of rule: its own pattern and scope (unless
+// the pattern is left empty next to other conditions) followed by its Conditions.
+func ruleConditions(rule *config.Rule) []config.Condition {
+	if rule.Pattern == "" && len(rule.Conditions) > 0 {
+		return rule.Conditions
+	}
+	return append([]config.Condition{{Pattern: rule.Pattern, Scope: rule.Scope}}, rule.Conditions...)
+}
+
+// matchPattern reports whether pattern matches the part of the URL selected by scope,
+// along with that part.
+func matchPattern(scope config.RuleScope, pattern string, parsedURL *url.URL) (bool, string, error) {
+	if scope == config.ScopeCIDR {
+		host := parsedURL.Hostname() // Strips brackets from IPv6 literals
+		matches, err := matchCIDR(pattern, host)
+		return matches, host, err
+	}
+
+	// Compile the regex pattern for the rule
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, "", err
+	}
+
+	// Get the appropriate part of the URL to match against based on the rule's scope
+	matchString := getMatchString(parsedURL, scope)
+	return re.MatchString(matchString), matchString, nil
+}
+
+// matchCIDR reports whether host is an IP literal contained in any of the
+// comma-separated CIDR ranges or IP addresses in pattern.
+func matchCIDR(pattern string, host string) (bool, error) {
+	prefixes, err := ParseCIDRList(pattern)
+	if err != nil {
+		return false, err
+	}
+
+	addr, err := netip.ParseAddr(strings.Trim(host, "[]"))
+	if err != nil {
+		return false, nil // Not an IP literal, so it cannot match
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ParseCIDRList parses a comma-separated list of CIDR ranges or single IP
+// addresses (treated as /32 or /128) as used by cidr-scoped rules.
+func ParseCIDRList(pattern string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, part := range strings.Split(pattern, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if strings.Contains(part, "/") {
+			prefix, err := netip.ParsePrefix(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR '%s': %w", part, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(strings.Trim(part, "[]"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address '%s': %w", part, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	if len(prefixes) == 0 {
+		return nil, fmt.Errorf("no CIDR ranges specified")
+	}
+	return prefixes, nil
+}
+
+// parseInputURL parses a URL to match rules against, accepting scheme-less
+// input such as "example.com/path" or "[::1]:8080/path".
+func parseInputURL(inputURL string) (*url.URL, error) {
 	parsedURL, err := url.Parse(inputURL)
+	if err != nil && strings.HasPrefix(inputURL, "[") {
+		// Scheme-less bracketed IPv6 host (e.g. "[::1]:8080/path"); parse with a dummy scheme
+		if tmpURL, tmpErr := url.Parse("http://" + inputURL); tmpErr == nil {
+			tmpURL.Scheme = ""
+			parsedURL, err = tmpURL, nil
+		}
+	}
 	if err != nil {
-		return MatchResult{}, fmt.Errorf("failed to parse URL '%s': %w", inputURL, err)
+		return nil, fmt.Errorf("failed to parse URL '%s': %w", inputURL, err)
 	}
 
 	// If there's no scheme and the path contains a domain-like string, treat it as the host
@@ -71,6 +333,145 @@ func ApplyRules(cfg *config.Config, inputURL string) (MatchResult, error) {
 			parsedURL.Path = tmpURL.Path
 		}
 	}
+	return parsedURL, nil
+}
+
+// ValidatePattern checks that pattern is valid for scope: a list of CIDR ranges for
+// the cidr scope, otherwise a regular expression.
+func ValidatePattern(scope config.RuleScope, pattern string) error {
+	switch scope {
+	case config.ScopeURL, config.ScopeDomain, config.ScopePath, config.ScopeScheme, config.ScopeExtension:
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	case config.ScopeCIDR:
+		if _, err := ParseCIDRList(pattern); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("scope must be one of url, domain, path, cidr, scheme, extension")
+	}
+	return nil
+}
+
+// ValidateRule checks the patterns of rule and of its conditions, and how they combine.
+func ValidateRule(rule *config.Rule) error {
+	if _, err := regexp.Compile(rule.WiFiSSID); err != nil {
+		return fmt.Errorf("invalid Wi-Fi SSID pattern: %w", err)
+	}
+	if _, err := regexp.Compile(rule.VPNInterface); err != nil {
+		return fmt.Errorf("invalid VPN interface pattern: %w", err)
+	}
+	if len(rule.Conditions) == 0 {
+		return ValidatePattern(rule.Scope, rule.Pattern)
+	}
+	if rule.Match != "" && rule.Match != config.MatchAll && rule.Match != config.MatchAny {
+		return fmt.Errorf("match must be %s or %s", config.MatchAll, config.MatchAny)
+	}
+	for i, c := range ruleConditions(rule) {
+		if err := ValidatePattern(c.Scope, c.Pattern); err != nil {
+			if i == 0 && rule.Pattern != "" {
+				return err
+			}
+			return fmt.Errorf("condition '%s': %w", c.Pattern, err)
+		}
+	}
+	return nil
+}
+
+// TestPattern reports whether pattern matches inputURL under scope, along with the
+// part of the URL the pattern was evaluated against. Content conditions are ignored.
+func TestPattern(scope config.RuleScope, pattern, inputURL string) (bool, string, error) {
+	if err := ValidatePattern(scope, pattern); err != nil {
+		return false, "", err
+	}
+	parsedURL, err := parseInputURL(inputURL)
+	if err != nil {
+		return false, "", err
+	}
+	return matchPattern(scope, pattern, parsedURL)
+}
+
+// sortedRules returns a copy of rules in evaluation order: by pattern length
+// descending (longer patterns first, counting the patterns of conditions), keeping
+// the configured order for ties.
+func sortedRules(rules []config.Rule) []config.Rule {
+	// Copy the rules to avoid modifying the original config order
+	sorted := make([]config.Rule, len(rules))
+	copy(sorted, rules)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return patternLength(&sorted[i]) > patternLength(&sorted[j])
+	})
+	return sorted
+}
+
+// patternLength returns the length of rule's pattern and the patterns of its
+// conditions, which orders rule evaluation.
+func patternLength(rule *config.Rule) int {
+	n := len(rule.Pattern)
+	for _, c := range rule.Conditions {
+		n += len(c.Pattern)
+	}
+	return n
+}
+
+// RuleEvaluation describes how a single rule fared against a URL.
+type RuleEvaluation struct {
+	Rule        config.Rule
+	Matched     bool   // The pattern matched (content conditions are not evaluated)
+	MatchString string // Part of the URL the pattern was evaluated against
+	Skipped     bool   // The rule is disabled and was not evaluated
+	Err         error  // The rule's pattern is invalid
+
+	ContentConditions bool // The rule also has content conditions, which were not evaluated
+	NetworkConditions bool // The rule also has network conditions, which were not evaluated
+	PluginCondition   bool // The rule also has a matcher plugin, which was not run
+}
+
+// EvaluateRules checks every rule against inputURL in the order ApplyRules uses
+// and reports the outcome of each, without stopping at the first match. Content
+// conditions and matcher plugins are not evaluated, so no network requests are made.
+func EvaluateRules(cfg *config.Config, inputURL string) ([]RuleEvaluation, error) {
+	parsedURL, err := parseInputURL(inputURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var evals []RuleEvaluation
+	for _, rule := range sortedRules(cfg.Rules) {
+		eval := RuleEvaluation{Rule: rule, ContentConditions: hasContentConditions(&rule), NetworkConditions: hasNetworkConditions(&rule), PluginCondition: rule.Plugin != ""}
+		if !rule.IsEnabled() {
+			eval.Skipped = true
+		} else if matchable, err := withPatternList(cfg, rule); err != nil {
+			eval.Err = err
+		} else {
+			eval.Matched, eval.MatchString, eval.Err = matchRule(&matchable, parsedURL)
+		}
+		evals = append(evals, eval)
+	}
+	return evals, nil
+}
+
+// ApplyRules iterates through the configured rules and returns the first match.
+// Rules are checked in order of pattern length (descending) to prioritize specificity,
+// after the overrides for the exact URL. If no rules match, it returns the default
+// profile.
+func ApplyRules(cfg *config.Config, inputURL string) (MatchResult, error) {
+	return ApplyRulesWithContext(cfg, inputURL, MatchContext{})
+}
+
+// ApplyRulesWithContext behaves like ApplyRules, additionally evaluating rule
+// conditions that depend on the supplied MatchContext.
+func ApplyRulesWithContext(cfg *config.Config, inputURL string, mctx MatchContext) (MatchResult, error) {
+	if cfg == nil {
+		return MatchResult{}, fmt.Errorf("configuration is nil")
+	}
+
+	// Parse the URL once for all rules
+	parsedURL, err := parseInputURL(inputURL)
+	if err != nil {
+		return MatchResult{}, err
+	}
 
 	log.Debug().
 		Str("input_url", inputURL).
@@ -80,47 +481,145 @@ func ApplyRules(cfg *config.Config, inputURL string) (MatchResult, error) {
 		Str("parsed_path", parsedURL.Path).
 		Msg("URL parsing results")
 
-	// Create a copy of the rules to avoid modifying the original config order
-	rulesToSort := make([]config.Rule, len(cfg.Rules))
-	copy(rulesToSort, cfg.Rules)
+	// Exact URL overrides take precedence over the rules
+	if profileID, ok := cfg.Overrides[inputURL]; ok {
+		profile, err := cfg.FindProfileByID(profileID)
+		if err != nil {
+			log.Error().Err(err).Str("url", inputURL).Str("profile_id", profileID).Msg("Profile specified in override not found")
+			return MatchResult{}, &NoProfileError{ProfileID: profileID, URL: inputURL}
+		}
+		log.Info().Str("url", inputURL).Str("profile_id", profile.ID).Msg("URL matched an override")
+		return MatchResult{ProfileID: profile.ID, Override: true}, nil
+	}
 
-	// Sort rules by pattern length descending (longer patterns first)
-	sort.Slice(rulesToSort, func(i, j int) bool {
-		return len(rulesToSort[i].Pattern) > len(rulesToSort[j].Pattern)
-	})
+	for rule := range matchingRules(cfg, parsedURL, inputURL, mctx) {
+		// Handlers run a command instead of a browser
+		if rule.Handler != "" {
+			return ruleMatchResult(rule, rule.ProfileID, inputURL, parsedURL), nil
+		}
 
-	log.Debug().Str("url", inputURL).Int("rule_count", len(rulesToSort)).Msg("Applying rules (sorted by pattern length desc)")
+		// Ensure the profile specified by the rule exists (it may be given by email)
+		profile, profileErr := cfg.FindProfileByID(rule.ProfileID)
+		if profileErr != nil {
+			log.Error().Err(profileErr).Str("rule_name", rule.Name).Str("profile_id", rule.ProfileID).Msg("Profile specified in matched rule not found")
+			// Fallback to default? Or return error? Returning error seems safer.
+			return MatchResult{}, &NoProfileError{ProfileID: rule.ProfileID, RuleName: rule.Name}
+		}
+		return ruleMatchResult(rule, profile.ID, inputURL, parsedURL), nil
+	}
 
-	for i := range rulesToSort {
-		rule := &rulesToSort[i] // Use pointer to the rule in the sorted slice
-		log.Debug().
-			Str("rule_name", rule.Name).
-			Str("pattern", rule.Pattern).
-			Int("pattern_len", len(rule.Pattern)).
-			Str("scope", string(rule.Scope)).
-			Msg("Checking rule")
+	// No rules matched, use the default profile (or the file viewer profile for files)
+	log.Debug().Str("url", inputURL).Msg("No rules matched")
+	defaultProfileID := cfg.DefaultProfileID
+	if parsedURL.Scheme == "file" && cfg.Behavior.FileProfileID != "" {
+		defaultProfileID = cfg.Behavior.FileProfileID
+	}
+	if defaultProfileID == "" {
+		log.Error().Msg("No rules matched and no default profile set.")
+		return MatchResult{}, &NoProfileError{}
+	}
 
-		// Compile the regex pattern for the rule
-		re, err := regexp.Compile(rule.Pattern)
-		if err != nil {
-			log.Error().Err(err).Str("rule_name", rule.Name).Str("pattern", rule.Pattern).Msg("Invalid regex pattern in rule")
-			// Skip this rule, but don't stop processing others
-			continue
+	// Ensure the default profile ID actually exists
+	profile, err := cfg.FindProfileByID(defaultProfileID)
+	if err != nil {
+		log.Error().Err(err).Str("default_profile_id", defaultProfileID).Msg("Default profile specified in config not found")
+		return MatchResult{}, &NoProfileError{ProfileID: defaultProfileID}
+	}
+
+	log.Info().Str("url", inputURL).Str("profile_id", profile.ID).Msg("Using default profile")
+	return MatchResult{
+		Rule:      nil, // No specific rule matched
+		ProfileID: profile.ID,
+		Incognito: cfg.Behavior.DefaultIncognito,
+	}, nil
+}
+
+// MatchAll returns a result for every rule matching inputURL, in the order ApplyRules
+// tries them, so the first is the rule ApplyRules picks and the others are shadowed by
+// it. Overrides and the default profile are not included, and rules with content or
+// network conditions or a matcher plugin only match with MatchAllWithContext.
+func MatchAll(cfg *config.Config, inputURL string) ([]MatchResult, error) {
+	return MatchAllWithContext(cfg, inputURL, MatchContext{})
+}
+
+// MatchAllWithContext behaves like MatchAll, additionally evaluating rule conditions
+// that depend on the supplied MatchContext.
+func MatchAllWithContext(cfg *config.Config, inputURL string, mctx MatchContext) ([]MatchResult, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("configuration is nil")
+	}
+	parsedURL, err := parseInputURL(inputURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []MatchResult
+	for rule := range matchingRules(cfg, parsedURL, inputURL, mctx) {
+		profileID := rule.ProfileID
+		if rule.Handler == "" {
+			if profile, err := cfg.FindProfileByID(rule.ProfileID); err == nil {
+				profileID = profile.ID
+			}
 		}
+		results = append(results, ruleMatchResult(rule, profileID, inputURL, parsedURL))
+	}
+	return results, nil
+}
+
+// matchingRules yields the enabled rules matching parsedURL in evaluation order,
+// skipping (and logging) rules whose patterns or plugins fail.
+func matchingRules(cfg *config.Config, parsedURL *url.URL, inputURL string, mctx MatchContext) iter.Seq[*config.Rule] {
+	return func(yield func(*config.Rule) bool) {
+		rulesToSort := sortedRules(cfg.Rules)
 
-		// Get the appropriate part of the URL to match against based on the rule's scope
-		matchString := getMatchString(parsedURL, rule.Scope)
+		log.Debug().Str("url", inputURL).Int("rule_count", len(rulesToSort)).Msg("Applying rules (sorted by pattern length desc)")
 
-		// Check if the URL matches the pattern
-		matches := re.MatchString(matchString)
-		log.Debug().
-			Str("rule_name", rule.Name).
-			Str("pattern", rule.Pattern).
-			Str("match_string", matchString).
-			Bool("matches", matches).
-			Msg("Rule match attempt")
+		for i := range rulesToSort {
+			rule := &rulesToSort[i] // Use pointer to the rule in the sorted slice
+			if !rule.IsEnabled() {
+				log.Debug().Str("rule_name", rule.Name).Msg("Skipping disabled rule")
+				continue
+			}
+			log.Debug().
+				Str("rule_name", rule.Name).
+				Str("pattern", rule.Pattern).
+				Int("pattern_len", patternLength(rule)).
+				Str("scope", string(rule.Scope)).
+				Msg("Checking rule")
+
+			matchable, err := withPatternList(cfg, *rule)
+			var matches bool
+			var matchString string
+			if err == nil {
+				matches, matchString, err = matchRule(&matchable, parsedURL)
+			}
+			if err == nil && matches {
+				matches, err = matchContentConditions(rule, mctx.Content)
+			}
+			if err == nil && matches {
+				matches, err = matchNetworkConditions(rule, mctx.Network)
+			}
+			if err == nil && matches {
+				if matches, err = matchPluginCondition(rule, inputURL, mctx); err != nil {
+					log.Warn().Err(err).Str("rule_name", rule.Name).Str("plugin", rule.Plugin).Msg("Matcher plugin failed, skipping rule")
+					continue
+				}
+			}
+			if err != nil {
+				log.Error().Err(err).Str("rule_name", rule.Name).Str("pattern", rule.Pattern).Msg("Invalid pattern in rule")
+				// Skip this rule, but don't stop processing others
+				continue
+			}
+			log.Debug().
+				Str("rule_name", rule.Name).
+				Str("pattern", rule.Pattern).
+				Str("match_string", matchString).
+				Bool("matches", matches).
+				Msg("Rule match attempt")
 
-		if matches {
+			if !matches {
+				continue
+			}
 			log.Info().
 				Str("url", inputURL).
 				Str("rule_name", rule.Name).
@@ -129,42 +628,84 @@ func ApplyRules(cfg *config.Config, inputURL string) (MatchResult, error) {
 				Str("scope", string(rule.Scope)).
 				Str("matched_part", matchString).
 				Msg("Rule matched")
-
-			// Ensure the profile ID specified by the rule exists
-			_, profileErr := cfg.FindProfileByID(rule.ProfileID)
-			if profileErr != nil {
-				log.Error().Err(profileErr).Str("rule_name", rule.Name).Str("profile_id", rule.ProfileID).Msg("Profile specified in matched rule not found")
-				// Fallback to default? Or return error? Returning error seems safer.
-				return MatchResult{}, fmt.Errorf("profile '%s' specified in rule '%s' not found", rule.ProfileID, rule.Name)
+			if !yield(rule) {
+				return
 			}
+		}
+	}
+}
 
-			// Return the match result
-			return MatchResult{
-				Rule:      rule,
-				ProfileID: rule.ProfileID,
-				Incognito: rule.Incognito,
-			}, nil
+// ruleMatchResult returns the result of rule matching inputURL, opening it in the
+// profile profileID.
+func ruleMatchResult(rule *config.Rule, profileID, inputURL string, parsedURL *url.URL) MatchResult {
+	// Handlers run a command instead of a browser
+	if rule.Handler != "" {
+		return MatchResult{
+			Rule:      rule,
+			ProfileID: profileID,
+			Handler:   rule.Handler,
 		}
 	}
+	return MatchResult{
+		Rule:           rule,
+		ProfileID:      profileID,
+		Incognito:      rule.Incognito,
+		PWAAppID:       rule.PWAAppID,
+		LaunchOriginal: rule.LaunchOriginal,
+		WindowMode:     rule.EffectiveWindowMode(),
+		DeepLinkURL:    deepLinkURL(rule, inputURL),
+		RewriteURL:     expandCaptures(rule, parsedURL, rule.RewriteURL),
+		ExtraArgs:      expandArgs(rule, parsedURL),
+	}
+}
 
-	// No rules matched, use the default profile
-	log.Debug().Str("url", inputURL).Msg("No rules matched")
-	if cfg.DefaultProfileID == "" {
-		log.Error().Msg("No rules matched and no default profile set.")
-		return MatchResult{}, fmt.Errorf("no matching rule found and no default profile is configured")
+// deepLinkURL returns the native app link for inputURL if rule opts in to deep links
+// and inputURL is a known meeting link.
+func deepLinkURL(rule *config.Rule, inputURL string) string {
+	if !rule.DeepLink {
+		return ""
 	}
+	link := urlhandler.DeepLinkURL(inputURL)
+	if link == "" {
+		log.Debug().Str("url", inputURL).Str("rule_name", rule.Name).Msg("Not a known meeting link, opening in the rule's profile")
+	}
+	return link
+}
 
-	// Ensure the default profile ID actually exists
-	_, err = cfg.FindProfileByID(cfg.DefaultProfileID)
+// ExpandCaptures replaces the references to capture groups in template ($1, ${name},
+// $$ for a literal $) with the groups of rule's pattern matched against inputURL, as
+// for the rule's RewriteURL and ExtraArgs.
+func ExpandCaptures(rule *config.Rule, inputURL, template string) (string, error) {
+	parsedURL, err := parseInputURL(inputURL)
 	if err != nil {
-		log.Error().Err(err).Str("default_profile_id", cfg.DefaultProfileID).Msg("Default profile specified in config not found")
-		return MatchResult{}, fmt.Errorf("default profile '%s' not found", cfg.DefaultProfileID)
+		return "", err
 	}
+	return expandCaptures(rule, parsedURL, template), nil
+}
 
-	log.Info().Str("url", inputURL).Str("profile_id", cfg.DefaultProfileID).Msg("Using default profile")
-	return MatchResult{
-		Rule:      nil, // No specific rule matched
-		ProfileID: cfg.DefaultProfileID,
-		Incognito: false, // Default is not incognito
-	}, nil
+// expandCaptures is ExpandCaptures for a parsed URL. References expand to "" when the
+// pattern did not match (a rule matching through other conditions) or has no groups
+// (CIDR patterns).
+func expandCaptures(rule *config.Rule, parsedURL *url.URL, template string) string {
+	if !strings.Contains(template, "$") {
+		return template
+	}
+	re, err := regexp.Compile(rule.Pattern)
+	if err != nil || rule.Scope == config.ScopeCIDR {
+		re = regexp.MustCompile("")
+	}
+	matchString := getMatchString(parsedURL, rule.Scope)
+	return string(re.ExpandString(nil, template, matchString, re.FindStringSubmatchIndex(matchString)))
+}
+
+// expandArgs returns rule's ExtraArgs with capture groups expanded.
+func expandArgs(rule *config.Rule, parsedURL *url.URL) []string {
+	if len(rule.ExtraArgs) == 0 {
+		return nil
+	}
+	args := make([]string, len(rule.ExtraArgs))
+	for i, arg := range rule.ExtraArgs {
+		args[i] = expandCaptures(rule, parsedURL, arg)
+	}
+	return args
 }
@@ -1,9 +1,15 @@
 package rules
 
 import (
+	"errors"
+	"fmt"
+	"slices"
 	"testing"
 
 	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/network"
+	"github.com/jmylchreest/rurl/internal/urlhandler"
+	"github.com/rs/zerolog"
 )
 
 func TestApplyRules(t *testing.T) {
@@ -90,6 +96,33 @@ func TestApplyRules(t *testing.T) {
 			want:    MatchResult{},
 			wantErr: true,
 		},
+		{
+			name: "rule targeting a profile by email",
+			cfg: &config.Config{
+				DefaultProfileID: "default-profile",
+				Profiles: []config.Profile{
+					{ID: "default-profile", Name: "Default"},
+					{ID: "chrome-profile-3", Name: "Work", Email: "me@company.example"},
+				},
+				Rules: []config.Rule{
+					{
+						Name:      "Work",
+						Pattern:   "^https://intranet\\.company\\.example",
+						ProfileID: "email:me@company.example",
+					},
+				},
+			},
+			url: "https://intranet.company.example/",
+			want: MatchResult{
+				Rule: &config.Rule{
+					Name:      "Work",
+					Pattern:   "^https://intranet\\.company\\.example",
+					ProfileID: "email:me@company.example",
+				},
+				ProfileID: "chrome-profile-3",
+			},
+			wantErr: false,
+		},
 		{
 			name: "multiple rules with different specificity",
 			cfg: &config.Config{
@@ -156,6 +189,87 @@ func TestApplyRules(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "cidr scope rule matches IPv4 literal",
+			cfg: &config.Config{
+				DefaultProfileID: "default-profile",
+				Profiles: []config.Profile{
+					{ID: "default-profile", Name: "Default"},
+					{ID: "work-profile", Name: "Work"},
+				},
+				Rules: []config.Rule{
+					{
+						Name:      "Internal Network",
+						Pattern:   "10.0.0.0/8, 192.168.1.10",
+						Scope:     config.ScopeCIDR,
+						ProfileID: "work-profile",
+					},
+				},
+			},
+			url: "http://10.1.2.3:8080/dashboard",
+			want: MatchResult{
+				Rule: &config.Rule{
+					Name:      "Internal Network",
+					Pattern:   "10.0.0.0/8, 192.168.1.10",
+					Scope:     config.ScopeCIDR,
+					ProfileID: "work-profile",
+				},
+				ProfileID: "work-profile",
+			},
+			wantErr: false,
+		},
+		{
+			name: "cidr scope rule matches bracketed IPv6 without scheme",
+			cfg: &config.Config{
+				DefaultProfileID: "default-profile",
+				Profiles: []config.Profile{
+					{ID: "default-profile", Name: "Default"},
+					{ID: "work-profile", Name: "Work"},
+				},
+				Rules: []config.Rule{
+					{
+						Name:      "ULA",
+						Pattern:   "fd00::/8",
+						Scope:     config.ScopeCIDR,
+						ProfileID: "work-profile",
+					},
+				},
+			},
+			url: "[fd12::1]:8443/path",
+			want: MatchResult{
+				Rule: &config.Rule{
+					Name:      "ULA",
+					Pattern:   "fd00::/8",
+					Scope:     config.ScopeCIDR,
+					ProfileID: "work-profile",
+				},
+				ProfileID: "work-profile",
+			},
+			wantErr: false,
+		},
+		{
+			name: "cidr scope rule ignores hostnames",
+			cfg: &config.Config{
+				DefaultProfileID: "default-profile",
+				Profiles: []config.Profile{
+					{ID: "default-profile", Name: "Default"},
+					{ID: "work-profile", Name: "Work"},
+				},
+				Rules: []config.Rule{
+					{
+						Name:      "Internal Network",
+						Pattern:   "10.0.0.0/8",
+						Scope:     config.ScopeCIDR,
+						ProfileID: "work-profile",
+					},
+				},
+			},
+			url: "https://example.com",
+			want: MatchResult{
+				ProfileID: "default-profile",
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -191,3 +305,529 @@ func TestApplyRules(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCIDRList(t *testing.T) {
+	prefixes, err := ParseCIDRList("10.0.0.0/8, 192.168.1.10,[::1]")
+	if err != nil {
+		t.Fatalf("ParseCIDRList() error = %v", err)
+	}
+	if len(prefixes) != 3 {
+		t.Fatalf("ParseCIDRList() returned %d prefixes, want 3", len(prefixes))
+	}
+	if prefixes[1].Bits() != 32 || prefixes[2].Bits() != 128 {
+		t.Errorf("ParseCIDRList() single addresses should be host prefixes, got %v", prefixes)
+	}
+
+	for _, bad := range []string{"", "10.0.0.0/33", "not-an-ip"} {
+		if _, err := ParseCIDRList(bad); err == nil {
+			t.Errorf("ParseCIDRList(%q) expected error", bad)
+		}
+	}
+}
+
+func TestApplyRulesWithContentConditions(t *testing.T) {
+	isDownload := true
+	cfg := &config.Config{
+		DefaultProfileID: "default-profile",
+		Profiles: []config.Profile{
+			{ID: "default-profile", Name: "Default"},
+			{ID: "downloads", Name: "Downloads"},
+			{ID: "pdf", Name: "PDF Viewer"},
+		},
+		Rules: []config.Rule{
+			{Name: "Downloads", Pattern: "", IsDownload: &isDownload, ProfileID: "downloads"},
+			{Name: "PDFs", Pattern: "example", Scope: config.ScopeDomain, ContentType: "^application/pdf$", ProfileID: "pdf"},
+		},
+	}
+
+	if !NeedsContentInspection(cfg) {
+		t.Fatal("NeedsContentInspection() = false, want true")
+	}
+
+	tests := []struct {
+		name    string
+		content *urlhandler.ContentInfo
+		want    string
+	}{
+		{"not inspected", nil, "default-profile"},
+		{"html page", &urlhandler.ContentInfo{ContentType: "text/html"}, "default-profile"},
+		{"pdf", &urlhandler.ContentInfo{ContentType: "application/pdf", IsDownload: true}, "pdf"},
+		{"archive", &urlhandler.ContentInfo{ContentType: "application/zip", IsDownload: true}, "downloads"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyRulesWithContext(cfg, "https://example.com/file", MatchContext{Content: tt.content})
+			if err != nil {
+				t.Fatalf("ApplyRulesWithContext() error = %v", err)
+			}
+			if got.ProfileID != tt.want {
+				t.Errorf("ApplyRulesWithContext() ProfileID = %v, want %v", got.ProfileID, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyRulesWithNetworkConditions(t *testing.T) {
+	onVPN, offVPN := true, false
+	cfg := &config.Config{
+		DefaultProfileID: "default-profile",
+		Profiles: []config.Profile{
+			{ID: "default-profile", Name: "Default"},
+			{ID: "work", Name: "Work"},
+			{ID: "private", Name: "Private"},
+		},
+		Rules: []config.Rule{
+			{Name: "Office", Pattern: "corp", Scope: config.ScopeDomain, WiFiSSID: "^CorpNet$", ProfileID: "work"},
+			{Name: "VPN", Pattern: "corp", Scope: config.ScopeDomain, VPN: &onVPN, ProfileID: "work"},
+			{Name: "Off VPN", Pattern: "news", Scope: config.ScopeDomain, VPN: &offVPN, VPNInterface: `^corp\d+$`, ProfileID: "private"},
+		},
+	}
+
+	if !NeedsNetworkInfo(cfg) {
+		t.Fatal("NeedsNetworkInfo() = false, want true")
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		network *network.Info
+		want    string
+	}{
+		{"not detected", "https://corp.example.com", nil, "default-profile"},
+		{"office wifi", "https://corp.example.com", &network.Info{SSID: "CorpNet", Interfaces: []string{"wlan0"}}, "work"},
+		{"other wifi", "https://corp.example.com", &network.Info{SSID: "CorpNet-Guest", Interfaces: []string{"wlan0"}}, "default-profile"},
+		{"vpn", "https://corp.example.com", &network.Info{SSID: "Home", Interfaces: []string{"wlan0", "tun0"}}, "work"},
+		{"macos vpn", "https://corp.example.com", &network.Info{Interfaces: []string{"en0", "utun3"}}, "work"},
+		{"custom vpn interface up", "https://news.example.com", &network.Info{Interfaces: []string{"eth0", "corp0"}}, "default-profile"},
+		{"custom vpn interface down", "https://news.example.com", &network.Info{Interfaces: []string{"eth0", "tun0"}}, "private"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyRulesWithContext(cfg, tt.url, MatchContext{Network: tt.network})
+			if err != nil {
+				t.Fatalf("ApplyRulesWithContext() error = %v", err)
+			}
+			if got.ProfileID != tt.want {
+				t.Errorf("ApplyRulesWithContext() ProfileID = %v, want %v", got.ProfileID, tt.want)
+			}
+		})
+	}
+
+	if err := ValidateRule(&config.Rule{Pattern: "x", WiFiSSID: "("}); err == nil {
+		t.Error("ValidateRule() accepted an invalid Wi-Fi SSID pattern")
+	}
+}
+
+func TestApplyRulesWithOverrides(t *testing.T) {
+	cfg := &config.Config{
+		DefaultProfileID: "default-profile",
+		Profiles: []config.Profile{
+			{ID: "default-profile", Name: "Default"},
+			{ID: "work", Name: "Work"},
+			{ID: "pinned", Name: "Pinned"},
+		},
+		Rules: []config.Rule{
+			{Name: "Example", Pattern: "example", Scope: config.ScopeDomain, ProfileID: "work"},
+		},
+		Overrides: map[string]string{
+			"https://example.com/Report.pdf": "pinned",
+			"https://example.com/gone":       "missing",
+		},
+	}
+
+	tests := []struct {
+		url      string
+		want     string
+		override bool
+	}{
+		{"https://example.com/Report.pdf", "pinned", true},
+		{"https://example.com/report.pdf", "work", false},
+		{"https://example.com/Report.pdf?x=1", "work", false},
+		{"https://other.test/", "default-profile", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := ApplyRules(cfg, tt.url)
+			if err != nil {
+				t.Fatalf("ApplyRules() error = %v", err)
+			}
+			if got.ProfileID != tt.want || got.Override != tt.override {
+				t.Errorf("ApplyRules() = %v (override %v), want %v (override %v)", got.ProfileID, got.Override, tt.want, tt.override)
+			}
+		})
+	}
+
+	_, err := ApplyRules(cfg, "https://example.com/gone")
+	var noProfile *NoProfileError
+	if !errors.As(err, &noProfile) || noProfile.URL != "https://example.com/gone" {
+		t.Errorf("ApplyRules() error = %v, want a NoProfileError for the override", err)
+	}
+}
+
+func TestApplyRulesWithPluginConditions(t *testing.T) {
+	cfg := &config.Config{
+		DefaultProfileID: "default-profile",
+		Profiles: []config.Profile{
+			{ID: "default-profile", Name: "Default"},
+			{ID: "tickets", Name: "Tickets"},
+		},
+		Rules: []config.Rule{
+			{ID: "tickets", Name: "Tickets", Pattern: "example", Scope: config.ScopeDomain, Plugin: "ticket-check", ProfileID: "tickets"},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		match func(rule *config.Rule, url string) (bool, error)
+		want  string
+	}{
+		{"plugins not run", nil, "default-profile"},
+		{"plugin accepts", func(rule *config.Rule, url string) (bool, error) { return rule.ID == "tickets", nil }, "tickets"},
+		{"plugin rejects", func(rule *config.Rule, url string) (bool, error) { return false, nil }, "default-profile"},
+		{"plugin fails", func(rule *config.Rule, url string) (bool, error) { return true, fmt.Errorf("boom") }, "default-profile"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyRulesWithContext(cfg, "https://example.com/", MatchContext{PluginMatch: tt.match})
+			if err != nil {
+				t.Fatalf("ApplyRulesWithContext() error = %v", err)
+			}
+			if got.ProfileID != tt.want {
+				t.Errorf("ApplyRulesWithContext() ProfileID = %v, want %v", got.ProfileID, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyRulesSkipsDisabledRules(t *testing.T) {
+	disabled := false
+	cfg := &config.Config{
+		DefaultProfileID: "default-profile",
+		Profiles: []config.Profile{
+			{ID: "default-profile", Name: "Default"},
+			{ID: "work", Name: "Work"},
+		},
+		Rules: []config.Rule{
+			{Name: "Work", Pattern: `^work\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "work", Enabled: &disabled},
+		},
+	}
+
+	got, err := ApplyRules(cfg, "https://work.example.com/")
+	if err != nil {
+		t.Fatalf("ApplyRules() error = %v", err)
+	}
+	if got.Rule != nil || got.ProfileID != "default-profile" {
+		t.Errorf("disabled rule matched: %+v", got)
+	}
+
+	enabled := true
+	cfg.Rules[0].Enabled = &enabled
+	got, err = ApplyRules(cfg, "https://work.example.com/")
+	if err != nil {
+		t.Fatalf("ApplyRules() error = %v", err)
+	}
+	if got.ProfileID != "work" {
+		t.Errorf("ApplyRules() ProfileID = %v, want work", got.ProfileID)
+	}
+}
+
+func TestApplyRulesDefaultIncognito(t *testing.T) {
+	cfg := &config.Config{
+		DefaultProfileID: "default-profile",
+		Profiles: []config.Profile{
+			{ID: "default-profile", Name: "Default"},
+			{ID: "work", Name: "Work"},
+		},
+		Rules: []config.Rule{
+			{Name: "Work", Pattern: `^work\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "work"},
+		},
+		Behavior: config.Behavior{DefaultIncognito: true},
+	}
+
+	// Unmatched URLs open privately, while matched ones follow their rule
+	got, err := ApplyRules(cfg, "https://example.com/")
+	if err != nil {
+		t.Fatalf("ApplyRules() error = %v", err)
+	}
+	if got.Rule != nil || !got.Incognito {
+		t.Errorf("ApplyRules() = %+v, want the default profile in incognito", got)
+	}
+	got, err = ApplyRules(cfg, "https://work.example.com/")
+	if err != nil {
+		t.Fatalf("ApplyRules() error = %v", err)
+	}
+	if got.Incognito {
+		t.Errorf("ApplyRules() = %+v, want the rule's non-incognito launch", got)
+	}
+}
+
+func TestApplyRulesFileURLs(t *testing.T) {
+	cfg := &config.Config{
+		DefaultProfileID: "personal",
+		Profiles:         []config.Profile{{ID: "personal"}, {ID: "viewer"}, {ID: "docs"}, {ID: "books"}},
+		Rules: []config.Rule{
+			{Name: "PDF", Pattern: `^pdf$`, Scope: config.ScopeExtension, ProfileID: "docs"},
+			{Name: "Books", Pattern: `^bücher\.example$`, Scope: config.ScopeDomain, ProfileID: "books"},
+		},
+		Behavior: config.Behavior{FileProfileID: "viewer"},
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"file:///home/me/Report.PDF", "docs"},           // By extension, whatever its case
+		{"https://example.com/files/a.pdf?dl=1", "docs"}, // Also for web URLs
+		{"file:///home/me/notes.txt", "viewer"},          // Unmatched files open in the file profile
+		{"file:///home/me/archive.pdf.gz", "viewer"},     // Only the last extension counts
+		{"https://example.com/a.txt", "personal"},        // Other URLs still use the default profile
+		{"https://bücher.example/katalog", "books"},      // Internationalized hosts match as written
+		{"data:text/plain;base64,aGVsbG8=", "personal"},  // data: URLs are routed like any other
+	}
+	for _, tt := range tests {
+		got, err := ApplyRules(cfg, tt.url)
+		if err != nil {
+			t.Errorf("ApplyRules(%q) error = %v", tt.url, err)
+			continue
+		}
+		if got.ProfileID != tt.want {
+			t.Errorf("ApplyRules(%q) = profile %q, want %q", tt.url, got.ProfileID, tt.want)
+		}
+	}
+
+	// Without a file profile, files fall back to the default profile
+	cfg.Behavior.FileProfileID = ""
+	if got, err := ApplyRules(cfg, "file:///home/me/notes.txt"); err != nil || got.ProfileID != "personal" {
+		t.Errorf("ApplyRules() = %+v, %v, want the default profile", got, err)
+	}
+	cfg.Behavior.FileProfileID = "gone"
+	var noProfile *NoProfileError
+	if _, err := ApplyRules(cfg, "file:///home/me/notes.txt"); !errors.As(err, &noProfile) || noProfile.ProfileID != "gone" {
+		t.Errorf("ApplyRules() error = %v, want a NoProfileError for the missing file profile", err)
+	}
+}
+
+func TestMatchAll(t *testing.T) {
+	disabled := false
+	cfg := &config.Config{
+		DefaultProfileID: "personal",
+		Profiles: []config.Profile{
+			{ID: "personal", Name: "Personal"},
+			{ID: "work", Name: "Work"},
+		},
+		Rules: []config.Rule{
+			{Name: "Example", Pattern: `example\.com$`, Scope: config.ScopeDomain, ProfileID: "personal"},
+			{Name: "Work", Pattern: `^work\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "work", Incognito: true},
+			{Name: "Disabled", Pattern: `^work\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "personal", Enabled: &disabled},
+			{Name: "Video", Pattern: `/watch`, Scope: config.ScopePath, Handler: "mpv %u"},
+			{Name: "Other", Pattern: `^other\.example\.org$`, Scope: config.ScopeDomain, ProfileID: "personal"},
+		},
+	}
+
+	// Every enabled matching rule, in the order ApplyRules tries them
+	got, err := MatchAll(cfg, "https://work.example.com/watch")
+	if err != nil {
+		t.Fatalf("MatchAll() error = %v", err)
+	}
+	var names []string
+	for _, m := range got {
+		names = append(names, m.Rule.Name)
+	}
+	if want := []string{"Work", "Example", "Video"}; !slices.Equal(names, want) {
+		t.Fatalf("MatchAll() rules = %v, want %v", names, want)
+	}
+	if got[0].ProfileID != "work" || !got[0].Incognito {
+		t.Errorf("MatchAll()[0] = %+v, want the work profile in incognito", got[0])
+	}
+	if got[2].Handler != "mpv %u" {
+		t.Errorf("MatchAll()[2].Handler = %q, want %q", got[2].Handler, "mpv %u")
+	}
+	first, err := ApplyRules(cfg, "https://work.example.com/watch")
+	if err != nil || first.Rule == nil || first.Rule.Name != got[0].Rule.Name {
+		t.Errorf("ApplyRules() = %+v, %v, want the first MatchAll() result", first, err)
+	}
+
+	// No match is not an error, but an invalid URL is
+	if got, err := MatchAll(cfg, "https://unrelated.test/"); err != nil || len(got) != 0 {
+		t.Errorf("MatchAll() = %v, %v, want no matches", got, err)
+	}
+	if _, err := MatchAll(cfg, "://"); err == nil {
+		t.Error("MatchAll() error = nil for an invalid URL")
+	}
+}
+
+func TestApplyRulesWithConditions(t *testing.T) {
+	cfg := &config.Config{
+		DefaultProfileID: "default-profile",
+		Profiles: []config.Profile{
+			{ID: "default-profile", Name: "Default"},
+			{ID: "admin", Name: "Admin"},
+			{ID: "insecure", Name: "Insecure"},
+			{ID: "media", Name: "Media"},
+		},
+		Rules: []config.Rule{
+			{Name: "Admin", Pattern: `^(?:.*\.)?example\.com$`, Scope: config.ScopeDomain, ProfileID: "admin",
+				Conditions: []config.Condition{
+					{Pattern: `^/admin(?:/|$)`, Scope: config.ScopePath},
+					{Pattern: `^https$`, Scope: config.ScopeScheme},
+				}},
+			{Name: "Insecure", ProfileID: "insecure",
+				Conditions: []config.Condition{{Pattern: `^https$`, Scope: config.ScopeScheme, Negate: true}}},
+			{Name: "Media", ProfileID: "media", Match: config.MatchAny,
+				Conditions: []config.Condition{
+					{Pattern: `\.(?:mp4|webm)$`, Scope: config.ScopePath},
+					{Pattern: `^(?:www\.)?youtube\.com$`, Scope: config.ScopeDomain},
+				}},
+		},
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.example.com/admin/users", "admin"},
+		{"http://www.example.com/admin/users", "insecure"},           // Scheme condition fails
+		{"https://www.example.com/administrator", "default-profile"}, // Path condition fails
+		{"https://cdn.example.org/clip.webm", "media"},
+		{"https://youtube.com/watch?v=1", "media"},
+		{"https://example.org/", "default-profile"},
+	}
+	for _, tt := range tests {
+		got, err := ApplyRules(cfg, tt.url)
+		if err != nil {
+			t.Fatalf("ApplyRules(%s) error = %v", tt.url, err)
+		}
+		if got.ProfileID != tt.want {
+			t.Errorf("ApplyRules(%s) ProfileID = %v, want %v", tt.url, got.ProfileID, tt.want)
+		}
+	}
+
+	// Invalid conditions and combinations are reported
+	bad := config.Rule{Pattern: "x", Conditions: []config.Condition{{Pattern: "(", Scope: config.ScopePath}}}
+	if err := ValidateRule(&bad); err == nil {
+		t.Error("ValidateRule() accepted an invalid condition pattern")
+	}
+	bad = config.Rule{Pattern: "x", Match: "some", Conditions: []config.Condition{{Pattern: "y", Scope: config.ScopePath}}}
+	if err := ValidateRule(&bad); err == nil {
+		t.Error("ValidateRule() accepted an unknown match")
+	}
+}
+
+func TestApplyRulesExpandsCaptures(t *testing.T) {
+	cfg := &config.Config{
+		DefaultProfileID: "default-profile",
+		Profiles:         []config.Profile{{ID: "default-profile", Name: "Default"}, {ID: "work", Name: "Work"}},
+		Rules: []config.Rule{
+			{Name: "Jira", Pattern: `^https://jira\.example\.com/browse/(?P<key>[A-Z]+-\d+)`, Scope: config.ScopeURL, ProfileID: "work",
+				RewriteURL: "https://tracker.example.com/issue/${key}",
+				ExtraArgs:  []string{"--app=https://kanban.example.com/$1", "--cost=$$5"}},
+			{Name: "Docs", Pattern: `^docs\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "work",
+				ExtraArgs: []string{"--flag=$1"}},
+		},
+	}
+
+	got, err := ApplyRules(cfg, "https://jira.example.com/browse/ABC-123?focus=1")
+	if err != nil {
+		t.Fatalf("ApplyRules() error = %v", err)
+	}
+	if want := "https://tracker.example.com/issue/ABC-123"; got.RewriteURL != want {
+		t.Errorf("RewriteURL = %q, want %q", got.RewriteURL, want)
+	}
+	wantArgs := []string{"--app=https://kanban.example.com/ABC-123", "--cost=$5"}
+	if !slices.Equal(got.ExtraArgs, wantArgs) {
+		t.Errorf("ExtraArgs = %q, want %q", got.ExtraArgs, wantArgs)
+	}
+
+	// References to missing groups expand to nothing
+	got, err = ApplyRules(cfg, "https://docs.example.com/")
+	if err != nil {
+		t.Fatalf("ApplyRules() error = %v", err)
+	}
+	if got.RewriteURL != "" || !slices.Equal(got.ExtraArgs, []string{"--flag="}) {
+		t.Errorf("got RewriteURL %q, ExtraArgs %q, want none and [--flag=]", got.RewriteURL, got.ExtraArgs)
+	}
+}
+
+func TestTestPattern(t *testing.T) {
+	tests := []struct {
+		scope      config.RuleScope
+		pattern    string
+		url        string
+		want       bool
+		wantString string
+		wantErr    bool
+	}{
+		{config.ScopeDomain, `^(.*\.)?example\.com$`, "https://mail.example.com/inbox", true, "mail.example.com", false},
+		{config.ScopeDomain, `^example\.com$`, "example.org", false, "example.org", false},
+		{config.ScopePath, `^/admin`, "https://example.com/admin/users", true, "/admin/users", false},
+		{config.ScopeCIDR, "10.0.0.0/8", "http://10.1.2.3:8080/", true, "10.1.2.3", false},
+		{config.ScopeURL, `(unclosed`, "https://example.com/", false, "", true},
+		{config.ScopeCIDR, "not-a-cidr", "http://10.1.2.3/", false, "", true},
+		{config.RuleScope("bogus"), ".*", "https://example.com/", false, "", true},
+	}
+
+	for _, tt := range tests {
+		got, matchString, err := TestPattern(tt.scope, tt.pattern, tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("TestPattern(%s, %q, %q) error = %v, wantErr %v", tt.scope, tt.pattern, tt.url, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want || matchString != tt.wantString {
+			t.Errorf("TestPattern(%s, %q, %q) = %v, %q, want %v, %q", tt.scope, tt.pattern, tt.url, got, matchString, tt.want, tt.wantString)
+		}
+	}
+}
+
+func BenchmarkApplyRules(b *testing.B) {
+	// Log at the CLI's default level, as debug logging would dominate the timings
+	defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+	zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+	for _, n := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("%d_rules", n), func(b *testing.B) {
+			cfg := &config.Config{
+				DefaultProfileID: "default-profile",
+				Profiles:         []config.Profile{{ID: "default-profile"}, {ID: "work-profile"}},
+			}
+			for i := range n {
+				cfg.Rules = append(cfg.Rules, config.Rule{
+					ID:        fmt.Sprintf("rule-%d", i),
+					Name:      fmt.Sprintf("Rule %d", i),
+					Pattern:   fmt.Sprintf(`^site%d\.example\.com$`, i),
+					Scope:     config.ScopeDomain,
+					ProfileID: "work-profile",
+				})
+			}
+			// Only the last rule matches, so every rule is evaluated
+			url := fmt.Sprintf("https://site%d.example.com/path?q=1", n-1)
+			b.ResetTimer()
+			for range b.N {
+				if _, err := ApplyRules(cfg, url); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyRulesWithHandler(t *testing.T) {
+	cfg := &config.Config{
+		DefaultProfileID: "default-profile",
+		Profiles:         []config.Profile{{ID: "default-profile", Name: "Default"}},
+		Rules: []config.Rule{
+			{Name: "Videos", Pattern: `^(www\.)?youtube\.com$`, Scope: config.ScopeDomain, Handler: `mpv "%u"`},
+		},
+	}
+	got, err := ApplyRules(cfg, "https://www.youtube.com/watch?v=abc")
+	if err != nil {
+		t.Fatalf("ApplyRules() error = %v", err)
+	}
+	if got.Handler != `mpv "%u"` || got.ProfileID != "" || got.Rule == nil {
+		t.Errorf("ApplyRules() = %+v, want the handler without a profile", got)
+	}
+
+	cfg.Rules[0].Handler = `mpv "%u`
+	issues := Lint(cfg)
+	if len(issues) != 1 || issues[0].Kind != LintInvalidHandler {
+		t.Errorf("Lint() = %+v, want an invalid handler issue", issues)
+	}
+}
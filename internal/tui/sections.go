@@ -0,0 +1,430 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/history"
+	"github.com/jmylchreest/rurl/internal/launcher"
+	"github.com/jmylchreest/rurl/internal/rules"
+)
+
+// field is one editable attribute of an item. Items are edited as copies
+// (pointers returned by section.load/create) and written back by section.store.
+type field struct {
+	label string
+	get   func(item any) string
+	set   func(item any, value string) error
+}
+
+// section is a tab listing one kind of configuration item.
+type section struct {
+	title   string
+	columns []string
+	count   func() int
+	row     func(i int) []string
+	fields  []field                     // Editable fields (nil for read-only sections)
+	load    func(i int) any             // Copy of item i for editing
+	create  func() any                  // New item for adding (nil if adding is unsupported)
+	store   func(i int, item any) error // Validate and write back; i < 0 appends
+	remove  func(i int) error           // nil if deleting is unsupported
+}
+
+func (s *section) editable() bool { return s.fields != nil }
+
+func parseBool(v string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "", "false", "no", "n", "0":
+		return false, nil
+	case "true", "yes", "y", "1":
+		return true, nil
+	}
+	return false, fmt.Errorf("'%s' is not a yes/no value", v)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// browserSection lists and edits cfg.Browsers.
+func browserSection(cfg *config.Config) section {
+	b := func(item any) *config.Browser { return item.(*config.Browser) }
+	return section{
+		title:   "Browsers",
+		columns: []string{"ID", "Name", "Executable"},
+		count:   func() int { return len(cfg.Browsers) },
+		row: func(i int) []string {
+			br := cfg.Browsers[i]
+			return []string{br.BrowserID, br.Name, br.Executable}
+		},
+		fields: []field{
+			{"ID", func(i any) string { return b(i).BrowserID }, func(i any, v string) error { b(i).BrowserID = v; return nil }},
+			{"Name", func(i any) string { return b(i).Name }, func(i any, v string) error { b(i).Name = v; return nil }},
+			{"Executable", func(i any) string { return b(i).Executable }, func(i any, v string) error { b(i).Executable = v; return nil }},
+			{"Profile arg", func(i any) string { return b(i).ProfileArg }, func(i any, v string) error { b(i).ProfileArg = v; return nil }},
+			{"Incognito arg", func(i any) string { return b(i).IncognitoArg }, func(i any, v string) error { b(i).IncognitoArg = v; return nil }},
+			{"Terminal", func(i any) string { return yesNo(b(i).Terminal) }, func(i any, v string) error {
+				t, err := parseBool(v)
+				b(i).Terminal = t
+				return err
+			}},
+		},
+		load:   func(i int) any { c := cfg.Browsers[i]; return &c },
+		create: func() any { return &config.Browser{Origin: config.OriginManual} },
+		store: func(i int, item any) error {
+			br := *b(item)
+			if br.BrowserID == "" || (br.Executable == "" && br.Remote == nil) {
+				return fmt.Errorf("ID and executable are required")
+			}
+			for j, other := range cfg.Browsers {
+				if j != i && other.BrowserID == br.BrowserID {
+					return fmt.Errorf("browser ID '%s' already exists", br.BrowserID)
+				}
+			}
+			if i < 0 {
+				cfg.Browsers = append(cfg.Browsers, br)
+				return nil
+			}
+			// Keep profiles attached when the ID is renamed
+			if old := cfg.Browsers[i].BrowserID; old != br.BrowserID {
+				for j := range cfg.Profiles {
+					if cfg.Profiles[j].BrowserID == old {
+						cfg.Profiles[j].BrowserID = br.BrowserID
+					}
+				}
+			}
+			cfg.Browsers[i] = br
+			return nil
+		},
+		remove: func(i int) error {
+			id := cfg.Browsers[i].BrowserID
+			for _, p := range cfg.Profiles {
+				if p.BrowserID == id {
+					return fmt.Errorf("browser '%s' is used by profile '%s'", id, p.ID)
+				}
+			}
+			cfg.Browsers = append(cfg.Browsers[:i], cfg.Browsers[i+1:]...)
+			return nil
+		},
+	}
+}
+
+// profileSection lists and edits cfg.Profiles.
+func profileSection(cfg *config.Config) section {
+	p := func(item any) *config.Profile { return item.(*config.Profile) }
+	return section{
+		title:   "Profiles",
+		columns: []string{"ID", "Name", "Browser", "Profile Dir", "Default"},
+		count:   func() int { return len(cfg.Profiles) },
+		row: func(i int) []string {
+			pr := cfg.Profiles[i]
+			return []string{pr.ID, pr.Name, pr.BrowserID, pr.ProfileDir, yesNo(pr.ID == cfg.DefaultProfileID)}
+		},
+		fields: []field{
+			{"ID", func(i any) string { return p(i).ID }, func(i any, v string) error { p(i).ID = v; return nil }},
+			{"Name", func(i any) string { return p(i).Name }, func(i any, v string) error { p(i).Name = v; return nil }},
+			{"Browser ID", func(i any) string { return p(i).BrowserID }, func(i any, v string) error { p(i).BrowserID = v; return nil }},
+			{"Profile dir", func(i any) string { return p(i).ProfileDir }, func(i any, v string) error { p(i).ProfileDir = v; return nil }},
+			{"Email", func(i any) string { return p(i).Email }, func(i any, v string) error { p(i).Email = v; return nil }},
+			{"User data dir", func(i any) string { return p(i).UserDataDir }, func(i any, v string) error { p(i).UserDataDir = v; return nil }},
+			{"Executable override", func(i any) string { return p(i).ExecutableOverride }, func(i any, v string) error { p(i).ExecutableOverride = v; return nil }},
+			{"Always incognito", func(i any) string { return yesNo(p(i).AlwaysIncognito) }, func(i any, v string) error {
+				b, err := parseBool(v)
+				p(i).AlwaysIncognito = b
+				return err
+			}},
+			{"Working dir", func(i any) string { return p(i).WorkingDir }, func(i any, v string) error { p(i).WorkingDir = v; return nil }},
+			{"Nice", func(i any) string { return strconv.Itoa(p(i).Nice) }, func(i any, v string) error {
+				n, err := strconv.Atoi(strings.TrimSpace(v))
+				if err != nil {
+					return fmt.Errorf("nice level must be a number")
+				}
+				p(i).Nice = n
+				return nil
+			}},
+			{"Sandbox", func(i any) string { return p(i).Sandbox }, func(i any, v string) error {
+				if v != "" {
+					if err := launcher.ValidateSandbox(v); err != nil {
+						return err
+					}
+				}
+				p(i).Sandbox = v
+				return nil
+			}},
+		},
+		load:   func(i int) any { c := cfg.Profiles[i]; return &c },
+		create: func() any { return &config.Profile{Origin: config.OriginManual} },
+		store: func(i int, item any) error {
+			pr := *p(item)
+			if pr.ID == "" {
+				return fmt.Errorf("ID is required")
+			}
+			if _, err := cfg.FindBrowserByID(pr.BrowserID); err != nil {
+				return fmt.Errorf("unknown browser ID '%s'", pr.BrowserID)
+			}
+			for j, other := range cfg.Profiles {
+				if j != i && other.ID == pr.ID {
+					return fmt.Errorf("profile ID '%s' already exists", pr.ID)
+				}
+			}
+			if i < 0 {
+				cfg.Profiles = append(cfg.Profiles, pr)
+				return nil
+			}
+			// Keep rules and the default pointing at a renamed profile
+			if old := cfg.Profiles[i].ID; old != pr.ID {
+				for j := range cfg.Rules {
+					if cfg.Rules[j].ProfileID == old {
+						cfg.Rules[j].ProfileID = pr.ID
+					}
+				}
+				if cfg.DefaultProfileID == old {
+					cfg.DefaultProfileID = pr.ID
+				}
+			}
+			cfg.Profiles[i] = pr
+			return nil
+		},
+		remove: func(i int) error {
+			id := cfg.Profiles[i].ID
+			if id == cfg.DefaultProfileID {
+				return fmt.Errorf("cannot delete the default profile '%s'", id)
+			}
+			for _, r := range cfg.Rules {
+				if r.ProfileID == id {
+					return fmt.Errorf("profile '%s' is used by rule '%s'", id, r.Name)
+				}
+			}
+			cfg.Profiles = append(cfg.Profiles[:i], cfg.Profiles[i+1:]...)
+			return nil
+		},
+	}
+}
+
+// validateRule checks a rule's patterns, scopes and profile.
+func validateRule(cfg *config.Config, r config.Rule) error {
+	if err := rules.ValidateRule(&r); err != nil {
+		return err
+	}
+	if r.Handler != "" {
+		_, err := launcher.HandlerCommand(r.Handler, "")
+		return err
+	}
+	if _, err := cfg.FindProfileByID(r.ProfileID); err != nil {
+		return fmt.Errorf("unknown profile ID '%s'", r.ProfileID)
+	}
+	return nil
+}
+
+// ruleSection lists and edits cfg.Rules.
+func ruleSection(cfg *config.Config) section {
+	r := func(item any) *config.Rule { return item.(*config.Rule) }
+	return section{
+		title:   "Rules",
+		columns: []string{"ID", "Name", "Scope", "Pattern", "Profile", "Incognito", "Enabled"},
+		count:   func() int { return len(cfg.Rules) },
+		row: func(i int) []string {
+			ru := cfg.Rules[i]
+			return []string{ru.ID, ru.Name, string(ru.Scope), ru.Pattern, ru.ProfileID, yesNo(ru.Incognito), yesNo(ru.IsEnabled())}
+		},
+		fields: []field{
+			{"Name", func(i any) string { return r(i).Name }, func(i any, v string) error { r(i).Name = v; return nil }},
+			{"Pattern", func(i any) string { return r(i).Pattern }, func(i any, v string) error { r(i).Pattern = v; return nil }},
+			{"Pattern list", func(i any) string { return r(i).PatternListRef }, func(i any, v string) error {
+				r(i).PatternListRef = strings.TrimSpace(v)
+				return nil
+			}},
+			{"Scope", func(i any) string { return string(r(i).Scope) }, func(i any, v string) error {
+				r(i).Scope = config.RuleScope(strings.ToLower(strings.TrimSpace(v)))
+				return nil
+			}},
+			{"Profile ID", func(i any) string { return r(i).ProfileID }, func(i any, v string) error { r(i).ProfileID = v; return nil }},
+			{"Description", func(i any) string { return r(i).Description }, func(i any, v string) error {
+				r(i).Description = strings.TrimSpace(v)
+				return nil
+			}},
+			{"Tags", func(i any) string { return strings.Join(r(i).Tags, ", ") }, func(i any, v string) error {
+				r(i).Tags = config.ParseTags(v)
+				return nil
+			}},
+			{"Incognito", func(i any) string { return yesNo(r(i).Incognito) }, func(i any, v string) error {
+				b, err := parseBool(v)
+				r(i).Incognito = b
+				return err
+			}},
+			{"Deep link", func(i any) string { return yesNo(r(i).DeepLink) }, func(i any, v string) error {
+				b, err := parseBool(v)
+				r(i).DeepLink = b
+				return err
+			}},
+			{"Rewrite URL", func(i any) string { return r(i).RewriteURL }, func(i any, v string) error {
+				r(i).RewriteURL = strings.TrimSpace(v)
+				return nil
+			}},
+			{"Handler", func(i any) string { return r(i).Handler }, func(i any, v string) error {
+				r(i).Handler = strings.TrimSpace(v)
+				return nil
+			}},
+			{"Enabled", func(i any) string { return yesNo(r(i).IsEnabled()) }, func(i any, v string) error {
+				if strings.TrimSpace(v) == "" {
+					r(i).Enabled = nil // Default (enabled)
+					return nil
+				}
+				b, err := parseBool(v)
+				r(i).Enabled = &b
+				return err
+			}},
+		},
+		load: func(i int) any { c := cfg.Rules[i]; return &c },
+		create: func() any {
+			return &config.Rule{Scope: config.ScopeDomain, ProfileID: cfg.DefaultProfileID}
+		},
+		store: func(i int, item any) error {
+			ru := *r(item)
+			if ru.Name == "" {
+				return fmt.Errorf("name is required")
+			}
+			if cfg.RuleNameExists(ru.Name, i) {
+				return fmt.Errorf("a rule named '%s' already exists", ru.Name)
+			}
+			if err := validateRule(cfg, ru); err != nil {
+				return err
+			}
+			if i < 0 {
+				ru.ID = cfg.GenerateRuleID(ru.Name)
+				cfg.Rules = append(cfg.Rules, ru)
+			} else {
+				cfg.Rules[i] = ru
+			}
+			return nil
+		},
+		remove: func(i int) error {
+			cfg.Rules = append(cfg.Rules[:i], cfg.Rules[i+1:]...)
+			return nil
+		},
+	}
+}
+
+// shortenerSection lists and edits cfg.ManualShorteners. Built-in shorteners are not editable.
+func shortenerSection(cfg *config.Config) section {
+	s := func(item any) *config.ShortenerService { return item.(*config.ShortenerService) }
+	return section{
+		title:   "Shorteners",
+		columns: []string{"Domain", "Safelink"},
+		count:   func() int { return len(cfg.ManualShorteners) },
+		row: func(i int) []string {
+			sh := cfg.ManualShorteners[i]
+			return []string{sh.Domain, yesNo(sh.IsSafelink)}
+		},
+		fields: []field{
+			{"Domain", func(i any) string { return s(i).Domain }, func(i any, v string) error {
+				s(i).Domain = strings.ToLower(strings.TrimSpace(v))
+				return nil
+			}},
+			{"Safelink", func(i any) string { return yesNo(s(i).IsSafelink) }, func(i any, v string) error {
+				b, err := parseBool(v)
+				s(i).IsSafelink = b
+				return err
+			}},
+			{"User agent", func(i any) string { return s(i).UserAgent }, func(i any, v string) error {
+				s(i).UserAgent = strings.TrimSpace(v)
+				return nil
+			}},
+		},
+		load:   func(i int) any { c := cfg.ManualShorteners[i]; return &c },
+		create: func() any { return &config.ShortenerService{} },
+		store: func(i int, item any) error {
+			sh := *s(item)
+			if sh.Domain == "" {
+				return fmt.Errorf("domain is required")
+			}
+			for j, other := range cfg.ManualShorteners {
+				if j != i && other.Domain == sh.Domain {
+					return fmt.Errorf("shortener '%s' already exists", sh.Domain)
+				}
+			}
+			if i < 0 {
+				cfg.ManualShorteners = append(cfg.ManualShorteners, sh)
+			} else {
+				cfg.ManualShorteners[i] = sh
+			}
+			return nil
+		},
+		remove: func(i int) error {
+			cfg.ManualShorteners = append(cfg.ManualShorteners[:i], cfg.ManualShorteners[i+1:]...)
+			return nil
+		},
+	}
+}
+
+// historySection lists recent launches, newest first. entries is re-read on refresh.
+func historySection(entries *[]history.Entry) section {
+	at := func(i int) history.Entry { return (*entries)[len(*entries)-1-i] }
+	return section{
+		title:   "History",
+		columns: []string{"Time", "URL", "Rule", "Profile", "Result"},
+		count:   func() int { return len(*entries) },
+		row: func(i int) []string {
+			e := at(i)
+			result := "ok"
+			if e.Error != "" {
+				result = "error: " + e.Error
+			}
+			rule := e.RuleName
+			if rule == "" {
+				rule = "(default)"
+			}
+			if len(e.RuleTags) > 0 {
+				rule += " [" + strings.Join(e.RuleTags, ", ") + "]"
+			}
+			profile := e.ProfileID
+			if e.Incognito {
+				profile += " (incognito)"
+			}
+			return []string{e.Time.Local().Format("2006-01-02 15:04:05"), e.URL, rule, profile, result}
+		},
+	}
+}
+
+// testURL evaluates the rules against rawURL without launching anything.
+func testURL(cfg *config.Config, rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return ""
+	}
+	res, err := rules.ApplyRules(cfg, rawURL)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	var b strings.Builder
+	if res.Rule != nil {
+		fmt.Fprintf(&b, "Matched rule: %s (%s: %s)\n", res.Rule.Name, res.Rule.Scope, res.Rule.Pattern)
+		if len(res.Rule.Tags) > 0 {
+			fmt.Fprintf(&b, "Tags:         %s\n", strings.Join(res.Rule.Tags, ", "))
+		}
+		// Rules that also match but never fire, as the matched rule comes first
+		if all, err := rules.MatchAll(cfg, rawURL); err == nil && len(all) > 1 {
+			shadowed := make([]string, len(all)-1)
+			for i, m := range all[1:] {
+				shadowed[i] = m.Rule.Name
+			}
+			fmt.Fprintf(&b, "Shadowed:     %s\n", strings.Join(shadowed, ", "))
+		}
+	} else if res.Override {
+		b.WriteString("Matched an override for the exact URL\n")
+	} else {
+		b.WriteString("No rule matched, using the default profile\n")
+	}
+	if res.Handler != "" {
+		fmt.Fprintf(&b, "Handler:      %s", res.Handler)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Profile:      %s\n", res.ProfileID)
+	fmt.Fprintf(&b, "Incognito:    %s", strconv.FormatBool(launcher.ResolveIncognito(cfg, res.ProfileID, res.Incognito, nil)))
+	return b.String()
+}
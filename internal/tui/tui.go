@@ -0,0 +1,426 @@
+// Package tui implements a full-screen terminal interface for managing the
+// rurl configuration, testing URLs against rules and viewing recent launches.
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/history"
+	"github.com/rs/zerolog/log"
+)
+
+type mode int
+
+const (
+	modeBrowse mode = iota
+	modeEdit
+	modeConfirmQuit
+)
+
+var (
+	tabStyle       = lipgloss.NewStyle().Padding(0, 1)
+	activeTabStyle = tabStyle.Bold(true).Reverse(true)
+	headerStyle    = lipgloss.NewStyle().Bold(true).Underline(true)
+	selectedStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	errorStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	helpStyle      = lipgloss.NewStyle().Faint(true)
+)
+
+// SaveFunc persists the configuration.
+type SaveFunc func(cfg *config.Config) error
+
+// Model is the bubbletea model for the management interface.
+type Model struct {
+	cfg         *config.Config
+	save        SaveFunc
+	historyPath string
+	history     []history.Entry
+
+	sections []section
+	tab      int // Index into sections, or len(sections) for the URL test tab
+	cursor   int
+	mode     mode
+
+	// Edit form state
+	inputs    []textinput.Model
+	focus     int
+	editIndex int // Item being edited, -1 when adding
+	editItem  any
+
+	testInput  textinput.Model
+	testResult string
+
+	dirty  bool
+	status string
+	err    string
+	width  int
+}
+
+// New creates the model. historyPath may be empty to disable the history tab contents.
+func New(cfg *config.Config, save SaveFunc, historyPath string) *Model {
+	m := &Model{
+		cfg:         cfg,
+		save:        save,
+		historyPath: historyPath,
+		testInput:   textinput.New(),
+	}
+	m.testInput.Placeholder = "https://example.com/"
+	m.testInput.Prompt = "URL: "
+	m.loadHistory()
+	m.sections = []section{
+		browserSection(cfg),
+		profileSection(cfg),
+		ruleSection(cfg),
+		shortenerSection(cfg),
+		historySection(&m.history),
+	}
+	return m
+}
+
+// Run starts the full-screen interface and blocks until the user quits.
+func Run(cfg *config.Config, save SaveFunc, historyPath string) error {
+	_, err := tea.NewProgram(New(cfg, save, historyPath), tea.WithAltScreen()).Run()
+	return err
+}
+
+func (m *Model) loadHistory() {
+	if m.historyPath == "" {
+		return
+	}
+	entries, err := history.Load(m.historyPath)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to load launch history")
+	}
+	m.history = entries
+}
+
+func (m *Model) isTestTab() bool { return m.tab == len(m.sections) }
+
+func (m *Model) current() *section {
+	if m.isTestTab() {
+		return nil
+	}
+	return &m.sections[m.tab]
+}
+
+// Init implements tea.Model.
+func (m *Model) Init() tea.Cmd { return nil }
+
+// Update implements tea.Model.
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+	case tea.KeyMsg:
+		if msg.Type == tea.KeyCtrlC {
+			return m, tea.Quit
+		}
+		switch m.mode {
+		case modeEdit:
+			return m.updateEdit(msg)
+		case modeConfirmQuit:
+			switch msg.String() {
+			case "y", "Y":
+				return m, tea.Quit
+			case "s", "S":
+				if m.saveConfig() {
+					return m, tea.Quit
+				}
+			}
+			m.mode = modeBrowse
+			return m, nil
+		}
+		if m.isTestTab() {
+			return m.updateTest(msg)
+		}
+		return m.updateBrowse(msg)
+	}
+	return m, nil
+}
+
+func (m *Model) switchTab(delta int) {
+	n := len(m.sections) + 1
+	m.tab = (m.tab + delta + n) % n
+	m.cursor = 0
+	m.err = ""
+	if m.isTestTab() {
+		m.testInput.Focus()
+	} else {
+		m.testInput.Blur()
+	}
+}
+
+func (m *Model) quit() tea.Cmd {
+	if m.dirty {
+		m.mode = modeConfirmQuit
+		return nil
+	}
+	return tea.Quit
+}
+
+func (m *Model) updateBrowse(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	sec := m.current()
+	m.status, m.err = "", ""
+	switch msg.String() {
+	case "q", "esc":
+		return m, m.quit()
+	case "tab", "right", "l":
+		m.switchTab(1)
+	case "shift+tab", "left", "h":
+		m.switchTab(-1)
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < sec.count()-1 {
+			m.cursor++
+		}
+	case "enter", "e":
+		if sec.editable() && sec.count() > 0 {
+			m.startEdit(m.cursor, sec.load(m.cursor))
+		}
+	case "a":
+		if sec.editable() && sec.create != nil {
+			m.startEdit(-1, sec.create())
+		}
+	case "d":
+		if sec.remove != nil && sec.count() > 0 {
+			if err := sec.remove(m.cursor); err != nil {
+				m.err = err.Error()
+			} else {
+				m.dirty = true
+				m.status = "Deleted."
+				if m.cursor >= sec.count() && m.cursor > 0 {
+					m.cursor--
+				}
+			}
+		}
+	case "s":
+		m.saveConfig()
+	case "r":
+		m.loadHistory()
+		m.status = "History reloaded."
+	}
+	return m, nil
+}
+
+func (m *Model) saveConfig() bool {
+	if err := m.save(m.cfg); err != nil {
+		m.err = "Save failed: " + err.Error()
+		return false
+	}
+	m.dirty = false
+	m.status = "Configuration saved."
+	return true
+}
+
+func (m *Model) startEdit(index int, item any) {
+	sec := m.current()
+	m.mode = modeEdit
+	m.editIndex = index
+	m.editItem = item
+	m.focus = 0
+	m.err = ""
+	m.inputs = make([]textinput.Model, len(sec.fields))
+	for i, f := range sec.fields {
+		in := textinput.New()
+		in.Prompt = fmt.Sprintf("%-14s ", f.label+":")
+		in.SetValue(f.get(item))
+		m.inputs[i] = in
+	}
+	m.inputs[0].Focus()
+}
+
+func (m *Model) moveFocus(delta int) {
+	m.inputs[m.focus].Blur()
+	m.focus = (m.focus + delta + len(m.inputs)) % len(m.inputs)
+	m.inputs[m.focus].Focus()
+}
+
+func (m *Model) updateEdit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeBrowse
+		m.status = "Edit cancelled."
+		return m, nil
+	case "tab", "down":
+		m.moveFocus(1)
+		return m, nil
+	case "shift+tab", "up":
+		m.moveFocus(-1)
+		return m, nil
+	case "enter", "ctrl+s":
+		if msg.String() == "enter" && m.focus < len(m.inputs)-1 {
+			m.moveFocus(1)
+			return m, nil
+		}
+		m.applyEdit()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.inputs[m.focus], cmd = m.inputs[m.focus].Update(msg)
+	return m, cmd
+}
+
+func (m *Model) applyEdit() {
+	sec := m.current()
+	for i, f := range sec.fields {
+		if err := f.set(m.editItem, strings.TrimSpace(m.inputs[i].Value())); err != nil {
+			m.err = fmt.Sprintf("%s: %v", f.label, err)
+			return
+		}
+	}
+	if err := sec.store(m.editIndex, m.editItem); err != nil {
+		m.err = err.Error()
+		return
+	}
+	if m.editIndex < 0 {
+		m.cursor = sec.count() - 1
+	}
+	m.dirty = true
+	m.mode = modeBrowse
+	m.status = "Changes applied (press s to save)."
+}
+
+func (m *Model) updateTest(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		return m, m.quit()
+	case "tab":
+		m.switchTab(1)
+		return m, nil
+	case "shift+tab":
+		m.switchTab(-1)
+		return m, nil
+	case "enter":
+		m.testResult = testURL(m.cfg, m.testInput.Value())
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.testInput, cmd = m.testInput.Update(msg)
+	return m, cmd
+}
+
+// View implements tea.Model.
+func (m *Model) View() string {
+	var b strings.Builder
+
+	// Tabs
+	tabs := make([]string, 0, len(m.sections)+1)
+	for i, s := range m.sections {
+		tabs = append(tabs, m.renderTab(i, s.title))
+	}
+	tabs = append(tabs, m.renderTab(len(m.sections), "Test URL"))
+	b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, tabs...))
+	if m.dirty {
+		b.WriteString("  [modified]")
+	}
+	b.WriteString("\n\n")
+
+	switch {
+	case m.mode == modeEdit:
+		title := "Edit"
+		if m.editIndex < 0 {
+			title = "Add"
+		}
+		fmt.Fprintf(&b, "%s %s\n\n", title, strings.TrimSuffix(m.current().title, "s"))
+		for _, in := range m.inputs {
+			b.WriteString(in.View() + "\n")
+		}
+	case m.isTestTab():
+		b.WriteString(m.testInput.View() + "\n\n")
+		b.WriteString(m.testResult + "\n")
+	default:
+		b.WriteString(m.renderTable(m.current()))
+	}
+
+	b.WriteString("\n")
+	if m.err != "" {
+		b.WriteString(errorStyle.Render(m.err) + "\n")
+	} else if m.status != "" {
+		b.WriteString(m.status + "\n")
+	}
+	b.WriteString(helpStyle.Render(m.help()) + "\n")
+	return b.String()
+}
+
+func (m *Model) renderTab(i int, title string) string {
+	if i == m.tab {
+		return activeTabStyle.Render(title)
+	}
+	return tabStyle.Render(title)
+}
+
+func (m *Model) help() string {
+	switch {
+	case m.mode == modeConfirmQuit:
+		return "Unsaved changes: y quit without saving • s save and quit • any other key to cancel"
+	case m.mode == modeEdit:
+		return "tab/↑↓ move • enter next/apply • ctrl+s apply • esc cancel"
+	case m.isTestTab():
+		return "enter test URL • tab/shift+tab switch tab • esc quit"
+	}
+	sec := m.current()
+	keys := []string{"←→ tabs", "↑↓ select"}
+	if sec.editable() {
+		keys = append(keys, "enter edit", "a add", "d delete", "s save")
+	} else {
+		keys = append(keys, "r reload")
+	}
+	return strings.Join(append(keys, "q quit"), " • ")
+}
+
+// renderTable renders the section as padded columns with the cursor row highlighted.
+func (m *Model) renderTable(sec *section) string {
+	n := sec.count()
+	if n == 0 {
+		return "(none)\n"
+	}
+
+	const maxCol = 60
+	widths := make([]int, len(sec.columns))
+	rows := make([][]string, n)
+	for c, h := range sec.columns {
+		widths[c] = len(h)
+	}
+	for i := 0; i < n; i++ {
+		rows[i] = sec.row(i)
+		for c, v := range rows[i] {
+			if r := []rune(v); len(r) > maxCol {
+				v = string(r[:maxCol-1]) + "…"
+				rows[i][c] = v
+			}
+			if w := lipgloss.Width(v); w > widths[c] {
+				widths[c] = w
+			}
+		}
+	}
+
+	format := func(cells []string) string {
+		parts := make([]string, len(cells))
+		for c, v := range cells {
+			parts[c] = v + strings.Repeat(" ", widths[c]-lipgloss.Width(v))
+		}
+		return strings.Join(parts, "  ")
+	}
+
+	var b strings.Builder
+	b.WriteString("  " + headerStyle.Render(format(sec.columns)) + "\n")
+	for i, r := range rows {
+		line := format(r)
+		if i == m.cursor {
+			b.WriteString(selectedStyle.Render("> "+line) + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	return b.String()
+}
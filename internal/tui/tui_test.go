@@ -0,0 +1,134 @@
+package tui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		DefaultProfileID: "personal",
+		Browsers:         []config.Browser{{Name: "Test", BrowserID: "test", Executable: "/bin/echo"}},
+		Profiles: []config.Profile{
+			{ID: "personal", Name: "Personal", BrowserID: "test"},
+			{ID: "work", Name: "Work", BrowserID: "test"},
+		},
+		Rules: []config.Rule{
+			{Name: "Work", Pattern: `^work\.example\.com$`, Scope: config.ScopeDomain, ProfileID: "work"},
+		},
+	}
+}
+
+func key(s string) tea.KeyMsg {
+	switch s {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	case "tab":
+		return tea.KeyMsg{Type: tea.KeyTab}
+	case "shift+tab":
+		return tea.KeyMsg{Type: tea.KeyShiftTab}
+	case "ctrl+s":
+		return tea.KeyMsg{Type: tea.KeyCtrlS}
+	case "ctrl+u":
+		return tea.KeyMsg{Type: tea.KeyCtrlU}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func send(m *Model, keys ...string) {
+	for _, k := range keys {
+		m.Update(key(k))
+	}
+}
+
+func TestEditRuleAndSave(t *testing.T) {
+	cfg := testConfig()
+	var saved int
+	m := New(cfg, func(*config.Config) error { saved++; return nil }, "")
+
+	send(m, "tab", "tab") // Rules tab
+	require.Equal(t, "Rules", m.current().title)
+	assert.Contains(t, m.View(), `^work\.example\.com$`)
+
+	// Edit the rule's pattern (second field) and apply
+	send(m, "enter", "tab", "ctrl+u", `^intranet\.example\.com$`, "ctrl+s")
+	assert.Equal(t, modeBrowse, m.mode, m.err)
+	assert.Equal(t, `^intranet\.example\.com$`, cfg.Rules[0].Pattern)
+	assert.True(t, m.dirty)
+
+	send(m, "s")
+	assert.Equal(t, 1, saved)
+	assert.False(t, m.dirty)
+}
+
+func TestAddRuleValidation(t *testing.T) {
+	cfg := testConfig()
+	m := New(cfg, func(*config.Config) error { return nil }, "")
+	send(m, "tab", "tab", "a")
+	require.Equal(t, modeEdit, m.mode)
+
+	// Invalid regex is rejected and the form stays open
+	send(m, "Bad", "tab", "(", "ctrl+s")
+	assert.Equal(t, modeEdit, m.mode)
+	assert.Contains(t, m.err, "invalid pattern")
+	assert.Len(t, cfg.Rules, 1)
+
+	// Cancelling leaves the config untouched
+	send(m, "esc")
+	assert.Equal(t, modeBrowse, m.mode)
+	assert.Len(t, cfg.Rules, 1)
+}
+
+func TestDeleteProfileInUse(t *testing.T) {
+	cfg := testConfig()
+	m := New(cfg, func(*config.Config) error { return nil }, "")
+	send(m, "tab") // Profiles
+	send(m, "d")   // Default profile
+	assert.Contains(t, m.err, "default profile")
+	send(m, "j", "d") // Used by rule
+	assert.Contains(t, m.err, "used by rule")
+	assert.Len(t, cfg.Profiles, 2)
+
+	// After deleting the rule, the profile can be deleted
+	send(m, "tab", "d", "shift+tab", "j", "d")
+	assert.Empty(t, m.err)
+	assert.Len(t, cfg.Profiles, 1)
+}
+
+func TestQuitWithUnsavedChanges(t *testing.T) {
+	cfg := testConfig()
+	m := New(cfg, func(*config.Config) error { return nil }, "")
+	send(m, "tab", "tab", "d")
+	require.True(t, m.dirty)
+
+	_, cmd := m.Update(key("q"))
+	assert.Nil(t, cmd)
+	assert.Equal(t, modeConfirmQuit, m.mode)
+
+	_, cmd = m.Update(key("y"))
+	assert.NotNil(t, cmd)
+}
+
+func TestTestURLTab(t *testing.T) {
+	cfg := testConfig()
+	m := New(cfg, func(*config.Config) error { return nil }, "")
+	send(m, "tab", "tab", "tab", "tab", "tab") // Browsers -> ... -> Test URL
+	require.True(t, m.isTestTab())
+
+	send(m, "https://work.example.com/x", "enter")
+	assert.Contains(t, m.testResult, "Matched rule: Work")
+	assert.Contains(t, m.testResult, "work")
+	assert.NotContains(t, m.testResult, "Shadowed")
+
+	// Other matching rules are listed as shadowed by the one that fires
+	cfg.Rules = append(cfg.Rules, config.Rule{Name: "Example", Pattern: `example\.com$`, Scope: config.ScopeDomain, ProfileID: "personal"})
+	send(m, "enter")
+	assert.Contains(t, m.testResult, "Matched rule: Work")
+	assert.Contains(t, m.testResult, "Shadowed:     Example")
+}
@@ -0,0 +1,65 @@
+package update
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// SignatureAsset is the minisign signature of ChecksumsAsset.
+const SignatureAsset = ChecksumsAsset + ".minisig"
+
+// PublicKey is the minisign public key (the base64 line of minisign.pub) that release
+// checksums are signed with. It is set at build time for release builds; builds
+// without it cannot verify releases and refuse to self-update unless told to skip
+// the signature check.
+var PublicKey = ""
+
+const (
+	minisignAlgorithm = "Ed" // Legacy (non-prehashed) Ed25519, produced by "minisign -S -l"
+	trustedPrefix     = "trusted comment: "
+)
+
+// VerifySignature checks that sig, the contents of a minisign signature file, is a
+// valid signature of message by publicKey. Only legacy (minisign -l) signatures are
+// supported.
+func VerifySignature(publicKey string, message, sig []byte) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(key) != 2+8+ed25519.PublicKeySize || string(key[:2]) != minisignAlgorithm {
+		return fmt.Errorf("invalid minisign public key")
+	}
+	keyID, pub := key[2:10], ed25519.PublicKey(key[10:])
+
+	lines := strings.Split(strings.ReplaceAll(string(sig), "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], trustedPrefix) {
+		return fmt.Errorf("malformed signature file")
+	}
+	sigBytes, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(sigBytes) != 2+8+ed25519.SignatureSize {
+		return fmt.Errorf("malformed signature")
+	}
+	if alg := string(sigBytes[:2]); alg != minisignAlgorithm {
+		return fmt.Errorf("unsupported signature algorithm '%s' (sign with minisign -l)", alg)
+	}
+	if !bytes.Equal(sigBytes[2:10], keyID) {
+		return fmt.Errorf("signature was made with a different key")
+	}
+	signature := sigBytes[10:]
+	if !ed25519.Verify(pub, message, signature) {
+		return fmt.Errorf("signature verification failed")
+	}
+
+	// The global signature covers the signature and trusted comment, so the comment
+	// cannot be altered either
+	globalSig, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return fmt.Errorf("malformed global signature")
+	}
+	trusted := strings.TrimPrefix(lines[2], trustedPrefix)
+	if !ed25519.Verify(pub, append(append([]byte{}, signature...), trusted...), globalSig) {
+		return fmt.Errorf("trusted comment signature verification failed")
+	}
+	return nil
+}
@@ -0,0 +1,380 @@
+// Package update checks GitHub releases for newer versions of rurl and replaces
+// the running binary with a verified release build.
+package update
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultAPIURL is the GitHub API endpoint for the latest rurl release.
+	DefaultAPIURL = "https://api.github.com/repos/jmylchreest/rurl/releases/latest"
+	// ChecksumsAsset is the release asset listing SHA-256 sums of the archives.
+	ChecksumsAsset = "checksums.txt"
+
+	binaryName = "rurl"
+	maxBinary  = 200 << 20 // Refuse to extract anything larger than this
+)
+
+// Asset is a downloadable file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Release describes a published GitHub release.
+type Release struct {
+	TagName string  `json:"tag_name"`
+	HTMLURL string  `json:"html_url"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Version returns the release version without a leading "v".
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.TagName, "v")
+}
+
+// FindAsset returns the asset with the given name, or nil.
+func (r *Release) FindAsset(name string) *Asset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// Client talks to the release API. The zero value is not usable; use NewClient.
+type Client struct {
+	APIURL     string
+	HTTPClient *http.Client
+	PublicKey  string // minisign key checksums.txt must be signed with
+	// SkipSignature installs releases verified only by checksums.txt, which does not
+	// protect against a compromised release. It is meant for builds without PublicKey.
+	SkipSignature bool
+}
+
+// NewClient creates a Client for the official rurl releases.
+func NewClient(timeout time.Duration) *Client {
+	return &Client{
+		APIURL:     DefaultAPIURL,
+		HTTPClient: &http.Client{Timeout: timeout},
+		PublicKey:  PublicKey,
+	}
+}
+
+// LatestRelease fetches metadata for the most recent release.
+func (c *Client) LatestRelease() (*Release, error) {
+	req, err := http.NewRequest(http.MethodGet, c.APIURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "rurl-update-check")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch latest release: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch latest release: unexpected status %s", resp.Status)
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to decode release metadata: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("release metadata has no tag name")
+	}
+	return &release, nil
+}
+
+// download fetches url into memory, limited to maxBinary bytes.
+func (c *Client) download(url string) ([]byte, error) {
+	resp, err := c.HTTPClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: unexpected status %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBinary+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	if len(data) > maxBinary {
+		return nil, fmt.Errorf("download %s exceeds size limit", url)
+	}
+	return data, nil
+}
+
+// ArchiveName returns the release archive name for a platform, matching the
+// goreleaser name template (e.g. rurl_Linux_x86_64.tar.gz).
+func ArchiveName(goos, goarch string) string {
+	osName := strings.ToUpper(goos[:1]) + goos[1:]
+	arch := goarch
+	if arch == "amd64" {
+		arch = "x86_64"
+	}
+	ext := ".tar.gz"
+	if goos == "windows" {
+		ext = ".zip"
+	}
+	return fmt.Sprintf("%s_%s_%s%s", binaryName, osName, arch, ext)
+}
+
+// IsNewer reports whether version latest is newer than current.
+// Versions are compared numerically by dotted component; a "dev" or otherwise
+// unparsable current version is never considered outdated.
+func IsNewer(latest, current string) bool {
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	for i := 0; i < len(l) || i < len(c); i++ {
+		var lv, cv int
+		if i < len(l) {
+			lv = l[i]
+		}
+		if i < len(c) {
+			cv = c[i]
+		}
+		if lv != cv {
+			return lv > cv
+		}
+	}
+	return false
+}
+
+// parseVersion splits "v1.2.3-rc1" into [1 2 3], ignoring pre-release/build suffixes.
+func parseVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return nil, false
+	}
+	parts := strings.Split(v, ".")
+	nums := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, false
+		}
+		nums[i] = n
+	}
+	return nums, true
+}
+
+// parseChecksums reads a goreleaser checksums.txt ("<sha256>  <filename>" per line).
+func parseChecksums(data []byte) map[string]string {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+		}
+	}
+	return sums
+}
+
+// DownloadBinary downloads the archive for the current platform, verifies it against
+// the release's signed checksums.txt and returns the extracted rurl binary.
+func (c *Client) DownloadBinary(release *Release) ([]byte, error) {
+	return c.downloadBinary(release, runtime.GOOS, runtime.GOARCH)
+}
+
+func (c *Client) downloadBinary(release *Release, goos, goarch string) ([]byte, error) {
+	archiveName := ArchiveName(goos, goarch)
+	archive := release.FindAsset(archiveName)
+	if archive == nil {
+		return nil, fmt.Errorf("release %s has no build for %s/%s (expected %s)", release.TagName, goos, goarch, archiveName)
+	}
+	checksums := release.FindAsset(ChecksumsAsset)
+	if checksums == nil {
+		return nil, fmt.Errorf("release %s has no %s; refusing to install an unverified binary", release.TagName, ChecksumsAsset)
+	}
+
+	sumData, err := c.download(checksums.URL)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.verifyChecksums(release, sumData); err != nil {
+		return nil, err
+	}
+	expected, ok := parseChecksums(sumData)[archiveName]
+	if !ok {
+		return nil, fmt.Errorf("%s has no entry for %s", ChecksumsAsset, archiveName)
+	}
+
+	data, err := c.download(archive.URL)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", archiveName, expected, actual)
+	}
+	log.Debug().Str("archive", archiveName).Str("sha256", expected).Msg("Release archive checksum verified")
+
+	exeName := binaryName
+	if goos == "windows" {
+		exeName += ".exe"
+	}
+	if strings.HasSuffix(archiveName, ".zip") {
+		return extractZip(data, exeName)
+	}
+	return extractTarGz(data, exeName)
+}
+
+// verifyChecksums checks the release's signature over checksums.txt, so a release
+// whose archives and checksums were both replaced is still rejected.
+func (c *Client) verifyChecksums(release *Release, sumData []byte) error {
+	if c.SkipSignature {
+		log.Warn().Str("release", release.TagName).Msg("Skipping signature verification of release checksums")
+		return nil
+	}
+	if c.PublicKey == "" {
+		return fmt.Errorf("this build has no release signing key and cannot verify %s; reinstall from a release or skip the signature check", ChecksumsAsset)
+	}
+	signature := release.FindAsset(SignatureAsset)
+	if signature == nil {
+		return fmt.Errorf("release %s has no %s; refusing to install an unverified binary", release.TagName, SignatureAsset)
+	}
+	sigData, err := c.download(signature.URL)
+	if err != nil {
+		return err
+	}
+	if err := VerifySignature(c.PublicKey, sumData, sigData); err != nil {
+		return fmt.Errorf("%s of release %s: %w", ChecksumsAsset, release.TagName, err)
+	}
+	log.Debug().Str("release", release.TagName).Msg("Release checksums signature verified")
+	return nil
+}
+
+// extractTarGz returns the contents of the file named name from a .tar.gz archive.
+func extractTarGz(data []byte, name string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeReg && filepath.Base(hdr.Name) == name {
+			return readLimited(tr)
+		}
+	}
+	return nil, fmt.Errorf("archive does not contain %s", name)
+}
+
+// extractZip returns the contents of the file named name from a .zip archive.
+func extractZip(data []byte, name string) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	for _, f := range zr.File {
+		if filepath.Base(f.Name) != name || f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from archive: %w", name, err)
+		}
+		defer rc.Close()
+		return readLimited(rc)
+	}
+	return nil, fmt.Errorf("archive does not contain %s", name)
+}
+
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBinary+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract binary: %w", err)
+	}
+	if len(data) > maxBinary {
+		return nil, fmt.Errorf("extracted binary exceeds size limit")
+	}
+	return data, nil
+}
+
+// ReplaceExecutable atomically replaces the file at exePath with binary.
+// The new binary is written next to the old one and renamed into place; the old
+// binary is first moved aside (required on Windows, where a running executable
+// cannot be overwritten) and removed where possible.
+func ReplaceExecutable(exePath string, binary []byte) error {
+	info, err := os.Stat(exePath)
+	if err != nil {
+		return fmt.Errorf("failed to stat current executable: %w", err)
+	}
+
+	dir := filepath.Dir(exePath)
+	tmp, err := os.CreateTemp(dir, ".rurl-update-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file in %s (is it writable?): %w", dir, err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // No-op once renamed
+
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write new binary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write new binary: %w", err)
+	}
+	if err := os.Chmod(tmpPath, info.Mode().Perm()|0o111); err != nil {
+		return fmt.Errorf("failed to set permissions on new binary: %w", err)
+	}
+
+	oldPath := exePath + ".old"
+	_ = os.Remove(oldPath)
+	if err := os.Rename(exePath, oldPath); err != nil {
+		return fmt.Errorf("failed to move current binary aside: %w", err)
+	}
+	if err := os.Rename(tmpPath, exePath); err != nil {
+		// Try to put the original back
+		_ = os.Rename(oldPath, exePath)
+		return fmt.Errorf("failed to install new binary: %w", err)
+	}
+	if err := os.Remove(oldPath); err != nil {
+		log.Debug().Err(err).Str("path", oldPath).Msg("Could not remove previous binary (it may still be running)")
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package update
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsNewer(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"1.2.0", "1.1.9", true},
+		{"v1.10.0", "1.9.0", true},
+		{"1.2", "1.2.0", false},
+		{"1.2.0", "1.2.0", false},
+		{"1.1.0", "1.2.0", false},
+		{"1.3.0-rc1", "1.2.0", true},
+		{"1.2.0", "dev", false},
+		{"garbage", "1.0.0", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, IsNewer(tt.latest, tt.current), "IsNewer(%q, %q)", tt.latest, tt.current)
+	}
+}
+
+func TestArchiveName(t *testing.T) {
+	assert.Equal(t, "rurl_Linux_x86_64.tar.gz", ArchiveName("linux", "amd64"))
+	assert.Equal(t, "rurl_Darwin_arm64.tar.gz", ArchiveName("darwin", "arm64"))
+	assert.Equal(t, "rurl_Windows_x86_64.zip", ArchiveName("windows", "amd64"))
+}
+
+func makeTarGz(t *testing.T, name string, content []byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "README.md", Mode: 0644, Size: 2, Typeflag: tar.TypeReg}))
+	_, _ = tw.Write([]byte("hi"))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, _ = tw.Write(content)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+// testKey returns a minisign public key line and a function signing like "minisign -S -l".
+func testKey(t *testing.T) (string, func(message []byte) []byte) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	keyID := make([]byte, 8)
+	_, err = rand.Read(keyID)
+	require.NoError(t, err)
+	publicKey := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), pub...))
+
+	sign := func(message []byte) []byte {
+		sig := ed25519.Sign(priv, message)
+		trusted := "timestamp:1700000000\tfile:checksums.txt"
+		global := ed25519.Sign(priv, append(append([]byte{}, sig...), trusted...))
+		return []byte("untrusted comment: signature from minisign secret key\n" +
+			base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), sig...)) + "\n" +
+			"trusted comment: " + trusted + "\n" +
+			base64.StdEncoding.EncodeToString(global) + "\n")
+	}
+	return publicKey, sign
+}
+
+// newReleaseServer serves release metadata, an archive, checksums.txt and its signature.
+func newReleaseServer(t *testing.T, archiveName string, archive []byte, checksum string, sign func([]byte) []byte) *httptest.Server {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	checksums := []byte(checksum + "  " + archiveName + "\n")
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(Release{
+			TagName: "v9.9.9",
+			Assets: []Asset{
+				{Name: archiveName, URL: srv.URL + "/archive"},
+				{Name: ChecksumsAsset, URL: srv.URL + "/checksums"},
+				{Name: SignatureAsset, URL: srv.URL + "/signature"},
+			},
+		})
+	})
+	mux.HandleFunc("/archive", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write(archive) })
+	mux.HandleFunc("/checksums", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write(checksums) })
+	mux.HandleFunc("/signature", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write(sign(checksums)) })
+	return srv
+}
+
+func TestDownloadBinary(t *testing.T) {
+	archiveName := ArchiveName("linux", "amd64")
+	archive := makeTarGz(t, "rurl", []byte("new-binary"))
+	sum := sha256.Sum256(archive)
+	publicKey, sign := testKey(t)
+
+	srv := newReleaseServer(t, archiveName, archive, hex.EncodeToString(sum[:]), sign)
+	client := &Client{APIURL: srv.URL + "/latest", HTTPClient: srv.Client(), PublicKey: publicKey}
+
+	release, err := client.LatestRelease()
+	require.NoError(t, err)
+	assert.Equal(t, "9.9.9", release.Version())
+
+	binary, err := client.downloadBinary(release, "linux", "amd64")
+	require.NoError(t, err)
+	assert.Equal(t, "new-binary", string(binary))
+
+	// Missing platform build
+	_, err = client.downloadBinary(release, "linux", "riscv64")
+	assert.Error(t, err)
+
+	// Checksums signed with another key are rejected
+	otherKey, _ := testKey(t)
+	client.PublicKey = otherKey
+	_, err = client.downloadBinary(release, "linux", "amd64")
+	assert.ErrorContains(t, err, "different key")
+
+	// Builds without a key refuse to update unless the check is skipped
+	client.PublicKey = ""
+	_, err = client.downloadBinary(release, "linux", "amd64")
+	assert.ErrorContains(t, err, "no release signing key")
+	client.SkipSignature = true
+	_, err = client.downloadBinary(release, "linux", "amd64")
+	assert.NoError(t, err)
+}
+
+func TestDownloadBinaryChecksumMismatch(t *testing.T) {
+	archiveName := ArchiveName("linux", "amd64")
+	archive := makeTarGz(t, "rurl", []byte("tampered"))
+	publicKey, sign := testKey(t)
+
+	srv := newReleaseServer(t, archiveName, archive, "0000000000000000000000000000000000000000000000000000000000000000", sign)
+	client := &Client{APIURL: srv.URL + "/latest", HTTPClient: srv.Client(), PublicKey: publicKey}
+
+	release, err := client.LatestRelease()
+	require.NoError(t, err)
+	_, err = client.downloadBinary(release, "linux", "amd64")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum mismatch")
+}
+
+func TestVerifySignature(t *testing.T) {
+	publicKey, sign := testKey(t)
+	message := []byte("abc  rurl_Linux_x86_64.tar.gz\n")
+	sig := sign(message)
+
+	assert.NoError(t, VerifySignature(publicKey, message, sig))
+	assert.ErrorContains(t, VerifySignature(publicKey, []byte("tampered"), sig), "verification failed")
+
+	// Altering the trusted comment invalidates the global signature
+	altered := bytes.Replace(sig, []byte("timestamp:1700000000"), []byte("timestamp:1800000000"), 1)
+	assert.ErrorContains(t, VerifySignature(publicKey, message, altered), "trusted comment")
+
+	assert.ErrorContains(t, VerifySignature("not-a-key", message, sig), "invalid minisign public key")
+	assert.ErrorContains(t, VerifySignature(publicKey, message, []byte("garbage")), "malformed")
+}
+
+func TestExtractZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("rurl.exe")
+	require.NoError(t, err)
+	_, _ = w.Write([]byte("win-binary"))
+	require.NoError(t, zw.Close())
+
+	data, err := extractZip(buf.Bytes(), "rurl.exe")
+	require.NoError(t, err)
+	assert.Equal(t, "win-binary", string(data))
+
+	_, err = extractZip(buf.Bytes(), "missing")
+	assert.Error(t, err)
+}
+
+func TestReplaceExecutable(t *testing.T) {
+	exe := filepath.Join(t.TempDir(), "rurl")
+	require.NoError(t, os.WriteFile(exe, []byte("old"), 0755))
+
+	require.NoError(t, ReplaceExecutable(exe, []byte("new")))
+
+	data, err := os.ReadFile(exe)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+	_, err = os.Stat(exe + ".old")
+	assert.True(t, os.IsNotExist(err))
+}
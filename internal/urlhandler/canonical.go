@@ -0,0 +1,94 @@
+package urlhandler
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// mobileHostSuffixes lists sites whose mobile variants live on a ".m." subdomain
+// (e.g. en.m.wikipedia.org) that can be rewritten to the desktop host.
+var mobileHostSuffixes = []string{
+	".m.wikipedia.org",
+}
+
+// ampCacheSuffix is the host suffix of the Google AMP cache.
+const ampCacheSuffix = ".cdn.ampproject.org"
+
+// CanonicalizeURL rewrites AMP and mobile variants of a URL to the canonical page:
+//   - https://en.m.wikipedia.org/wiki/Go -> https://en.wikipedia.org/wiki/Go
+//   - https://www.google.com/amp/s/example.com/a -> https://example.com/a
+//   - https://example-com.cdn.ampproject.org/c/s/example.com/a -> https://example.com/a
+//
+// URLs that are not http(s) or do not match a known pattern are returned unchanged.
+func CanonicalizeURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return rawURL
+	}
+
+	result := rawURL
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case isGoogleHost(host) && strings.HasPrefix(u.Path, "/amp/"):
+		result = unwrapAMPPath(strings.TrimPrefix(u.Path, "/amp/"), u)
+	case strings.HasSuffix(host, ampCacheSuffix):
+		// Cache paths are /<type>/[s/]<host>/<path>, where type is c, v, i, wp, etc.
+		if parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2); len(parts) == 2 {
+			result = unwrapAMPPath(parts[1], u)
+		}
+	default:
+		for _, suffix := range mobileHostSuffixes {
+			if strings.HasSuffix(host, suffix) {
+				desktop := *u
+				desktop.Host = strings.TrimSuffix(host, suffix) + strings.TrimPrefix(suffix, ".m")
+				if port := u.Port(); port != "" {
+					desktop.Host += ":" + port
+				}
+				result = desktop.String()
+				break
+			}
+		}
+	}
+
+	if result != rawURL {
+		log.Debug().Str("from", rawURL).Str("to", result).Msg("Canonicalized URL")
+	}
+	return result
+}
+
+// googleHostRegex matches Google search hosts: google.<tld>, google.com.<cc> and
+// google.co.<cc>, optionally with "www.". Anything else, such as google.evil.com,
+// is not Google.
+var googleHostRegex = regexp.MustCompile(`^(?:www\.)?google\.(?:[a-z]{2,3}|(?:com|co)\.[a-z]{2})$`)
+
+// isGoogleHost reports whether host is a Google search host.
+func isGoogleHost(host string) bool {
+	return googleHostRegex.MatchString(host)
+}
+
+// unwrapAMPPath converts an AMP path of the form "[s/]host/path" to a URL, keeping
+// the wrapper's query and fragment. A leading "s/" denotes https.
+// The wrapper URL is returned unchanged if no target host is present.
+func unwrapAMPPath(p string, wrapper *url.URL) string {
+	scheme := "http"
+	if strings.HasPrefix(p, "s/") {
+		scheme = "https"
+		p = strings.TrimPrefix(p, "s/")
+	}
+	host, path, _ := strings.Cut(p, "/")
+	if host == "" || !strings.Contains(host, ".") {
+		return wrapper.String()
+	}
+
+	target := url.URL{
+		Scheme:   scheme,
+		Host:     host,
+		Path:     "/" + path,
+		RawQuery: wrapper.RawQuery,
+		Fragment: wrapper.Fragment,
+	}
+	return target.String()
+}
@@ -0,0 +1,49 @@
+package urlhandler
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// zoomMeetingPath matches the path of a Zoom meeting or webinar join link.
+var zoomMeetingPath = regexp.MustCompile(`^/[jw]/(\d+)/?$`)
+
+// DeepLinkURL converts a meeting web link to the deep link of the meeting's native app,
+// which the operating system hands to the app instead of opening a browser:
+//   - https://zoom.us/j/123456789?pwd=abc -> zoommtg://zoom.us/join?action=join&confno=123456789&pwd=abc
+//   - https://teams.microsoft.com/l/meetup-join/... -> msteams:/l/meetup-join/...
+//
+// Company subdomains (e.g. example.zoom.us) keep their host. It returns "" for URLs
+// that are not meeting links of a known app.
+func DeepLinkURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case host == "zoom.us" || strings.HasSuffix(host, ".zoom.us"):
+		m := zoomMeetingPath.FindStringSubmatch(u.Path)
+		if m == nil {
+			return ""
+		}
+		query := url.Values{"action": {"join"}, "confno": {m[1]}}
+		for _, key := range []string{"pwd", "tk", "uname"} {
+			if v := u.Query().Get(key); v != "" {
+				query.Set(key, v)
+			}
+		}
+		return (&url.URL{Scheme: "zoommtg", Host: host, Path: "/join", RawQuery: query.Encode()}).String()
+	case host == "teams.microsoft.com" || host == "teams.live.com":
+		if !strings.HasPrefix(u.EscapedPath(), "/l/") {
+			return ""
+		}
+		link := "msteams:" + u.EscapedPath()
+		if u.RawQuery != "" {
+			link += "?" + u.RawQuery
+		}
+		return link
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+package urlhandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DNS record types requested from DoH resolvers.
+const (
+	dnsTypeA    = 1
+	dnsTypeAAAA = 28
+)
+
+// dohClient makes the requests to DoH resolvers. Their own hostname is looked up with
+// the system resolver, unless the resolver URL uses an IP address.
+var dohClient = &http.Client{Timeout: 5 * time.Second}
+
+// dohResolver looks up hostnames with DNS over HTTPS, using the JSON API served by
+// public resolvers (e.g. https://cloudflare-dns.com/dns-query or
+// https://dns.google/resolve).
+type dohResolver struct {
+	endpoint string
+}
+
+// newDoHResolver returns a resolver for the DoH endpoint, which must be an https URL.
+func newDoHResolver(endpoint string) (*dohResolver, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid DNS over HTTPS URL '%s': an https URL is required", endpoint)
+	}
+	return &dohResolver{endpoint: endpoint}, nil
+}
+
+// dohResponse is the part of a DoH JSON response used.
+type dohResponse struct {
+	Status int `json:"Status"` // DNS response code; 0 is NOERROR
+	Answer []struct {
+		Type int    `json:"type"`
+		Data string `json:"data"`
+	} `json:"Answer"`
+}
+
+// lookupIP returns the IPv4 addresses of host or, if it has none, its IPv6 addresses.
+func (r *dohResolver) lookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	for _, recordType := range []int{dnsTypeA, dnsTypeAAAA} {
+		ips, err := r.query(ctx, host, recordType)
+		if err != nil {
+			return nil, err
+		}
+		if len(ips) > 0 {
+			return ips, nil
+		}
+	}
+	return nil, fmt.Errorf("no addresses found for %s", host)
+}
+
+// query requests the records of recordType for host.
+func (r *dohResolver) query(ctx context.Context, host string, recordType int) ([]net.IP, error) {
+	u, _ := url.Parse(r.endpoint)
+	q := u.Query()
+	q.Set("name", host)
+	q.Set("type", fmt.Sprint(recordType))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DNS over HTTPS request: %w", err)
+	}
+	req.Header.Set("Accept", "application/dns-json")
+	resp, err := dohClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("DNS over HTTPS lookup of %s failed: %w", host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DNS over HTTPS lookup of %s failed with status %d", host, resp.StatusCode)
+	}
+
+	var answer dohResponse
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return nil, fmt.Errorf("failed to parse DNS over HTTPS response for %s: %w", host, err)
+	}
+	if answer.Status != 0 {
+		return nil, fmt.Errorf("DNS over HTTPS lookup of %s failed with DNS status %d", host, answer.Status)
+	}
+	var ips []net.IP
+	for _, a := range answer.Answer {
+		if a.Type != recordType {
+			continue // e.g. CNAME records leading to the addresses
+		}
+		if ip := net.ParseIP(a.Data); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// dialContext connects to addr like net.Dialer.DialContext, looking its host up with
+// r. The system resolver is never used, so hostnames are not leaked to the local network.
+func (r *dohResolver) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	var dialer net.Dialer
+	if net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	ips, err := r.lookupIP(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, ip := range ips {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,110 @@
+package urlhandler
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/jmylchreest/rurl/internal/config"
+)
+
+// maxHTMLRedirectBody is the number of bytes of a page read when looking for HTML
+// redirects. Interstitials put them in the head, so a small prefix is enough.
+const maxHTMLRedirectBody = 64 << 10
+
+var (
+	htmlTagRegex  = regexp.MustCompile(`(?i)<(meta|link)\s[^>]*>`)
+	htmlAttrRegex = regexp.MustCompile(`([a-zA-Z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	refreshURL    = regexp.MustCompile(`(?i)^\s*\d*(?:\.\d*)?\s*[;,]\s*url\s*=\s*['"]?([^'"]+)`)
+)
+
+// htmlRedirectTarget fetches pageURL and returns the absolute target of a
+// <meta http-equiv="refresh"> tag in it or, failing that, of a canonical link to a
+// different site. It returns an empty string if the page is not HTML or has neither.
+func htmlRedirectTarget(ctx context.Context, client *http.Client, cfg *config.Config, pageURL string) (string, error) {
+	req, err := newResolveRequest(ctx, cfg, "GET", pageURL)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to perform request for %s: %w", pageURL, err)
+	}
+	defer resp.Body.Close()
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return "", nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTMLRedirectBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", pageURL, err)
+	}
+
+	base, _ := url.Parse(pageURL)
+	refresh, canonical := parseHTMLRedirects(string(body))
+	if target := resolveHTMLTarget(base, refresh); target != nil && target.String() != pageURL {
+		return target.String(), nil
+	}
+	if target := resolveHTMLTarget(base, canonical); target != nil && !sameSite(base.Hostname(), target.Hostname()) {
+		return target.String(), nil
+	}
+	return "", nil
+}
+
+// parseHTMLRedirects returns the URLs of the first meta refresh and canonical link
+// tags in page, as written.
+func parseHTMLRedirects(page string) (refresh, canonical string) {
+	for _, tag := range htmlTagRegex.FindAllStringSubmatch(page, -1) {
+		attrs := make(map[string]string)
+		for _, m := range htmlAttrRegex.FindAllStringSubmatch(tag[0], -1) {
+			attrs[strings.ToLower(m[1])] = html.UnescapeString(m[2] + m[3] + m[4])
+		}
+		switch strings.ToLower(tag[1]) {
+		case "meta":
+			if refresh == "" && strings.EqualFold(attrs["http-equiv"], "refresh") {
+				if m := refreshURL.FindStringSubmatch(attrs["content"]); m != nil {
+					refresh = strings.TrimSpace(m[1])
+				}
+			}
+		case "link":
+			if canonical == "" && attrs["href"] != "" && hasToken(attrs["rel"], "canonical") {
+				canonical = attrs["href"]
+			}
+		}
+	}
+	return refresh, canonical
+}
+
+// resolveHTMLTarget resolves ref against base, and returns nil unless it is an http
+// or https URL.
+func resolveHTMLTarget(base *url.URL, ref string) *url.URL {
+	if ref == "" {
+		return nil
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return nil
+	}
+	u = base.ResolveReference(u)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+	return u
+}
+
+// hasToken reports whether the space separated list contains token (case-insensitive).
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package urlhandler
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultInspectTimeout is used when no content inspection timeout is configured.
+const defaultInspectTimeout = 5 * time.Second
+
+// ContentInfo describes the content served at a URL, as reported by a HEAD request.
+type ContentInfo struct {
+	ContentType        string // Media type without parameters (e.g. "application/pdf")
+	ContentDisposition string // Raw Content-Disposition header value
+	IsDownload         bool   // True if the response is an attachment or a non-page media type
+}
+
+// InspectURL performs a HEAD request (following redirects) against targetURL and
+// reports its content type and whether it is likely to be a download.
+func InspectURL(ctx context.Context, targetURL string, timeout time.Duration) (*ContentInfo, error) {
+	if timeout <= 0 {
+		timeout = defaultInspectTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", targetURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", targetURL, err)
+	}
+	req.Header.Set("User-Agent", "rurl/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect %s: %w", targetURL, err)
+	}
+	if resp.Body != nil {
+		resp.Body.Close()
+	}
+
+	info := &ContentInfo{
+		ContentDisposition: resp.Header.Get("Content-Disposition"),
+	}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		info.ContentType = mediaType
+	}
+	info.IsDownload = isDownload(info.ContentType, info.ContentDisposition)
+
+	log.Debug().
+		Str("url", targetURL).
+		Int("status", resp.StatusCode).
+		Str("content_type", info.ContentType).
+		Str("content_disposition", info.ContentDisposition).
+		Bool("is_download", info.IsDownload).
+		Msg("Inspected URL content")
+	return info, nil
+}
+
+// isDownload decides whether a response should be treated as a download rather than a page.
+func isDownload(contentType string, contentDisposition string) bool {
+	if disposition, _, err := mime.ParseMediaType(contentDisposition); err == nil && disposition == "attachment" {
+		return true
+	}
+	switch {
+	case contentType == "":
+		return false
+	case strings.HasPrefix(contentType, "text/"),
+		contentType == "application/xhtml+xml",
+		contentType == "application/json",
+		contentType == "application/xml":
+		return false
+	case strings.HasPrefix(contentType, "image/"),
+		strings.HasPrefix(contentType, "video/"),
+		strings.HasPrefix(contentType, "audio/"):
+		return false // Browsers display these inline
+	default:
+		return true // application/pdf, application/octet-stream, archives, etc.
+	}
+}
@@ -0,0 +1,70 @@
+package urlhandler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultLearnTimeout is used when no shortener learning timeout is configured.
+const defaultLearnTimeout = 3 * time.Second
+
+// DetectOffsiteRedirect makes a single HEAD request (not following redirects) to
+// targetURL and, if it answers with a 30x redirect to a different site, returns the
+// absolute redirect target. Redirects within the same site (e.g. http to https, or
+// example.com to www.example.com) return an empty string.
+func DetectOffsiteRedirect(ctx context.Context, targetURL string, timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		timeout = defaultLearnTimeout
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	base, err := url.Parse(targetURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", targetURL, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "HEAD", targetURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request for %s: %w", targetURL, err)
+	}
+	req.Header.Set("User-Agent", "rurl/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to check %s for redirects: %w", targetURL, err)
+	}
+	if resp.Body != nil {
+		resp.Body.Close()
+	}
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return "", nil
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.Header.Get("Location") == "" {
+		return "", nil
+	}
+	target := base.ResolveReference(location)
+	if sameSite(base.Hostname(), target.Hostname()) {
+		return "", nil
+	}
+	log.Debug().Str("url", targetURL).Str("redirect_to", target.String()).Int("status", resp.StatusCode).Msg("Detected off-site redirect")
+	return target.String(), nil
+}
+
+// sameSite reports whether two hostnames differ only by a leading "www.".
+func sameSite(a, b string) bool {
+	a = strings.TrimPrefix(strings.ToLower(a), "www.")
+	b = strings.TrimPrefix(strings.ToLower(b), "www.")
+	return a == b
+}
@@ -0,0 +1,115 @@
+package urlhandler
+
+import (
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultPorts maps schemes to the port they use when none is given.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// NormalizeURL returns rawURL in the form shortener matching and the rules see it,
+// so that equivalent spellings of a URL route the same way:
+//   - HTTPS://Example.COM./a -> https://example.com/a (host lowercased, trailing dot removed;
+//     internationalized hosts are kept as written, e.g. https://bücher.example/)
+//   - http://example.com:80/ -> http://example.com/ (default port removed)
+//   - https://example.com/%7euser/%2f?q=%e2%82%ac -> https://example.com/~user/%2F?q=%E2%82%AC
+//     (unreserved characters decoded, other escapes uppercased)
+//
+// URLs that cannot be parsed are returned unchanged.
+func NormalizeURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Opaque != "" {
+		return rawURL
+	}
+
+	if u.Host != "" {
+		host := strings.TrimRight(strings.ToLower(u.Hostname()), ".")
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]" // IPv6 literal
+		}
+		if port := u.Port(); port != "" && port != defaultPorts[u.Scheme] {
+			host += ":" + port
+		}
+		u.Host = host
+	}
+	if p := normalizePercentEncoding(u.EscapedPath()); p != u.EscapedPath() {
+		if u.Path, err = url.PathUnescape(p); err != nil {
+			return rawURL
+		}
+		u.RawPath = p
+	}
+	u.RawQuery = normalizePercentEncoding(u.RawQuery)
+	if f := normalizePercentEncoding(u.EscapedFragment()); f != u.EscapedFragment() {
+		if u.Fragment, err = url.PathUnescape(f); err != nil {
+			return rawURL
+		}
+		u.RawFragment = f
+	}
+
+	result := u.String()
+	if u.Host != "" && strings.ContainsFunc(u.Host, func(r rune) bool { return r >= utf8.RuneSelf }) {
+		// Keep internationalized hosts readable rather than percent-encoded
+		escaped := strings.TrimPrefix((&url.URL{Host: u.Host}).String(), "//")
+		result = strings.Replace(result, escaped, u.Host, 1)
+	}
+	if result != rawURL {
+		log.Debug().Str("from", rawURL).Str("to", result).Msg("Normalized URL")
+	}
+	return result
+}
+
+// normalizePercentEncoding decodes the percent-encoded unreserved characters of s
+// (letters, digits, '-', '.', '_' and '~') and uppercases the hex digits of the other
+// escapes. Malformed escapes are left as they are.
+func normalizePercentEncoding(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+// isUnreserved reports whether c is an unreserved URL character (RFC 3986), which
+// never needs escaping.
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// isHex reports whether c is a hexadecimal digit.
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// unhex returns the value of the hexadecimal digit c.
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
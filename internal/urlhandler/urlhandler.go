@@ -1,20 +1,27 @@
 package urlhandler
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
+	"strings"
 	"time"
 
+	"github.com/jmylchreest/rurl/internal/breaker"
 	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/metrics"
 	"github.com/rs/zerolog/log"
 )
 
 // ProcessURL takes an input URL string, checks if the domain matches known or
 // manually added shortener services, and resolves if necessary. It returns the final URL
 // to be used for rule matching, the original input URL, a flag indicating if the
-// original domain was marked as a safelink, and any fatal processing error.
-func ProcessURL(cfg *config.Config, inputURL string) (urlForMatching string, originalURL string, isSafelink bool, err error) {
+// original domain was marked as a safelink, and any fatal processing error. A
+// resolution failure falls back to the input URL unless ctx was cancelled.
+func ProcessURL(ctx context.Context, cfg *config.Config, inputURL string) (urlForMatching string, originalURL string, isSafelink bool, err error) {
 	originalURL = inputURL // Store the original input
 
 	// 1. Parse the input URL
@@ -33,29 +40,22 @@ func ProcessURL(cfg *config.Config, inputURL string) (urlForMatching string, ori
 	// Only attempt shortener resolution for http/https URLs
 	if parsedURL.Scheme == "http" || parsedURL.Scheme == "https" {
 		// 2. Check if the hostname matches any known (built-in or manual) shortener domain
-		// Check manual list first, then built-in
-		for i := range cfg.ManualShorteners {
-			if cfg.ManualShorteners[i].Domain == hostname {
-				matchedShortener = &cfg.ManualShorteners[i]
-				log.Debug().Str("domain", hostname).Msg("Matched manual shortener domain.")
-				break
-			}
-		}
-
-		if matchedShortener == nil { // If not found in manual list, check built-in
-			for i := range cfg.Shorteners {
-				if cfg.Shorteners[i].Domain == hostname {
-					matchedShortener = &cfg.Shorteners[i]
-					log.Debug().Str("domain", hostname).Msg("Matched built-in shortener domain.")
-					break
-				}
-			}
-		}
+		matchedShortener = FindShortener(cfg, hostname)
 
 		// 3. If a shortener domain was matched, attempt resolution
 		if matchedShortener != nil {
+			if err := acquireResolution(cfg, hostname); err != nil {
+				log.Warn().Err(err).Str("original_url", inputURL).Msg("Skipping shortener resolution, using original for matching.")
+				return inputURL, originalURL, false, nil
+			}
 			log.Info().Str("domain", hostname).Msg("Detected shortener domain, resolving...")
-			resolved, resolveErr := ResolveShortenedURL(inputURL)
+			start := time.Now()
+			resolved, resolveErr := ResolveShortenedURL(ctx, cfg, inputURL)
+			if resolveErr != nil && ctx.Err() != nil {
+				return inputURL, originalURL, false, fmt.Errorf("shortener resolution stopped: %w", context.Cause(ctx))
+			}
+			metrics.ObserveResolution(hostname, time.Since(start), resolveErr == nil)
+			reportResolution(cfg, hostname, resolveErr == nil)
 			if resolveErr != nil {
 				log.Warn().Err(resolveErr).Str("original_url", inputURL).Msg("Failed to resolve shortened URL, using original for matching.")
 				// Return original URL for matching, original input, safelink=false, nil error (non-fatal for matching)
@@ -74,37 +74,118 @@ func ProcessURL(cfg *config.Config, inputURL string) (urlForMatching string, ori
 	return inputURL, originalURL, false, nil
 }
 
-// ResolveShortenedURL attempts to follow redirects for a given URL.
-func ResolveShortenedURL(shortURL string) (string, error) {
+// breakerPath returns the file keeping the state of the resolution breaker.
+var breakerPath = breaker.DefaultPath
+
+// resolutionLimits returns the breaker limits configured in cfg.
+func resolutionLimits(cfg *config.Config) breaker.Limits {
+	return breaker.Limits{
+		MaxPerMinute:     cfg.ResolutionBreaker.MaxPerMinute,
+		FailureThreshold: cfg.ResolutionBreaker.FailureThreshold,
+		Cooldown:         time.Duration(cfg.ResolutionBreaker.CooldownSeconds) * time.Second,
+	}
+}
+
+// acquireResolution returns an error if the resolution breaker is enabled and domain
+// must not be resolved now. Failures to keep the breaker state are only logged.
+func acquireResolution(cfg *config.Config, domain string) error {
+	if !cfg.ResolutionBreaker.Enabled {
+		return nil
+	}
+	path, err := breakerPath()
+	if err == nil {
+		err = breaker.Acquire(path, domain, resolutionLimits(cfg), time.Now())
+	}
+	if errors.Is(err, breaker.ErrOpen) || errors.Is(err, breaker.ErrRateLimited) {
+		return err
+	}
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to update the resolution breaker")
+	}
+	return nil
+}
+
+// reportResolution records the outcome of resolving a URL on domain, if the resolution
+// breaker is enabled.
+func reportResolution(cfg *config.Config, domain string, ok bool) {
+	if !cfg.ResolutionBreaker.Enabled {
+		return
+	}
+	path, err := breakerPath()
+	if err == nil {
+		err = breaker.Report(path, domain, ok, resolutionLimits(cfg), time.Now())
+	}
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to update the resolution breaker")
+	}
+}
+
+// FindShortener returns the manual or built-in shortener for hostname, or nil.
+// Manual shorteners take precedence over built-in ones.
+func FindShortener(cfg *config.Config, hostname string) *config.ShortenerService {
+	for i := range cfg.ManualShorteners {
+		if cfg.ManualShorteners[i].Domain == hostname {
+			log.Debug().Str("domain", hostname).Msg("Matched manual shortener domain.")
+			return &cfg.ManualShorteners[i]
+		}
+	}
+	for i := range cfg.Shorteners {
+		if cfg.Shorteners[i].Domain == hostname {
+			log.Debug().Str("domain", hostname).Msg("Matched built-in shortener domain.")
+			return &cfg.Shorteners[i]
+		}
+	}
+	return nil
+}
+
+// ResolveShortenedURL attempts to follow redirects for a given URL. When a page on
+// the shortener itself is returned instead of a redirect, its meta refresh or
+// canonical link is followed. Requests to shortener domains in cfg (which may be nil)
+// carry their User-Agent and headers, and cookies set along the way are sent back.
+// Hostnames are looked up with the DNS over HTTPS resolver configured in cfg, if any.
+// Requests are aborted when ctx is cancelled.
+func ResolveShortenedURL(ctx context.Context, cfg *config.Config, shortURL string) (string, error) {
+	jar, _ := cookiejar.New(nil)
 	client := &http.Client{
 		Timeout: 10 * time.Second, // Add a timeout
+		Jar:     jar,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
 	}
+	if cfg != nil && cfg.ResolutionDNS.DoHURL != "" {
+		resolver, err := newDoHResolver(cfg.ResolutionDNS.DoHURL)
+		if err != nil {
+			return "", err
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = resolver.dialContext
+		client.Transport = transport
+	}
 
 	maxRedirects := 5
 	currentURL := shortURL
+	method := "HEAD"
 
 	for i := 0; i < maxRedirects; i++ {
-		req, err := http.NewRequest("HEAD", currentURL, nil)
+		req, err := newResolveRequest(ctx, cfg, method, currentURL)
 		if err != nil {
-			return "", fmt.Errorf("failed to create request for %s: %w", currentURL, err)
+			return "", err
 		}
-		req.Header.Set("User-Agent", "rurl/1.0")
 
 		resp, err := client.Do(req)
-		if err != nil {
-			// Fallback to GET if HEAD fails
-			if i == 0 { // Only try GET on the first attempt
-				log.Debug().Str("url", currentURL).Msg("HEAD request failed, falling back to GET")
-				req, _ = http.NewRequest("GET", currentURL, nil)
-				req.Header.Set("User-Agent", "rurl/1.0")
-				resp, err = client.Do(req)
-			}
-			if err != nil {
-				return "", fmt.Errorf("failed to perform request for %s: %w", currentURL, err)
+		if method == "HEAD" && ctx.Err() == nil && (err != nil || resp.StatusCode == http.StatusMethodNotAllowed) {
+			// Fallback to GET if HEAD fails or is refused
+			if resp != nil && resp.Body != nil {
+				resp.Body.Close()
 			}
+			log.Debug().Str("url", currentURL).Msg("HEAD request failed, falling back to GET")
+			method = "GET"
+			req, _ = newResolveRequest(ctx, cfg, method, currentURL)
+			resp, err = client.Do(req)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to perform request for %s: %w", currentURL, err)
 		}
 
 		if resp.Body != nil {
@@ -124,11 +205,36 @@ func ResolveShortenedURL(shortURL string) (string, error) {
 			baseReqURL, _ := url.Parse(currentURL)
 			currentURL = baseReqURL.ResolveReference(redirectURL).String()
 			log.Debug().Str("from", resp.Request.URL.String()).Str("to", currentURL).Int("status", resp.StatusCode).Msg("Following redirect")
+			// 307 and 308 require the same method to be used for the target; other
+			// redirects try HEAD again
+			if resp.StatusCode != http.StatusTemporaryRedirect && resp.StatusCode != http.StatusPermanentRedirect {
+				method = "HEAD"
+			}
 
 			if i == maxRedirects-1 {
 				return "", fmt.Errorf("too many redirects resolving %s", shortURL)
 			}
 		} else if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			// Shorteners that answer with an interstitial page instead of a 30x may
+			// redirect with a meta refresh or canonical link
+			if sameHost(currentURL, shortURL) {
+				target, err := htmlRedirectTarget(ctx, client, cfg, currentURL)
+				if err != nil && ctx.Err() != nil {
+					return "", err
+				}
+				if err != nil {
+					log.Debug().Err(err).Str("url", currentURL).Msg("Failed to check page for HTML redirects")
+				}
+				if target != "" {
+					log.Debug().Str("from", currentURL).Str("to", target).Msg("Following HTML redirect")
+					if i == maxRedirects-1 {
+						return "", fmt.Errorf("too many redirects resolving %s", shortURL)
+					}
+					currentURL = target
+					method = "HEAD"
+					continue
+				}
+			}
 			log.Debug().Str("url", currentURL).Int("status", resp.StatusCode).Msg("Resolved URL")
 			return currentURL, nil
 		} else {
@@ -138,3 +244,84 @@ func ResolveShortenedURL(shortURL string) (string, error) {
 
 	return "", fmt.Errorf("unexpected state after resolving redirects for %s", shortURL) // Should not be reached
 }
+
+// newResolveRequest creates a request for resolving target, with the User-Agent and
+// headers configured for its shortener domain in cfg (which may be nil).
+func newResolveRequest(ctx context.Context, cfg *config.Config, method, target string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", target, err)
+	}
+	req.Header.Set("User-Agent", "rurl/1.0")
+	if cfg == nil {
+		return req, nil
+	}
+	if s := FindShortener(cfg, req.URL.Hostname()); s != nil {
+		if s.UserAgent != "" {
+			req.Header.Set("User-Agent", s.UserAgent)
+		}
+		for name, value := range s.Headers {
+			req.Header.Set(name, value)
+		}
+	}
+	return req, nil
+}
+
+// sameHost reports whether two URLs have the same host.
+func sameHost(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	return errA == nil && errB == nil && strings.EqualFold(ua.Host, ub.Host)
+}
+
+// RedirectHop is a single request in a redirect chain.
+type RedirectHop struct {
+	URL      string // URL requested
+	Status   int    // HTTP status code of the response
+	Location string // Absolute redirect target (empty if the response was not a redirect)
+}
+
+// TraceRedirects follows redirects from startURL using HEAD requests, up to maxHops
+// requests, and returns every hop. Hops gathered before an error (including ctx being
+// cancelled) are returned with it.
+func TraceRedirects(ctx context.Context, startURL string, maxHops int, timeout time.Duration) ([]RedirectHop, error) {
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	var hops []RedirectHop
+	currentURL := startURL
+	for i := 0; i < maxHops; i++ {
+		req, err := http.NewRequestWithContext(ctx, "HEAD", currentURL, nil)
+		if err != nil {
+			return hops, fmt.Errorf("failed to create request for %s: %w", currentURL, err)
+		}
+		req.Header.Set("User-Agent", "rurl/1.0")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return hops, fmt.Errorf("failed to perform request for %s: %w", currentURL, err)
+		}
+		if resp.Body != nil {
+			resp.Body.Close()
+		}
+
+		hop := RedirectHop{URL: currentURL, Status: resp.StatusCode}
+		location := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+			return append(hops, hop), nil
+		}
+		redirectURL, err := url.Parse(location)
+		if err != nil {
+			return append(hops, hop), fmt.Errorf("failed to parse redirect location '%s': %w", location, err)
+		}
+		baseReqURL, _ := url.Parse(currentURL)
+		hop.Location = baseReqURL.ResolveReference(redirectURL).String()
+		hops = append(hops, hop)
+		currentURL = hop.Location
+	}
+	return hops, fmt.Errorf("stopped after %d redirects", maxHops)
+}
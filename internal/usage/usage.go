@@ -0,0 +1,107 @@
+// Package usage counts, when enabled, how often each rule and profile opens a URL and
+// when it last did, so that rules and profiles nobody uses any more can be found and
+// deleted.
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Counter is the use of a rule or profile.
+type Counter struct {
+	Hits     int       `json:"hits"`      // Number of URLs opened
+	LastUsed time.Time `json:"last_used"` // When the last one was opened
+}
+
+// Stats is the use of the rules and profiles since counting started.
+type Stats struct {
+	Since    time.Time          `json:"since"`    // When the first use was recorded
+	Rules    map[string]Counter `json:"rules"`    // By rule ID
+	Profiles map[string]Counter `json:"profiles"` // By profile ID
+}
+
+// DefaultPath returns the usage file location in the user cache directory.
+func DefaultPath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "rurl", "usage.json"), nil
+}
+
+// Load returns the stats stored at path. A missing file is not an error: it returns
+// empty stats with a zero Since.
+func Load(path string) (*Stats, error) {
+	stats := &Stats{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage '%s': %w", path, err)
+	}
+	if err := json.Unmarshal(data, stats); err != nil {
+		return nil, fmt.Errorf("failed to parse usage '%s': %w", path, err)
+	}
+	return stats, nil
+}
+
+// Save writes stats to path, replacing its contents.
+func Save(path string, stats *Stats) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create usage directory: %w", err)
+	}
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode usage: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write usage: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write usage: %w", err)
+	}
+	return nil
+}
+
+// Record counts a URL opened at at by the rule ruleID in the profile profileID. Either
+// may be empty, e.g. for the default profile or a rule's handler.
+func Record(path, ruleID, profileID string, at time.Time) error {
+	stats, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if stats.Since.IsZero() {
+		stats.Since = at
+	}
+	stats.Rules = count(stats.Rules, ruleID, at)
+	stats.Profiles = count(stats.Profiles, profileID, at)
+	return Save(path, stats)
+}
+
+// count adds a hit at at to the counter of id in counters, unless id is empty.
+func count(counters map[string]Counter, id string, at time.Time) map[string]Counter {
+	if id == "" {
+		return counters
+	}
+	if counters == nil {
+		counters = make(map[string]Counter)
+	}
+	c := counters[id]
+	c.Hits++
+	c.LastUsed = at
+	counters[id] = c
+	return counters
+}
+
+// UnusedSince reports whether the counter of id in counters has no use at or after
+// cutoff, including if id was never used.
+func UnusedSince(counters map[string]Counter, id string, cutoff time.Time) bool {
+	return counters[id].LastUsed.Before(cutoff)
+}
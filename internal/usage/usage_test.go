@@ -0,0 +1,46 @@
+package usage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "usage.json")
+
+	stats, err := Load(path)
+	require.NoError(t, err)
+	assert.True(t, stats.Since.IsZero())
+	assert.Empty(t, stats.Rules)
+
+	first := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, Record(path, "rule-work", "work", first))
+	require.NoError(t, Record(path, "", "personal", first.Add(time.Hour)))
+	require.NoError(t, Record(path, "rule-work", "work", first.Add(2*time.Hour)))
+
+	stats, err = Load(path)
+	require.NoError(t, err)
+	assert.True(t, first.Equal(stats.Since))
+	assert.Len(t, stats.Rules, 1)
+	assert.Equal(t, 2, stats.Rules["rule-work"].Hits)
+	assert.True(t, first.Add(2*time.Hour).Equal(stats.Rules["rule-work"].LastUsed))
+	assert.Equal(t, 2, stats.Profiles["work"].Hits)
+	assert.Equal(t, 1, stats.Profiles["personal"].Hits)
+}
+
+func TestUnusedSince(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	counters := map[string]Counter{
+		"recent": {Hits: 3, LastUsed: now.Add(-24 * time.Hour)},
+		"stale":  {Hits: 9, LastUsed: now.Add(-100 * 24 * time.Hour)},
+	}
+	cutoff := now.Add(-90 * 24 * time.Hour)
+	assert.False(t, UnusedSince(counters, "recent", cutoff))
+	assert.True(t, UnusedSince(counters, "stale", cutoff))
+	assert.True(t, UnusedSince(counters, "never", cutoff))
+	assert.True(t, UnusedSince(nil, "never", cutoff))
+}
@@ -0,0 +1,65 @@
+// Package wsl detects the Windows Subsystem for Linux and bridges from inside it to
+// the Windows host: its user profile directory and its default URL handler.
+package wsl
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// osReleasePath is the kernel release file, whose WSL kernels mention "microsoft".
+var osReleasePath = "/proc/sys/kernel/osrelease"
+
+// DriveRoot is where the Windows system drive is mounted inside WSL.
+var DriveRoot = "/mnt/c"
+
+// IsWSL reports whether rurl runs inside WSL (version 1 or 2).
+func IsWSL() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	if os.Getenv("WSL_DISTRO_NAME") != "" || os.Getenv("WSL_INTEROP") != "" {
+		return true
+	}
+	data, err := os.ReadFile(osReleasePath)
+	return err == nil && strings.Contains(strings.ToLower(string(data)), "microsoft")
+}
+
+// WindowsHome returns the Linux path of the Windows user's profile directory (e.g.
+// /mnt/c/Users/me), asking Windows through interop. If that fails, the directory
+// under DriveRoot named after the Linux user is tried.
+func WindowsHome() (string, error) {
+	if out, err := exec.Command("cmd.exe", "/c", "echo %USERPROFILE%").Output(); err == nil {
+		winPath := strings.TrimSpace(string(out))
+		if winPath != "" && winPath != "%USERPROFILE%" {
+			if out, err := exec.Command("wslpath", "-u", winPath).Output(); err == nil {
+				return strings.TrimSpace(string(out)), nil
+			}
+		}
+	}
+
+	home := DriveRoot + "/Users/" + os.Getenv("USER")
+	if info, err := os.Stat(home); err == nil && info.IsDir() {
+		return home, nil
+	}
+	return "", fmt.Errorf("failed to find the Windows user profile directory")
+}
+
+// OpenCommand returns the command handing url to the Windows default handler: wslview
+// (from wslu) if installed, otherwise the Windows URL protocol handler through
+// interop. As on Windows, "cmd.exe /c start" is avoided because it re-parses the URL
+// and mangles '&'.
+func OpenCommand(url string) *exec.Cmd {
+	if path, err := exec.LookPath("wslview"); err == nil {
+		return exec.Command(path, url)
+	}
+	cmd := exec.Command("rundll32.exe", "url.dll,FileProtocolHandler", url)
+	// Windows programs warn about (and may fail in) a Linux working directory
+	if info, err := os.Stat(DriveRoot); err == nil && info.IsDir() {
+		cmd.Dir = DriveRoot
+	}
+	return cmd
+}
@@ -0,0 +1,50 @@
+package wsl
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsWSL(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		assert.False(t, IsWSL())
+		return
+	}
+	dir := t.TempDir()
+	osReleasePath = filepath.Join(dir, "osrelease")
+	t.Cleanup(func() { osReleasePath = "/proc/sys/kernel/osrelease" })
+	t.Setenv("WSL_DISTRO_NAME", "")
+	t.Setenv("WSL_INTEROP", "")
+
+	require.NoError(t, os.WriteFile(osReleasePath, []byte("6.8.0-45-generic\n"), 0644))
+	assert.False(t, IsWSL())
+
+	require.NoError(t, os.WriteFile(osReleasePath, []byte("5.15.153.1-microsoft-standard-WSL2\n"), 0644))
+	assert.True(t, IsWSL())
+
+	require.NoError(t, os.Remove(osReleasePath))
+	assert.False(t, IsWSL())
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+	assert.True(t, IsWSL())
+}
+
+func TestOpenCommand(t *testing.T) {
+	bin := t.TempDir()
+	t.Setenv("PATH", bin)
+	DriveRoot = t.TempDir()
+	t.Cleanup(func() { DriveRoot = "/mnt/c" })
+
+	url := "https://example.com/?a=1&b=2"
+	cmd := OpenCommand(url)
+	assert.Equal(t, []string{"rundll32.exe", "url.dll,FileProtocolHandler", url}, cmd.Args)
+	assert.Equal(t, DriveRoot, cmd.Dir)
+
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "wslview"), []byte("#!/bin/sh\n"), 0755))
+	cmd = OpenCommand(url)
+	assert.Equal(t, []string{filepath.Join(bin, "wslview"), url}, cmd.Args)
+}
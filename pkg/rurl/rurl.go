@@ -0,0 +1,162 @@
+// Package rurl lets other Go programs (e.g. a mail client or a launcher bar) route
+// URLs the way the rurl command does, without shelling out to the binary.
+//
+// A Router is created from a configuration, usually the user's rurl config:
+//
+//	cfg, err := rurl.LoadConfig("") // Default location
+//	if err != nil {
+//		return err
+//	}
+//	decision, err := rurl.NewRouter(cfg).Open("https://example.com/")
+//
+// Route only decides where a URL would go; Open also launches it. Launch hooks,
+// headless fallbacks and launch history are features of the rurl command and are not
+// applied by this package.
+package rurl
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/jmylchreest/rurl/internal/launcher"
+	"github.com/jmylchreest/rurl/internal/router"
+)
+
+// Configuration types, aliased so embedders can build or adjust a configuration.
+type (
+	Config  = config.Config  // Browsers, profiles, rules and options
+	Browser = config.Browser // A browser installation
+	Profile = config.Profile // A browser profile
+	Rule    = config.Rule    // A routing rule
+)
+
+// Rule scopes.
+const (
+	ScopeURL    = config.ScopeURL
+	ScopeDomain = config.ScopeDomain
+	ScopePath   = config.ScopePath
+	ScopeCIDR   = config.ScopeCIDR
+)
+
+// LoadConfig loads the configuration file at path, or the user's rurl configuration
+// if path is empty. It never writes files or prints: if the user has no configuration
+// yet, the default configuration is returned without creating one.
+func LoadConfig(path string) (*Config, error) {
+	return config.LoadConfigOrDefault(path)
+}
+
+// Decision describes where a URL is routed.
+type Decision struct {
+	URL         string // URL as given
+	MatchedURL  string // URL the rules were matched against (shortener resolved, rewritten and cleaned)
+	LaunchURL   string // URL that is opened
+	Passthrough bool   // The URL's scheme is handed to the system's default handler instead
+	RuleID      string // Matched rule (empty if the default profile or an override is used)
+	RuleName    string
+	Override    bool     // The profile is given by an override for the exact URL
+	ProfileID   string   // Profile the URL is opened in
+	Incognito   bool     // Opened in a private window
+	AppID       string   // Installed PWA the URL is opened in (empty for a normal window)
+	WindowMode  string   // Window handling requested by the rule (empty uses the browser's)
+	DeepLinkURL string   // Native meeting app link opened instead of LaunchURL (empty for a browser)
+	ExtraArgs   []string // Extra browser arguments requested by the rule
+	Handler     string   // Command template LaunchURL is passed to instead of a browser (empty for a browser)
+	// PolicyViolation is why the resolved URL violates the configured resolution policy,
+	// if it does and the policy's action is "prompt". Open refuses such URLs with a
+	// *BlockedError, as it cannot ask the user.
+	PolicyViolation string
+}
+
+// BlockedError is returned when a resolved URL violates the resolution policy and the
+// policy blocks it, or when the URL to launch fails the URL safety checks.
+type BlockedError = router.BlockedError
+
+// Router routes URLs according to a configuration.
+type Router struct {
+	cfg      *Config
+	launcher launcher.Launcher
+}
+
+// NewRouter returns a Router for cfg that launches browsers as detached processes.
+func NewRouter(cfg *Config) *Router {
+	return &Router{cfg: cfg, launcher: launcher.NewExecLauncher()}
+}
+
+// Route decides where rawURL would be opened, without opening it. Known shorteners are
+// resolved (a network request) and content inspection is done if enabled.
+func (r *Router) Route(rawURL string) (Decision, error) {
+	return r.RouteContext(context.Background(), rawURL)
+}
+
+// RouteContext is like Route, but stops network requests and plugins once ctx is
+// cancelled and returns an error instead of a decision.
+func (r *Router) RouteContext(ctx context.Context, rawURL string) (Decision, error) {
+	if r.cfg == nil {
+		return Decision{}, fmt.Errorf("configuration is nil")
+	}
+	if u, err := url.Parse(rawURL); err == nil && u.Scheme != "" && r.cfg.IsPassthroughScheme(u.Scheme) {
+		return Decision{URL: rawURL, MatchedURL: rawURL, LaunchURL: rawURL, Passthrough: true}, nil
+	}
+
+	result, err := router.Route(ctx, r.cfg, rawURL)
+	if err != nil {
+		return Decision{}, err
+	}
+	d := Decision{
+		URL:         rawURL,
+		MatchedURL:  result.MatchURL,
+		LaunchURL:   result.LaunchURL,
+		ProfileID:   result.Match.ProfileID,
+		Incognito:   launcher.ResolveIncognito(r.cfg, result.Match.ProfileID, result.Match.Incognito, nil),
+		AppID:       result.Match.PWAAppID,
+		WindowMode:  result.Match.WindowMode,
+		DeepLinkURL: result.Match.DeepLinkURL,
+		ExtraArgs:   result.Match.ExtraArgs,
+		Handler:     result.Match.Handler,
+		Override:    result.Match.Override,
+
+		PolicyViolation: result.PolicyViolation,
+	}
+	if result.Match.Rule != nil {
+		d.RuleID = result.Match.Rule.ID
+		d.RuleName = result.Match.Rule.Name
+	}
+	return d, nil
+}
+
+// Open routes rawURL and opens it.
+func (r *Router) Open(rawURL string) (Decision, error) {
+	return r.OpenContext(context.Background(), rawURL)
+}
+
+// OpenContext is like Open, with routing bounded by ctx as in RouteContext.
+func (r *Router) OpenContext(ctx context.Context, rawURL string) (Decision, error) {
+	d, err := r.RouteContext(ctx, rawURL)
+	if err != nil {
+		return d, err
+	}
+	if d.Passthrough {
+		return d, launcher.OpenWithSystem(d.LaunchURL)
+	}
+	if d.PolicyViolation != "" {
+		return d, &BlockedError{URL: d.MatchedURL, Reason: d.PolicyViolation}
+	}
+	if d.DeepLinkURL != "" {
+		return d, launcher.OpenWithSystem(d.DeepLinkURL)
+	}
+	if d.Handler != "" {
+		return d, launcher.RunHandler(d.Handler, launcher.HookInfo{
+			URL:         d.LaunchURL,
+			OriginalURL: d.URL,
+			Mode:        launcher.LaunchModeHandler,
+			RuleID:      d.RuleID,
+			RuleName:    d.RuleName,
+		})
+	}
+	if d.AppID != "" {
+		return d, launcher.LaunchProfileApp(r.launcher, r.cfg, d.ProfileID, d.AppID, d.LaunchURL, d.Incognito)
+	}
+	return d, launcher.LaunchProfileWindow(r.launcher, r.cfg, d.ProfileID, d.LaunchURL, d.Incognito, d.WindowMode, d.ExtraArgs...)
+}
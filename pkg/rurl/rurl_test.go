@@ -0,0 +1,58 @@
+package rurl
+
+import (
+	"testing"
+
+	"github.com/jmylchreest/rurl/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouterRoute(t *testing.T) {
+	cfg := &Config{
+		DefaultProfileID: "personal",
+		Browsers:         []Browser{{Name: "Test", BrowserID: "test", Executable: "/bin/true"}},
+		Profiles: []Profile{
+			{ID: "personal", BrowserID: "test"},
+			{ID: "work", BrowserID: "test"},
+		},
+		Rules: []Rule{
+			{ID: "work", Name: "Work", Pattern: `^work\.example\.com$`, Scope: ScopeDomain, ProfileID: "work", Incognito: true},
+		},
+		URLCleaning: config.URLCleaning{UnAMP: true},
+		Behavior:    config.Behavior{PassthroughSchemes: []string{"mailto"}},
+	}
+	r := NewRouter(cfg)
+
+	d, err := r.Route("https://www.google.com/amp/s/work.example.com/page")
+	require.NoError(t, err)
+	assert.Equal(t, "https://work.example.com/page", d.MatchedURL)
+	assert.Equal(t, "https://work.example.com/page", d.LaunchURL)
+	assert.Equal(t, "work", d.RuleID)
+	assert.Equal(t, "work", d.ProfileID)
+	assert.True(t, d.Incognito)
+
+	d, err = r.Route("https://other.example.com/")
+	require.NoError(t, err)
+	assert.Empty(t, d.RuleID)
+	assert.Equal(t, "personal", d.ProfileID)
+
+	d, err = r.Route("mailto:someone@example.com")
+	require.NoError(t, err)
+	assert.True(t, d.Passthrough)
+
+	_, err = NewRouter(nil).Route("https://example.com/")
+	assert.Error(t, err)
+}
+
+func TestLoadConfigDoesNotCreate(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path, err := config.DefaultConfigFile()
+	require.NoError(t, err)
+
+	// Programs using the library get the defaults without a file appearing
+	cfg, err := LoadConfig("")
+	require.NoError(t, err)
+	assert.NotEmpty(t, cfg.Shorteners)
+	assert.NoFileExists(t, path)
+}